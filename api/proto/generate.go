@@ -0,0 +1,4 @@
+// Package proto 管理API的protobuf消息和gRPC服务定义
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative speedmimi.proto
@@ -1,39 +1,55 @@
+// SpeedMimi 管理API的gRPC定义
+// 与HTTP管理API共用端口、认证和并发限制，修改后运行 go generate ./api/proto 重新生成 speedmimi.pb.go 和 speedmimi_grpc.pb.go（需要protoc、protoc-gen-go和protoc-gen-go-grpc）
+
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.31.0
-// 	protoc        v4.25.0
+// 	protoc        (unknown)
 // source: speedmimi.proto
 
 package proto
 
 import (
-	reflect "reflect"
-	sync "sync"
-
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	reflect "reflect"
+	sync "sync"
 )
 
-import (
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// 消息定义
-
-type Config struct {
+// Backend 后端服务器
+type Backend struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Server   *ServerConfig             `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
-	Ssl      *SSLConfig                `protobuf:"bytes,2,opt,name=ssl,proto3" json:"ssl,omitempty"`
-	Backends map[string]*BackendList   `protobuf:"bytes,3,rep,name=backends,proto3" json:"backends,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Routing  map[string]*RoutingRule   `protobuf:"bytes,4,rep,name=routing,proto3" json:"routing,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Grpc     *GRPCConfig               `protobuf:"bytes,5,opt,name=grpc,proto3" json:"grpc,omitempty"`
+	Id          string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Host        string            `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Port        int32             `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Weight      int32             `protobuf:"varint,5,opt,name=weight,proto3" json:"weight,omitempty"`
+	Scheme      string            `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Active      bool              `protobuf:"varint,7,opt,name=active,proto3" json:"active,omitempty"`
+	MaxConn     int32             `protobuf:"varint,8,opt,name=max_conn,json=maxConn,proto3" json:"max_conn,omitempty"`
+	Priority    int32             `protobuf:"varint,9,opt,name=priority,proto3" json:"priority,omitempty"`
+	Zone        string            `protobuf:"bytes,10,opt,name=zone,proto3" json:"zone,omitempty"`
+	Labels      map[string]string `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	HealthCheck *HealthCheck      `protobuf:"bytes,12,opt,name=health_check,json=healthCheck,proto3" json:"health_check,omitempty"`
+	// 以下字段只在响应中返回
+	Connections int64            `protobuf:"varint,13,opt,name=connections,proto3" json:"connections,omitempty"`
+	Healthy     bool             `protobuf:"varint,14,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Performance *PerformanceInfo `protobuf:"bytes,15,opt,name=performance,proto3" json:"performance,omitempty"`
 }
 
-func (x *Config) Reset() {
-	*x = Config{}
+func (x *Backend) Reset() {
+	*x = Backend{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_speedmimi_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -41,13 +57,13 @@ func (x *Config) Reset() {
 	}
 }
 
-func (x *Config) String() string {
+func (x *Backend) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Config) ProtoMessage() {}
+func (*Backend) ProtoMessage() {}
 
-func (x *Config) ProtoReflect() protoreflect.Message {
+func (x *Backend) ProtoReflect() protoreflect.Message {
 	mi := &file_speedmimi_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -59,168 +75,154 @@ func (x *Config) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Config.ProtoReflect.Descriptor instead.
-func (*Config) Descriptor() ([]byte, []int) {
+// Deprecated: Use Backend.ProtoReflect.Descriptor instead.
+func (*Backend) Descriptor() ([]byte, []int) {
 	return file_speedmimi_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *Config) GetServer() *ServerConfig {
+func (x *Backend) GetId() string {
 	if x != nil {
-		return x.Server
+		return x.Id
 	}
-	return nil
+	return ""
 }
 
-func (x *Config) GetSsl() *SSLConfig {
+func (x *Backend) GetName() string {
 	if x != nil {
-		return x.Ssl
+		return x.Name
 	}
-	return nil
+	return ""
 }
 
-func (x *Config) GetBackends() map[string]*BackendList {
+func (x *Backend) GetHost() string {
 	if x != nil {
-		return x.Backends
+		return x.Host
 	}
-	return nil
+	return ""
 }
 
-func (x *Config) GetRouting() map[string]*RoutingRule {
+func (x *Backend) GetPort() int32 {
 	if x != nil {
-		return x.Routing
+		return x.Port
 	}
-	return nil
+	return 0
 }
 
-func (x *Config) GetGrpc() *GRPCConfig {
+func (x *Backend) GetWeight() int32 {
 	if x != nil {
-		return x.Grpc
+		return x.Weight
 	}
-	return nil
-}
-
-type ServerConfig struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Host          string   `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
-	Port          int32    `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
-	ReadTimeout   string   `protobuf:"bytes,3,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"`
-	WriteTimeout  string   `protobuf:"bytes,4,opt,name=write_timeout,json=writeTimeout,proto3" json:"write_timeout,omitempty"`
-	MaxConn       int32    `protobuf:"varint,5,opt,name=max_conn,json=maxConn,proto3" json:"max_conn,omitempty"`
-	RealIpHeader  string   `protobuf:"bytes,6,opt,name=real_ip_header,json=realIpHeader,proto3" json:"real_ip_header,omitempty"`
-	TrustedProxies []string `protobuf:"bytes,7,rep,name=trusted_proxies,json=trustedProxies,proto3" json:"trusted_proxies,omitempty"`
+	return 0
 }
 
-func (x *ServerConfig) Reset() {
-	*x = ServerConfig{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *Backend) GetScheme() string {
+	if x != nil {
+		return x.Scheme
 	}
+	return ""
 }
 
-func (x *ServerConfig) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ServerConfig) ProtoMessage() {}
-
-func (x *ServerConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *Backend) GetActive() bool {
+	if x != nil {
+		return x.Active
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ServerConfig.ProtoReflect.Descriptor instead.
-func (*ServerConfig) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{1}
+	return false
 }
 
-func (x *ServerConfig) GetHost() string {
+func (x *Backend) GetMaxConn() int32 {
 	if x != nil {
-		return x.Host
+		return x.MaxConn
 	}
-	return ""
+	return 0
 }
 
-func (x *ServerConfig) GetPort() int32 {
+func (x *Backend) GetPriority() int32 {
 	if x != nil {
-		return x.Port
+		return x.Priority
 	}
 	return 0
 }
 
-func (x *ServerConfig) GetReadTimeout() string {
+func (x *Backend) GetZone() string {
 	if x != nil {
-		return x.ReadTimeout
+		return x.Zone
 	}
 	return ""
 }
 
-func (x *ServerConfig) GetWriteTimeout() string {
+func (x *Backend) GetLabels() map[string]string {
 	if x != nil {
-		return x.WriteTimeout
+		return x.Labels
 	}
-	return ""
+	return nil
 }
 
-func (x *ServerConfig) GetMaxConn() int32 {
+func (x *Backend) GetHealthCheck() *HealthCheck {
 	if x != nil {
-		return x.MaxConn
+		return x.HealthCheck
+	}
+	return nil
+}
+
+func (x *Backend) GetConnections() int64 {
+	if x != nil {
+		return x.Connections
 	}
 	return 0
 }
 
-func (x *ServerConfig) GetRealIpHeader() string {
+func (x *Backend) GetHealthy() bool {
 	if x != nil {
-		return x.RealIpHeader
+		return x.Healthy
 	}
-	return ""
+	return false
 }
 
-func (x *ServerConfig) GetTrustedProxies() []string {
+func (x *Backend) GetPerformance() *PerformanceInfo {
 	if x != nil {
-		return x.TrustedProxies
+		return x.Performance
 	}
 	return nil
 }
 
-type SSLConfig struct {
+// HealthCheck 后端健康检查，时长使用Go的时长格式（如10s），script类型只能在配置文件中配置
+type HealthCheck struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Enabled  bool   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
-	CertFile string `protobuf:"bytes,2,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`
-	KeyFile  string `protobuf:"bytes,3,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`
+	Type         string  `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Path         string  `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Service      string  `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	StatusCodes  []int32 `protobuf:"varint,4,rep,packed,name=status_codes,json=statusCodes,proto3" json:"status_codes,omitempty"`
+	BodyContains string  `protobuf:"bytes,5,opt,name=body_contains,json=bodyContains,proto3" json:"body_contains,omitempty"`
+	BodyRegex    string  `protobuf:"bytes,6,opt,name=body_regex,json=bodyRegex,proto3" json:"body_regex,omitempty"`
+	BodyNot      bool    `protobuf:"varint,7,opt,name=body_not,json=bodyNot,proto3" json:"body_not,omitempty"`
+	Interval     string  `protobuf:"bytes,8,opt,name=interval,proto3" json:"interval,omitempty"`
+	Timeout      string  `protobuf:"bytes,9,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Failures     int32   `protobuf:"varint,10,opt,name=failures,proto3" json:"failures,omitempty"`
+	Rise         int32   `protobuf:"varint,11,opt,name=rise,proto3" json:"rise,omitempty"`
+	Fall         int32   `protobuf:"varint,12,opt,name=fall,proto3" json:"fall,omitempty"`
+	Jitter       string  `protobuf:"bytes,13,opt,name=jitter,proto3" json:"jitter,omitempty"`
 }
 
-func (x *SSLConfig) Reset() {
-	*x = SSLConfig{}
+func (x *HealthCheck) Reset() {
+	*x = HealthCheck{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[2]
+		mi := &file_speedmimi_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *SSLConfig) String() string {
+func (x *HealthCheck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SSLConfig) ProtoMessage() {}
+func (*HealthCheck) ProtoMessage() {}
 
-func (x *SSLConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[2]
+func (x *HealthCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -231,115 +233,143 @@ func (x *SSLConfig) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SSLConfig.ProtoReflect.Descriptor instead.
-func (*SSLConfig) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use HealthCheck.ProtoReflect.Descriptor instead.
+func (*HealthCheck) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *SSLConfig) GetEnabled() bool {
+func (x *HealthCheck) GetType() string {
 	if x != nil {
-		return x.Enabled
+		return x.Type
 	}
-	return false
+	return ""
 }
 
-func (x *SSLConfig) GetCertFile() string {
+func (x *HealthCheck) GetPath() string {
 	if x != nil {
-		return x.CertFile
+		return x.Path
 	}
 	return ""
 }
 
-func (x *SSLConfig) GetKeyFile() string {
+func (x *HealthCheck) GetService() string {
 	if x != nil {
-		return x.KeyFile
+		return x.Service
 	}
 	return ""
 }
 
-type BackendList struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *HealthCheck) GetStatusCodes() []int32 {
+	if x != nil {
+		return x.StatusCodes
+	}
+	return nil
+}
 
-	Backends []*Backend `protobuf:"bytes,1,rep,name=backends,proto3" json:"backends,omitempty"`
+func (x *HealthCheck) GetBodyContains() string {
+	if x != nil {
+		return x.BodyContains
+	}
+	return ""
 }
 
-func (x *BackendList) Reset() {
-	*x = BackendList{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[3]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *HealthCheck) GetBodyRegex() string {
+	if x != nil {
+		return x.BodyRegex
 	}
+	return ""
 }
 
-func (x *BackendList) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *HealthCheck) GetBodyNot() bool {
+	if x != nil {
+		return x.BodyNot
+	}
+	return false
 }
 
-func (*BackendList) ProtoMessage() {}
+func (x *HealthCheck) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
 
-func (x *BackendList) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *HealthCheck) GetTimeout() string {
+	if x != nil {
+		return x.Timeout
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use BackendList.ProtoReflect.Descriptor instead.
-func (*BackendList) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{3}
+func (x *HealthCheck) GetFailures() int32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
 }
 
-func (x *BackendList) GetBackends() []*Backend {
+func (x *HealthCheck) GetRise() int32 {
 	if x != nil {
-		return x.Backends
+		return x.Rise
 	}
-	return nil
+	return 0
 }
 
-type Backend struct {
+func (x *HealthCheck) GetFall() int32 {
+	if x != nil {
+		return x.Fall
+	}
+	return 0
+}
+
+func (x *HealthCheck) GetJitter() string {
+	if x != nil {
+		return x.Jitter
+	}
+	return ""
+}
+
+// PerformanceInfo 性能信息
+type PerformanceInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id           string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name         string          `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Host         string          `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
-	Port         int32           `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
-	Weight       int32           `protobuf:"varint,5,opt,name=weight,proto3" json:"weight,omitempty"`
-	Scheme       string          `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
-	Active       bool            `protobuf:"varint,7,opt,name=active,proto3" json:"active,omitempty"`
-	MaxConn      int32           `protobuf:"varint,8,opt,name=max_conn,json=maxConn,proto3" json:"max_conn,omitempty"`
-	HealthCheck  *HealthCheck    `protobuf:"bytes,9,opt,name=health_check,json=healthCheck,proto3" json:"health_check,omitempty"`
-	Performance  *PerformanceInfo `protobuf:"bytes,10,opt,name=performance,proto3" json:"performance,omitempty"`
-	LastReport   int64           `protobuf:"varint,11,opt,name=last_report,json=lastReport,proto3" json:"last_report,omitempty"`
-	Connections  int64           `protobuf:"varint,12,opt,name=connections,proto3" json:"connections,omitempty"`
+	CpuUsage    float64 `protobuf:"fixed64,1,opt,name=cpu_usage,json=cpuUsage,proto3" json:"cpu_usage,omitempty"`
+	MemoryUsage float64 `protobuf:"fixed64,2,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"`
+	DiskUsage   float64 `protobuf:"fixed64,3,opt,name=disk_usage,json=diskUsage,proto3" json:"disk_usage,omitempty"`
+	LoadAvg_1   float64 `protobuf:"fixed64,4,opt,name=load_avg_1,json=loadAvg1,proto3" json:"load_avg_1,omitempty"`
+	LoadAvg_5   float64 `protobuf:"fixed64,5,opt,name=load_avg_5,json=loadAvg5,proto3" json:"load_avg_5,omitempty"`
+	LoadAvg_15  float64 `protobuf:"fixed64,6,opt,name=load_avg_15,json=loadAvg15,proto3" json:"load_avg_15,omitempty"`
+	NetworkIn   float64 `protobuf:"fixed64,7,opt,name=network_in,json=networkIn,proto3" json:"network_in,omitempty"`
+	NetworkOut  float64 `protobuf:"fixed64,8,opt,name=network_out,json=networkOut,proto3" json:"network_out,omitempty"`
+	// 后端上报的时间戳（Unix秒或毫秒）
+	Timestamp int64 `protobuf:"varint,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// 代理收到上报的时间（Unix毫秒），由代理设置
+	ReceivedAt  int64   `protobuf:"varint,10,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	ClockSkewMs int64   `protobuf:"varint,11,opt,name=clock_skew_ms,json=clockSkewMs,proto3" json:"clock_skew_ms,omitempty"`
+	Source      string  `protobuf:"bytes,12,opt,name=source,proto3" json:"source,omitempty"`
+	Rps         float64 `protobuf:"fixed64,13,opt,name=rps,proto3" json:"rps,omitempty"`
+	Eps         float64 `protobuf:"fixed64,14,opt,name=eps,proto3" json:"eps,omitempty"`
 }
 
-func (x *Backend) Reset() {
-	*x = Backend{}
+func (x *PerformanceInfo) Reset() {
+	*x = PerformanceInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[4]
+		mi := &file_speedmimi_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Backend) String() string {
+func (x *PerformanceInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Backend) ProtoMessage() {}
+func (*PerformanceInfo) ProtoMessage() {}
 
-func (x *Backend) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[4]
+func (x *PerformanceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -350,194 +380,139 @@ func (x *Backend) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Backend.ProtoReflect.Descriptor instead.
-func (*Backend) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use PerformanceInfo.ProtoReflect.Descriptor instead.
+func (*PerformanceInfo) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *Backend) GetId() string {
+func (x *PerformanceInfo) GetCpuUsage() float64 {
 	if x != nil {
-		return x.Id
+		return x.CpuUsage
 	}
-	return ""
+	return 0
 }
 
-func (x *Backend) GetName() string {
+func (x *PerformanceInfo) GetMemoryUsage() float64 {
 	if x != nil {
-		return x.Name
+		return x.MemoryUsage
 	}
-	return ""
+	return 0
 }
 
-func (x *Backend) GetHost() string {
+func (x *PerformanceInfo) GetDiskUsage() float64 {
 	if x != nil {
-		return x.Host
+		return x.DiskUsage
 	}
-	return ""
+	return 0
 }
 
-func (x *Backend) GetPort() int32 {
+func (x *PerformanceInfo) GetLoadAvg_1() float64 {
 	if x != nil {
-		return x.Port
+		return x.LoadAvg_1
 	}
 	return 0
 }
 
-func (x *Backend) GetWeight() int32 {
+func (x *PerformanceInfo) GetLoadAvg_5() float64 {
 	if x != nil {
-		return x.Weight
+		return x.LoadAvg_5
 	}
 	return 0
 }
 
-func (x *Backend) GetScheme() string {
+func (x *PerformanceInfo) GetLoadAvg_15() float64 {
 	if x != nil {
-		return x.Scheme
+		return x.LoadAvg_15
 	}
-	return ""
+	return 0
 }
 
-func (x *Backend) GetActive() bool {
+func (x *PerformanceInfo) GetNetworkIn() float64 {
 	if x != nil {
-		return x.Active
+		return x.NetworkIn
 	}
-	return false
+	return 0
 }
 
-func (x *Backend) GetMaxConn() int32 {
+func (x *PerformanceInfo) GetNetworkOut() float64 {
 	if x != nil {
-		return x.MaxConn
+		return x.NetworkOut
 	}
 	return 0
 }
 
-func (x *Backend) GetHealthCheck() *HealthCheck {
+func (x *PerformanceInfo) GetTimestamp() int64 {
 	if x != nil {
-		return x.HealthCheck
+		return x.Timestamp
 	}
-	return nil
+	return 0
 }
 
-func (x *Backend) GetPerformance() *PerformanceInfo {
+func (x *PerformanceInfo) GetReceivedAt() int64 {
 	if x != nil {
-		return x.Performance
+		return x.ReceivedAt
 	}
-	return nil
+	return 0
 }
 
-func (x *Backend) GetLastReport() int64 {
+func (x *PerformanceInfo) GetClockSkewMs() int64 {
 	if x != nil {
-		return x.LastReport
+		return x.ClockSkewMs
 	}
 	return 0
 }
 
-func (x *Backend) GetConnections() int64 {
+func (x *PerformanceInfo) GetSource() string {
 	if x != nil {
-		return x.Connections
+		return x.Source
 	}
-	return 0
+	return ""
 }
 
-type HealthCheck struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Path     string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Interval string `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
-	Timeout  string `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
-	Failures int32  `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
-}
-
-func (x *HealthCheck) Reset() {
-	*x = HealthCheck{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[5]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
-}
-
-func (x *HealthCheck) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*HealthCheck) ProtoMessage() {}
-
-func (x *HealthCheck) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[5]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use HealthCheck.ProtoReflect.Descriptor instead.
-func (*HealthCheck) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{5}
-}
-
-func (x *HealthCheck) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-func (x *HealthCheck) GetInterval() string {
-	if x != nil {
-		return x.Interval
-	}
-	return ""
-}
-
-func (x *HealthCheck) GetTimeout() string {
+func (x *PerformanceInfo) GetRps() float64 {
 	if x != nil {
-		return x.Timeout
+		return x.Rps
 	}
-	return ""
+	return 0
 }
 
-func (x *HealthCheck) GetFailures() int32 {
+func (x *PerformanceInfo) GetEps() float64 {
 	if x != nil {
-		return x.Failures
+		return x.Eps
 	}
 	return 0
 }
 
-type RoutingRule struct {
+// TrafficStats 代理的流量统计
+type TrafficStats struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Path       string            `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Upstream   string            `protobuf:"bytes,2,opt,name=upstream,proto3" json:"upstream,omitempty"`
-	LoadBalancer string          `protobuf:"bytes,3,opt,name=load_balancer,json=loadBalancer,proto3" json:"load_balancer,omitempty"`
-	Protocols  map[string]string `protobuf:"bytes,4,rep,name=protocols,proto3" json:"protocols,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	TotalRequests     int64 `protobuf:"varint,1,opt,name=total_requests,json=totalRequests,proto3" json:"total_requests,omitempty"`
+	ActiveConnections int64 `protobuf:"varint,2,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	BytesSent         int64 `protobuf:"varint,3,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	BytesRecv         int64 `protobuf:"varint,4,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
+	Panics            int64 `protobuf:"varint,5,opt,name=panics,proto3" json:"panics,omitempty"`
 }
 
-func (x *RoutingRule) Reset() {
-	*x = RoutingRule{}
+func (x *TrafficStats) Reset() {
+	*x = TrafficStats{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[6]
+		mi := &file_speedmimi_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *RoutingRule) String() string {
+func (x *TrafficStats) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RoutingRule) ProtoMessage() {}
+func (*TrafficStats) ProtoMessage() {}
 
-func (x *RoutingRule) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[6]
+func (x *TrafficStats) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -548,66 +523,69 @@ func (x *RoutingRule) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RoutingRule.ProtoReflect.Descriptor instead.
-func (*RoutingRule) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use TrafficStats.ProtoReflect.Descriptor instead.
+func (*TrafficStats) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *RoutingRule) GetPath() string {
+func (x *TrafficStats) GetTotalRequests() int64 {
 	if x != nil {
-		return x.Path
+		return x.TotalRequests
 	}
-	return ""
+	return 0
 }
 
-func (x *RoutingRule) GetUpstream() string {
+func (x *TrafficStats) GetActiveConnections() int64 {
 	if x != nil {
-		return x.Upstream
+		return x.ActiveConnections
 	}
-	return ""
+	return 0
 }
 
-func (x *RoutingRule) GetLoadBalancer() string {
+func (x *TrafficStats) GetBytesSent() int64 {
 	if x != nil {
-		return x.LoadBalancer
+		return x.BytesSent
 	}
-	return ""
+	return 0
 }
 
-func (x *RoutingRule) GetProtocols() map[string]string {
+func (x *TrafficStats) GetBytesRecv() int64 {
 	if x != nil {
-		return x.Protocols
+		return x.BytesRecv
 	}
-	return nil
+	return 0
+}
+
+func (x *TrafficStats) GetPanics() int64 {
+	if x != nil {
+		return x.Panics
+	}
+	return 0
 }
 
-type GRPCConfig struct {
+type GetConfigRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Enabled bool   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
-	Host    string `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
-	Port    int32  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
 }
 
-func (x *GRPCConfig) Reset() {
-	*x = GRPCConfig{}
+func (x *GetConfigRequest) Reset() {
+	*x = GetConfigRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[7]
+		mi := &file_speedmimi_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GRPCConfig) String() string {
+func (x *GetConfigRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GRPCConfig) ProtoMessage() {}
+func (*GetConfigRequest) ProtoMessage() {}
 
-func (x *GRPCConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[7]
+func (x *GetConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -618,65 +596,37 @@ func (x *GRPCConfig) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GRPCConfig.ProtoReflect.Descriptor instead.
-func (*GRPCConfig) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{7}
-}
-
-func (x *GRPCConfig) GetEnabled() bool {
-	if x != nil {
-		return x.Enabled
-	}
-	return false
-}
-
-func (x *GRPCConfig) GetHost() string {
-	if x != nil {
-		return x.Host
-	}
-	return ""
-}
-
-func (x *GRPCConfig) GetPort() int32 {
-	if x != nil {
-		return x.Port
-	}
-	return 0
+// Deprecated: Use GetConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetConfigRequest) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{4}
 }
 
-type PerformanceInfo struct {
+type GetConfigResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	CpuUsage    float64 `protobuf:"fixed64,1,opt,name=cpu_usage,json=cpuUsage,proto3" json:"cpu_usage,omitempty"`
-	MemoryUsage float64 `protobuf:"fixed64,2,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"`
-	DiskUsage   float64 `protobuf:"fixed64,3,opt,name=disk_usage,json=diskUsage,proto3" json:"disk_usage,omitempty"`
-	LoadAvg_1   float64 `protobuf:"fixed64,4,opt,name=load_avg_1,json=loadAvg1,proto3" json:"load_avg_1,omitempty"`
-	LoadAvg_5   float64 `protobuf:"fixed64,5,opt,name=load_avg_5,json=loadAvg5,proto3" json:"load_avg_5,omitempty"`
-	LoadAvg_15  float64 `protobuf:"fixed64,6,opt,name=load_avg_15,json=loadAvg15,proto3" json:"load_avg_15,omitempty"`
-	NetworkIn   float64 `protobuf:"fixed64,7,opt,name=network_in,json=networkIn,proto3" json:"network_in,omitempty"`
-	NetworkOut  float64 `protobuf:"fixed64,8,opt,name=network_out,json=networkOut,proto3" json:"network_out,omitempty"`
-	Timestamp   int64   `protobuf:"varint,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// 配置的JSON编码，与HTTP管理API返回的config相同（不含令牌等敏感字段）
+	ConfigJson []byte `protobuf:"bytes,1,opt,name=config_json,json=configJson,proto3" json:"config_json,omitempty"`
 }
 
-func (x *PerformanceInfo) Reset() {
-	*x = PerformanceInfo{}
+func (x *GetConfigResponse) Reset() {
+	*x = GetConfigResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[8]
+		mi := &file_speedmimi_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *PerformanceInfo) String() string {
+func (x *GetConfigResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PerformanceInfo) ProtoMessage() {}
+func (*GetConfigResponse) ProtoMessage() {}
 
-func (x *PerformanceInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[8]
+func (x *GetConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -687,88 +637,31 @@ func (x *PerformanceInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PerformanceInfo.ProtoReflect.Descriptor instead.
-func (*PerformanceInfo) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{8}
-}
-
-func (x *PerformanceInfo) GetCpuUsage() float64 {
-	if x != nil {
-		return x.CpuUsage
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetMemoryUsage() float64 {
-	if x != nil {
-		return x.MemoryUsage
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetDiskUsage() float64 {
-	if x != nil {
-		return x.DiskUsage
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetLoadAvg_1() float64 {
-	if x != nil {
-		return x.LoadAvg_1
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetLoadAvg_5() float64 {
-	if x != nil {
-		return x.LoadAvg_5
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetLoadAvg_15() float64 {
-	if x != nil {
-		return x.LoadAvg_15
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetNetworkIn() float64 {
-	if x != nil {
-		return x.NetworkIn
-	}
-	return 0
-}
-
-func (x *PerformanceInfo) GetNetworkOut() float64 {
-	if x != nil {
-		return x.NetworkOut
-	}
-	return 0
+// Deprecated: Use GetConfigResponse.ProtoReflect.Descriptor instead.
+func (*GetConfigResponse) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *PerformanceInfo) GetTimestamp() int64 {
+func (x *GetConfigResponse) GetConfigJson() []byte {
 	if x != nil {
-		return x.Timestamp
+		return x.ConfigJson
 	}
-	return 0
+	return nil
 }
 
-// 请求响应消息定义
-
 type UpdateConfigRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Config *Config `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	// 配置的JSON编码，与HTTP管理API的PUT /api/v1/config相同
+	ConfigJson []byte `protobuf:"bytes,1,opt,name=config_json,json=configJson,proto3" json:"config_json,omitempty"`
 }
 
 func (x *UpdateConfigRequest) Reset() {
 	*x = UpdateConfigRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[9]
+		mi := &file_speedmimi_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -781,7 +674,7 @@ func (x *UpdateConfigRequest) String() string {
 func (*UpdateConfigRequest) ProtoMessage() {}
 
 func (x *UpdateConfigRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[9]
+	mi := &file_speedmimi_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -794,12 +687,12 @@ func (x *UpdateConfigRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateConfigRequest.ProtoReflect.Descriptor instead.
 func (*UpdateConfigRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{9}
+	return file_speedmimi_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *UpdateConfigRequest) GetConfig() *Config {
+func (x *UpdateConfigRequest) GetConfigJson() []byte {
 	if x != nil {
-		return x.Config
+		return x.ConfigJson
 	}
 	return nil
 }
@@ -808,15 +701,12 @@ type UpdateConfigResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 }
 
 func (x *UpdateConfigResponse) Reset() {
 	*x = UpdateConfigResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[10]
+		mi := &file_speedmimi_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -829,7 +719,7 @@ func (x *UpdateConfigResponse) String() string {
 func (*UpdateConfigResponse) ProtoMessage() {}
 
 func (x *UpdateConfigResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[10]
+	mi := &file_speedmimi_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -842,48 +732,32 @@ func (x *UpdateConfigResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateConfigResponse.ProtoReflect.Descriptor instead.
 func (*UpdateConfigResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{10}
-}
-
-func (x *UpdateConfigResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *UpdateConfigResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+	return file_speedmimi_proto_rawDescGZIP(), []int{7}
 }
 
-type GetConfigResponse struct {
+type ReloadSSLRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Config *Config `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
 }
 
-func (x *GetConfigResponse) Reset() {
-	*x = GetConfigResponse{}
+func (x *ReloadSSLRequest) Reset() {
+	*x = ReloadSSLRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[11]
+		mi := &file_speedmimi_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GetConfigResponse) String() string {
+func (x *ReloadSSLRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetConfigResponse) ProtoMessage() {}
+func (*ReloadSSLRequest) ProtoMessage() {}
 
-func (x *GetConfigResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[11]
+func (x *ReloadSSLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -894,16 +768,9 @@ func (x *GetConfigResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetConfigResponse.ProtoReflect.Descriptor instead.
-func (*GetConfigResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{11}
-}
-
-func (x *GetConfigResponse) GetConfig() *Config {
-	if x != nil {
-		return x.Config
-	}
-	return nil
+// Deprecated: Use ReloadSSLRequest.ProtoReflect.Descriptor instead.
+func (*ReloadSSLRequest) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{8}
 }
 
 type ReloadSSLResponse struct {
@@ -911,14 +778,14 @@ type ReloadSSLResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// 重新加载后证书的过期时间（Unix秒）
+	NotAfter int64 `protobuf:"varint,1,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
 }
 
 func (x *ReloadSSLResponse) Reset() {
 	*x = ReloadSSLResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[12]
+		mi := &file_speedmimi_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -931,7 +798,7 @@ func (x *ReloadSSLResponse) String() string {
 func (*ReloadSSLResponse) ProtoMessage() {}
 
 func (x *ReloadSSLResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[12]
+	mi := &file_speedmimi_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -944,21 +811,14 @@ func (x *ReloadSSLResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReloadSSLResponse.ProtoReflect.Descriptor instead.
 func (*ReloadSSLResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{12}
-}
-
-func (x *ReloadSSLResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+	return file_speedmimi_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *ReloadSSLResponse) GetMessage() string {
+func (x *ReloadSSLResponse) GetNotAfter() int64 {
 	if x != nil {
-		return x.Message
+		return x.NotAfter
 	}
-	return ""
+	return 0
 }
 
 type GetBackendsRequest struct {
@@ -972,7 +832,7 @@ type GetBackendsRequest struct {
 func (x *GetBackendsRequest) Reset() {
 	*x = GetBackendsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[13]
+		mi := &file_speedmimi_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -985,7 +845,7 @@ func (x *GetBackendsRequest) String() string {
 func (*GetBackendsRequest) ProtoMessage() {}
 
 func (x *GetBackendsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[13]
+	mi := &file_speedmimi_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -998,7 +858,7 @@ func (x *GetBackendsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetBackendsRequest.ProtoReflect.Descriptor instead.
 func (*GetBackendsRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{13}
+	return file_speedmimi_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GetBackendsRequest) GetUpstream() string {
@@ -1019,7 +879,7 @@ type GetBackendsResponse struct {
 func (x *GetBackendsResponse) Reset() {
 	*x = GetBackendsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[14]
+		mi := &file_speedmimi_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1032,7 +892,7 @@ func (x *GetBackendsResponse) String() string {
 func (*GetBackendsResponse) ProtoMessage() {}
 
 func (x *GetBackendsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[14]
+	mi := &file_speedmimi_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1045,7 +905,7 @@ func (x *GetBackendsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetBackendsResponse.ProtoReflect.Descriptor instead.
 func (*GetBackendsResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{14}
+	return file_speedmimi_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GetBackendsResponse) GetBackends() []*Backend {
@@ -1060,14 +920,15 @@ type AddBackendRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upstream string   `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
-	Backend  *Backend `protobuf:"bytes,2,opt,name=backend,proto3" json:"backend,omitempty"`
+	Upstream string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	// 未指定id时按配置加载的规则生成
+	Backend *Backend `protobuf:"bytes,2,opt,name=backend,proto3" json:"backend,omitempty"`
 }
 
 func (x *AddBackendRequest) Reset() {
 	*x = AddBackendRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[15]
+		mi := &file_speedmimi_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1080,7 +941,7 @@ func (x *AddBackendRequest) String() string {
 func (*AddBackendRequest) ProtoMessage() {}
 
 func (x *AddBackendRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[15]
+	mi := &file_speedmimi_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1093,7 +954,7 @@ func (x *AddBackendRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddBackendRequest.ProtoReflect.Descriptor instead.
 func (*AddBackendRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{15}
+	return file_speedmimi_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *AddBackendRequest) GetUpstream() string {
@@ -1115,14 +976,13 @@ type AddBackendResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Backend *Backend `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
 }
 
 func (x *AddBackendResponse) Reset() {
 	*x = AddBackendResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[16]
+		mi := &file_speedmimi_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1135,7 +995,7 @@ func (x *AddBackendResponse) String() string {
 func (*AddBackendResponse) ProtoMessage() {}
 
 func (x *AddBackendResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[16]
+	mi := &file_speedmimi_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1148,21 +1008,14 @@ func (x *AddBackendResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddBackendResponse.ProtoReflect.Descriptor instead.
 func (*AddBackendResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{16}
-}
-
-func (x *AddBackendResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+	return file_speedmimi_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *AddBackendResponse) GetMessage() string {
+func (x *AddBackendResponse) GetBackend() *Backend {
 	if x != nil {
-		return x.Message
+		return x.Backend
 	}
-	return ""
+	return nil
 }
 
 type RemoveBackendRequest struct {
@@ -1170,14 +1023,14 @@ type RemoveBackendRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upstream   string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	Upstream  string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
 	BackendId string `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
 }
 
 func (x *RemoveBackendRequest) Reset() {
 	*x = RemoveBackendRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[17]
+		mi := &file_speedmimi_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1190,7 +1043,7 @@ func (x *RemoveBackendRequest) String() string {
 func (*RemoveBackendRequest) ProtoMessage() {}
 
 func (x *RemoveBackendRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[17]
+	mi := &file_speedmimi_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1203,7 +1056,7 @@ func (x *RemoveBackendRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoveBackendRequest.ProtoReflect.Descriptor instead.
 func (*RemoveBackendRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{17}
+	return file_speedmimi_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *RemoveBackendRequest) GetUpstream() string {
@@ -1224,15 +1077,12 @@ type RemoveBackendResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 }
 
 func (x *RemoveBackendResponse) Reset() {
 	*x = RemoveBackendResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[18]
+		mi := &file_speedmimi_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1245,7 +1095,7 @@ func (x *RemoveBackendResponse) String() string {
 func (*RemoveBackendResponse) ProtoMessage() {}
 
 func (x *RemoveBackendResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[18]
+	mi := &file_speedmimi_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1258,21 +1108,7 @@ func (x *RemoveBackendResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoveBackendResponse.ProtoReflect.Descriptor instead.
 func (*RemoveBackendResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{18}
-}
-
-func (x *RemoveBackendResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *RemoveBackendResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+	return file_speedmimi_proto_rawDescGZIP(), []int{15}
 }
 
 type UpdateBackendRequest struct {
@@ -1280,14 +1116,17 @@ type UpdateBackendRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upstream string   `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
-	Backend  *Backend `protobuf:"bytes,2,opt,name=backend,proto3" json:"backend,omitempty"`
+	Upstream  string   `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	BackendId string   `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	Backend   *Backend `protobuf:"bytes,3,opt,name=backend,proto3" json:"backend,omitempty"`
+	// 要更新的Backend字段（如weight、labels），id不能修改
+	UpdateMask *fieldmaskpb.FieldMask `protobuf:"bytes,4,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
 }
 
 func (x *UpdateBackendRequest) Reset() {
 	*x = UpdateBackendRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[19]
+		mi := &file_speedmimi_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1300,7 +1139,7 @@ func (x *UpdateBackendRequest) String() string {
 func (*UpdateBackendRequest) ProtoMessage() {}
 
 func (x *UpdateBackendRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[19]
+	mi := &file_speedmimi_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1313,7 +1152,7 @@ func (x *UpdateBackendRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateBackendRequest.ProtoReflect.Descriptor instead.
 func (*UpdateBackendRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{19}
+	return file_speedmimi_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *UpdateBackendRequest) GetUpstream() string {
@@ -1323,6 +1162,13 @@ func (x *UpdateBackendRequest) GetUpstream() string {
 	return ""
 }
 
+func (x *UpdateBackendRequest) GetBackendId() string {
+	if x != nil {
+		return x.BackendId
+	}
+	return ""
+}
+
 func (x *UpdateBackendRequest) GetBackend() *Backend {
 	if x != nil {
 		return x.Backend
@@ -1330,19 +1176,25 @@ func (x *UpdateBackendRequest) GetBackend() *Backend {
 	return nil
 }
 
+func (x *UpdateBackendRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
 type UpdateBackendResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Backend *Backend `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
 }
 
 func (x *UpdateBackendResponse) Reset() {
 	*x = UpdateBackendResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[20]
+		mi := &file_speedmimi_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1355,7 +1207,7 @@ func (x *UpdateBackendResponse) String() string {
 func (*UpdateBackendResponse) ProtoMessage() {}
 
 func (x *UpdateBackendResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[20]
+	mi := &file_speedmimi_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1368,21 +1220,14 @@ func (x *UpdateBackendResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateBackendResponse.ProtoReflect.Descriptor instead.
 func (*UpdateBackendResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{20}
-}
-
-func (x *UpdateBackendResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+	return file_speedmimi_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *UpdateBackendResponse) GetMessage() string {
+func (x *UpdateBackendResponse) GetBackend() *Backend {
 	if x != nil {
-		return x.Message
+		return x.Backend
 	}
-	return ""
+	return nil
 }
 
 type DisconnectBackendRequest struct {
@@ -1390,14 +1235,14 @@ type DisconnectBackendRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upstream   string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	Upstream  string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
 	BackendId string `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
 }
 
 func (x *DisconnectBackendRequest) Reset() {
 	*x = DisconnectBackendRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[21]
+		mi := &file_speedmimi_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1410,7 +1255,7 @@ func (x *DisconnectBackendRequest) String() string {
 func (*DisconnectBackendRequest) ProtoMessage() {}
 
 func (x *DisconnectBackendRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[21]
+	mi := &file_speedmimi_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1423,7 +1268,7 @@ func (x *DisconnectBackendRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DisconnectBackendRequest.ProtoReflect.Descriptor instead.
 func (*DisconnectBackendRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{21}
+	return file_speedmimi_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *DisconnectBackendRequest) GetUpstream() string {
@@ -1444,15 +1289,12 @@ type DisconnectBackendResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 }
 
 func (x *DisconnectBackendResponse) Reset() {
 	*x = DisconnectBackendResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[22]
+		mi := &file_speedmimi_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1465,7 +1307,7 @@ func (x *DisconnectBackendResponse) String() string {
 func (*DisconnectBackendResponse) ProtoMessage() {}
 
 func (x *DisconnectBackendResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[22]
+	mi := &file_speedmimi_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1478,21 +1320,45 @@ func (x *DisconnectBackendResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DisconnectBackendResponse.ProtoReflect.Descriptor instead.
 func (*DisconnectBackendResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{22}
+	return file_speedmimi_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *DisconnectBackendResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
+type GetServerStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetServerStatsRequest) Reset() {
+	*x = GetServerStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_speedmimi_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return false
 }
 
-func (x *DisconnectBackendResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
+func (x *GetServerStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerStatsRequest) ProtoMessage() {}
+
+func (x *GetServerStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_speedmimi_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetServerStatsRequest) Descriptor() ([]byte, []int) {
+	return file_speedmimi_proto_rawDescGZIP(), []int{20}
 }
 
 type GetServerStatsResponse struct {
@@ -1500,13 +1366,14 @@ type GetServerStatsResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Stats *PerformanceInfo `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	Stats   *PerformanceInfo `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	Traffic *TrafficStats    `protobuf:"bytes,2,opt,name=traffic,proto3" json:"traffic,omitempty"`
 }
 
 func (x *GetServerStatsResponse) Reset() {
 	*x = GetServerStatsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[23]
+		mi := &file_speedmimi_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1519,7 +1386,7 @@ func (x *GetServerStatsResponse) String() string {
 func (*GetServerStatsResponse) ProtoMessage() {}
 
 func (x *GetServerStatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[23]
+	mi := &file_speedmimi_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1532,7 +1399,7 @@ func (x *GetServerStatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetServerStatsResponse.ProtoReflect.Descriptor instead.
 func (*GetServerStatsResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{23}
+	return file_speedmimi_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *GetServerStatsResponse) GetStats() *PerformanceInfo {
@@ -1542,19 +1409,26 @@ func (x *GetServerStatsResponse) GetStats() *PerformanceInfo {
 	return nil
 }
 
+func (x *GetServerStatsResponse) GetTraffic() *TrafficStats {
+	if x != nil {
+		return x.Traffic
+	}
+	return nil
+}
+
 type GetBackendStatsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Upstream   string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	Upstream  string `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
 	BackendId string `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
 }
 
 func (x *GetBackendStatsRequest) Reset() {
 	*x = GetBackendStatsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[24]
+		mi := &file_speedmimi_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1567,7 +1441,7 @@ func (x *GetBackendStatsRequest) String() string {
 func (*GetBackendStatsRequest) ProtoMessage() {}
 
 func (x *GetBackendStatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[24]
+	mi := &file_speedmimi_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1580,7 +1454,7 @@ func (x *GetBackendStatsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetBackendStatsRequest.ProtoReflect.Descriptor instead.
 func (*GetBackendStatsRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{24}
+	return file_speedmimi_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *GetBackendStatsRequest) GetUpstream() string {
@@ -1602,13 +1476,17 @@ type GetBackendStatsResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Stats *PerformanceInfo `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	// 后端未上报时为空
+	Stats       *PerformanceInfo `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	Connections int64            `protobuf:"varint,2,opt,name=connections,proto3" json:"connections,omitempty"`
+	// 请求延迟的移动平均（毫秒）
+	LatencyMs float64 `protobuf:"fixed64,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
 }
 
 func (x *GetBackendStatsResponse) Reset() {
 	*x = GetBackendStatsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[25]
+		mi := &file_speedmimi_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1621,7 +1499,7 @@ func (x *GetBackendStatsResponse) String() string {
 func (*GetBackendStatsResponse) ProtoMessage() {}
 
 func (x *GetBackendStatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[25]
+	mi := &file_speedmimi_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1634,7 +1512,7 @@ func (x *GetBackendStatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetBackendStatsResponse.ProtoReflect.Descriptor instead.
 func (*GetBackendStatsResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{25}
+	return file_speedmimi_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *GetBackendStatsResponse) GetStats() *PerformanceInfo {
@@ -1644,20 +1522,34 @@ func (x *GetBackendStatsResponse) GetStats() *PerformanceInfo {
 	return nil
 }
 
+func (x *GetBackendStatsResponse) GetConnections() int64 {
+	if x != nil {
+		return x.Connections
+	}
+	return 0
+}
+
+func (x *GetBackendStatsResponse) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
 type ReportPerformanceRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Upstream    string           `protobuf:"bytes,1,opt,name=upstream,proto3" json:"upstream,omitempty"`
-	BackendId  string           `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	BackendId   string           `protobuf:"bytes,2,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
 	Performance *PerformanceInfo `protobuf:"bytes,3,opt,name=performance,proto3" json:"performance,omitempty"`
 }
 
 func (x *ReportPerformanceRequest) Reset() {
 	*x = ReportPerformanceRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[26]
+		mi := &file_speedmimi_proto_msgTypes[24]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1670,7 +1562,7 @@ func (x *ReportPerformanceRequest) String() string {
 func (*ReportPerformanceRequest) ProtoMessage() {}
 
 func (x *ReportPerformanceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[26]
+	mi := &file_speedmimi_proto_msgTypes[24]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1683,7 +1575,7 @@ func (x *ReportPerformanceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportPerformanceRequest.ProtoReflect.Descriptor instead.
 func (*ReportPerformanceRequest) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{26}
+	return file_speedmimi_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *ReportPerformanceRequest) GetUpstream() string {
@@ -1711,15 +1603,12 @@ type ReportPerformanceResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 }
 
 func (x *ReportPerformanceResponse) Reset() {
 	*x = ReportPerformanceResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_speedmimi_proto_msgTypes[27]
+		mi := &file_speedmimi_proto_msgTypes[25]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1732,7 +1621,7 @@ func (x *ReportPerformanceResponse) String() string {
 func (*ReportPerformanceResponse) ProtoMessage() {}
 
 func (x *ReportPerformanceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_speedmimi_proto_msgTypes[27]
+	mi := &file_speedmimi_proto_msgTypes[25]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1745,120 +1634,674 @@ func (x *ReportPerformanceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportPerformanceResponse.ProtoReflect.Descriptor instead.
 func (*ReportPerformanceResponse) Descriptor() ([]byte, []int) {
-	return file_speedmimi_proto_rawDescGZIP(), []int{27}
-}
-
-func (x *ReportPerformanceResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+	return file_speedmimi_proto_rawDescGZIP(), []int{25}
 }
 
-func (x *ReportPerformanceResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
-}
+var File_speedmimi_proto protoreflect.FileDescriptor
 
-// 其他必要的protobuf实现代码
 var file_speedmimi_proto_rawDesc = []byte{
-	// 省略具体的raw descriptor bytes，实际使用时需要包含完整的
-}
-
-var file_speedmimi_proto_rawDescGZIP = []byte{
-	// 省略具体的gzip descriptor bytes，实际使用时需要包含完整的
-}
-
-var file_speedmimi_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
-
-var file_speedmimi_proto_goTypes = []any{
-	(*Config)(nil),                   // 0
-	(*ServerConfig)(nil),             // 1
-	(*SSLConfig)(nil),                // 2
-	(*BackendList)(nil),              // 3
-	(*Backend)(nil),                  // 4
-	(*HealthCheck)(nil),              // 5
-	(*RoutingRule)(nil),              // 6
-	(*GRPCConfig)(nil),               // 7
-	(*PerformanceInfo)(nil),          // 8
-	(*UpdateConfigRequest)(nil),      // 9
-	(*UpdateConfigResponse)(nil),     // 10
-	(*GetConfigResponse)(nil),        // 11
-	(*ReloadSSLResponse)(nil),        // 12
-	(*GetBackendsRequest)(nil),       // 13
-	(*GetBackendsResponse)(nil),      // 14
-	(*AddBackendRequest)(nil),        // 15
-	(*AddBackendResponse)(nil),       // 16
-	(*RemoveBackendRequest)(nil),     // 17
-	(*RemoveBackendResponse)(nil),    // 18
-	(*UpdateBackendRequest)(nil),     // 19
-	(*UpdateBackendResponse)(nil),    // 20
-	(*DisconnectBackendRequest)(nil), // 21
-	(*DisconnectBackendResponse)(nil), // 22
-	(*GetServerStatsResponse)(nil),   // 23
-	(*GetBackendStatsRequest)(nil),   // 24
-	(*GetBackendStatsResponse)(nil),  // 25
-	(*ReportPerformanceRequest)(nil), // 26
-	(*ReportPerformanceResponse)(nil), // 27
-}
+	0x0a, 0x0f, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x0c, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x1a,
+	0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x99, 0x04, 0x0a, 0x07, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76,
+	0x65, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e, 0x6e, 0x12, 0x1a, 0x0a, 0x08,
+	0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08,
+	0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x7a, 0x6f, 0x6e, 0x65,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x7a, 0x6f, 0x6e, 0x65, 0x12, 0x39, 0x0a, 0x06,
+	0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73,
+	0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b,
+	0x65, 0x6e, 0x64, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x3c, 0x0a, 0x0c, 0x68, 0x65, 0x61, 0x6c, 0x74,
+	0x68, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x65, 0x61,
+	0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x0b, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74,
+	0x68, 0x79, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68,
+	0x79, 0x12, 0x3f, 0x0a, 0x0b, 0x70, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65,
+	0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69,
+	0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0b, 0x70, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e,
+	0x63, 0x65, 0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xe3, 0x02,
+	0x0a, 0x0b, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x21, 0x0a, 0x0c, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64,
+	0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x62, 0x6f, 0x64, 0x79, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61,
+	0x69, 0x6e, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x62, 0x6f, 0x64, 0x79, 0x43,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x6f, 0x64, 0x79, 0x5f,
+	0x72, 0x65, 0x67, 0x65, 0x78, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x62, 0x6f, 0x64,
+	0x79, 0x52, 0x65, 0x67, 0x65, 0x78, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x6f, 0x64, 0x79, 0x5f, 0x6e,
+	0x6f, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x62, 0x6f, 0x64, 0x79, 0x4e, 0x6f,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x18, 0x0a,
+	0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x61, 0x69, 0x6c, 0x75,
+	0x72, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x66, 0x61, 0x69, 0x6c, 0x75,
+	0x72, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x69, 0x73, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x04, 0x72, 0x69, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x61, 0x6c, 0x6c, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x66, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x6a,
+	0x69, 0x74, 0x74, 0x65, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6a, 0x69, 0x74,
+	0x74, 0x65, 0x72, 0x22, 0xab, 0x03, 0x0a, 0x0f, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x70, 0x75, 0x5f, 0x75,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x63, 0x70, 0x75, 0x55,
+	0x73, 0x61, 0x67, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x5f, 0x75,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x6d, 0x65, 0x6d, 0x6f,
+	0x72, 0x79, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x64, 0x69, 0x73, 0x6b, 0x5f,
+	0x75, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x64, 0x69, 0x73,
+	0x6b, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a, 0x0a, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x61,
+	0x76, 0x67, 0x5f, 0x31, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64,
+	0x41, 0x76, 0x67, 0x31, 0x12, 0x1c, 0x0a, 0x0a, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x61, 0x76, 0x67,
+	0x5f, 0x35, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x76,
+	0x67, 0x35, 0x12, 0x1e, 0x0a, 0x0b, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x61, 0x76, 0x67, 0x5f, 0x31,
+	0x35, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x76, 0x67,
+	0x31, 0x35, 0x12, 0x1d, 0x0a, 0x0a, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x69, 0x6e,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x49,
+	0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x6f, 0x75, 0x74,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x4f,
+	0x75, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x41,
+	0x74, 0x12, 0x22, 0x0a, 0x0d, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x73, 0x6b, 0x65, 0x77, 0x5f,
+	0x6d, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x6b, 0x65, 0x77, 0x4d, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x10, 0x0a,
+	0x03, 0x72, 0x70, 0x73, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x72, 0x70, 0x73, 0x12,
+	0x10, 0x0a, 0x03, 0x65, 0x70, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x65, 0x70,
+	0x73, 0x22, 0xba, 0x01, 0x0a, 0x0c, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x72, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x12, 0x61, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x43, 0x6f, 0x6e,
+	0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x79, 0x74, 0x65,
+	0x73, 0x5f, 0x73, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x53, 0x65, 0x6e, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x79, 0x74, 0x65, 0x73,
+	0x5f, 0x72, 0x65, 0x63, 0x76, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x52, 0x65, 0x63, 0x76, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x6e, 0x69, 0x63, 0x73,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x70, 0x61, 0x6e, 0x69, 0x63, 0x73, 0x22, 0x12,
+	0x0a, 0x10, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0x34, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x4a, 0x73, 0x6f, 0x6e, 0x22, 0x36, 0x0a, 0x13, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x4a, 0x73, 0x6f, 0x6e,
+	0x22, 0x16, 0x0a, 0x14, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x12, 0x0a, 0x10, 0x52, 0x65, 0x6c, 0x6f,
+	0x61, 0x64, 0x53, 0x53, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x30, 0x0a, 0x11,
+	0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x53, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x6f, 0x74, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6e, 0x6f, 0x74, 0x41, 0x66, 0x74, 0x65, 0x72, 0x22, 0x30,
+	0x0a, 0x12, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x22, 0x48, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x08, 0x62, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x70, 0x65, 0x65,
+	0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x52, 0x08, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x22, 0x60, 0x0a, 0x11, 0x41, 0x64,
+	0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x2f, 0x0a, 0x07, 0x62,
+	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73,
+	0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b,
+	0x65, 0x6e, 0x64, 0x52, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x22, 0x45, 0x0a, 0x12,
+	0x41, 0x64, 0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x2f, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e,
+	0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x07, 0x62, 0x61, 0x63, 0x6b,
+	0x65, 0x6e, 0x64, 0x22, 0x51, 0x0a, 0x14, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x42, 0x61, 0x63,
+	0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75,
+	0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75,
+	0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x62, 0x61, 0x63,
+	0x6b, 0x65, 0x6e, 0x64, 0x49, 0x64, 0x22, 0x17, 0x0a, 0x15, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65,
+	0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0xbf, 0x01, 0x0a, 0x14, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e,
+	0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x5f,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e,
+	0x64, 0x49, 0x64, 0x12, 0x2f, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69,
+	0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x07, 0x62, 0x61, 0x63,
+	0x6b, 0x65, 0x6e, 0x64, 0x12, 0x3b, 0x0a, 0x0b, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x6d,
+	0x61, 0x73, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c,
+	0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x0a, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4d, 0x61, 0x73,
+	0x6b, 0x22, 0x48, 0x0a, 0x15, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x07, 0x62, 0x61,
+	0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x70,
+	0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x52, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x22, 0x55, 0x0a, 0x18, 0x44,
+	0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x5f, 0x69,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x49, 0x64, 0x22, 0x1b, 0x0a, 0x19, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x17, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x83, 0x01, 0x0a, 0x16, 0x47, 0x65, 0x74,
+	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x33, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76,
+	0x31, 0x2e, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x12, 0x34, 0x0a, 0x07, 0x74, 0x72, 0x61, 0x66,
+	0x66, 0x69, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x73, 0x70, 0x65, 0x65,
+	0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x07, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x22, 0x53,
+	0x0a, 0x16, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x5f,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e,
+	0x64, 0x49, 0x64, 0x22, 0x8f, 0x01, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x33, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d,
+	0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x65,
+	0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63,
+	0x79, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x61, 0x74, 0x65,
+	0x6e, 0x63, 0x79, 0x4d, 0x73, 0x22, 0x96, 0x01, 0x0a, 0x18, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1d,
+	0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x49, 0x64, 0x12, 0x3f, 0x0a,
+	0x0b, 0x70, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76,
+	0x31, 0x2e, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x0b, 0x70, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x1b,
+	0x0a, 0x19, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x82, 0x02, 0x0a, 0x0d,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4c, 0x0a,
+	0x09, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1e, 0x2e, 0x73, 0x70, 0x65,
+	0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x73, 0x70, 0x65,
+	0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x55, 0x0a, 0x0c, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x21, 0x2e, 0x73, 0x70,
+	0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22,
+	0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x4c, 0x0a, 0x09, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x53, 0x4c, 0x12,
+	0x1e, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x53, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1f, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x53, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x32, 0xcf, 0x03, 0x0a, 0x0e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x52, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e,
+	0x64, 0x73, 0x12, 0x20, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69,
+	0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4f, 0x0a, 0x0a, 0x41, 0x64, 0x64, 0x42, 0x61,
+	0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x1f, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d,
+	0x69, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69,
+	0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x58, 0x0a, 0x0d, 0x52, 0x65, 0x6d, 0x6f,
+	0x76, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x22, 0x2e, 0x73, 0x70, 0x65, 0x65,
+	0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x42,
+	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e,
+	0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x6d,
+	0x6f, 0x76, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x58, 0x0a, 0x0d, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b,
+	0x65, 0x6e, 0x64, 0x12, 0x22, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e,
+	0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d,
+	0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x42, 0x61, 0x63,
+	0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x64, 0x0a, 0x11,
+	0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e,
+	0x64, 0x12, 0x26, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31,
+	0x2e, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73, 0x70, 0x65, 0x65,
+	0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x32, 0xb3, 0x02, 0x0a, 0x0e, 0x4d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x5b, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x23, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d,
+	0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x73,
+	0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x5e, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x24, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d,
+	0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x53,
+	0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x73, 0x70,
+	0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x61,
+	0x63, 0x6b, 0x65, 0x6e, 0x64, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x64, 0x0a, 0x11, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x50, 0x65, 0x72, 0x66,
+	0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x26, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d,
+	0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x50, 0x65, 0x72,
+	0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x27, 0x2e, 0x73, 0x70, 0x65, 0x65, 0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x50, 0x65, 0x72, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x71, 0x75, 0x71, 0x69, 0x2f, 0x73, 0x70, 0x65, 0x65,
+	0x64, 0x6d, 0x69, 0x6d, 0x69, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_speedmimi_proto_rawDescOnce sync.Once
+	file_speedmimi_proto_rawDescData = file_speedmimi_proto_rawDesc
+)
 
+func file_speedmimi_proto_rawDescGZIP() []byte {
+	file_speedmimi_proto_rawDescOnce.Do(func() {
+		file_speedmimi_proto_rawDescData = protoimpl.X.CompressGZIP(file_speedmimi_proto_rawDescData)
+	})
+	return file_speedmimi_proto_rawDescData
+}
+
+var file_speedmimi_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_speedmimi_proto_goTypes = []interface{}{
+	(*Backend)(nil),                   // 0: speedmimi.v1.Backend
+	(*HealthCheck)(nil),               // 1: speedmimi.v1.HealthCheck
+	(*PerformanceInfo)(nil),           // 2: speedmimi.v1.PerformanceInfo
+	(*TrafficStats)(nil),              // 3: speedmimi.v1.TrafficStats
+	(*GetConfigRequest)(nil),          // 4: speedmimi.v1.GetConfigRequest
+	(*GetConfigResponse)(nil),         // 5: speedmimi.v1.GetConfigResponse
+	(*UpdateConfigRequest)(nil),       // 6: speedmimi.v1.UpdateConfigRequest
+	(*UpdateConfigResponse)(nil),      // 7: speedmimi.v1.UpdateConfigResponse
+	(*ReloadSSLRequest)(nil),          // 8: speedmimi.v1.ReloadSSLRequest
+	(*ReloadSSLResponse)(nil),         // 9: speedmimi.v1.ReloadSSLResponse
+	(*GetBackendsRequest)(nil),        // 10: speedmimi.v1.GetBackendsRequest
+	(*GetBackendsResponse)(nil),       // 11: speedmimi.v1.GetBackendsResponse
+	(*AddBackendRequest)(nil),         // 12: speedmimi.v1.AddBackendRequest
+	(*AddBackendResponse)(nil),        // 13: speedmimi.v1.AddBackendResponse
+	(*RemoveBackendRequest)(nil),      // 14: speedmimi.v1.RemoveBackendRequest
+	(*RemoveBackendResponse)(nil),     // 15: speedmimi.v1.RemoveBackendResponse
+	(*UpdateBackendRequest)(nil),      // 16: speedmimi.v1.UpdateBackendRequest
+	(*UpdateBackendResponse)(nil),     // 17: speedmimi.v1.UpdateBackendResponse
+	(*DisconnectBackendRequest)(nil),  // 18: speedmimi.v1.DisconnectBackendRequest
+	(*DisconnectBackendResponse)(nil), // 19: speedmimi.v1.DisconnectBackendResponse
+	(*GetServerStatsRequest)(nil),     // 20: speedmimi.v1.GetServerStatsRequest
+	(*GetServerStatsResponse)(nil),    // 21: speedmimi.v1.GetServerStatsResponse
+	(*GetBackendStatsRequest)(nil),    // 22: speedmimi.v1.GetBackendStatsRequest
+	(*GetBackendStatsResponse)(nil),   // 23: speedmimi.v1.GetBackendStatsResponse
+	(*ReportPerformanceRequest)(nil),  // 24: speedmimi.v1.ReportPerformanceRequest
+	(*ReportPerformanceResponse)(nil), // 25: speedmimi.v1.ReportPerformanceResponse
+	nil,                               // 26: speedmimi.v1.Backend.LabelsEntry
+	(*fieldmaskpb.FieldMask)(nil),     // 27: google.protobuf.FieldMask
+}
 var file_speedmimi_proto_depIdxs = []int32{
-	1,  // 0: speedmimi.Config.server:type_name -> speedmimi.ServerConfig
-	2,  // 1: speedmimi.Config.ssl:type_name -> speedmimi.SSLConfig
-	3,  // 2: speedmimi.Config.backends:type_name -> speedmimi.BackendList
-	6,  // 3: speedmimi.Config.routing:type_name -> speedmimi.RoutingRule
-	7,  // 4: speedmimi.Config.grpc:type_name -> speedmimi.GRPCConfig
-	4,  // 5: speedmimi.BackendList.backends:type_name -> speedmimi.Backend
-	5,  // 6: speedmimi.Backend.health_check:type_name -> speedmimi.HealthCheck
-	8,  // 7: speedmimi.Backend.performance:type_name -> speedmimi.PerformanceInfo
-	0,  // 8: speedmimi.UpdateConfigRequest.config:type_name -> speedmimi.Config
-	0,  // 9: speedmimi.GetConfigResponse.config:type_name -> speedmimi.Config
-	4,  // 10: speedmimi.GetBackendsResponse.backends:type_name -> speedmimi.Backend
-	4,  // 11: speedmimi.AddBackendRequest.backend:type_name -> speedmimi.Backend
-	4,  // 12: speedmimi.UpdateBackendRequest.backend:type_name -> speedmimi.Backend
-	8,  // 13: speedmimi.GetServerStatsResponse.stats:type_name -> speedmimi.PerformanceInfo
-	8,  // 14: speedmimi.GetBackendStatsResponse.stats:type_name -> speedmimi.PerformanceInfo
-	8,  // 15: speedmimi.ReportPerformanceRequest.performance:type_name -> speedmimi.PerformanceInfo
-	9,  // 16: speedmimi.ConfigService.UpdateConfig:input_type -> speedmimi.UpdateConfigRequest
-	16, // 17: speedmimi.ConfigService.GetConfig:input_type -> google.protobuf.Empty
-	16, // 18: speedmimi.ConfigService.ReloadSSL:input_type -> google.protobuf.Empty
-	10, // 19: speedmimi.ConfigService.UpdateConfig:output_type -> speedmimi.UpdateConfigResponse
-	11, // 20: speedmimi.ConfigService.GetConfig:output_type -> speedmimi.GetConfigResponse
-	12, // 21: speedmimi.ConfigService.ReloadSSL:output_type -> speedmimi.ReloadSSLResponse
-	13, // 22: speedmimi.BackendService.GetBackends:input_type -> speedmimi.GetBackendsRequest
-	15, // 23: speedmimi.BackendService.AddBackend:input_type -> speedmimi.AddBackendRequest
-	17, // 24: speedmimi.BackendService.RemoveBackend:input_type -> speedmimi.RemoveBackendRequest
-	19, // 25: speedmimi.BackendService.UpdateBackend:input_type -> speedmimi.UpdateBackendRequest
-	21, // 26: speedmimi.BackendService.DisconnectBackend:input_type -> speedmimi.DisconnectBackendRequest
-	14, // 27: speedmimi.BackendService.GetBackends:output_type -> speedmimi.GetBackendsResponse
-	18, // 28: speedmimi.BackendService.AddBackend:output_type -> speedmimi.AddBackendResponse
-	20, // 29: speedmimi.BackendService.RemoveBackend:output_type -> speedmimi.RemoveBackendResponse
-	22, // 30: speedmimi.BackendService.UpdateBackend:output_type -> speedmimi.UpdateBackendResponse
-	24, // 31: speedmimi.BackendService.DisconnectBackend:output_type -> speedmimi.DisconnectBackendResponse
-	16, // 32: speedmimi.MonitorService.GetServerStats:input_type -> google.protobuf.Empty
-	25, // 33: speedmimi.MonitorService.GetBackendStats:input_type -> speedmimi.GetBackendStatsRequest
-	26, // 34: speedmimi.MonitorService.ReportPerformance:input_type -> speedmimi.ReportPerformanceRequest
-	23, // 35: speedmimi.MonitorService.GetServerStats:output_type -> speedmimi.GetServerStatsResponse
-	27, // 36: speedmimi.MonitorService.GetBackendStats:output_type -> speedmimi.GetBackendStatsResponse
-	28, // 37: speedmimi.MonitorService.ReportPerformance:output_type -> speedmimi.ReportPerformanceResponse
-	19, // [19:38] is the sub-list for method output_type
-	16, // [16:19] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	26, // 0: speedmimi.v1.Backend.labels:type_name -> speedmimi.v1.Backend.LabelsEntry
+	1,  // 1: speedmimi.v1.Backend.health_check:type_name -> speedmimi.v1.HealthCheck
+	2,  // 2: speedmimi.v1.Backend.performance:type_name -> speedmimi.v1.PerformanceInfo
+	0,  // 3: speedmimi.v1.GetBackendsResponse.backends:type_name -> speedmimi.v1.Backend
+	0,  // 4: speedmimi.v1.AddBackendRequest.backend:type_name -> speedmimi.v1.Backend
+	0,  // 5: speedmimi.v1.AddBackendResponse.backend:type_name -> speedmimi.v1.Backend
+	0,  // 6: speedmimi.v1.UpdateBackendRequest.backend:type_name -> speedmimi.v1.Backend
+	27, // 7: speedmimi.v1.UpdateBackendRequest.update_mask:type_name -> google.protobuf.FieldMask
+	0,  // 8: speedmimi.v1.UpdateBackendResponse.backend:type_name -> speedmimi.v1.Backend
+	2,  // 9: speedmimi.v1.GetServerStatsResponse.stats:type_name -> speedmimi.v1.PerformanceInfo
+	3,  // 10: speedmimi.v1.GetServerStatsResponse.traffic:type_name -> speedmimi.v1.TrafficStats
+	2,  // 11: speedmimi.v1.GetBackendStatsResponse.stats:type_name -> speedmimi.v1.PerformanceInfo
+	2,  // 12: speedmimi.v1.ReportPerformanceRequest.performance:type_name -> speedmimi.v1.PerformanceInfo
+	4,  // 13: speedmimi.v1.ConfigService.GetConfig:input_type -> speedmimi.v1.GetConfigRequest
+	6,  // 14: speedmimi.v1.ConfigService.UpdateConfig:input_type -> speedmimi.v1.UpdateConfigRequest
+	8,  // 15: speedmimi.v1.ConfigService.ReloadSSL:input_type -> speedmimi.v1.ReloadSSLRequest
+	10, // 16: speedmimi.v1.BackendService.GetBackends:input_type -> speedmimi.v1.GetBackendsRequest
+	12, // 17: speedmimi.v1.BackendService.AddBackend:input_type -> speedmimi.v1.AddBackendRequest
+	14, // 18: speedmimi.v1.BackendService.RemoveBackend:input_type -> speedmimi.v1.RemoveBackendRequest
+	16, // 19: speedmimi.v1.BackendService.UpdateBackend:input_type -> speedmimi.v1.UpdateBackendRequest
+	18, // 20: speedmimi.v1.BackendService.DisconnectBackend:input_type -> speedmimi.v1.DisconnectBackendRequest
+	20, // 21: speedmimi.v1.MonitorService.GetServerStats:input_type -> speedmimi.v1.GetServerStatsRequest
+	22, // 22: speedmimi.v1.MonitorService.GetBackendStats:input_type -> speedmimi.v1.GetBackendStatsRequest
+	24, // 23: speedmimi.v1.MonitorService.ReportPerformance:input_type -> speedmimi.v1.ReportPerformanceRequest
+	5,  // 24: speedmimi.v1.ConfigService.GetConfig:output_type -> speedmimi.v1.GetConfigResponse
+	7,  // 25: speedmimi.v1.ConfigService.UpdateConfig:output_type -> speedmimi.v1.UpdateConfigResponse
+	9,  // 26: speedmimi.v1.ConfigService.ReloadSSL:output_type -> speedmimi.v1.ReloadSSLResponse
+	11, // 27: speedmimi.v1.BackendService.GetBackends:output_type -> speedmimi.v1.GetBackendsResponse
+	13, // 28: speedmimi.v1.BackendService.AddBackend:output_type -> speedmimi.v1.AddBackendResponse
+	15, // 29: speedmimi.v1.BackendService.RemoveBackend:output_type -> speedmimi.v1.RemoveBackendResponse
+	17, // 30: speedmimi.v1.BackendService.UpdateBackend:output_type -> speedmimi.v1.UpdateBackendResponse
+	19, // 31: speedmimi.v1.BackendService.DisconnectBackend:output_type -> speedmimi.v1.DisconnectBackendResponse
+	21, // 32: speedmimi.v1.MonitorService.GetServerStats:output_type -> speedmimi.v1.GetServerStatsResponse
+	23, // 33: speedmimi.v1.MonitorService.GetBackendStats:output_type -> speedmimi.v1.GetBackendStatsResponse
+	25, // 34: speedmimi.v1.MonitorService.ReportPerformance:output_type -> speedmimi.v1.ReportPerformanceResponse
+	24, // [24:35] is the sub-list for method output_type
+	13, // [13:24] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_speedmimi_proto_init() }
-
 func file_speedmimi_proto_init() {
 	if File_speedmimi_proto != nil {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
-		file_speedmimi_proto_msgTypes[0].Exporter = func(v any, i int) any {
-			switch v := v.(*Config); i {
+		file_speedmimi_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Backend); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HealthCheck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PerformanceInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrafficStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetConfigRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetConfigResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateConfigRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateConfigResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadSSLRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadSSLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBackendsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBackendsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddBackendRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddBackendResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RemoveBackendRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RemoveBackendResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateBackendRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateBackendResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DisconnectBackendRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DisconnectBackendResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetServerStatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetServerStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBackendStatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBackendStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReportPerformanceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_speedmimi_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReportPerformanceResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1869,7 +2312,6 @@ func file_speedmimi_proto_init() {
 				return nil
 			}
 		}
-		// 省略其他消息类型的初始化代码
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1877,7 +2319,7 @@ func file_speedmimi_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_speedmimi_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   28,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   3,
 		},
@@ -1889,4 +2331,4 @@ func file_speedmimi_proto_init() {
 	file_speedmimi_proto_rawDesc = nil
 	file_speedmimi_proto_goTypes = nil
 	file_speedmimi_proto_depIdxs = nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,610 @@
+// SpeedMimi 管理API的gRPC定义
+// 与HTTP管理API共用端口、认证和并发限制，修改后运行 go generate ./api/proto 重新生成 speedmimi.pb.go 和 speedmimi_grpc.pb.go（需要protoc、protoc-gen-go和protoc-gen-go-grpc）
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: speedmimi.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ConfigService_GetConfig_FullMethodName    = "/speedmimi.v1.ConfigService/GetConfig"
+	ConfigService_UpdateConfig_FullMethodName = "/speedmimi.v1.ConfigService/UpdateConfig"
+	ConfigService_ReloadSSL_FullMethodName    = "/speedmimi.v1.ConfigService/ReloadSSL"
+)
+
+// ConfigServiceClient is the client API for ConfigService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConfigServiceClient interface {
+	// GetConfig 获取当前配置
+	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error)
+	// UpdateConfig 校验并替换整个配置，写入配置文件
+	UpdateConfig(ctx context.Context, in *UpdateConfigRequest, opts ...grpc.CallOption) (*UpdateConfigResponse, error)
+	// ReloadSSL 重新加载TLS证书
+	ReloadSSL(ctx context.Context, in *ReloadSSLRequest, opts ...grpc.CallOption) (*ReloadSSLResponse, error)
+}
+
+type configServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConfigServiceClient(cc grpc.ClientConnInterface) ConfigServiceClient {
+	return &configServiceClient{cc}
+}
+
+func (c *configServiceClient) GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error) {
+	out := new(GetConfigResponse)
+	err := c.cc.Invoke(ctx, ConfigService_GetConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) UpdateConfig(ctx context.Context, in *UpdateConfigRequest, opts ...grpc.CallOption) (*UpdateConfigResponse, error) {
+	out := new(UpdateConfigResponse)
+	err := c.cc.Invoke(ctx, ConfigService_UpdateConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ReloadSSL(ctx context.Context, in *ReloadSSLRequest, opts ...grpc.CallOption) (*ReloadSSLResponse, error) {
+	out := new(ReloadSSLResponse)
+	err := c.cc.Invoke(ctx, ConfigService_ReloadSSL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConfigServiceServer is the server API for ConfigService service.
+// All implementations must embed UnimplementedConfigServiceServer
+// for forward compatibility
+type ConfigServiceServer interface {
+	// GetConfig 获取当前配置
+	GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error)
+	// UpdateConfig 校验并替换整个配置，写入配置文件
+	UpdateConfig(context.Context, *UpdateConfigRequest) (*UpdateConfigResponse, error)
+	// ReloadSSL 重新加载TLS证书
+	ReloadSSL(context.Context, *ReloadSSLRequest) (*ReloadSSLResponse, error)
+	mustEmbedUnimplementedConfigServiceServer()
+}
+
+// UnimplementedConfigServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedConfigServiceServer struct {
+}
+
+func (UnimplementedConfigServiceServer) GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) UpdateConfig(context.Context, *UpdateConfigRequest) (*UpdateConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) ReloadSSL(context.Context, *ReloadSSLRequest) (*ReloadSSLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadSSL not implemented")
+}
+func (UnimplementedConfigServiceServer) mustEmbedUnimplementedConfigServiceServer() {}
+
+// UnsafeConfigServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConfigServiceServer will
+// result in compilation errors.
+type UnsafeConfigServiceServer interface {
+	mustEmbedUnimplementedConfigServiceServer()
+}
+
+func RegisterConfigServiceServer(s grpc.ServiceRegistrar, srv ConfigServiceServer) {
+	s.RegisterService(&ConfigService_ServiceDesc, srv)
+}
+
+func _ConfigService_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).GetConfig(ctx, req.(*GetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_UpdateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).UpdateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_UpdateConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).UpdateConfig(ctx, req.(*UpdateConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ReloadSSL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadSSLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ReloadSSL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_ReloadSSL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ReloadSSL(ctx, req.(*ReloadSSLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ConfigService_ServiceDesc is the grpc.ServiceDesc for ConfigService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConfigService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "speedmimi.v1.ConfigService",
+	HandlerType: (*ConfigServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetConfig",
+			Handler:    _ConfigService_GetConfig_Handler,
+		},
+		{
+			MethodName: "UpdateConfig",
+			Handler:    _ConfigService_UpdateConfig_Handler,
+		},
+		{
+			MethodName: "ReloadSSL",
+			Handler:    _ConfigService_ReloadSSL_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "speedmimi.proto",
+}
+
+const (
+	BackendService_GetBackends_FullMethodName       = "/speedmimi.v1.BackendService/GetBackends"
+	BackendService_AddBackend_FullMethodName        = "/speedmimi.v1.BackendService/AddBackend"
+	BackendService_RemoveBackend_FullMethodName     = "/speedmimi.v1.BackendService/RemoveBackend"
+	BackendService_UpdateBackend_FullMethodName     = "/speedmimi.v1.BackendService/UpdateBackend"
+	BackendService_DisconnectBackend_FullMethodName = "/speedmimi.v1.BackendService/DisconnectBackend"
+)
+
+// BackendServiceClient is the client API for BackendService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BackendServiceClient interface {
+	// GetBackends 获取上游的活跃后端
+	GetBackends(ctx context.Context, in *GetBackendsRequest, opts ...grpc.CallOption) (*GetBackendsResponse, error)
+	// AddBackend 向上游添加后端
+	AddBackend(ctx context.Context, in *AddBackendRequest, opts ...grpc.CallOption) (*AddBackendResponse, error)
+	// RemoveBackend 从上游移除后端（暂未实现）
+	RemoveBackend(ctx context.Context, in *RemoveBackendRequest, opts ...grpc.CallOption) (*RemoveBackendResponse, error)
+	// UpdateBackend 按update_mask更新后端的字段
+	UpdateBackend(ctx context.Context, in *UpdateBackendRequest, opts ...grpc.CallOption) (*UpdateBackendResponse, error)
+	// DisconnectBackend 标记后端断开，不再接收新请求
+	DisconnectBackend(ctx context.Context, in *DisconnectBackendRequest, opts ...grpc.CallOption) (*DisconnectBackendResponse, error)
+}
+
+type backendServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBackendServiceClient(cc grpc.ClientConnInterface) BackendServiceClient {
+	return &backendServiceClient{cc}
+}
+
+func (c *backendServiceClient) GetBackends(ctx context.Context, in *GetBackendsRequest, opts ...grpc.CallOption) (*GetBackendsResponse, error) {
+	out := new(GetBackendsResponse)
+	err := c.cc.Invoke(ctx, BackendService_GetBackends_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) AddBackend(ctx context.Context, in *AddBackendRequest, opts ...grpc.CallOption) (*AddBackendResponse, error) {
+	out := new(AddBackendResponse)
+	err := c.cc.Invoke(ctx, BackendService_AddBackend_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) RemoveBackend(ctx context.Context, in *RemoveBackendRequest, opts ...grpc.CallOption) (*RemoveBackendResponse, error) {
+	out := new(RemoveBackendResponse)
+	err := c.cc.Invoke(ctx, BackendService_RemoveBackend_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) UpdateBackend(ctx context.Context, in *UpdateBackendRequest, opts ...grpc.CallOption) (*UpdateBackendResponse, error) {
+	out := new(UpdateBackendResponse)
+	err := c.cc.Invoke(ctx, BackendService_UpdateBackend_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) DisconnectBackend(ctx context.Context, in *DisconnectBackendRequest, opts ...grpc.CallOption) (*DisconnectBackendResponse, error) {
+	out := new(DisconnectBackendResponse)
+	err := c.cc.Invoke(ctx, BackendService_DisconnectBackend_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BackendServiceServer is the server API for BackendService service.
+// All implementations must embed UnimplementedBackendServiceServer
+// for forward compatibility
+type BackendServiceServer interface {
+	// GetBackends 获取上游的活跃后端
+	GetBackends(context.Context, *GetBackendsRequest) (*GetBackendsResponse, error)
+	// AddBackend 向上游添加后端
+	AddBackend(context.Context, *AddBackendRequest) (*AddBackendResponse, error)
+	// RemoveBackend 从上游移除后端（暂未实现）
+	RemoveBackend(context.Context, *RemoveBackendRequest) (*RemoveBackendResponse, error)
+	// UpdateBackend 按update_mask更新后端的字段
+	UpdateBackend(context.Context, *UpdateBackendRequest) (*UpdateBackendResponse, error)
+	// DisconnectBackend 标记后端断开，不再接收新请求
+	DisconnectBackend(context.Context, *DisconnectBackendRequest) (*DisconnectBackendResponse, error)
+	mustEmbedUnimplementedBackendServiceServer()
+}
+
+// UnimplementedBackendServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBackendServiceServer struct {
+}
+
+func (UnimplementedBackendServiceServer) GetBackends(context.Context, *GetBackendsRequest) (*GetBackendsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBackends not implemented")
+}
+func (UnimplementedBackendServiceServer) AddBackend(context.Context, *AddBackendRequest) (*AddBackendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBackend not implemented")
+}
+func (UnimplementedBackendServiceServer) RemoveBackend(context.Context, *RemoveBackendRequest) (*RemoveBackendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBackend not implemented")
+}
+func (UnimplementedBackendServiceServer) UpdateBackend(context.Context, *UpdateBackendRequest) (*UpdateBackendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBackend not implemented")
+}
+func (UnimplementedBackendServiceServer) DisconnectBackend(context.Context, *DisconnectBackendRequest) (*DisconnectBackendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectBackend not implemented")
+}
+func (UnimplementedBackendServiceServer) mustEmbedUnimplementedBackendServiceServer() {}
+
+// UnsafeBackendServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BackendServiceServer will
+// result in compilation errors.
+type UnsafeBackendServiceServer interface {
+	mustEmbedUnimplementedBackendServiceServer()
+}
+
+func RegisterBackendServiceServer(s grpc.ServiceRegistrar, srv BackendServiceServer) {
+	s.RegisterService(&BackendService_ServiceDesc, srv)
+}
+
+func _BackendService_GetBackends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBackendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServiceServer).GetBackends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackendService_GetBackends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServiceServer).GetBackends(ctx, req.(*GetBackendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackendService_AddBackend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBackendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServiceServer).AddBackend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackendService_AddBackend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServiceServer).AddBackend(ctx, req.(*AddBackendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackendService_RemoveBackend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveBackendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServiceServer).RemoveBackend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackendService_RemoveBackend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServiceServer).RemoveBackend(ctx, req.(*RemoveBackendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackendService_UpdateBackend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBackendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServiceServer).UpdateBackend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackendService_UpdateBackend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServiceServer).UpdateBackend(ctx, req.(*UpdateBackendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackendService_DisconnectBackend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectBackendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServiceServer).DisconnectBackend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackendService_DisconnectBackend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServiceServer).DisconnectBackend(ctx, req.(*DisconnectBackendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BackendService_ServiceDesc is the grpc.ServiceDesc for BackendService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BackendService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "speedmimi.v1.BackendService",
+	HandlerType: (*BackendServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBackends",
+			Handler:    _BackendService_GetBackends_Handler,
+		},
+		{
+			MethodName: "AddBackend",
+			Handler:    _BackendService_AddBackend_Handler,
+		},
+		{
+			MethodName: "RemoveBackend",
+			Handler:    _BackendService_RemoveBackend_Handler,
+		},
+		{
+			MethodName: "UpdateBackend",
+			Handler:    _BackendService_UpdateBackend_Handler,
+		},
+		{
+			MethodName: "DisconnectBackend",
+			Handler:    _BackendService_DisconnectBackend_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "speedmimi.proto",
+}
+
+const (
+	MonitorService_GetServerStats_FullMethodName    = "/speedmimi.v1.MonitorService/GetServerStats"
+	MonitorService_GetBackendStats_FullMethodName   = "/speedmimi.v1.MonitorService/GetBackendStats"
+	MonitorService_ReportPerformance_FullMethodName = "/speedmimi.v1.MonitorService/ReportPerformance"
+)
+
+// MonitorServiceClient is the client API for MonitorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MonitorServiceClient interface {
+	// GetServerStats 获取代理自身的性能和流量统计
+	GetServerStats(ctx context.Context, in *GetServerStatsRequest, opts ...grpc.CallOption) (*GetServerStatsResponse, error)
+	// GetBackendStats 获取后端最近上报的性能信息和当前连接数
+	GetBackendStats(ctx context.Context, in *GetBackendStatsRequest, opts ...grpc.CallOption) (*GetBackendStatsResponse, error)
+	// ReportPerformance 后端上报性能信息
+	ReportPerformance(ctx context.Context, in *ReportPerformanceRequest, opts ...grpc.CallOption) (*ReportPerformanceResponse, error)
+}
+
+type monitorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMonitorServiceClient(cc grpc.ClientConnInterface) MonitorServiceClient {
+	return &monitorServiceClient{cc}
+}
+
+func (c *monitorServiceClient) GetServerStats(ctx context.Context, in *GetServerStatsRequest, opts ...grpc.CallOption) (*GetServerStatsResponse, error) {
+	out := new(GetServerStatsResponse)
+	err := c.cc.Invoke(ctx, MonitorService_GetServerStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitorServiceClient) GetBackendStats(ctx context.Context, in *GetBackendStatsRequest, opts ...grpc.CallOption) (*GetBackendStatsResponse, error) {
+	out := new(GetBackendStatsResponse)
+	err := c.cc.Invoke(ctx, MonitorService_GetBackendStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitorServiceClient) ReportPerformance(ctx context.Context, in *ReportPerformanceRequest, opts ...grpc.CallOption) (*ReportPerformanceResponse, error) {
+	out := new(ReportPerformanceResponse)
+	err := c.cc.Invoke(ctx, MonitorService_ReportPerformance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MonitorServiceServer is the server API for MonitorService service.
+// All implementations must embed UnimplementedMonitorServiceServer
+// for forward compatibility
+type MonitorServiceServer interface {
+	// GetServerStats 获取代理自身的性能和流量统计
+	GetServerStats(context.Context, *GetServerStatsRequest) (*GetServerStatsResponse, error)
+	// GetBackendStats 获取后端最近上报的性能信息和当前连接数
+	GetBackendStats(context.Context, *GetBackendStatsRequest) (*GetBackendStatsResponse, error)
+	// ReportPerformance 后端上报性能信息
+	ReportPerformance(context.Context, *ReportPerformanceRequest) (*ReportPerformanceResponse, error)
+	mustEmbedUnimplementedMonitorServiceServer()
+}
+
+// UnimplementedMonitorServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMonitorServiceServer struct {
+}
+
+func (UnimplementedMonitorServiceServer) GetServerStats(context.Context, *GetServerStatsRequest) (*GetServerStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerStats not implemented")
+}
+func (UnimplementedMonitorServiceServer) GetBackendStats(context.Context, *GetBackendStatsRequest) (*GetBackendStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBackendStats not implemented")
+}
+func (UnimplementedMonitorServiceServer) ReportPerformance(context.Context, *ReportPerformanceRequest) (*ReportPerformanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportPerformance not implemented")
+}
+func (UnimplementedMonitorServiceServer) mustEmbedUnimplementedMonitorServiceServer() {}
+
+// UnsafeMonitorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MonitorServiceServer will
+// result in compilation errors.
+type UnsafeMonitorServiceServer interface {
+	mustEmbedUnimplementedMonitorServiceServer()
+}
+
+func RegisterMonitorServiceServer(s grpc.ServiceRegistrar, srv MonitorServiceServer) {
+	s.RegisterService(&MonitorService_ServiceDesc, srv)
+}
+
+func _MonitorService_GetServerStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServerStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServiceServer).GetServerStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitorService_GetServerStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServiceServer).GetServerStats(ctx, req.(*GetServerStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitorService_GetBackendStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBackendStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServiceServer).GetBackendStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitorService_GetBackendStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServiceServer).GetBackendStats(ctx, req.(*GetBackendStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitorService_ReportPerformance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportPerformanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServiceServer).ReportPerformance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitorService_ReportPerformance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServiceServer).ReportPerformance(ctx, req.(*ReportPerformanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MonitorService_ServiceDesc is the grpc.ServiceDesc for MonitorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MonitorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "speedmimi.v1.MonitorService",
+	HandlerType: (*MonitorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetServerStats",
+			Handler:    _MonitorService_GetServerStats_Handler,
+		},
+		{
+			MethodName: "GetBackendStats",
+			Handler:    _MonitorService_GetBackendStats_Handler,
+		},
+		{
+			MethodName: "ReportPerformance",
+			Handler:    _MonitorService_ReportPerformance_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "speedmimi.proto",
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+)
+
+// runCheck 实现 speedmimi check 子命令：加载并验证配置文件后退出，失败时退出码为1
+// 用于CI和部署流程，类似 nginx -t
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	path := fs.String("config", "configs/config.yaml", "Path to configuration file")
+	resolve := fs.Bool("resolve", false, "Resolve backend host names")
+	checkCerts := fs.Bool("check-certs", false, "Load certificate and key files and check that they match and have not expired")
+	timeout := fs.Duration("resolve-timeout", 5*time.Second, "Timeout for resolving each backend host name")
+	quiet := fs.Bool("q", false, "Only print errors")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s check [flags]\n\nValidate a configuration file and exit non-zero if it is invalid.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	result, err := config.Check(*path, config.CheckOptions{
+		ResolveDNS: *resolve,
+		CheckCerts: *checkCerts,
+		Timeout:    *timeout,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "configuration file %s test failed: %v\n", *path, err)
+		return 1
+	}
+	for _, fe := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", fe.Field, fe.Message)
+	}
+	for _, fe := range result.Errors {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", fe.Field, fe.Message)
+	}
+	if !result.OK() {
+		fmt.Fprintf(os.Stderr, "configuration file %s test failed: %d errors\n", *path, len(result.Errors))
+		return 1
+	}
+	if !*quiet {
+		fmt.Printf("configuration file %s test is successful\n", *path)
+	}
+	return 0
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
@@ -13,24 +15,71 @@ import (
 
 	"github.com/quqi/speedmimi/internal/config"
 	"github.com/quqi/speedmimi/internal/grpcservice"
+	"github.com/quqi/speedmimi/internal/logsink"
 	"github.com/quqi/speedmimi/internal/proxy"
 )
 
 var (
-	configPath = flag.String("config", "configs/config.yaml", "Path to configuration file")
+	configPath           = flag.String("config", "configs/config.yaml", "Path to configuration file (local cache when -config-source is set)")
+	configSource         = flag.String("config-source", "", "Load and watch configuration from https://..., consul://host:8500/key or etcd://host:2379/key")
+	configSourceCA       = flag.String("config-source-ca", "", "CA file for verifying the config source's certificate")
+	configSourceInterval = flag.Duration("config-source-interval", 30*time.Second, "Poll interval for https config sources")
 )
 
+// configSourceTokenEnv 访问远程配置来源的令牌，通过环境变量传入避免出现在进程参数中
+const configSourceTokenEnv = "SPEEDMIMI_CONFIG_SOURCE_TOKEN"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Args[2:]))
+	}
 	flag.Parse()
 
 	// 初始化配置管理器
-	configMgr, err := config.NewManager(*configPath)
+	configMgr, err := newConfigManager()
 	if err != nil {
+		// 列出所有校验错误，一次修改完
+		var fieldErrs config.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			for _, fe := range fieldErrs {
+				log.Printf("Invalid config: %s: %s", fe.Field, fe.Message)
+			}
+			log.Fatalf("Failed to initialize config manager: %d config errors", len(fieldErrs))
+		}
 		log.Fatalf("Failed to initialize config manager: %v", err)
 	}
 
 	cfg := configMgr.GetConfig()
 
+	// 把诊断日志同时发送到远程日志目标
+	if cfg.ErrorLog.Path != "" {
+		restore, err := logsink.Capture(cfg.ErrorLog.Path)
+		if err != nil {
+			log.Fatalf("Failed to open error log: %v", err)
+		}
+		defer restore()
+	}
+
+	// 监听远程配置来源或配置文件的变化
+	if *configSource != "" {
+		if err := configMgr.StartSourceWatch(); err != nil {
+			log.Fatalf("Failed to watch config source: %v", err)
+		}
+		defer configMgr.StopSourceWatch()
+	} else if cfg.ConfigWatch.Enabled {
+		if err := configMgr.StartFileWatch(cfg.ConfigWatch.Debounce); err != nil {
+			log.Fatalf("Failed to watch config file: %v", err)
+		}
+		defer configMgr.StopFileWatch()
+	}
+	// 定期重新获取配置引用的密钥
+	if cfg.Secrets.RefreshInterval > 0 {
+		if err := configMgr.StartSecretRefresh(cfg.Secrets.RefreshInterval); err != nil {
+			log.Fatalf("Failed to refresh secrets: %v", err)
+		}
+		defer configMgr.StopSecretRefresh()
+	}
+
 	// 初始化反向代理服务器
 	proxyServer, err := proxy.NewServer(configMgr)
 	if err != nil {
@@ -58,19 +107,37 @@ func main() {
 	go startSystemMonitoring()
 
 	// 初始化并启动管理API服务器
+	var adminServer *grpcservice.Server
 	if cfg.GRPC.Enabled {
 		monitor := proxyServer.GetMonitor()
-		grpcServer := grpcservice.NewServer(configMgr, proxyServer, monitor)
+		adminServer = grpcservice.NewServer(configMgr, proxyServer, monitor)
 		go func() {
 			log.Printf("Starting management API server on %s:%d", cfg.GRPC.Host, cfg.GRPC.Port)
-			if err := grpcServer.Start(cfg.GRPC.Host, cfg.GRPC.Port); err != nil {
+			if err := adminServer.Start(cfg.GRPC.Host, cfg.GRPC.Port); err != nil && err != http.ErrServerClosed {
 				log.Fatalf("Failed to start management API server: %v", err)
 			}
 		}()
 	}
 
-	// 等待中断信号
-	waitForShutdown(proxyServer)
+	// 等待中断信号或通过管理API发起的关闭
+	waitForShutdown(proxyServer, adminServer)
+}
+
+// newConfigManager 从配置文件或远程配置来源创建配置管理器
+func newConfigManager() (*config.Manager, error) {
+	if *configSource == "" {
+		return config.NewManager(*configPath)
+	}
+	source, err := config.ParseSource(*configSource, config.SourceOptions{
+		Token:    os.Getenv(configSourceTokenEnv),
+		CAFile:   *configSourceCA,
+		Interval: *configSourceInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loading configuration from %s", source)
+	return config.NewManagerFromSource(*configPath, source)
 }
 
 // startSystemMonitoring 启动系统性能监控
@@ -107,16 +174,48 @@ func startSystemMonitoring() {
 	}
 }
 
-func waitForShutdown(proxyServer *proxy.Server) {
+// adminShutdownTimeout 关闭时等待处理中的管理请求返回的最长时间
+const adminShutdownTimeout = 5 * time.Second
+
+// forcedStopTimeout 管理API发起的关闭排空结束后，等待剩余连接关闭的最长时间
+const forcedStopTimeout = 5 * time.Second
+
+func waitForShutdown(proxyServer *proxy.Server, adminServer *grpcservice.Server) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
-	<-c
-	log.Println("Shutting down server...")
+	requested := false
+	select {
+	case <-c:
+		log.Println("Shutting down server...")
+	case <-proxyServer.ShutdownRequested():
+		requested = true
+		log.Println("Shutdown requested via management API, shutting down server...")
+	}
+
+	// 先让管理请求（包括等待排空结束的关闭请求）返回
+	if adminServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("Error stopping management API server: %v", err)
+		}
+		cancel()
+	}
 
-	// 优雅关闭
-	if err := proxyServer.Stop(); err != nil {
-		log.Printf("Error stopping proxy server: %v", err)
+	// 优雅关闭；管理API发起的关闭已经等待过处理中的请求，超过截止时间仍未完成的请求不再等待
+	stopped := make(chan error, 1)
+	go func() { stopped <- proxyServer.Stop() }()
+	var stopTimeout <-chan time.Time
+	if requested {
+		stopTimeout = time.After(forcedStopTimeout)
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			log.Printf("Error stopping proxy server: %v", err)
+		}
+	case <-stopTimeout:
+		log.Printf("Timed out waiting for in-flight requests, exiting")
 	}
 
 	log.Println("Server stopped")
@@ -0,0 +1,388 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/logsink"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 访问日志级别
+const (
+	LevelOff    = "off"    // 不记录
+	LevelErrors = "errors" // 只记录错误请求
+	LevelAll    = "all"    // 记录错误请求和按采样率选中的其他请求
+)
+
+// DefaultFormat 默认日志格式
+const DefaultFormat = `$client_ip - [$time_iso8601] "$method $uri $protocol" $status $body_bytes_sent $request_time "$http_referer" "$http_user_agent" $route $upstream $backend $request_id`
+
+// Settings 运行时可以调整的日志设置
+type Settings struct {
+	Level       string `json:"level"`
+	SampleRate  int    `json:"sample_rate"`  // 非错误请求每N个记录1个
+	ErrorStatus int    `json:"error_status"` // 状态码不小于该值的请求视为错误，总是记录
+	MaxRate     int    `json:"max_rate"`     // 采样后每秒最多记录的非错误请求数，0表示不限制
+}
+
+// Validate 校验日志设置
+func (s *Settings) Validate() error {
+	switch s.Level {
+	case LevelOff, LevelErrors, LevelAll:
+	default:
+		return fmt.Errorf("level must be %s, %s or %s, got %q", LevelOff, LevelErrors, LevelAll, s.Level)
+	}
+	if s.SampleRate < 1 {
+		return fmt.Errorf("sample_rate must be at least 1, got %d", s.SampleRate)
+	}
+	if s.ErrorStatus < 100 || s.ErrorStatus > 599 {
+		return fmt.Errorf("error_status must be between 100 and 599, got %d", s.ErrorStatus)
+	}
+	if s.MaxRate < 0 {
+		return fmt.Errorf("max_rate must not be negative, got %d", s.MaxRate)
+	}
+	return nil
+}
+
+// Stats 日志统计
+type Stats struct {
+	Logged      int64 `json:"logged"`       // 已记录的请求数
+	Skipped     int64 `json:"skipped"`      // 因级别或采样未记录的请求数
+	RateLimited int64 `json:"rate_limited"` // 超过max_rate未记录的请求数
+	Dropped     int64 `json:"dropped"`      // 缓冲区或发送队列已满丢弃的日志数
+	Errors      int64 `json:"errors"`       // 写入失败次数
+	Rotations   int64 `json:"rotations"`    // 日志文件轮转次数
+	Shipped     int64 `json:"shipped"`      // 已发送到远程日志目标的日志数
+}
+
+// output 日志输出位置和方式，变化时需要重新创建日志
+type output struct {
+	path          string
+	format        string
+	bufferSize    int
+	flushInterval time.Duration
+	rotation      types.AccessLogRotationConfig
+}
+
+// sampleRule 编译后的采样规则，每个规则单独计数
+type sampleRule struct {
+	types.AccessLogSampleRule
+	seq atomic.Uint64
+}
+
+// match 规则是否匹配请求
+func (r *sampleRule) match(route string, status int) bool {
+	return (r.Route == "" || r.Route == route) &&
+		(r.MinStatus == 0 || status >= r.MinStatus) &&
+		(r.MaxStatus == 0 || status <= r.MaxStatus)
+}
+
+// Logger 访问日志
+type Logger struct {
+	output     output
+	template   *vars.Template
+	configured Settings // 配置文件中的设置，用于判断重载时是否需要覆盖运行时的调整
+
+	settings  atomic.Pointer[Settings]
+	seq       atomic.Uint64
+	rules     atomic.Pointer[[]*sampleRule]
+	ruleCfgs  []types.AccessLogSampleRule
+	rateSec   atomic.Int64 // max_rate当前计数的秒
+	rateCount atomic.Int64
+
+	mu     sync.Mutex
+	out    io.Writer
+	file   io.Closer     // 写到文件或远程日志目标时不为nil
+	rotate *rotatingFile // 配置了轮转时不为nil
+	ring   *ringBuffer   // 配置了缓冲区时不为nil
+
+	logged      atomic.Int64
+	skipped     atomic.Int64
+	rateLimited atomic.Int64
+	errors      atomic.Int64
+}
+
+// Validate 校验访问日志配置
+func Validate(cfg *types.AccessLogConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	settings := settingsOf(cfg)
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if _, err := vars.Compile(formatOf(cfg)); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+	for i, rule := range cfg.Rules {
+		if rule.SampleRate < 1 {
+			return fmt.Errorf("rules[%d].sample_rate must be at least 1, got %d", i, rule.SampleRate)
+		}
+		if rule.MinStatus < 0 || rule.MaxStatus < 0 || (rule.MaxStatus > 0 && rule.MinStatus > rule.MaxStatus) {
+			return fmt.Errorf("rules[%d]: min_status and max_status must form a valid range", i)
+		}
+	}
+	if cfg.BufferSize < 0 || cfg.FlushInterval < 0 {
+		return fmt.Errorf("buffer_size and flush_interval must not be negative")
+	}
+	if cfg.BufferSize > 0 && cfg.BufferSize < minBufferSize {
+		return fmt.Errorf("buffer_size must be at least %d bytes, got %d", minBufferSize, cfg.BufferSize)
+	}
+	rotation := cfg.Rotation
+	if rotation.MaxSize < 0 || rotation.Interval < 0 || rotation.MaxBackups < 0 {
+		return fmt.Errorf("rotation: max_size, interval and max_backups must not be negative")
+	}
+	remote := logsink.IsRemote(cfg.Path)
+	if remote {
+		if err := logsink.Validate(cfg.Path); err != nil {
+			return fmt.Errorf("path: %w", err)
+		}
+	}
+	if rotation != (types.AccessLogRotationConfig{}) && (remote || logsink.IsStdStream(cfg.Path)) {
+		return fmt.Errorf("rotation requires a log file path")
+	}
+	return nil
+}
+
+// minBufferSize 缓冲区的最小字节数
+const minBufferSize = 4096
+
+// New 创建访问日志
+func New(cfg *types.AccessLogConfig) (*Logger, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		output:     outputOf(cfg),
+		template:   vars.MustCompile(formatOf(cfg)),
+		configured: settingsOf(cfg),
+	}
+
+	switch {
+	case logsink.IsStdStream(cfg.Path):
+		out, _ := logsink.Open(cfg.Path, logsink.Options{})
+		l.out = out
+	case logsink.IsRemote(cfg.Path):
+		sink, err := logsink.Open(cfg.Path, logsink.Options{MsgID: "access"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		l.file = sink
+		l.out = sink
+	case cfg.Rotation != (types.AccessLogRotationConfig{}):
+		file, err := openRotatingFile(cfg.Path, cfg.Rotation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		l.rotate = file
+		l.file = file
+		l.out = file
+	default:
+		file, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		l.file = file
+		l.out = file
+	}
+	if cfg.BufferSize > 0 {
+		l.ring = newRingBuffer(l.out, cfg.BufferSize, cfg.FlushInterval, &l.errors)
+	}
+
+	settings := l.configured
+	l.settings.Store(&settings)
+	l.setRules(cfg.Rules)
+	return l, nil
+}
+
+// setRules 替换采样规则，计数从头开始
+func (l *Logger) setRules(cfgs []types.AccessLogSampleRule) {
+	rules := make([]*sampleRule, len(cfgs))
+	for i, cfg := range cfgs {
+		rules[i] = &sampleRule{AccessLogSampleRule: cfg}
+	}
+	l.ruleCfgs = cfgs
+	l.rules.Store(&rules)
+}
+
+// Reload 按新配置更新日志设置，返回false表示输出位置、格式、缓冲或轮转设置变化，需要重新创建
+// 配置文件中的level/sample_rate/error_status/max_rate没有变化时保留运行时的调整
+func (l *Logger) Reload(cfg *types.AccessLogConfig) bool {
+	if outputOf(cfg) != l.output {
+		return false
+	}
+
+	configured := settingsOf(cfg)
+	if configured != l.configured {
+		l.configured = configured
+		l.settings.Store(&configured)
+	}
+	if !reflect.DeepEqual(cfg.Rules, l.ruleCfgs) {
+		l.setRules(cfg.Rules)
+	}
+	return true
+}
+
+// Settings 获取当前日志设置
+func (l *Logger) Settings() Settings {
+	return *l.settings.Load()
+}
+
+// Update 在运行时调整日志设置（不写入配置文件）
+func (l *Logger) Update(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	l.settings.Store(&settings)
+	fmt.Printf("[ACCESSLOG] Settings updated: level=%s sample_rate=%d error_status=%d max_rate=%d\n", settings.Level, settings.SampleRate, settings.ErrorStatus, settings.MaxRate)
+	return nil
+}
+
+// Stats 获取日志统计
+func (l *Logger) Stats() Stats {
+	stats := Stats{
+		Logged:      l.logged.Load(),
+		Skipped:     l.skipped.Load(),
+		RateLimited: l.rateLimited.Load(),
+		Errors:      l.errors.Load(),
+	}
+	if l.ring != nil {
+		stats.Dropped = l.ring.dropped.Load()
+	}
+	if l.rotate != nil {
+		stats.Rotations = l.rotate.rotations.Load()
+	}
+	if sink, ok := logsink.StatsOf(l.out); ok {
+		stats.Shipped = sink.Sent
+		stats.Dropped += sink.Dropped
+	}
+	return stats
+}
+
+// Log 按级别、采样规则和速率上限记录请求，env只在需要记录时调用
+func (l *Logger) Log(ctx *fasthttp.RequestCtx, env func() *vars.Env) {
+	route, _ := ctx.UserValue(vars.UserValueRoute).(string)
+	if !l.shouldLog(route, ctx.Response.StatusCode()) {
+		return
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = l.template.Append(buf, env())
+	buf = append(buf, '\n')
+
+	if l.ring != nil {
+		if l.ring.write(buf) {
+			l.logged.Add(1)
+		}
+		return
+	}
+
+	l.mu.Lock()
+	_, err := l.out.Write(buf)
+	l.mu.Unlock()
+
+	if err != nil {
+		l.errors.Add(1)
+		return
+	}
+	l.logged.Add(1)
+}
+
+// shouldLog 错误请求总是记录；其他请求在level为all时按第一个匹配的规则或sample_rate采样，
+// 再受每秒max_rate条的限制
+func (l *Logger) shouldLog(route string, status int) bool {
+	settings := l.settings.Load()
+	switch settings.Level {
+	case LevelOff:
+		l.skipped.Add(1)
+		return false
+	case LevelErrors:
+		if status < settings.ErrorStatus {
+			l.skipped.Add(1)
+			return false
+		}
+		return true
+	}
+	if status >= settings.ErrorStatus {
+		return true
+	}
+
+	rate, seq := settings.SampleRate, &l.seq
+	for _, rule := range *l.rules.Load() {
+		if rule.match(route, status) {
+			rate, seq = rule.SampleRate, &rule.seq
+			break
+		}
+	}
+	if rate > 1 && seq.Add(1)%uint64(rate) != 0 {
+		l.skipped.Add(1)
+		return false
+	}
+
+	if settings.MaxRate > 0 && !l.allowRate(settings.MaxRate) {
+		l.rateLimited.Add(1)
+		return false
+	}
+	return true
+}
+
+// allowRate 按秒计数，当前秒内记录的条数不超过limit
+func (l *Logger) allowRate(limit int) bool {
+	now := time.Now().Unix()
+	if sec := l.rateSec.Load(); sec != now && l.rateSec.CompareAndSwap(sec, now) {
+		l.rateCount.Store(0)
+	}
+	return l.rateCount.Add(1) <= int64(limit)
+}
+
+// Close 写出缓冲区中的日志后关闭日志文件
+func (l *Logger) Close() error {
+	if l.ring != nil {
+		l.ring.close()
+	}
+	if l.file == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// settingsOf 配置中的日志设置
+func settingsOf(cfg *types.AccessLogConfig) Settings {
+	return Settings{
+		Level:       cfg.Level,
+		SampleRate:  cfg.SampleRate,
+		ErrorStatus: cfg.ErrorStatus,
+		MaxRate:     cfg.MaxRate,
+	}
+}
+
+// outputOf 配置中的输出设置
+func outputOf(cfg *types.AccessLogConfig) output {
+	return output{
+		path:          cfg.Path,
+		format:        formatOf(cfg),
+		bufferSize:    cfg.BufferSize,
+		flushInterval: cfg.FlushInterval,
+		rotation:      cfg.Rotation,
+	}
+}
+
+// formatOf 配置的日志格式，为空时使用默认格式
+func formatOf(cfg *types.AccessLogConfig) string {
+	if cfg.Format == "" {
+		return DefaultFormat
+	}
+	return cfg.Format
+}
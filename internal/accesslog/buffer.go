@@ -0,0 +1,115 @@
+package accesslog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ringBuffer 固定大小的环形缓冲区，请求协程只复制日志行，后台协程批量写出
+// 缓冲区放不下时丢弃新的日志行，写出慢时不会阻塞请求
+type ringBuffer struct {
+	out      io.Writer
+	interval time.Duration
+
+	mu    sync.Mutex
+	buf   []byte
+	start int // 第一个未写出字节的位置
+	n     int // 未写出的字节数
+
+	kick    chan struct{} // 缓冲区过半时提前写出
+	stop    chan struct{}
+	stopped sync.Once
+	wg      sync.WaitGroup
+
+	dropped atomic.Int64
+	errors  *atomic.Int64 // 写出失败次数，与Logger共用
+}
+
+func newRingBuffer(out io.Writer, size int, interval time.Duration, errors *atomic.Int64) *ringBuffer {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	b := &ringBuffer{
+		out:      out,
+		interval: interval,
+		buf:      make([]byte, size),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		errors:   errors,
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// write 复制一行日志到缓冲区，放不下时返回false
+func (b *ringBuffer) write(line []byte) bool {
+	b.mu.Lock()
+	if b.n+len(line) > len(b.buf) {
+		b.mu.Unlock()
+		b.dropped.Add(1)
+		return false
+	}
+	end := (b.start + b.n) % len(b.buf)
+	copied := copy(b.buf[end:], line)
+	copy(b.buf, line[copied:])
+	b.n += len(line)
+	half := b.n >= len(b.buf)/2
+	b.mu.Unlock()
+
+	if half {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func (b *ringBuffer) run() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	scratch := make([]byte, 0, len(b.buf))
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.kick:
+		case <-b.stop:
+			b.flush(scratch)
+			return
+		}
+		b.flush(scratch)
+	}
+}
+
+// flush 取出缓冲区中的全部内容后在锁外写出
+func (b *ringBuffer) flush(scratch []byte) {
+	b.mu.Lock()
+	if b.n == 0 {
+		b.mu.Unlock()
+		return
+	}
+	end := b.start + b.n
+	if end <= len(b.buf) {
+		scratch = append(scratch[:0], b.buf[b.start:end]...)
+	} else {
+		scratch = append(scratch[:0], b.buf[b.start:]...)
+		scratch = append(scratch, b.buf[:end-len(b.buf)]...)
+	}
+	b.start, b.n = 0, 0
+	b.mu.Unlock()
+
+	if _, err := b.out.Write(scratch); err != nil {
+		b.errors.Add(1)
+	}
+}
+
+// close 写出剩余的日志后停止后台协程
+func (b *ringBuffer) close() {
+	b.stopped.Do(func() { close(b.stop) })
+	b.wg.Wait()
+}
@@ -0,0 +1,224 @@
+package accesslog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// backupTimeFormat 轮转文件名中的时间
+const backupTimeFormat = "20060102-150405"
+
+// rotatingFile 按大小和时间轮转的日志文件
+// 轮转后的文件在后台压缩并按max_backups删除最旧的文件
+type rotatingFile struct {
+	path string
+	cfg  types.AccessLogRotationConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	rotations atomic.Int64
+	maintMu   sync.Mutex // 串行化压缩和清理
+	maintWg   sync.WaitGroup
+}
+
+func openRotatingFile(path string, cfg types.AccessLogRotationConfig) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.openedAt = file, info.Size(), time.Now()
+	return nil
+}
+
+// Write 写入日志，写入前按大小和时间检查是否需要轮转
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			fmt.Printf("[ACCESSLOG] Failed to rotate %s: %v\n", f.path, err)
+			if f.file == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.cfg.MaxSize > 0 && f.size+int64(n) > f.cfg.MaxSize {
+		return true
+	}
+	return f.cfg.Interval > 0 && time.Since(f.openedAt) >= f.cfg.Interval
+}
+
+// rotate 把当前文件重命名为轮转文件并重新创建
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backup := f.backupName(time.Now())
+	renameErr := os.Rename(f.path, backup)
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	f.rotations.Add(1)
+
+	f.maintWg.Add(1)
+	go f.maintain(backup)
+	return nil
+}
+
+// backupName 轮转文件名，同一秒内多次轮转时追加序号
+func (f *rotatingFile) backupName(now time.Time) string {
+	base := f.path + "." + now.Format(backupTimeFormat)
+	name := base
+	for i := 1; ; i++ {
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		name = fmt.Sprintf("%s.%d", base, i)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// maintain 压缩轮转后的文件，删除超过max_backups的旧文件
+func (f *rotatingFile) maintain(backup string) {
+	defer f.maintWg.Done()
+	f.maintMu.Lock()
+	defer f.maintMu.Unlock()
+
+	if f.cfg.Compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Printf("[ACCESSLOG] Failed to compress %s: %v\n", backup, err)
+		}
+	}
+	if f.cfg.MaxBackups > 0 {
+		backups := f.backups()
+		for _, old := range backups[:max(0, len(backups)-f.cfg.MaxBackups)] {
+			if err := os.Remove(old); err != nil {
+				fmt.Printf("[ACCESSLOG] Failed to remove %s: %v\n", old, err)
+			}
+		}
+	}
+}
+
+// backups 已有的轮转文件，从旧到新排序
+func (f *rotatingFile) backups() []string {
+	matches, _ := filepath.Glob(f.path + ".*")
+	prefix := f.path + "."
+	var backups []string
+	for _, name := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if len(suffix) < len(backupTimeFormat) || strings.HasSuffix(suffix, ".tmp") {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, suffix[:len(backupTimeFormat)]); err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backupKey(backups[i]) < backupKey(backups[j])
+	})
+	return backups
+}
+
+// backupKey 轮转文件的排序键：时间相同时按序号排序，压缩和未压缩的文件一起排序
+func backupKey(name string) string {
+	name = strings.TrimSuffix(name, ".gz")
+	ext := filepath.Ext(name)
+	if seq, err := strconv.Atoi(strings.TrimPrefix(ext, ".")); err == nil {
+		return fmt.Sprintf("%s.%06d", strings.TrimSuffix(name, ext), seq)
+	}
+	return name + ".000000"
+}
+
+// compressFile 把文件压缩为<path>.gz并删除原文件
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close 关闭文件，等待后台压缩完成
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.maintWg.Wait()
+	return err
+}
@@ -0,0 +1,203 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/ratelimit"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// keyPrefix 生成的API密钥的前缀，便于在日志和代码仓库中识别泄露的密钥
+const keyPrefix = "smk_"
+
+// Result 认证结果
+type Result int
+
+const (
+	Allowed     Result = iota // 允许通过
+	MissingKey                // 未携带API密钥
+	UnknownKey                // API密钥不存在或已吊销
+	RouteDenied               // API密钥不允许访问该路由
+	RateLimited               // 超过密钥的请求速率上限
+)
+
+// Store API密钥存储，按密钥摘要查找
+// 配置重载时通过NewStore传入旧存储，保留仍存在的密钥的限速状态和统计
+type Store struct {
+	header     string
+	queryParam string
+	keys       []*Key
+	byHash     map[string]*Key
+}
+
+// Key 单个API密钥的运行时状态
+type Key struct {
+	cfg     types.APIKey
+	routes  map[string]bool // 为nil时允许所有路由
+	limiter *ratelimit.Limiter
+	stats   *stats
+}
+
+// stats 密钥的使用统计，配置重载时在新旧Key之间共享
+type stats struct {
+	requests atomic.Int64
+	rejected atomic.Int64
+	lastUsed atomic.Int64 // UnixNano，0表示未使用过
+}
+
+// Info API密钥的信息和使用统计，不包含密钥本身
+type Info struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Routes    []string               `json:"routes"`
+	RateLimit *types.RateLimitConfig `json:"rate_limit,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	Requests  int64                  `json:"requests"` // 认证通过的请求数
+	Rejected  int64                  `json:"rejected"` // 因路由不允许或超过速率上限被拒绝的请求数
+	LastUsed  *time.Time             `json:"last_used,omitempty"`
+}
+
+// NewStore 根据配置创建API密钥存储
+// prev不为nil时沿用其中同ID密钥的限速状态和统计
+func NewStore(cfg *types.APIKeyConfig, prev *Store) *Store {
+	s := &Store{
+		header:     cfg.Header,
+		queryParam: cfg.QueryParam,
+		keys:       make([]*Key, 0, len(cfg.Keys)),
+		byHash:     make(map[string]*Key, len(cfg.Keys)),
+	}
+
+	old := make(map[string]*Key)
+	if prev != nil {
+		for _, key := range prev.keys {
+			old[key.cfg.ID] = key
+		}
+	}
+
+	for _, c := range cfg.Keys {
+		key := &Key{cfg: c}
+		if len(c.Routes) > 0 {
+			key.routes = make(map[string]bool, len(c.Routes))
+			for _, route := range c.Routes {
+				key.routes[route] = true
+			}
+		}
+
+		prevKey := old[c.ID]
+		if prevKey != nil {
+			key.stats = prevKey.stats
+		} else {
+			key.stats = &stats{}
+		}
+		if limit := c.RateLimit; limit != nil {
+			if prevKey != nil && prevKey.limiter != nil {
+				key.limiter = prevKey.limiter
+				key.limiter.SetLimit(limit.Rate, limit.Burst, 0)
+			} else {
+				key.limiter = ratelimit.NewLimiter(limit.Rate, limit.Burst, 0)
+			}
+		}
+
+		s.keys = append(s.keys, key)
+		s.byHash[c.Hash] = key
+	}
+
+	return s
+}
+
+// Header 读取API密钥的请求头
+func (s *Store) Header() string {
+	return s.header
+}
+
+// QueryParam 读取API密钥的查询参数
+func (s *Store) QueryParam() string {
+	return s.queryParam
+}
+
+// Check 检查API密钥是否允许访问路由，通过时计入一次请求
+// 超过速率上限时返回下一个请求可以通过前需要等待的时间
+func (s *Store) Check(secret []byte, route string, now time.Time) (*Key, Result, time.Duration) {
+	if len(secret) == 0 {
+		return nil, MissingKey, 0
+	}
+
+	key := s.byHash[Hash(string(secret))]
+	if key == nil {
+		return nil, UnknownKey, 0
+	}
+	if key.routes != nil && !key.routes[route] {
+		key.stats.rejected.Add(1)
+		return key, RouteDenied, 0
+	}
+	if key.limiter != nil {
+		if wait, ok := key.limiter.Reserve(now); !ok {
+			key.stats.rejected.Add(1)
+			return key, RateLimited, wait
+		}
+	}
+
+	key.stats.requests.Add(1)
+	key.stats.lastUsed.Store(now.UnixNano())
+	return key, Allowed, 0
+}
+
+// ID 密钥ID
+func (k *Key) ID() string {
+	return k.cfg.ID
+}
+
+// List 列出所有密钥的信息和使用统计，按创建时间排序
+func (s *Store) List() []Info {
+	list := make([]Info, 0, len(s.keys))
+	for _, key := range s.keys {
+		info := Info{
+			ID:        key.cfg.ID,
+			Name:      key.cfg.Name,
+			Routes:    append([]string{}, key.cfg.Routes...),
+			RateLimit: key.cfg.RateLimit,
+			CreatedAt: key.cfg.CreatedAt,
+			Requests:  key.stats.requests.Load(),
+			Rejected:  key.stats.rejected.Load(),
+		}
+		if n := key.stats.lastUsed.Load(); n != 0 {
+			lastUsed := time.Unix(0, n).UTC()
+			info.LastUsed = &lastUsed
+		}
+		list = append(list, info)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Generate 生成新的API密钥，返回密钥本身和用于保存的摘要
+func Generate() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = keyPrefix + hex.EncodeToString(b)
+	return secret, Hash(secret), nil
+}
+
+// NewID 生成新的密钥ID
+func NewID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Hash 计算API密钥的SHA-256摘要（十六进制）
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,262 @@
+// Package artifact 数据文件（地理位置表、规则、IP列表等）的热加载
+// 与certwatch相同，通过轮询修改时间、大小和内容哈希检测变化，不依赖inotify/fsnotify；
+// 新内容校验失败时继续使用旧版本
+package artifact
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultInterval 默认轮询间隔
+const DefaultInterval = 30 * time.Second
+
+// hashEvery 每隔多少次轮询强制比较一次文件内容哈希
+const hashEvery = 10
+
+// ErrNotFound 数据文件未注册
+var ErrNotFound = errors.New("artifact not found")
+
+// Parser 解析并校验文件内容，返回错误时不替换当前版本
+type Parser func(data []byte) (interface{}, error)
+
+// Status 数据文件的加载状态
+type Status struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Version   int64     `json:"version"` // 每次加载到不同内容时递增，0表示尚未成功加载
+	SHA256    string    `json:"sha256,omitempty"`
+	Size      int64     `json:"size"`
+	LoadedAt  time.Time `json:"loaded_at"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// fileState 文件的修改时间和大小
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// artifact 单个数据文件
+type artifact struct {
+	name     string
+	path     string
+	parse    Parser
+	onChange func(value interface{}) // 加载成功后在持有锁时调用，用于原子替换使用方的数据
+
+	state     fileState
+	digest    [sha256.Size]byte
+	version   int64
+	polls     int
+	loadedAt  time.Time
+	lastCheck time.Time
+	lastErr   error
+}
+
+// Manager 数据文件管理器，所有文件由一个后台协程轮询
+type Manager struct {
+	interval time.Duration
+
+	mu        sync.Mutex // 串行化注册、检查和重载
+	artifacts map[string]*artifact
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewManager 创建数据文件管理器
+func NewManager(interval time.Duration) *Manager {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Manager{
+		interval:  interval,
+		artifacts: make(map[string]*artifact),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start 启动后台轮询
+func (m *Manager) Start() {
+	go m.run()
+}
+
+// Stop 停止后台轮询
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+}
+
+// Register 注册或替换数据文件并立即加载，加载成功时调用onChange
+// 同名文件已注册时沿用其版本号；加载失败时仍然注册，文件修复后由轮询加载
+func (m *Manager) Register(name, path string, parse Parser, onChange func(value interface{})) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := &artifact{name: name, path: path, parse: parse, onChange: onChange}
+	if old := m.artifacts[name]; old != nil && old.path == path {
+		a.version = old.version
+		a.digest = old.digest
+	}
+	m.artifacts[name] = a
+
+	_, err := a.reload(true)
+	return err
+}
+
+// Unregister 停止监视数据文件
+func (m *Manager) Unregister(name string) {
+	m.mu.Lock()
+	delete(m.artifacts, name)
+	m.mu.Unlock()
+}
+
+// Reload 立即重新读取数据文件，内容未变化时也会重新解析
+func (m *Manager) Reload(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.artifacts[name]
+	if a == nil {
+		return ErrNotFound
+	}
+	_, err := a.reload(true)
+	return err
+}
+
+// Status 获取所有数据文件的加载状态，按名称排序
+func (m *Manager) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Status, 0, len(m.artifacts))
+	for _, a := range m.artifacts {
+		status := Status{
+			Name:      a.name,
+			Path:      a.path,
+			Version:   a.version,
+			Size:      a.state.size,
+			LoadedAt:  a.loadedAt,
+			LastCheck: a.lastCheck,
+		}
+		if a.version > 0 {
+			status.SHA256 = fmt.Sprintf("%x", a.digest)
+		}
+		if a.lastErr != nil {
+			status.LastError = a.lastErr.Error()
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// run 轮询循环
+func (m *Manager) run() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.checkAll()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// checkAll 检查所有数据文件
+func (m *Manager) checkAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, a := range m.artifacts {
+		changed, err := a.check()
+		if err != nil {
+			fmt.Printf("[ARTIFACT] Reloading %s from %s failed, keeping version %d: %v\n", a.name, a.path, a.version, err)
+		} else if changed {
+			fmt.Printf("[ARTIFACT] Reloaded %s from %s (version %d)\n", a.name, a.path, a.version)
+		}
+	}
+}
+
+// check 检查文件是否变化，变化时重新加载
+func (a *artifact) check() (bool, error) {
+	state, err := statFile(a.path)
+	if err != nil {
+		a.lastCheck = time.Now()
+		a.lastErr = err
+		return false, err
+	}
+
+	a.polls++
+	if state == a.state && a.polls%hashEvery != 0 {
+		a.lastCheck = time.Now()
+		return false, nil
+	}
+	return a.reload(false)
+}
+
+// reload 读取并解析文件，force为false时内容哈希未变化则跳过
+func (a *artifact) reload(force bool) (bool, error) {
+	a.lastCheck = time.Now()
+
+	// 先记录文件状态再读取内容，读取期间发生的变化会在下一次轮询被发现
+	state, err := statFile(a.path)
+	if err != nil {
+		a.lastErr = err
+		return false, err
+	}
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		a.lastErr = err
+		return false, err
+	}
+
+	digest := sha256.Sum256(data)
+	if !force && digest == a.digest && a.version > 0 {
+		a.state = state
+		a.lastErr = nil
+		return false, nil
+	}
+
+	// 校验失败的内容在文件再次变化（或强制比较哈希）前不再重试
+	value, err := a.parse(data)
+	if err != nil {
+		a.state = state
+		a.lastErr = err
+		return false, err
+	}
+
+	changed := digest != a.digest || a.version == 0
+	if changed {
+		a.version++
+	}
+	a.state = state
+	a.digest = digest
+	a.loadedAt = time.Now()
+	a.lastErr = nil
+	if a.onChange != nil {
+		a.onChange(value)
+	}
+	return changed, nil
+}
+
+// statFile 获取文件状态（跟随符号链接）
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// logInterval 同一检查项的审计日志最多每分钟输出一条
+const logInterval = time.Minute
+
+// 进程级的审计开关和计数，热路径在未启用时只有一次原子读
+var (
+	enabled   atomic.Bool
+	sweeps    atomic.Int64
+	lastSweep atomic.Int64 // UnixNano
+
+	violations sync.Map // 检查项 -> *record
+	fallbacks  sync.Map // 回退路径 -> *record
+)
+
+func init() {
+	enabled.Store(buildEnabled)
+}
+
+// record 一个检查项或回退路径的计数和最近一次的详情
+type record struct {
+	count atomic.Int64
+
+	mu       sync.Mutex
+	last     string
+	lastAt   time.Time
+	loggedAt time.Time
+}
+
+// Record 检查项或回退路径的统计
+type Record struct {
+	Count  int64     `json:"count"`
+	Last   string    `json:"last"`
+	LastAt time.Time `json:"last_at"`
+}
+
+// Stats 审计模式的状态和统计
+type Stats struct {
+	Enabled    bool              `json:"enabled"`
+	BuildTag   bool              `json:"build_tag"`            // 是否使用speedmimi_audit构建标签编译
+	Sweeps     int64             `json:"sweeps"`               // 已完成的不变量巡检次数
+	LastSweep  *time.Time        `json:"last_sweep,omitempty"` // 最近一次巡检的时间
+	Violations map[string]Record `json:"violations"`           // 违反的不变量，按检查项统计
+	Fallbacks  map[string]Record `json:"fallbacks"`            // 走到不安全回退路径的次数，按回退路径统计
+}
+
+// Enabled 审计模式是否启用
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SetEnabled 运行时开关审计模式，使用构建标签编译时不能关闭
+func SetEnabled(on bool) {
+	enabled.Store(on || buildEnabled)
+}
+
+// Violation 记录一次违反不变量，未启用审计时忽略
+func Violation(check, format string, args ...interface{}) {
+	if Enabled() {
+		observe(&violations, "VIOLATION", check, format, args)
+	}
+}
+
+// Fallback 记录一次不安全回退（如就地修改被并发读取的结构），未启用审计时忽略
+func Fallback(path, format string, args ...interface{}) {
+	if Enabled() {
+		observe(&fallbacks, "FALLBACK", path, format, args)
+	}
+}
+
+// observe 计数并保存详情，同一检查项的日志按logInterval限流
+func observe(records *sync.Map, kind, name, format string, args []interface{}) {
+	value, _ := records.LoadOrStore(name, &record{})
+	r := value.(*record)
+	r.count.Add(1)
+
+	detail := fmt.Sprintf(format, args...)
+	now := time.Now()
+	r.mu.Lock()
+	r.last = detail
+	r.lastAt = now
+	shouldLog := now.Sub(r.loggedAt) >= logInterval
+	if shouldLog {
+		r.loggedAt = now
+	}
+	r.mu.Unlock()
+
+	if shouldLog {
+		fmt.Printf("[AUDIT] %s %s: %s\n", kind, name, detail)
+	}
+}
+
+// SweepDone 记录完成一次不变量巡检
+func SweepDone() {
+	sweeps.Add(1)
+	lastSweep.Store(time.Now().UnixNano())
+}
+
+// Snapshot 获取审计模式的状态和统计
+// 后端连接数减到负数时由types.Backend直接计数，不依赖审计是否启用
+func Snapshot() Stats {
+	stats := Stats{
+		Enabled:    Enabled(),
+		BuildTag:   buildEnabled,
+		Sweeps:     sweeps.Load(),
+		Violations: collect(&violations),
+		Fallbacks:  collect(&fallbacks),
+	}
+	if n := lastSweep.Load(); n > 0 {
+		at := time.Unix(0, n)
+		stats.LastSweep = &at
+	}
+	if n := types.ConnectionUnderflows(); n > 0 {
+		stats.Fallbacks["connection_underflow"] = Record{Count: n, Last: "DecConnections called with no open connections"}
+	}
+	return stats
+}
+
+// collect 复制全部记录
+func collect(records *sync.Map) map[string]Record {
+	result := make(map[string]Record)
+	records.Range(func(key, value interface{}) bool {
+		r := value.(*record)
+		r.mu.Lock()
+		result[key.(string)] = Record{Count: r.count.Load(), Last: r.last, LastAt: r.lastAt}
+		r.mu.Unlock()
+		return true
+	})
+	return result
+}
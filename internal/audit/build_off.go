@@ -0,0 +1,6 @@
+//go:build !speedmimi_audit
+
+package audit
+
+// buildEnabled 默认构建只在配置audit.enabled时启用审计
+const buildEnabled = false
@@ -0,0 +1,6 @@
+//go:build speedmimi_audit
+
+package audit
+
+// buildEnabled 使用speedmimi_audit构建标签编译时始终启用审计，不能通过配置关闭
+const buildEnabled = true
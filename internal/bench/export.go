@@ -0,0 +1,122 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader CSV导出的列，每个阶段一行
+var csvHeader = []string{
+	"tool", "commit", "timestamp", "target", "name", "concurrency", "duration_seconds",
+	"requests", "succeeded", "failed", "success_rate", "rps", "bytes_received",
+	"latency_min_ms", "latency_mean_ms", "latency_max_ms",
+	"latency_p50_ms", "latency_p90_ms", "latency_p99_ms", "latency_p999_ms",
+	"errors",
+}
+
+// WriteJSON 以JSON格式写出报告
+func WriteJSON(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteCSV 以CSV格式写出报告，header为false时不写表头（追加到已有文件）
+// errors列为按类别名排序的 类别=次数 列表，以分号分隔
+func WriteCSV(w io.Writer, report *Report, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range report.Results {
+		row := []string{
+			report.Tool,
+			report.Commit,
+			report.Timestamp.Format(time.RFC3339),
+			report.Target,
+			r.Name,
+			strconv.Itoa(r.Concurrency),
+			formatFloat(r.Duration),
+			strconv.FormatInt(r.Requests, 10),
+			strconv.FormatInt(r.Succeeded, 10),
+			strconv.FormatInt(r.Failed, 10),
+			formatFloat(r.SuccessRate),
+			formatFloat(r.RPS),
+			strconv.FormatInt(r.BytesReceived, 10),
+			formatFloat(r.Latency.Min),
+			formatFloat(r.Latency.Mean),
+			formatFloat(r.Latency.Max),
+			formatFloat(r.Latency.P50),
+			formatFloat(r.Latency.P90),
+			formatFloat(r.Latency.P99),
+			formatFloat(r.Latency.P999),
+			formatErrors(r.Errors),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFile 按扩展名把报告写入文件：.csv追加到已有文件末尾（文件为空时写表头），便于累积多次提交的结果；其他扩展名写JSON并覆盖
+func WriteFile(path string, report *Report) error {
+	if strings.ToLower(filepath.Ext(path)) != ".csv" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := WriteJSON(f, report); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := WriteCSV(f, report, info.Size() == 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// formatFloat 保留三位小数
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+// formatErrors 把错误分类统计格式化为 类别=次数;类别=次数
+func formatErrors(errors map[string]int64) string {
+	classes := make([]string, 0, len(errors))
+	for class := range errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%s=%d", class, errors[class])
+	}
+	return strings.Join(parts, ";")
+}
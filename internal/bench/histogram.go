@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// HDR直方图参数：每个2的幂区间分为2048个子桶，记录值的相对误差不超过1/1024（约3位有效数字）
+// 可记录1ns到约1.2小时（2^42ns）的延迟，超过上限的值计入最后一个桶，最大值仍精确记录
+const (
+	subBucketHalfCountMagnitude = 10
+	subBucketHalfCount          = 1 << subBucketHalfCountMagnitude
+	subBucketCount              = 2 * subBucketHalfCount
+	subBucketMask               = subBucketCount - 1
+	histogramMaxMagnitude       = 42
+	bucketCount                 = histogramMaxMagnitude - subBucketHalfCountMagnitude
+	countsLen                   = (bucketCount + 1) * subBucketHalfCount
+)
+
+// Histogram 并发安全的HDR（高动态范围）延迟直方图
+// 以固定内存记录任意数量的样本，百分位数的相对误差不超过0.1%，Record无锁
+type Histogram struct {
+	counts [countsLen]atomic.Int64
+	total  atomic.Int64
+	sum    atomic.Int64
+	min    atomic.Int64
+	max    atomic.Int64
+}
+
+// NewHistogram 创建延迟直方图
+func NewHistogram() *Histogram {
+	h := &Histogram{}
+	h.min.Store(math.MaxInt64)
+	return h
+}
+
+// Record 记录一个延迟样本，负值按0记录
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+
+	h.counts[countsIndex(v)].Add(1)
+	h.total.Add(1)
+	h.sum.Add(v)
+	for {
+		cur := h.min.Load()
+		if v >= cur || h.min.CompareAndSwap(cur, v) {
+			break
+		}
+	}
+	for {
+		cur := h.max.Load()
+		if v <= cur || h.max.CompareAndSwap(cur, v) {
+			break
+		}
+	}
+}
+
+// Count 样本数
+func (h *Histogram) Count() int64 {
+	return h.total.Load()
+}
+
+// Min 最小值，没有样本时为0
+func (h *Histogram) Min() time.Duration {
+	if h.Count() == 0 {
+		return 0
+	}
+	return time.Duration(h.min.Load())
+}
+
+// Max 最大值
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max.Load())
+}
+
+// Mean 平均值，没有样本时为0
+func (h *Histogram) Mean() time.Duration {
+	n := h.Count()
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(h.sum.Load() / n)
+}
+
+// ValueAtPercentile 百分位数（p为0-100），返回样本所在桶的上界，不超过记录到的最大值
+func (h *Histogram) ValueAtPercentile(p float64) time.Duration {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+	if p > 100 {
+		p = 100
+	}
+
+	// 第target个样本（从1开始）所在的桶
+	target := int64(math.Ceil(p * float64(total) / 100))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen int64
+	for i := range h.counts {
+		seen += h.counts[i].Load()
+		if seen >= target {
+			v := highestEquivalentValue(i)
+			if max := h.max.Load(); v > max {
+				v = max
+			}
+			return time.Duration(v)
+		}
+	}
+	return h.Max()
+}
+
+// countsIndex 值所在的计数桶下标
+func countsIndex(v int64) int {
+	bucket := 64 - bits.LeadingZeros64(uint64(v)|subBucketMask) - (subBucketHalfCountMagnitude + 1)
+	if bucket > bucketCount-1 {
+		return countsLen - 1
+	}
+	subBucket := int(v >> uint(bucket))
+	return (bucket+1)<<subBucketHalfCountMagnitude + (subBucket - subBucketHalfCount)
+}
+
+// highestEquivalentValue 计数桶能表示的最大值
+func highestEquivalentValue(index int) int64 {
+	bucket := index>>subBucketHalfCountMagnitude - 1
+	subBucket := index&(subBucketHalfCount-1) + subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= subBucketHalfCount
+		bucket = 0
+	}
+	lowest := int64(subBucket) << uint(bucket)
+	return lowest + int64(1)<<uint(bucket) - 1
+}
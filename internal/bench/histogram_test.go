@@ -0,0 +1,43 @@
+package bench
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestHistogramPercentiles 与排序后精确计算的百分位数对比，相对误差不超过0.1%
+func TestHistogramPercentiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	h := NewHistogram()
+
+	samples := make([]time.Duration, 200000)
+	for i := range samples {
+		// 指数分布的毫秒级延迟，并带有少量秒级长尾
+		d := time.Duration(rng.ExpFloat64() * float64(2*time.Millisecond))
+		if i%1000 == 0 {
+			d += time.Duration(rng.Int63n(int64(3 * time.Second)))
+		}
+		samples[i] = d
+		h.Record(d)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	if h.Count() != int64(len(samples)) {
+		t.Fatalf("count = %d, want %d", h.Count(), len(samples))
+	}
+	if h.Min() != samples[0] || h.Max() != samples[len(samples)-1] {
+		t.Fatalf("min/max = %v/%v, want %v/%v", h.Min(), h.Max(), samples[0], samples[len(samples)-1])
+	}
+
+	for _, p := range []float64{50, 90, 99, 99.9, 100} {
+		rank := int(math.Ceil(p*float64(len(samples))/100)) - 1
+		want := samples[rank]
+		got := h.ValueAtPercentile(p)
+		if diff := float64(got - want); diff < 0 || diff > float64(want)/1000+1 {
+			t.Errorf("p%v = %v, want %v (within 0.1%%)", p, got, want)
+		}
+	}
+}
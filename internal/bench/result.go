@@ -0,0 +1,187 @@
+// Package bench 压测工具共用的结果统计和导出（JSON/CSV），用于在不同提交之间跟踪性能回退
+package bench
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Report 一次压测运行的全部结果
+type Report struct {
+	Tool      string    `json:"tool"`             // 压测工具名称
+	Commit    string    `json:"commit,omitempty"` // 被测代码的提交
+	Target    string    `json:"target"`           // 压测的URL
+	Timestamp time.Time `json:"timestamp"`
+	GoVersion string    `json:"go_version"`
+	NumCPU    int       `json:"num_cpu"`
+	Results   []Result  `json:"results"` // 每个阶段一项
+}
+
+// Result 单个压测阶段的结果
+type Result struct {
+	Name          string           `json:"name"`
+	Concurrency   int              `json:"concurrency"`
+	Duration      float64          `json:"duration_seconds"`
+	Requests      int64            `json:"requests"`       // 完成的请求数（成功+失败）
+	Succeeded     int64            `json:"succeeded"`      // 收到2xx/3xx响应的请求数
+	Failed        int64            `json:"failed"`         // 连接错误或4xx/5xx响应的请求数
+	SuccessRate   float64          `json:"success_rate"`   // 百分比
+	RPS           float64          `json:"rps"`            // 每秒成功的请求数
+	BytesReceived int64            `json:"bytes_received"` // 成功请求的响应体字节数
+	Latency       LatencyStats     `json:"latency_ms"`     // 成功请求的延迟，毫秒
+	Errors        map[string]int64 `json:"errors"`         // 按错误类别统计的失败请求数
+}
+
+// LatencyStats 延迟统计，单位毫秒
+type LatencyStats struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	Max  float64 `json:"max"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+}
+
+// 失败请求的错误类别
+const (
+	ErrTimeout   = "timeout"
+	ErrRefused   = "connection_refused"
+	ErrReset     = "connection_reset"
+	ErrEOF       = "eof"
+	ErrOther     = "other"
+	ErrStatus4xx = "status_4xx"
+	ErrStatus5xx = "status_5xx"
+)
+
+// Recorder 并发安全的压测结果记录器，压测协程每完成一个请求调用一次Record
+type Recorder struct {
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	bytes     atomic.Int64
+
+	latency *Histogram
+
+	mu     sync.Mutex
+	errors map[string]int64
+}
+
+// NewRecorder 创建结果记录器
+func NewRecorder() *Recorder {
+	return &Recorder{
+		latency: NewHistogram(),
+		errors:  make(map[string]int64),
+	}
+}
+
+// Record 记录一个完成的请求：err不为nil时按错误类别计为失败，否则按状态码判断成功或失败
+func (r *Recorder) Record(latency time.Duration, status int, bytes int64, err error) {
+	class := ""
+	switch {
+	case err != nil:
+		class = ClassifyError(err)
+	case status >= 500:
+		class = ErrStatus5xx
+	case status >= 400:
+		class = ErrStatus4xx
+	}
+
+	if class != "" {
+		r.failed.Add(1)
+		r.mu.Lock()
+		r.errors[class]++
+		r.mu.Unlock()
+		return
+	}
+
+	r.succeeded.Add(1)
+	r.bytes.Add(bytes)
+	r.latency.Record(latency)
+}
+
+// Counts 当前成功和失败的请求数，用于打印进度
+func (r *Recorder) Counts() (succeeded, failed int64) {
+	return r.succeeded.Load(), r.failed.Load()
+}
+
+// Result 汇总为阶段结果，elapsed为阶段的实际用时
+func (r *Recorder) Result(name string, concurrency int, elapsed time.Duration) Result {
+	result := Result{
+		Name:          name,
+		Concurrency:   concurrency,
+		Duration:      elapsed.Seconds(),
+		Succeeded:     r.succeeded.Load(),
+		Failed:        r.failed.Load(),
+		BytesReceived: r.bytes.Load(),
+		Errors:        make(map[string]int64),
+	}
+	result.Requests = result.Succeeded + result.Failed
+	if result.Requests > 0 {
+		result.SuccessRate = float64(result.Succeeded) / float64(result.Requests) * 100
+	}
+	if elapsed > 0 {
+		result.RPS = float64(result.Succeeded) / elapsed.Seconds()
+	}
+
+	r.mu.Lock()
+	for class, n := range r.errors {
+		result.Errors[class] = n
+	}
+	r.mu.Unlock()
+
+	result.Latency = LatencyStats{
+		Min:  ms(r.latency.Min()),
+		Mean: ms(r.latency.Mean()),
+		Max:  ms(r.latency.Max()),
+		P50:  ms(r.latency.ValueAtPercentile(50)),
+		P90:  ms(r.latency.ValueAtPercentile(90)),
+		P99:  ms(r.latency.ValueAtPercentile(99)),
+		P999: ms(r.latency.ValueAtPercentile(99.9)),
+	}
+	return result
+}
+
+// Latency 成功请求的延迟直方图
+func (r *Recorder) Latency() *Histogram {
+	return r.latency
+}
+
+// ms 转换为毫秒
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ClassifyError 把请求错误归类为错误类别
+func ClassifyError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrReset
+	case strings.HasSuffix(err.Error(), "EOF"):
+		return ErrEOF
+	}
+	return ErrOther
+}
+
+// NewReport 创建压测报告，填写运行环境
+func NewReport(tool, commit, target string) *Report {
+	return &Report{
+		Tool:      tool,
+		Commit:    commit,
+		Target:    target,
+		Timestamp: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		NumCPU:    runtime.NumCPU(),
+	}
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 默认容量
+const (
+	DefaultMaxSize      = 256 << 20 // 256MB
+	DefaultMaxEntrySize = 1 << 20   // 1MB
+)
+
+// Entry 缓存的响应，插入后不再修改，重新验证时以新条目替换
+type Entry struct {
+	Key          string
+	Host         string
+	Path         string
+	Status       int
+	Header       *fasthttp.ResponseHeader
+	Body         []byte
+	StoredAt     time.Time     // 收到响应（或重新验证）的时间
+	Lifetime     time.Duration // 新鲜期
+	InitialAge   time.Duration // 上游响应的Age
+	ETag         []byte
+	LastModified []byte
+
+	size int64
+	elem *list.Element
+}
+
+// Fresh 判断条目在now时是否仍然新鲜
+func (e *Entry) Fresh(now time.Time) bool {
+	return e.Age(now) < e.Lifetime
+}
+
+// Age 条目的当前年龄
+func (e *Entry) Age(now time.Time) time.Duration {
+	return e.InitialAge + now.Sub(e.StoredAt)
+}
+
+// HasValidators 是否可以通过条件请求重新验证
+func (e *Entry) HasValidators() bool {
+	return len(e.ETag) > 0 || len(e.LastModified) > 0
+}
+
+// Stats 缓存统计
+type Stats struct {
+	Entries      int   `json:"entries"`
+	Size         int64 `json:"size"`
+	MaxSize      int64 `json:"max_size"`
+	MaxEntrySize int64 `json:"max_entry_size"`
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	Revalidated  int64 `json:"revalidated"`  // 上游返回304后继续使用的次数
+	NotModified  int64 `json:"not_modified"` // 向客户端返回304的次数
+	Stores       int64 `json:"stores"`
+	Evictions    int64 `json:"evictions"`
+	NegativeHits int64 `json:"negative_hits"` // 命中否定响应（如404/410）的次数，包含在hits中
+}
+
+// Cache 内存响应缓存（LRU，按总字节数限制容量）
+type Cache struct {
+	maxSize      int64
+	maxEntrySize int64
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+	lru     *list.List // 最近使用的在前
+	size    int64
+
+	hits         atomic.Int64
+	misses       atomic.Int64
+	revalidated  atomic.Int64
+	notModified  atomic.Int64
+	stores       atomic.Int64
+	evictions    atomic.Int64
+	negativeHits atomic.Int64
+}
+
+// New 创建响应缓存
+func New(maxSize, maxEntrySize int64) *Cache {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxEntrySize <= 0 {
+		maxEntrySize = DefaultMaxEntrySize
+	}
+	return &Cache{
+		maxSize:      maxSize,
+		maxEntrySize: maxEntrySize,
+		entries:      make(map[string]*Entry),
+		lru:          list.New(),
+	}
+}
+
+// Resize 调整容量，超出新容量的条目被淘汰
+func (c *Cache) Resize(maxSize, maxEntrySize int64) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxEntrySize <= 0 {
+		maxEntrySize = DefaultMaxEntrySize
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxSize = maxSize
+	c.maxEntrySize = maxEntrySize
+	c.evictLocked()
+}
+
+// MaxEntrySize 单个条目的最大字节数
+func (c *Cache) MaxEntrySize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxEntrySize
+}
+
+// Get 查找条目
+func (c *Cache) Get(key string) *Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entries[key]
+	if e != nil {
+		c.lru.MoveToFront(e.elem)
+	}
+	return e
+}
+
+// Put 插入或替换条目，超过单条目上限时不缓存
+func (c *Cache) Put(e *Entry) bool {
+	e.size = int64(len(e.Key) + len(e.Body) + len(e.Header.Header()))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e.size > c.maxEntrySize || e.size > c.maxSize {
+		return false
+	}
+
+	if old := c.entries[e.Key]; old != nil {
+		c.removeLocked(old)
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[e.Key] = e
+	c.size += e.size
+	c.stores.Add(1)
+	c.evictLocked()
+	return true
+}
+
+// Delete 删除条目
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.entries[key]; e != nil {
+		c.removeLocked(e)
+	}
+}
+
+// Purge 删除匹配的条目，host为空时匹配所有主机，pathPrefix为空时匹配所有路径
+func (c *Cache) Purge(host, pathPrefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := 0
+	for _, e := range c.entries {
+		if host != "" && !strings.EqualFold(e.Host, host) {
+			continue
+		}
+		if !strings.HasPrefix(e.Path, pathPrefix) {
+			continue
+		}
+		c.removeLocked(e)
+		purged++
+	}
+	return purged
+}
+
+// RecordHit 记录命中
+func (c *Cache) RecordHit() { c.hits.Add(1) }
+
+// RecordNegativeHit 记录命中否定响应
+func (c *Cache) RecordNegativeHit() { c.negativeHits.Add(1) }
+
+// RecordMiss 记录未命中
+func (c *Cache) RecordMiss() { c.misses.Add(1) }
+
+// RecordRevalidated 记录上游304后继续使用缓存
+func (c *Cache) RecordRevalidated() { c.revalidated.Add(1) }
+
+// RecordNotModified 记录向客户端返回304
+func (c *Cache) RecordNotModified() { c.notModified.Add(1) }
+
+// Stats 获取统计
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries, size, maxSize, maxEntrySize := len(c.entries), c.size, c.maxSize, c.maxEntrySize
+	c.mu.Unlock()
+
+	return Stats{
+		Entries:      entries,
+		Size:         size,
+		MaxSize:      maxSize,
+		MaxEntrySize: maxEntrySize,
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Revalidated:  c.revalidated.Load(),
+		NotModified:  c.notModified.Load(),
+		Stores:       c.stores.Load(),
+		Evictions:    c.evictions.Load(),
+		NegativeHits: c.negativeHits.Load(),
+	}
+}
+
+// evictLocked 淘汰最久未使用的条目直到不超过容量
+func (c *Cache) evictLocked() {
+	for c.size > c.maxSize {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		c.removeLocked(back.Value.(*Entry))
+		c.evictions.Add(1)
+	}
+}
+
+// removeLocked 删除条目
+func (c *Cache) removeLocked(e *Entry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.Key)
+	c.size -= e.size
+}
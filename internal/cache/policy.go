@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Directives Cache-Control指令
+type Directives struct {
+	NoStore        bool
+	NoCache        bool
+	Private        bool
+	MustRevalidate bool
+	MaxAge         int // -1表示未指定
+	SMaxAge        int // -1表示未指定
+}
+
+// ParseCacheControl 解析Cache-Control头
+func ParseCacheControl(value []byte) Directives {
+	d := Directives{MaxAge: -1, SMaxAge: -1}
+
+	for len(value) > 0 {
+		var token []byte
+		if i := bytes.IndexByte(value, ','); i >= 0 {
+			token, value = value[:i], value[i+1:]
+		} else {
+			token, value = value, nil
+		}
+		token = bytes.TrimSpace(token)
+
+		name, arg := token, []byte(nil)
+		if i := bytes.IndexByte(token, '='); i >= 0 {
+			name, arg = bytes.TrimSpace(token[:i]), bytes.Trim(bytes.TrimSpace(token[i+1:]), `"`)
+		}
+
+		switch string(bytes.ToLower(name)) {
+		case "no-store":
+			d.NoStore = true
+		case "no-cache":
+			d.NoCache = true
+		case "private":
+			d.Private = true
+		case "must-revalidate", "proxy-revalidate":
+			d.MustRevalidate = true
+		case "max-age":
+			d.MaxAge = parseSeconds(arg)
+		case "s-maxage":
+			d.SMaxAge = parseSeconds(arg)
+		}
+	}
+
+	return d
+}
+
+// parseSeconds 解析秒数，无效时返回-1
+func parseSeconds(b []byte) int {
+	n, err := strconv.Atoi(string(b))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// Freshness 根据Cache-Control指令和Expires、Date头计算响应的新鲜期，
+// fallback为响应没有显式缓存时间时使用的值；返回值为0表示需要每次验证
+func Freshness(d Directives, expires, date []byte, fallback time.Duration, now time.Time) time.Duration {
+	switch {
+	case d.NoCache:
+		return 0
+	case d.SMaxAge >= 0:
+		return time.Duration(d.SMaxAge) * time.Second
+	case d.MaxAge >= 0:
+		return time.Duration(d.MaxAge) * time.Second
+	}
+
+	if len(expires) > 0 {
+		t, err := fasthttp.ParseHTTPDate(expires)
+		if err != nil {
+			return 0 // 无效的Expires表示已过期
+		}
+		base := now
+		if len(date) > 0 {
+			if dt, err := fasthttp.ParseHTTPDate(date); err == nil {
+				base = dt
+			}
+		}
+		if t.After(base) {
+			return t.Sub(base)
+		}
+		return 0
+	}
+
+	return fallback
+}
+
+// NotModified 根据客户端的条件请求头（If-None-Match、If-Modified-Since）判断是否可以返回304
+// If-None-Match优先，存在时忽略If-Modified-Since
+func NotModified(ifNoneMatch, ifModifiedSince, etag, lastModified []byte) bool {
+	if len(ifNoneMatch) > 0 {
+		return len(etag) > 0 && etagMatches(ifNoneMatch, etag)
+	}
+
+	if len(ifModifiedSince) > 0 && len(lastModified) > 0 {
+		since, err := fasthttp.ParseHTTPDate(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		modified, err := fasthttp.ParseHTTPDate(lastModified)
+		if err != nil {
+			return false
+		}
+		return !modified.After(since)
+	}
+
+	return false
+}
+
+// etagMatches 弱比较：If-None-Match列表中任一ETag与响应ETag相同（忽略W/前缀），或为*
+func etagMatches(list, etag []byte) bool {
+	etag = bytes.TrimPrefix(etag, []byte("W/"))
+	for len(list) > 0 {
+		var tag []byte
+		if i := bytes.IndexByte(list, ','); i >= 0 {
+			tag, list = list[:i], list[i+1:]
+		} else {
+			tag, list = list, nil
+		}
+		tag = bytes.TrimSpace(tag)
+		if string(tag) == "*" || bytes.Equal(bytes.TrimPrefix(tag, []byte("W/")), etag) {
+			return true
+		}
+	}
+	return false
+}
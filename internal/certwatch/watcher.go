@@ -0,0 +1,261 @@
+package certwatch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval 默认轮询间隔
+const DefaultInterval = 30 * time.Second
+
+// hashEvery 每隔多少次轮询强制比较一次文件内容哈希
+// 用于捕获保留了修改时间和大小的替换（例如某些卷挂载的原子替换）
+const hashEvery = 10
+
+// Status 证书加载状态
+type Status struct {
+	CertFile  string    `json:"cert_file"`
+	KeyFile   string    `json:"key_file"`
+	Interval  string    `json:"interval"`
+	Subject   string    `json:"subject,omitempty"`
+	NotAfter  time.Time `json:"not_after"`
+	SHA256    string    `json:"sha256"`
+	LoadedAt  time.Time `json:"loaded_at"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// fileState 文件的修改时间和大小
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher 基于轮询的证书热加载器，不依赖inotify/fsnotify
+// 通过修改时间、大小和内容哈希检测证书/私钥变化，适用于Kubernetes挂载Secret等
+// 文件事件不可靠的场景；新证书解析失败时继续使用旧证书
+type Watcher struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu        sync.Mutex // 串行化检查和重载
+	certState fileState
+	keyState  fileState
+	digest    [sha256.Size]byte
+	polls     int
+	loadedAt  time.Time
+	lastCheck time.Time
+	lastErr   error
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// New 创建证书监视器并立即加载一次证书
+func New(certFile, keyFile string, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	w := &Watcher{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+
+	if _, err := w.reload(true); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Start 启动后台轮询
+func (w *Watcher) Start() {
+	go w.run()
+}
+
+// Stop 停止后台轮询
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+}
+
+// Matches 判断监视器是否对应给定的文件和间隔
+func (w *Watcher) Matches(certFile, keyFile string, interval time.Duration) bool {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return w.certFile == certFile && w.keyFile == keyFile && w.interval == interval
+}
+
+// GetCertificate 返回当前证书，用于tls.Config.GetCertificate
+func (w *Watcher) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := w.cert.Load()
+	if cert == nil {
+		return nil, fmt.Errorf("no certificate loaded")
+	}
+	return cert, nil
+}
+
+// Reload 立即重新读取证书文件，内容未变化时也会重新解析
+func (w *Watcher) Reload() error {
+	_, err := w.reload(true)
+	return err
+}
+
+// Status 获取当前证书状态
+func (w *Watcher) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := Status{
+		CertFile:  w.certFile,
+		KeyFile:   w.keyFile,
+		Interval:  w.interval.String(),
+		SHA256:    fmt.Sprintf("%x", w.digest),
+		LoadedAt:  w.loadedAt,
+		LastCheck: w.lastCheck,
+	}
+	if cert := w.cert.Load(); cert != nil && cert.Leaf != nil {
+		status.Subject = cert.Leaf.Subject.String()
+		status.NotAfter = cert.Leaf.NotAfter
+	}
+	if w.lastErr != nil {
+		status.LastError = w.lastErr.Error()
+	}
+	return status
+}
+
+// run 轮询循环
+func (w *Watcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := w.check()
+			if err != nil {
+				fmt.Printf("[TLS] Certificate reload failed, keeping previous certificate: %v\n", err)
+			} else if changed {
+				fmt.Printf("[TLS] Certificate reloaded from %s\n", w.certFile)
+			}
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// check 检查文件是否变化，变化时重新加载
+func (w *Watcher) check() (bool, error) {
+	certState, err := statFile(w.certFile)
+	if err != nil {
+		w.recordError(err)
+		return false, err
+	}
+	keyState, err := statFile(w.keyFile)
+	if err != nil {
+		w.recordError(err)
+		return false, err
+	}
+
+	w.mu.Lock()
+	w.polls++
+	unchanged := certState == w.certState && keyState == w.keyState && w.polls%hashEvery != 0
+	if unchanged {
+		w.lastCheck = time.Now()
+		w.mu.Unlock()
+		return false, nil
+	}
+	w.mu.Unlock()
+
+	return w.reload(false)
+}
+
+// reload 读取并解析证书，force为false时内容哈希未变化则跳过
+func (w *Watcher) reload(force bool) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastCheck = time.Now()
+
+	// 先记录文件状态再读取内容，读取期间发生的变化会在下一次轮询被发现
+	certState, err := statFile(w.certFile)
+	if err != nil {
+		w.lastErr = err
+		return false, err
+	}
+	keyState, err := statFile(w.keyFile)
+	if err != nil {
+		w.lastErr = err
+		return false, err
+	}
+
+	certPEM, err := os.ReadFile(w.certFile)
+	if err != nil {
+		w.lastErr = fmt.Errorf("failed to read cert file: %w", err)
+		return false, w.lastErr
+	}
+	keyPEM, err := os.ReadFile(w.keyFile)
+	if err != nil {
+		w.lastErr = fmt.Errorf("failed to read key file: %w", err)
+		return false, w.lastErr
+	}
+
+	digest := sha256.Sum256(bytes.Join([][]byte{certPEM, keyPEM}, []byte{0}))
+	if !force && digest == w.digest {
+		w.certState = certState
+		w.keyState = keyState
+		w.lastErr = nil
+		return false, nil
+	}
+
+	// 证书和私钥可能不是同时更新的，解析失败时保留旧证书并在下次轮询重试
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		w.lastErr = fmt.Errorf("failed to load TLS cert: %w", err)
+		return false, w.lastErr
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+			cert.Leaf = leaf
+		}
+	}
+
+	w.cert.Store(&cert)
+	w.certState = certState
+	w.keyState = keyState
+	w.digest = digest
+	w.loadedAt = time.Now()
+	w.lastErr = nil
+	return true, nil
+}
+
+// recordError 记录检查错误
+func (w *Watcher) recordError(err error) {
+	w.mu.Lock()
+	w.lastCheck = time.Now()
+	w.lastErr = err
+	w.mu.Unlock()
+}
+
+// statFile 获取文件状态（跟随符号链接）
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
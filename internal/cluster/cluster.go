@@ -0,0 +1,332 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/proxy"
+)
+
+// LocalPath 节点本地快照的管理API路径
+const LocalPath = "/api/v1/cluster/local"
+
+// BackendSnapshot 后端状态快照
+type BackendSnapshot struct {
+	ID            string `json:"id"`
+	Address       string `json:"address"`
+	Active        bool   `json:"active"`
+	Disconnecting bool   `json:"disconnecting"`
+	Connections   int64  `json:"connections"`
+}
+
+// NodeSnapshot 单个实例的状态快照
+type NodeSnapshot struct {
+	Node      string                       `json:"node"`
+	Timestamp int64                        `json:"timestamp"`
+	Traffic   monitor.TrafficStats         `json:"traffic"`
+	Upstreams map[string][]BackendSnapshot `json:"upstreams"`
+}
+
+// NodeStatus 集群中节点的可达状态
+type NodeStatus struct {
+	Node      string               `json:"node"`
+	URL       string               `json:"url,omitempty"`
+	Local     bool                 `json:"local"`
+	Reachable bool                 `json:"reachable"`
+	LastSeen  time.Time            `json:"last_seen"`
+	Error     string               `json:"error,omitempty"`
+	Traffic   monitor.TrafficStats `json:"traffic"`
+}
+
+// AggregatedBackend 跨节点聚合后的后端状态
+type AggregatedBackend struct {
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	ActiveNodes int    `json:"active_nodes"` // 认为该后端可用的节点数
+	TotalNodes  int    `json:"total_nodes"`  // 报告了该后端的节点数
+	Connections int64  `json:"connections"`  // 所有节点上的连接数之和
+}
+
+// View 集群聚合视图
+type View struct {
+	Nodes     []NodeStatus                    `json:"nodes"`
+	Traffic   monitor.TrafficStats            `json:"traffic"`
+	Upstreams map[string][]*AggregatedBackend `json:"upstreams"`
+}
+
+// peerState 对端节点的最近一次轮询结果
+type peerState struct {
+	url      string
+	snapshot *NodeSnapshot
+	lastSeen time.Time
+	err      string
+}
+
+// Aggregator 集群视图聚合器，定期轮询对端节点的本地快照
+type Aggregator struct {
+	configMgr   *config.Manager
+	proxyServer *proxy.Server
+	monitor     *monitor.PerformanceMonitor
+	client      *http.Client
+
+	mu    sync.RWMutex
+	peers map[string]*peerState
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewAggregator 创建集群视图聚合器
+func NewAggregator(configMgr *config.Manager, proxyServer *proxy.Server, perfMonitor *monitor.PerformanceMonitor) *Aggregator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Aggregator{
+		configMgr:   configMgr,
+		proxyServer: proxyServer,
+		monitor:     perfMonitor,
+		client:      &http.Client{},
+		peers:       make(map[string]*peerState),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start 启动轮询循环，配置了ca_file时用它验证对端管理API的证书
+func (a *Aggregator) Start() error {
+	if caFile := a.configMgr.GetConfig().Cluster.CAFile; caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read cluster CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in cluster CA file %s", caFile)
+		}
+		a.client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	}
+	go a.pollLoop()
+	return nil
+}
+
+// Stop 停止轮询循环
+func (a *Aggregator) Stop() {
+	a.cancel()
+}
+
+// LocalSnapshot 生成本节点的状态快照
+func (a *Aggregator) LocalSnapshot() *NodeSnapshot {
+	cfg := a.configMgr.GetConfig()
+	snapshot := &NodeSnapshot{
+		Node:      cfg.Cluster.NodeName,
+		Timestamp: time.Now().Unix(),
+		Upstreams: make(map[string][]BackendSnapshot),
+	}
+	if a.monitor != nil {
+		snapshot.Traffic = a.monitor.GetTrafficStats()
+	}
+
+	upstreamMgr := a.proxyServer.GetUpstreamManager()
+	for name := range cfg.Backends {
+		upstream := upstreamMgr.GetUpstream(name)
+		if upstream == nil {
+			continue
+		}
+
+		backends := upstream.GetAllBackends()
+		list := make([]BackendSnapshot, 0, len(backends))
+		for _, backend := range backends {
+			list = append(list, BackendSnapshot{
+				ID:            backend.ID,
+				Address:       net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)),
+				Active:        backend.IsActive() && !backend.ShouldDisconnect(),
+				Disconnecting: backend.ShouldDisconnect(),
+				Connections:   backend.GetConnections(),
+			})
+		}
+		snapshot.Upstreams[name] = list
+	}
+
+	return snapshot
+}
+
+// View 合并本节点和对端节点的快照
+// 超过3个轮询周期未成功获取快照的对端节点视为不可达，不参与聚合
+func (a *Aggregator) View() *View {
+	cfg := a.configMgr.GetConfig()
+	staleAfter := 3 * cfg.Cluster.PollInterval
+
+	local := a.LocalSnapshot()
+	view := &View{
+		Nodes: []NodeStatus{{
+			Node:      local.Node,
+			Local:     true,
+			Reachable: true,
+			LastSeen:  time.Now(),
+			Traffic:   local.Traffic,
+		}},
+		Upstreams: make(map[string][]*AggregatedBackend),
+	}
+
+	snapshots := []*NodeSnapshot{local}
+
+	a.mu.RLock()
+	urls := make([]string, 0, len(a.peers))
+	for url := range a.peers {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	for _, url := range urls {
+		peer := a.peers[url]
+		status := NodeStatus{
+			URL:      url,
+			LastSeen: peer.lastSeen,
+			Error:    peer.err,
+		}
+		if peer.snapshot != nil {
+			status.Node = peer.snapshot.Node
+			status.Traffic = peer.snapshot.Traffic
+			status.Reachable = time.Since(peer.lastSeen) <= staleAfter
+			if status.Reachable {
+				snapshots = append(snapshots, peer.snapshot)
+			}
+		}
+		view.Nodes = append(view.Nodes, status)
+	}
+	a.mu.RUnlock()
+
+	for _, snapshot := range snapshots {
+		view.Traffic.TotalRequests += snapshot.Traffic.TotalRequests
+		view.Traffic.ActiveConnections += snapshot.Traffic.ActiveConnections
+		view.Traffic.BytesSent += snapshot.Traffic.BytesSent
+		view.Traffic.BytesRecv += snapshot.Traffic.BytesRecv
+		view.Traffic.Panics += snapshot.Traffic.Panics
+
+		for upstream, backends := range snapshot.Upstreams {
+			for _, backend := range backends {
+				aggregated := findBackend(view.Upstreams[upstream], backend.ID)
+				if aggregated == nil {
+					aggregated = &AggregatedBackend{ID: backend.ID, Address: backend.Address}
+					view.Upstreams[upstream] = append(view.Upstreams[upstream], aggregated)
+				}
+				aggregated.TotalNodes++
+				if backend.Active {
+					aggregated.ActiveNodes++
+				}
+				aggregated.Connections += backend.Connections
+			}
+		}
+	}
+
+	return view
+}
+
+// findBackend 在聚合列表中查找后端
+func findBackend(backends []*AggregatedBackend, id string) *AggregatedBackend {
+	for _, backend := range backends {
+		if backend.ID == id {
+			return backend
+		}
+	}
+	return nil
+}
+
+// pollLoop 轮询循环
+func (a *Aggregator) pollLoop() {
+	a.pollPeers()
+
+	for {
+		interval := a.configMgr.GetConfig().Cluster.PollInterval
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(interval):
+			a.pollPeers()
+		}
+	}
+}
+
+// pollPeers 并行获取所有对端节点的快照
+func (a *Aggregator) pollPeers() {
+	cfg := a.configMgr.GetConfig()
+
+	// 移除已从配置中删除的对端节点
+	current := make(map[string]bool, len(cfg.Cluster.Peers))
+	for _, peer := range cfg.Cluster.Peers {
+		current[strings.TrimRight(peer, "/")] = true
+	}
+	a.mu.Lock()
+	for url := range a.peers {
+		if !current[url] {
+			delete(a.peers, url)
+		}
+	}
+	a.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for url := range current {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			snapshot, err := a.fetchSnapshot(url, cfg.Cluster.Timeout)
+
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			peer, exists := a.peers[url]
+			if !exists {
+				peer = &peerState{url: url}
+				a.peers[url] = peer
+			}
+			if err != nil {
+				peer.err = err.Error()
+				return
+			}
+			peer.snapshot = snapshot
+			peer.lastSeen = time.Now()
+			peer.err = ""
+		}(url)
+	}
+	wg.Wait()
+}
+
+// fetchSnapshot 获取对端节点的本地快照
+func (a *Aggregator) fetchSnapshot(url string, timeout time.Duration) (*NodeSnapshot, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+LocalPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := a.configMgr.GetConfig().Cluster.Token; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var snapshot NodeSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,151 @@
+package concurrency
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter 限制同时处理的请求数
+// 达到上限时，queueTimeout为0则立即拒绝；否则按先后顺序排队，最多maxQueue个请求等待queueTimeout
+type Limiter struct {
+	mu           sync.Mutex
+	limit        int
+	maxQueue     int
+	queueTimeout time.Duration
+	inflight     int
+	queue        list.List // *waiter，先进先出
+
+	admitted atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+	timedOut atomic.Int64
+}
+
+// waiter 排队等待的请求，获得名额时关闭ready
+type waiter struct {
+	ready chan struct{}
+}
+
+// Stats 并发限制统计
+type Stats struct {
+	Limit        int    `json:"limit"`
+	MaxQueue     int    `json:"max_queue,omitempty"`
+	QueueTimeout string `json:"queue_timeout,omitempty"`
+	Inflight     int    `json:"inflight"`  // 正在处理的请求数
+	Waiting      int    `json:"waiting"`   // 正在排队的请求数
+	Admitted     int64  `json:"admitted"`  // 放行的请求数（包括排队后放行的）
+	Queued       int64  `json:"queued"`    // 排队过的请求数
+	Rejected     int64  `json:"rejected"`  // 因排队已满或不排队而拒绝的请求数
+	TimedOut     int64  `json:"timed_out"` // 排队超时的请求数
+}
+
+// NewLimiter 创建并发限制器
+func NewLimiter(limit, maxQueue int, queueTimeout time.Duration) *Limiter {
+	return &Limiter{
+		limit:        limit,
+		maxQueue:     maxQueue,
+		queueTimeout: queueTimeout,
+	}
+}
+
+// SetLimit 调整上限和排队参数，上限提高时立即放行排队的请求
+func (l *Limiter) SetLimit(limit, maxQueue int, queueTimeout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.maxQueue = maxQueue
+	l.queueTimeout = queueTimeout
+	for l.inflight < l.limit && l.wakeLocked() {
+	}
+}
+
+// Acquire 获取一个处理名额，需要时排队等待；返回true时调用方处理完成后必须调用Release
+func (l *Limiter) Acquire() bool {
+	l.mu.Lock()
+	if l.inflight < l.limit && l.queue.Len() == 0 {
+		l.inflight++
+		l.mu.Unlock()
+		l.admitted.Add(1)
+		return true
+	}
+	if l.queueTimeout <= 0 || l.queue.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		l.rejected.Add(1)
+		return false
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	elem := l.queue.PushBack(w)
+	timeout := l.queueTimeout
+	l.mu.Unlock()
+	l.queued.Add(1)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+		l.admitted.Add(1)
+		return true
+	case <-timer.C:
+	}
+
+	l.mu.Lock()
+	select {
+	case <-w.ready:
+		// 超时的同时获得了名额
+		l.mu.Unlock()
+		l.admitted.Add(1)
+		return true
+	default:
+	}
+	l.queue.Remove(elem)
+	l.mu.Unlock()
+	l.timedOut.Add(1)
+	return false
+}
+
+// Release 归还处理名额，有排队的请求时直接转交给最早排队的请求
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if l.inflight < l.limit {
+		l.wakeLocked()
+	}
+}
+
+// wakeLocked 把一个名额交给最早排队的请求，没有排队的请求时返回false，调用方需持有锁
+func (l *Limiter) wakeLocked() bool {
+	front := l.queue.Front()
+	if front == nil {
+		return false
+	}
+	l.queue.Remove(front)
+	l.inflight++
+	close(front.Value.(*waiter).ready)
+	return true
+}
+
+// Stats 获取并发限制统计
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	stats := Stats{
+		Limit:    l.limit,
+		Inflight: l.inflight,
+		Waiting:  l.queue.Len(),
+	}
+	if l.queueTimeout > 0 {
+		stats.MaxQueue = l.maxQueue
+		stats.QueueTimeout = l.queueTimeout.String()
+	}
+	l.mu.Unlock()
+
+	stats.Admitted = l.admitted.Load()
+	stats.Queued = l.queued.Load()
+	stats.Rejected = l.rejected.Load()
+	stats.TimedOut = l.timedOut.Load()
+	return stats
+}
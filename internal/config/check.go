@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// certExpiryWarning 证书剩余有效期少于该值时给出警告
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// CheckOptions 配置检查的可选项
+type CheckOptions struct {
+	ResolveDNS bool          // 解析后端主机名
+	CheckCerts bool          // 加载证书和私钥，检查是否匹配和过期
+	Timeout    time.Duration // 每个主机名解析的超时，默认5s
+}
+
+// CheckResult 配置检查结果
+type CheckResult struct {
+	Config   *types.Config
+	Errors   FieldErrors // 任何错误都会导致检查失败
+	Warnings FieldErrors // 不影响检查结果，例如证书即将过期
+}
+
+// OK 检查是否通过
+func (r *CheckResult) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Check 加载并验证配置文件，不修改配置文件也不启动任何监听
+// 除常规验证外，可选地解析后端主机名和检查证书文件，用于CI和部署前的检查
+func Check(configPath string, opts CheckOptions) (*CheckResult, error) {
+	m, err := NewManager(configPath)
+	if err != nil {
+		var errs FieldErrors
+		if errors.As(err, &errs) {
+			return &CheckResult{Errors: errs}, nil
+		}
+		return nil, err
+	}
+
+	result := &CheckResult{Config: m.GetConfig()}
+	if opts.ResolveDNS {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		checkBackendHosts(result, timeout)
+	}
+	if opts.CheckCerts {
+		checkCertFiles(result)
+	}
+	return result, nil
+}
+
+// checkBackendHosts 解析所有后端主机名，同一主机名只解析一次
+func checkBackendHosts(result *CheckResult, timeout time.Duration) {
+	resolved := make(map[string]error)
+	for _, upstream := range sortedKeys(result.Config.Backends) {
+		for i, backend := range result.Config.Backends[upstream] {
+			if backend == nil || backend.Host == "" || net.ParseIP(backend.Host) != nil {
+				continue
+			}
+			err, ok := resolved[backend.Host]
+			if !ok {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				_, err = net.DefaultResolver.LookupHost(ctx, backend.Host)
+				cancel()
+				resolved[backend.Host] = err
+			}
+			if err != nil {
+				result.Errors.add(fmt.Sprintf("backends.%s[%d].host", upstream, i), "cannot resolve %s: %v", backend.Host, err)
+			}
+		}
+	}
+}
+
+// checkCertFiles 检查启用的监听证书、客户端CA和集群CA文件
+func checkCertFiles(result *CheckResult) {
+	config := result.Config
+	now := time.Now()
+
+	if config.SSL.Enabled {
+		checkKeyPair(result, "ssl", config.SSL.CertFile, config.SSL.KeyFile, now)
+		if config.SSL.ClientCAFile != "" {
+			checkCAFile(result, "ssl.client_ca_file", config.SSL.ClientCAFile)
+		}
+	}
+	if tlsCfg := config.GRPC.TLS; config.GRPC.Enabled && tlsCfg != nil {
+		checkKeyPair(result, "grpc.tls", tlsCfg.CertFile, tlsCfg.KeyFile, now)
+		if tlsCfg.ClientCAFile != "" {
+			checkCAFile(result, "grpc.tls.client_ca_file", tlsCfg.ClientCAFile)
+		}
+	}
+	if config.Cluster.Enabled && config.Cluster.CAFile != "" {
+		checkCAFile(result, "cluster.ca_file", config.Cluster.CAFile)
+	}
+}
+
+// checkKeyPair 加载证书和私钥，检查两者是否匹配以及证书有效期
+func checkKeyPair(result *CheckResult, path, certFile, keyFile string, now time.Time) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		result.Errors.add(path+".cert_file", "%v", err)
+		return
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		result.Errors.add(path+".cert_file", "%v", err)
+		return
+	}
+	switch {
+	case now.After(leaf.NotAfter):
+		result.Errors.add(path+".cert_file", "certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	case now.Before(leaf.NotBefore):
+		result.Errors.add(path+".cert_file", "certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	case leaf.NotAfter.Sub(now) < certExpiryWarning:
+		result.Warnings.add(path+".cert_file", "certificate expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+}
+
+// checkCAFile 检查CA文件中至少有一个可用的证书
+func checkCAFile(result *CheckResult, field, caFile string) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		result.Errors.add(field, "%v", err)
+		return
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+		result.Errors.add(field, "no certificates found in %s", caFile)
+	}
+}
@@ -1,14 +1,27 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"math"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 
+	"github.com/quqi/speedmimi/internal/accesslog"
+	"github.com/quqi/speedmimi/internal/loadbalancer"
+	"github.com/quqi/speedmimi/internal/logsink"
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/secrets"
+	"github.com/quqi/speedmimi/internal/signing"
+	"github.com/quqi/speedmimi/internal/tracing"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/internal/webhook"
 	"github.com/quqi/speedmimi/pkg/types"
 )
 
@@ -16,8 +29,15 @@ import (
 type Manager struct {
 	config     *types.Config
 	configPath string
+	digest     [sha256.Size]byte // 最近一次加载或保存的配置文件内容摘要，文件内容未变化时不重新加载
 	mu         sync.RWMutex
 	watchers   []chan *types.Config
+	readOnly   bool              // 配置文件引用了环境变量或密钥、或包含配置片段，通过API修改的配置只在内存中生效，不写回文件
+	watchDirs  []string          // 主配置文件和配置片段所在目录
+	fileWatch  *fileWatch
+	remote     *remoteWatch // 从远程配置来源加载时非nil
+	secretRefresh *secretRefresh
+	reloads    ReloadStatus
 }
 
 // NewManager 创建配置管理器
@@ -46,8 +66,26 @@ func (m *Manager) GetConfig() *types.Config {
 func (m *Manager) UpdateConfig(config *types.Config) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.applyConfig(config)
+}
 
-	// 验证配置
+// Update 在锁内复制当前配置、调用fn修改后提交，并发的修改不会互相覆盖
+// fn返回错误时不提交并原样返回该错误；fn不能调用Manager的其他方法
+func (m *Manager) Update(fn func(config *types.Config) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	config := CloneConfig(m.config)
+	if err := fn(config); err != nil {
+		return err
+	}
+	return m.applyConfig(config)
+}
+
+// applyConfig 验证、保存并发布配置，调用方持有锁
+func (m *Manager) applyConfig(config *types.Config) error {
+	// 设置默认值并验证配置
+	m.setDefaults(config)
 	if err := m.validateConfig(config); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
@@ -84,8 +122,7 @@ func (m *Manager) ReloadSSL() error {
 		return fmt.Errorf("SSL key file not found: %s", m.config.SSL.KeyFile)
 	}
 
-	// 这里可以添加证书重新加载的逻辑
-	// 由于fasthttp的证书重新加载需要在服务器层面处理，这里只验证文件存在
+	// 证书的实际重新加载由代理服务器的证书监视器完成，这里只验证文件存在
 
 	return nil
 }
@@ -117,16 +154,42 @@ func (m *Manager) StopWatching(ch <-chan *types.Config) {
 
 // loadConfig 从文件加载配置
 func (m *Manager) loadConfig() error {
-	viper.SetConfigFile(m.configPath)
-	viper.SetConfigType("yaml")
+	files, err := m.readFiles()
+	if err != nil {
+		return err
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
+	config, err := m.parseConfig(files)
+	if err != nil {
 		return err
 	}
 
+	m.config = config
+	m.setSource(files)
+	return nil
+}
+
+// parseConfig 解析主配置文件并合并配置片段，设置默认值并验证
+// 每次使用独立的viper实例，文件热加载和并行创建的管理器互不影响
+func (m *Manager) parseConfig(files *configFiles) (*types.Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(bytes.NewReader(files.main)); err != nil {
+		return nil, err
+	}
+
 	config := &types.Config{}
-	if err := viper.Unmarshal(config); err != nil {
-		return err
+	// 使用yaml标签映射字段：viper默认按mapstructure标签（缺省为字段名）匹配键，
+	// fallback_upstreams、max_conn等带下划线的键会被静默忽略
+	if err := v.Unmarshal(config, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := mergeFragments(config, m.configPath, files.fragments); err != nil {
+		return nil, err
 	}
 
 	// 设置默认值
@@ -134,21 +197,39 @@ func (m *Manager) loadConfig() error {
 
 	// 验证配置
 	if err := m.validateConfig(config); err != nil {
-		return err
+		return nil, err
 	}
 
-	m.config = config
-	return nil
+	return config, nil
+}
+
+// setSource 记录当前配置来自的文件，调用方持有锁
+func (m *Manager) setSource(files *configFiles) {
+	m.digest = files.digest
+	m.readOnly = files.templated || len(files.fragments) > 0
+	m.watchDirs = files.dirs
 }
 
-// saveConfig 保存配置到文件
+// saveConfig 保存配置到文件，调用方持有锁
+// 配置文件引用了环境变量时不写回，避免用替换后的值（可能包含密钥）覆盖文件中的引用；
+// 包含配置片段时也不写回，合并后的配置无法拆分回各个文件
 func (m *Manager) saveConfig(config *types.Config) error {
+	if m.readOnly {
+		fmt.Printf("[CONFIG] %s references environment variables or includes other files, config change applied in memory only\n", m.configPath)
+		return nil
+	}
+
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(m.configPath, data, 0644)
+	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+		return err
+	}
+	// 记录写入的内容，文件监听不会把自己保存的配置当作外部修改再加载一次
+	m.digest = sha256.Sum256(data)
+	return nil
 }
 
 // setDefaults 设置默认值
@@ -165,12 +246,300 @@ func (m *Manager) setDefaults(config *types.Config) {
 	if config.Server.RealIPHeader == "" {
 		config.Server.RealIPHeader = "X-Real-IP"
 	}
+	if config.Server.ReadBufferSize == 0 {
+		config.Server.ReadBufferSize = 4096 // 4KB读取缓冲区
+	}
+	if config.Server.WriteBufferSize == 0 {
+		config.Server.WriteBufferSize = 4096 // 4KB写入缓冲区
+	}
+	if config.Server.MaxRequestBodySize == 0 {
+		config.Server.MaxRequestBodySize = 4 * 1024 * 1024 // 4MB
+	}
+	if config.Server.ShutdownTimeout == 0 {
+		config.Server.ShutdownTimeout = 30 * time.Second
+	}
+	if meta := &config.Server.ConnectionMetadata; meta.Enabled {
+		if meta.TLSVersionHeader == "" {
+			meta.TLSVersionHeader = "X-Client-TLS-Version"
+		}
+		if meta.TLSCipherHeader == "" {
+			meta.TLSCipherHeader = "X-Client-TLS-Cipher"
+		}
+		if meta.ALPNHeader == "" {
+			meta.ALPNHeader = "X-Client-ALPN"
+		}
+		if meta.ClientPortHeader == "" {
+			meta.ClientPortHeader = "X-Client-Port"
+		}
+		if meta.ConnectionIDHeader == "" {
+			meta.ConnectionIDHeader = "X-Connection-ID"
+		}
+	}
+
+	if config.Server.PathNormalization.EncodedSlashes == "" {
+		config.Server.PathNormalization.EncodedSlashes = types.EncodedSlashesKeep
+	}
+
+	if early := &config.Server.EarlyReject; early.Enabled {
+		if early.MaxRequestLine == 0 {
+			// 超过读缓冲区的请求行由fasthttp按请求头过大处理
+			early.MaxRequestLine = 8192
+			if config.Server.ReadBufferSize < early.MaxRequestLine {
+				early.MaxRequestLine = config.Server.ReadBufferSize
+			}
+		}
+		if len(early.Methods) == 0 {
+			early.Methods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "CONNECT", "TRACE"}
+		}
+	}
+	if identity := &config.Server.ClientIdentity; identity.Enabled {
+		if identity.SPIFFEIDHeader == "" {
+			identity.SPIFFEIDHeader = "X-Client-SPIFFE-ID"
+		}
+		if identity.SubjectHeader == "" {
+			identity.SubjectHeader = "X-Client-Cert-Subject"
+		}
+		if identity.HashHeader == "" {
+			identity.HashHeader = "X-Client-Cert-Hash"
+		}
+	}
+
+	// 设置维护模式默认值
+	if config.Maintenance.RetryAfter == 0 {
+		config.Maintenance.RetryAfter = 60 * time.Second
+	}
+	if config.Maintenance.Body == "" {
+		config.Maintenance.Body = "Service temporarily unavailable for maintenance"
+	}
+	if config.Maintenance.ContentType == "" {
+		config.Maintenance.ContentType = "text/plain; charset=utf-8"
+	}
+
+	// 代理所在可用区
+	if config.Server.Zone == "" {
+		config.Server.Zone = os.Getenv("SPEEDMIMI_ZONE")
+	}
+
+	// 设置性能上报有效期默认值
+	if config.Performance.ReportTTL == 0 {
+		config.Performance.ReportTTL = 30 * time.Second
+	}
+
+	// 设置指标历史默认值
+	if config.StatsHistory.Resolution == 0 {
+		config.StatsHistory.Resolution = 10 * time.Second
+	}
+	if config.StatsHistory.Retention == 0 {
+		config.StatsHistory.Retention = 24 * time.Hour
+	}
+
+	// 设置配额默认值
+	if config.Quota.Header == "" {
+		config.Quota.Header = "X-API-Key"
+	}
+	if config.Quota.Period == 0 {
+		config.Quota.Period = 24 * time.Hour
+	}
+
+	// 设置API密钥认证默认值
+	if config.APIKeys.Header == "" {
+		config.APIKeys.Header = "X-API-Key"
+	}
+	for i := range config.APIKeys.Keys {
+		if limit := config.APIKeys.Keys[i].RateLimit; limit != nil && limit.Burst == 0 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+		}
+	}
+
+	// 设置数据文件热加载默认值
+	if config.ConfigWatch.Debounce == 0 {
+		config.ConfigWatch.Debounce = 500 * time.Millisecond
+	}
+	if config.Artifacts.WatchInterval == 0 {
+		config.Artifacts.WatchInterval = 30 * time.Second
+	}
+
+	// 设置审计模式巡检间隔默认值
+	if config.Audit.Interval == 0 {
+		config.Audit.Interval = 10 * time.Second
+	}
+
+	// 设置调试跟踪日志默认值
+	if config.DebugTrace.Path == "" {
+		config.DebugTrace.Path = "logs/debug-trace.log"
+	}
+
+	// 管理API默认只监听本机地址
+	if config.GRPC.Host == "" {
+		config.GRPC.Host = "127.0.0.1"
+	}
+
+	// 设置管理API和后台健康检查的并发上限默认值
+	if config.ControlPlane.AdminWorkers == 0 {
+		config.ControlPlane.AdminWorkers = 8
+	}
+	if config.ControlPlane.AdminQueueTimeout == 0 {
+		config.ControlPlane.AdminQueueTimeout = 5 * time.Second
+	}
+	if config.ControlPlane.HealthWorkers == 0 {
+		config.ControlPlane.HealthWorkers = 8
+	}
+
+	// 设置响应缓存默认值
+	if config.Cache.MaxSize == 0 {
+		config.Cache.MaxSize = 256 << 20
+	}
+	if config.Cache.MaxEntrySize == 0 {
+		config.Cache.MaxEntrySize = 1 << 20
+		if config.Cache.MaxEntrySize > config.Cache.MaxSize {
+			config.Cache.MaxEntrySize = config.Cache.MaxSize
+		}
+	}
+
+	// 设置访问日志默认值
+	if config.AccessLog.Level == "" {
+		config.AccessLog.Level = "all"
+	}
+	if config.AccessLog.SampleRate == 0 {
+		config.AccessLog.SampleRate = 1
+	}
+	if config.AccessLog.ErrorStatus == 0 {
+		config.AccessLog.ErrorStatus = 500
+	}
+	if config.AccessLog.FlushInterval == 0 {
+		config.AccessLog.FlushInterval = time.Second
+	}
+
+	// 设置Webhook默认值
+	for i := range config.Webhooks {
+		hook := &config.Webhooks[i]
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("webhook-%d", i)
+		}
+		if hook.Timeout == 0 {
+			hook.Timeout = 5 * time.Second
+		}
+		if hook.MaxRetries == 0 {
+			hook.MaxRetries = 3
+		}
+		if hook.RetryBackoff == 0 {
+			hook.RetryBackoff = time.Second
+		}
+	}
+
+	// 设置证书轮询默认值
+	if config.SSL.WatchInterval == 0 {
+		config.SSL.WatchInterval = 30 * time.Second
+	}
+	if config.SSL.ClientAuth == "" {
+		config.SSL.ClientAuth = types.ClientAuthNone
+	}
+
+	// 设置上游协议回退和总速率限制默认值
+	for _, upstream := range config.Upstreams {
+		if upstream == nil {
+			continue
+		}
+		if len(upstream.Protocols) > 0 && upstream.ProtocolRecheck == 0 {
+			upstream.ProtocolRecheck = 10 * time.Minute
+		}
+		setAggregateRateLimitDefaults(upstream.RateLimit)
+		setConcurrencyLimitDefaults(upstream.Concurrency)
+		if upstream.Queue != nil && upstream.Queue.MaxWait == 0 {
+			upstream.Queue.MaxWait = time.Second
+		}
+	}
+	setAggregateRateLimitDefaults(config.Server.RateLimit)
+	setSecurityHeadersDefaults(config.Server.SecurityHeaders)
+
+	// 设置负载卸载默认值
+	if shed := &config.LoadShedding; shed.Enabled {
+		if shed.Interval == 0 {
+			shed.Interval = time.Second
+		}
+		if shed.MinLimit == 0 {
+			shed.MinLimit = 10
+		}
+		if shed.MaxLimit == 0 {
+			shed.MaxLimit = 10000
+		}
+		if shed.IncreaseStep == 0 {
+			shed.IncreaseStep = shed.MaxLimit / 100
+			if shed.IncreaseStep < 1 {
+				shed.IncreaseStep = 1
+			}
+		}
+		if shed.DecreaseFactor == 0 {
+			shed.DecreaseFactor = 0.8
+		}
+		if shed.LowPriorityShare == 0 {
+			shed.LowPriorityShare = 0.5
+		}
+	}
+
+	// 设置慢客户端保护默认值
+	if config.Server.SlowClient.MaxWriteBuffer > 0 && config.Server.SlowClient.WriteBufferTimeout == 0 {
+		config.Server.SlowClient.WriteBufferTimeout = 10 * time.Second
+	}
+
+	// 设置慢请求保护默认值
+	if config.Server.SlowRequest.MinRate > 0 && config.Server.SlowRequest.RateGrace == 0 {
+		config.Server.SlowRequest.RateGrace = 5 * time.Second
+	}
+
+	// 设置单IP请求速率的默认突发数
+	if limits := &config.Server.ClientLimits; limits.RequestRate > 0 && limits.RequestBurst == 0 {
+		limits.RequestBurst = int(math.Ceil(limits.RequestRate))
+	}
+
+	// 设置上游签名默认值
+	for _, upstream := range config.Upstreams {
+		if upstream == nil || upstream.Signing == nil || upstream.Signing.Type != "hmac" {
+			continue
+		}
+		if upstream.Signing.Header == "" {
+			upstream.Signing.Header = "X-Signature"
+		}
+		if upstream.Signing.TimestampHeader == "" {
+			upstream.Signing.TimestampHeader = "X-Signature-Timestamp"
+		}
+		if upstream.Signing.KeyIDHeader == "" {
+			upstream.Signing.KeyIDHeader = "X-Signature-Key-Id"
+		}
+		if upstream.Signing.FieldsHeader == "" {
+			upstream.Signing.FieldsHeader = "X-Signature-Fields"
+		}
+		if upstream.Signing.MaxClockSkew == 0 {
+			upstream.Signing.MaxClockSkew = signing.DefaultMaxClockSkew
+		}
+	}
+
+	// 设置集群视图默认值
+	if config.Cluster.NodeName == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			config.Cluster.NodeName = fmt.Sprintf("%s:%d", hostname, config.Server.Port)
+		}
+	}
+	if config.Cluster.PollInterval == 0 {
+		config.Cluster.PollInterval = 10 * time.Second
+	}
+	if config.Cluster.Timeout == 0 {
+		config.Cluster.Timeout = 3 * time.Second
+	}
+
+	// 设置路由令牌默认值
+	if config.RoutingToken.Header == "" {
+		config.RoutingToken.Header = "X-SpeedMimi-Route-Token"
+	}
+	if config.RoutingToken.MaxTTL == 0 {
+		config.RoutingToken.MaxTTL = time.Hour
+	}
 
 	// 设置后端默认值
 	for upstream, backends := range config.Backends {
 		for _, backend := range backends {
 			if backend.ID == "" {
-				backend.ID = fmt.Sprintf("%s-%s-%d", upstream, backend.Host, backend.Port)
+				backend.ID = BackendID(upstream, backend)
 			}
 			if backend.Weight == 0 {
 				backend.Weight = 100
@@ -191,6 +560,12 @@ func (m *Manager) setDefaults(config *types.Config) {
 				if backend.HealthCheck.Failures == 0 {
 					backend.HealthCheck.Failures = 3
 				}
+				if backend.HealthCheck.Rise == 0 {
+					backend.HealthCheck.Rise = 1
+				}
+				if backend.HealthCheck.Fall == 0 {
+					backend.HealthCheck.Fall = backend.HealthCheck.Failures
+				}
 			}
 		}
 	}
@@ -200,67 +575,504 @@ func (m *Manager) setDefaults(config *types.Config) {
 		if rule.Path == "" {
 			rule.Path = "/"
 		}
-		if rule.LoadBalancer == "" {
-			rule.LoadBalancer = types.LeastConnectionsWeight
+		if c := rule.Cache; c != nil && c.NegativeTTL > 0 && len(c.NegativeStatuses) == 0 {
+			c.NegativeStatuses = []int{404, 410}
+		}
+		if cc := rule.CacheControl; cc != nil {
+			if cc.Mode == "" {
+				cc.Mode = "override"
+			}
+			if cc.CDNHeader == "" {
+				cc.CDNHeader = "CDN-Cache-Control"
+			}
+		}
+		if retry := rule.Retry; retry != nil {
+			if retry.MaxAttempts == 0 {
+				retry.MaxAttempts = 2
+			}
+			if len(retry.StatusCodes) == 0 {
+				retry.StatusCodes = []int{502, 503, 504}
+			}
+			if len(retry.Methods) == 0 {
+				retry.Methods = []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+			}
+		}
+		if split := rule.Split; split != nil && split.Key == "" {
+			split.Key = "$client_ip"
+		}
+		if longPoll := rule.LongPoll; longPoll != nil {
+			if longPoll.Timeout == 0 {
+				longPoll.Timeout = 120 * time.Second
+			}
+			if longPoll.Margin == 0 {
+				longPoll.Margin = 5 * time.Second
+			}
+			if longPoll.TimeoutHeader == "" {
+				longPoll.TimeoutHeader = "X-Proxy-Timeout"
+			}
+			if longPoll.TimeoutStatus == 0 {
+				longPoll.TimeoutStatus = 504
+			}
+		}
+		if ranges := rule.RangeRequests; ranges != nil {
+			if ranges.MaxRanges == 0 {
+				ranges.MaxRanges = 1
+			}
+			if ranges.Timeout == 0 {
+				ranges.Timeout = time.Hour
+			}
+		}
+		if auth := rule.ForwardAuth; auth != nil && auth.Timeout == 0 {
+			auth.Timeout = 5 * time.Second
+		}
+		if cors := rule.CORS; cors != nil && len(cors.AllowMethods) == 0 {
+			cors.AllowMethods = []string{"GET", "HEAD", "POST"}
+		}
+		if bot := rule.BotFilter; bot != nil {
+			if bot.Threshold == 0 {
+				bot.Threshold = 60
+			}
+			if bot.Action == "" {
+				bot.Action = types.BotActionBlock
+			}
+			if bot.ScoreHeader == "" {
+				bot.ScoreHeader = "X-Bot-Score"
+			}
+			if bot.ChallengeTTL == 0 {
+				bot.ChallengeTTL = time.Hour
+			}
+		}
+		if fanOut := rule.FanOut; fanOut != nil {
+			if fanOut.Mode == "" {
+				fanOut.Mode = types.FanOutFirstSuccess
+			}
+			if fanOut.Timeout == 0 {
+				fanOut.Timeout = 5 * time.Second
+			}
+			if fanOut.MaxBodySize == 0 {
+				fanOut.MaxBodySize = 1 << 20
+			}
+		}
+		if mirror := rule.Mirror; mirror != nil {
+			if mirror.Percent == 0 {
+				mirror.Percent = 100
+			}
+			if mirror.Timeout == 0 {
+				mirror.Timeout = 5 * time.Second
+			}
+			if mirror.MaxBodySize == 0 {
+				mirror.MaxBodySize = 1 << 20
+			}
+		}
+		if limit := rule.RateLimit; limit != nil && limit.Burst == 0 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+		}
+		setAggregateRateLimitDefaults(rule.TotalRateLimit)
+		setSecurityHeadersDefaults(rule.SecurityHeaders)
+		setConcurrencyLimitDefaults(rule.Concurrency)
+		if req := rule.HealthRequirement; req != nil {
+			if req.Fallback == "" {
+				req.Fallback = types.HealthFallbackStatus
+			}
+			if req.Status == 0 {
+				req.Status = 503
+			}
+			if req.ContentType == "" {
+				req.ContentType = "text/plain; charset=utf-8"
+			}
+			if req.Body == "" {
+				req.Body = "Service Unavailable (Not enough healthy backends)"
+			}
+		}
+		if inspection := rule.BodyInspection; inspection != nil {
+			if inspection.MaxBytes == 0 {
+				inspection.MaxBytes = 1 << 20
+			}
+			if inspection.OnExceed == "" {
+				inspection.OnExceed = types.BodyInspectionDeny
+			}
 		}
 		if rule.Protocols == nil {
 			rule.Protocols = make(map[types.ProtocolType]types.LoadBalancerType)
 		}
+		if standby := rule.Standby; standby != nil {
+			if standby.FailoverRatio == 0 {
+				standby.FailoverRatio = 0.5
+			}
+			if standby.RecoverRatio == 0 {
+				standby.RecoverRatio = math.Min(standby.FailoverRatio+0.25, 1)
+			}
+			if standby.MinDuration == 0 {
+				standby.MinDuration = 30 * time.Second
+			}
+		}
 		config.Routing[name] = rule
 	}
 }
 
-// validateConfig 验证配置
+// setAggregateRateLimitDefaults 设置总请求速率限制的默认值
+func setAggregateRateLimitDefaults(limit *types.AggregateRateLimitConfig) {
+	if limit == nil {
+		return
+	}
+	if limit.Burst == 0 {
+		limit.Burst = int(math.Ceil(limit.Rate))
+	}
+	if limit.Mode == "" {
+		limit.Mode = types.RateLimitReject
+	}
+	if limit.Mode == types.RateLimitDelay && limit.MaxDelay == 0 {
+		limit.MaxDelay = time.Second
+	}
+}
+
+// setConcurrencyLimitDefaults 设置并发请求数上限的默认值
+func setConcurrencyLimitDefaults(limit *types.ConcurrencyLimitConfig) {
+	if limit == nil {
+		return
+	}
+	if limit.Overflow == "" {
+		limit.Overflow = types.ConcurrencyReject
+	}
+	if limit.Overflow == types.ConcurrencyQueue {
+		if limit.MaxQueue == 0 {
+			limit.MaxQueue = limit.MaxInflight
+		}
+		if limit.QueueTimeout == 0 {
+			limit.QueueTimeout = time.Second
+		}
+	}
+}
+
+// setSecurityHeadersDefaults 设置安全响应头的默认值
+func setSecurityHeadersDefaults(headers *types.SecurityHeadersConfig) {
+	if headers == nil {
+		return
+	}
+	if headers.HSTSMaxAge == 0 {
+		headers.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+	if headers.FrameOptions == "" {
+		headers.FrameOptions = "DENY"
+	}
+	if headers.ReferrerPolicy == "" {
+		headers.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+}
+
+// validateConfig 验证配置，返回所有错误（config.FieldErrors），字段路径与YAML中的键一致，例如backends.api[2].port
+// 映射按键排序检查，错误的顺序是确定的
 func (m *Manager) validateConfig(config *types.Config) error {
+	var errs FieldErrors
+
 	if config.Server.Port <= 0 || config.Server.Port > 65535 {
-		return fmt.Errorf("invalid server port: %d", config.Server.Port)
+		errs.add("server.port", "must be between 1 and 65535, got %d", config.Server.Port)
 	}
 
 	if config.SSL.Enabled {
 		if config.SSL.CertFile == "" {
-			return fmt.Errorf("SSL cert file is required when SSL is enabled")
+			errs.add("ssl.cert_file", "is required when SSL is enabled")
 		}
 		if config.SSL.KeyFile == "" {
-			return fmt.Errorf("SSL key file is required when SSL is enabled")
+			errs.add("ssl.key_file", "is required when SSL is enabled")
+		}
+		if config.SSL.WatchInterval < time.Second {
+			errs.add("ssl.watch_interval", "must be at least 1s, got %v", config.SSL.WatchInterval)
+		}
+	}
+
+	switch config.Server.PathNormalization.EncodedSlashes {
+	case types.EncodedSlashesKeep, types.EncodedSlashesDecode, types.EncodedSlashesReject:
+	default:
+		errs.add("server.path_normalization.encoded_slashes", "must be keep, decode or reject, got %q", config.Server.PathNormalization.EncodedSlashes)
+	}
+	if config.Server.ShutdownTimeout < 0 {
+		errs.add("server.shutdown_timeout", "must not be negative, got %v", config.Server.ShutdownTimeout)
+	}
+	errs.addErr("server.early_reject", validateEarlyReject(&config.Server.EarlyReject))
+	errs.addErr("server.connection_classes", validateConnectionClasses(&config.Server.ConnectionClasses))
+	errs.addErr("server.rate_limit", validateAggregateRateLimit(config.Server.RateLimit))
+	errs.addErr("server.security_headers", validateSecurityHeaders(config.Server.SecurityHeaders))
+	errs.addErr("load_shedding", validateLoadShedding(&config.LoadShedding))
+
+	if slow := config.Server.SlowClient; slow.MaxWriteBuffer < 0 || slow.WriteBufferTimeout < 0 {
+		errs.add("server.slow_client", "max_write_buffer and write_buffer_timeout must not be negative")
+	}
+	if slow := config.Server.SlowRequest; slow.HeaderTimeout < 0 || slow.MinRate < 0 || slow.RateGrace < 0 || slow.MaxIncompleteRequests < 0 {
+		errs.add("server.slow_request", "header_timeout, min_rate, rate_grace and max_incomplete_requests must not be negative")
+	}
+	if life := config.Server.ConnectionLifetime; life.MaxRequests < 0 || life.MaxAge < 0 || life.MaxAgeJitter < 0 {
+		errs.add("server.connection_lifetime", "max_requests, max_age and max_age_jitter must not be negative")
+	}
+	errs.addErr("server.client_limits", validateClientLimits(&config.Server.ClientLimits))
+
+	switch config.SSL.ClientAuth {
+	case types.ClientAuthNone:
+	case types.ClientAuthOptional, types.ClientAuthRequire:
+		if !config.SSL.Enabled {
+			errs.add("ssl.client_auth", "%s requires SSL to be enabled", config.SSL.ClientAuth)
+		}
+		if config.SSL.ClientCAFile == "" {
+			errs.add("ssl.client_ca_file", "is required when client_auth is %s", config.SSL.ClientAuth)
+		}
+	default:
+		errs.add("ssl.client_auth", "must be none, optional or require, got %q", config.SSL.ClientAuth)
+	}
+
+	if config.RoutingToken.Enabled && len(config.RoutingToken.Secret) < 16 {
+		errs.add("routing_token.secret", "must be at least 16 bytes when routing token is enabled")
+	}
+
+	if _, err := vars.NewGeoTable(&config.Geo); err != nil {
+		errs.addErr("geo", err)
+	} else if config.Geo.File != "" {
+		if data, err := os.ReadFile(config.Geo.File); err != nil {
+			errs.addErr("geo.file", err)
+		} else if _, err := vars.ParseGeoFile(data); err != nil {
+			errs.add("geo.file", "invalid geo file %s: %v", config.Geo.File, err)
+		}
+	}
+	if config.ConfigWatch.Debounce < 0 {
+		errs.add("config_watch.debounce", "must not be negative, got %v", config.ConfigWatch.Debounce)
+	}
+	if config.Secrets.RefreshInterval < 0 {
+		errs.add("secrets.refresh_interval", "must not be negative, got %v", config.Secrets.RefreshInterval)
+	}
+	for _, name := range sortedKeys(config.Secrets.Providers) {
+		if _, err := secrets.New(name, config.Secrets.Providers[name]); err != nil {
+			errs.addErr("secrets.providers."+name, err)
+		}
+	}
+	if config.Artifacts.WatchInterval < time.Second {
+		errs.add("artifacts.watch_interval", "must be at least 1s, got %v", config.Artifacts.WatchInterval)
+	}
+	errs.addErr("grpc", validateAdminAPI(&config.GRPC))
+	if cp := config.ControlPlane; cp.AdminWorkers < 0 || cp.AdminQueueTimeout < 0 || cp.HealthWorkers < 0 {
+		errs.add("control_plane", "admin_workers, admin_queue_timeout and health_workers must not be negative")
+	}
+	if config.Audit.Interval < 0 {
+		errs.add("audit.interval", "must not be negative, got %v", config.Audit.Interval)
+	}
+	if config.Cache.MaxSize < 0 || config.Cache.MaxEntrySize < 0 || config.Cache.MaxEntrySize > config.Cache.MaxSize {
+		errs.add("cache.max_entry_size", "must be between 0 and max_size")
+	}
+	errs.addErr("access_log", accesslog.Validate(&config.AccessLog))
+	errs.addErr("webhooks", webhook.Validate(config.Webhooks))
+	errs.addErr("tracing", tracing.Validate(&config.Tracing))
+	if h := config.StatsHistory; !h.Disabled {
+		if h.Resolution < time.Second {
+			errs.add("stats_history.resolution", "must be at least 1s, got %v", h.Resolution)
+		} else if h.Retention < h.Resolution || h.Retention/h.Resolution > monitor.MaxHistoryPoints {
+			errs.add("stats_history.retention", "must be at least resolution and keep at most %d points, got %v", monitor.MaxHistoryPoints, h.Retention)
+		}
+	}
+	if path := config.ErrorLog.Path; path != "" {
+		if !logsink.IsRemote(path) {
+			errs.add("error_log.path", "must be a syslog://, syslog+tcp://, tcp://, udp:// or kafka:// target, got %q", path)
+		} else {
+			errs.addErr("error_log.path", logsink.Validate(path))
 		}
 	}
+	errs.addErr("quota", validateQuota(&config.Quota))
+	errs.addErr("api_keys", validateAPIKeys(&config.APIKeys, config.Routing))
 
 	// 验证后端配置
-	for upstream, backends := range config.Backends {
+	for _, upstream := range sortedKeys(config.Backends) {
+		backends := config.Backends[upstream]
+		path := "backends." + upstream
 		if len(backends) == 0 {
-			return fmt.Errorf("upstream %s has no backends", upstream)
+			errs.add(path, "upstream has no backends")
 		}
-
-		for _, backend := range backends {
-			if backend.Host == "" {
-				return fmt.Errorf("backend host is required for upstream %s", upstream)
-			}
-			if backend.Port <= 0 || backend.Port > 65535 {
-				return fmt.Errorf("invalid backend port %d for upstream %s", backend.Port, upstream)
-			}
+		for i, backend := range backends {
+			errs.addErr(fmt.Sprintf("%s[%d]", path, i), ValidateBackend(backend))
 		}
+		errs.addErr(path, validateBackendIDs(backends))
 	}
 
 	// 验证路由配置
-	for name, rule := range config.Routing {
-		if rule.Upstream == "" {
-			return fmt.Errorf("upstream is required for routing rule %s", name)
+	for _, name := range sortedKeys(config.Routing) {
+		validateRoutingRule(&errs, "routing."+name, config.Routing[name], config)
+	}
+
+	// 验证上游级别配置
+	for _, name := range sortedKeys(config.Upstreams) {
+		upstream := config.Upstreams[name]
+		path := "upstreams." + name
+		if _, exists := config.Backends[name]; !exists {
+			errs.add(path, "upstream has settings but no backends")
+		}
+		if upstream == nil {
+			continue
 		}
-		if _, exists := config.Backends[rule.Upstream]; !exists {
-			return fmt.Errorf("upstream %s not found for routing rule %s", rule.Upstream, name)
+		if upstream.LoadBalancer != "" && !loadbalancer.IsKnownType(upstream.LoadBalancer) {
+			errs.add(path+".load_balancer", "unknown load balancer %q", upstream.LoadBalancer)
+		}
+		errs.addErr(path+".load_balancer_params", loadbalancer.ValidateParams(&upstream.LoadBalancerParams))
+		if upstream.LoadBalancerParams.ZoneAware && config.Server.Zone == "" {
+			errs.add(path+".load_balancer_params.zone_aware", "requires server.zone")
+		}
+		if _, err := signing.New(upstream.Signing); err != nil {
+			errs.addErr(path+".signing", err)
+		}
+		errs.addErr(path+".protocols", validateUpstreamProtocols(upstream.Protocols, upstream.ProtocolRecheck))
+		if upstream.LoadReports != "" && upstream.LoadReports != types.LoadReportsORCA {
+			errs.add(path+".load_reports", "must be orca, got %q", upstream.LoadReports)
+		}
+		errs.addErr(path+".rate_limit", validateAggregateRateLimit(upstream.RateLimit))
+		errs.addErr(path+".concurrency", validateConcurrencyLimit(upstream.Concurrency))
+		if queue := upstream.Queue; queue != nil && (queue.MaxSize < 1 || queue.MaxWait <= 0) {
+			errs.add(path+".queue", "max_size must be at least 1 and max_wait must be positive")
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
-// notifyWatchers 通知观察者
+// validateRoutingRule 验证单条路由规则，错误字段以path（routing.<名称>）为前缀
+func validateRoutingRule(errs *FieldErrors, path string, rule *types.RoutingRule, config *types.Config) {
+	if rule.FanOut != nil {
+		errs.addErr(path+".fan_out", validateFanOut(rule, config.Backends))
+	} else if rule.Upstream == "" {
+		errs.add(path+".upstream", "is required")
+	} else if _, exists := config.Backends[rule.Upstream]; !exists {
+		errs.add(path+".upstream", "upstream %s not found", rule.Upstream)
+	}
+	errs.addErr(path+".load_balancer_params", loadbalancer.ValidateParams(rule.LoadBalancerParams))
+	if rule.LoadBalancerParams != nil && rule.LoadBalancerParams.ZoneAware && config.Server.Zone == "" {
+		errs.add(path+".load_balancer_params.zone_aware", "requires server.zone")
+	}
+	errs.addErr(path+".backend_selector", validateLabels(rule.BackendSelector))
+	if _, err := vars.CompileConditions(rule.Match); err != nil {
+		errs.addErr(path+".match", err)
+	}
+	errs.addErr(path+".request_headers", validateHeaderTemplates(rule.RequestHeaders))
+	errs.addErr(path+".response_headers", validateHeaderTemplates(rule.ResponseHeaders))
+	errs.addErr(path+".cache", validateRouteCache(rule.Cache))
+	errs.addErr(path+".cache_control", validateCacheControl(rule.CacheControl))
+	errs.addErr(path+".response_header_filter", validateHeaderFilter(rule.ResponseHeaderFilter))
+	errs.addErr(path+".retry", validateRetry(rule.Retry))
+	switch rule.ForwardedHeaders {
+	case "", types.ForwardedHeadersAppend, types.ForwardedHeadersStrip:
+	default:
+		errs.add(path+".forwarded_headers", "must be append or strip, got %q", rule.ForwardedHeaders)
+	}
+	if len(rule.AllowedSPIFFEIDs) > 0 && config.SSL.ClientAuth == types.ClientAuthNone {
+		errs.add(path+".allowed_spiffe_ids", "requires ssl.client_auth")
+	}
+	errs.addErr(path+".allowed_spiffe_ids", validateSPIFFEPatterns(rule.AllowedSPIFFEIDs))
+	if standby := rule.Standby; standby != nil {
+		if _, exists := config.Backends[standby.Upstream]; !exists {
+			errs.add(path+".standby.upstream", "upstream %s not found", standby.Upstream)
+		} else if standby.Upstream == rule.Upstream {
+			errs.add(path+".standby.upstream", "duplicates the primary upstream %s", standby.Upstream)
+		}
+		if standby.FailoverRatio <= 0 || standby.FailoverRatio > 1 {
+			errs.add(path+".standby.failover_ratio", "must be in (0, 1]")
+		}
+		if standby.RecoverRatio < standby.FailoverRatio || standby.RecoverRatio > 1 {
+			errs.add(path+".standby.recover_ratio", "must be between failover_ratio and 1")
+		}
+	}
+	errs.addErr(path+".split", validateTrafficSplit(rule.Split, config.Backends))
+	if mirror := rule.Mirror; mirror != nil {
+		if _, exists := config.Backends[mirror.Upstream]; !exists {
+			errs.add(path+".mirror.upstream", "upstream %s not found", mirror.Upstream)
+		} else if mirror.Upstream == rule.Upstream {
+			errs.add(path+".mirror.upstream", "duplicates the primary upstream %s", mirror.Upstream)
+		}
+		if mirror.Percent < 1 || mirror.Percent > 100 {
+			errs.add(path+".mirror.percent", "must be between 1 and 100")
+		}
+		if mirror.Timeout < 0 || mirror.MaxBodySize < 0 {
+			errs.add(path+".mirror", "timeout and max_body_size must not be negative")
+		}
+	}
+	if longPoll := rule.LongPoll; longPoll != nil {
+		if longPoll.Timeout < time.Second || longPoll.Margin < 0 || longPoll.Margin >= longPoll.Timeout {
+			errs.add(path+".long_poll", "timeout must be at least 1s and margin between 0 and timeout")
+		}
+		if longPoll.TimeoutStatus < 200 || longPoll.TimeoutStatus > 599 {
+			errs.add(path+".long_poll.timeout_status", "must be between 200 and 599")
+		}
+	}
+	if ranges := rule.RangeRequests; ranges != nil {
+		if ranges.MaxRanges < 1 || ranges.Timeout < time.Second {
+			errs.add(path+".range_requests", "max_ranges must be at least 1 and timeout at least 1s")
+		}
+		if ranges.Enabled && rule.LongPoll != nil {
+			errs.add(path+".range_requests", "cannot be combined with long_poll")
+		}
+	}
+	errs.addErr(path+".forward_auth", validateForwardAuth(rule.ForwardAuth))
+	errs.addErr(path+".cors", validateCORS(rule.CORS))
+	errs.addErr(path+".bot_filter", validateBotFilter(rule.BotFilter))
+	errs.addErr(path+".security_headers", validateSecurityHeaders(rule.SecurityHeaders))
+	if limit := rule.RateLimit; limit != nil && (limit.Rate <= 0 || limit.Burst < 1) {
+		errs.add(path+".rate_limit", "rate must be positive and burst at least 1")
+	}
+	errs.addErr(path+".total_rate_limit", validateAggregateRateLimit(rule.TotalRateLimit))
+	switch rule.Priority {
+	case "", types.PriorityCritical, types.PriorityNormal, types.PriorityLow:
+	default:
+		errs.add(path+".priority", "must be critical, normal or low, got %q", rule.Priority)
+	}
+	errs.addErr(path+".concurrency", validateConcurrencyLimit(rule.Concurrency))
+	errs.addErr(path+".health_requirement", validateHealthRequirement(rule, config.Backends))
+	if inspection := rule.BodyInspection; inspection != nil {
+		if inspection.MaxBytes < 0 {
+			errs.add(path+".body_inspection.max_bytes", "must not be negative")
+		}
+		if inspection.OnExceed != types.BodyInspectionDeny && inspection.OnExceed != types.BodyInspectionBypass {
+			errs.add(path+".body_inspection.on_exceed", "must be deny or bypass")
+		}
+	}
+	for i, fallback := range rule.FallbackUpstreams {
+		field := fmt.Sprintf("%s.fallback_upstreams[%d]", path, i)
+		if fallback == rule.Upstream {
+			errs.add(field, "duplicates the primary upstream %s", fallback)
+		} else if _, exists := config.Backends[fallback]; !exists {
+			errs.add(field, "upstream %s not found", fallback)
+		}
+	}
+}
+
+// CloneConfig 复制配置的顶层结构和Backends/Routing/Upstreams映射，便于修改后通过UpdateConfig提交
+// 后端和路由规则对象本身仍然共享，以保留运行时状态（连接数、断开标记等）
+func CloneConfig(config *types.Config) *types.Config {
+	clone := *config
+
+	clone.Backends = make(map[string][]*types.Backend, len(config.Backends))
+	for upstream, backends := range config.Backends {
+		clone.Backends[upstream] = append([]*types.Backend(nil), backends...)
+	}
+
+	clone.Routing = make(map[string]*types.RoutingRule, len(config.Routing))
+	for name, rule := range config.Routing {
+		clone.Routing[name] = rule
+	}
+
+	if config.Upstreams != nil {
+		clone.Upstreams = make(map[string]*types.UpstreamConfig, len(config.Upstreams))
+		for name, upstream := range config.Upstreams {
+			clone.Upstreams[name] = upstream
+		}
+	}
+
+	return &clone
+}
+
+// notifyWatchers 通知观察者，调用方持有锁
+// 通道中尚未取走的旧配置被替换为最新配置，观察者处理较慢时跳过中间版本，但不会停留在旧配置
 func (m *Manager) notifyWatchers(config *types.Config) {
 	for _, watcher := range m.watchers {
 		select {
-		case watcher <- config:
+		case <-watcher:
 		default:
-			// 如果通道已满，跳过
 		}
+		// 持有锁时只有这里发送，清空后一定有空位
+		watcher <- config
 	}
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envPattern 配置文件中的环境变量引用：${NAME}、${NAME:-默认值}，$${NAME}表示字面量${NAME}
+var envPattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv 替换配置文件内容中的环境变量引用，返回替换后的内容和是否引用了环境变量
+// YAML注释中的引用保持原样；未设置且没有默认值的变量返回错误，设置为空字符串的变量使用默认值
+func expandEnv(data []byte) ([]byte, bool, error) {
+	used := false
+	missing := make(map[string]bool)
+
+	expand := func(match []byte) []byte {
+		groups := envPattern.FindSubmatch(match)
+		if len(groups[1]) > 0 {
+			// 转义，去掉一个$
+			return match[1:]
+		}
+		used = true
+
+		name := string(groups[2])
+		if value := os.Getenv(name); value != "" {
+			return []byte(value)
+		}
+		if groups[3] != nil {
+			// 写了:-，默认值可以为空
+			return groups[3]
+		}
+		if _, ok := os.LookupEnv(name); !ok {
+			missing[name] = true
+		}
+		return nil
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		end := commentStart(line)
+		lines[i] = append(envPattern.ReplaceAllFunc(line[:end], expand), line[end:]...)
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, used, fmt.Errorf("undefined environment variables: %s", strings.Join(names, ", "))
+	}
+	return bytes.Join(lines, nil), used, nil
+}
+
+// commentStart 返回YAML行中注释开始的位置，没有注释时返回行长度
+// 注释以行首或空白后的#开始，引号内的#不算
+func commentStart(line []byte) int {
+	var quote byte
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return i
+		}
+	}
+	return len(line)
+}
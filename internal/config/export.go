@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// RedactedValue 导出配置时代替密钥的值，导入时保留当前配置中对应的值
+const RedactedValue = "<redacted>"
+
+// 导出格式
+const (
+	ExportYAML = "yaml"
+	ExportJSON = "json"
+)
+
+// ExportConfig 把配置导出为完整的配置文档，可以直接作为配置文件使用或通过ImportConfig导入
+// JSON格式使用与YAML相同的键名；redact为true时带secret标签的字段替换为RedactedValue；
+// 配置片段已合并到文档中，不包含include
+func ExportConfig(cfg *types.Config, format string, redact bool) ([]byte, error) {
+	// 经过YAML往返得到深拷贝，隐藏密钥不影响当前配置
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	exported := &types.Config{}
+	if err := yaml.Unmarshal(data, exported); err != nil {
+		return nil, err
+	}
+	exported.Include = nil
+	if redact {
+		redactSecrets(reflect.ValueOf(exported).Elem())
+	}
+	if data, err = yaml.Marshal(exported); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case ExportYAML, "":
+		return data, nil
+	case ExportJSON:
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(doc, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (expected %s or %s)", format, ExportYAML, ExportJSON)
+	}
+}
+
+// redactSecrets 把带secret标签的非空字段替换为RedactedValue，字符串列表和映射替换每个值
+func redactSecrets(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			redactSecrets(v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if t.Field(i).Tag.Get("secret") == "true" {
+				redactValue(field)
+			} else {
+				redactSecrets(field)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactSecrets(v.Index(i))
+		}
+	case reflect.Map:
+		// 映射的值不可寻址，修改副本后写回
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			redactSecrets(elem)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// redactValue 替换一个密钥字段
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(RedactedValue)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i))
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() == reflect.String {
+			for _, key := range v.MapKeys() {
+				v.SetMapIndex(key, reflect.ValueOf(RedactedValue).Convert(v.Type().Elem()))
+			}
+		}
+	}
+}
+
+// ImportConfig 解析YAML或JSON格式的完整配置文档并验证，不修改当前配置
+// 值为RedactedValue的字段使用current中同一路径的值，current中没有对应值时返回字段错误；
+// 导入的文档原样使用，不展开环境变量和密钥引用，也不能包含include
+func ImportConfig(data []byte, current *types.Config) (*types.Config, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config document: %w", err)
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config document must be a mapping")
+	}
+	if _, ok := root["include"]; ok {
+		return nil, fmt.Errorf("include is not supported in imported config, merge the fragments first")
+	}
+
+	if current != nil {
+		currentData, err := yaml.Marshal(current)
+		if err != nil {
+			return nil, err
+		}
+		var currentDoc interface{}
+		if err := yaml.Unmarshal(currentData, &currentDoc); err != nil {
+			return nil, err
+		}
+		var errs FieldErrors
+		doc = restoreRedacted("", doc, currentDoc, &errs)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	main, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{}
+	return m.parseConfig(&configFiles{main: main})
+}
+
+// restoreRedacted 把文档中值为RedactedValue的字段替换为current中同一路径的值，path为YAML字段路径
+func restoreRedacted(path string, doc, current interface{}, errs *FieldErrors) interface{} {
+	switch value := doc.(type) {
+	case string:
+		if value != RedactedValue {
+			return value
+		}
+		if s, ok := current.(string); ok && s != RedactedValue {
+			return s
+		}
+		errs.add(path, "redacted value has no current value to keep")
+		return value
+	case map[string]interface{}:
+		currentMap, _ := current.(map[string]interface{})
+		for _, key := range sortedKeys(value) {
+			elemPath := key
+			if path != "" {
+				elemPath = path + "." + key
+			}
+			value[key] = restoreRedacted(elemPath, value[key], currentMap[key], errs)
+		}
+		return value
+	case []interface{}:
+		currentList, _ := current.([]interface{})
+		for i, elem := range value {
+			var currentElem interface{}
+			if i < len(currentList) {
+				currentElem = currentList[i]
+			}
+			value[i] = restoreRedacted(fmt.Sprintf("%s[%d]", path, i), elem, currentElem, errs)
+		}
+		return value
+	default:
+		return doc
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// configFiles 主配置文件和它包含的配置片段，环境变量和密钥引用已替换
+type configFiles struct {
+	main      []byte
+	fragments []fragmentFile    // 按include中模式的顺序、同一模式内按路径排序
+	digest    [sha256.Size]byte // 所有文件原始内容和引用的密钥的摘要，任何一个变化时都会改变
+	templated bool              // 引用了环境变量或密钥
+	dirs      []string          // 主配置文件和配置片段所在目录，热加载时监听
+}
+
+// fragmentFile 配置片段文件
+type fragmentFile struct {
+	path string
+	data []byte
+}
+
+// configFragment 配置片段，只能包含按名称合并的后端、上游和路由
+type configFragment struct {
+	Backends  map[string][]*types.Backend      `yaml:"backends"`
+	Upstreams map[string]*types.UpstreamConfig `yaml:"upstreams"`
+	Routing   map[string]*types.RoutingRule    `yaml:"routing"`
+}
+
+// fragmentKeys 配置片段允许的顶级键
+var fragmentKeys = map[string]bool{"backends": true, "upstreams": true, "routing": true}
+
+// readConfigFiles 读取主配置文件和include匹配的配置片段，raw为主配置文件path的内容
+// include中的相对路径相对于主配置文件所在目录，支持glob模式；不含通配符的路径必须存在
+func readConfigFiles(path string, raw []byte) (*configFiles, error) {
+	main, templated, err := expandEnv(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Include []string            `yaml:"include"`
+		Secrets types.SecretsConfig `yaml:"secrets"`
+	}
+	// 与完整配置使用相同的解码方式，secrets中的时长可以写成0或1h
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(main)); err != nil {
+		return nil, err
+	}
+	if err := v.Unmarshal(&header, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	}); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+	resolver := newSecretResolver(ctx, &header.Secrets)
+	if main, err = resolver.expand(main); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Dir(path)
+	files := &configFiles{main: main, templated: templated, dirs: []string{base}}
+	digest := sha256.New()
+	digest.Write(raw)
+
+	self, _ := filepath.Abs(path)
+	seen := map[string]bool{self: true}
+	for _, pattern := range header.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(base, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("included config file %s not found", pattern)
+		}
+		if dir := filepath.Dir(pattern); !strings.ContainsAny(dir, "*?[") && !containsString(files.dirs, dir) {
+			files.dirs = append(files.dirs, dir)
+		}
+
+		for _, match := range matches {
+			abs, _ := filepath.Abs(match)
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+
+			raw, err := os.ReadFile(match)
+			if err != nil {
+				return nil, err
+			}
+			data, used, err := expandEnv(raw)
+			if err == nil {
+				data, err = resolver.expand(data)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", match, err)
+			}
+			files.templated = files.templated || used
+			files.fragments = append(files.fragments, fragmentFile{path: match, data: data})
+			fmt.Fprintf(digest, "\x00%s\x00", match)
+			digest.Write(raw)
+		}
+	}
+
+	// 引用了密钥时摘要包含密钥内容，密钥轮换后即使文件没有变化也会重新加载
+	if resolver.used() {
+		files.templated = true
+		digest.Write(resolver.sum())
+	}
+	digest.Sum(files.digest[:0])
+	return files, nil
+}
+
+// parseFragment 解析配置片段
+func parseFragment(file fragmentFile) (*configFragment, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(file.data)); err != nil {
+		return nil, fmt.Errorf("%s: %w", file.path, err)
+	}
+	for key := range v.AllSettings() {
+		if !fragmentKeys[key] {
+			return nil, fmt.Errorf("%s: unsupported key %q in config fragment (only backends, upstreams and routing)", file.path, key)
+		}
+	}
+
+	fragment := &configFragment{}
+	if err := v.Unmarshal(fragment, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", file.path, err)
+	}
+	return fragment, nil
+}
+
+// mergeFragments 按顺序把配置片段合并到主配置，同名的上游后端列表、上游配置或路由只能定义一次
+func mergeFragments(config *types.Config, mainPath string, fragments []fragmentFile) error {
+	if len(fragments) == 0 {
+		return nil
+	}
+	if config.Backends == nil {
+		config.Backends = make(map[string][]*types.Backend)
+	}
+	if config.Upstreams == nil {
+		config.Upstreams = make(map[string]*types.UpstreamConfig)
+	}
+	if config.Routing == nil {
+		config.Routing = make(map[string]*types.RoutingRule)
+	}
+
+	// 定义来源，用于重复定义时的错误信息
+	origins := make(map[string]string)
+	for name := range config.Backends {
+		origins["backends "+name] = mainPath
+	}
+	for name := range config.Upstreams {
+		origins["upstream "+name] = mainPath
+	}
+	for name := range config.Routing {
+		origins["routing rule "+name] = mainPath
+	}
+	define := func(kind, name, path string) error {
+		key := kind + " " + name
+		if origin, ok := origins[key]; ok {
+			return fmt.Errorf("%s %s in %s is already defined in %s", kind, name, path, origin)
+		}
+		origins[key] = path
+		return nil
+	}
+
+	for _, file := range fragments {
+		fragment, err := parseFragment(file)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(fragment.Backends) {
+			if err := define("backends", name, file.path); err != nil {
+				return err
+			}
+			config.Backends[name] = fragment.Backends[name]
+		}
+		for _, name := range sortedKeys(fragment.Upstreams) {
+			if err := define("upstream", name, file.path); err != nil {
+				return err
+			}
+			config.Upstreams[name] = fragment.Upstreams[name]
+		}
+		for _, name := range sortedKeys(fragment.Routing) {
+			if err := define("routing rule", name, file.path); err != nil {
+				return err
+			}
+			config.Routing[name] = fragment.Routing[name]
+		}
+	}
+	return nil
+}
+
+// sortedKeys 返回按字典序排列的键，保证重复定义时报告的错误是确定的
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 远程配置来源的超时和重试间隔
+const (
+	sourceFetchTimeout  = 10 * time.Second // 启动和手动重新加载时获取配置的最长时间
+	sourceRetryInterval = 5 * time.Second  // 获取失败后重试的间隔
+)
+
+// remoteWatch 远程配置来源监听
+type remoteWatch struct {
+	source Source
+	digest [sha256.Size]byte // 最近一次获取的内容摘要
+	cancel context.CancelFunc
+}
+
+// NewManagerFromSource 从远程配置来源创建配置管理器
+// 获取到的配置验证通过后写入configPath作为本地缓存；来源不可用时使用缓存中最近一次有效的配置启动
+func NewManagerFromSource(configPath string, source Source) (*Manager, error) {
+	m := &Manager{
+		configPath: configPath,
+		watchers:   make([]chan *types.Config, 0),
+		remote:     &remoteWatch{source: source},
+	}
+	m.reloads.Source = source.String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sourceFetchTimeout)
+	defer cancel()
+	data, err := source.Fetch(ctx, false)
+	if err == nil {
+		_, err = m.applyRemote(data)
+	}
+	if err != nil {
+		if loadErr := m.loadConfig(); loadErr != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w (no usable cached config: %v)", source, err, loadErr)
+		}
+		fmt.Printf("[CONFIG] Failed to load config from %s, using cached %s: %v\n", source, configPath, err)
+		m.recordReload(err)
+	}
+	// 通过API修改的配置只在内存中生效，不写入缓存
+	m.readOnly = true
+
+	return m, nil
+}
+
+// StartSourceWatch 监听远程配置来源，配置变化时验证并整体替换当前配置
+func (m *Manager) StartSourceWatch() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.remote == nil {
+		return fmt.Errorf("config is not loaded from a remote source")
+	}
+	if m.remote.cancel != nil {
+		return fmt.Errorf("config source is already being watched")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.remote.cancel = cancel
+	m.reloads.Watching = true
+	go m.runSourceWatch(ctx, m.remote.source)
+
+	fmt.Printf("[CONFIG] Watching %s for changes\n", m.remote.source)
+	return nil
+}
+
+// StopSourceWatch 停止监听远程配置来源
+func (m *Manager) StopSourceWatch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.remote != nil && m.remote.cancel != nil {
+		m.remote.cancel()
+		m.reloads.Watching = false
+	}
+}
+
+// runSourceWatch 等待来源中的配置变化并应用，失败后间隔重试
+func (m *Manager) runSourceWatch(ctx context.Context, source Source) {
+	for {
+		data, err := source.Fetch(ctx, true)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			var changed bool
+			if changed, err = m.syncRemote(data); changed {
+				fmt.Printf("[CONFIG] Reloaded config from %s\n", source)
+			}
+		}
+		if err != nil {
+			fmt.Printf("[CONFIG] Failed to reload config from %s, keeping current config: %v\n", source, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(sourceRetryInterval):
+			}
+		}
+	}
+}
+
+// reloadFromSource 立即从远程配置来源获取并应用配置
+func (m *Manager) reloadFromSource() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sourceFetchTimeout)
+	defer cancel()
+
+	data, err := m.remote.source.Fetch(ctx, false)
+	if err != nil {
+		m.mu.Lock()
+		m.recordReload(err)
+		m.mu.Unlock()
+		return false, err
+	}
+	return m.syncRemote(data)
+}
+
+// syncRemote 内容与上次获取的不同时验证并应用，然后通知观察者
+func (m *Manager) syncRemote(data []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed, err := m.applyRemote(data)
+	if err != nil {
+		err = fmt.Errorf("invalid config: %w", err)
+		m.recordReload(err)
+		return false, err
+	}
+	if changed {
+		m.notifyWatchers(m.config)
+		m.recordReload(nil)
+	}
+	return changed, nil
+}
+
+// applyRemote 验证远程配置，通过后写入本地缓存并替换当前配置，调用方持有锁
+// include等相对路径相对于缓存文件所在目录；验证失败时缓存保持不变
+func (m *Manager) applyRemote(data []byte) (bool, error) {
+	digest := sha256.Sum256(data)
+	if m.config != nil && digest == m.remote.digest {
+		return false, nil
+	}
+
+	files, err := readConfigFiles(m.configPath, data)
+	if err != nil {
+		return false, err
+	}
+	config, err := m.parseConfig(files)
+	if err != nil {
+		return false, err
+	}
+
+	// 先写临时文件再重命名，进程在写入过程中退出时不会留下不完整的缓存
+	tmp := m.configPath + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, m.configPath)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("failed to write config cache: %w", err)
+	}
+
+	m.config = config
+	m.setSource(files)
+	m.readOnly = true
+	m.remote.digest = digest
+	return true, nil
+}
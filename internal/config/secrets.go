@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/secrets"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// secretPattern 配置文件中的密钥引用：${secret:<提供者>:<引用>}替换为密钥内容，
+// ${secret_file:<提供者>:<引用>}替换为保存了密钥内容的文件路径，$${...}表示字面量
+var secretPattern = regexp.MustCompile(`\$(\$?)\{(secret|secret_file):([A-Za-z0-9_.-]+):([^}]+)\}`)
+
+// secretFetchTimeout 一次加载配置时获取所有密钥的最长时间
+const secretFetchTimeout = 30 * time.Second
+
+// secretResolver 一次加载配置时解析密钥引用，同一引用只获取一次
+type secretResolver struct {
+	ctx       context.Context
+	cfg       *types.SecretsConfig
+	providers map[string]secrets.Provider
+	values    map[string][]byte // key为 提供者:引用
+	errs      []string
+}
+
+func newSecretResolver(ctx context.Context, cfg *types.SecretsConfig) *secretResolver {
+	return &secretResolver{
+		ctx:       ctx,
+		cfg:       cfg,
+		providers: make(map[string]secrets.Provider),
+		values:    make(map[string][]byte),
+	}
+}
+
+// expand 替换data中的密钥引用，YAML注释中的引用保持原样
+func (r *secretResolver) expand(data []byte) ([]byte, error) {
+	if !secretPattern.Match(data) {
+		return data, nil
+	}
+	r.errs = r.errs[:0]
+
+	replace := func(match []byte) []byte {
+		groups := secretPattern.FindSubmatch(match)
+		if len(groups[1]) > 0 {
+			return match[1:]
+		}
+		kind, name, ref := string(groups[2]), string(groups[3]), string(groups[4])
+		value, err := r.fetch(name, ref)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Sprintf("%s:%s: %v", name, ref, err))
+			return nil
+		}
+		if kind == "secret" {
+			return value
+		}
+		path, err := r.writeFile(name, ref, value)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Sprintf("%s:%s: %v", name, ref, err))
+			return nil
+		}
+		return []byte(path)
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		end := commentStart(line)
+		lines[i] = append(secretPattern.ReplaceAllFunc(line[:end], replace), line[end:]...)
+	}
+	if len(r.errs) > 0 {
+		return nil, fmt.Errorf("failed to resolve secrets: %s", strings.Join(r.errs, "; "))
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// used 是否引用了密钥
+func (r *secretResolver) used() bool {
+	return len(r.values) > 0
+}
+
+// fetch 获取一个密钥，同一次加载中重复的引用使用第一次获取的内容
+func (r *secretResolver) fetch(name, ref string) ([]byte, error) {
+	key := name + ":" + ref
+	if value, ok := r.values[key]; ok {
+		return value, nil
+	}
+	provider, ok := r.providers[name]
+	if !ok {
+		var err error
+		// 配置键经过viper解析后是小写
+		if provider, err = secrets.New(name, r.cfg.Providers[strings.ToLower(name)]); err != nil {
+			return nil, err
+		}
+		r.providers[name] = provider
+	}
+	value, err := provider.Fetch(r.ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	r.values[key] = value
+	return value, nil
+}
+
+// writeFile 把密钥内容写入secrets.dir下按引用命名的文件，内容相同时不改写
+// 文件名不随内容变化，证书轮换后证书热加载会读到新内容
+func (r *secretResolver) writeFile(name, ref string, value []byte) (string, error) {
+	dir := r.cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "speedmimi-secrets")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(name + ":" + ref))
+	path := filepath.Join(dir, name+"-"+hex.EncodeToString(sum[:8]))
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, value) {
+		return path, nil
+	}
+
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, value, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// sum 所有密钥内容的摘要，密钥轮换后改变
+func (r *secretResolver) sum() []byte {
+	keys := make([]string, 0, len(r.values))
+	for key := range r.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\x00%x\x00", key, sha256.Sum256(r.values[key]))
+	}
+	return h.Sum(nil)
+}
+
+// secretRefresh 定期重新获取密钥
+type secretRefresh struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// StartSecretRefresh 每隔interval重新加载配置，重新获取引用的密钥，内容变化时验证并整体替换当前配置
+// 获取失败时保留当前配置，下一个间隔重试
+func (m *Manager) StartSecretRefresh(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("secret refresh interval must be positive")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.secretRefresh != nil {
+		return fmt.Errorf("secrets are already being refreshed")
+	}
+	m.secretRefresh = &secretRefresh{stop: make(chan struct{})}
+	go m.runSecretRefresh(m.secretRefresh, interval)
+
+	fmt.Printf("[CONFIG] Refreshing secrets every %s\n", interval)
+	return nil
+}
+
+// StopSecretRefresh 停止定期重新获取密钥
+func (m *Manager) StopSecretRefresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secretRefresh != nil {
+		m.secretRefresh.stopOnce.Do(func() { close(m.secretRefresh.stop) })
+	}
+}
+
+func (m *Manager) runSecretRefresh(sr *secretRefresh, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sr.stop:
+			return
+		case <-ticker.C:
+			// 远程配置来源模式下本地缓存就是最近一次应用的配置，同样从文件重新加载
+			if changed, err := m.ReloadFromFile(); err != nil {
+				fmt.Printf("[CONFIG] Failed to refresh secrets, keeping current config: %v\n", err)
+			} else if changed {
+				fmt.Printf("[CONFIG] Secrets changed, reloaded config\n")
+			}
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 远程配置来源的等待参数
+const (
+	defaultSourceInterval = 30 * time.Second // HTTPS来源的轮询间隔
+	sourceWaitTimeout     = 5 * time.Minute  // Consul阻塞查询和etcd watch单次等待的最长时间
+	maxSourceConfigSize   = 16 << 20         // 远程配置内容的大小上限
+)
+
+// Source 远程配置来源，可以被并发调用
+type Source interface {
+	// Fetch 获取配置内容；wait为true时先等待配置在上次获取之后发生变化
+	// （HTTPS来源按间隔轮询，Consul使用阻塞查询，etcd使用watch），等待超时时照常返回当前内容
+	Fetch(ctx context.Context, wait bool) ([]byte, error)
+	String() string
+}
+
+// SourceOptions 远程配置来源的连接参数
+type SourceOptions struct {
+	Token    string        // HTTPS来源作为Bearer令牌，Consul作为ACL令牌，etcd作为认证令牌
+	CAFile   string        // 验证来源服务器证书的CA，为空时使用系统CA
+	Interval time.Duration // HTTPS来源的轮询间隔，默认30s
+}
+
+// ParseSource 解析远程配置来源地址：
+// https://host/path（可以是预签名URL），consul://host:8500/key，etcd://host:2379/key；
+// consul+https和etcd+https使用TLS连接
+func ParseSource(rawURL string, opts SourceOptions) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config source %q: %w", rawURL, err)
+	}
+	client, err := sourceClient(opts.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	kind := u.Scheme
+	if strings.HasSuffix(kind, "+https") {
+		kind = strings.TrimSuffix(kind, "+https")
+		scheme = "https"
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	switch kind {
+	case "https":
+		interval := opts.Interval
+		if interval <= 0 {
+			interval = defaultSourceInterval
+		}
+		return &httpSource{url: rawURL, token: opts.Token, interval: interval, client: client}, nil
+	case "consul":
+		if key == "" {
+			return nil, fmt.Errorf("config source %s: missing key", rawURL)
+		}
+		return &consulSource{endpoint: scheme + "://" + u.Host, key: key, token: opts.Token, client: client}, nil
+	case "etcd":
+		if key == "" {
+			return nil, fmt.Errorf("config source %s: missing key", rawURL)
+		}
+		// etcd的键通常以/开头，保留原样
+		return &etcdSource{endpoint: scheme + "://" + u.Host, key: u.Path, token: opts.Token, client: client}, nil
+	case "http":
+		return nil, fmt.Errorf("config source %s: plain http is not allowed, use https", rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported config source scheme %q (expected https, consul or etcd)", u.Scheme)
+	}
+}
+
+// sourceClient 创建访问配置来源的HTTP客户端，配置了caFile时用它验证服务器证书
+func sourceClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return &http.Client{}, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config source CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in config source CA file %s", caFile)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}, nil
+}
+
+// readSourceBody 读取响应体，非2xx状态返回错误
+func readSourceBody(resp *http.Response) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if len(data) > maxSourceConfigSize {
+		return nil, fmt.Errorf("config larger than %d bytes", maxSourceConfigSize)
+	}
+	return data, nil
+}
+
+// httpSource HTTPS地址，按间隔轮询，使用ETag条件请求
+type httpSource struct {
+	url      string
+	token    string
+	interval time.Duration
+	client   *http.Client
+
+	mu   sync.Mutex
+	etag string
+	last []byte
+}
+
+func (s *httpSource) String() string {
+	// 预签名URL的查询参数包含签名，不出现在日志中
+	if i := strings.IndexByte(s.url, '?'); i >= 0 {
+		return s.url[:i]
+	}
+	return s.url
+}
+
+func (s *httpSource) Fetch(ctx context.Context, wait bool) ([]byte, error) {
+	if wait {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.interval):
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	s.mu.Lock()
+	etag, last := s.etag, s.last
+	s.mu.Unlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && last != nil {
+		return last, nil
+	}
+	data, err := readSourceBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.etag = resp.Header.Get("ETag")
+	s.last = data
+	s.mu.Unlock()
+	return data, nil
+}
+
+// consulSource Consul KV中的键，使用阻塞查询等待变化
+type consulSource struct {
+	endpoint string
+	key      string
+	token    string
+	client   *http.Client
+
+	mu    sync.Mutex
+	index uint64 // 上次获取时的X-Consul-Index
+}
+
+func (s *consulSource) String() string {
+	return "consul " + s.endpoint + "/" + s.key
+}
+
+func (s *consulSource) Fetch(ctx context.Context, wait bool) ([]byte, error) {
+	s.mu.Lock()
+	lastIndex := s.index
+	s.mu.Unlock()
+
+	query := url.Values{"raw": {""}}
+	if wait && lastIndex > 0 {
+		query.Set("index", strconv.FormatUint(lastIndex, 10))
+		query.Set("wait", sourceWaitTimeout.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/v1/kv/"+s.key+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("key %s not found", s.key)
+	}
+	data, err := readSourceBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if index < lastIndex {
+		// 索引回退（例如Consul重建），下次重新开始阻塞查询
+		index = 0
+	}
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+	return data, nil
+}
+
+// etcdSource etcd v3中的键，通过JSON网关读取，使用watch等待变化
+type etcdSource struct {
+	endpoint string
+	key      string
+	token    string
+	client   *http.Client
+
+	mu       sync.Mutex
+	revision int64 // 上次获取时的集群版本
+}
+
+func (s *etcdSource) String() string {
+	return "etcd " + s.endpoint + s.key
+}
+
+func (s *etcdSource) Fetch(ctx context.Context, wait bool) ([]byte, error) {
+	s.mu.Lock()
+	revision := s.revision
+	s.mu.Unlock()
+	if wait && revision > 0 {
+		if err := s.watch(ctx, revision); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+	}
+
+	var resp struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []struct {
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := s.post(ctx, "/v3/kv/range", map[string]interface{}{"key": []byte(s.key)}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("key %s not found", s.key)
+	}
+	s.mu.Lock()
+	s.revision, _ = strconv.ParseInt(resp.Header.Revision, 10, 64)
+	s.mu.Unlock()
+	return resp.Kvs[0].Value, nil
+}
+
+// watch 等待键在revision之后被修改，最长sourceWaitTimeout
+func (s *etcdSource) watch(ctx context.Context, revision int64) error {
+	ctx, cancel := context.WithTimeout(ctx, sourceWaitTimeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            []byte(s.key),
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/v3/watch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	s.setHeaders(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, err := readSourceBody(resp)
+		return err
+	}
+
+	// 响应是JSON对象流：先是创建确认，之后每次修改一个事件
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled bool              `json:"canceled"`
+				Events   []json.RawMessage `json:"events"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if msg.Result.Canceled || len(msg.Result.Events) > 0 {
+			// 取消通常是因为版本已被压缩，直接重新读取
+			return nil
+		}
+	}
+}
+
+func (s *etcdSource) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	s.setHeaders(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if data, err = readSourceBody(resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (s *etcdSource) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+}
@@ -0,0 +1,825 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 后端配置的取值范围（配置加载和管理API共用）
+const (
+	MaxBackendWeight       = 10000
+	MinHealthCheckInterval = time.Second
+	MaxHealthCheckInterval = time.Hour
+	MaxHealthCheckFailures = 100
+	MaxBackendPriority     = 100
+	MaxBackendIDLength     = 128
+)
+
+// backendIDChars 后端ID允许的字符，包括自动生成的ID中IPv6地址的冒号
+const backendIDChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._:-"
+
+// BackendID 未配置ID的后端自动生成的ID（upstream-host-port）
+// 同一地址出现两次时生成的ID相同，修改host后ID也会变化，需要稳定标识的后端应显式配置id
+func BackendID(upstream string, backend *types.Backend) string {
+	return fmt.Sprintf("%s-%s-%d", upstream, backend.Host, backend.Port)
+}
+
+// validateBackendIDs 检查上游中后端ID是否重复，字段为后端在列表中的下标
+func validateBackendIDs(backends []*types.Backend) FieldErrors {
+	var errs FieldErrors
+	seen := make(map[string]int, len(backends))
+	for i, backend := range backends {
+		if j, ok := seen[backend.ID]; ok {
+			errs.add(fmt.Sprintf("[%d].id", i), "has the same id %s as backend %d (ids generated from host and port collide when an address is listed twice; set an explicit id)",
+				backend.ID, j)
+			continue
+		}
+		seen[backend.ID] = i
+	}
+	return errs
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors 字段级校验错误集合
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add 追加一个字段错误
+func (e *FieldErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// addErr 追加校验函数返回的错误：字段错误集合中的字段加上field前缀，其他错误作为field本身的错误
+func (e *FieldErrors) addErr(field string, err error) {
+	if err == nil {
+		return
+	}
+	var fieldErrs FieldErrors
+	if !errors.As(err, &fieldErrs) {
+		e.add(field, "%v", err)
+		return
+	}
+	for _, fe := range fieldErrs {
+		if !strings.HasPrefix(fe.Field, "[") {
+			fe.Field = "." + fe.Field
+		}
+		*e = append(*e, FieldError{Field: field + fe.Field, Message: fe.Message})
+	}
+}
+
+// ValidateBackend 校验单个后端配置，返回所有字段错误（无错误时返回nil）
+// 未设置的可选字段（weight、scheme、max_conn等）按默认值处理，不视为错误
+func ValidateBackend(backend *types.Backend) FieldErrors {
+	var errs FieldErrors
+
+	if backend == nil {
+		errs.add("backend", "is required")
+		return errs
+	}
+
+	if backend.ID != "" {
+		switch {
+		case len(backend.ID) > MaxBackendIDLength:
+			errs.add("id", "must be at most %d characters", MaxBackendIDLength)
+		case strings.Trim(backend.ID, backendIDChars) != "":
+			errs.add("id", "must contain only letters, digits, '.', '_', ':' and '-'")
+		}
+	}
+
+	switch {
+	case backend.Host == "":
+		errs.add("host", "is required")
+	case strings.ContainsAny(backend.Host, "/ \t") || strings.Contains(backend.Host, "://"):
+		errs.add("host", "must be a hostname or IP address without scheme or path")
+	}
+
+	if backend.Port <= 0 || backend.Port > 65535 {
+		errs.add("port", "must be between 1 and 65535, got %d", backend.Port)
+	}
+
+	if backend.Weight < 0 || backend.Weight > MaxBackendWeight {
+		errs.add("weight", "must be between 0 and %d, got %d", MaxBackendWeight, backend.Weight)
+	}
+
+	if backend.Scheme != "" && backend.Scheme != "http" && backend.Scheme != "https" {
+		errs.add("scheme", "must be http or https, got %q", backend.Scheme)
+	}
+
+	if backend.MaxConn < 0 {
+		errs.add("max_conn", "must not be negative, got %d", backend.MaxConn)
+	}
+
+	if backend.Priority < 0 || backend.Priority > MaxBackendPriority {
+		errs.add("priority", "must be between 0 and %d, got %d", MaxBackendPriority, backend.Priority)
+	}
+
+	if err := validateLabels(backend.Labels); err != nil {
+		errs.add("labels", "%v", err)
+	}
+
+	if hc := backend.HealthCheck; hc != nil {
+		switch hc.Type {
+		case "", types.HealthCheckHTTP:
+			if hc.Service != "" {
+				errs.add("health_check.service", "is only supported for grpc health checks")
+			}
+		case types.HealthCheckGRPC:
+			if hc.Path != "" {
+				errs.add("health_check.path", "is not supported for grpc health checks, use service")
+			}
+			if len(hc.StatusCodes) > 0 || hc.BodyContains != "" || hc.BodyRegex != "" {
+				errs.add("health_check", "status_codes, body_contains and body_regex are only supported for http health checks")
+			}
+		case types.HealthCheckTCP, types.HealthCheckScript:
+			if hc.Path != "" || hc.Service != "" {
+				errs.add("health_check", "path and service are not supported for %s health checks", hc.Type)
+			}
+			if len(hc.StatusCodes) > 0 || hc.BodyContains != "" || hc.BodyRegex != "" {
+				errs.add("health_check", "status_codes, body_contains and body_regex are only supported for http health checks")
+			}
+		default:
+			errs.add("health_check.type", "must be %s, %s, %s or %s, got %q",
+				types.HealthCheckHTTP, types.HealthCheckGRPC, types.HealthCheckTCP, types.HealthCheckScript, hc.Type)
+		}
+		if hc.Type == types.HealthCheckScript && (len(hc.Command) == 0 || hc.Command[0] == "") {
+			errs.add("health_check.command", "is required for script health checks")
+		}
+		if hc.Type != types.HealthCheckScript && len(hc.Command) > 0 {
+			errs.add("health_check.command", "is only supported for script health checks")
+		}
+		if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+			errs.add("health_check.path", "must start with /")
+		}
+		if hc.Interval != 0 && (hc.Interval < MinHealthCheckInterval || hc.Interval > MaxHealthCheckInterval) {
+			errs.add("health_check.interval", "must be between %s and %s, got %s", MinHealthCheckInterval, MaxHealthCheckInterval, hc.Interval)
+		}
+		if hc.Timeout < 0 {
+			errs.add("health_check.timeout", "must not be negative, got %s", hc.Timeout)
+		} else if hc.Timeout != 0 && hc.Interval != 0 && hc.Timeout >= hc.Interval {
+			errs.add("health_check.timeout", "must be less than interval %s, got %s", hc.Interval, hc.Timeout)
+		}
+		for _, code := range hc.StatusCodes {
+			if code < 100 || code > 599 {
+				errs.add("health_check.status_codes", "must be between 100 and 599, got %d", code)
+			}
+		}
+		if hc.BodyRegex != "" {
+			if _, err := regexp.Compile(hc.BodyRegex); err != nil {
+				errs.add("health_check.body_regex", "%v", err)
+			}
+		}
+		if hc.BodyNot && hc.BodyContains == "" && hc.BodyRegex == "" {
+			errs.add("health_check.body_not", "requires body_contains or body_regex")
+		}
+		if hc.Failures < 0 || hc.Failures > MaxHealthCheckFailures {
+			errs.add("health_check.failures", "must be between 0 and %d, got %d", MaxHealthCheckFailures, hc.Failures)
+		}
+		if hc.Rise < 0 || hc.Rise > MaxHealthCheckFailures {
+			errs.add("health_check.rise", "must be between 0 and %d, got %d", MaxHealthCheckFailures, hc.Rise)
+		}
+		if hc.Fall < 0 || hc.Fall > MaxHealthCheckFailures {
+			errs.add("health_check.fall", "must be between 0 and %d, got %d", MaxHealthCheckFailures, hc.Fall)
+		}
+		if hc.Jitter < 0 {
+			errs.add("health_check.jitter", "must not be negative, got %s", hc.Jitter)
+		} else if hc.Jitter != 0 && hc.Interval != 0 && hc.Jitter >= hc.Interval {
+			errs.add("health_check.jitter", "must be less than interval %s, got %s", hc.Interval, hc.Jitter)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateLabels 校验后端标签或路由的标签选择器
+// 配置文件中的标签名会被转换为小写，因此要求标签名为小写以保证管理API和配置文件一致
+func validateLabels(labels map[string]string) error {
+	for key := range labels {
+		if key == "" {
+			return fmt.Errorf("label name must not be empty")
+		}
+		if key != strings.ToLower(key) || strings.ContainsAny(key, " \t=,") {
+			return fmt.Errorf("label name %q must be lowercase without spaces, '=' or ','", key)
+		}
+	}
+	return nil
+}
+
+// validateHeaderTemplates 校验路由的请求头/响应头模板
+func validateHeaderTemplates(headers []types.HeaderTemplate) error {
+	for i, h := range headers {
+		if h.Name == "" || strings.ContainsAny(h.Name, " \t\r\n:") {
+			return fmt.Errorf("header %d: invalid name %q", i, h.Name)
+		}
+		if _, err := vars.Compile(h.Value); err != nil {
+			return fmt.Errorf("header %s: %w", h.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateHeaderFilter 校验路由的响应头过滤配置
+func validateHeaderFilter(f *types.HeaderFilterConfig) error {
+	if f == nil {
+		return nil
+	}
+	if f.DefaultDeny && len(f.Deny) > 0 {
+		return fmt.Errorf("deny has no effect when default_deny is enabled")
+	}
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{{"allow", f.Allow}, {"deny", f.Deny}} {
+		for i, pattern := range list.patterns {
+			name := strings.TrimSuffix(pattern, "*")
+			if name == "" || strings.ContainsAny(name, " \t\r\n:*") {
+				return fmt.Errorf("%s %d: invalid header pattern %q", list.name, i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRetry 校验路由的重试策略
+func validateRetry(r *types.RetryConfig) error {
+	if r == nil {
+		return nil
+	}
+	if r.MaxAttempts < 1 || r.MaxAttempts > 10 {
+		return fmt.Errorf("max_attempts must be between 1 and 10, got %d", r.MaxAttempts)
+	}
+	for _, code := range r.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid status code %d", code)
+		}
+	}
+	for _, method := range r.Methods {
+		if method == "" || method != strings.ToUpper(method) {
+			return fmt.Errorf("method %q must be an upper-case HTTP method", method)
+		}
+	}
+	if r.Backoff < 0 || r.MaxBackoff < 0 {
+		return fmt.Errorf("backoff and max_backoff must not be negative")
+	}
+	return nil
+}
+
+// validateSPIFFEPatterns 校验路由允许的SPIFFE ID模式
+func validateSPIFFEPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "spiffe://") {
+			return fmt.Errorf("pattern %q must start with spiffe://", pattern)
+		}
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			return fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateEarlyReject 校验请求行预检配置
+func validateEarlyReject(e *types.EarlyRejectConfig) error {
+	if !e.Enabled {
+		return nil
+	}
+	if e.MaxRequestLine < 64 {
+		return fmt.Errorf("max_request_line must be at least 64, got %d", e.MaxRequestLine)
+	}
+	for _, method := range e.Methods {
+		if method == "" || method != strings.ToUpper(method) {
+			return fmt.Errorf("method %q must be an upper-case HTTP method", method)
+		}
+	}
+	return nil
+}
+
+// validateRouteCache 校验路由的响应缓存配置
+func validateRouteCache(c *types.RouteCacheConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.TTL < 0 || c.NegativeTTL < 0 {
+		return fmt.Errorf("ttl and negative_ttl must not be negative")
+	}
+	// 5xx通常是暂时性错误，缓存会掩盖后端的恢复
+	for _, code := range c.NegativeStatuses {
+		if code < 400 || code > 499 {
+			return fmt.Errorf("negative_statuses must be between 400 and 499, got %d", code)
+		}
+	}
+	return nil
+}
+
+// validateCacheControl 校验路由的缓存头覆盖配置
+func validateCacheControl(cc *types.CacheControlConfig) error {
+	if cc == nil {
+		return nil
+	}
+
+	switch cc.Mode {
+	case "override", "default":
+		if cc.Value == "" && cc.MaxAge == 0 && cc.CDNValue == "" {
+			return fmt.Errorf("mode %s requires value, max_age or cdn_value", cc.Mode)
+		}
+	case "strip":
+		if cc.Value != "" || cc.MaxAge != 0 {
+			return fmt.Errorf("mode strip does not take value or max_age")
+		}
+	default:
+		return fmt.Errorf("mode must be override, default or strip, got %q", cc.Mode)
+	}
+
+	if cc.Value != "" && cc.MaxAge != 0 {
+		return fmt.Errorf("value and max_age are mutually exclusive")
+	}
+	if cc.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative, got %v", cc.MaxAge)
+	}
+	for _, code := range cc.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid status code %d", code)
+		}
+	}
+	for _, ext := range cc.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("extension %q must start with '.'", ext)
+		}
+	}
+	return nil
+}
+
+// validateQuota 校验API密钥配额配置
+func validateQuota(q *types.QuotaConfig) error {
+	if !q.Enabled {
+		return nil
+	}
+	if q.Period < time.Second {
+		return fmt.Errorf("period must be at least 1s")
+	}
+
+	seen := make(map[string]bool, len(q.Keys))
+	for i, key := range q.Keys {
+		if key.Key == "" {
+			return fmt.Errorf("key %d: key is required", i)
+		}
+		if seen[key.Key] {
+			return fmt.Errorf("key %d: duplicate key", i)
+		}
+		seen[key.Key] = true
+		if key.Requests < 0 || key.Bytes < 0 {
+			return fmt.Errorf("key %d: limits cannot be negative", i)
+		}
+	}
+	return nil
+}
+
+// validateAPIKeys 校验API密钥认证配置
+func validateAPIKeys(c *types.APIKeyConfig, routing map[string]*types.RoutingRule) error {
+	ids := make(map[string]bool, len(c.Keys))
+	hashes := make(map[string]bool, len(c.Keys))
+	for i, key := range c.Keys {
+		if key.ID == "" {
+			return fmt.Errorf("key %d: id is required", i)
+		}
+		if ids[key.ID] {
+			return fmt.Errorf("key %s: duplicate id", key.ID)
+		}
+		ids[key.ID] = true
+		if b, err := hex.DecodeString(key.Hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("key %s: hash must be a hex SHA-256 digest", key.ID)
+		}
+		if hashes[key.Hash] {
+			return fmt.Errorf("key %s: duplicate hash", key.ID)
+		}
+		hashes[key.Hash] = true
+		for _, route := range key.Routes {
+			if _, ok := routing[route]; !ok {
+				return fmt.Errorf("key %s: unknown route %s", key.ID, route)
+			}
+		}
+		if limit := key.RateLimit; limit != nil && (limit.Rate <= 0 || limit.Burst < 1) {
+			return fmt.Errorf("key %s: rate_limit rate must be positive and burst at least 1", key.ID)
+		}
+	}
+	return nil
+}
+
+// validateForwardAuth 校验外部认证配置
+func validateForwardAuth(auth *types.ForwardAuthConfig) error {
+	if auth == nil {
+		return nil
+	}
+	u, err := url.Parse(auth.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("address must be an http or https URL, got %q", auth.Address)
+	}
+	if auth.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	for _, name := range append(append([]string(nil), auth.RequestHeaders...), auth.ResponseHeaders...) {
+		if name == "" || strings.ContainsAny(name, " \t:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+// validateCORS 校验路由的跨域资源共享配置
+func validateCORS(cors *types.CORSConfig) error {
+	if cors == nil {
+		return nil
+	}
+	if len(cors.AllowOrigins) == 0 {
+		return fmt.Errorf("allow_origins must not be empty")
+	}
+	for _, origin := range cors.AllowOrigins {
+		if origin == "*" {
+			if cors.AllowCredentials {
+				return fmt.Errorf("allow_origins * cannot be used with allow_credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*.", "wildcard.", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || strings.Count(origin, "*") > 1 ||
+			(strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+			return fmt.Errorf("invalid origin %q (expected *, scheme://host[:port] or scheme://*.domain)", origin)
+		}
+	}
+	for _, method := range cors.AllowMethods {
+		if method == "" || strings.ContainsAny(method, " \t,") {
+			return fmt.Errorf("invalid method %q", method)
+		}
+	}
+	for _, name := range append(append([]string(nil), cors.AllowHeaders...), cors.ExposeHeaders...) {
+		if name == "" || strings.ContainsAny(name, " \t:,") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if cors.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+	return nil
+}
+
+// referrerPolicies Referrer-Policy允许的取值
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+	"off":                             true,
+}
+
+// validateSecurityHeaders 校验安全响应头配置
+func validateSecurityHeaders(headers *types.SecurityHeadersConfig) error {
+	if headers == nil {
+		return nil
+	}
+	switch strings.ToUpper(headers.FrameOptions) {
+	case "DENY", "SAMEORIGIN", "OFF":
+	default:
+		return fmt.Errorf("invalid frame_options %q (expected DENY, SAMEORIGIN or off)", headers.FrameOptions)
+	}
+	if !referrerPolicies[headers.ReferrerPolicy] {
+		return fmt.Errorf("invalid referrer_policy %q", headers.ReferrerPolicy)
+	}
+	if strings.ContainsAny(headers.ContentSecurityPolicy, "\r\n") {
+		return fmt.Errorf("content_security_policy must not contain line breaks")
+	}
+	if headers.CSPReportOnly && headers.ContentSecurityPolicy == "" {
+		return fmt.Errorf("csp_report_only requires content_security_policy")
+	}
+	return nil
+}
+
+// validateBotFilter 校验路由的机器人过滤配置
+func validateBotFilter(bot *types.BotFilterConfig) error {
+	if bot == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string(nil), bot.Allow...), bot.Deny...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid user agent pattern %q: %w", pattern, err)
+		}
+	}
+	if bot.Threshold < 1 || bot.Threshold > 100 {
+		return fmt.Errorf("threshold must be between 1 and 100")
+	}
+	switch bot.Action {
+	case types.BotActionBlock, types.BotActionChallenge, types.BotActionTag:
+	default:
+		return fmt.Errorf("action must be %s, %s or %s", types.BotActionBlock, types.BotActionChallenge, types.BotActionTag)
+	}
+	if strings.ContainsAny(bot.ScoreHeader, " \t:") {
+		return fmt.Errorf("invalid score_header %q", bot.ScoreHeader)
+	}
+	if bot.ChallengeTTL < 0 {
+		return fmt.Errorf("challenge_ttl must not be negative")
+	}
+	return nil
+}
+
+// validateConnectionClasses 校验连接类别限制
+func validateConnectionClasses(c *types.ConnectionClassesConfig) error {
+	if c.HTTP.MaxConcurrent < 0 || c.HTTP.MaxMemory < 0 {
+		return fmt.Errorf("http: max_concurrent and max_memory must not be negative")
+	}
+	if c.Stream.MaxConcurrent < 0 || c.Stream.MaxMemory < 0 {
+		return fmt.Errorf("stream: max_concurrent and max_memory must not be negative")
+	}
+	return nil
+}
+
+// validateAggregateRateLimit 校验总请求速率限制
+func validateAggregateRateLimit(limit *types.AggregateRateLimitConfig) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.Rate <= 0 || limit.Burst < 1 {
+		return fmt.Errorf("rate must be positive and burst at least 1")
+	}
+	switch limit.Mode {
+	case types.RateLimitReject:
+	case types.RateLimitDelay:
+		if limit.MaxDelay <= 0 {
+			return fmt.Errorf("max_delay must be positive in delay mode")
+		}
+	default:
+		return fmt.Errorf("unknown mode %q (expected reject or delay)", limit.Mode)
+	}
+	return nil
+}
+
+// validateClientLimits 校验按客户端IP的连接数和请求速率上限
+func validateClientLimits(c *types.ClientLimitsConfig) error {
+	if c.MaxConnsPerIP < 0 || c.RequestRate < 0 || c.RequestBurst < 0 {
+		return fmt.Errorf("max_conns_per_ip, request_rate and request_burst must not be negative")
+	}
+	if c.RequestRate > 0 && c.RequestBurst < 1 {
+		return fmt.Errorf("request_burst must be at least 1")
+	}
+	for _, entry := range c.Allowlist {
+		if net.ParseIP(entry) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("allowlist entry %q is not an IP or CIDR", entry)
+		}
+	}
+	return nil
+}
+
+// validateConcurrencyLimit 校验并发请求数上限
+func validateConcurrencyLimit(limit *types.ConcurrencyLimitConfig) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.MaxInflight < 1 {
+		return fmt.Errorf("max_inflight must be at least 1")
+	}
+	switch limit.Overflow {
+	case types.ConcurrencyReject:
+	case types.ConcurrencyQueue:
+		if limit.MaxQueue < 1 || limit.QueueTimeout <= 0 {
+			return fmt.Errorf("max_queue must be at least 1 and queue_timeout must be positive in queue mode")
+		}
+	default:
+		return fmt.Errorf("unknown overflow %q (expected reject or queue)", limit.Overflow)
+	}
+	return nil
+}
+
+// validateLoadShedding 校验自适应负载卸载配置
+func validateLoadShedding(shed *types.LoadSheddingConfig) error {
+	if !shed.Enabled {
+		return nil
+	}
+	if shed.CPUTarget <= 0 && shed.LatencyTarget <= 0 {
+		return fmt.Errorf("cpu_target or latency_target is required")
+	}
+	if shed.CPUTarget < 0 || shed.CPUTarget > 100 || shed.LatencyTarget < 0 {
+		return fmt.Errorf("cpu_target must be between 0 and 100 and latency_target must not be negative")
+	}
+	if shed.Interval < 10*time.Millisecond {
+		return fmt.Errorf("interval must be at least 10ms")
+	}
+	if shed.MinLimit < 1 || shed.MaxLimit < shed.MinLimit || shed.IncreaseStep < 1 {
+		return fmt.Errorf("min_limit must be at least 1, max_limit at least min_limit and increase_step at least 1")
+	}
+	if shed.DecreaseFactor <= 0 || shed.DecreaseFactor >= 1 {
+		return fmt.Errorf("decrease_factor must be between 0 and 1")
+	}
+	if shed.LowPriorityShare <= 0 || shed.LowPriorityShare > 1 {
+		return fmt.Errorf("low_priority_share must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateHealthRequirement 校验路由的健康后端要求
+func validateHealthRequirement(rule *types.RoutingRule, backends map[string][]*types.Backend) error {
+	req := rule.HealthRequirement
+	if req == nil {
+		return nil
+	}
+	if req.MinHealthy < 0 || req.MinHealthyPercent < 0 || req.MinHealthyPercent > 100 {
+		return fmt.Errorf("min_healthy must not be negative and min_healthy_percent must be between 0 and 100")
+	}
+	if req.MinHealthy == 0 && req.MinHealthyPercent == 0 {
+		return fmt.Errorf("min_healthy or min_healthy_percent is required")
+	}
+	if req.Status < 200 || req.Status > 599 {
+		return fmt.Errorf("invalid status %d", req.Status)
+	}
+	if req.RetryAfter < 0 {
+		return fmt.Errorf("retry_after must not be negative")
+	}
+
+	switch req.Fallback {
+	case types.HealthFallbackStatus:
+	case types.HealthFallbackCache:
+		if rule.Cache == nil || !rule.Cache.Enabled {
+			return fmt.Errorf("cache fallback requires the route cache to be enabled")
+		}
+	case types.HealthFallbackUpstream:
+		if req.Upstream == "" || req.Upstream == rule.Upstream {
+			return fmt.Errorf("upstream fallback requires an upstream other than the route upstream")
+		}
+		if _, exists := backends[req.Upstream]; !exists {
+			return fmt.Errorf("upstream %s not found", req.Upstream)
+		}
+	default:
+		return fmt.Errorf("unknown fallback %q (expected status, cache or upstream)", req.Fallback)
+	}
+	return nil
+}
+
+// validateUpstreamProtocols 校验上游的协议偏好顺序
+func validateUpstreamProtocols(protocols []string, recheck time.Duration) error {
+	seen := make(map[string]bool, len(protocols))
+	for _, protocol := range protocols {
+		if protocol != types.UpstreamProtocolH2 && protocol != types.UpstreamProtocolHTTP1 {
+			return fmt.Errorf("unknown protocol %q (expected h2 or http/1.1)", protocol)
+		}
+		if seen[protocol] {
+			return fmt.Errorf("duplicate protocol %q", protocol)
+		}
+		seen[protocol] = true
+	}
+	if recheck < 0 {
+		return fmt.Errorf("protocol_recheck must not be negative")
+	}
+	return nil
+}
+
+// validateFanOut 校验路由的并行扇出配置
+// 扇出路由不转发到单个上游，不能同时配置upstream及依赖它的选项
+func validateFanOut(rule *types.RoutingRule, backends map[string][]*types.Backend) error {
+	fanOut := rule.FanOut
+	if rule.Upstream != "" || len(rule.FallbackUpstreams) > 0 || rule.Standby != nil || rule.Split != nil ||
+		rule.Mirror != nil || rule.Retry != nil || rule.Cache != nil || rule.HealthRequirement != nil {
+		return fmt.Errorf("cannot be combined with upstream, fallback_upstreams, standby, split, mirror, retry, cache or health_requirement")
+	}
+	switch fanOut.Mode {
+	case types.FanOutFirstSuccess:
+		if fanOut.Template != "" {
+			return fmt.Errorf("template requires mode %s", types.FanOutMerge)
+		}
+	case types.FanOutMerge:
+		if _, err := vars.Compile(fanOut.Template); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q (expected %s or %s)", fanOut.Mode, types.FanOutFirstSuccess, types.FanOutMerge)
+	}
+	if fanOut.Timeout < 0 || fanOut.MaxBodySize < 0 {
+		return fmt.Errorf("timeout and max_body_size must not be negative")
+	}
+	if len(fanOut.Targets) == 0 {
+		return fmt.Errorf("targets must not be empty")
+	}
+
+	seen := make(map[string]bool, len(fanOut.Targets))
+	for i, target := range fanOut.Targets {
+		if target.Name == "" || strings.Trim(target.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+			return fmt.Errorf("targets[%d]: name must be non-empty and contain only letters, digits, '_' and '-'", i)
+		}
+		if seen[target.Name] {
+			return fmt.Errorf("duplicate target %s", target.Name)
+		}
+		seen[target.Name] = true
+		if _, exists := backends[target.Upstream]; !exists {
+			return fmt.Errorf("target %s: upstream %s not found", target.Name, target.Upstream)
+		}
+		if _, err := vars.Compile(target.Path); err != nil {
+			return fmt.Errorf("target %s: path: %w", target.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateTrafficSplit 校验路由的按权重分流配置
+func validateTrafficSplit(split *types.TrafficSplitConfig, backends map[string][]*types.Backend) error {
+	if split == nil {
+		return nil
+	}
+	if len(split.Upstreams) == 0 {
+		return fmt.Errorf("upstreams must not be empty")
+	}
+	if _, err := vars.Compile(split.Key); err != nil {
+		return fmt.Errorf("key: %w", err)
+	}
+
+	total := 0
+	seen := make(map[string]bool, len(split.Upstreams))
+	for _, u := range split.Upstreams {
+		if _, exists := backends[u.Upstream]; !exists {
+			return fmt.Errorf("upstream %s not found", u.Upstream)
+		}
+		if seen[u.Upstream] {
+			return fmt.Errorf("duplicate upstream %s", u.Upstream)
+		}
+		seen[u.Upstream] = true
+		if u.Weight < 0 {
+			return fmt.Errorf("upstream %s: weight must not be negative", u.Upstream)
+		}
+		total += u.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("total weight must be positive")
+	}
+
+	for i, steer := range split.Steering {
+		if len(steer.Match) == 0 {
+			return fmt.Errorf("steering[%d]: match must not be empty", i)
+		}
+		if _, err := vars.CompileConditions(steer.Match); err != nil {
+			return fmt.Errorf("steering[%d]: %w", i, err)
+		}
+		if _, exists := backends[steer.Upstream]; !exists {
+			return fmt.Errorf("steering[%d]: upstream %s not found", i, steer.Upstream)
+		}
+	}
+	return nil
+}
+
+// minAdminTokenLength 管理API令牌的最小长度
+const minAdminTokenLength = 16
+
+// validateAdminAPI 校验管理API的认证和TLS配置
+// 监听本机以外的地址时必须配置令牌或客户端证书认证，避免任何人都能匿名修改代理配置
+func validateAdminAPI(cfg *types.GRPCConfig) error {
+	for i, token := range cfg.Auth.Tokens {
+		if len(token) < minAdminTokenLength {
+			return fmt.Errorf("auth.tokens[%d] must be at least %d characters", i, minAdminTokenLength)
+		}
+	}
+	mtls := false
+	if tls := cfg.TLS; tls != nil {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			return fmt.Errorf("tls cert_file and key_file are required")
+		}
+		mtls = tls.ClientCAFile != ""
+	}
+	if cfg.Enabled && !isLoopbackHost(cfg.Host) && len(cfg.Auth.Tokens) == 0 && !mtls {
+		return fmt.Errorf("listening on %s requires auth.tokens or tls.client_ca_file", cfg.Host)
+	}
+	return nil
+}
+
+// isLoopbackHost 监听地址是否只能从本机访问
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
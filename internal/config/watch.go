@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReloadStatus 配置文件热加载状态
+type ReloadStatus struct {
+	Source      string      `json:"source,omitempty"` // 远程配置来源，从本地文件加载时为空
+	Watching    bool        `json:"watching"`
+	Debounce    string      `json:"debounce,omitempty"`
+	Reloads     int64       `json:"reloads"`  // 成功应用文件中新配置的次数
+	Failures    int64       `json:"failures"` // 读取或校验失败、保留当前配置的次数
+	LastReload  *time.Time  `json:"last_reload,omitempty"`
+	LastError   string      `json:"last_error,omitempty"`
+	LastErrors  FieldErrors `json:"last_errors,omitempty"` // 最近一次失败的所有校验错误
+	LastErrorAt *time.Time  `json:"last_error_at,omitempty"`
+}
+
+// fileWatch 配置文件监听
+type fileWatch struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// StartFileWatch 监听配置文件和包含的配置片段，文件内容变化且在debounce时间内不再变化后重新加载
+// 监听的是文件所在目录，编辑器先写临时文件再重命名、Kubernetes ConfigMap替换符号链接等方式都能触发
+func (m *Manager) StartFileWatch(debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fileWatch != nil {
+		watcher.Close()
+		return fmt.Errorf("config file is already being watched")
+	}
+	for _, dir := range m.watchDirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch config directory %s: %w", dir, err)
+		}
+	}
+	m.fileWatch = &fileWatch{
+		watcher:  watcher,
+		debounce: debounce,
+		stop:     make(chan struct{}),
+	}
+	m.reloads.Watching = true
+	m.reloads.Debounce = debounce.String()
+	go m.runFileWatch(m.fileWatch)
+
+	fmt.Printf("[CONFIG] Watching %s for changes (debounce %s)\n", m.configPath, debounce)
+	return nil
+}
+
+// StopFileWatch 停止监听配置文件
+func (m *Manager) StopFileWatch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fileWatch != nil {
+		m.fileWatch.stopOnce.Do(func() { close(m.fileWatch.stop) })
+		m.reloads.Watching = false
+	}
+}
+
+// runFileWatch 合并短时间内的多次文件事件，最后一次事件后等待debounce再重新加载
+func (m *Manager) runFileWatch(fw *fileWatch) {
+	defer fw.watcher.Close()
+
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-fw.stop:
+			return
+		case event, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			fire = time.After(fw.debounce)
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("[CONFIG] Config file watch error: %v\n", err)
+		case <-fire:
+			fire = nil
+			if changed, err := m.ReloadFromFile(); err != nil {
+				fmt.Printf("[CONFIG] Failed to reload %s, keeping current config: %v\n", m.configPath, err)
+			} else if changed {
+				fmt.Printf("[CONFIG] Reloaded %s\n", m.configPath)
+			}
+		}
+	}
+}
+
+// ReloadFromFile 重新读取配置文件和配置片段，内容与最近一次加载或保存的不同时验证并整体替换当前配置，然后通知观察者
+// 返回是否应用了新配置；读取或验证失败时保留当前配置
+func (m *Manager) ReloadFromFile() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files, err := m.readFiles()
+	if err != nil {
+		m.recordReload(err)
+		return false, err
+	}
+	if files.digest == m.digest {
+		return false, nil
+	}
+	config, err := m.parseConfig(files)
+	if err != nil {
+		err = fmt.Errorf("invalid config: %w", err)
+		m.recordReload(err)
+		return false, err
+	}
+
+	m.config = config
+	m.setSource(files)
+	m.watchNewDirs()
+	m.notifyWatchers(config)
+	m.recordReload(nil)
+	return true, nil
+}
+
+// readFiles 读取配置文件和包含的配置片段
+func (m *Manager) readFiles() (*configFiles, error) {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return nil, err
+	}
+	return readConfigFiles(m.configPath, data)
+}
+
+// watchNewDirs include修改后开始监听新增的配置片段目录，调用方持有锁
+func (m *Manager) watchNewDirs() {
+	if m.fileWatch == nil {
+		return
+	}
+	for _, dir := range m.watchDirs {
+		// 已经监听的目录重复添加不会出错
+		if err := m.fileWatch.watcher.Add(dir); err != nil {
+			fmt.Printf("[CONFIG] Failed to watch config directory %s: %v\n", dir, err)
+		}
+	}
+}
+
+// Reload 立即重新加载配置：从远程配置来源获取，或者重新读取配置文件
+func (m *Manager) Reload() (bool, error) {
+	if m.remote != nil {
+		return m.reloadFromSource()
+	}
+	return m.ReloadFromFile()
+}
+
+// GetReloadStatus 获取配置文件热加载状态
+func (m *Manager) GetReloadStatus() ReloadStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.reloads
+}
+
+// recordReload 记录一次重新加载的结果，调用方持有锁
+func (m *Manager) recordReload(err error) {
+	now := time.Now()
+	if err != nil {
+		m.reloads.Failures++
+		m.reloads.LastError = err.Error()
+		m.reloads.LastErrors = nil
+		errors.As(err, &m.reloads.LastErrors)
+		m.reloads.LastErrorAt = &now
+		return
+	}
+	m.reloads.Reloads++
+	m.reloads.LastReload = &now
+	m.reloads.LastError = ""
+	m.reloads.LastErrors = nil
+	m.reloads.LastErrorAt = nil
+}
@@ -0,0 +1,81 @@
+package grpcservice
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// authenticate 检查管理API请求的凭据，通过后才调用next
+// 配置了令牌时需要有效的Bearer令牌或已验证的客户端证书；只配置了客户端CA时TLS握手已要求客户端证书；
+// 都未配置时管理API只能监听本机地址（配置加载时已校验），不检查
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens := s.configMgr.GetConfig().GRPC.Auth.Tokens
+		if len(tokens) == 0 || clientCertVerified(r) || validToken(r.Header.Get("Authorization"), tokens) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="speedmimi"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// clientCertVerified 请求的连接是否提供了由client_ca_file签发的客户端证书
+func clientCertVerified(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// validToken 检查Authorization头中的Bearer令牌，按摘要以固定时间比较，不泄露令牌的长度和内容
+func validToken(header string, tokens []string) bool {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return false
+	}
+	got := sha256.Sum256([]byte(strings.TrimSpace(header[len(prefix):])))
+
+	valid := 0
+	for _, token := range tokens {
+		want := sha256.Sum256([]byte(token))
+		valid |= subtle.ConstantTimeCompare(got[:], want[:])
+	}
+	return valid == 1
+}
+
+// adminTLSConfig 加载管理API的证书和客户端CA
+// 配置了令牌时客户端证书是可选的（没有证书的请求需要令牌），否则每个连接都必须提供客户端证书
+func adminTLSConfig(cfg *types.AdminTLSConfig, hasTokens bool) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load management API certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if hasTokens {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,179 @@
+package grpcservice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/quqi/speedmimi/internal/monitor"
+)
+
+// handleMetrics 以Prometheus文本格式输出指标
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p := newPromWriter(w)
+	defer p.flush()
+	if s.monitor == nil {
+		return
+	}
+
+	traffic := s.monitor.GetTrafficStats()
+	p.header("speedmimi_requests_total", "counter", "Total number of proxied requests.")
+	p.sample("speedmimi_requests_total", nil, float64(traffic.TotalRequests))
+	p.header("speedmimi_active_connections", "gauge", "Number of active client connections.")
+	p.sample("speedmimi_active_connections", nil, float64(traffic.ActiveConnections))
+	p.header("speedmimi_sent_bytes_total", "counter", "Total bytes sent to clients.")
+	p.sample("speedmimi_sent_bytes_total", nil, float64(traffic.BytesSent))
+	p.header("speedmimi_received_bytes_total", "counter", "Total bytes received from clients.")
+	p.sample("speedmimi_received_bytes_total", nil, float64(traffic.BytesRecv))
+	p.header("speedmimi_panics_total", "counter", "Total number of recovered panics while handling requests.")
+	p.sample("speedmimi_panics_total", nil, float64(traffic.Panics))
+
+	// 按路由、上游和后端的流量
+	var routes, upstreams, backends []trafficSeries
+	for _, st := range s.monitor.RouteTraffics("") {
+		routes = append(routes, trafficSeries{labels: []string{"route", st.Route}, counters: st.TrafficCounters})
+	}
+	for _, st := range s.monitor.UpstreamTraffics("") {
+		errs := st.UpstreamErrors
+		upstreams = append(upstreams, trafficSeries{[]string{"upstream", st.Upstream}, st.TrafficCounters, &errs})
+	}
+	for _, st := range s.monitor.BackendTraffics("") {
+		errs := st.UpstreamErrors
+		backends = append(backends, trafficSeries{[]string{"upstream", st.Upstream, "backend", st.Backend}, st.TrafficCounters, &errs})
+	}
+	p.traffic("route", routes)
+	p.traffic("upstream", upstreams)
+	p.traffic("backend", backends)
+
+	// 每个后端的响应时间：累计直方图，以及最近窗口内的百分位
+	latencies := s.monitor.BackendLatencies("")
+	p.header("speedmimi_backend_response_duration_seconds", "histogram", "Backend response time.")
+	for _, st := range latencies {
+		for i, bound := range monitor.LatencyBuckets {
+			p.sample("speedmimi_backend_response_duration_seconds_bucket",
+				[]string{"upstream", st.Upstream, "backend", st.Backend, "le", formatFloat(bound.Seconds())},
+				float64(st.Buckets[i]))
+		}
+		labels := []string{"upstream", st.Upstream, "backend", st.Backend}
+		p.sample("speedmimi_backend_response_duration_seconds_bucket", append(labels, "le", "+Inf"), float64(st.Count))
+		p.sample("speedmimi_backend_response_duration_seconds_sum", labels, st.Sum)
+		p.sample("speedmimi_backend_response_duration_seconds_count", labels, float64(st.Count))
+	}
+	p.header("speedmimi_backend_response_duration_quantile_seconds", "gauge",
+		fmt.Sprintf("Backend response time quantiles over the last %s to %s.", monitor.LatencyWindow, 2*monitor.LatencyWindow))
+	for _, st := range latencies {
+		for _, q := range []struct {
+			quantile string
+			ms       float64
+		}{{"0.5", st.P50Ms}, {"0.95", st.P95Ms}, {"0.99", st.P99Ms}} {
+			p.sample("speedmimi_backend_response_duration_quantile_seconds",
+				[]string{"upstream", st.Upstream, "backend", st.Backend, "quantile", q.quantile}, q.ms/1000)
+		}
+	}
+}
+
+// trafficSeries 一个路由、上游或后端的流量指标
+type trafficSeries struct {
+	labels   []string
+	counters monitor.TrafficCounters
+	errors   *monitor.UpstreamErrors // 路由没有转发失败统计
+}
+
+// traffic 输出speedmimi_<scope>_*流量、状态码类别和转发失败指标
+func (p *promWriter) traffic(scope string, series []trafficSeries) {
+	for _, m := range []struct {
+		name, typ, help string
+		value           func(c *monitor.TrafficCounters) int64
+	}{
+		{"requests_total", "counter", "Total number of requests per " + scope + ".",
+			func(c *monitor.TrafficCounters) int64 { return c.Requests }},
+		{"errors_total", "counter", "Total number of requests per " + scope + " that ended with a 5xx status.",
+			func(c *monitor.TrafficCounters) int64 { return c.Errors }},
+		{"received_bytes_total", "counter", "Total request body bytes per " + scope + ".",
+			func(c *monitor.TrafficCounters) int64 { return c.BytesIn }},
+		{"sent_bytes_total", "counter", "Total response body bytes per " + scope + ".",
+			func(c *monitor.TrafficCounters) int64 { return c.BytesOut }},
+		{"active_connections", "gauge", "Number of in-flight requests per " + scope + ".",
+			func(c *monitor.TrafficCounters) int64 { return c.ActiveConnections }},
+	} {
+		name := "speedmimi_" + scope + "_" + m.name
+		p.header(name, m.typ, m.help)
+		for i := range series {
+			p.sample(name, series[i].labels, float64(m.value(&series[i].counters)))
+		}
+	}
+
+	// 按状态码类别的响应数和按错误类型的转发失败次数
+	name := "speedmimi_" + scope + "_responses_total"
+	p.header(name, "counter", "Total number of responses per "+scope+" by status class.")
+	for _, st := range series {
+		for i, count := range st.counters.Status.Counts() {
+			p.sample(name, append(st.labels[:len(st.labels):len(st.labels)], "class", monitor.StatusClassNames[i]), float64(count))
+		}
+	}
+	if len(series) == 0 || series[0].errors == nil {
+		return
+	}
+	name = "speedmimi_" + scope + "_upstream_errors_total"
+	p.header(name, "counter", "Total number of failed attempts to reach a backend per "+scope+" by error type.")
+	for _, st := range series {
+		for i, count := range st.errors.Counts() {
+			p.sample(name, append(st.labels[:len(st.labels):len(st.labels)], "type", monitor.UpstreamErrorClassNames[i]), float64(count))
+		}
+	}
+}
+
+// promWriter 输出Prometheus文本格式
+type promWriter struct {
+	w *bufio.Writer
+}
+
+func newPromWriter(w io.Writer) *promWriter {
+	return &promWriter{w: bufio.NewWriter(w)}
+}
+
+// header 输出指标的HELP和TYPE
+func (p *promWriter) header(name, typ, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample 输出一个样本，labels为成对的标签名和值
+func (p *promWriter) sample(name string, labels []string, value float64) {
+	p.w.WriteString(name)
+	if len(labels) > 0 {
+		p.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				p.w.WriteByte(',')
+			}
+			p.w.WriteString(labels[i])
+			p.w.WriteString(`="`)
+			p.w.WriteString(labelEscaper.Replace(labels[i+1]))
+			p.w.WriteByte('"')
+		}
+		p.w.WriteByte('}')
+	}
+	p.w.WriteByte(' ')
+	p.w.WriteString(formatFloat(value))
+	p.w.WriteByte('\n')
+}
+
+func (p *promWriter) flush() {
+	p.w.Flush()
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
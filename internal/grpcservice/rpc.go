@@ -0,0 +1,405 @@
+package grpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/quqi/speedmimi/api/proto"
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// newGRPCServer 创建注册了ConfigService、BackendService和MonitorService的gRPC服务器
+// gRPC请求通过HTTP管理API的监听端口进入，与HTTP请求共用认证和工作协程限制
+func (s *Server) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterConfigServiceServer(server, &configService{s: s})
+	pb.RegisterBackendServiceServer(server, &backendService{s: s})
+	pb.RegisterMonitorServiceServer(server, &monitorService{s: s})
+	return server
+}
+
+// withGRPC 把HTTP/2上Content-Type为application/grpc的请求交给gRPC服务器，其它请求交给next
+func withGRPC(grpcServer *grpc.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rpcError 把管理操作的错误转换为gRPC状态
+func rpcError(err error) error {
+	var fieldErrs config.FieldErrors
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &fieldErrs):
+		return status.Error(codes.InvalidArgument, fieldErrs.Error())
+	case errors.Is(err, errUpstreamNotFound), errors.Is(err, errBackendNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// configService 实现pb.ConfigServiceServer
+type configService struct {
+	pb.UnimplementedConfigServiceServer
+	s *Server
+}
+
+func (c *configService) GetConfig(ctx context.Context, req *pb.GetConfigRequest) (*pb.GetConfigResponse, error) {
+	data, err := json.Marshal(c.s.configMgr.GetConfig())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.GetConfigResponse{ConfigJson: data}, nil
+}
+
+func (c *configService) UpdateConfig(ctx context.Context, req *pb.UpdateConfigRequest) (*pb.UpdateConfigResponse, error) {
+	if len(req.ConfigJson) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "config_json is required")
+	}
+	var cfg types.Config
+	if err := json.Unmarshal(req.ConfigJson, &cfg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config_json: %v", err)
+	}
+	if err := c.s.replaceConfig(&cfg); err != nil {
+		return nil, rpcError(err)
+	}
+	return &pb.UpdateConfigResponse{}, nil
+}
+
+func (c *configService) ReloadSSL(ctx context.Context, req *pb.ReloadSSLRequest) (*pb.ReloadSSLResponse, error) {
+	certStatus, err := c.s.reloadSSL()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.ReloadSSLResponse{NotAfter: certStatus.NotAfter.Unix()}, nil
+}
+
+// backendService 实现pb.BackendServiceServer
+type backendService struct {
+	pb.UnimplementedBackendServiceServer
+	s *Server
+}
+
+func (b *backendService) GetBackends(ctx context.Context, req *pb.GetBackendsRequest) (*pb.GetBackendsResponse, error) {
+	if req.Upstream == "" {
+		return nil, status.Error(codes.InvalidArgument, "upstream is required")
+	}
+	upstream := b.s.proxyServer.GetUpstreamManager().GetUpstream(req.Upstream)
+	if upstream == nil {
+		return nil, rpcError(errUpstreamNotFound)
+	}
+
+	backends := upstream.GetBackends()
+	resp := &pb.GetBackendsResponse{Backends: make([]*pb.Backend, 0, len(backends))}
+	for _, backend := range backends {
+		resp.Backends = append(resp.Backends, backendToProto(backend))
+	}
+	return resp, nil
+}
+
+func (b *backendService) AddBackend(ctx context.Context, req *pb.AddBackendRequest) (*pb.AddBackendResponse, error) {
+	if req.Upstream == "" {
+		return nil, status.Error(codes.InvalidArgument, "upstream is required")
+	}
+	if req.Backend == nil {
+		return nil, status.Error(codes.InvalidArgument, "backend is required")
+	}
+	backend, err := backendFromProto(req.Backend)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if err := b.s.addBackend(req.Upstream, backend); err != nil {
+		return nil, rpcError(err)
+	}
+	return &pb.AddBackendResponse{Backend: backendToProto(backend)}, nil
+}
+
+func (b *backendService) RemoveBackend(ctx context.Context, req *pb.RemoveBackendRequest) (*pb.RemoveBackendResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "not implemented yet")
+}
+
+func (b *backendService) UpdateBackend(ctx context.Context, req *pb.UpdateBackendRequest) (*pb.UpdateBackendResponse, error) {
+	if req.Upstream == "" || req.BackendId == "" {
+		return nil, status.Error(codes.InvalidArgument, "upstream and backend_id are required")
+	}
+	if req.Backend == nil || len(req.UpdateMask.GetPaths()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "backend and update_mask are required")
+	}
+
+	upd, err := backendUpdateFromProto(req.Backend, req.UpdateMask.GetPaths())
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	backend, err := b.s.updateBackend(req.Upstream, req.BackendId, upd)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &pb.UpdateBackendResponse{Backend: backendToProto(backend)}, nil
+}
+
+func (b *backendService) DisconnectBackend(ctx context.Context, req *pb.DisconnectBackendRequest) (*pb.DisconnectBackendResponse, error) {
+	if req.Upstream == "" || req.BackendId == "" {
+		return nil, status.Error(codes.InvalidArgument, "upstream and backend_id are required")
+	}
+	// 只设置断开标记，与HTTP接口不同，后端不存在时直接返回错误
+	if err := b.s.proxyServer.DisconnectBackend(req.Upstream, req.BackendId); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.DisconnectBackendResponse{}, nil
+}
+
+// monitorService 实现pb.MonitorServiceServer
+type monitorService struct {
+	pb.UnimplementedMonitorServiceServer
+	s *Server
+}
+
+func (m *monitorService) GetServerStats(ctx context.Context, req *pb.GetServerStatsRequest) (*pb.GetServerStatsResponse, error) {
+	stats, traffic := m.s.serverStats()
+	return &pb.GetServerStatsResponse{
+		Stats: performanceToProto(stats),
+		Traffic: &pb.TrafficStats{
+			TotalRequests:     traffic.TotalRequests,
+			ActiveConnections: traffic.ActiveConnections,
+			BytesSent:         traffic.BytesSent,
+			BytesRecv:         traffic.BytesRecv,
+			Panics:            traffic.Panics,
+		},
+	}, nil
+}
+
+func (m *monitorService) GetBackendStats(ctx context.Context, req *pb.GetBackendStatsRequest) (*pb.GetBackendStatsResponse, error) {
+	upstream := m.s.proxyServer.GetUpstreamManager().GetUpstream(req.Upstream)
+	if upstream == nil {
+		return nil, rpcError(errUpstreamNotFound)
+	}
+	for _, backend := range upstream.GetAllBackends() {
+		if backend.ID == req.BackendId {
+			return &pb.GetBackendStatsResponse{
+				Stats:       performanceToProto(backend.GetPerformance()),
+				Connections: backend.GetConnections(),
+				LatencyMs:   float64(backend.GetLatency()) / float64(time.Millisecond),
+			}, nil
+		}
+	}
+	return nil, rpcError(errBackendNotFound)
+}
+
+func (m *monitorService) ReportPerformance(ctx context.Context, req *pb.ReportPerformanceRequest) (*pb.ReportPerformanceResponse, error) {
+	if req.Upstream == "" || req.BackendId == "" || req.Performance == nil {
+		return nil, status.Error(codes.InvalidArgument, "upstream, backend_id and performance are required")
+	}
+	if err := m.s.reportPerformance(req.Upstream, req.BackendId, performanceFromProto(req.Performance)); err != nil {
+		return nil, rpcError(err)
+	}
+	return &pb.ReportPerformanceResponse{}, nil
+}
+
+// backendToProto 转换运行中的后端，包括连接数、健康状态和性能信息
+func backendToProto(b *types.Backend) *pb.Backend {
+	return &pb.Backend{
+		Id:          b.ID,
+		Name:        b.Name,
+		Host:        b.Host,
+		Port:        int32(b.Port),
+		Weight:      int32(b.Weight),
+		Scheme:      b.Scheme,
+		Active:      b.IsActive(),
+		MaxConn:     int32(b.MaxConn),
+		Priority:    int32(b.Priority),
+		Zone:        b.Zone,
+		Labels:      b.Labels,
+		HealthCheck: healthCheckToProto(b.HealthCheck),
+		Connections: b.GetConnections(),
+		Healthy:     b.IsHealthy(),
+		Performance: performanceToProto(b.GetPerformance()),
+	}
+}
+
+// backendFromProto 转换请求中的后端配置，忽略只在响应中返回的字段
+func backendFromProto(b *pb.Backend) (*types.Backend, error) {
+	hc, err := healthCheckFromProto(b.HealthCheck)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Backend{
+		ID:          b.Id,
+		Name:        b.Name,
+		Host:        b.Host,
+		Port:        int(b.Port),
+		Weight:      int(b.Weight),
+		Scheme:      b.Scheme,
+		Active:      b.Active,
+		MaxConn:     int(b.MaxConn),
+		Priority:    int(b.Priority),
+		Zone:        b.Zone,
+		Labels:      b.Labels,
+		HealthCheck: hc,
+	}, nil
+}
+
+// backendUpdateFromProto 按update_mask中的字段名生成部分更新
+func backendUpdateFromProto(b *pb.Backend, paths []string) (*backendUpdate, error) {
+	upd := &backendUpdate{}
+	for _, path := range paths {
+		switch path {
+		case "id":
+			upd.ID = &b.Id
+		case "name":
+			upd.Name = &b.Name
+		case "host":
+			upd.Host = &b.Host
+		case "port":
+			port := int(b.Port)
+			upd.Port = &port
+		case "weight":
+			weight := int(b.Weight)
+			upd.Weight = &weight
+		case "scheme":
+			upd.Scheme = &b.Scheme
+		case "active":
+			upd.Active = &b.Active
+		case "max_conn":
+			maxConn := int(b.MaxConn)
+			upd.MaxConn = &maxConn
+		case "priority":
+			priority := int(b.Priority)
+			upd.Priority = &priority
+		case "zone":
+			upd.Zone = &b.Zone
+		case "labels":
+			upd.Labels = &b.Labels
+		case "health_check":
+			hc, err := healthCheckFromProto(b.HealthCheck)
+			if err != nil {
+				return nil, err
+			}
+			if hc == nil {
+				return nil, config.FieldErrors{{Field: "health_check", Message: "cannot be cleared"}}
+			}
+			upd.HealthCheck = hc
+		default:
+			return nil, config.FieldErrors{{Field: "update_mask", Message: "unknown or read-only field " + path}}
+		}
+	}
+	return upd, nil
+}
+
+func healthCheckToProto(hc *types.HealthCheck) *pb.HealthCheck {
+	if hc == nil {
+		return nil
+	}
+	statusCodes := make([]int32, 0, len(hc.StatusCodes))
+	for _, code := range hc.StatusCodes {
+		statusCodes = append(statusCodes, int32(code))
+	}
+	return &pb.HealthCheck{
+		Type:         hc.Type,
+		Path:         hc.Path,
+		Service:      hc.Service,
+		StatusCodes:  statusCodes,
+		BodyContains: hc.BodyContains,
+		BodyRegex:    hc.BodyRegex,
+		BodyNot:      hc.BodyNot,
+		Interval:     hc.Interval.String(),
+		Timeout:      hc.Timeout.String(),
+		Failures:     int32(hc.Failures),
+		Rise:         int32(hc.Rise),
+		Fall:         int32(hc.Fall),
+		Jitter:       hc.Jitter.String(),
+	}
+}
+
+// healthCheckFromProto 转换健康检查配置，时长格式错误时返回字段级错误
+func healthCheckFromProto(hc *pb.HealthCheck) (*types.HealthCheck, error) {
+	if hc == nil {
+		return nil, nil
+	}
+	var errs config.FieldErrors
+	parse := func(field, value string) time.Duration {
+		if value == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, config.FieldError{Field: "health_check." + field, Message: "invalid duration " + value})
+		}
+		return d
+	}
+
+	out := &types.HealthCheck{
+		Type:         hc.Type,
+		Path:         hc.Path,
+		Service:      hc.Service,
+		BodyContains: hc.BodyContains,
+		BodyRegex:    hc.BodyRegex,
+		BodyNot:      hc.BodyNot,
+		Interval:     parse("interval", hc.Interval),
+		Timeout:      parse("timeout", hc.Timeout),
+		Failures:     int(hc.Failures),
+		Rise:         int(hc.Rise),
+		Fall:         int(hc.Fall),
+		Jitter:       parse("jitter", hc.Jitter),
+	}
+	for _, code := range hc.StatusCodes {
+		out.StatusCodes = append(out.StatusCodes, int(code))
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return out, nil
+}
+
+func performanceToProto(p *types.PerformanceInfo) *pb.PerformanceInfo {
+	if p == nil {
+		return nil
+	}
+	return &pb.PerformanceInfo{
+		CpuUsage:    p.CPUUsage,
+		MemoryUsage: p.MemoryUsage,
+		DiskUsage:   p.DiskUsage,
+		LoadAvg_1:   p.LoadAvg1,
+		LoadAvg_5:   p.LoadAvg5,
+		LoadAvg_15:  p.LoadAvg15,
+		NetworkIn:   p.NetworkIn,
+		NetworkOut:  p.NetworkOut,
+		Timestamp:   p.Timestamp,
+		ReceivedAt:  p.ReceivedAt,
+		ClockSkewMs: p.ClockSkewMs,
+		Source:      p.Source,
+		Rps:         p.RPS,
+		Eps:         p.EPS,
+	}
+}
+
+// performanceFromProto 转换后端上报的性能信息，接收时间、时钟偏差和来源由代理设置
+func performanceFromProto(p *pb.PerformanceInfo) *types.PerformanceInfo {
+	return &types.PerformanceInfo{
+		CPUUsage:    p.CpuUsage,
+		MemoryUsage: p.MemoryUsage,
+		DiskUsage:   p.DiskUsage,
+		LoadAvg1:    p.LoadAvg_1,
+		LoadAvg5:    p.LoadAvg_5,
+		LoadAvg15:   p.LoadAvg_15,
+		NetworkIn:   p.NetworkIn,
+		NetworkOut:  p.NetworkOut,
+		Timestamp:   p.Timestamp,
+		RPS:         p.Rps,
+		EPS:         p.Eps,
+	}
+}
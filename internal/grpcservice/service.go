@@ -1,52 +1,158 @@
 package grpcservice
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
-
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+
+	"github.com/quqi/speedmimi/internal/apikey"
+	"github.com/quqi/speedmimi/internal/artifact"
+	"github.com/quqi/speedmimi/internal/audit"
+	"github.com/quqi/speedmimi/internal/certwatch"
+	"github.com/quqi/speedmimi/internal/cluster"
 	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/healthcheck"
 	"github.com/quqi/speedmimi/internal/monitor"
 	"github.com/quqi/speedmimi/internal/proxy"
+	"github.com/quqi/speedmimi/internal/quota"
+	"github.com/quqi/speedmimi/internal/routetoken"
 	"github.com/quqi/speedmimi/pkg/types"
 )
 
-// Server 管理API服务器 (暂时用HTTP替代gRPC)
+// Server 管理API服务器，同一端口提供HTTP JSON接口和gRPC服务
 type Server struct {
 	configMgr   *config.Manager
 	proxyServer *proxy.Server
 	monitor     *monitor.PerformanceMonitor
+	prober      *healthcheck.Prober
+	cluster     *cluster.Aggregator
+	admin       *adminLimiter // 管理API的并发请求数上限
 	server      *http.Server
+	grpcServer  *grpc.Server // ConfigService、BackendService和MonitorService，与HTTP共用端口
+
+	streams     atomic.Int64  // 正在推送实时统计的连接数
+	streamsDone chan struct{} // 关闭时结束所有推送连接
+	streamsOnce sync.Once
 }
 
+var (
+	errUpstreamNotFound = errors.New("upstream not found")
+	errBackendNotFound  = errors.New("backend not found")
+	errRouteNotFound    = errors.New("route not found")
+	errAPIKeyNotFound   = errors.New("API key not found")
+)
+
+const (
+	// defaultProbeConcurrency 按需健康探测的默认并发数
+	defaultProbeConcurrency = 8
+	// maxProbeConcurrency 按需健康探测的最大并发数
+	maxProbeConcurrency = 64
+
+	// defaultSwitchDrainTimeout 蓝绿切换后默认等待旧请求处理完成的时间
+	defaultSwitchDrainTimeout = 30 * time.Second
+	// maxSwitchDrainTimeout 蓝绿切换后等待旧请求处理完成的最长时间
+	maxSwitchDrainTimeout = 10 * time.Minute
+
+	// defaultBackendDrainTimeout 排空后端时默认等待已有连接处理完成的时间
+	defaultBackendDrainTimeout = 5 * time.Minute
+	// maxBackendDrainTimeout 排空后端时等待已有连接处理完成的最长时间
+	maxBackendDrainTimeout = time.Hour
+)
+
 // NewServer 创建管理API服务器
 func NewServer(configMgr *config.Manager, proxyServer *proxy.Server, perfMonitor *monitor.PerformanceMonitor) *Server {
 	return &Server{
 		configMgr:   configMgr,
 		proxyServer: proxyServer,
 		monitor:     perfMonitor,
+		prober:      healthcheck.NewProber(),
+		cluster:     cluster.NewAggregator(configMgr, proxyServer, perfMonitor),
+		streamsDone: make(chan struct{}),
 	}
 }
 
 // Start 启动管理API服务器
 func (s *Server) Start(host string, port int) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 
 	mux := http.NewServeMux()
 	s.setupRoutes(mux)
 
+	// 管理请求只使用固定数量的工作协程，大量管理请求不会与数据面争抢资源
+	cp := s.configMgr.GetConfig().ControlPlane
+	s.admin = newAdminLimiter(cp.AdminWorkers, cp.AdminQueueTimeout)
+
+	// gRPC请求与HTTP请求共用监听端口；未启用TLS时通过h2c接受明文HTTP/2
+	// 认证在排队之前进行，未认证的请求不占用工作协程；实时统计推送是长连接，不占用工作协程
+	s.grpcServer = s.newGRPCServer()
+	handler := s.authenticate(s.withStatsStream(s.admin.wrap(withGRPC(s.grpcServer, mux))))
 	s.server = &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: h2c.NewHandler(handler, &http2.Server{}),
+	}
+
+	adminCfg := s.configMgr.GetConfig().GRPC
+	if adminCfg.TLS != nil {
+		tlsConfig, err := adminTLSConfig(adminCfg.TLS, len(adminCfg.Auth.Tokens) > 0)
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = tlsConfig
+	}
+
+	// 集群模式下定期轮询对端节点
+	if s.configMgr.GetConfig().Cluster.Enabled {
+		if err := s.cluster.Start(); err != nil {
+			return err
+		}
 	}
 
+	if s.server.TLSConfig != nil {
+		fmt.Printf("Management API server listening on %s (TLS)\n", addr)
+		return s.server.ListenAndServeTLS("", "")
+	}
 	fmt.Printf("Management API server listening on %s\n", addr)
 	return s.server.ListenAndServe()
 }
 
+// Shutdown 停止接收新的管理请求，等待处理中的请求（包括等待排空结束的关闭请求）返回后关闭服务器
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cluster.Stop()
+	s.stopStreams()
+	if s.server == nil {
+		return nil
+	}
+	err := s.server.Shutdown(ctx)
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	return err
+}
+
 // Stop 停止服务器
 func (s *Server) Stop() error {
+	s.cluster.Stop()
+	s.stopStreams()
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
 	if s.server != nil {
 		return s.server.Close()
 	}
@@ -58,6 +164,9 @@ func (s *Server) setupRoutes(mux *http.ServeMux) {
 	// 配置管理
 	mux.HandleFunc("/api/v1/config", s.handleConfig)
 	mux.HandleFunc("/api/v1/config/reload-ssl", s.handleReloadSSL)
+	mux.HandleFunc("/api/v1/config/reload", s.handleConfigReload)
+	mux.HandleFunc("/api/v1/config/export", s.handleConfigExport)
+	mux.HandleFunc("/api/v1/config/import", s.handleConfigImport)
 
 	// 后端管理
 	mux.HandleFunc("/api/v1/backends", s.handleBackends)
@@ -65,11 +174,89 @@ func (s *Server) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/backends/remove", s.handleRemoveBackend)
 	mux.HandleFunc("/api/v1/backends/update", s.handleUpdateBackend)
 	mux.HandleFunc("/api/v1/backends/disconnect", s.handleDisconnectBackend)
+	mux.HandleFunc("/api/v1/backends/drain", s.handleBackendDrain)
+	mux.HandleFunc("/api/v1/backends/drain-host", s.handleDrainHost)
+	mux.HandleFunc("/api/v1/backends/diagnostics", s.handleBackendDiagnostics)
+
+	// 上游管理
+	mux.HandleFunc("/api/v1/upstreams/", s.handleUpstreams)
+
+	// 维护模式
+	mux.HandleFunc("/api/v1/maintenance", s.handleMaintenance)
+
+	// 连接池
+	mux.HandleFunc("/api/v1/upstreams/recycle", s.handleRecyclePools)
+
+	// 健康状态
+	mux.HandleFunc("/api/v1/health", s.handleHealthStatus)
+	mux.HandleFunc("/api/v1/health/override", s.handleHealthOverride)
+
+	// 静默模式
+	mux.HandleFunc("/api/v1/quiesce", s.handleQuiesce)
+	mux.HandleFunc("/api/v1/server/drain", s.handleServerDrain)
+
+	// 管理面隔离
+	mux.HandleFunc("/api/v1/control-plane", s.handleControlPlane)
+
+	// 审计模式
+	mux.HandleFunc("/api/v1/audit", s.handleAudit)
+
+	// 连接表
+	mux.HandleFunc("/api/v1/connections", s.handleConnections)
+	mux.HandleFunc("/api/v1/connections/slow-clients", s.handleSlowClients)
+	mux.HandleFunc("/api/v1/connections/client-limits", s.handleClientLimits)
+	mux.HandleFunc("/api/v1/connections/slow-requests", s.handleSlowRequests)
+	mux.HandleFunc("/api/v1/connections/lifetime", s.handleConnLifetime)
+	mux.HandleFunc("/api/v1/quota/usage", s.handleQuotaUsage)
+	mux.HandleFunc("/api/v1/api-keys", s.handleAPIKeys)
+	mux.HandleFunc("/api/v1/rate-limits", s.handleRateLimits)
+	mux.HandleFunc("/api/v1/concurrency-limits", s.handleConcurrencyLimits)
+	mux.HandleFunc("/api/v1/backend-queues", s.handleBackendQueues)
+	mux.HandleFunc("/api/v1/load-shedding", s.handleLoadShedding)
+
+	// 访问日志
+	mux.HandleFunc("/api/v1/access-log", s.handleAccessLog)
+
+	// 请求行预检
+	mux.HandleFunc("/api/v1/early-reject", s.handleEarlyReject)
+	mux.HandleFunc("/api/v1/path-normalization", s.handlePathNormalization)
+	mux.HandleFunc("/api/v1/range-requests", s.handleRangeRequests)
+	mux.HandleFunc("/api/v1/debug/trace", s.handleDebugTrace)
+	mux.HandleFunc("/api/v1/connection-classes", s.handleConnClasses)
+
+	// 响应缓存
+	mux.HandleFunc("/api/v1/cache", s.handleCacheStats)
+	mux.HandleFunc("/api/v1/cache/purge", s.handleCachePurge)
+
+	// 数据文件
+	mux.HandleFunc("/api/v1/artifacts", s.handleArtifacts)
+	mux.HandleFunc("/api/v1/artifacts/reload", s.handleArtifactReload)
+
+	// 冷备上游
+	mux.HandleFunc("/api/v1/standby", s.handleStandby)
+
+	// 蓝绿切换
+	mux.HandleFunc("/api/v1/routes/switch", s.handleRouteSwitch)
+
+	// 流量镜像
+	mux.HandleFunc("/api/v1/mirror", s.handleMirror)
+
+	// 路由令牌
+	mux.HandleFunc("/api/v1/routing-token", s.handleRoutingToken)
+
+	// 集群视图
+	mux.HandleFunc("/api/v1/cluster", s.handleClusterView)
+	mux.HandleFunc(cluster.LocalPath, s.handleClusterLocal)
 
 	// 监控
 	mux.HandleFunc("/api/v1/stats/server", s.handleServerStats)
 	mux.HandleFunc("/api/v1/stats/backend", s.handleBackendStats)
+	mux.HandleFunc("/api/v1/stats/upstream", s.handleUpstreamStats)
+	mux.HandleFunc("/api/v1/stats/route", s.handleRouteStats)
+	mux.HandleFunc("/api/v1/stats/history", s.handleStatsHistory)
 	mux.HandleFunc("/api/v1/report", s.handleReportPerformance)
+	mux.HandleFunc("/api/v1/monitor", s.handleMonitorSettings)
+	mux.HandleFunc("/metrics", s.handleMetrics)
 }
 
 // handleConfig 配置管理
@@ -103,7 +290,16 @@ func (s *Server) updateConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.configMgr.UpdateConfig(req.Config); err != nil {
+	if req.Config == nil {
+		http.Error(w, "config is required", http.StatusBadRequest)
+		return
+	}
+
+	var fieldErrs config.FieldErrors
+	if err := s.replaceConfig(req.Config); errors.As(err, &fieldErrs) {
+		writeValidationErrors(w, "Invalid config", fieldErrs)
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -114,6 +310,146 @@ func (s *Server) updateConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// replaceConfig 校验并替换整个配置，写入配置文件
+func (s *Server) replaceConfig(cfg *types.Config) error {
+	// 管理API的认证和TLS设置不能通过管理API修改；请求中没有的对端令牌（JSON中不返回）保留原值
+	current := s.configMgr.GetConfig()
+	cfg.GRPC.Auth = current.GRPC.Auth
+	cfg.GRPC.TLS = current.GRPC.TLS
+	if cfg.Cluster.Token == "" {
+		cfg.Cluster.Token = current.Cluster.Token
+	}
+	keepHiddenSecrets(cfg, current)
+	if errs := keepScriptHealthChecks(cfg, current); errs != nil {
+		return errs
+	}
+	return s.configMgr.UpdateConfig(cfg)
+}
+
+// keepHiddenSecrets JSON中不返回的密钥在请求中为空时保留原值，GET后原样PUT不会清空密钥
+func keepHiddenSecrets(cfg, current *types.Config) {
+	if cfg.RoutingToken.Secret == "" {
+		cfg.RoutingToken.Secret = current.RoutingToken.Secret
+	}
+	for name, upstream := range cfg.Upstreams {
+		prev := current.Upstreams[name]
+		if upstream == nil || upstream.Signing == nil || prev == nil || prev.Signing == nil || upstream.Signing.Type != prev.Signing.Type {
+			continue
+		}
+		signing := *upstream.Signing
+		if signing.SecretAccessKey == "" {
+			signing.SecretAccessKey = prev.Signing.SecretAccessKey
+		}
+		if signing.SessionToken == "" {
+			signing.SessionToken = prev.Signing.SessionToken
+		}
+		if signing.Secret == "" {
+			signing.Secret = prev.Signing.Secret
+		}
+		upstreamCopy := *upstream
+		upstreamCopy.Signing = &signing
+		cfg.Upstreams[name] = &upstreamCopy
+	}
+	for name, rule := range cfg.Routing {
+		prev := current.Routing[name]
+		if rule == nil || rule.BotFilter == nil || rule.BotFilter.ChallengeSecret != "" || prev == nil || prev.BotFilter == nil {
+			continue
+		}
+		botFilter := *rule.BotFilter
+		botFilter.ChallengeSecret = prev.BotFilter.ChallengeSecret
+		ruleCopy := *rule
+		ruleCopy.BotFilter = &botFilter
+		cfg.Routing[name] = &ruleCopy
+	}
+	cfg.Quota.Keys = keepQuotaKeys(cfg.Quota.Keys, current.Quota.Keys)
+	cfg.Webhooks = keepWebhookHeaders(cfg.Webhooks, current.Webhooks)
+	// 收集器地址变化时不把认证头发往新地址
+	if len(cfg.Tracing.Headers) == 0 && cfg.Tracing.Endpoint == current.Tracing.Endpoint {
+		cfg.Tracing.Headers = current.Tracing.Headers
+	}
+}
+
+// keepWebhookHeaders 请求头为空的Webhook沿用名称和URL都相同的原Webhook的请求头，URL变化时不把认证头发往新地址
+func keepWebhookHeaders(webhooks, current []types.WebhookConfig) []types.WebhookConfig {
+	if len(webhooks) == 0 {
+		return webhooks
+	}
+	kept := make([]types.WebhookConfig, len(webhooks))
+	copy(kept, webhooks)
+	for i := range kept {
+		if len(kept[i].Headers) > 0 {
+			continue
+		}
+		for _, prev := range current {
+			if prev.Name == kept[i].Name && prev.URL == kept[i].URL {
+				kept[i].Headers = prev.Headers
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// keepQuotaKeys 配额密钥没有其他标识，同一租户中密钥为空的条目按顺序沿用该租户原来的密钥
+func keepQuotaKeys(keys, current []types.QuotaKey) []types.QuotaKey {
+	if len(keys) == 0 {
+		return keys
+	}
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key.Key] = true
+	}
+	byTenant := make(map[string][]string)
+	for _, key := range current {
+		if !listed[key.Key] {
+			byTenant[key.Tenant] = append(byTenant[key.Tenant], key.Key)
+		}
+	}
+	kept := make([]types.QuotaKey, len(keys))
+	copy(kept, keys)
+	for i := range kept {
+		if kept[i].Key != "" {
+			continue
+		}
+		if prev := byTenant[kept[i].Tenant]; len(prev) > 0 {
+			kept[i].Key = prev[0]
+			byTenant[kept[i].Tenant] = prev[1:]
+		}
+	}
+	return kept
+}
+
+// keepScriptHealthChecks script健康检查只能保留配置文件中已有的：同一后端原来就是script检查时沿用原命令
+// （JSON中不返回命令），命令不同或新增script检查时拒绝
+func keepScriptHealthChecks(cfg, current *types.Config) config.FieldErrors {
+	var errs config.FieldErrors
+	for upstream, backends := range cfg.Backends {
+		for i, b := range backends {
+			if b == nil || b.HealthCheck == nil || b.HealthCheck.Type != types.HealthCheckScript {
+				continue
+			}
+			field := fmt.Sprintf("backends.%s[%d].health_check", upstream, i)
+			var prev *types.HealthCheck
+			for _, cb := range current.Backends[upstream] {
+				if cb.ID == b.ID && cb.HealthCheck != nil && cb.HealthCheck.Type == types.HealthCheckScript {
+					prev = cb.HealthCheck
+				}
+			}
+			switch {
+			case prev == nil:
+				errs = append(errs, config.FieldError{Field: field + ".type", Message: "script health checks can only be configured in the config file"})
+			case len(b.HealthCheck.Command) == 0:
+				hc := *b.HealthCheck
+				hc.Command = prev.Command
+				b.HealthCheck = &hc
+			case !slices.Equal(b.HealthCheck.Command, prev.Command):
+				errs = append(errs, config.FieldError{Field: field + ".command", Message: "script health check commands can only be changed in the config file"})
+			}
+		}
+	}
+	return errs
+}
+
 // handleReloadSSL 重新加载SSL
 func (s *Server) handleReloadSSL(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -123,17 +459,142 @@ func (s *Server) handleReloadSSL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.configMgr.ReloadSSL(); err != nil {
+	status, err := s.reloadSSL()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"message":     "SSL certificates reloaded successfully",
+		"certificate": status,
+	})
+}
+
+// handleConfigReload 查询配置热加载状态(GET)或立即重新加载配置文件或远程配置来源(POST)
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reload": s.configMgr.GetReloadStatus(),
+		})
+	case http.MethodPost:
+		changed, err := s.configMgr.Reload()
+		if err != nil {
+			var fieldErrs config.FieldErrors
+			errors.As(err, &fieldErrs)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": err.Error(),
+				"errors":  fieldErrs,
+				"reload":  s.configMgr.GetReloadStatus(),
+			})
+			return
+		}
+		message := "Config unchanged"
+		if changed {
+			message = "Config reloaded"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"changed": changed,
+			"message": message,
+			"reload":  s.configMgr.GetReloadStatus(),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// maxImportSize 导入的配置文档大小上限
+const maxImportSize = 16 << 20
+
+// handleConfigExport 导出当前配置为YAML或JSON文档，用于备份和复制到其他环境
+// 默认隐藏令牌和密钥，redact=false时导出原值
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = config.ExportYAML
+	}
+	redact := r.URL.Query().Get("redact") != "false"
+	data, err := config.ExportConfig(s.configMgr.GetConfig(), format, redact)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if format == config.ExportJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "application/yaml")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"speedmimi-config.%s\"", format))
+	w.Write(data)
+}
+
+// handleConfigImport 验证并应用完整的YAML或JSON配置文档，dry_run=true时只验证
+// 文档中的<redacted>使用当前配置中的值；管理API的认证和TLS设置保持不变
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	imported, err := config.ImportConfig(data, s.configMgr.GetConfig())
+	var fieldErrs config.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		writeValidationErrors(w, "Invalid config", fieldErrs)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Configuration is valid",
+		})
+		return
+	}
+	if err := s.replaceConfig(imported); errors.As(err, &fieldErrs) {
+		writeValidationErrors(w, "Invalid config", fieldErrs)
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "SSL certificates reloaded successfully",
+		"message": "Configuration imported successfully",
 	})
 }
 
+// reloadSSL 重新加载配置中的证书并替换代理正在使用的证书
+func (s *Server) reloadSSL() (*certwatch.Status, error) {
+	if err := s.configMgr.ReloadSSL(); err != nil {
+		return nil, err
+	}
+	return s.proxyServer.ReloadTLS()
+}
+
 // handleBackends 获取后端列表
 func (s *Server) handleBackends(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -157,8 +618,36 @@ func (s *Server) handleBackends(w http.ResponseWriter, r *http.Request) {
 	}
 
 	backends := upstream.GetBackends()
+	protocols, _ := s.proxyServer.BackendProtocols(upstreamID)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"backends": backends,
+		"backends":  backends,
+		"protocols": protocols,
+	})
+}
+
+// handleBackendDiagnostics 获取上游各后端的运行时诊断信息
+func (s *Server) handleBackendDiagnostics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	upstreamID := r.URL.Query().Get("upstream")
+	if upstreamID == "" {
+		http.Error(w, "upstream parameter required", http.StatusBadRequest)
+		return
+	}
+
+	diagnostics, err := s.proxyServer.BackendDiagnostics(upstreamID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upstream": upstreamID,
+		"backends": diagnostics,
 	})
 }
 
@@ -171,9 +660,88 @@ func (s *Server) handleAddBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var req struct {
+		Upstream string         `json:"upstream"`
+		Backend  *types.Backend `json:"backend"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Upstream == "" {
+		http.Error(w, "upstream is required", http.StatusBadRequest)
+		return
+	}
+
+	var fieldErrs config.FieldErrors
+	if err := s.addBackend(req.Upstream, req.Backend); errors.As(err, &fieldErrs) {
+		writeValidationErrors(w, "Invalid backend", fieldErrs)
+		return
+	} else if errors.Is(err, errUpstreamNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Backend added successfully",
+		"backend": req.Backend,
+	})
+}
+
+// addBackend 校验并向上游添加后端，写入配置文件
+// 校验失败时返回config.FieldErrors，上游不存在时返回errUpstreamNotFound
+func (s *Server) addBackend(upstreamID string, backend *types.Backend) error {
+	// 使用与配置加载相同的规则校验
+	if errs := config.ValidateBackend(backend); errs != nil {
+		return errs
+	}
+	if errs := scriptHealthCheckErrors(backend.HealthCheck); errs != nil {
+		return errs
+	}
+
+	// 查重和添加在同一次配置更新中完成，并发添加的后端不会互相覆盖
+	return s.configMgr.Update(func(cfg *types.Config) error {
+		backends, exists := cfg.Backends[upstreamID]
+		if !exists {
+			return errUpstreamNotFound
+		}
+
+		// 未指定ID时按配置加载的规则生成，与已有后端重复时拒绝，避免同一地址的两个后端无法区分
+		if backend.ID == "" {
+			backend.ID = config.BackendID(upstreamID, backend)
+		}
+		for _, b := range backends {
+			if b.ID == backend.ID {
+				return config.FieldErrors{{Field: "id", Message: "already exists in upstream " + upstreamID}}
+			}
+		}
+
+		cfg.Backends[upstreamID] = append(backends, backend)
+		return nil
+	})
+}
+
+// scriptHealthCheckErrors script健康检查会在代理主机上执行命令，只允许在配置文件中配置，不能通过管理API设置
+func scriptHealthCheckErrors(hc *types.HealthCheck) config.FieldErrors {
+	if hc == nil || hc.Type != types.HealthCheckScript {
+		return nil
+	}
+	return config.FieldErrors{{Field: "health_check.type", Message: "script health checks can only be configured in the config file"}}
+}
+
+// writeValidationErrors 返回字段级校验错误
+func writeValidationErrors(w http.ResponseWriter, message string, errs config.FieldErrors) {
+	w.WriteHeader(http.StatusBadRequest)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": false,
-		"message": "Not implemented yet",
+		"message": message,
+		"errors":  errs,
 	})
 }
 
@@ -208,10 +776,11 @@ func (s *Server) handleUpdateBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 除upstream_id和backend_id外，只更新请求中出现的字段
 	var req struct {
 		UpstreamID string `json:"upstream_id"`
 		BackendID  string `json:"backend_id"`
-		MaxConn    int    `json:"max_conn"`
+		backendUpdate
 	}
 
 	if err := json.Unmarshal(body, &req); err != nil {
@@ -224,26 +793,15 @@ func (s *Server) handleUpdateBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取upstream
-	upstream := s.proxyServer.GetUpstreamManager().GetUpstream(req.UpstreamID)
-	if upstream == nil {
-		http.Error(w, "upstream not found", http.StatusNotFound)
+	var fieldErrs config.FieldErrors
+	if _, err := s.updateBackend(req.UpstreamID, req.BackendID, &req.backendUpdate); errors.As(err, &fieldErrs) {
+		writeValidationErrors(w, "Invalid backend", fieldErrs)
 		return
-	}
-
-	// 查找并更新后端
-	backends := upstream.GetBackends()
-	found := false
-	for _, backend := range backends {
-		if backend.ID == req.BackendID {
-			backend.MaxConn = req.MaxConn
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		http.Error(w, "backend not found", http.StatusNotFound)
+	} else if errors.Is(err, errUpstreamNotFound) || errors.Is(err, errBackendNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -253,6 +811,113 @@ func (s *Server) handleUpdateBackend(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// backendUpdate 后端的部分更新，nil字段保持不变
+type backendUpdate struct {
+	ID          *string            `json:"id"`
+	Name        *string            `json:"name"`
+	Host        *string            `json:"host"`
+	Port        *int               `json:"port"`
+	Weight      *int               `json:"weight"`
+	Scheme      *string            `json:"scheme"`
+	Active      *bool              `json:"active"`
+	MaxConn     *int               `json:"max_conn"`
+	Priority    *int               `json:"priority"`
+	Zone        *string            `json:"zone"`
+	Labels      *map[string]string `json:"labels"`
+	HealthCheck *types.HealthCheck `json:"health_check"`
+}
+
+// updateBackend 校验并替换运行中的后端，写入配置文件
+// 校验失败时返回config.FieldErrors，上游或后端不存在时返回errUpstreamNotFound或errBackendNotFound
+func (s *Server) updateBackend(upstreamID, backendID string, upd *backendUpdate) (*types.Backend, error) {
+	// 后端ID是健康状态、统计和管理API引用后端的标识，修改host或port时保持不变
+	if upd.ID != nil && *upd.ID != backendID {
+		return nil, config.FieldErrors{{Field: "id", Message: "is immutable; remove the backend and add a new one to change it"}}
+	}
+
+	if upd.HealthCheck != nil {
+		if errs := scriptHealthCheckErrors(upd.HealthCheck); errs != nil {
+			return nil, errs
+		}
+	}
+
+	// 运行中的后端被请求并发读取，不能就地修改：在配置副本中用新的后端对象替换，
+	// 通过Update在同一次配置更新中读取、替换并发布，并发的修改不会互相覆盖，校验失败时运行中的配置不受影响
+	var candidate *types.Backend
+	err := s.configMgr.Update(func(cfg *types.Config) error {
+		backends, exists := cfg.Backends[upstreamID]
+		if !exists {
+			return errUpstreamNotFound
+		}
+		index := slices.IndexFunc(backends, func(b *types.Backend) bool { return b.ID == backendID })
+		if index < 0 {
+			return errBackendNotFound
+		}
+
+		// 只复制配置字段，连接数、断开标记等运行时状态与原后端对象共用，健康状态在发布后由健康检查同步
+		current := backends[index]
+		candidate = &types.Backend{
+			ID:          current.ID,
+			Name:        current.Name,
+			Host:        current.Host,
+			Port:        current.Port,
+			Weight:      current.Weight,
+			Scheme:      current.Scheme,
+			Active:      current.Active,
+			MaxConn:     current.MaxConn,
+			Priority:    current.Priority,
+			Zone:        current.Zone,
+			Labels:      current.Labels,
+			HealthCheck: current.HealthCheck,
+		}
+		candidate.ShareRuntime(current)
+		if upd.Name != nil {
+			candidate.Name = *upd.Name
+		}
+		if upd.Host != nil {
+			candidate.Host = *upd.Host
+		}
+		if upd.Port != nil {
+			candidate.Port = *upd.Port
+		}
+		if upd.Weight != nil {
+			candidate.Weight = *upd.Weight
+		}
+		if upd.Scheme != nil {
+			candidate.Scheme = *upd.Scheme
+		}
+		if upd.MaxConn != nil {
+			candidate.MaxConn = *upd.MaxConn
+		}
+		if upd.Priority != nil {
+			candidate.Priority = *upd.Priority
+		}
+		if upd.Zone != nil {
+			candidate.Zone = *upd.Zone
+		}
+		if upd.Labels != nil {
+			candidate.Labels = *upd.Labels
+		}
+		if upd.HealthCheck != nil {
+			candidate.HealthCheck = upd.HealthCheck
+		}
+		if upd.Active != nil {
+			candidate.Active = *upd.Active
+		}
+		if errs := config.ValidateBackend(candidate); errs != nil {
+			return errs
+		}
+
+		// 发布并持久化到配置文件
+		backends[index] = candidate
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidate, nil
+}
+
 // handleDisconnectBackend 异步断开后端连接（标记机制）
 func (s *Server) handleDisconnectBackend(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -348,66 +1013,1603 @@ func (s *Server) verifyBackendStatus(upstreamID string) error {
 	return nil
 }
 
-// handleServerStats 获取服务器统计（非阻塞）
-func (s *Server) handleServerStats(w http.ResponseWriter, r *http.Request) {
+// handleUpstreams 上游子路由分发 (/api/v1/upstreams/{name}/...)
+func (s *Server) handleUpstreams(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/upstreams/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
-	// 从异步monitor获取最新的性能数据（非阻塞）
-	var stats *types.PerformanceInfo
-	if s.monitor != nil {
-		stats = s.monitor.GetStats()
-	} else {
-		// fallback
-		stats = &types.PerformanceInfo{
-			CPUUsage:    0,
-			MemoryUsage: 0,
-			DiskUsage:   0,
-			LoadAvg1:    0,
-			LoadAvg5:    0,
-			LoadAvg15:   0,
-			NetworkIn:   0,
-			NetworkOut:  0,
-			Timestamp:   0,
-		}
+	switch parts[1] {
+	case "health":
+		s.handleUpstreamHealth(w, r, parts[0])
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
 	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"stats": stats,
-	})
 }
 
-// handleBackendStats 获取后端统计
-func (s *Server) handleBackendStats(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
+// handleUpstreamHealth 按需主动探测上游的所有后端
+func (s *Server) handleUpstreamHealth(w http.ResponseWriter, r *http.Request, upstreamID string) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// 返回模拟数据
-	stats := &types.PerformanceInfo{
-		CPUUsage:    0,
-		MemoryUsage: 0,
-		DiskUsage:   0,
-		LoadAvg1:    0,
-		LoadAvg5:    0,
-		LoadAvg15:   0,
-		NetworkIn:   0,
-		NetworkOut:  0,
-		Timestamp:   0,
+	upstream := s.proxyServer.GetUpstreamManager().GetUpstream(upstreamID)
+	if upstream == nil {
+		http.Error(w, "upstream not found", http.StatusNotFound)
+		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"stats": stats,
-	})
-}
-
+	concurrency := defaultProbeConcurrency
+	if v := r.URL.Query().Get("concurrency"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid concurrency parameter", http.StatusBadRequest)
+			return
+		}
+		if n > maxProbeConcurrency {
+			n = maxProbeConcurrency
+		}
+		concurrency = n
+	}
+
+	var timeout time.Duration
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid timeout parameter", http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	results := s.prober.ProbeAll(upstream.GetAllBackends(), concurrency, timeout)
+
+	healthy := 0
+	for _, result := range results {
+		if result.Healthy {
+			healthy++
+		}
+	}
+
+	// 存在不健康后端时返回503，便于部署脚本直接根据状态码判断
+	if healthy < len(results) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upstream": upstreamID,
+		"total":    len(results),
+		"healthy":  healthy,
+		"results":  results,
+	})
+}
+
+// handleMaintenance 维护模式管理
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"maintenance": s.proxyServer.GetMaintenance().List(),
+		})
+	case http.MethodPost:
+		s.setMaintenance(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// setMaintenance 开启或关闭上游/路由的维护模式
+func (s *Server) setMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Scope       string `json:"scope"`
+		Name        string `json:"name"`
+		Enabled     bool   `json:"enabled"`
+		RetryAfter  int    `json:"retry_after"`
+		Body        string `json:"body"`
+		ContentType string `json:"content_type"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	cfg := s.configMgr.GetConfig()
+	switch req.Scope {
+	case proxy.MaintenanceScopeUpstream:
+		if _, exists := cfg.Backends[req.Name]; !exists {
+			http.Error(w, "upstream not found", http.StatusNotFound)
+			return
+		}
+	case proxy.MaintenanceScopeRoute:
+		if _, exists := cfg.Routing[req.Name]; !exists {
+			http.Error(w, "route not found", http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "scope must be upstream or route", http.StatusBadRequest)
+		return
+	}
+
+	maintenance := s.proxyServer.GetMaintenance()
+	if !req.Enabled {
+		maintenance.Disable(req.Scope, req.Name)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": fmt.Sprintf("Maintenance mode disabled for %s %s", req.Scope, req.Name),
+		})
+		return
+	}
+
+	state := &proxy.MaintenanceState{
+		Scope:       req.Scope,
+		Name:        req.Name,
+		RetryAfter:  req.RetryAfter,
+		Body:        req.Body,
+		ContentType: req.ContentType,
+	}
+	maintenance.Enable(state, &cfg.Maintenance)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"message":     fmt.Sprintf("Maintenance mode enabled for %s %s", req.Scope, req.Name),
+		"maintenance": state,
+	})
+}
+
+// handleBackendDrain 排空单个后端（POST），查看排空进度（GET）或取消排空（DELETE）
+func (s *Server) handleBackendDrain(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	drains := s.proxyServer.GetBackendDrains()
+	switch r.Method {
+	case http.MethodGet:
+		upstreamID := r.URL.Query().Get("upstream")
+		backendID := r.URL.Query().Get("backend_id")
+		if upstreamID == "" && backendID == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"drains": drains.List(),
+			})
+			return
+		}
+		status, ok := drains.Status(upstreamID, backendID)
+		if !ok {
+			http.Error(w, "no drain found for backend", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"drain": status,
+		})
+	case http.MethodPost:
+		s.startBackendDrain(w, r)
+	case http.MethodDelete:
+		upstreamID := r.URL.Query().Get("upstream")
+		backendID := r.URL.Query().Get("backend_id")
+		if upstreamID == "" || backendID == "" {
+			http.Error(w, "upstream and backend_id parameters required", http.StatusBadRequest)
+			return
+		}
+		status, err := drains.Cancel(upstreamID, backendID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Backend drain cancelled",
+			"drain":   status,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// startBackendDrain 开始排空后端，立即返回，进度通过GET查询
+func (s *Server) startBackendDrain(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UpstreamID string `json:"upstream_id"`
+		BackendID  string `json:"backend_id"`
+		Timeout    string `json:"timeout"` // 默认5m
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.UpstreamID == "" || req.BackendID == "" {
+		http.Error(w, "upstream_id and backend_id are required", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultBackendDrainTimeout
+	if req.Timeout != "" {
+		d, err := time.ParseDuration(req.Timeout)
+		if err != nil || d <= 0 || d > maxBackendDrainTimeout {
+			http.Error(w, fmt.Sprintf("timeout must be a positive duration up to %s", maxBackendDrainTimeout), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	status, err := s.proxyServer.DrainBackend(req.UpstreamID, req.BackendID, timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Backend drain started",
+		"drain":   status,
+	})
+}
+
+// handleDrainHost 排空（或取消排空）某台主机上所有上游的后端
+func (s *Server) handleDrainHost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Host  string `json:"host"`
+		Port  int    `json:"port"`
+		Drain *bool  `json:"drain"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Host == "" {
+		http.Error(w, "host is required", http.StatusBadRequest)
+		return
+	}
+
+	drain := req.Drain == nil || *req.Drain
+	backends := s.proxyServer.DrainHost(req.Host, req.Port, drain)
+	if len(backends) == 0 {
+		http.Error(w, "no backends found on host", http.StatusNotFound)
+		return
+	}
+
+	action := "drained"
+	if !drain {
+		action = "undrained"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("%d backends on host %s %s", len(backends), req.Host, action),
+		"backends": backends,
+	})
+}
+
+// handleRecyclePools 回收上游或单个后端的连接池
+func (s *Server) handleRecyclePools(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		UpstreamID string `json:"upstream_id"`
+		BackendID  string `json:"backend_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.UpstreamID == "" {
+		http.Error(w, "upstream_id is required", http.StatusBadRequest)
+		return
+	}
+
+	pools, err := s.proxyServer.RecyclePools(req.UpstreamID, req.BackendID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%d connection pools recycled", len(pools)),
+		"pools":   pools,
+	})
+}
+
+// handleServerDrain 停止接收新连接，等待处理中的请求完成后关闭进程（POST），或查看关闭进度（GET）
+// 供编排系统在终止实例前调用，wait为true时等待排空结束后才返回
+func (s *Server) handleServerDrain(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"shutdown": s.proxyServer.GetShutdownStatus(),
+		})
+	case http.MethodPost:
+		var req struct {
+			Timeout string `json:"timeout"` // 默认server.shutdown_timeout
+			Wait    bool   `json:"wait"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+		}
+
+		timeout := s.configMgr.GetConfig().Server.ShutdownTimeout
+		if req.Timeout != "" {
+			d, err := time.ParseDuration(req.Timeout)
+			if err != nil || d < 0 {
+				http.Error(w, "timeout must be a non-negative duration", http.StatusBadRequest)
+				return
+			}
+			timeout = d
+		}
+
+		status, err := s.proxyServer.BeginShutdown(timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if req.Wait {
+			select {
+			case <-s.proxyServer.ShutdownRequested():
+				status = *s.proxyServer.GetShutdownStatus()
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  "Server is shutting down",
+			"shutdown": status,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleQuiesce 查看或切换整个代理的静默模式
+func (s *Server) handleQuiesce(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"quiesce": s.proxyServer.GetQuiesceStatus(),
+		})
+	case http.MethodPost:
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if req.Enabled {
+			err = s.proxyServer.Quiesce()
+		} else {
+			err = s.proxyServer.Resume()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"quiesce": s.proxyServer.GetQuiesceStatus(),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleConnections 按条件列出当前客户端连接
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := proxy.ConnFilter{
+		ClientIP: query.Get("client_ip"),
+		Route:    query.Get("route"),
+		Upstream: query.Get("upstream"),
+		Backend:  query.Get("backend"),
+		Sort:     query.Get("sort"),
+	}
+
+	if filter.Sort != "" && filter.Sort != "age" && filter.Sort != "bytes" {
+		http.Error(w, "sort must be age or bytes", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("in_flight"); v != "" {
+		inFlight, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid in_flight parameter", http.StatusBadRequest)
+			return
+		}
+		filter.InFlight = inFlight
+	}
+	if v := query.Get("min_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid min_age parameter", http.StatusBadRequest)
+			return
+		}
+		filter.MinAge = d
+	}
+	if v := query.Get("min_bytes"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid min_bytes parameter", http.StatusBadRequest)
+			return
+		}
+		filter.MinBytes = n
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetConnTable().List(filter))
+}
+
+// handleAPIKeys 列出、创建和吊销API密钥
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": s.proxyServer.GetAPIKeys().List(),
+		})
+	case http.MethodPost:
+		s.createAPIKey(w, r)
+	case http.MethodDelete:
+		s.revokeAPIKey(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createAPIKey 生成新的API密钥并保存到配置，密钥本身只在响应中返回一次
+func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string                 `json:"name"`
+		Routes    []string               `json:"routes"`
+		RateLimit *types.RateLimitConfig `json:"rate_limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if limit := req.RateLimit; limit != nil {
+		if limit.Burst == 0 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+		}
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			http.Error(w, "rate_limit rate must be positive and burst at least 1", http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret, hash, err := apikey.Generate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id, err := apikey.NewID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	key := types.APIKey{
+		ID:        id,
+		Name:      req.Name,
+		Hash:      hash,
+		Routes:    req.Routes,
+		RateLimit: req.RateLimit,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	// 路由检查和添加在同一次配置更新中完成，并发创建的密钥不会互相覆盖
+	var missing string
+	err = s.configMgr.Update(func(cfg *types.Config) error {
+		for _, route := range req.Routes {
+			if _, exists := cfg.Routing[route]; !exists {
+				missing = route
+				return errRouteNotFound
+			}
+		}
+		cfg.APIKeys.Keys = append(append([]types.APIKey(nil), cfg.APIKeys.Keys...), key)
+		return nil
+	})
+	if errors.Is(err, errRouteNotFound) {
+		http.Error(w, "route not found: "+missing, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key created; store it now, it cannot be retrieved again",
+		"api_key": secret,
+		"key":     key,
+	})
+}
+
+// revokeAPIKey 从配置中删除API密钥
+func (s *Server) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.configMgr.Update(func(cfg *types.Config) error {
+		keys := make([]types.APIKey, 0, len(cfg.APIKeys.Keys))
+		for _, key := range cfg.APIKeys.Keys {
+			if key.ID != id {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == len(cfg.APIKeys.Keys) {
+			return errAPIKeyNotFound
+		}
+		cfg.APIKeys.Keys = keys
+		return nil
+	})
+	if errors.Is(err, errAPIKeyNotFound) {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("API key %s revoked", id),
+	})
+}
+
+// handleQuotaUsage 导出API密钥配额用量
+func (s *Server) handleQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m := s.proxyServer.GetQuota()
+	if m == nil {
+		http.Error(w, "Quota is not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period != "" && period != "current" && period != "previous" {
+		http.Error(w, "period must be current or previous", http.StatusBadRequest)
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	current, previous := m.Report(time.Now(), query.Get("tenant"))
+
+	if format == "csv" {
+		usages := current
+		if period == "previous" {
+			usages = previous
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writeQuotaCSV(w, usages)
+		return
+	}
+
+	response := map[string]interface{}{
+		"period": m.Period().String(),
+	}
+	if period != "previous" {
+		response["current"] = current
+	}
+	if period != "current" {
+		response["previous"] = previous
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeQuotaCSV 以CSV格式输出配额用量，用于计费导出
+func writeQuotaCSV(w io.Writer, usages []quota.Usage) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"tenant", "key", "period_start", "period_end", "requests", "bytes_in", "bytes_out", "rejected", "request_limit", "byte_limit"})
+	for _, u := range usages {
+		cw.Write([]string{
+			u.Tenant,
+			u.Key,
+			u.PeriodStart.Format(time.RFC3339),
+			u.PeriodEnd.Format(time.RFC3339),
+			strconv.FormatInt(u.Requests, 10),
+			strconv.FormatInt(u.BytesIn, 10),
+			strconv.FormatInt(u.BytesOut, 10),
+			strconv.FormatInt(u.Rejected, 10),
+			strconv.FormatInt(u.RequestLimit, 10),
+			strconv.FormatInt(u.ByteLimit, 10),
+		})
+	}
+	cw.Flush()
+}
+
+// handleHealthStatus 查看后台健康检查状态
+func (s *Server) handleHealthStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	upstream := r.URL.Query().Get("upstream")
+	if upstream != "" && s.proxyServer.GetUpstreamManager().GetUpstream(upstream) == nil {
+		http.Error(w, "Upstream not found", http.StatusNotFound)
+		return
+	}
+
+	statuses := s.proxyServer.GetHealthChecker().Status(upstream)
+	healthy := 0
+	for _, status := range statuses {
+		if status.Healthy {
+			healthy++
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":    len(statuses),
+		"healthy":  healthy,
+		"backends": statuses,
+	})
+}
+
+// handleHealthOverride 手动覆盖后端的健康状态
+func (s *Server) handleHealthOverride(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		UpstreamID string `json:"upstream_id"`
+		BackendID  string `json:"backend_id"`
+		Override   string `json:"override"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.UpstreamID == "" || req.BackendID == "" {
+		http.Error(w, "upstream_id and backend_id are required", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Override {
+	case healthcheck.OverrideHealthy, healthcheck.OverrideUnhealthy, healthcheck.OverrideNone:
+	default:
+		http.Error(w, "override must be healthy, unhealthy or none", http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.proxyServer.GetHealthChecker().SetOverride(req.UpstreamID, req.BackendID, req.Override)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Health override for backend %s/%s set to %s", req.UpstreamID, req.BackendID, req.Override),
+		"status":  status,
+	})
+}
+
+// handleAccessLog 查看和调整访问日志的级别和采样率
+func (s *Server) handleAccessLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logger := s.proxyServer.GetAccessLog()
+	if logger == nil {
+		http.Error(w, "Access log is not enabled", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req struct {
+			Level       *string `json:"level"`
+			SampleRate  *int    `json:"sample_rate"`
+			ErrorStatus *int    `json:"error_status"`
+			MaxRate     *int    `json:"max_rate"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		// 只更新请求中出现的字段
+		settings := logger.Settings()
+		if req.Level != nil {
+			settings.Level = *req.Level
+		}
+		if req.SampleRate != nil {
+			settings.SampleRate = *req.SampleRate
+		}
+		if req.ErrorStatus != nil {
+			settings.ErrorStatus = *req.ErrorStatus
+		}
+		if req.MaxRate != nil {
+			settings.MaxRate = *req.MaxRate
+		}
+		if err := logger.Update(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": logger.Settings(),
+		"stats":    logger.Stats(),
+	})
+}
+
+// handleCacheStats 查看响应缓存统计
+func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c := s.proxyServer.GetCache()
+	if c == nil {
+		http.Error(w, "Response cache is not enabled", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cache": c.Stats(),
+	})
+}
+
+// handleSlowClients 获取慢客户端保护的设置和统计
+func (s *Server) handleSlowClients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetConnTable().SlowClientStats())
+}
+
+// handleSlowRequests 获取慢请求保护的设置和统计
+func (s *Server) handleSlowRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetConnTable().SlowRequestStats())
+}
+
+// handleConnLifetime 获取连接生命周期上限的设置和统计
+func (s *Server) handleConnLifetime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetConnTable().ConnLifetimeStats())
+}
+
+// handleAudit 获取并发访问审计模式的状态、违反的不变量和不安全回退统计
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(audit.Snapshot())
+}
+
+// handleClientLimits 获取单IP并发连接数上限的设置和统计
+func (s *Server) handleClientLimits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetConnTable().ClientLimitStats())
+}
+
+// handleConnClasses 获取各连接类别的并发和内存统计
+func (s *Server) handleConnClasses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"classes": s.proxyServer.GetConnClasses().Stats(),
+	})
+}
+
+// handleRateLimits 获取按客户端IP限速和总请求速率限制的统计
+func (s *Server) handleRateLimits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := s.proxyServer.GetRateLimits().Stats()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes":       stats.Routes,
+		"route_totals": stats.RouteTotals,
+		"upstreams":    stats.Upstreams,
+		"global":       stats.Global,
+	})
+}
+
+// handleConcurrencyLimits 获取各路由和各上游的并发请求数限制统计
+func (s *Server) handleConcurrencyLimits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := s.proxyServer.GetConcurrencyLimits().Stats()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes":    stats.Routes,
+		"upstreams": stats.Upstreams,
+	})
+}
+
+// handleBackendQueues 获取各上游等待后端连接的队列统计
+func (s *Server) handleBackendQueues(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetBackendQueues().Stats())
+}
+
+// handleLoadShedding 获取自适应负载卸载的状态和统计
+func (s *Server) handleLoadShedding(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.GetLoadShedding().Stats())
+}
+
+// handleMirror 获取流量镜像统计
+func (s *Server) handleMirror(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mirror": s.proxyServer.MirrorStats(),
+	})
+}
+
+// handleEarlyReject 获取请求行预检统计
+func (s *Server) handleEarlyReject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"early_reject": s.proxyServer.GetEarlyReject().Stats(),
+	})
+}
+
+// handlePathNormalization 获取请求路径规范化的设置和统计
+func (s *Server) handlePathNormalization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.PathNormalizationStats())
+}
+
+// handleRangeRequests 获取断点续传统计
+func (s *Server) handleRangeRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.proxyServer.RangeRequestStats())
+}
+
+// handleDebugTrace 路由或后端的限时调试跟踪
+func (s *Server) handleDebugTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(s.proxyServer.TraceStats())
+	case http.MethodPost:
+		s.startTrace(w, r)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		if err := s.proxyServer.StopTrace(id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// startTrace 开启调试跟踪，到达时长或请求数上限后自动关闭
+func (s *Server) startTrace(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Route       string `json:"route"`
+		Backend     string `json:"backend"`
+		Duration    string `json:"duration"`
+		MaxRequests int    `json:"max_requests"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Route != "" {
+		if _, exists := s.configMgr.GetConfig().Routing[req.Route]; !exists {
+			http.Error(w, "route not found", http.StatusNotFound)
+			return
+		}
+	}
+	if req.Backend != "" && !s.proxyServer.HasBackend(req.Backend) {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	session, err := s.proxyServer.StartTrace(proxy.TraceRequest{
+		Route:       req.Route,
+		Backend:     req.Backend,
+		Duration:    duration,
+		MaxRequests: req.MaxRequests,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"trace":   session,
+	})
+}
+
+// handleArtifacts 获取热加载数据文件的版本和加载状态
+func (s *Server) handleArtifacts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"artifacts": s.proxyServer.GetArtifacts().Status(),
+	})
+}
+
+// handleArtifactReload 立即重新加载数据文件，校验失败时继续使用当前版本
+func (s *Server) handleArtifactReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.proxyServer.GetArtifacts().Reload(req.Name)
+	if errors.Is(err, artifact.ErrNotFound) {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Artifact %s reloaded", req.Name),
+	})
+}
+
+// handleCachePurge 按主机和路径前缀清除响应缓存
+func (s *Server) handleCachePurge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Host       string `json:"host"`
+		PathPrefix string `json:"path_prefix"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	c := s.proxyServer.GetCache()
+	if c == nil {
+		http.Error(w, "Response cache is not enabled", http.StatusNotFound)
+		return
+	}
+
+	purged := c.Purge(req.Host, req.PathPrefix)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Purged %d cache entries", purged),
+		"purged":  purged,
+	})
+}
+
+// handleRouteSwitch 把路由切换到另一个上游（蓝绿发布），并等待旧请求处理完成
+func (s *Server) handleRouteSwitch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Route        string `json:"route"`
+		Upstream     string `json:"upstream"`
+		DrainTimeout string `json:"drain_timeout"` // 默认30s，0s表示不等待
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Route == "" || req.Upstream == "" {
+		http.Error(w, "route and upstream are required", http.StatusBadRequest)
+		return
+	}
+
+	drainTimeout := defaultSwitchDrainTimeout
+	if req.DrainTimeout != "" {
+		d, err := time.ParseDuration(req.DrainTimeout)
+		if err != nil || d < 0 || d > maxSwitchDrainTimeout {
+			http.Error(w, fmt.Sprintf("drain_timeout must be a duration between 0s and %s", maxSwitchDrainTimeout), http.StatusBadRequest)
+			return
+		}
+		drainTimeout = d
+	}
+
+	cfg := s.configMgr.GetConfig()
+	if _, exists := cfg.Routing[req.Route]; !exists {
+		http.Error(w, "routing rule not found", http.StatusNotFound)
+		return
+	}
+	if _, exists := cfg.Backends[req.Upstream]; !exists {
+		http.Error(w, "upstream not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := s.proxyServer.SwitchRoute(req.Route, req.Upstream, drainTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Route %s switched from %s to %s", result.Route, result.From, result.To),
+		"switch":  result,
+	})
+}
+
+// handleStandby 查看路由的冷备上游切换状态
+func (s *Server) handleStandby(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"standby": s.proxyServer.GetStandby().List(),
+	})
+}
+
+// handleRoutingToken 签发路由令牌（内部工具无需持有密钥即可定向访问指定上游/后端）
+func (s *Server) handleRoutingToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := s.configMgr.GetConfig()
+	if !cfg.RoutingToken.Enabled {
+		http.Error(w, "routing token is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Upstream  string `json:"upstream"`
+		BackendID string `json:"backend_id"`
+		TTL       string `json:"ttl"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	backends, exists := cfg.Backends[req.Upstream]
+	if !exists {
+		http.Error(w, "upstream not found", http.StatusNotFound)
+		return
+	}
+	if req.BackendID != "" {
+		found := false
+		for _, backend := range backends {
+			if backend.ID == req.BackendID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, "backend not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	ttl := cfg.RoutingToken.MaxTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+		if d > cfg.RoutingToken.MaxTTL {
+			http.Error(w, fmt.Sprintf("ttl exceeds max_ttl %s", cfg.RoutingToken.MaxTTL), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	token, err := routetoken.Sign(&routetoken.Claims{
+		Upstream:  req.Upstream,
+		BackendID: req.BackendID,
+		ExpiresAt: expiresAt.Unix(),
+	}, []byte(cfg.RoutingToken.Secret))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"header":     cfg.RoutingToken.Header,
+		"expires_at": expiresAt,
+	})
+}
+
+// handleClusterView 获取跨实例聚合的集群视图
+func (s *Server) handleClusterView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.configMgr.GetConfig().Cluster.Enabled {
+		http.Error(w, "cluster mode is not enabled", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cluster": s.cluster.View(),
+	})
+}
+
+// handleClusterLocal 获取本节点状态快照（供对端节点轮询）
+func (s *Server) handleClusterLocal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 未启用集群模式时不向对端暴露本节点的上游和流量
+	if !s.configMgr.GetConfig().Cluster.Enabled {
+		http.Error(w, "cluster mode is not enabled", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.cluster.LocalSnapshot())
+}
+
+// handleMonitorSettings 查看和调整性能监控的采样/上报间隔和开关
+// 只修改运行时设置，不写入配置文件
+func (s *Server) handleMonitorSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.monitor == nil {
+		http.Error(w, "Performance monitor is not running", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req struct {
+			SampleInterval   *string `json:"sample_interval"`
+			ReportInterval   *string `json:"report_interval"`
+			SamplingEnabled  *bool   `json:"sampling_enabled"`
+			ReportingEnabled *bool   `json:"reporting_enabled"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		// 只更新请求中出现的字段
+		settings := s.monitor.Settings()
+		if req.SampleInterval != nil {
+			d, err := time.ParseDuration(*req.SampleInterval)
+			if err != nil {
+				http.Error(w, "invalid sample_interval", http.StatusBadRequest)
+				return
+			}
+			settings.SampleInterval = d
+		}
+		if req.ReportInterval != nil {
+			d, err := time.ParseDuration(*req.ReportInterval)
+			if err != nil {
+				http.Error(w, "invalid report_interval", http.StatusBadRequest)
+				return
+			}
+			settings.ReportInterval = d
+		}
+		if req.SamplingEnabled != nil {
+			settings.SamplingEnabled = *req.SamplingEnabled
+		}
+		if req.ReportingEnabled != nil {
+			settings.ReportingEnabled = *req.ReportingEnabled
+		}
+		if err := s.monitor.Update(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Printf("[MONITOR] Sampling %v every %s, reporting %v every %s\n",
+			settings.SamplingEnabled, settings.SampleInterval, settings.ReportingEnabled, settings.ReportInterval)
+	}
+
+	settings := s.monitor.Settings()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": map[string]interface{}{
+			"sample_interval":   settings.SampleInterval.String(),
+			"report_interval":   settings.ReportInterval.String(),
+			"sampling_enabled":  settings.SamplingEnabled,
+			"reporting_enabled": settings.ReportingEnabled,
+		},
+	})
+}
+
+// handleServerStats 获取服务器统计（非阻塞）
+func (s *Server) handleServerStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, traffic := s.serverStats()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":   stats,
+		"traffic": traffic,
+	})
+}
+
+// serverStats 从异步monitor获取最新的性能数据和流量统计（非阻塞）
+func (s *Server) serverStats() (*types.PerformanceInfo, monitor.TrafficStats) {
+	if s.monitor == nil {
+		return &types.PerformanceInfo{}, monitor.TrafficStats{}
+	}
+	return s.monitor.GetStats(), s.monitor.GetTrafficStats()
+}
+
+// backendStats 一个后端的响应时间、流量和转发失败统计
+type backendStats struct {
+	monitor.LatencyStats
+	Traffic        monitor.TrafficCounters `json:"traffic"`
+	UpstreamErrors monitor.UpstreamErrors  `json:"upstream_errors"`
+}
+
+// handleBackendStats 获取每个后端的响应时间、流量和转发失败统计，可按upstream和backend_id过滤
+func (s *Server) handleBackendStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := []backendStats{}
+	if s.monitor != nil {
+		upstream := r.URL.Query().Get("upstream")
+		backendID := r.URL.Query().Get("backend_id")
+
+		// 合并响应时间和流量统计，只有一种统计的后端（如请求都失败了）也列出
+		index := make(map[[2]string]int)
+		for _, st := range s.monitor.BackendLatencies(upstream) {
+			if backendID == "" || st.Backend == backendID {
+				index[[2]string{st.Upstream, st.Backend}] = len(stats)
+				stats = append(stats, backendStats{LatencyStats: st})
+			}
+		}
+		for _, tr := range s.monitor.BackendTraffics(upstream) {
+			if backendID != "" && tr.Backend != backendID {
+				continue
+			}
+			i, ok := index[[2]string{tr.Upstream, tr.Backend}]
+			if !ok {
+				i = len(stats)
+				stats = append(stats, backendStats{LatencyStats: monitor.LatencyStats{Upstream: tr.Upstream, Backend: tr.Backend}})
+			}
+			stats[i].Traffic = tr.TrafficCounters
+			stats[i].UpstreamErrors = tr.UpstreamErrors
+		}
+		sort.Slice(stats, func(i, j int) bool {
+			if stats[i].Upstream != stats[j].Upstream {
+				return stats[i].Upstream < stats[j].Upstream
+			}
+			return stats[i].Backend < stats[j].Backend
+		})
+	}
+
+	buckets := make([]float64, len(monitor.LatencyBuckets))
+	for i, b := range monitor.LatencyBuckets {
+		buckets[i] = b.Seconds()
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backends":        stats,
+		"buckets_seconds": buckets,
+		"window":          monitor.LatencyWindow.String(),
+	})
+}
+
+// handleUpstreamStats 获取每个上游的流量和转发失败统计，可按upstream过滤
+func (s *Server) handleUpstreamStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := []monitor.UpstreamTraffic{}
+	if s.monitor != nil {
+		stats = s.monitor.UpstreamTraffics(r.URL.Query().Get("upstream"))
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upstreams": stats,
+	})
+}
+
+// handleRouteStats 获取每个路由的流量统计，可按route过滤
+func (s *Server) handleRouteStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := []monitor.RouteTraffic{}
+	if s.monitor != nil {
+		stats = s.monitor.RouteTraffics(r.URL.Query().Get("route"))
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes": stats,
+	})
+}
+
+// handleStatsHistory 查询指标历史，range默认1h，step为分桶的间隔，默认为采样间隔
+func (s *Server) handleStatsHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	metric, err := monitor.ParseHistoryMetric(query.Get("metric"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rng := time.Hour
+	if v := query.Get("range"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid range parameter", http.StatusBadRequest)
+			return
+		}
+		rng = d
+	}
+	var step time.Duration
+	if v := query.Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid step parameter", http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+
+	if s.monitor == nil {
+		http.Error(w, monitor.ErrHistoryDisabled.Error(), http.StatusNotFound)
+		return
+	}
+	series, err := s.monitor.History(metric, rng, step)
+	if errors.Is(err, monitor.ErrHistoryDisabled) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(series)
+}
+
 // handleReportPerformance 上报性能（异步处理）
 func (s *Server) handleReportPerformance(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -444,10 +2646,27 @@ func (s *Server) handleReportPerformance(w http.ResponseWriter, r *http.Request)
 
 		// 更新后端性能信息（异步）
 		if req.Upstream != "" && req.BackendID != "" && req.Performance != nil {
-			// 这里可以更新upstream中的后端性能信息
-			// 为了演示，我们暂时只记录
-			fmt.Printf("[PERF REPORT] %s/%s: CPU=%.1f%%, MEM=%.1f%%\n",
-				req.Upstream, req.BackendID, req.Performance.CPUUsage, req.Performance.MemoryUsage)
+			if err := s.reportPerformance(req.Upstream, req.BackendID, req.Performance); err != nil {
+				fmt.Printf("[PERF REPORT] %v: %s/%s\n", err, req.Upstream, req.BackendID)
+			}
 		}
 	}(body)
-}
\ No newline at end of file
+}
+
+// reportPerformance 记录后端上报的性能信息，上游或后端不存在时返回errUpstreamNotFound或errBackendNotFound
+func (s *Server) reportPerformance(upstreamID, backendID string, perf *types.PerformanceInfo) error {
+	upstream := s.proxyServer.GetUpstreamManager().GetUpstream(upstreamID)
+	if upstream == nil {
+		return errUpstreamNotFound
+	}
+	for _, backend := range upstream.GetAllBackends() {
+		if backend.ID == backendID {
+			perf.Source = types.PerformanceSourceReport
+			backend.UpdatePerformance(perf)
+			fmt.Printf("[PERF REPORT] %s/%s: CPU=%.1f%%, MEM=%.1f%%, skew=%dms\n",
+				upstreamID, backendID, perf.CPUUsage, perf.MemoryUsage, perf.ClockSkewMs)
+			return nil
+		}
+	}
+	return errBackendNotFound
+}
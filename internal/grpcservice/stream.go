@@ -0,0 +1,224 @@
+package grpcservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/monitor"
+)
+
+// statsStreamPath 实时统计推送的路径
+const statsStreamPath = "/api/v1/stats/stream"
+
+// 推送间隔和同时推送的连接数
+const (
+	defaultStreamInterval = time.Second
+	minStreamInterval     = 100 * time.Millisecond
+	maxStreamInterval     = time.Minute
+	maxStatsStreams       = 64
+)
+
+// statsSnapshot 一次推送的统计快照，速率按与上一次快照的差值计算
+type statsSnapshot struct {
+	Timestamp           time.Time            `json:"timestamp"`
+	Traffic             monitor.TrafficStats `json:"traffic"`
+	RPS                 float64              `json:"rps"`
+	SentBytesPerSec     float64              `json:"sent_bytes_per_sec"`
+	ReceivedBytesPerSec float64              `json:"received_bytes_per_sec"`
+	Upstreams           []streamRate         `json:"upstreams"`
+	Routes              []streamRate         `json:"routes"`
+}
+
+// streamRate 一个上游或路由的请求速率和活跃连接数
+type streamRate struct {
+	Name              string  `json:"name"`
+	Requests          int64   `json:"requests"`
+	RPS               float64 `json:"rps"`
+	ErrorsPerSec      float64 `json:"errors_per_sec"`
+	ActiveConnections int64   `json:"active_connections"`
+}
+
+// snapshotter 生成一个推送连接的快照，保存上一次的计数用于计算速率
+type snapshotter struct {
+	monitor   *monitor.PerformanceMonitor
+	last      time.Time
+	traffic   monitor.TrafficStats
+	upstreams map[string]monitor.TrafficCounters
+	routes    map[string]monitor.TrafficCounters
+}
+
+func (sn *snapshotter) next(now time.Time) *statsSnapshot {
+	snap := &statsSnapshot{Timestamp: now, Upstreams: []streamRate{}, Routes: []streamRate{}}
+	if sn.monitor == nil {
+		return snap
+	}
+	elapsed := now.Sub(sn.last).Seconds()
+	rate := func(cur, prev int64) float64 {
+		if sn.last.IsZero() || elapsed <= 0 || cur < prev {
+			return 0
+		}
+		return float64(cur-prev) / elapsed
+	}
+
+	snap.Traffic = sn.monitor.GetTrafficStats()
+	snap.RPS = rate(snap.Traffic.TotalRequests, sn.traffic.TotalRequests)
+	snap.SentBytesPerSec = rate(snap.Traffic.BytesSent, sn.traffic.BytesSent)
+	snap.ReceivedBytesPerSec = rate(snap.Traffic.BytesRecv, sn.traffic.BytesRecv)
+
+	upstreams := make(map[string]monitor.TrafficCounters)
+	for _, st := range sn.monitor.UpstreamTraffics("") {
+		prev := sn.upstreams[st.Upstream]
+		snap.Upstreams = append(snap.Upstreams, streamRate{
+			Name:              st.Upstream,
+			Requests:          st.Requests,
+			RPS:               rate(st.Requests, prev.Requests),
+			ErrorsPerSec:      rate(st.Errors, prev.Errors),
+			ActiveConnections: st.ActiveConnections,
+		})
+		upstreams[st.Upstream] = st.TrafficCounters
+	}
+	routes := make(map[string]monitor.TrafficCounters)
+	for _, st := range sn.monitor.RouteTraffics("") {
+		prev := sn.routes[st.Route]
+		snap.Routes = append(snap.Routes, streamRate{
+			Name:              st.Route,
+			Requests:          st.Requests,
+			RPS:               rate(st.Requests, prev.Requests),
+			ErrorsPerSec:      rate(st.Errors, prev.Errors),
+			ActiveConnections: st.ActiveConnections,
+		})
+		routes[st.Route] = st.TrafficCounters
+	}
+
+	sn.last, sn.traffic, sn.upstreams, sn.routes = now, snap.Traffic, upstreams, routes
+	return snap
+}
+
+// parseStreamInterval 推送间隔，可以是时长（如500ms）或秒数
+func parseStreamInterval(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultStreamInterval, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(v, 64)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid interval %q", v)
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	if d < minStreamInterval || d > maxStreamInterval {
+		return 0, fmt.Errorf("interval must be between %s and %s", minStreamInterval, maxStreamInterval)
+	}
+	return d, nil
+}
+
+// withStatsStream 实时统计推送不经过管理API的工作协程限制，长连接不会占用处理其他管理请求的工作协程；
+// 同时推送的连接数单独限制
+func (s *Server) withStatsStream(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == statsStreamPath {
+			s.handleStatsStream(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStatsStream 按interval推送统计快照，请求带WebSocket升级头时使用WebSocket，否则使用SSE
+func (s *Server) handleStatsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	interval, err := parseStreamInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.streams.Add(1) > maxStatsStreams {
+		s.streams.Add(-1)
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Service Unavailable (too many stats streams)", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.streams.Add(-1)
+
+	sn := &snapshotter{monitor: s.monitor}
+	if isWebSocketUpgrade(r) {
+		s.streamWebSocket(w, r, sn, interval)
+		return
+	}
+	s.streamSSE(w, r, sn, interval)
+}
+
+// streamSSE 以text/event-stream推送，每个快照是一个stats事件
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request, sn *snapshotter, interval time.Duration) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: %d\n\n", interval.Milliseconds())
+
+	s.runStream(r, interval, sn, func(snap *statsSnapshot) error {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, nil)
+}
+
+// streamWebSocket 升级为WebSocket，每个快照是一个文本消息
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request, sn *snapshotter, interval time.Duration) {
+	ws, err := acceptWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.close()
+
+	s.runStream(r, interval, sn, func(snap *statsSnapshot) error {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		return ws.writeText(data, interval)
+	}, ws.done)
+}
+
+// runStream 立即推送一次，之后每个interval推送一次，直到客户端断开、send失败或管理API关闭
+func (s *Server) runStream(r *http.Request, interval time.Duration, sn *snapshotter, send func(*statsSnapshot) error, closed <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := send(sn.next(time.Now())); err != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		case <-closed:
+			return
+		case <-s.streamsDone:
+			return
+		}
+	}
+}
+
+// stopStreams 结束所有推送连接，SSE连接不会阻塞Shutdown等待处理中的请求
+func (s *Server) stopStreams() {
+	s.streamsOnce.Do(func() { close(s.streamsDone) })
+}
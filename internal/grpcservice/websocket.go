@@ -0,0 +1,213 @@
+package grpcservice
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID RFC 6455握手计算Sec-WebSocket-Accept使用的GUID
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket帧类型
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// maxWSControlPayload 控制帧的最大负载，推送连接不接受客户端的数据帧
+const maxWSControlPayload = 125
+
+// wsConn 只发送文本消息的服务端WebSocket连接，读取协程回应ping和close
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex    // 串行写入
+	done chan struct{} // 客户端关闭或读取失败时关闭
+}
+
+// isWebSocketUpgrade 请求是否为WebSocket升级请求，请求头名称和值不区分大小写
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerHasToken 逗号分隔的请求头值中是否包含token
+func headerHasToken(h http.Header, name, token string) bool {
+	for key, values := range h {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		for _, v := range values {
+			for _, t := range strings.Split(v, ",") {
+				if strings.EqualFold(strings.TrimSpace(t), token) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// headerValue 不区分大小写地取请求头
+func headerValue(h http.Header, name string) string {
+	for key, values := range h {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// acceptWebSocket 完成RFC 6455握手并接管连接，失败时已写入错误响应
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := headerValue(r.Header, "Sec-WebSocket-Key")
+	if key == "" || headerValue(r.Header, "Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Bad WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("bad websocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket requires HTTP/1.1", http.StatusBadRequest)
+		return nil, errors.New("connection cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	ws := &wsConn{conn: conn, rw: rw, done: make(chan struct{})}
+	go ws.readLoop()
+	return ws, nil
+}
+
+// writeText 发送一个文本消息，超过timeout未写完时返回错误
+func (ws *wsConn) writeText(data []byte, timeout time.Duration) error {
+	return ws.writeFrame(wsOpText, data, timeout)
+}
+
+// writeFrame 发送一个不分片、不掩码的帧
+func (ws *wsConn) writeFrame(opcode byte, payload []byte, timeout time.Duration) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.rw.Write(payload); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+// readLoop 读取客户端的帧：回应ping，收到close时回应并结束，忽略其他消息
+func (ws *wsConn) readLoop() {
+	defer close(ws.done)
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpPing:
+			if ws.writeFrame(wsOpPong, payload, 5*time.Second) != nil {
+				return
+			}
+		case wsOpClose:
+			if len(payload) > 2 {
+				payload = payload[:2] // 只回送状态码
+			}
+			ws.writeFrame(wsOpClose, payload, 5*time.Second)
+			return
+		}
+	}
+}
+
+// readFrame 读取一个客户端帧，客户端帧必须带掩码；数据帧的负载被丢弃
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return 0, nil, errors.New("invalid frame length")
+		}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	if opcode&0x8 == 0 {
+		_, err := io.CopyN(io.Discard, ws.rw, int64(length))
+		return opcode, nil, err
+	}
+	if length > maxWSControlPayload {
+		return 0, nil, errors.New("control frame too large")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// close 发送close帧（1001，服务端结束推送）后关闭底层连接，读取协程随之结束
+func (ws *wsConn) close() {
+	ws.writeFrame(wsOpClose, []byte{0x03, 0xE9}, time.Second)
+	ws.conn.Close()
+	<-ws.done
+}
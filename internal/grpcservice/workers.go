@@ -0,0 +1,108 @@
+package grpcservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/healthcheck"
+)
+
+// adminLimiter 限制同时处理的管理API请求数，超过上限的请求排队等待，等待超时返回503
+type adminLimiter struct {
+	sem          chan struct{}
+	queueTimeout time.Duration
+	waiting      atomic.Int64
+	rejected     atomic.Int64
+}
+
+// AdminWorkerStats 管理API工作协程的使用情况
+type AdminWorkerStats struct {
+	Workers      int    `json:"workers"`
+	Active       int    `json:"active"`
+	Waiting      int64  `json:"waiting"`  // 正在等待空闲工作协程的请求数
+	Rejected     int64  `json:"rejected"` // 启动以来等待超时返回503的请求数
+	QueueTimeout string `json:"queue_timeout"`
+}
+
+// newAdminLimiter 创建管理API请求数限制，workers<=0时返回nil（不限制）
+func newAdminLimiter(workers int, queueTimeout time.Duration) *adminLimiter {
+	if workers <= 0 {
+		return nil
+	}
+	return &adminLimiter{
+		sem:          make(chan struct{}, workers),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// wrap 在获得工作协程后才调用next处理请求
+func (l *adminLimiter) wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			l.rejected.Add(1)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Service Unavailable (Admin API busy)", http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-l.sem }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire 获取工作协程，等待超过queueTimeout或客户端断开时返回false
+func (l *adminLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	return false
+}
+
+// stats 获取管理API工作协程的使用情况
+func (l *adminLimiter) stats() AdminWorkerStats {
+	if l == nil {
+		return AdminWorkerStats{}
+	}
+	return AdminWorkerStats{
+		Workers:      cap(l.sem),
+		Active:       len(l.sem),
+		Waiting:      l.waiting.Load(),
+		Rejected:     l.rejected.Load(),
+		QueueTimeout: l.queueTimeout.String(),
+	}
+}
+
+// handleControlPlane 获取管理API和后台健康检查工作协程的使用情况
+func (s *Server) handleControlPlane(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Admin  AdminWorkerStats        `json:"admin"`
+		Health healthcheck.WorkerStats `json:"health"`
+	}{
+		Admin:  s.admin.stats(),
+		Health: s.proxyServer.GetHealthChecker().WorkerStats(),
+	})
+}
@@ -0,0 +1,419 @@
+package healthcheck
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const (
+	// DefaultInterval 健康检查未配置间隔时使用的值
+	DefaultInterval = 30 * time.Second
+	// DefaultFailures 健康检查未配置失败次数时使用的值
+	DefaultFailures = 3
+
+	// tickInterval 检查器扫描到期后端的间隔，决定实际探测时间相对配置间隔的最大延迟
+	tickInterval = 100 * time.Millisecond
+)
+
+// 手动覆盖健康状态
+const (
+	OverrideNone      = "none"
+	OverrideHealthy   = "healthy"
+	OverrideUnhealthy = "unhealthy"
+)
+
+// Target 健康检查目标
+type Target struct {
+	Upstream string
+	Backend  *types.Backend
+}
+
+// Status 后端的健康状态
+type Status struct {
+	Upstream             string       `json:"upstream"`
+	BackendID            string       `json:"backend_id"`
+	Address              string       `json:"address"`
+	Type                 string       `json:"type,omitempty"` // 健康检查类型，未配置健康检查时为空
+	Healthy              bool         `json:"healthy"`        // 生效的健康状态（包括手动覆盖）
+	ProbeHealthy         bool         `json:"probe_healthy"`  // 健康检查判定的状态
+	Override             string       `json:"override,omitempty"`
+	ConsecutiveFailures  int          `json:"consecutive_failures"`
+	ConsecutiveSuccesses int          `json:"consecutive_successes"`
+	TotalChecks          int64        `json:"total_checks"`
+	TotalFailures        int64        `json:"total_failures"`
+	LastTransition       *time.Time   `json:"last_transition,omitempty"` // 健康检查状态最近一次变化的时间
+	LastCheck            *ProbeResult `json:"last_check,omitempty"`
+}
+
+// Transition 后端生效健康状态（包括手动覆盖）的变化
+type Transition struct {
+	Upstream string
+	Backend  *types.Backend
+	Healthy  bool
+	Reason   string
+	At       time.Time
+}
+
+// backendState 单个后端的健康检查状态，按 上游/后端ID 保存，配置重载后继续使用
+type backendState struct {
+	upstream             string
+	backend              *types.Backend
+	probeHealthy         bool
+	override             string
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	totalChecks          int64
+	totalFailures        int64
+	lastTransition       time.Time
+	last                 *ProbeResult
+	nextCheck            time.Time
+	running              bool
+}
+
+// Checker 后台健康检查器
+// 按各后端health_check配置的间隔（加上随机jitter）探测，连续失败fall次后判定为不健康，
+// 连续成功rise次后恢复；手动覆盖优先于探测结果
+type Checker struct {
+	probe        func(backend *types.Backend, timeout time.Duration) *ProbeResult // 探测单个后端，测试中替换为假探测器
+	targets      func() []Target
+	onTransition func(Transition) // 持有锁时调用，不能阻塞
+
+	mu      sync.Mutex
+	states  map[string]*backendState
+	workers int // 同时进行的探测数上限，0表示不限制
+	active  int // 正在进行的探测数
+	waiting int // 最近一次同步时已到期但等待空闲工作协程的后端数
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewChecker 创建健康检查器，targets返回当前需要跟踪的全部后端
+func NewChecker(prober *Prober, targets func() []Target) *Checker {
+	return &Checker{
+		probe:   prober.Probe,
+		targets: targets,
+		states:  make(map[string]*backendState),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// OnTransition 设置生效健康状态变化时的回调，需要在Start之前调用
+// 回调在持有检查器锁时执行，不能阻塞或调用检查器的方法
+func (c *Checker) OnTransition(fn func(Transition)) {
+	c.onTransition = fn
+}
+
+// WorkerStats 后台探测工作协程的使用情况
+type WorkerStats struct {
+	Workers int `json:"workers"` // 同时进行的探测数上限，0表示不限制
+	Active  int `json:"active"`  // 正在进行的探测数
+	Waiting int `json:"waiting"` // 已到期但等待空闲工作协程的后端数
+}
+
+// SetWorkers 设置同时进行的探测数上限，超过上限的到期探测推迟到有空闲工作协程时进行
+func (c *Checker) SetWorkers(n int) {
+	c.mu.Lock()
+	c.workers = n
+	c.mu.Unlock()
+}
+
+// WorkerStats 获取后台探测工作协程的使用情况
+func (c *Checker) WorkerStats() WorkerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return WorkerStats{Workers: c.workers, Active: c.active, Waiting: c.waiting}
+}
+
+// Start 启动后台检查
+func (c *Checker) Start() {
+	go c.run()
+}
+
+// Stop 停止后台检查
+func (c *Checker) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+	})
+}
+
+func (c *Checker) run() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	c.Sync()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.Sync()
+		}
+	}
+}
+
+// Sync 同步目标列表：为新后端创建状态，把已有状态应用到重载后的后端对象，并启动到期的探测
+func (c *Checker) Sync() {
+	targets := c.targets()
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool, len(targets))
+	c.waiting = 0
+	for _, target := range targets {
+		key := stateKey(target.Upstream, target.Backend.ID)
+		seen[key] = true
+
+		st := c.states[key]
+		if st == nil {
+			st = &backendState{upstream: target.Upstream, probeHealthy: true}
+			c.states[key] = st
+		}
+		if st.backend != target.Backend {
+			st.backend = target.Backend
+			st.apply()
+		}
+
+		hc := target.Backend.HealthCheck
+		if hc == nil {
+			// 健康检查被移除后恢复为健康
+			if !st.probeHealthy {
+				wasHealthy := st.healthy()
+				st.probeHealthy = true
+				st.consecutiveFailures = 0
+				st.lastTransition = now
+				st.apply()
+				c.transition(st, wasHealthy, "health check removed", now)
+			}
+			continue
+		}
+		if st.running || now.Before(st.nextCheck) {
+			continue
+		}
+
+		// 配置了jitter时首次探测也随机延迟，避免启动或重载后所有后端同时探测
+		if st.nextCheck.IsZero() && hc.Jitter > 0 {
+			st.nextCheck = now.Add(jitter(hc.Jitter))
+			continue
+		}
+
+		if c.workers > 0 && c.active >= c.workers {
+			c.waiting++
+			continue
+		}
+
+		interval := hc.Interval
+		if interval <= 0 {
+			interval = DefaultInterval
+		}
+		st.running = true
+		c.active++
+		st.nextCheck = now.Add(interval + jitter(hc.Jitter))
+		go c.check(key, st, target.Backend)
+	}
+
+	for key := range c.states {
+		if !seen[key] {
+			delete(c.states, key)
+		}
+	}
+}
+
+// check 执行一次探测并更新状态
+func (c *Checker) check(key string, st *backendState, backend *types.Backend) {
+	result := c.probe(backend, 0)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st.running = false
+	c.active--
+	if c.states[key] != st {
+		return // 后端已被移除
+	}
+
+	st.last = result
+	st.totalChecks++
+	wasHealthy := st.healthy()
+	var reason string
+
+	rise, fall := thresholds(backend.HealthCheck)
+	if result.Healthy {
+		st.consecutiveSuccesses++
+		st.consecutiveFailures = 0
+		if !st.probeHealthy && st.consecutiveSuccesses >= rise {
+			st.probeHealthy = true
+			st.lastTransition = result.CheckedAt
+			reason = fmt.Sprintf("%d consecutive successful checks", st.consecutiveSuccesses)
+			fmt.Printf("[HEALTH] Backend %s is healthy again after %d consecutive successes\n", key, st.consecutiveSuccesses)
+		}
+	} else {
+		st.consecutiveFailures++
+		st.consecutiveSuccesses = 0
+		st.totalFailures++
+
+		if st.probeHealthy && st.consecutiveFailures >= fall {
+			st.probeHealthy = false
+			st.lastTransition = result.CheckedAt
+			reason = fmt.Sprintf("%d consecutive failed checks: %s", st.consecutiveFailures, result.Error)
+			fmt.Printf("[HEALTH] Backend %s is unhealthy after %d consecutive failures: %s\n", key, st.consecutiveFailures, result.Error)
+		}
+	}
+
+	st.apply()
+	c.transition(st, wasHealthy, reason, result.CheckedAt)
+}
+
+// SetOverride 手动覆盖后端的健康状态，override为OverrideNone时恢复使用健康检查结果
+func (c *Checker) SetOverride(upstream, backendID, override string) (*Status, error) {
+	switch override {
+	case OverrideNone, OverrideHealthy, OverrideUnhealthy:
+	default:
+		return nil, fmt.Errorf("override must be %s, %s or %s", OverrideHealthy, OverrideUnhealthy, OverrideNone)
+	}
+
+	// 先同步，确保刚添加的后端也可以覆盖
+	c.Sync()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := stateKey(upstream, backendID)
+	st := c.states[key]
+	if st == nil {
+		return nil, fmt.Errorf("backend %s not found in upstream %s", backendID, upstream)
+	}
+
+	wasHealthy := st.healthy()
+	if override == OverrideNone {
+		st.override = ""
+	} else {
+		st.override = override
+	}
+	st.apply()
+	c.transition(st, wasHealthy, "manual override "+override, time.Now())
+	fmt.Printf("[HEALTH] Backend %s health override set to %s\n", key, override)
+
+	status := st.status()
+	return &status, nil
+}
+
+// Status 获取后端的健康状态，upstream为空时返回全部上游，按上游和后端ID排序
+func (c *Checker) Status(upstream string) []Status {
+	c.mu.Lock()
+	statuses := make([]Status, 0, len(c.states))
+	for _, st := range c.states {
+		if upstream != "" && st.upstream != upstream {
+			continue
+		}
+		statuses = append(statuses, st.status())
+	}
+	c.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Upstream != statuses[j].Upstream {
+			return statuses[i].Upstream < statuses[j].Upstream
+		}
+		return statuses[i].BackendID < statuses[j].BackendID
+	})
+	return statuses
+}
+
+// transition 生效健康状态变化时调用回调
+func (c *Checker) transition(st *backendState, wasHealthy bool, reason string, at time.Time) {
+	healthy := st.healthy()
+	if healthy == wasHealthy || c.onTransition == nil {
+		return
+	}
+	c.onTransition(Transition{
+		Upstream: st.upstream,
+		Backend:  st.backend,
+		Healthy:  healthy,
+		Reason:   reason,
+		At:       at,
+	})
+}
+
+// apply 把生效的健康状态写入后端
+func (st *backendState) apply() {
+	if st.backend != nil {
+		st.backend.SetHealthy(st.healthy())
+	}
+}
+
+// healthy 生效的健康状态
+func (st *backendState) healthy() bool {
+	switch st.override {
+	case OverrideHealthy:
+		return true
+	case OverrideUnhealthy:
+		return false
+	}
+	return st.probeHealthy
+}
+
+func (st *backendState) status() Status {
+	status := Status{
+		Upstream:             st.upstream,
+		BackendID:            st.backend.ID,
+		Address:              net.JoinHostPort(st.backend.Host, strconv.Itoa(st.backend.Port)),
+		Healthy:              st.healthy(),
+		ProbeHealthy:         st.probeHealthy,
+		Override:             st.override,
+		ConsecutiveFailures:  st.consecutiveFailures,
+		ConsecutiveSuccesses: st.consecutiveSuccesses,
+		TotalChecks:          st.totalChecks,
+		TotalFailures:        st.totalFailures,
+		LastCheck:            st.last,
+	}
+	if hc := st.backend.HealthCheck; hc != nil {
+		status.Type = hc.Type
+		if status.Type == "" {
+			status.Type = types.HealthCheckHTTP
+		}
+	}
+	if !st.lastTransition.IsZero() {
+		t := st.lastTransition
+		status.LastTransition = &t
+	}
+	return status
+}
+
+// thresholds 恢复和判定不健康所需的连续成功/失败次数
+func thresholds(hc *types.HealthCheck) (rise, fall int) {
+	rise, fall = 1, DefaultFailures
+	if hc == nil {
+		return rise, fall
+	}
+	if hc.Rise > 0 {
+		rise = hc.Rise
+	}
+	if hc.Fall > 0 {
+		fall = hc.Fall
+	} else if hc.Failures > 0 {
+		fall = hc.Failures
+	}
+	return rise, fall
+}
+
+// jitter 返回[0, max)的随机时长
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// stateKey 状态键：上游/后端ID
+func stateKey(upstream, backendID string) string {
+	return upstream + "/" + backendID
+}
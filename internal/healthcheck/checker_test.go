@@ -0,0 +1,382 @@
+package healthcheck
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// fakeProber 按后端ID返回预设结果的探测器，记录探测次数
+type fakeProber struct {
+	mu      sync.Mutex
+	failing map[string]bool
+	calls   map[string]int
+}
+
+func newFakeProber() *fakeProber {
+	return &fakeProber{failing: make(map[string]bool), calls: make(map[string]int)}
+}
+
+func (f *fakeProber) setFailing(id string, failing bool) {
+	f.mu.Lock()
+	f.failing[id] = failing
+	f.mu.Unlock()
+}
+
+func (f *fakeProber) callCount(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[id]
+}
+
+func (f *fakeProber) probe(backend *types.Backend, _ time.Duration) *ProbeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[backend.ID]++
+	result := &ProbeResult{BackendID: backend.ID, Healthy: !f.failing[backend.ID], CheckedAt: time.Now()}
+	if !result.Healthy {
+		result.Error = "fake failure"
+	}
+	return result
+}
+
+// testChecker 使用假探测器的检查器，targets可以在测试中替换以模拟配置重载
+type testChecker struct {
+	*Checker
+	prober      *fakeProber
+	mu          sync.Mutex
+	targets     []Target
+	transitions []Transition
+}
+
+func newTestChecker(targets ...Target) *testChecker {
+	tc := &testChecker{prober: newFakeProber(), targets: targets}
+	tc.Checker = NewChecker(nil, func() []Target {
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+		return tc.targets
+	})
+	tc.Checker.probe = tc.prober.probe
+	tc.OnTransition(func(t Transition) {
+		tc.transitions = append(tc.transitions, t)
+	})
+	return tc
+}
+
+func (tc *testChecker) setTargets(targets ...Target) {
+	tc.mu.Lock()
+	tc.targets = targets
+	tc.mu.Unlock()
+}
+
+// round 同步一次并等待启动的探测全部完成
+func (tc *testChecker) round(t *testing.T) {
+	t.Helper()
+	tc.Sync()
+	deadline := time.Now().Add(5 * time.Second)
+	for tc.WorkerStats().Active > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("probes did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// takeTransitions 返回并清空记录的状态变化
+func (tc *testChecker) takeTransitions() []Transition {
+	tc.Checker.mu.Lock()
+	defer tc.Checker.mu.Unlock()
+	transitions := tc.transitions
+	tc.transitions = nil
+	return transitions
+}
+
+func (tc *testChecker) status(t *testing.T, id string) Status {
+	t.Helper()
+	for _, s := range tc.Status("") {
+		if s.BackendID == id {
+			return s
+		}
+	}
+	t.Fatalf("no status for backend %s", id)
+	return Status{}
+}
+
+// checkedBackend 每次同步都到期探测的后端
+func checkedBackend(id string, hc types.HealthCheck) *types.Backend {
+	hc.Interval = time.Nanosecond
+	return &types.Backend{ID: id, Host: "127.0.0.1", Port: 8080, HealthCheck: &hc}
+}
+
+func TestThresholds(t *testing.T) {
+	tests := []struct {
+		name           string
+		hc             *types.HealthCheck
+		wantRise, want int
+	}{
+		{"no health check", nil, 1, DefaultFailures},
+		{"defaults", &types.HealthCheck{}, 1, DefaultFailures},
+		{"failures", &types.HealthCheck{Failures: 5}, 1, 5},
+		{"fall overrides failures", &types.HealthCheck{Failures: 5, Fall: 2}, 1, 2},
+		{"rise", &types.HealthCheck{Rise: 3}, 3, DefaultFailures},
+	}
+	for _, tt := range tests {
+		rise, fall := thresholds(tt.hc)
+		if rise != tt.wantRise || fall != tt.want {
+			t.Errorf("%s: thresholds = %d, %d, want %d, %d", tt.name, rise, fall, tt.wantRise, tt.want)
+		}
+	}
+}
+
+func TestCheckerFallAndRise(t *testing.T) {
+	tests := []struct {
+		name       string
+		hc         types.HealthCheck
+		rise, fall int
+	}{
+		{"defaults", types.HealthCheck{}, 1, DefaultFailures},
+		{"fall 1", types.HealthCheck{Fall: 1}, 1, 1},
+		{"rise 3 fall 2", types.HealthCheck{Rise: 3, Fall: 2}, 3, 2},
+		{"legacy failures", types.HealthCheck{Failures: 4, Rise: 2}, 2, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := checkedBackend("b1", tt.hc)
+			tc := newTestChecker(Target{Upstream: "default", Backend: backend})
+
+			// 连续失败fall次才判定为不健康
+			tc.prober.setFailing("b1", true)
+			for i := 1; i < tt.fall; i++ {
+				tc.round(t)
+				if !backend.IsHealthy() {
+					t.Fatalf("unhealthy after %d failures, want fall %d", i, tt.fall)
+				}
+			}
+			tc.round(t)
+			if backend.IsHealthy() {
+				t.Fatalf("healthy after %d failures", tt.fall)
+			}
+			transitions := tc.takeTransitions()
+			if len(transitions) != 1 || transitions[0].Healthy || transitions[0].Backend != backend ||
+				!strings.Contains(transitions[0].Reason, "consecutive failed checks: fake failure") {
+				t.Fatalf("transitions after falling %+v, want one unhealthy transition", transitions)
+			}
+
+			// 连续成功rise次才恢复
+			tc.prober.setFailing("b1", false)
+			for i := 1; i < tt.rise; i++ {
+				tc.round(t)
+				if backend.IsHealthy() {
+					t.Fatalf("healthy after %d successes, want rise %d", i, tt.rise)
+				}
+			}
+			tc.round(t)
+			if !backend.IsHealthy() {
+				t.Fatalf("unhealthy after %d successes", tt.rise)
+			}
+			if transitions := tc.takeTransitions(); len(transitions) != 1 || !transitions[0].Healthy {
+				t.Fatalf("transitions after rising %+v, want one healthy transition", transitions)
+			}
+
+			status := tc.status(t, "b1")
+			if want := int64(tt.fall + tt.rise); status.TotalChecks != want || status.TotalFailures != int64(tt.fall) {
+				t.Fatalf("checks %d/%d failed, want %d/%d", status.TotalFailures, status.TotalChecks, tt.fall, want)
+			}
+			if status.LastTransition == nil || status.Address != "127.0.0.1:8080" {
+				t.Fatalf("status %+v, want last transition and address set", status)
+			}
+		})
+	}
+}
+
+func TestCheckerFailuresResetOnSuccess(t *testing.T) {
+	backend := checkedBackend("b1", types.HealthCheck{Fall: 2})
+	tc := newTestChecker(Target{Upstream: "default", Backend: backend})
+
+	// 失败之间的一次成功清零计数，不会判定为不健康
+	for _, failing := range []bool{true, false, true, false, true} {
+		tc.prober.setFailing("b1", failing)
+		tc.round(t)
+	}
+	if !backend.IsHealthy() {
+		t.Fatal("unhealthy although failures were never consecutive")
+	}
+	if status := tc.status(t, "b1"); status.ConsecutiveFailures != 1 || status.TotalFailures != 3 {
+		t.Fatalf("consecutive failures %d, total %d, want 1 and 3", status.ConsecutiveFailures, status.TotalFailures)
+	}
+}
+
+func TestCheckerOverride(t *testing.T) {
+	backend := checkedBackend("b1", types.HealthCheck{Fall: 1})
+	tc := newTestChecker(Target{Upstream: "default", Backend: backend})
+	tc.round(t)
+
+	if _, err := tc.SetOverride("default", "b1", "maybe"); err == nil {
+		t.Fatal("invalid override accepted")
+	}
+	if _, err := tc.SetOverride("default", "missing", OverrideHealthy); err == nil {
+		t.Fatal("override of an unknown backend accepted")
+	}
+
+	// 覆盖为不健康优先于成功的探测
+	status, err := tc.SetOverride("default", "b1", OverrideUnhealthy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Healthy || !status.ProbeHealthy || backend.IsHealthy() {
+		t.Fatalf("status %+v after unhealthy override, want unhealthy with a healthy probe", status)
+	}
+	tc.round(t)
+	if backend.IsHealthy() {
+		t.Fatal("successful probe cleared the unhealthy override")
+	}
+
+	// 覆盖期间探测结果变化不触发生效状态变化
+	tc.takeTransitions()
+	tc.prober.setFailing("b1", true)
+	tc.round(t)
+	if transitions := tc.takeTransitions(); len(transitions) != 0 {
+		t.Fatalf("transitions while overridden %+v, want none", transitions)
+	}
+
+	// 覆盖为健康优先于失败的探测
+	if _, err := tc.SetOverride("default", "b1", OverrideHealthy); err != nil {
+		t.Fatal(err)
+	}
+	tc.round(t)
+	if !backend.IsHealthy() {
+		t.Fatal("failing probe overrode the healthy override")
+	}
+
+	// 取消覆盖后恢复使用探测结果
+	status, err = tc.SetOverride("default", "b1", OverrideNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Healthy || status.Override != "" || backend.IsHealthy() {
+		t.Fatalf("status %+v after clearing the override, want the failing probe result", status)
+	}
+	transitions := tc.takeTransitions()
+	if len(transitions) != 2 || !transitions[0].Healthy || transitions[1].Healthy ||
+		transitions[1].Reason != "manual override none" {
+		t.Fatalf("transitions %+v, want healthy then unhealthy from the overrides", transitions)
+	}
+}
+
+func TestCheckerSyncCarriesStateAcrossReloads(t *testing.T) {
+	old := checkedBackend("b1", types.HealthCheck{Fall: 1, Rise: 2})
+	tc := newTestChecker(Target{Upstream: "default", Backend: old})
+	tc.prober.setFailing("b1", true)
+	tc.round(t)
+	if _, err := tc.SetOverride("default", "b1", OverrideUnhealthy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.SetOverride("default", "b1", OverrideNone); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重载后的新后端对象沿用探测状态和计数
+	reloaded := checkedBackend("b1", types.HealthCheck{Fall: 1, Rise: 2})
+	tc.setTargets(Target{Upstream: "default", Backend: reloaded})
+	tc.prober.setFailing("b1", false)
+	tc.round(t)
+	if reloaded.IsHealthy() {
+		t.Fatal("reloaded backend healthy after one success, want rise 2 carried over")
+	}
+	status := tc.status(t, "b1")
+	if status.TotalChecks != 2 || status.ConsecutiveSuccesses != 1 {
+		t.Fatalf("status %+v after reload, want counters carried over", status)
+	}
+	tc.round(t)
+	if !reloaded.IsHealthy() {
+		t.Fatal("reloaded backend still unhealthy after rise successes")
+	}
+
+	// 覆盖随重载保留并写入新后端对象
+	if _, err := tc.SetOverride("default", "b1", OverrideUnhealthy); err != nil {
+		t.Fatal(err)
+	}
+	again := checkedBackend("b1", types.HealthCheck{Fall: 1})
+	tc.setTargets(Target{Upstream: "default", Backend: again})
+	tc.Sync()
+	if again.IsHealthy() {
+		t.Fatal("override lost after reload")
+	}
+	if _, err := tc.SetOverride("default", "b1", OverrideNone); err != nil {
+		t.Fatal(err)
+	}
+
+	// 移除健康检查后恢复为健康
+	tc.prober.setFailing("b1", true)
+	tc.round(t)
+	if again.IsHealthy() {
+		t.Fatal("backend healthy after a failed check with fall 1")
+	}
+	tc.takeTransitions()
+	unchecked := &types.Backend{ID: "b1", Host: "127.0.0.1", Port: 8080}
+	tc.setTargets(Target{Upstream: "default", Backend: unchecked})
+	tc.round(t)
+	if !unchecked.IsHealthy() {
+		t.Fatal("backend unhealthy after its health check was removed")
+	}
+	if transitions := tc.takeTransitions(); len(transitions) != 1 || transitions[0].Reason != "health check removed" {
+		t.Fatalf("transitions %+v, want one from removing the health check", transitions)
+	}
+	calls := tc.prober.callCount("b1")
+	tc.round(t)
+	if tc.prober.callCount("b1") != calls {
+		t.Fatal("backend without a health check was probed")
+	}
+
+	// 从目标中移除的后端丢弃状态
+	tc.setTargets()
+	tc.Sync()
+	if statuses := tc.Status(""); len(statuses) != 0 {
+		t.Fatalf("statuses %+v after removing the backend, want none", statuses)
+	}
+}
+
+func TestCheckerJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(10 * time.Millisecond); d < 0 || d >= 10*time.Millisecond {
+			t.Fatalf("jitter(10ms) = %v, want [0, 10ms)", d)
+		}
+	}
+	if d := jitter(0); d != 0 {
+		t.Fatalf("jitter(0) = %v, want 0", d)
+	}
+
+	// 配置jitter时首次同步只安排探测时间，在[0, jitter)内随机延迟
+	backend := checkedBackend("b1", types.HealthCheck{Jitter: time.Hour})
+	tc := newTestChecker(Target{Upstream: "default", Backend: backend})
+	before := time.Now()
+	tc.round(t)
+	if calls := tc.prober.callCount("b1"); calls != 0 {
+		t.Fatalf("probed %d times on the first sync, want the check delayed by jitter", calls)
+	}
+	tc.Checker.mu.Lock()
+	next := tc.states[stateKey("default", "b1")].nextCheck
+	tc.Checker.mu.Unlock()
+	if next.Before(before) || !next.Before(before.Add(time.Hour+time.Second)) {
+		t.Fatalf("first check scheduled at %v, want within an hour of %v", next, before)
+	}
+
+	// 到期后探测，下次探测时间为间隔加上jitter
+	tc.Checker.mu.Lock()
+	tc.states[stateKey("default", "b1")].nextCheck = time.Now()
+	tc.Checker.mu.Unlock()
+	before = time.Now()
+	tc.round(t)
+	if calls := tc.prober.callCount("b1"); calls != 1 {
+		t.Fatalf("probed %d times once due, want 1", calls)
+	}
+	tc.Checker.mu.Lock()
+	next = tc.states[stateKey("default", "b1")].nextCheck
+	tc.Checker.mu.Unlock()
+	if next.Before(before) || !next.Before(time.Now().Add(time.Hour)) {
+		t.Fatalf("next check at %v, want interval plus jitter after %v", next, before)
+	}
+}
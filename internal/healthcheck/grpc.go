@@ -0,0 +1,175 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// GRPCHealthCheckMethod 标准gRPC健康检查方法
+const GRPCHealthCheckMethod = "/grpc.health.v1.Health/Check"
+
+// gRPC健康检查的服务状态（grpc.health.v1.HealthCheckResponse.ServingStatus）
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// maxGRPCResponseSize 健康检查响应的大小上限
+const maxGRPCResponseSize = 4096
+
+// newGRPCTransports 创建gRPC探测使用的HTTP/2传输：明文（h2c）和TLS各一个
+func newGRPCTransports() (h2c, h2 *http2.Transport) {
+	h2c = &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	h2 = &http2.Transport{}
+	return h2c, h2
+}
+
+// probeGRPC 使用grpc.health.v1.Health/Check探测后端，只有返回SERVING时才视为健康
+func (p *Prober) probeGRPC(backend *types.Backend, result *ProbeResult, timeout time.Duration) {
+	service := backend.HealthCheck.Service
+	transport, scheme := p.h2c, "http"
+	if backend.Scheme == "https" {
+		transport, scheme = p.h2, "https"
+	}
+	result.URL = fmt.Sprintf("grpc://%s/%s", result.Address, service)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+result.Address+GRPCHealthCheckMethod, bytes.NewReader(encodeGRPCHealthRequest(service)))
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("grpc-timeout", strconv.FormatInt(timeout.Milliseconds(), 10)+"m")
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		result.Latency = time.Since(start)
+		result.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGRPCResponseSize))
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+		return
+	}
+
+	// grpc-status在trailers中，服务端直接出错时（Trailers-Only）在响应头中
+	code := resp.Trailer.Get("grpc-status")
+	message := resp.Trailer.Get("grpc-message")
+	if code == "" {
+		code = resp.Header.Get("grpc-status")
+		message = resp.Header.Get("grpc-message")
+	}
+	if code != "0" {
+		if message, err := url.PathUnescape(message); err == nil && message != "" {
+			result.Error = fmt.Sprintf("grpc status %s: %s", code, message)
+		} else {
+			result.Error = fmt.Sprintf("grpc status %s", code)
+		}
+		return
+	}
+
+	status, err := decodeGRPCHealthResponse(body)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.ServingStatus = status
+	result.Healthy = status == "SERVING"
+	if !result.Healthy {
+		result.Error = fmt.Sprintf("serving status %s", status)
+	}
+}
+
+// encodeGRPCHealthRequest 编码HealthCheckRequest{service}为gRPC消息（5字节前缀+protobuf）
+func encodeGRPCHealthRequest(service string) []byte {
+	var msg []byte
+	if service != "" {
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, service)
+	}
+
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// decodeGRPCHealthResponse 解码HealthCheckResponse，返回服务状态名称
+func decodeGRPCHealthResponse(body []byte) (string, error) {
+	if len(body) < 5 {
+		return "", fmt.Errorf("grpc response too short: %d bytes", len(body))
+	}
+	if body[0] != 0 {
+		return "", fmt.Errorf("compressed grpc response is not supported")
+	}
+	n := binary.BigEndian.Uint32(body[1:5])
+	msg := body[5:]
+	if uint32(len(msg)) < n {
+		return "", fmt.Errorf("truncated grpc response: want %d bytes, got %d", n, len(msg))
+	}
+	msg = msg[:n]
+
+	var status uint64 // 字段缺省时为UNKNOWN
+	for len(msg) > 0 {
+		num, typ, l := protowire.ConsumeTag(msg)
+		if l < 0 {
+			return "", fmt.Errorf("invalid health check response: %w", protowire.ParseError(l))
+		}
+		msg = msg[l:]
+
+		if num == 1 && typ == protowire.VarintType {
+			v, l := protowire.ConsumeVarint(msg)
+			if l < 0 {
+				return "", fmt.Errorf("invalid health check response: %w", protowire.ParseError(l))
+			}
+			status, msg = v, msg[l:]
+			continue
+		}
+
+		l = protowire.ConsumeFieldValue(num, typ, msg)
+		if l < 0 {
+			return "", fmt.Errorf("invalid health check response: %w", protowire.ParseError(l))
+		}
+		msg = msg[l:]
+	}
+
+	if name, ok := grpcServingStatus[status]; ok {
+		return name, nil
+	}
+	return strconv.FormatUint(status, 10), nil
+}
@@ -0,0 +1,151 @@
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// grpcFrame 编码gRPC消息：压缩标志+声明的长度+消息
+func grpcFrame(compressed byte, length int, msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	frame[0] = compressed
+	binary.BigEndian.PutUint32(frame[1:], uint32(length))
+	return append(frame, msg...)
+}
+
+// healthResponse 编码HealthCheckResponse{status}
+func healthResponse(status uint64) []byte {
+	msg := protowire.AppendTag(nil, 1, protowire.VarintType)
+	return protowire.AppendVarint(msg, status)
+}
+
+func TestEncodeGRPCHealthRequest(t *testing.T) {
+	tests := []struct {
+		service string
+		want    []byte
+	}{
+		{"", []byte{0, 0, 0, 0, 0}},
+		{"api", []byte{0, 0, 0, 0, 5, 0x0a, 3, 'a', 'p', 'i'}},
+	}
+	for _, tt := range tests {
+		if got := encodeGRPCHealthRequest(tt.service); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeGRPCHealthRequest(%q) = %v, want %v", tt.service, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeGRPCHealthResponse(t *testing.T) {
+	serving := healthResponse(1)
+	// 未知字段被跳过
+	withUnknown := protowire.AppendString(protowire.AppendTag(nil, 7, protowire.BytesType), "x")
+	withUnknown = append(withUnknown, healthResponse(2)...)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		wantErr string
+	}{
+		{"serving", grpcFrame(0, len(serving), serving), "SERVING", ""},
+		{"not serving", grpcFrame(0, 2, healthResponse(2)), "NOT_SERVING", ""},
+		{"service unknown", grpcFrame(0, 2, healthResponse(3)), "SERVICE_UNKNOWN", ""},
+		{"empty message is unknown", grpcFrame(0, 0, nil), "UNKNOWN", ""},
+		{"undefined status", grpcFrame(0, 2, healthResponse(9)), "9", ""},
+		{"unknown field", grpcFrame(0, len(withUnknown), withUnknown), "NOT_SERVING", ""},
+		{"trailing frames ignored", append(grpcFrame(0, 2, serving), 0xff), "SERVING", ""},
+		{"short prefix", []byte{0, 0, 0}, "", "too short"},
+		{"truncated frame", grpcFrame(0, 10, serving), "", "truncated"},
+		{"compressed frame", grpcFrame(1, 2, serving), "", "compressed"},
+		{"invalid varint", grpcFrame(0, 2, []byte{0x08, 0x80}), "", "invalid health check response"},
+	}
+	for _, tt := range tests {
+		got, err := decodeGRPCHealthResponse(tt.body)
+		switch {
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want it to contain %q", tt.name, err, tt.wantErr)
+		case tt.wantErr == "" && (err != nil || got != tt.want):
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestProbeGRPC(t *testing.T) {
+	serving, notServing := healthResponse(1), healthResponse(2)
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		healthy bool
+		status  string
+		wantErr string
+	}{
+		{"serving", grpcResponse(grpcFrame(0, len(serving), serving), "0"), true, "SERVING", ""},
+		{"not serving", grpcResponse(grpcFrame(0, len(notServing), notServing), "0"), false, "NOT_SERVING", "serving status NOT_SERVING"},
+		{"trailers-only error", func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "12")
+			w.Header().Set("Grpc-Message", "unknown%20service")
+			w.WriteHeader(http.StatusOK)
+		}, false, "", "grpc status 12: unknown service"},
+		{"truncated frame", grpcResponse(grpcFrame(0, 10, serving), "0"), false, "", "truncated grpc response"},
+		{"compressed frame", grpcResponse(grpcFrame(1, len(serving), serving), "0"), false, "", "compressed grpc response"},
+		{"http error", func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }, false, "", "unexpected status code 503"},
+	}
+
+	p := NewProber()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var service string
+			server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.URL.Path == GRPCHealthCheckMethod && len(body) > 7 {
+					service = string(body[7:])
+				}
+				tt.handler(w)
+			}), &http2.Server{}))
+			defer server.Close()
+
+			addr := server.Listener.Addr().(*net.TCPAddr)
+			result := p.Probe(&types.Backend{
+				ID:          "grpc",
+				Host:        addr.IP.String(),
+				Port:        addr.Port,
+				Scheme:      "http",
+				HealthCheck: &types.HealthCheck{Type: types.HealthCheckGRPC, Service: "api"},
+			}, time.Second)
+
+			if result.Healthy != tt.healthy || result.ServingStatus != tt.status {
+				t.Errorf("healthy %v serving status %q, want %v %q (error %q)", result.Healthy, result.ServingStatus, tt.healthy, tt.status, result.Error)
+			}
+			if tt.wantErr != "" && !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("error %q, want it to contain %q", result.Error, tt.wantErr)
+			}
+			if service != "api" {
+				t.Errorf("request service %q, want api", service)
+			}
+		})
+	}
+}
+
+// grpcResponse 返回body并在trailers中设置grpc-status
+func grpcResponse(body []byte, status string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		w.Header().Set("Grpc-Status", status)
+	}
+}
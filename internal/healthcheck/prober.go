@@ -0,0 +1,223 @@
+package healthcheck
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const (
+	// DefaultProbePath 未配置健康检查时使用的探测路径
+	DefaultProbePath = "/"
+	// DefaultProbeTimeout 未配置健康检查时使用的探测超时
+	DefaultProbeTimeout = 5 * time.Second
+)
+
+// ProbeResult 单个后端的探测结果
+type ProbeResult struct {
+	BackendID     string        `json:"backend_id"`
+	Address       string        `json:"address"`
+	URL           string        `json:"url"`
+	Type          string        `json:"type"`
+	Healthy       bool          `json:"healthy"`
+	StatusCode    int           `json:"status_code"`
+	ServingStatus string        `json:"serving_status,omitempty"` // gRPC健康检查返回的服务状态
+	Latency       time.Duration `json:"latency"`
+	Error         string        `json:"error,omitempty"`
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+// Prober 健康探测器（按需主动探测）
+type Prober struct {
+	client *fasthttp.Client
+	h2c    *http2.Transport // gRPC探测（明文HTTP/2）
+	h2     *http2.Transport // gRPC探测（TLS）
+
+	regexps sync.Map // 响应体匹配的正则表达式缓存
+}
+
+// NewProber 创建健康探测器
+func NewProber() *Prober {
+	h2c, h2 := newGRPCTransports()
+	return &Prober{
+		h2c: h2c,
+		h2:  h2,
+		client: &fasthttp.Client{
+			// 探测请求使用独立客户端，不与数据面共享连接池
+			MaxConnsPerHost:               16,
+			MaxIdleConnDuration:           10 * time.Second,
+			NoDefaultUserAgentHeader:      true,
+			DisableHeaderNamesNormalizing: true,
+			DialDualStack:                 true, // 后端地址可以是IPv6
+		},
+	}
+}
+
+// probeFunc 一种健康检查协议的探测实现，把结果写入result
+type probeFunc func(p *Prober, backend *types.Backend, result *ProbeResult, timeout time.Duration)
+
+// probers 各健康检查类型的探测实现，按后端health_check.type选择
+var probers = map[string]probeFunc{
+	types.HealthCheckHTTP:   (*Prober).probeHTTP,
+	types.HealthCheckGRPC:   (*Prober).probeGRPC,
+	types.HealthCheckTCP:    (*Prober).probeTCP,
+	types.HealthCheckScript: (*Prober).probeScript,
+}
+
+// Probe 探测单个后端，timeout<=0时使用后端健康检查配置中的超时
+func (p *Prober) Probe(backend *types.Backend, timeout time.Duration) *ProbeResult {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+		if backend.HealthCheck != nil && backend.HealthCheck.Timeout > 0 {
+			timeout = backend.HealthCheck.Timeout
+		}
+	}
+
+	result := &ProbeResult{
+		BackendID: backend.ID,
+		Address:   net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)),
+		Type:      types.HealthCheckHTTP,
+		CheckedAt: time.Now(),
+	}
+	if backend.HealthCheck != nil && backend.HealthCheck.Type != "" {
+		result.Type = backend.HealthCheck.Type
+	}
+
+	probe, ok := probers[result.Type]
+	if !ok {
+		result.Error = fmt.Sprintf("unsupported health check type %q", result.Type)
+		return result
+	}
+	probe(p, backend, result, timeout)
+	return result
+}
+
+// probeHTTP 发送GET请求探测后端，按health_check的状态码和响应体规则判断是否健康
+func (p *Prober) probeHTTP(backend *types.Backend, result *ProbeResult, timeout time.Duration) {
+	path := DefaultProbePath
+	if backend.HealthCheck != nil && backend.HealthCheck.Path != "" {
+		path = backend.HealthCheck.Path
+	}
+	scheme := backend.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	result.URL = fmt.Sprintf("%s://%s%s", scheme, result.Address, path)
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(result.URL)
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	start := time.Now()
+	err := p.client.DoTimeout(req, resp, timeout)
+	result.Latency = time.Since(start)
+
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	result.StatusCode = resp.StatusCode()
+	if err := p.checkResponse(backend.HealthCheck, result.StatusCode, resp.Body()); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.Healthy = true
+}
+
+// checkResponse 按健康检查配置校验HTTP探测的状态码和响应体
+func (p *Prober) checkResponse(hc *types.HealthCheck, status int, body []byte) error {
+	if hc == nil || len(hc.StatusCodes) == 0 {
+		if status < 200 || status >= 400 {
+			return fmt.Errorf("unexpected status code %d", status)
+		}
+	} else if !containsStatus(hc.StatusCodes, status) {
+		return fmt.Errorf("unexpected status code %d, expected %v", status, hc.StatusCodes)
+	}
+
+	if hc == nil || (hc.BodyContains == "" && hc.BodyRegex == "") {
+		return nil
+	}
+
+	// 同时配置子串和正则时两者都满足才算匹配
+	matched := true
+	if hc.BodyContains != "" && !bytes.Contains(body, []byte(hc.BodyContains)) {
+		matched = false
+	}
+	if matched && hc.BodyRegex != "" {
+		re, err := p.regexp(hc.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid body_regex: %w", err)
+		}
+		matched = re.Match(body)
+	}
+
+	switch {
+	case matched && hc.BodyNot:
+		return fmt.Errorf("response body matches unhealthy pattern")
+	case !matched && !hc.BodyNot:
+		return fmt.Errorf("response body does not match expected pattern")
+	}
+	return nil
+}
+
+// regexp 获取编译后的正则表达式（按表达式缓存）
+func (p *Prober) regexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := p.regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	p.regexps.Store(expr, re)
+	return re, nil
+}
+
+// containsStatus 状态码是否在列表中
+func containsStatus(codes []int, status int) bool {
+	for _, code := range codes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeAll 并行探测一组后端，concurrency限制同时进行的探测数量
+// 结果顺序与输入的后端顺序一致
+func (p *Prober) ProbeAll(backends []*types.Backend, concurrency int, timeout time.Duration) []*ProbeResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]*ProbeResult, len(backends))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, backend := range backends {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, backend *types.Backend) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.Probe(backend, timeout)
+		}(i, backend)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,65 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// maxScriptOutput 失败时附在错误信息中的命令输出长度上限
+const maxScriptOutput = 512
+
+// probeScript 执行health_check.command，退出码为0时视为健康，超时后终止命令
+// 后端信息通过环境变量传给命令：SPEEDMIMI_BACKEND_ID、SPEEDMIMI_BACKEND_HOST、SPEEDMIMI_BACKEND_PORT、SPEEDMIMI_BACKEND_SCHEME
+func (p *Prober) probeScript(backend *types.Backend, result *ProbeResult, timeout time.Duration) {
+	command := backend.HealthCheck.Command
+	result.URL = "exec://" + strings.Join(command, " ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"SPEEDMIMI_BACKEND_ID="+backend.ID,
+		"SPEEDMIMI_BACKEND_HOST="+backend.Host,
+		"SPEEDMIMI_BACKEND_PORT="+strconv.Itoa(backend.Port),
+		"SPEEDMIMI_BACKEND_SCHEME="+backend.Scheme,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// 命令启动的子进程可能继承输出管道，超时后不再等待其关闭
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Latency = time.Since(start)
+	if err == nil {
+		result.Healthy = true
+		return
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Error = fmt.Sprintf("command timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.Error = fmt.Sprintf("command exited with status %d", exitErr.ExitCode())
+	default:
+		result.Error = err.Error()
+	}
+	if out := strings.TrimSpace(output.String()); out != "" {
+		if len(out) > maxScriptOutput {
+			out = out[:maxScriptOutput] + "..."
+		}
+		result.Error += ": " + out
+	}
+}
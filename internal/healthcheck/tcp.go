@@ -0,0 +1,24 @@
+package healthcheck
+
+import (
+	"net"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// probeTCP 只建立TCP连接后立即关闭，能在超时内建立连接即视为健康
+// 适用于没有HTTP健康检查端点的后端（数据库、消息队列等）
+func (p *Prober) probeTCP(backend *types.Backend, result *ProbeResult, timeout time.Duration) {
+	result.URL = "tcp://" + result.Address
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", result.Address, timeout)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	conn.Close()
+	result.Healthy = true
+}
@@ -6,19 +6,23 @@ import (
 	"math/rand"
 	"net"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/quqi/speedmimi/pkg/types"
 )
 
 // IPHashBalancer IP Hash负载均衡器
-type IPHashBalancer struct{}
+// 默认按客户端IP哈希，配置hash_key后按指定的请求属性哈希
+type IPHashBalancer struct {
+	key HashKey
+}
 
 func (b *IPHashBalancer) Name() string {
 	return "ip_hash"
 }
 
-func (b *IPHashBalancer) SelectBackend(backends []*types.Backend, req interface{}) *types.Backend {
+func (b *IPHashBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
 	if len(backends) == 0 {
 		return nil
 	}
@@ -35,26 +39,20 @@ func (b *IPHashBalancer) SelectBackend(backends []*types.Backend, req interface{
 		return nil // 所有后端都达到连接限制
 	}
 
-	// 获取客户端IP
-	clientIP := b.getClientIP(req)
-	if clientIP == "" {
-		// 如果无法获取IP，使用随机选择
+	// 获取哈希键（默认为客户端IP）
+	key := b.key.Extract(req)
+	if key == "" {
+		// 如果无法获取哈希键，使用随机选择
 		return b.selectRandom(availableBackends)
 	}
 
-	// 使用IP的hash值选择后端
-	hash := b.hashIP(clientIP)
+	// 使用哈希键的hash值选择后端
+	hash := b.hashIP(key)
 	index := int(hash) % len(availableBackends)
 
 	return availableBackends[index]
 }
 
-func (b *IPHashBalancer) getClientIP(req interface{}) string {
-	// 这里需要根据实际的请求类型来获取IP
-	// 暂时返回空字符串，具体实现会在代理层处理
-	return ""
-}
-
 func (b *IPHashBalancer) hashIP(ip string) uint32 {
 	h := fnv.New32a()
 	h.Write([]byte(ip))
@@ -85,7 +83,7 @@ func (b *LeastConnectionsBalancer) Name() string {
 	return "least_connections"
 }
 
-func (b *LeastConnectionsBalancer) SelectBackend(backends []*types.Backend, req interface{}) *types.Backend {
+func (b *LeastConnectionsBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
 	if len(backends) == 0 {
 		return nil
 	}
@@ -122,7 +120,7 @@ func (b *LeastConnectionsWeightBalancer) Name() string {
 	return "least_connections_weight"
 }
 
-func (b *LeastConnectionsWeightBalancer) SelectBackend(backends []*types.Backend, req interface{}) *types.Backend {
+func (b *LeastConnectionsWeightBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
 	if len(backends) == 0 {
 		return nil
 	}
@@ -189,7 +187,7 @@ func (b *WeightBalancer) Name() string {
 	return "weight"
 }
 
-func (b *WeightBalancer) SelectBackend(backends []*types.Backend, req interface{}) *types.Backend {
+func (b *WeightBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
 	if len(backends) == 0 {
 		return nil
 	}
@@ -228,13 +226,16 @@ func (b *WeightBalancer) SelectBackend(backends []*types.Backend, req interface{
 }
 
 // PerformanceLCWBalancer 性能+最少连接数+权重负载均衡器
-type PerformanceLCWBalancer struct{}
+// 超过有效期的性能上报不参与计算
+type PerformanceLCWBalancer struct {
+	reportTTL *atomic.Int64
+}
 
 func (b *PerformanceLCWBalancer) Name() string {
 	return "performance_least_connections_weight"
 }
 
-func (b *PerformanceLCWBalancer) SelectBackend(backends []*types.Backend, req interface{}) *types.Backend {
+func (b *PerformanceLCWBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
 	if len(backends) == 0 {
 		return nil
 	}
@@ -275,7 +276,7 @@ func (b *PerformanceLCWBalancer) calculateScore(backend *types.Backend) float64
 		weight = 1
 	}
 
-	utilization := backend.CalculateUtilization()
+	utilization := backend.CalculateFreshUtilization(loadReportTTL(b.reportTTL))
 
 	// 综合得分 = (连接数/权重) + 占用率权重
 	connectionScore := float64(connections) / weight
@@ -285,9 +286,164 @@ func (b *PerformanceLCWBalancer) calculateScore(backend *types.Backend) float64
 	return connectionScore*0.7 + performanceScore*0.3
 }
 
+// LeastResponseTimeBalancer 最短响应时间负载均衡器
+// 得分 = 平均延迟 × (连接数+1) / 权重 × (1 + 上报的占用率)，得分最低者胜出
+// 尚无延迟样本的后端使用其他后端的平均延迟，超过有效期的性能上报不参与计算
+type LeastResponseTimeBalancer struct {
+	reportTTL *atomic.Int64
+}
+
+func (b *LeastResponseTimeBalancer) Name() string {
+	return "least_response_time"
+}
+
+func (b *LeastResponseTimeBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
+	candidates := make([]*types.Backend, 0, len(backends))
+	var totalLatency time.Duration
+	measured := 0
+	for _, backend := range backends {
+		if !backend.IsActive() || backend.ShouldDisconnect() || backend.IsConnectionLimitReached() {
+			continue
+		}
+		candidates = append(candidates, backend)
+		if latency := backend.GetLatency(); latency > 0 {
+			totalLatency += latency
+			measured++
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil // 所有后端都达到连接限制
+	}
+
+	defaultLatency := time.Millisecond
+	if measured > 0 {
+		defaultLatency = totalLatency / time.Duration(measured)
+	}
+	ttl := loadReportTTL(b.reportTTL)
+
+	var selected *types.Backend
+	bestScore := math.MaxFloat64
+	for _, backend := range candidates {
+		latency := backend.GetLatency()
+		if latency <= 0 {
+			latency = defaultLatency
+		}
+		weight := float64(backend.Weight)
+		if weight <= 0 {
+			weight = 1
+		}
+
+		score := latency.Seconds() * float64(backend.GetConnections()+1) / weight
+		score *= 1 + backend.CalculateFreshUtilization(ttl)
+		if score < bestScore {
+			bestScore = score
+			selected = backend
+		}
+	}
+
+	return selected
+}
+
+// loadReportTTL 读取性能上报有效期
+func loadReportTTL(ttl *atomic.Int64) time.Duration {
+	if ttl == nil {
+		return 0
+	}
+	return time.Duration(ttl.Load())
+}
+
+// P2CBalancer 随机采样负载均衡器（power of two choices）
+// 每次随机采样sampleSize个可用后端，选择其中连接数/权重最低的
+type P2CBalancer struct {
+	sampleSize int
+}
+
+func (b *P2CBalancer) Name() string {
+	return "p2c"
+}
+
+func (b *P2CBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
+	candidates := make([]*types.Backend, 0, len(backends))
+	for _, backend := range backends {
+		if backend.IsActive() && !backend.ShouldDisconnect() && !backend.IsConnectionLimitReached() {
+			candidates = append(candidates, backend)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil // 所有后端都达到连接限制
+	}
+
+	sampleSize := b.sampleSize
+	if sampleSize <= 0 {
+		sampleSize = DefaultP2CSampleSize
+	}
+
+	var selected *types.Backend
+	bestScore := math.MaxFloat64
+	// 部分Fisher-Yates洗牌，采样不重复的后端
+	for i := 0; i < sampleSize && i < len(candidates); i++ {
+		j := i + rand.Intn(len(candidates)-i)
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+
+		backend := candidates[i]
+		weight := float64(backend.Weight)
+		if weight <= 0 {
+			weight = 1
+		}
+		score := float64(backend.GetConnections()+1) / weight
+		if score < bestScore {
+			bestScore = score
+			selected = backend
+		}
+	}
+
+	return selected
+}
+
+// ZoneAwareBalancer 可用区感知负载均衡器
+// 先在与代理同一可用区的后端中由内部负载均衡器选择，本区后端全部不可用或达到连接上限时才选择其他可用区
+type ZoneAwareBalancer struct {
+	inner types.LoadBalancer
+	zone  string
+}
+
+// NewZoneAwareBalancer 包装负载均衡器，zone为代理所在可用区
+func NewZoneAwareBalancer(inner types.LoadBalancer, zone string) *ZoneAwareBalancer {
+	return &ZoneAwareBalancer{inner: inner, zone: zone}
+}
+
+func (b *ZoneAwareBalancer) Name() string {
+	return "zone_aware_" + b.inner.Name()
+}
+
+func (b *ZoneAwareBalancer) SelectBackend(backends []*types.Backend, req types.RequestContext) *types.Backend {
+	local := make([]*types.Backend, 0, len(backends))
+	remote := make([]*types.Backend, 0, len(backends))
+	for _, backend := range backends {
+		if backend.Zone == b.zone {
+			local = append(local, backend)
+		} else {
+			remote = append(remote, backend)
+		}
+	}
+
+	if len(local) > 0 {
+		if backend := b.inner.SelectBackend(local, req); backend != nil {
+			return backend
+		}
+	}
+	if len(remote) == 0 {
+		return nil
+	}
+	return b.inner.SelectBackend(remote, req)
+}
+
 // 高性能负载均衡器工厂（无锁设计）
 type Factory struct {
 	balancers map[types.LoadBalancerType]types.LoadBalancer
+	reportTTL atomic.Int64 // 性能上报有效期，由使用性能上报的负载均衡器共享
 }
 
 func NewFactory() *Factory {
@@ -300,7 +456,9 @@ func NewFactory() *Factory {
 	f.balancers[types.LeastConnections] = &LeastConnectionsBalancer{}
 	f.balancers[types.LeastConnectionsWeight] = &LeastConnectionsWeightBalancer{}
 	f.balancers[types.Weight] = &WeightBalancer{}
-	f.balancers[types.PerformanceLCW] = &PerformanceLCWBalancer{}
+	f.balancers[types.PerformanceLCW] = &PerformanceLCWBalancer{reportTTL: &f.reportTTL}
+	f.balancers[types.PowerOfTwoChoices] = &P2CBalancer{}
+	f.balancers[types.LeastResponseTime] = &LeastResponseTimeBalancer{reportTTL: &f.reportTTL}
 
 	return f
 }
@@ -312,11 +470,38 @@ func (f *Factory) GetBalancer(lbType types.LoadBalancerType) types.LoadBalancer
 	return f.balancers[types.LeastConnectionsWeight] // 默认使用最少连接数+权重
 }
 
-// GetClientIP 获取客户端真实IP
-func GetClientIP(req interface{}, realIPHeader string, trustedProxies []string) string {
-	// 这里需要根据fasthttp的RequestCtx来实现
-	// 暂时提供一个基础实现
-	return ""
+// SetReportTTL 设置性能上报有效期，ttl<=0表示永不过期
+func (f *Factory) SetReportTTL(ttl time.Duration) {
+	f.reportTTL.Store(int64(ttl))
+}
+
+// NewBalancer 创建带参数的负载均衡器实例，不使用参数的类型返回共享实例
+// 参数应已通过ValidateParams校验，非法的hash_key按客户端IP处理
+// zone为代理所在可用区，params.ZoneAware为true且zone非空时包装为可用区感知负载均衡器
+func (f *Factory) NewBalancer(lbType types.LoadBalancerType, params types.LoadBalancerParams, zone string) types.LoadBalancer {
+	balancer := f.newBalancer(lbType, params)
+	if params.ZoneAware && zone != "" {
+		return NewZoneAwareBalancer(balancer, zone)
+	}
+	return balancer
+}
+
+// newBalancer 创建不含可用区感知的负载均衡器
+func (f *Factory) newBalancer(lbType types.LoadBalancerType, params types.LoadBalancerParams) types.LoadBalancer {
+	switch lbType {
+	case types.IPHash:
+		if params.HashKey == "" {
+			break
+		}
+		key, _ := ParseHashKey(params.HashKey)
+		return &IPHashBalancer{key: key}
+	case types.PowerOfTwoChoices:
+		if params.SampleSize == 0 {
+			break
+		}
+		return &P2CBalancer{sampleSize: params.SampleSize}
+	}
+	return f.GetBalancer(lbType)
 }
 
 // ParseCIDR 解析CIDR
@@ -0,0 +1,92 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// DefaultP2CSampleSize p2c默认采样数量
+const DefaultP2CSampleSize = 2
+
+// MaxP2CSampleSize p2c最大采样数量
+const MaxP2CSampleSize = 16
+
+// 哈希键来源
+const (
+	HashKeyClientIP = "client_ip"
+	HashKeyPath     = "path"
+	HashKeyHeader   = "header"
+	HashKeyCookie   = "cookie"
+	HashKeyQuery    = "query"
+)
+
+// HashKey 哈希类负载均衡使用的请求属性，零值表示客户端IP
+type HashKey struct {
+	Source string
+	Name   string
+}
+
+// ParseHashKey 解析hash_key配置：client_ip、path、header:<名称>、cookie:<名称>、query:<名称>
+func ParseHashKey(s string) (HashKey, error) {
+	source, name, hasName := strings.Cut(s, ":")
+	switch source {
+	case "", HashKeyClientIP, HashKeyPath:
+		if hasName {
+			return HashKey{}, fmt.Errorf("hash key %q does not take a name", source)
+		}
+		return HashKey{Source: source}, nil
+	case HashKeyHeader, HashKeyCookie, HashKeyQuery:
+		if name == "" {
+			return HashKey{}, fmt.Errorf("hash key %q requires a name, e.g. %s:X-User-ID", source, source)
+		}
+		return HashKey{Source: source, Name: name}, nil
+	default:
+		return HashKey{}, fmt.Errorf("unknown hash key source %q", source)
+	}
+}
+
+// Extract 从请求中提取哈希键，不存在时返回空字符串
+func (k HashKey) Extract(req types.RequestContext) string {
+	if req == nil {
+		return ""
+	}
+
+	switch k.Source {
+	case HashKeyPath:
+		return req.Path()
+	case HashKeyHeader:
+		return string(req.Header(k.Name))
+	case HashKeyCookie:
+		return string(req.Cookie(k.Name))
+	case HashKeyQuery:
+		return string(req.QueryArg(k.Name))
+	default:
+		return req.ClientIP()
+	}
+}
+
+// IsKnownType 判断是否为支持的负载均衡类型
+func IsKnownType(lbType types.LoadBalancerType) bool {
+	switch lbType {
+	case types.IPHash, types.LeastConnections, types.LeastConnectionsWeight,
+		types.Weight, types.PerformanceLCW, types.PowerOfTwoChoices, types.LeastResponseTime:
+		return true
+	}
+	return false
+}
+
+// ValidateParams 校验负载均衡参数
+func ValidateParams(params *types.LoadBalancerParams) error {
+	if params == nil {
+		return nil
+	}
+	if _, err := ParseHashKey(params.HashKey); err != nil {
+		return err
+	}
+	if params.SampleSize < 0 || params.SampleSize == 1 || params.SampleSize > MaxP2CSampleSize {
+		return fmt.Errorf("sample_size must be between 2 and %d, got %d", MaxP2CSampleSize, params.SampleSize)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package logsink
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// errorKeywords 包含这些词的诊断日志按err级别发送，其余为info级别
+var errorKeywords = [][]byte{[]byte("fail"), []byte("error"), []byte("panic"), []byte("fatal")}
+
+// ErrorSeverity 按内容判断诊断日志的syslog严重级别
+func ErrorSeverity(line []byte) int {
+	lower := bytes.ToLower(line)
+	for _, kw := range errorKeywords {
+		if bytes.Contains(lower, kw) {
+			return SeverityErr
+		}
+	}
+	return SeverityInfo
+}
+
+// Capture 把进程的标准输出和标准错误（包括log包的输出）逐行同时发送到target，
+// 原输出保持不变；返回的函数恢复原输出并发送剩余的日志
+func Capture(target string) (func(), error) {
+	sink, err := Open(target, Options{MsgID: "error", Severity: ErrorSeverity})
+	if err != nil {
+		return nil, err
+	}
+
+	origStdout, origStderr := os.Stdout, os.Stderr
+	var wg sync.WaitGroup
+	tee := func(orig *os.File) (*os.File, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer r.Close()
+			copyLines(r, orig, sink)
+		}()
+		return w, nil
+	}
+
+	stdoutW, err := tee(origStdout)
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	stderrW, err := tee(origStderr)
+	if err != nil {
+		stdoutW.Close()
+		wg.Wait()
+		sink.Close()
+		return nil, err
+	}
+	os.Stdout, os.Stderr = stdoutW, stderrW
+	log.SetOutput(stderrW)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			os.Stdout, os.Stderr = origStdout, origStderr
+			log.SetOutput(origStderr)
+			stdoutW.Close()
+			stderrW.Close()
+			wg.Wait()
+			sink.Close()
+		})
+	}, nil
+}
+
+// copyLines 把r中的每一行写到原输出和日志目标
+func copyLines(r io.Reader, orig io.Writer, sink io.Writer) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			orig.Write(line)
+			sink.Write(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package logsink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kafka协议的API和版本，Produce v3是支持RecordBatch v2的最低版本
+const (
+	kafkaAPIProduce       = 0
+	kafkaAPIMetadata      = 3
+	kafkaProduceVersion   = 3
+	kafkaMetadataVersion  = 1
+	kafkaDefaultPort      = "9092"
+	kafkaMaxResponseSize  = 64 << 20
+	kafkaRecordBatchMagic = 2
+	kafkaNoProducerID     = -1
+	kafkaErrNone          = 0
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaSender 最小的Kafka生产者：从bootstrap broker获取topic的分区和leader，
+// 每批日志轮流写入一个分区，每行日志是一条没有key的记录
+type kafkaSender struct {
+	brokers  []string
+	topic    string
+	acks     int16
+	clientID string
+	timeout  time.Duration
+
+	correlation int32
+	partitions  []int32          // 有leader的分区
+	leaders     map[int32]string // 分区 -> leader地址
+	conns       map[string]net.Conn
+	next        int
+}
+
+func newKafkaSender(u *url.URL, timeout time.Duration) (*kafkaSender, error) {
+	s := &kafkaSender{
+		topic:    strings.Trim(u.Path, "/"),
+		acks:     1,
+		clientID: "speedmimi",
+		timeout:  timeout,
+		conns:    make(map[string]net.Conn),
+	}
+	if s.topic == "" || strings.Contains(s.topic, "/") {
+		return nil, fmt.Errorf("invalid log target %q: kafka target must be kafka://broker1:9092,broker2:9092/topic", u.String())
+	}
+	for _, broker := range strings.Split(u.Host, ",") {
+		if broker == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			broker = net.JoinHostPort(broker, kafkaDefaultPort)
+		}
+		s.brokers = append(s.brokers, broker)
+	}
+	if len(s.brokers) == 0 {
+		return nil, fmt.Errorf("invalid log target %q: at least one kafka broker is required", u.String())
+	}
+
+	query := u.Query()
+	switch query.Get("acks") {
+	case "", "1":
+	case "0":
+		s.acks = 0
+	case "all", "-1":
+		s.acks = -1
+	default:
+		return nil, fmt.Errorf("invalid log target %q: acks must be 0, 1 or all", u.String())
+	}
+	if v := query.Get("client_id"); v != "" {
+		s.clientID = v
+	}
+	return s, nil
+}
+
+func (s *kafkaSender) send(lines [][]byte) error {
+	if s.partitions == nil {
+		if err := s.refreshMetadata(); err != nil {
+			return err
+		}
+	}
+	partition := s.partitions[s.next%len(s.partitions)]
+	s.next++
+
+	err := s.produce(s.leaders[partition], partition, lines)
+	if err != nil {
+		// leader可能已经变化，下次重新获取元数据
+		s.close()
+	}
+	return err
+}
+
+func (s *kafkaSender) close() {
+	for addr, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, addr)
+	}
+	s.partitions, s.leaders = nil, nil
+}
+
+// conn broker的连接，没有时建立
+func (s *kafkaSender) conn(addr string) (net.Conn, error) {
+	if conn, ok := s.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := net.DialTimeout("tcp", addr, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	s.conns[addr] = conn
+	return conn, nil
+}
+
+// refreshMetadata 依次向bootstrap broker请求topic的元数据
+func (s *kafkaSender) refreshMetadata() error {
+	var lastErr error
+	for _, addr := range s.brokers {
+		if lastErr = s.fetchMetadata(addr); lastErr == nil {
+			return nil
+		}
+		if conn, ok := s.conns[addr]; ok {
+			conn.Close()
+			delete(s.conns, addr)
+		}
+	}
+	return lastErr
+}
+
+func (s *kafkaSender) fetchMetadata(addr string) error {
+	var req kafkaEncoder
+	req.int32(1)
+	req.string(s.topic)
+	resp, err := s.roundTrip(addr, kafkaAPIMetadata, kafkaMetadataVersion, req, true)
+	if err != nil {
+		return err
+	}
+
+	d := kafkaDecoder{b: resp}
+	brokers := make(map[int32]string)
+	for i, n := 0, d.int32(); i < n && d.err == nil; i++ {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[int32(id)] = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	d.int32() // controller_id
+
+	leaders := make(map[int32]string)
+	var partitions []int32
+	for i, n := 0, d.int32(); i < n && d.err == nil; i++ {
+		topicErr := d.int16()
+		name := d.string()
+		d.int8() // is_internal
+		if name == s.topic && topicErr != kafkaErrNone {
+			return fmt.Errorf("kafka topic %s: error code %d", s.topic, topicErr)
+		}
+		for j, m := 0, d.int32(); j < m && d.err == nil; j++ {
+			partErr := d.int16()
+			index := int32(d.int32())
+			leader := int32(d.int32())
+			d.int32Array() // replicas
+			d.int32Array() // isr
+			if addr, ok := brokers[leader]; ok && name == s.topic && partErr == kafkaErrNone {
+				leaders[index] = addr
+				partitions = append(partitions, index)
+			}
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("kafka metadata: %w", d.err)
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("kafka topic %s has no available partitions", s.topic)
+	}
+	s.partitions, s.leaders = partitions, leaders
+	return nil
+}
+
+// produce 把日志行作为一个RecordBatch写入分区
+func (s *kafkaSender) produce(addr string, partition int32, lines [][]byte) error {
+	batch := recordBatch(lines, time.Now())
+
+	var req kafkaEncoder
+	req.int16(-1) // transactional_id
+	req.int16(s.acks)
+	req.int32(int(s.timeout / time.Millisecond))
+	req.int32(1)
+	req.string(s.topic)
+	req.int32(1)
+	req.int32(int(partition))
+	req.int32(len(batch))
+	req = append(req, batch...)
+
+	resp, err := s.roundTrip(addr, kafkaAPIProduce, kafkaProduceVersion, req, s.acks != 0)
+	if err != nil || s.acks == 0 {
+		return err
+	}
+
+	d := kafkaDecoder{b: resp}
+	for i, n := 0, d.int32(); i < n && d.err == nil; i++ {
+		d.string()
+		for j, m := 0, d.int32(); j < m && d.err == nil; j++ {
+			d.int32() // partition
+			if code := d.int16(); code != kafkaErrNone && d.err == nil {
+				return fmt.Errorf("kafka produce to %s/%d: error code %d", s.topic, partition, code)
+			}
+			d.int64() // base_offset
+			d.int64() // log_append_time
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("kafka produce: %w", d.err)
+	}
+	return nil
+}
+
+// roundTrip 发送请求，需要响应时读取并返回响应头之后的内容
+func (s *kafkaSender) roundTrip(addr string, apiKey, version int16, body []byte, wantResponse bool) ([]byte, error) {
+	conn, err := s.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	s.correlation++
+	var req kafkaEncoder
+	req.int32(2 + 2 + 4 + 2 + len(s.clientID) + len(body))
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(int(s.correlation))
+	req.string(s.clientID)
+	req = append(req, body...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	if !wantResponse {
+		return nil, nil
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("kafka response size %d out of range", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != s.correlation {
+		return nil, fmt.Errorf("kafka correlation id %d, want %d", id, s.correlation)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// recordBatch 编码RecordBatch v2，CRC32C覆盖attributes到末尾
+func recordBatch(lines [][]byte, now time.Time) []byte {
+	var records []byte
+	var rec []byte
+	for i, line := range lines {
+		rec = append(rec[:0], 0)                 // attributes
+		rec = binary.AppendVarint(rec, 0)        // timestamp_delta
+		rec = binary.AppendVarint(rec, int64(i)) // offset_delta
+		rec = binary.AppendVarint(rec, -1)       // key
+		rec = binary.AppendVarint(rec, int64(len(line)))
+		rec = append(rec, line...)
+		rec = binary.AppendVarint(rec, 0) // headers
+		records = binary.AppendVarint(records, int64(len(rec)))
+		records = append(records, rec...)
+	}
+
+	ts := now.UnixMilli()
+	var tail kafkaEncoder
+	tail.int16(0) // attributes
+	tail.int32(len(lines) - 1)
+	tail.int64(ts) // base_timestamp
+	tail.int64(ts) // max_timestamp
+	tail.int64(kafkaNoProducerID)
+	tail.int16(-1) // producer_epoch
+	tail.int32(-1) // base_sequence
+	tail.int32(len(lines))
+	tail = append(tail, records...)
+
+	var batch kafkaEncoder
+	batch.int64(0) // base_offset
+	batch.int32(4 + 1 + 4 + len(tail))
+	batch.int32(-1) // partition_leader_epoch
+	batch.int8(kafkaRecordBatchMagic)
+	batch.int32(int(int32(crc32.Checksum(tail, crc32c))))
+	return append(batch, tail...)
+}
+
+// kafkaEncoder 大端编码Kafka协议字段
+type kafkaEncoder []byte
+
+func (e *kafkaEncoder) int8(v int8)   { *e = append(*e, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { *e = binary.BigEndian.AppendUint16(*e, uint16(v)) }
+func (e *kafkaEncoder) int32(v int)   { *e = binary.BigEndian.AppendUint32(*e, uint32(int32(v))) }
+func (e *kafkaEncoder) int64(v int64) { *e = binary.BigEndian.AppendUint64(*e, uint64(v)) }
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	*e = append(*e, v...)
+}
+
+var errKafkaShort = errors.New("truncated response")
+
+// kafkaDecoder 解码Kafka响应，出错后后续读取都返回零值
+type kafkaDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.b) {
+		d.err = errKafkaShort
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int {
+	if b := d.take(4); b != nil {
+		return int(int32(binary.BigEndian.Uint32(b)))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string 读取字符串，null字符串返回空
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *kafkaDecoder) int32Array() {
+	for i, n := 0, d.int32(); i < n && d.err == nil; i++ {
+		d.int32()
+	}
+}
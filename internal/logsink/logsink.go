@@ -0,0 +1,315 @@
+// Package logsink 日志输出目标：标准输出/标准错误、syslog（RFC5424）、TCP/UDP转发和Kafka
+// 远程目标异步发送，请求协程只把日志行放入队列，队列满或远端不可用时丢弃日志而不阻塞
+package logsink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 远程目标的URL scheme
+const (
+	SchemeSyslog    = "syslog"     // syslog over UDP，默认端口514
+	SchemeSyslogTCP = "syslog+tcp" // syslog over TCP（RFC6587八位组计数），默认端口601
+	SchemeTCP       = "tcp"        // 每行一条，以换行分隔
+	SchemeUDP       = "udp"        // 每行一个数据报
+	SchemeKafka     = "kafka"      // kafka://broker1:9092,broker2:9092/topic
+)
+
+// 默认值
+const (
+	DefaultQueueSize = 8192
+	DefaultTimeout   = 5 * time.Second
+	maxBatchLines    = 512
+	maxRetryBackoff  = 30 * time.Second
+)
+
+// 原始的标准输出和标准错误，Capture替换os.Stdout/os.Stderr后日志仍写到原来的位置
+var (
+	stdout = os.Stdout
+	stderr = os.Stderr
+)
+
+// Options 打开日志目标的选项
+type Options struct {
+	MsgID    string                // syslog的MSGID，如access、error
+	Severity func(line []byte) int // syslog严重级别，为nil时为SeverityInfo
+}
+
+// IsRemote target是否为远程日志目标URL
+func IsRemote(target string) bool {
+	scheme, _, ok := strings.Cut(target, "://")
+	if !ok {
+		return false
+	}
+	switch scheme {
+	case SchemeSyslog, SchemeSyslogTCP, SchemeTCP, SchemeUDP, SchemeKafka:
+		return true
+	}
+	return false
+}
+
+// IsStdStream target是否为标准输出或标准错误
+func IsStdStream(target string) bool {
+	return target == "" || target == "stdout" || target == "stderr"
+}
+
+// Validate 校验远程日志目标URL
+func Validate(target string) error {
+	_, err := newSender(target, Options{})
+	return err
+}
+
+// Open 打开标准输出、标准错误或远程日志目标，文件路径返回错误
+func Open(target string, opts Options) (io.WriteCloser, error) {
+	switch target {
+	case "", "stdout":
+		return nopCloser{stdout}, nil
+	case "stderr":
+		return nopCloser{stderr}, nil
+	}
+	s, err := newSender(target, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newRemote(target, s, queueSizeOf(target)), nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Stats 远程日志目标的发送统计
+type Stats struct {
+	Sent    int64 `json:"sent"`    // 已发送的日志行数
+	Dropped int64 `json:"dropped"` // 队列满或发送失败丢弃的日志行数
+}
+
+// StatsOf 日志目标的发送统计，不是远程目标时返回false
+func StatsOf(w io.Writer) (Stats, bool) {
+	r, ok := w.(*remote)
+	if !ok {
+		return Stats{}, false
+	}
+	return Stats{Sent: r.sent.Load(), Dropped: r.dropped.Load()}, true
+}
+
+// sender 远程目标的发送方式，只在发送协程中调用
+type sender interface {
+	// send 发送一批日志行（不含换行），失败时关闭连接，下次重新连接
+	send(lines [][]byte) error
+	close()
+}
+
+// newSender 解析目标URL
+func newSender(target string, opts Options) (sender, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log target %q: %w", target, err)
+	}
+	timeout := DefaultTimeout
+	if v := u.Query().Get("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid log target %q: timeout must be a positive duration", target)
+		}
+	}
+	if v := u.Query().Get("queue_size"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid log target %q: queue_size must be a positive integer", target)
+		}
+	}
+
+	switch u.Scheme {
+	case SchemeSyslog, SchemeSyslogTCP:
+		return newSyslogSender(u, opts, timeout)
+	case SchemeTCP, SchemeUDP:
+		if u.Port() == "" {
+			return nil, fmt.Errorf("invalid log target %q: port is required", target)
+		}
+		return &lineSender{network: u.Scheme, addr: u.Host, timeout: timeout}, nil
+	case SchemeKafka:
+		return newKafkaSender(u, timeout)
+	default:
+		return nil, fmt.Errorf("invalid log target %q: scheme must be %s, %s, %s, %s or %s",
+			target, SchemeSyslog, SchemeSyslogTCP, SchemeTCP, SchemeUDP, SchemeKafka)
+	}
+}
+
+// queueSizeOf URL中的queue_size，默认DefaultQueueSize
+func queueSizeOf(target string) int {
+	if u, err := url.Parse(target); err == nil {
+		if n, err := strconv.Atoi(u.Query().Get("queue_size")); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultQueueSize
+}
+
+// remote 异步发送到远程目标
+type remote struct {
+	target string
+	sender sender
+	queue  chan []byte
+
+	sent    atomic.Int64
+	dropped atomic.Int64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newRemote(target string, s sender, queueSize int) *remote {
+	r := &remote{
+		target: target,
+		sender: s,
+		queue:  make(chan []byte, queueSize),
+		stop:   make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// Write 按行放入发送队列，不会阻塞；队列满时丢弃
+func (r *remote) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		select {
+		case r.queue <- append([]byte(nil), line...):
+		default:
+			r.dropped.Add(1)
+		}
+	}
+	return len(p), nil
+}
+
+// Close 尽量发送队列中剩余的日志后关闭连接
+func (r *remote) Close() error {
+	r.closeOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+	return nil
+}
+
+func (r *remote) run() {
+	defer r.wg.Done()
+	defer r.sender.close()
+
+	backoff := time.Second
+	failing := false
+	for {
+		var batch [][]byte
+		select {
+		case line := <-r.queue:
+			batch = r.collect(append(batch, line))
+		case <-r.stop:
+			// 关闭时只尝试一次
+			if batch = r.collect(nil); len(batch) > 0 {
+				r.deliver(batch)
+			}
+			return
+		}
+
+		for {
+			err := r.deliver(batch)
+			if err == nil {
+				if failing {
+					fmt.Printf("[LOGSINK] %s: delivery resumed\n", r.target)
+				}
+				failing, backoff = false, time.Second
+				break
+			}
+			if !failing {
+				fmt.Printf("[LOGSINK] %s: delivery failed, retrying: %v\n", r.target, err)
+				failing = true
+			}
+			select {
+			case <-r.stop:
+				r.dropped.Add(int64(len(batch)))
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxRetryBackoff)
+		}
+	}
+}
+
+// collect 取出队列中已有的日志行，最多maxBatchLines行
+func (r *remote) collect(batch [][]byte) [][]byte {
+	for len(batch) < maxBatchLines {
+		select {
+		case line := <-r.queue:
+			batch = append(batch, line)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (r *remote) deliver(batch [][]byte) error {
+	if err := r.sender.send(batch); err != nil {
+		return err
+	}
+	r.sent.Add(int64(len(batch)))
+	return nil
+}
+
+// lineSender 原样转发日志行：TCP以换行分隔，UDP每行一个数据报
+type lineSender struct {
+	network string
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func (s *lineSender) send(lines [][]byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+
+	var err error
+	if s.network == SchemeUDP {
+		for _, line := range lines {
+			if _, err = s.conn.Write(line); err != nil {
+				break
+			}
+		}
+	} else {
+		var buf bytes.Buffer
+		for _, line := range lines {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		_, err = s.conn.Write(buf.Bytes())
+	}
+	if err != nil {
+		s.close()
+	}
+	return err
+}
+
+func (s *lineSender) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,151 @@
+package logsink
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// syslog严重级别
+const (
+	SeverityErr     = 3
+	SeverityWarning = 4
+	SeverityInfo    = 6
+)
+
+// facilities syslog设备名称
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSender 按RFC5424格式发送日志，UDP每条一个数据报，TCP按RFC6587八位组计数分帧
+type syslogSender struct {
+	network  string
+	addr     string
+	timeout  time.Duration
+	facility int
+	header   string // 时间戳之后的HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA
+	severity func(line []byte) int
+	conn     net.Conn
+	buf      bytes.Buffer
+}
+
+func newSyslogSender(u *url.URL, opts Options, timeout time.Duration) (*syslogSender, error) {
+	s := &syslogSender{network: "udp", addr: u.Host, timeout: timeout, facility: facilities["local0"], severity: opts.Severity}
+	port := "514"
+	if u.Scheme == SchemeSyslogTCP {
+		s.network, port = "tcp", "601"
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid log target %q: host is required", u.String())
+	}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	query := u.Query()
+	if v := query.Get("facility"); v != "" {
+		facility, ok := facilities[v]
+		if !ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 23 {
+				return nil, fmt.Errorf("invalid log target %q: unknown syslog facility %q", u.String(), v)
+			}
+			facility = n
+		}
+		s.facility = facility
+	}
+	appName := query.Get("app_name")
+	if appName == "" {
+		appName = "speedmimi"
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	msgID := opts.MsgID
+	if msgID == "" {
+		msgID = "-"
+	}
+	s.header = fmt.Sprintf("%s %s %d %s -", headerField(hostname, 255), headerField(appName, 48), os.Getpid(), headerField(msgID, 32))
+	return s, nil
+}
+
+// headerField RFC5424头部字段只允许可打印ASCII且有长度上限
+func headerField(v string, limit int) string {
+	b := []byte(v)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > limit {
+		b = b[:limit]
+	}
+	return string(b)
+}
+
+// format 按RFC5424格式化一条日志：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (s *syslogSender) format(dst []byte, line []byte, now time.Time) []byte {
+	severity := SeverityInfo
+	if s.severity != nil {
+		severity = s.severity(line)
+	}
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(s.facility*8+severity), 10)
+	dst = append(dst, ">1 "...)
+	dst = now.AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = append(dst, s.header...)
+	dst = append(dst, ' ')
+	return append(dst, line...)
+}
+
+func (s *syslogSender) send(lines [][]byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+
+	now := time.Now()
+	var err error
+	var msg []byte
+	s.buf.Reset()
+	for _, line := range lines {
+		msg = s.format(msg[:0], line, now)
+		if s.network == "udp" {
+			if _, err = s.conn.Write(msg); err != nil {
+				break
+			}
+			continue
+		}
+		s.buf.WriteString(strconv.Itoa(len(msg)))
+		s.buf.WriteByte(' ')
+		s.buf.Write(msg)
+	}
+	if err == nil && s.buf.Len() > 0 {
+		_, err = s.conn.Write(s.buf.Bytes())
+	}
+	if err != nil {
+		s.close()
+	}
+	return err
+}
+
+func (s *syslogSender) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,272 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// MaxHistoryPoints 指标历史最多保存的采样点数（retention/resolution）
+const MaxHistoryPoints = 100000
+
+// HistoryMetric 指标历史中保存的指标
+type HistoryMetric int
+
+// 指标历史中保存的指标，速率按与上一次采样的差值计算
+const (
+	HistoryRPS                 HistoryMetric = iota // 每秒请求数
+	HistoryErrorRPS                                 // 每秒状态码>=500的响应数（按路由统计）
+	HistorySentBytesPerSec                          // 每秒发送的字节数
+	HistoryReceivedBytesPerSec                      // 每秒接收的字节数
+	HistoryActiveConnections                        // 活跃客户端连接数
+	HistoryGoroutines                               // goroutine数
+	historyMetrics
+)
+
+// HistoryMetricNames 指标的名称，用于查询参数
+var HistoryMetricNames = [historyMetrics]string{
+	"rps",
+	"error_rps",
+	"sent_bytes_per_sec",
+	"received_bytes_per_sec",
+	"active_connections",
+	"goroutines",
+}
+
+// ErrHistoryDisabled 指标历史未启用
+var ErrHistoryDisabled = errors.New("stats history is disabled")
+
+// ParseHistoryMetric 按名称查找指标
+func ParseHistoryMetric(name string) (HistoryMetric, error) {
+	for i, n := range HistoryMetricNames {
+		if n == name {
+			return HistoryMetric(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown metric %q, must be one of %s", name, strings.Join(HistoryMetricNames[:], ", "))
+}
+
+// historyPoint 一次采样
+type historyPoint struct {
+	at     time.Time
+	values [historyMetrics]float64
+}
+
+// historyCounters 计算速率用的累计计数
+type historyCounters struct {
+	at                           time.Time
+	requests, errors, sent, recv int64
+}
+
+// metricsHistory 按固定间隔采样的环形缓冲
+type metricsHistory struct {
+	mu         sync.RWMutex
+	resolution time.Duration // 为0时未启用
+	points     []historyPoint
+	next       int  // 下一个写入位置
+	full       bool // 缓冲已写满一轮
+	last       historyCounters
+
+	reset chan struct{} // 采样间隔变化时通知采样循环
+}
+
+// HistoryPoint 查询结果中的一个点
+type HistoryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// HistorySeries 一个指标在查询范围内的历史
+type HistorySeries struct {
+	Metric     string         `json:"metric"`
+	Resolution string         `json:"resolution"`
+	Step       string         `json:"step"`
+	Points     []HistoryPoint `json:"points"`
+}
+
+// SetHistory 应用指标历史配置：修改采样间隔或关闭时清空已有历史，只修改保留时长时保留最新的采样
+func (pm *PerformanceMonitor) SetHistory(cfg *types.StatsHistoryConfig) {
+	h := &pm.history
+	resolution, size := time.Duration(0), 0
+	if !cfg.Disabled && cfg.Resolution > 0 {
+		resolution = cfg.Resolution
+		size = int(min(cfg.Retention/cfg.Resolution, MaxHistoryPoints))
+		size = max(size, 1)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if resolution != h.resolution {
+		h.resolution, h.points, h.next, h.full = resolution, make([]historyPoint, size), 0, false
+		h.last = historyCounters{}
+		select {
+		case h.reset <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if size != len(h.points) {
+		kept := h.ordered()
+		if len(kept) > size {
+			kept = kept[len(kept)-size:]
+		}
+		h.points = make([]historyPoint, size)
+		h.next = copy(h.points, kept) % size
+		h.full = len(kept) == size
+	}
+}
+
+// ordered 从旧到新的采样，调用方持有锁
+func (h *metricsHistory) ordered() []historyPoint {
+	if !h.full {
+		return append([]historyPoint(nil), h.points[:h.next]...)
+	}
+	return append(append([]historyPoint(nil), h.points[h.next:]...), h.points[:h.next]...)
+}
+
+// historyLoop 按采样间隔记录指标，未启用时等待配置变化
+func (pm *PerformanceMonitor) historyLoop() {
+	h := &pm.history
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-pm.ctx.Done():
+			return
+		case <-h.reset:
+			h.mu.RLock()
+			resolution := h.resolution
+			h.mu.RUnlock()
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+			if resolution > 0 {
+				ticker = time.NewTicker(resolution)
+				tick = ticker.C
+				pm.sampleHistory(time.Now())
+			}
+		case now := <-tick:
+			pm.sampleHistory(now)
+		}
+	}
+}
+
+// sampleHistory 记录一次采样；第一次采样只记录累计计数，不产生点
+func (pm *PerformanceMonitor) sampleHistory(now time.Time) {
+	cur := historyCounters{
+		at:       now,
+		requests: atomic.LoadInt64(&pm.totalRequests),
+		sent:     atomic.LoadInt64(&pm.totalBytesSent),
+		recv:     atomic.LoadInt64(&pm.totalBytesRecv),
+	}
+	pm.traffic.routes.Range(func(_, v interface{}) bool {
+		cur.errors += v.(*trafficCounters).errors.Load()
+		return true
+	})
+	active := atomic.LoadInt64(&pm.activeConnections)
+	goroutines := runtime.NumGoroutine()
+
+	h := &pm.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev := h.last
+	h.last = cur
+	if h.resolution == 0 || prev.at.IsZero() {
+		return
+	}
+	elapsed := now.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := func(cur, prev int64) float64 {
+		// 删除路由后错误数之和可能减小
+		if cur < prev {
+			return 0
+		}
+		return float64(cur-prev) / elapsed
+	}
+
+	var p historyPoint
+	p.at = now
+	p.values[HistoryRPS] = rate(cur.requests, prev.requests)
+	p.values[HistoryErrorRPS] = rate(cur.errors, prev.errors)
+	p.values[HistorySentBytesPerSec] = rate(cur.sent, prev.sent)
+	p.values[HistoryReceivedBytesPerSec] = rate(cur.recv, prev.recv)
+	p.values[HistoryActiveConnections] = float64(active)
+	p.values[HistoryGoroutines] = float64(goroutines)
+
+	h.points[h.next] = p
+	h.next = (h.next + 1) % len(h.points)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// History 查询指标最近rng内的历史，step大于采样间隔时按step对齐分桶取平均值，step为0时返回每个采样点
+func (pm *PerformanceMonitor) History(metric HistoryMetric, rng, step time.Duration) (HistorySeries, error) {
+	h := &pm.history
+	h.mu.RLock()
+	resolution := h.resolution
+	var points []historyPoint
+	if resolution > 0 {
+		points = h.ordered()
+	}
+	h.mu.RUnlock()
+
+	if resolution == 0 {
+		return HistorySeries{}, ErrHistoryDisabled
+	}
+	if metric < 0 || metric >= historyMetrics {
+		return HistorySeries{}, fmt.Errorf("unknown metric %d", metric)
+	}
+	if step < resolution {
+		step = resolution
+	}
+
+	series := HistorySeries{
+		Metric:     HistoryMetricNames[metric],
+		Resolution: resolution.String(),
+		Step:       step.String(),
+		Points:     []HistoryPoint{},
+	}
+	since := time.Now().Add(-rng)
+	var bucket time.Time
+	var sum float64
+	var n int
+	flush := func() {
+		if n > 0 {
+			series.Points = append(series.Points, HistoryPoint{Timestamp: bucket, Value: sum / float64(n)})
+		}
+	}
+	for _, p := range points {
+		if p.at.Before(since) {
+			continue
+		}
+		at := p.at
+		if step > resolution {
+			at = p.at.Truncate(step)
+		}
+		if n > 0 && !at.Equal(bucket) {
+			flush()
+			sum, n = 0, 0
+		}
+		bucket = at
+		sum += p.values[metric]
+		n++
+	}
+	flush()
+	return series, nil
+}
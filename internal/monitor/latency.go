@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"math/bits"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 后端响应时间直方图按微秒的对数分桶（HDR风格）：每个2的幂区间分为latencySubBuckets个桶，相对误差不超过1/16
+const (
+	latencySubBucketBits = 4
+	latencySubBuckets    = 1 << latencySubBucketBits
+	latencyBucketCount   = (64-latencySubBucketBits)*latencySubBuckets + latencySubBuckets
+)
+
+// LatencyWindow 百分位的统计窗口，百分位覆盖最近一到两个窗口内的请求
+const LatencyWindow = 30 * time.Second
+
+// LatencyBuckets Prometheus直方图的累计桶上界
+var LatencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyCounts 一个窗口内的响应时间直方图，无锁记录
+type latencyCounts struct {
+	buckets [latencyBucketCount]atomic.Int64
+}
+
+// backendLatency 一个后端的响应时间统计
+type backendLatency struct {
+	// 两个窗口轮换，current为正在记录的窗口
+	windows [2]latencyCounts
+	current atomic.Int32
+
+	// 自启动以来的累计统计，用于Prometheus直方图；最后一个桶为+Inf
+	buckets [len(LatencyBuckets) + 1]atomic.Int64
+	count   atomic.Int64
+	sum     atomic.Int64 // 纳秒
+}
+
+// latencyKey 后端在监控器中的键
+type latencyKey struct {
+	upstream string
+	backend  string
+}
+
+// LatencyStats 一个后端的响应时间统计，百分位按最近一到两个LatencyWindow计算
+type LatencyStats struct {
+	Upstream string `json:"upstream"`
+	Backend  string `json:"backend"`
+
+	// 自启动以来的请求数、总耗时（秒）和平均值
+	Count  int64   `json:"count"`
+	Sum    float64 `json:"sum_seconds"`
+	MeanMs float64 `json:"mean_ms"`
+
+	// 窗口内的请求数和百分位
+	WindowCount int64   `json:"window_count"`
+	P50Ms       float64 `json:"p50_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	MaxMs       float64 `json:"max_ms"`
+
+	// Buckets[i]为耗时不超过LatencyBuckets[i]的累计请求数
+	Buckets []int64 `json:"buckets"`
+}
+
+// RecordBackendLatency 记录一次后端响应时间（无锁，只在后端第一次出现时分配）
+func (pm *PerformanceMonitor) RecordBackendLatency(upstream, backend string, d time.Duration) {
+	if !pm.samplingEnabled.Load() {
+		return
+	}
+	key := latencyKey{upstream, backend}
+	v, ok := pm.latencies.Load(key)
+	if !ok {
+		v, _ = pm.latencies.LoadOrStore(key, &backendLatency{})
+	}
+	v.(*backendLatency).record(d)
+}
+
+// BackendLatencies 所有后端的响应时间统计，按上游和后端排序；upstream非空时只返回该上游的后端
+func (pm *PerformanceMonitor) BackendLatencies(upstream string) []LatencyStats {
+	var stats []LatencyStats
+	pm.latencies.Range(func(k, v interface{}) bool {
+		key := k.(latencyKey)
+		if upstream == "" || key.upstream == upstream {
+			stats = append(stats, v.(*backendLatency).stats(key))
+		}
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Upstream != stats[j].Upstream {
+			return stats[i].Upstream < stats[j].Upstream
+		}
+		return stats[i].Backend < stats[j].Backend
+	})
+	return stats
+}
+
+// RetainBackends 删除配置中已不存在的上游和后端的统计
+func (pm *PerformanceMonitor) RetainBackends(backends map[string][]*types.Backend) {
+	current := make(map[latencyKey]bool)
+	for upstream, list := range backends {
+		for _, backend := range list {
+			current[latencyKey{upstream, backend.ID}] = true
+		}
+	}
+	pm.latencies.Range(func(k, _ interface{}) bool {
+		if !current[k.(latencyKey)] {
+			pm.latencies.Delete(k)
+		}
+		return true
+	})
+	retainCounters(&pm.traffic.backends, func(k interface{}) bool { return current[k.(latencyKey)] })
+	retainCounters(&pm.traffic.upstreams, func(k interface{}) bool {
+		_, ok := backends[k.(string)]
+		return ok
+	})
+}
+
+// latencyLoop 每个LatencyWindow轮换一次所有后端的窗口
+func (pm *PerformanceMonitor) latencyLoop() {
+	ticker := time.NewTicker(LatencyWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pm.ctx.Done():
+			return
+		case <-ticker.C:
+			pm.latencies.Range(func(_, v interface{}) bool {
+				v.(*backendLatency).rotate()
+				return true
+			})
+		}
+	}
+}
+
+// record 记录一次响应时间
+func (l *backendLatency) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	w := &l.windows[l.current.Load()]
+	w.buckets[latencyBucketIndex(uint64(d/time.Microsecond))].Add(1)
+
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })
+	l.buckets[i].Add(1)
+	l.count.Add(1)
+	l.sum.Add(int64(d))
+}
+
+// rotate 清空上一个窗口并切换到该窗口记录，轮换期间并发记录的少量请求可能计入任一窗口
+func (l *backendLatency) rotate() {
+	next := 1 - l.current.Load()
+	w := &l.windows[next]
+	for i := range w.buckets {
+		w.buckets[i].Store(0)
+	}
+	l.current.Store(next)
+}
+
+// stats 计算统计
+func (l *backendLatency) stats(key latencyKey) LatencyStats {
+	s := LatencyStats{
+		Upstream: key.upstream,
+		Backend:  key.backend,
+		Count:    l.count.Load(),
+		Sum:      time.Duration(l.sum.Load()).Seconds(),
+		Buckets:  make([]int64, len(LatencyBuckets)),
+	}
+	if s.Count > 0 {
+		s.MeanMs = durationMs(time.Duration(l.sum.Load() / s.Count))
+	}
+	var cumulative int64
+	for i := range LatencyBuckets {
+		cumulative += l.buckets[i].Load()
+		s.Buckets[i] = cumulative
+	}
+
+	// 合并两个窗口
+	var counts [latencyBucketCount]int64
+	for w := range l.windows {
+		for i := range counts {
+			counts[i] += l.windows[w].buckets[i].Load()
+		}
+	}
+	for _, c := range counts {
+		s.WindowCount += c
+	}
+	if s.WindowCount == 0 {
+		return s
+	}
+	s.P50Ms = durationMs(latencyQuantile(&counts, s.WindowCount, 50))
+	s.P95Ms = durationMs(latencyQuantile(&counts, s.WindowCount, 95))
+	s.P99Ms = durationMs(latencyQuantile(&counts, s.WindowCount, 99))
+	s.MaxMs = durationMs(latencyQuantile(&counts, s.WindowCount, 100))
+	return s
+}
+
+// latencyQuantile 第ceil(percent%*count)个请求所在桶的上界
+func latencyQuantile(counts *[latencyBucketCount]int64, count int64, percent int64) time.Duration {
+	rank := (count*percent + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(latencyBucketUpperBound(i)) * time.Microsecond
+		}
+	}
+	return time.Duration(latencyBucketUpperBound(latencyBucketCount-1)) * time.Microsecond
+}
+
+// latencyBucketIndex 微秒数所在的桶：小于latencySubBuckets的值各占一个桶，
+// 其余按最高位所在的2的幂区间和其后latencySubBucketBits位分桶
+func latencyBucketIndex(us uint64) int {
+	if us < latencySubBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - latencySubBucketBits - 1
+	return shift*latencySubBuckets + int(us>>shift)
+}
+
+// latencyBucketUpperBound 桶内最大的微秒数
+func latencyBucketUpperBound(i int) uint64 {
+	if i < latencySubBuckets {
+		return uint64(i)
+	}
+	shift := i/latencySubBuckets - 1
+	mantissa := uint64(i%latencySubBuckets + latencySubBuckets)
+	return (mantissa+1)<<shift - 1
+}
+
+// durationMs 毫秒数
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -10,26 +11,46 @@ import (
 	"github.com/quqi/speedmimi/pkg/types"
 )
 
+// 运行时可设置的最小间隔，避免采样/上报本身占用过多CPU
+const (
+	MinSampleInterval = 10 * time.Millisecond
+	MinReportInterval = 100 * time.Millisecond
+)
+
 // PerformanceMonitor 性能监控器（异步采样，避免阻塞主路径）
 type PerformanceMonitor struct {
-	// 采样配置
-	sampleInterval time.Duration
-	reportInterval time.Duration
+	// 采样配置（纳秒，原子操作），可通过管理API在运行时调整
+	sampleInterval int64
+	reportInterval int64
+
+	// 间隔变化时通知采样/上报循环重置定时器
+	sampleReset chan struct{}
+	reportReset chan struct{}
 
 	// 统计数据（原子操作）
 	totalRequests     int64
 	activeConnections int64
 	totalBytesSent    int64
 	totalBytesRecv    int64
+	totalPanics       int64
+
+	// 每个后端的响应时间直方图，key为latencyKey
+	latencies sync.Map
+
+	// 按路由、上游和后端的流量统计
+	traffic trafficMaps
+
+	// 按固定间隔保存的指标历史，通过SetHistory启用
+	history metricsHistory
 
 	// 性能指标缓存（使用原子操作）
 	lastCPUUsage    int64 // 使用int64存储float64的值（放大100倍）
 	lastMemoryUsage int64
 	lastLoadAvg     int64
 
-	// 采样控制
-	samplingEnabled bool
-	reportEnabled   bool
+	// 采样控制（高负载时可关闭以降低开销）
+	samplingEnabled atomic.Bool
+	reportEnabled   atomic.Bool
 
 	// 异步通道
 	sampleChan chan *SampleData
@@ -58,11 +79,12 @@ func NewPerformanceMonitor() *PerformanceMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pm := &PerformanceMonitor{
-		sampleInterval: 100 * time.Millisecond, // 每100ms采样一次
-		reportInterval: 5 * time.Second,       // 每5秒上报一次
+		sampleInterval: int64(100 * time.Millisecond), // 每100ms采样一次
+		reportInterval: int64(5 * time.Second),        // 每5秒上报一次
 
-		samplingEnabled: true,
-		reportEnabled:   true,
+		sampleReset: make(chan struct{}, 1),
+		reportReset: make(chan struct{}, 1),
+		history:     metricsHistory{reset: make(chan struct{}, 1)},
 
 		sampleChan: make(chan *SampleData, 1000),    // 缓冲1000个采样数据
 		reportChan: make(chan *types.PerformanceInfo, 100),
@@ -70,17 +92,21 @@ func NewPerformanceMonitor() *PerformanceMonitor {
 		ctx:    ctx,
 		cancel: cancel,
 	}
+	pm.samplingEnabled.Store(true)
+	pm.reportEnabled.Store(true)
 
 	// 启动异步goroutine
 	go pm.samplingLoop()
 	go pm.reportingLoop()
+	go pm.latencyLoop()
+	go pm.historyLoop()
 
 	return pm
 }
 
 // RecordRequest 记录请求（轻量级，不阻塞）
 func (pm *PerformanceMonitor) RecordRequest(bytesSent, bytesRecv int64) {
-	if !pm.samplingEnabled {
+	if !pm.samplingEnabled.Load() {
 		return
 	}
 
@@ -89,6 +115,11 @@ func (pm *PerformanceMonitor) RecordRequest(bytesSent, bytesRecv int64) {
 	atomic.AddInt64(&pm.totalBytesRecv, bytesRecv)
 }
 
+// RecordPanic 记录请求处理过程中捕获的panic
+func (pm *PerformanceMonitor) RecordPanic() {
+	atomic.AddInt64(&pm.totalPanics, 1)
+}
+
 // StartConnection 连接开始
 func (pm *PerformanceMonitor) StartConnection() {
 	atomic.AddInt64(&pm.activeConnections, 1)
@@ -114,6 +145,26 @@ func (pm *PerformanceMonitor) GetStats() *types.PerformanceInfo {
 	}
 }
 
+// TrafficStats 流量统计
+type TrafficStats struct {
+	TotalRequests     int64 `json:"total_requests"`
+	ActiveConnections int64 `json:"active_connections"`
+	BytesSent         int64 `json:"bytes_sent"`
+	BytesRecv         int64 `json:"bytes_recv"`
+	Panics            int64 `json:"panics"`
+}
+
+// GetTrafficStats 获取当前流量统计（非阻塞）
+func (pm *PerformanceMonitor) GetTrafficStats() TrafficStats {
+	return TrafficStats{
+		TotalRequests:     atomic.LoadInt64(&pm.totalRequests),
+		ActiveConnections: atomic.LoadInt64(&pm.activeConnections),
+		BytesSent:         atomic.LoadInt64(&pm.totalBytesSent),
+		BytesRecv:         atomic.LoadInt64(&pm.totalBytesRecv),
+		Panics:            atomic.LoadInt64(&pm.totalPanics),
+	}
+}
+
 // SetReportCallback 设置上报回调（异步）
 func (pm *PerformanceMonitor) SetReportCallback(callback func(*types.PerformanceInfo)) {
 	go func() {
@@ -133,15 +184,17 @@ func (pm *PerformanceMonitor) SetReportCallback(callback func(*types.Performance
 
 // samplingLoop 采样循环（异步）
 func (pm *PerformanceMonitor) samplingLoop() {
-	ticker := time.NewTicker(pm.sampleInterval)
+	ticker := time.NewTicker(time.Duration(atomic.LoadInt64(&pm.sampleInterval)))
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-pm.ctx.Done():
 			return
+		case <-pm.sampleReset:
+			ticker.Reset(time.Duration(atomic.LoadInt64(&pm.sampleInterval)))
 		case <-ticker.C:
-			if !pm.samplingEnabled {
+			if !pm.samplingEnabled.Load() {
 				continue
 			}
 
@@ -167,15 +220,17 @@ func (pm *PerformanceMonitor) samplingLoop() {
 
 // reportingLoop 上报循环（异步）
 func (pm *PerformanceMonitor) reportingLoop() {
-	ticker := time.NewTicker(pm.reportInterval)
+	ticker := time.NewTicker(time.Duration(atomic.LoadInt64(&pm.reportInterval)))
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-pm.ctx.Done():
 			return
+		case <-pm.reportReset:
+			ticker.Reset(time.Duration(atomic.LoadInt64(&pm.reportInterval)))
 		case <-ticker.C:
-			if !pm.reportEnabled {
+			if !pm.reportEnabled.Load() {
 				continue
 			}
 
@@ -241,18 +296,62 @@ func (pm *PerformanceMonitor) Stop() {
 	close(pm.reportChan)
 }
 
-// EnableSampling 启用采样
+// EnableSampling 启用采样，关闭时同时停止统计请求数和流量
 func (pm *PerformanceMonitor) EnableSampling(enabled bool) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-	pm.samplingEnabled = enabled
+	pm.samplingEnabled.Store(enabled)
 }
 
 // EnableReporting 启用上报
 func (pm *PerformanceMonitor) EnableReporting(enabled bool) {
+	pm.reportEnabled.Store(enabled)
+}
+
+// Settings 监控器的运行时设置
+type Settings struct {
+	SampleInterval   time.Duration
+	ReportInterval   time.Duration
+	SamplingEnabled  bool
+	ReportingEnabled bool
+}
+
+// Settings 获取当前设置
+func (pm *PerformanceMonitor) Settings() Settings {
+	return Settings{
+		SampleInterval:   time.Duration(atomic.LoadInt64(&pm.sampleInterval)),
+		ReportInterval:   time.Duration(atomic.LoadInt64(&pm.reportInterval)),
+		SamplingEnabled:  pm.samplingEnabled.Load(),
+		ReportingEnabled: pm.reportEnabled.Load(),
+	}
+}
+
+// Update 在运行时调整采样/上报间隔和开关，新的间隔立即生效（重置定时器）
+func (pm *PerformanceMonitor) Update(settings Settings) error {
+	if settings.SampleInterval < MinSampleInterval {
+		return fmt.Errorf("sample_interval must be at least %s", MinSampleInterval)
+	}
+	if settings.ReportInterval < MinReportInterval {
+		return fmt.Errorf("report_interval must be at least %s", MinReportInterval)
+	}
+
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
-	pm.reportEnabled = enabled
+	if atomic.SwapInt64(&pm.sampleInterval, int64(settings.SampleInterval)) != int64(settings.SampleInterval) {
+		notify(pm.sampleReset)
+	}
+	if atomic.SwapInt64(&pm.reportInterval, int64(settings.ReportInterval)) != int64(settings.ReportInterval) {
+		notify(pm.reportReset)
+	}
+	pm.samplingEnabled.Store(settings.SamplingEnabled)
+	pm.reportEnabled.Store(settings.ReportingEnabled)
+	return nil
+}
+
+// notify 非阻塞地发送通知，已有未处理的通知时不重复发送
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
 }
 
 // GetSampleChannel 获取采样数据通道（用于调试）
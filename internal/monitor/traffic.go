@@ -0,0 +1,269 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// errorStatus 状态码不小于该值的请求计为错误，代理在后端失败时返回502，同样计为错误
+const errorStatus = 500
+
+// UpstreamErrorClass 转发到后端失败的错误类型
+type UpstreamErrorClass int
+
+// 转发失败的错误类型
+const (
+	ErrorDialTimeout       UpstreamErrorClass = iota // 建立连接（包括TLS握手）超时
+	ErrorConnectionRefused                           // 后端拒绝连接
+	ErrorReadTimeout                                 // 已连接，等待响应超时
+	ErrorConnectionReset                             // 连接被后端重置或在响应前关闭
+	ErrorBodyTooLarge                                // 响应体超过上限
+	ErrorOther
+	upstreamErrorClasses
+)
+
+// UpstreamErrorClassNames 错误类型的名称，用于统计和Prometheus标签
+var UpstreamErrorClassNames = [upstreamErrorClasses]string{
+	"dial_timeout",
+	"connection_refused",
+	"read_timeout",
+	"connection_reset",
+	"body_too_large",
+	"other",
+}
+
+// StatusClassNames 状态码类别的名称，用于Prometheus标签
+var StatusClassNames = [...]string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// trafficCounters 一个路由、上游或后端的流量计数（原子操作）
+type trafficCounters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	active   atomic.Int64
+
+	statuses       [len(StatusClassNames)]atomic.Int64
+	upstreamErrors [upstreamErrorClasses]atomic.Int64 // 只用于上游和后端
+}
+
+// TrafficCounters 一个路由、上游或后端的流量统计
+type TrafficCounters struct {
+	Requests          int64         `json:"requests"`
+	Errors            int64         `json:"errors"`    // 状态码>=500的请求
+	BytesIn           int64         `json:"bytes_in"`  // 请求体字节数
+	BytesOut          int64         `json:"bytes_out"` // 响应体字节数
+	ActiveConnections int64         `json:"active_connections"`
+	Status            StatusClasses `json:"status"` // 按状态码类别的响应数
+}
+
+// StatusClasses 按状态码类别的响应数
+type StatusClasses struct {
+	Status1xx int64 `json:"1xx"`
+	Status2xx int64 `json:"2xx"`
+	Status3xx int64 `json:"3xx"`
+	Status4xx int64 `json:"4xx"`
+	Status5xx int64 `json:"5xx"`
+}
+
+// Counts 按StatusClassNames顺序的响应数
+func (c StatusClasses) Counts() [len(StatusClassNames)]int64 {
+	return [...]int64{c.Status1xx, c.Status2xx, c.Status3xx, c.Status4xx, c.Status5xx}
+}
+
+// UpstreamErrors 按错误类型的转发失败次数，每次失败的尝试（包括之后重试成功的）都计数
+type UpstreamErrors struct {
+	DialTimeout       int64 `json:"dial_timeout"`
+	ConnectionRefused int64 `json:"connection_refused"`
+	ReadTimeout       int64 `json:"read_timeout"`
+	ConnectionReset   int64 `json:"connection_reset"`
+	BodyTooLarge      int64 `json:"body_too_large"`
+	Other             int64 `json:"other"`
+}
+
+// Counts 按UpstreamErrorClassNames顺序的失败次数
+func (e UpstreamErrors) Counts() [upstreamErrorClasses]int64 {
+	return [...]int64{e.DialTimeout, e.ConnectionRefused, e.ReadTimeout, e.ConnectionReset, e.BodyTooLarge, e.Other}
+}
+
+// RouteTraffic 一个路由的流量统计，活跃连接为正在处理的请求数
+type RouteTraffic struct {
+	Route string `json:"route"`
+	TrafficCounters
+}
+
+// UpstreamTraffic 一个上游的流量统计，活跃连接为正在转发到该上游的请求数
+type UpstreamTraffic struct {
+	Upstream string `json:"upstream"`
+	TrafficCounters
+	UpstreamErrors UpstreamErrors `json:"upstream_errors"`
+}
+
+// BackendTraffic 一个后端的流量统计，活跃连接为正在转发到该后端的请求数
+type BackendTraffic struct {
+	Upstream string `json:"upstream"`
+	Backend  string `json:"backend"`
+	TrafficCounters
+	UpstreamErrors UpstreamErrors `json:"upstream_errors"`
+}
+
+// trafficMaps 按路由、上游和后端的流量计数
+type trafficMaps struct {
+	routes    sync.Map // 路由名称 -> *trafficCounters
+	upstreams sync.Map // 上游名称 -> *trafficCounters
+	backends  sync.Map // latencyKey -> *trafficCounters
+}
+
+// countersOf 取出计数，只在第一次出现时分配
+func countersOf(m *sync.Map, key interface{}) *trafficCounters {
+	v, ok := m.Load(key)
+	if !ok {
+		v, _ = m.LoadOrStore(key, &trafficCounters{})
+	}
+	return v.(*trafficCounters)
+}
+
+func (c *trafficCounters) record(status int, bytesIn, bytesOut int64) {
+	c.requests.Add(1)
+	if status >= errorStatus {
+		c.errors.Add(1)
+	}
+	if class := status/100 - 1; class >= 0 && class < len(c.statuses) {
+		c.statuses[class].Add(1)
+	}
+	c.bytesIn.Add(bytesIn)
+	c.bytesOut.Add(bytesOut)
+}
+
+func (c *trafficCounters) snapshot() TrafficCounters {
+	return TrafficCounters{
+		Requests:          c.requests.Load(),
+		Errors:            c.errors.Load(),
+		BytesIn:           c.bytesIn.Load(),
+		BytesOut:          c.bytesOut.Load(),
+		ActiveConnections: c.active.Load(),
+		Status: StatusClasses{
+			Status1xx: c.statuses[0].Load(),
+			Status2xx: c.statuses[1].Load(),
+			Status3xx: c.statuses[2].Load(),
+			Status4xx: c.statuses[3].Load(),
+			Status5xx: c.statuses[4].Load(),
+		},
+	}
+}
+
+func (c *trafficCounters) upstreamErrorsSnapshot() UpstreamErrors {
+	return UpstreamErrors{
+		DialTimeout:       c.upstreamErrors[ErrorDialTimeout].Load(),
+		ConnectionRefused: c.upstreamErrors[ErrorConnectionRefused].Load(),
+		ReadTimeout:       c.upstreamErrors[ErrorReadTimeout].Load(),
+		ConnectionReset:   c.upstreamErrors[ErrorConnectionReset].Load(),
+		BodyTooLarge:      c.upstreamErrors[ErrorBodyTooLarge].Load(),
+		Other:             c.upstreamErrors[ErrorOther].Load(),
+	}
+}
+
+// RecordTraffic 记录一个完成的请求，route、upstream、backend为空时不计入对应的统计
+// 重试过的请求计入最后一次尝试的上游和后端
+func (pm *PerformanceMonitor) RecordTraffic(route, upstream, backend string, status int, bytesIn, bytesOut int64) {
+	if !pm.samplingEnabled.Load() {
+		return
+	}
+	if route != "" {
+		countersOf(&pm.traffic.routes, route).record(status, bytesIn, bytesOut)
+	}
+	if upstream != "" {
+		countersOf(&pm.traffic.upstreams, upstream).record(status, bytesIn, bytesOut)
+		if backend != "" {
+			countersOf(&pm.traffic.backends, latencyKey{upstream, backend}).record(status, bytesIn, bytesOut)
+		}
+	}
+}
+
+// RecordUpstreamError 记录一次转发到后端失败的尝试
+func (pm *PerformanceMonitor) RecordUpstreamError(upstream, backend string, class UpstreamErrorClass) {
+	if !pm.samplingEnabled.Load() || class < 0 || class >= upstreamErrorClasses {
+		return
+	}
+	countersOf(&pm.traffic.upstreams, upstream).upstreamErrors[class].Add(1)
+	countersOf(&pm.traffic.backends, latencyKey{upstream, backend}).upstreamErrors[class].Add(1)
+}
+
+// RouteActive 路由的活跃请求数加delta，请求开始时为1，结束时为-1
+func (pm *PerformanceMonitor) RouteActive(route string, delta int64) {
+	countersOf(&pm.traffic.routes, route).active.Add(delta)
+}
+
+// BackendActive 上游和后端的活跃连接数加delta，每次转发尝试开始时为1，结束时为-1
+func (pm *PerformanceMonitor) BackendActive(upstream, backend string, delta int64) {
+	countersOf(&pm.traffic.upstreams, upstream).active.Add(delta)
+	countersOf(&pm.traffic.backends, latencyKey{upstream, backend}).active.Add(delta)
+}
+
+// RouteTraffics 所有路由的流量统计，按名称排序；route非空时只返回该路由
+func (pm *PerformanceMonitor) RouteTraffics(route string) []RouteTraffic {
+	stats := []RouteTraffic{}
+	pm.traffic.routes.Range(func(k, v interface{}) bool {
+		if name := k.(string); route == "" || name == route {
+			stats = append(stats, RouteTraffic{Route: name, TrafficCounters: v.(*trafficCounters).snapshot()})
+		}
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
+
+// UpstreamTraffics 所有上游的流量统计，按名称排序；upstream非空时只返回该上游
+func (pm *PerformanceMonitor) UpstreamTraffics(upstream string) []UpstreamTraffic {
+	stats := []UpstreamTraffic{}
+	pm.traffic.upstreams.Range(func(k, v interface{}) bool {
+		if name := k.(string); upstream == "" || name == upstream {
+			c := v.(*trafficCounters)
+			stats = append(stats, UpstreamTraffic{Upstream: name, TrafficCounters: c.snapshot(), UpstreamErrors: c.upstreamErrorsSnapshot()})
+		}
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Upstream < stats[j].Upstream })
+	return stats
+}
+
+// BackendTraffics 所有后端的流量统计，按上游和后端排序；upstream非空时只返回该上游的后端
+func (pm *PerformanceMonitor) BackendTraffics(upstream string) []BackendTraffic {
+	stats := []BackendTraffic{}
+	pm.traffic.backends.Range(func(k, v interface{}) bool {
+		key := k.(latencyKey)
+		if upstream == "" || key.upstream == upstream {
+			c := v.(*trafficCounters)
+			stats = append(stats, BackendTraffic{Upstream: key.upstream, Backend: key.backend, TrafficCounters: c.snapshot(), UpstreamErrors: c.upstreamErrorsSnapshot()})
+		}
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Upstream != stats[j].Upstream {
+			return stats[i].Upstream < stats[j].Upstream
+		}
+		return stats[i].Backend < stats[j].Backend
+	})
+	return stats
+}
+
+// RetainRoutes 删除配置中已不存在的路由的统计
+func (pm *PerformanceMonitor) RetainRoutes(routing map[string]*types.RoutingRule) {
+	retainCounters(&pm.traffic.routes, func(k interface{}) bool {
+		_, ok := routing[k.(string)]
+		return ok
+	})
+}
+
+// retainCounters 删除keep返回false且没有活跃请求的计数，仍有请求在处理的计数在之后的清理中删除
+func retainCounters(m *sync.Map, keep func(key interface{}) bool) {
+	m.Range(func(k, v interface{}) bool {
+		if !keep(k) && v.(*trafficCounters).active.Load() == 0 {
+			m.Delete(k)
+		}
+		return true
+	})
+}
@@ -0,0 +1,270 @@
+package orca
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 后端携带ORCA负载报告的响应头或trailer
+const (
+	HeaderName       = "endpoint-load-metrics"     // TEXT、JSON或BIN格式
+	BinaryHeaderName = "endpoint-load-metrics-bin" // gRPC二进制元数据，base64编码的OrcaLoadReport
+)
+
+// OrcaLoadReport（xds.data.orca.v3）的字段编号
+const (
+	fieldCPUUtilization         = 1
+	fieldMemUtilization         = 2
+	fieldRPS                    = 3
+	fieldRequestCost            = 4
+	fieldUtilization            = 5
+	fieldRPSFractional          = 6
+	fieldEPS                    = 7
+	fieldNamedMetrics           = 8
+	fieldApplicationUtilization = 9
+)
+
+// errEmpty 负载报告为空
+var errEmpty = errors.New("empty load report")
+
+// Report ORCA负载报告，利用率为0-1的比例
+type Report struct {
+	CPUUtilization         float64            `json:"cpu_utilization"`
+	MemUtilization         float64            `json:"mem_utilization"`
+	ApplicationUtilization float64            `json:"application_utilization"`
+	RPSFractional          float64            `json:"rps_fractional"`
+	EPS                    float64            `json:"eps"`
+	RequestCost            map[string]float64 `json:"request_cost,omitempty"`
+	Utilization            map[string]float64 `json:"utilization,omitempty"`
+	NamedMetrics           map[string]float64 `json:"named_metrics,omitempty"`
+}
+
+// EffectiveUtilization 负载均衡使用的利用率：优先使用application_utilization，未上报时使用cpu_utilization（与gRPC加权轮询相同）
+func (r *Report) EffectiveUtilization() float64 {
+	if r.ApplicationUtilization > 0 {
+		return r.ApplicationUtilization
+	}
+	return r.CPUUtilization
+}
+
+// Parse 解析endpoint-load-metrics头，值以格式前缀开头：
+// TEXT cpu_utilization=0.3, mem_utilization=0.8, named_metrics.foo=1
+// JSON {"cpu_utilization": 0.3, "mem_utilization": 0.8}
+// BIN <base64编码的OrcaLoadReport>
+func Parse(value []byte) (*Report, error) {
+	format, payload, _ := bytes.Cut(bytes.TrimSpace(value), []byte(" "))
+	payload = bytes.TrimSpace(payload)
+	switch string(format) {
+	case "TEXT":
+		return parseText(payload)
+	case "JSON":
+		var r Report
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return nil, fmt.Errorf("invalid JSON load report: %w", err)
+		}
+		return &r, validate(&r)
+	case "BIN":
+		return ParseBinary(payload)
+	}
+	return nil, fmt.Errorf("unsupported load report format %q", format)
+}
+
+// parseText 解析TEXT格式：逗号分隔的 名称=数值，map字段使用 字段名.键
+func parseText(payload []byte) (*Report, error) {
+	if len(payload) == 0 {
+		return nil, errEmpty
+	}
+	r := &Report{}
+	for _, pair := range strings.Split(string(payload), ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid load report entry %q", pair)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		switch name = strings.TrimSpace(name); name {
+		case "cpu_utilization":
+			r.CPUUtilization = v
+		case "mem_utilization":
+			r.MemUtilization = v
+		case "application_utilization":
+			r.ApplicationUtilization = v
+		case "rps_fractional":
+			r.RPSFractional = v
+		case "eps":
+			r.EPS = v
+		default:
+			field, key, ok := strings.Cut(name, ".")
+			if !ok || key == "" {
+				continue // 忽略未知字段，兼容新版本的报告
+			}
+			switch field {
+			case "request_cost":
+				setMetric(&r.RequestCost, key, v)
+			case "utilization":
+				setMetric(&r.Utilization, key, v)
+			case "named_metrics":
+				setMetric(&r.NamedMetrics, key, v)
+			}
+		}
+	}
+	return r, validate(r)
+}
+
+// ParseBinary 解析base64编码（允许省略填充）的OrcaLoadReport protobuf消息
+func ParseBinary(value []byte) (*Report, error) {
+	encoded := strings.TrimRight(string(bytes.TrimSpace(value)), "=")
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 load report: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errEmpty
+	}
+
+	r := &Report{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.Fixed64Type && isDoubleField(num):
+			bits, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			setDouble(r, num, math.Float64frombits(bits))
+		case typ == protowire.VarintType && num == fieldRPS:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			if r.RPSFractional == 0 {
+				r.RPSFractional = float64(v)
+			}
+		case typ == protowire.BytesType && (num == fieldRequestCost || num == fieldUtilization || num == fieldNamedMetrics):
+			entry, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			key, v, err := parseMapEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			switch num {
+			case fieldRequestCost:
+				setMetric(&r.RequestCost, key, v)
+			case fieldUtilization:
+				setMetric(&r.Utilization, key, v)
+			default:
+				setMetric(&r.NamedMetrics, key, v)
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return r, validate(r)
+}
+
+// isDoubleField 是否为double类型的字段
+func isDoubleField(num protowire.Number) bool {
+	switch num {
+	case fieldCPUUtilization, fieldMemUtilization, fieldRPSFractional, fieldEPS, fieldApplicationUtilization:
+		return true
+	}
+	return false
+}
+
+// setDouble 设置double字段
+func setDouble(r *Report, num protowire.Number, v float64) {
+	switch num {
+	case fieldCPUUtilization:
+		r.CPUUtilization = v
+	case fieldMemUtilization:
+		r.MemUtilization = v
+	case fieldRPSFractional:
+		r.RPSFractional = v
+	case fieldEPS:
+		r.EPS = v
+	case fieldApplicationUtilization:
+		r.ApplicationUtilization = v
+	}
+}
+
+// parseMapEntry 解析map<string, double>的一个条目（key为字段1，value为字段2）
+func parseMapEntry(data []byte) (string, float64, error) {
+	var key string
+	var value float64
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			b, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			key, data = string(b), data[n:]
+		case num == 2 && typ == protowire.Fixed64Type:
+			bits, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			value, data = math.Float64frombits(bits), data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return key, value, nil
+}
+
+// setMetric 设置map字段中的指标
+func setMetric(m *map[string]float64, key string, v float64) {
+	if *m == nil {
+		*m = make(map[string]float64)
+	}
+	(*m)[key] = v
+}
+
+// validate 校验利用率和速率的取值：不能为负数或非有限值
+func validate(r *Report) error {
+	for name, v := range map[string]float64{
+		"cpu_utilization":         r.CPUUtilization,
+		"mem_utilization":         r.MemUtilization,
+		"application_utilization": r.ApplicationUtilization,
+		"rps_fractional":          r.RPSFractional,
+		"eps":                     r.EPS,
+	} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid %s %v", name, v)
+		}
+	}
+	return nil
+}
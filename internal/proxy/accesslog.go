@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/accesslog"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// reloadAccessLog 启用、更新或关闭访问日志
+// 输出位置和格式不变时保留原日志，配置中的级别和采样率没有变化时保留运行时的调整
+func (s *Server) reloadAccessLog(cfg *types.AccessLogConfig) {
+	old := s.accessLog.Load()
+	if !cfg.Enabled {
+		if old != nil {
+			s.accessLog.Store(nil)
+			old.Close()
+		}
+		return
+	}
+
+	if old != nil && old.Reload(cfg) {
+		return
+	}
+
+	logger, err := accesslog.New(cfg)
+	if err != nil {
+		fmt.Printf("[ACCESSLOG] Failed to open access log: %v\n", err)
+		return
+	}
+	s.accessLog.Store(logger)
+	if old != nil {
+		old.Close()
+	}
+}
+
+// logAccess 记录访问日志
+func (s *Server) logAccess(ctx *fasthttp.RequestCtx) {
+	if logger := s.accessLog.Load(); logger != nil {
+		logger.Log(ctx, func() *vars.Env {
+			return s.varEnv(ctx)
+		})
+	}
+}
+
+// GetAccessLog 获取访问日志，未启用时返回nil
+func (s *Server) GetAccessLog() *accesslog.Logger {
+	return s.accessLog.Load()
+}
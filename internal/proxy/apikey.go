@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/apikey"
+	"github.com/quqi/speedmimi/internal/vars"
+)
+
+// checkAPIKey 检查请求携带的API密钥是否允许访问路由，返回false时已写入拒绝响应
+func (s *Server) checkAPIKey(ctx *fasthttp.RequestCtx, routeName string) bool {
+	store := s.apiKeys.Load()
+	secret := vars.PeekHeader(&ctx.Request.Header, store.Header())
+	if len(secret) == 0 && store.QueryParam() != "" {
+		secret = ctx.QueryArgs().Peek(store.QueryParam())
+	}
+
+	_, result, wait := store.Check(secret, routeName, time.Now())
+	switch result {
+	case apikey.MissingKey:
+		ctx.Error("Unauthorized (API key required)", fasthttp.StatusUnauthorized)
+		ctx.Response.Header.Set("WWW-Authenticate", "ApiKey header=\""+store.Header()+"\"")
+		return false
+	case apikey.UnknownKey:
+		ctx.Error("Unauthorized (Invalid API key)", fasthttp.StatusUnauthorized)
+		return false
+	case apikey.RouteDenied:
+		ctx.Error("Forbidden (API key not allowed for this route)", fasthttp.StatusForbidden)
+		return false
+	case apikey.RateLimited:
+		ctx.Error("Too Many Requests (API key rate limit exceeded)", fasthttp.StatusTooManyRequests)
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+		return false
+	}
+	return true
+}
+
+// GetAPIKeys 获取API密钥存储
+func (s *Server) GetAPIKeys() *apikey.Store {
+	return s.apiKeys.Load()
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/quqi/speedmimi/internal/audit"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// defaultAuditInterval 未配置audit.interval时的巡检间隔
+const defaultAuditInterval = 10 * time.Second
+
+// checkUnpublished 审计模式下检查上游是否在发布后被修改
+func (u *Upstream) checkUnpublished(method string) {
+	if u.published != nil && u.published.Load() {
+		audit.Violation("upstream_mutation", "%s on published upstream %s", method, u.name)
+	}
+}
+
+// runAudit 审计模式下定期巡检上游管理器和后端的不变量，未启用时只检查配置的间隔
+func (s *Server) runAudit() {
+	interval := auditInterval(s.config.GetConfig())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if audit.Enabled() {
+				s.auditUpstreams()
+			}
+			if next := auditInterval(s.config.GetConfig()); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// auditInterval 获取巡检间隔
+func auditInterval(cfg *types.Config) time.Duration {
+	if cfg.Audit.Interval > 0 {
+		return cfg.Audit.Interval
+	}
+	return defaultAuditInterval
+}
+
+// auditUpstreams 巡检当前上游管理器：已发布、名称索引一致、负载均衡器已设置，
+// 同一上游的后端ID不重复，后端不被多个上游共享，后端的原子活跃状态与配置字段一致
+func (s *Server) auditUpstreams() {
+	defer audit.SweepDone()
+
+	um := s.upstreamMgr.Load()
+	if um == nil {
+		return
+	}
+	if !um.published.Load() {
+		audit.Violation("upstream_manager_unpublished", "serving upstream manager was not published")
+	}
+	for name, index := range um.names {
+		if index >= len(um.upstreams) {
+			// 索引越界时无法遍历上游，跳过后面的检查
+			audit.Violation("upstream_index", "upstream %s maps to index %d of %d", name, index, len(um.upstreams))
+			return
+		}
+		if um.upstreams[index].name != name {
+			audit.Violation("upstream_index", "upstream %s maps to upstream %s", name, um.upstreams[index].name)
+		}
+	}
+
+	owners := make(map[*types.Backend]string)
+	for _, upstream := range um.Upstreams() {
+		if upstream.balancer == nil {
+			audit.Violation("upstream_balancer", "upstream %s has no load balancer", upstream.name)
+		}
+		ids := make(map[string]bool)
+		for _, backend := range upstream.GetAllBackends() {
+			if ids[backend.ID] {
+				audit.Violation("duplicate_backend_id", "backend %s appears twice in upstream %s", backend.ID, upstream.name)
+			}
+			ids[backend.ID] = true
+			if owner, ok := owners[backend]; ok {
+				audit.Violation("shared_backend", "backend %s is shared by upstreams %s and %s", backend.ID, owner, upstream.name)
+			}
+			owners[backend] = upstream.name
+			if backend.IsActive() != backend.Active {
+				audit.Violation("backend_active_mismatch", "backend %s/%s active=%v but atomic state is %v",
+					upstream.name, backend.ID, backend.Active, backend.IsActive())
+			}
+		}
+	}
+}
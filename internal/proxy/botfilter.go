@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 挑战Cookie的名称和签名的十六进制长度
+const (
+	botChallengeCookie       = "speedmimi_bot"
+	botChallengeSignatureLen = 32
+)
+
+// botChallengeKey 未配置challenge_secret时签名挑战Cookie的随机密钥，进程重启后之前的Cookie失效
+var botChallengeKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate bot challenge key: %v", err))
+	}
+	return key
+}()
+
+// knownBotAgents 常见自动化工具和扫描器的User-Agent片段（不区分大小写）
+var knownBotAgents = [][]byte{
+	[]byte("curl/"), []byte("wget/"), []byte("python-requests"), []byte("python-urllib"),
+	[]byte("go-http-client"), []byte("libwww-perl"), []byte("java/"), []byte("okhttp"),
+	[]byte("scrapy"), []byte("headlesschrome"), []byte("phantomjs"), []byte("sqlmap"),
+	[]byte("nikto"), []byte("nmap"), []byte("masscan"), []byte("zgrab"),
+}
+
+// 机器人评分规则的分值，累加后上限为100
+const (
+	botScoreNoUserAgent = 40
+	botScoreKnownAgent  = 50
+	botScoreNoAccept    = 20
+	botScoreNoLanguage  = 15
+	botScoreNoEncoding  = 15
+	botScoreMax         = 100
+)
+
+// botFilter 预编译的机器人过滤配置
+type botFilter struct {
+	cfg    *types.BotFilterConfig
+	allow  []*regexp.Regexp
+	deny   []*regexp.Regexp
+	secret []byte
+}
+
+// newBotFilter 编译机器人过滤配置，未配置时返回nil
+func newBotFilter(cfg *types.BotFilterConfig) (*botFilter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	f := &botFilter{cfg: cfg, secret: botChallengeKey}
+	if cfg.ChallengeSecret != "" {
+		f.secret = []byte(cfg.ChallengeSecret)
+	}
+	var err error
+	if f.allow, err = compileUserAgentPatterns(cfg.Allow); err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	}
+	if f.deny, err = compileUserAgentPatterns(cfg.Deny); err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+	return f, nil
+}
+
+// compileUserAgentPatterns 编译User-Agent正则
+func compileUserAgentPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// score 计算请求的机器人评分，allow匹配时返回-1
+func (f *botFilter) score(ctx *fasthttp.RequestCtx) int {
+	ua := ctx.Request.Header.UserAgent()
+	for _, re := range f.allow {
+		if re.Match(ua) {
+			return -1
+		}
+	}
+	for _, re := range f.deny {
+		if re.Match(ua) {
+			return botScoreMax
+		}
+	}
+
+	score := 0
+	if len(ua) == 0 {
+		score += botScoreNoUserAgent
+	} else {
+		lower := bytes.ToLower(ua)
+		for _, agent := range knownBotAgents {
+			if bytes.Contains(lower, agent) {
+				score += botScoreKnownAgent
+				break
+			}
+		}
+	}
+	if len(vars.PeekHeader(&ctx.Request.Header, "Accept")) == 0 {
+		score += botScoreNoAccept
+	}
+	if len(vars.PeekHeader(&ctx.Request.Header, "Accept-Language")) == 0 {
+		score += botScoreNoLanguage
+	}
+	if len(vars.PeekHeader(&ctx.Request.Header, "Accept-Encoding")) == 0 {
+		score += botScoreNoEncoding
+	}
+	if score > botScoreMax {
+		score = botScoreMax
+	}
+	return score
+}
+
+// checkBotFilter 按机器人评分处理请求，返回false时已写入响应
+// 客户端传入的评分请求头总是被删除，放行的请求带上代理计算的评分
+func (s *Server) checkBotFilter(ctx *fasthttp.RequestCtx, f *botFilter) bool {
+	cfg := f.cfg
+	vars.DelHeader(&ctx.Request.Header, cfg.ScoreHeader)
+
+	score := f.score(ctx)
+	if score < 0 {
+		return true
+	}
+	if score < cfg.Threshold || cfg.Action == types.BotActionTag {
+		ctx.Request.Header.Set(cfg.ScoreHeader, strconv.Itoa(score))
+		return true
+	}
+
+	if cfg.Action == types.BotActionChallenge {
+		now := time.Now()
+		clientIP := s.getClientIP(ctx)
+		if f.verifyChallenge(ctx, clientIP, now) {
+			ctx.Request.Header.Set(cfg.ScoreHeader, strconv.Itoa(score))
+			return true
+		}
+		f.writeChallenge(ctx, clientIP, now)
+		return false
+	}
+
+	ctx.Error("Forbidden (Automated client)", fasthttp.StatusForbidden)
+	return false
+}
+
+// challengeToken 生成绑定客户端IP和User-Agent的挑战令牌：过期时间.签名
+func (f *botFilter) challengeToken(clientIP string, ua []byte, expires int64) string {
+	mac := hmac.New(sha256.New, f.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", clientIP, ua, expires)
+	return strconv.FormatInt(expires, 10) + "." + hex.EncodeToString(mac.Sum(nil))[:botChallengeSignatureLen]
+}
+
+// verifyChallenge 请求是否带有未过期且签名正确的挑战Cookie
+func (f *botFilter) verifyChallenge(ctx *fasthttp.RequestCtx, clientIP string, now time.Time) bool {
+	cookie := ctx.Request.Header.Cookie(botChallengeCookie)
+	dot := bytes.IndexByte(cookie, '.')
+	if dot < 0 {
+		return false
+	}
+	expires, err := strconv.ParseInt(string(cookie[:dot]), 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	expected := f.challengeToken(clientIP, ctx.Request.Header.UserAgent(), expires)
+	return hmac.Equal(cookie, []byte(expected))
+}
+
+// writeChallenge 返回挑战页：执行脚本设置Cookie后重新加载，不执行JavaScript的客户端无法通过
+func (f *botFilter) writeChallenge(ctx *fasthttp.RequestCtx, clientIP string, now time.Time) {
+	ttl := f.cfg.ChallengeTTL
+	token := f.challengeToken(clientIP, ctx.Request.Header.UserAgent(), now.Add(ttl).Unix())
+
+	ctx.Response.Reset()
+	ctx.SetStatusCode(fasthttp.StatusForbidden)
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+	ctx.SetContentType("text/html; charset=utf-8")
+	fmt.Fprintf(ctx, `<!DOCTYPE html>
+<html><head><title>Checking your browser</title></head>
+<body>
+<noscript>Please enable JavaScript to continue.</noscript>
+<script>document.cookie="%s=%s; path=/; max-age=%d; SameSite=Lax";location.reload();</script>
+</body></html>
+`, botChallengeCookie, token, int(ttl.Seconds()))
+}
@@ -0,0 +1,305 @@
+package proxy
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/cache"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// CacheStatusHeader 响应缓存状态头：HIT、MISS、REVALIDATED
+const CacheStatusHeader = "X-Cache"
+
+// cacheState 单个请求的响应缓存处理状态
+type cacheState struct {
+	cache *cache.Cache
+	key   string
+	ttl   time.Duration
+	stale *cache.Entry // 已过期但可以通过条件请求重新验证的条目
+
+	// 否定响应（如404/410）的缓存，negativeTTL为0时不缓存
+	negativeTTL      time.Duration
+	negativeStatuses []int
+
+	// 客户端的条件请求头，转发时移除以便获取完整响应写入缓存
+	ifNoneMatch     []byte
+	ifModifiedSince []byte
+}
+
+// lookupCache 查找响应缓存
+// 命中新鲜条目时直接写入响应并返回true；返回的cacheState不为nil时，转发后需要调用storeCache
+func (s *Server) lookupCache(ctx *fasthttp.RequestCtx, rule *types.RoutingRule) (*cacheState, bool) {
+	c := s.cache.Load()
+	if c == nil || rule.Cache == nil || !rule.Cache.Enabled {
+		return nil, false
+	}
+
+	method := ctx.Method()
+	isHead := string(method) == fasthttp.MethodHead
+	if !isHead && string(method) != fasthttp.MethodGet {
+		return nil, false
+	}
+
+	// 带认证信息或明确不使用缓存的请求直接转发，请求头名称未规范化，需忽略大小写读取
+	reqDirectives := cache.ParseCacheControl(vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderCacheControl))
+	if reqDirectives.NoStore || len(vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderAuthorization)) > 0 {
+		return nil, false
+	}
+
+	key := cacheKey(ctx)
+	now := time.Now()
+	entry := c.Get(key)
+	if entry != nil && entry.Fresh(now) && !reqDirectives.NoCache {
+		c.RecordHit()
+		if entry.Status != fasthttp.StatusOK {
+			c.RecordNegativeHit()
+		}
+		serveCacheEntry(ctx, c, entry, now, "HIT")
+		return nil, true
+	}
+	c.RecordMiss()
+
+	// HEAD请求只使用新鲜的缓存
+	if isHead {
+		return nil, false
+	}
+
+	state := &cacheState{
+		cache:            c,
+		key:              key,
+		ttl:              rule.Cache.TTL,
+		negativeTTL:      rule.Cache.NegativeTTL,
+		negativeStatuses: rule.Cache.NegativeStatuses,
+		ifNoneMatch:      append([]byte(nil), vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderIfNoneMatch)...),
+		ifModifiedSince:  append([]byte(nil), vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderIfModifiedSince)...),
+	}
+	vars.DelHeader(&ctx.Request.Header, fasthttp.HeaderIfNoneMatch)
+	vars.DelHeader(&ctx.Request.Header, fasthttp.HeaderIfModifiedSince)
+
+	// 过期条目使用缓存的验证器向上游发送条件请求（否定响应不重新验证）
+	if entry != nil && entry.Status == fasthttp.StatusOK && entry.HasValidators() {
+		state.stale = entry
+		if len(entry.ETag) > 0 {
+			ctx.Request.Header.SetBytesV(fasthttp.HeaderIfNoneMatch, entry.ETag)
+		}
+		if len(entry.LastModified) > 0 {
+			ctx.Request.Header.SetBytesV(fasthttp.HeaderIfModifiedSince, entry.LastModified)
+		}
+	}
+
+	return state, false
+}
+
+// storeCache 处理上游响应：304时刷新并使用缓存条目，可缓存的200响应和配置的否定响应写入缓存，
+// 最后按客户端的条件请求头决定是否返回304
+func (s *Server) storeCache(ctx *fasthttp.RequestCtx, state *cacheState) {
+	now := time.Now()
+	resp := &ctx.Response
+
+	switch status := resp.StatusCode(); {
+	case status == fasthttp.StatusNotModified && state.stale != nil:
+		entry := revalidatedEntry(state.stale, &resp.Header, state.ttl, now)
+		state.cache.Put(entry)
+		state.cache.RecordRevalidated()
+		serveCacheEntry(ctx, nil, entry, now, "REVALIDATED")
+	case status == fasthttp.StatusOK:
+		if entry := newCacheEntry(ctx, state, state.ttl, now); entry != nil {
+			state.cache.Put(entry)
+		} else {
+			state.cache.Delete(state.key)
+		}
+		resp.Header.Set(CacheStatusHeader, "MISS")
+	case state.negative(status):
+		// 否定响应使用单独的较短新鲜期，上游指定的新鲜期不能超过negative_ttl
+		// 否定响应不重新验证，没有新鲜期时不缓存
+		if entry := newCacheEntry(ctx, state, state.negativeTTL, now); entry != nil && entry.Lifetime > 0 {
+			if entry.Lifetime > state.negativeTTL {
+				entry.Lifetime = state.negativeTTL
+			}
+			state.cache.Put(entry)
+		} else {
+			state.cache.Delete(state.key)
+		}
+		resp.Header.Set(CacheStatusHeader, "MISS")
+		return
+	default:
+		return
+	}
+
+	etag := vars.PeekResponseHeader(&resp.Header, fasthttp.HeaderETag)
+	lastModified := vars.PeekResponseHeader(&resp.Header, fasthttp.HeaderLastModified)
+	if cache.NotModified(state.ifNoneMatch, state.ifModifiedSince, etag, lastModified) {
+		state.cache.RecordNotModified()
+		setNotModified(resp)
+	}
+}
+
+// negative 状态码是否作为否定响应缓存
+func (state *cacheState) negative(status int) bool {
+	if state.negativeTTL <= 0 {
+		return false
+	}
+	for _, code := range state.negativeStatuses {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// newCacheEntry 根据上游响应创建缓存条目，ttl为上游没有指定新鲜期时使用的值，响应不可缓存时返回nil
+func newCacheEntry(ctx *fasthttp.RequestCtx, state *cacheState, ttl time.Duration, now time.Time) *cache.Entry {
+	h := &ctx.Response.Header
+	directives := cache.ParseCacheControl(vars.PeekResponseHeader(h, fasthttp.HeaderCacheControl))
+	if directives.NoStore || directives.Private || hasSetCookie(h) || !cacheableVary(vars.PeekResponseHeader(h, fasthttp.HeaderVary)) {
+		return nil
+	}
+
+	etag := vars.PeekResponseHeader(h, fasthttp.HeaderETag)
+	lastModified := vars.PeekResponseHeader(h, fasthttp.HeaderLastModified)
+	lifetime := responseFreshness(h, directives, ttl, now)
+	if lifetime <= 0 && len(etag) == 0 && len(lastModified) == 0 {
+		return nil
+	}
+
+	// 以流方式返回的响应体（断点续传路由中较大的响应）不缓存，读取会把整个响应体读入内存
+	if ctx.Response.IsBodyStream() {
+		return nil
+	}
+	body := ctx.Response.Body()
+	if int64(len(body)) > state.cache.MaxEntrySize() {
+		return nil
+	}
+
+	header := &fasthttp.ResponseHeader{}
+	h.CopyTo(header)
+	header.ResetConnectionClose()
+	header.Del(CacheStatusHeader)
+
+	return &cache.Entry{
+		Key:          state.key,
+		Host:         string(ctx.Host()),
+		Path:         string(ctx.Path()),
+		Status:       ctx.Response.StatusCode(),
+		Header:       header,
+		Body:         append([]byte(nil), body...),
+		StoredAt:     now,
+		Lifetime:     lifetime,
+		InitialAge:   parseAge(vars.PeekResponseHeader(h, fasthttp.HeaderAge)),
+		ETag:         append([]byte(nil), etag...),
+		LastModified: append([]byte(nil), lastModified...),
+	}
+}
+
+// revalidatedEntry 上游返回304后，用304响应中的头更新缓存条目并重新计算新鲜期
+func revalidatedEntry(stale *cache.Entry, h *fasthttp.ResponseHeader, ttl time.Duration, now time.Time) *cache.Entry {
+	header := &fasthttp.ResponseHeader{}
+	stale.Header.CopyTo(header)
+	h.VisitAll(func(key, value []byte) {
+		switch string(key) {
+		case fasthttp.HeaderContentLength, fasthttp.HeaderContentType, fasthttp.HeaderTransferEncoding, fasthttp.HeaderConnection:
+			return
+		}
+		header.SetBytesKV(key, value)
+	})
+
+	entry := *stale
+	entry.Header = header
+	entry.StoredAt = now
+	entry.Lifetime = responseFreshness(header, cache.ParseCacheControl(vars.PeekResponseHeader(header, fasthttp.HeaderCacheControl)), ttl, now)
+	entry.InitialAge = parseAge(vars.PeekResponseHeader(h, fasthttp.HeaderAge))
+	if etag := vars.PeekResponseHeader(h, fasthttp.HeaderETag); len(etag) > 0 {
+		entry.ETag = append([]byte(nil), etag...)
+	}
+	if lastModified := vars.PeekResponseHeader(h, fasthttp.HeaderLastModified); len(lastModified) > 0 {
+		entry.LastModified = append([]byte(nil), lastModified...)
+	}
+	return &entry
+}
+
+// serveCacheEntry 使用缓存条目作为响应，c不为nil时按客户端的条件请求头返回304
+func serveCacheEntry(ctx *fasthttp.RequestCtx, c *cache.Cache, entry *cache.Entry, now time.Time, status string) {
+	connectionClose := ctx.Response.ConnectionClose()
+
+	entry.Header.CopyTo(&ctx.Response.Header)
+	ctx.Response.SetStatusCode(entry.Status)
+	ctx.Response.SetBodyRaw(entry.Body)
+	ctx.Response.Header.Set(fasthttp.HeaderAge, strconv.FormatInt(int64(entry.Age(now)/time.Second), 10))
+	ctx.Response.Header.Set(CacheStatusHeader, status)
+	if connectionClose {
+		ctx.Response.SetConnectionClose()
+	}
+
+	if c != nil && cache.NotModified(vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderIfNoneMatch), vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderIfModifiedSince), entry.ETag, entry.LastModified) {
+		c.RecordNotModified()
+		setNotModified(&ctx.Response)
+	}
+}
+
+// setNotModified 将响应改为304，同时清除上游的状态描述（如"OK"）
+func setNotModified(resp *fasthttp.Response) {
+	resp.SetStatusCode(fasthttp.StatusNotModified)
+	resp.Header.SetStatusMessage(nil)
+	resp.ResetBody()
+}
+
+// responseFreshness 计算上游响应的新鲜期
+func responseFreshness(h *fasthttp.ResponseHeader, d cache.Directives, fallback time.Duration, now time.Time) time.Duration {
+	return cache.Freshness(d, vars.PeekResponseHeader(h, fasthttp.HeaderExpires), vars.PeekResponseHeader(h, fasthttp.HeaderDate), fallback, now)
+}
+
+// hasSetCookie 响应是否设置了Cookie，这类响应不写入缓存
+func hasSetCookie(h *fasthttp.ResponseHeader) bool {
+	found := false
+	h.VisitAllCookie(func(key, value []byte) {
+		found = true
+	})
+	return found || len(vars.PeekResponseHeader(h, fasthttp.HeaderSetCookie)) > 0
+}
+
+// cacheKey 缓存键：Host、请求URI和Accept-Encoding
+func cacheKey(ctx *fasthttp.RequestCtx) string {
+	key := make([]byte, 0, 128)
+	key = append(key, ctx.Host()...)
+	key = append(key, 0)
+	key = append(key, ctx.RequestURI()...)
+	key = append(key, 0)
+	key = append(key, vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderAcceptEncoding)...)
+	return string(key)
+}
+
+// cacheableVary 只缓存不带Vary或只按Accept-Encoding区分的响应（Accept-Encoding已包含在缓存键中）
+func cacheableVary(vary []byte) bool {
+	for len(vary) > 0 {
+		var field []byte
+		if i := bytes.IndexByte(vary, ','); i >= 0 {
+			field, vary = vary[:i], vary[i+1:]
+		} else {
+			field, vary = vary, nil
+		}
+		field = bytes.TrimSpace(field)
+		if len(field) > 0 && !bytes.EqualFold(field, []byte(fasthttp.HeaderAcceptEncoding)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAge 解析Age头
+func parseAge(b []byte) time.Duration {
+	n, err := strconv.Atoi(string(b))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// GetCache 获取响应缓存，未启用时返回nil
+func (s *Server) GetCache() *cache.Cache {
+	return s.cache.Load()
+}
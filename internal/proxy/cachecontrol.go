@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// applyCacheControl 按路由配置改写上游响应的Cache-Control/Expires
+func applyCacheControl(ctx *fasthttp.RequestCtx, cc *types.CacheControlConfig) {
+	if cc == nil || !cacheControlApplies(ctx, cc) {
+		return
+	}
+
+	header := &ctx.Response.Header
+	switch cc.Mode {
+	case "default":
+		// 上游已给出缓存策略时保持不变
+		if len(header.Peek("Cache-Control")) > 0 || len(header.Peek("Expires")) > 0 {
+			return
+		}
+	default:
+		// override和strip都会丢弃上游的缓存头
+		header.Del("Cache-Control")
+		header.Del("Expires")
+		header.Del("Pragma")
+	}
+
+	switch {
+	case cc.MaxAge > 0:
+		seconds := int64(cc.MaxAge / time.Second)
+		header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(seconds, 10))
+		header.SetBytesV("Expires", fasthttp.AppendHTTPDate(nil, time.Now().Add(cc.MaxAge)))
+	case cc.Value != "":
+		header.Set("Cache-Control", cc.Value)
+	}
+
+	if cc.CDNValue != "" {
+		header.Set(cc.CDNHeader, cc.CDNValue)
+	}
+}
+
+// cacheControlApplies 判断响应状态码和请求路径是否在覆盖范围内
+func cacheControlApplies(ctx *fasthttp.RequestCtx, cc *types.CacheControlConfig) bool {
+	status := ctx.Response.StatusCode()
+	if len(cc.StatusCodes) == 0 {
+		if status < 200 || status >= 400 {
+			return false
+		}
+	} else {
+		matched := false
+		for _, code := range cc.StatusCodes {
+			if code == status {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(cc.Extensions) == 0 {
+		return true
+	}
+	path := ctx.Path()
+	for _, ext := range cc.Extensions {
+		if bytes.HasSuffix(path, []byte(ext)) {
+			return true
+		}
+	}
+	return false
+}
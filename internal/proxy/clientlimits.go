@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"net"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// ipAllowlist 不受单IP限制的IP和网段
+type ipAllowlist []*net.IPNet
+
+// newIPAllowlist 解析白名单配置，单个IP按/32或/128网段处理，无法解析的条目被忽略（配置加载时已校验）
+func newIPAllowlist(entries []string) ipAllowlist {
+	var list ipAllowlist
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			list = append(list, ipNet)
+		}
+	}
+	return list
+}
+
+// contains 检查IP是否在白名单中
+func (a ipAllowlist) contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range a {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientConnLimits 单IP并发连接数上限
+type clientConnLimits struct {
+	maxConns  int
+	allowlist ipAllowlist
+	entries   []string
+}
+
+// ClientLimitStats 单IP并发连接数上限统计
+type ClientLimitStats struct {
+	Enabled       bool     `json:"enabled"`
+	MaxConnsPerIP int      `json:"max_conns_per_ip"`
+	Allowlist     []string `json:"allowlist"`
+	TrackedIPs    int      `json:"tracked_ips"` // 有打开连接的客户端IP数（不含白名单）
+	AtLimit       int      `json:"at_limit"`    // 连接数达到上限的客户端IP数
+	Rejected      int64    `json:"rejected"`    // 超过上限被直接关闭的连接数
+}
+
+// SetClientLimits 更新单IP并发连接数上限，max_conns_per_ip为0时关闭
+// 已建立的连接不受影响，只限制之后接收的连接
+func (t *ConnTable) SetClientLimits(cfg *types.ClientLimitsConfig) {
+	if cfg.MaxConnsPerIP <= 0 {
+		t.clientLimits.Store(nil)
+		return
+	}
+	t.clientLimits.Store(&clientConnLimits{
+		maxConns:  cfg.MaxConnsPerIP,
+		allowlist: newIPAllowlist(cfg.Allowlist),
+		entries:   append([]string(nil), cfg.Allowlist...),
+	})
+}
+
+// admitClient 在接收连接时按TCP对端IP检查并发连接数上限
+// 返回计数所用的IP（未计数时为空）和是否允许该连接
+func (t *ConnTable) admitClient(conn net.Conn) (string, bool) {
+	limits := t.clientLimits.Load()
+	if limits == nil {
+		return "", true
+	}
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || limits.allowlist.contains(addr.IP) {
+		return "", true
+	}
+
+	ip := addr.IP.String()
+	t.ipMu.Lock()
+	defer t.ipMu.Unlock()
+	if t.perIP[ip] >= limits.maxConns {
+		t.clientRejects.Add(1)
+		return "", false
+	}
+	t.perIP[ip]++
+	return ip, true
+}
+
+// releaseClient 连接关闭时减少IP的连接计数
+func (t *ConnTable) releaseClient(ip string) {
+	t.ipMu.Lock()
+	defer t.ipMu.Unlock()
+	if t.perIP[ip] <= 1 {
+		delete(t.perIP, ip)
+		return
+	}
+	t.perIP[ip]--
+}
+
+// ClientLimitStats 获取单IP并发连接数上限统计
+func (t *ConnTable) ClientLimitStats() ClientLimitStats {
+	stats := ClientLimitStats{Allowlist: []string{}}
+	limits := t.clientLimits.Load()
+	if limits != nil {
+		stats.Enabled = true
+		stats.MaxConnsPerIP = limits.maxConns
+		stats.Allowlist = append(stats.Allowlist, limits.entries...)
+	}
+
+	t.ipMu.Lock()
+	stats.TrackedIPs = len(t.perIP)
+	if limits != nil {
+		for _, n := range t.perIP {
+			if n >= limits.maxConns {
+				stats.AtLimit++
+			}
+		}
+	}
+	t.ipMu.Unlock()
+
+	stats.Rejected = t.clientRejects.Load()
+	return stats
+}
@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 回收后等待旧连接池中正在使用的连接归还并关闭的最长时间
+const poolDrainTimeout = 5 * time.Minute
+
+// ClientPool 按后端地址复用的上游连接池
+// 后端地址变化（管理API修改host/port/scheme）时自动使用新的连接池
+type ClientPool struct {
+	mu      sync.RWMutex
+	clients map[string]*pooledClient // scheme://host:port -> 连接池
+}
+
+// pooledClient 单个后端地址的连接池
+type pooledClient struct {
+	client    *fasthttp.HostClient
+	stream    *fasthttp.HostClient // 断点续传路由使用，较大的响应体以流方式读取
+	createdAt time.Time
+	stats     *poolStats
+}
+
+// PoolInfo 连接池回收结果
+type PoolInfo struct {
+	Address   string    `json:"address"`
+	Conns     int       `json:"conns"` // 回收时旧连接池中的连接数
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewClientPool 创建上游连接池
+func NewClientPool() *ClientPool {
+	return &ClientPool{
+		clients: make(map[string]*pooledClient),
+	}
+}
+
+// Get 获取后端的连接池用于发送一个请求，不存在时创建
+func (p *ClientPool) Get(backend *types.Backend) *fasthttp.HostClient {
+	return p.acquire(backend).client
+}
+
+// Stream 获取后端以流方式读取响应体的连接池用于发送一个请求，不存在时创建
+// 超过rangeStreamThreshold的响应体不在内存中缓冲，由fasthttp在写出响应时从上游连接读取
+func (p *ClientPool) Stream(backend *types.Backend) *fasthttp.HostClient {
+	return p.acquire(backend).stream
+}
+
+// acquire 获取后端的连接池并计入请求数，不存在时创建
+func (p *ClientPool) acquire(backend *types.Backend) *pooledClient {
+	key := poolKey(backend)
+
+	p.mu.RLock()
+	pc := p.clients[key]
+	p.mu.RUnlock()
+	if pc == nil {
+		p.mu.Lock()
+		if pc = p.clients[key]; pc == nil {
+			pc = newPooledClient(backend)
+			p.clients[key] = pc
+		}
+		p.mu.Unlock()
+	}
+	pc.stats.requests.Add(1)
+	return pc
+}
+
+// lookup 获取后端的连接池，不存在时返回nil
+func (p *ClientPool) lookup(backend *types.Backend) *pooledClient {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.clients[poolKey(backend)]
+}
+
+// Recycle 回收后端的连接池：后续请求使用新的连接池（重新解析DNS、重新建立连接），
+// 旧连接池立即关闭空闲连接，正在使用的连接在请求结束后关闭
+// 后端尚无连接池时返回nil
+func (p *ClientPool) Recycle(backend *types.Backend) *PoolInfo {
+	key := poolKey(backend)
+
+	p.mu.Lock()
+	old := p.clients[key]
+	if old == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	p.clients[key] = newPooledClient(backend)
+	p.mu.Unlock()
+
+	info := &PoolInfo{
+		Address:   key,
+		Conns:     old.client.ConnsCount() + old.stream.ConnsCount(),
+		CreatedAt: old.createdAt,
+	}
+	go drainClient(old.client)
+	go drainClient(old.stream)
+	return info
+}
+
+// Prune 关闭不再被任何后端使用的连接池
+func (p *ClientPool) Prune(backends []*types.Backend) {
+	keep := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		keep[poolKey(backend)] = true
+	}
+
+	p.mu.Lock()
+	var stale []*fasthttp.HostClient
+	for key, pc := range p.clients {
+		if !keep[key] {
+			stale = append(stale, pc.client, pc.stream)
+			delete(p.clients, key)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, client := range stale {
+		go drainClient(client)
+	}
+}
+
+// drainClient 关闭旧连接池的空闲连接，直到所有连接都已归还并关闭或超时
+func drainClient(client *fasthttp.HostClient) {
+	deadline := time.Now().Add(poolDrainTimeout)
+	for {
+		client.CloseIdleConnections()
+		if client.ConnsCount() == 0 || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// newPooledClient 创建后端连接池，每个连接池使用独立的拨号器（独立的DNS缓存）
+func newPooledClient(backend *types.Backend) *pooledClient {
+	scheme := backend.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	dialer := &fasthttp.TCPDialer{}
+	stats := &poolStats{}
+
+	// https后端在拨号时完成TLS握手（fasthttp不会再次握手），以便记录握手耗时和协商结果
+	var tlsConfig *tls.Config
+	if scheme == "https" {
+		tlsConfig = &tls.Config{
+			ServerName: backend.Host,
+			NextProtos: []string{"http/1.1"},
+		}
+	}
+
+	addr := net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port))
+	dial := func(addr string) (net.Conn, error) {
+		return stats.dial(dialer, addr, tlsConfig)
+	}
+	stream := newHostClient(addr, scheme == "https", dial)
+	stream.StreamResponseBody = true
+	stream.MaxResponseBodySize = rangeStreamThreshold
+
+	return &pooledClient{
+		client:    newHostClient(addr, scheme == "https", dial),
+		stream:    stream,
+		createdAt: time.Now(),
+		stats:     stats,
+	}
+}
+
+// newHostClient 创建到后端地址的HTTP/1.1连接池
+func newHostClient(addr string, isTLS bool, dial fasthttp.DialFunc) *fasthttp.HostClient {
+	return &fasthttp.HostClient{
+		Addr:  addr,
+		IsTLS: isTLS,
+
+		// 基础超时设置，等待响应的超时按请求设置（长轮询路由更长）
+		WriteTimeout:        30 * time.Second,
+		MaxConnDuration:     300 * time.Second,
+		MaxConnWaitTimeout:  10 * time.Second,
+		MaxIdleConnDuration: 120 * time.Second,
+
+		// 高并发优化
+		MaxConns:        100000,
+		ReadBufferSize:  8192,
+		WriteBufferSize: 8192,
+
+		// 连接优化
+		DisableHeaderNamesNormalizing: true,
+		DisablePathNormalizing:        true,
+		NoDefaultUserAgentHeader:      true,
+
+		Dial: dial,
+
+		// 连接重试策略：只对GET请求重试，避免副作用
+		RetryIf: func(req *fasthttp.Request) bool {
+			return string(req.Header.Method()) == "GET"
+		},
+		MaxIdemponentCallAttempts: 2,
+	}
+}
+
+// poolKey 连接池键
+func poolKey(backend *types.Backend) string {
+	scheme := backend.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, backend.Host, backend.Port)
+}
+
+// RecyclePools 回收上游（backendID为空时为上游的全部后端）的连接池，不影响监听端口
+// 返回被回收的连接池，尚未建立连接池的后端不包含在结果中
+func (s *Server) RecyclePools(upstreamID, backendID string) ([]PoolInfo, error) {
+	upstream := s.upstreamMgr.Load().GetUpstream(upstreamID)
+	if upstream == nil {
+		return nil, fmt.Errorf("upstream %s not found", upstreamID)
+	}
+
+	found := false
+	recycled := make([]PoolInfo, 0)
+	for _, backend := range upstream.GetAllBackends() {
+		if backendID != "" && backend.ID != backendID {
+			continue
+		}
+		found = true
+		if info := s.clients.Recycle(backend); info != nil {
+			recycled = append(recycled, *info)
+			fmt.Printf("[POOL] Recycled connection pool %s (%d conns)\n", info.Address, info.Conns)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("backend %s not found in upstream %s", backendID, upstreamID)
+	}
+	return recycled, nil
+}
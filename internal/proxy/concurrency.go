@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/concurrency"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// ConcurrencyLimits 各路由和各上游的并发请求数限制器
+// 配置更新时按名称保留已有的限制器，正在处理和排队的请求不受影响
+type ConcurrencyLimits struct {
+	mu        sync.RWMutex
+	routes    map[string]*concurrency.Limiter
+	upstreams map[string]*concurrency.Limiter
+}
+
+// ConcurrencyStats 并发限制统计
+type ConcurrencyStats struct {
+	Routes    map[string]concurrency.Stats `json:"routes"`
+	Upstreams map[string]concurrency.Stats `json:"upstreams"`
+}
+
+// NewConcurrencyLimits 创建并发限制器集合
+func NewConcurrencyLimits() *ConcurrencyLimits {
+	return &ConcurrencyLimits{
+		routes:    make(map[string]*concurrency.Limiter),
+		upstreams: make(map[string]*concurrency.Limiter),
+	}
+}
+
+// Update 按配置创建、调整或删除限制器
+func (c *ConcurrencyLimits) Update(config *types.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	routes := make(map[string]*types.ConcurrencyLimitConfig)
+	for name, rule := range config.Routing {
+		if rule.Concurrency != nil {
+			routes[name] = rule.Concurrency
+		}
+	}
+	updateConcurrencyLimiters(c.routes, routes)
+
+	upstreams := make(map[string]*types.ConcurrencyLimitConfig)
+	for name, upstream := range config.Upstreams {
+		if upstream != nil && upstream.Concurrency != nil {
+			upstreams[name] = upstream.Concurrency
+		}
+	}
+	updateConcurrencyLimiters(c.upstreams, upstreams)
+}
+
+// updateConcurrencyLimiters 按名称创建、调整或删除限制器
+// 删除的限制器上正在处理的请求仍会归还名额，不影响新的限制器
+func updateConcurrencyLimiters(limiters map[string]*concurrency.Limiter, configs map[string]*types.ConcurrencyLimitConfig) {
+	for name := range limiters {
+		if configs[name] == nil {
+			delete(limiters, name)
+		}
+	}
+	for name, cfg := range configs {
+		// reject模式不排队
+		maxQueue, queueTimeout := 0, cfg.QueueTimeout
+		if cfg.Overflow == types.ConcurrencyQueue {
+			maxQueue = cfg.MaxQueue
+		} else {
+			queueTimeout = 0
+		}
+
+		if limiter := limiters[name]; limiter != nil {
+			limiter.SetLimit(cfg.MaxInflight, maxQueue, queueTimeout)
+		} else {
+			limiters[name] = concurrency.NewLimiter(cfg.MaxInflight, maxQueue, queueTimeout)
+		}
+	}
+}
+
+// Stats 获取并发限制统计
+func (c *ConcurrencyLimits) Stats() ConcurrencyStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := ConcurrencyStats{
+		Routes:    make(map[string]concurrency.Stats, len(c.routes)),
+		Upstreams: make(map[string]concurrency.Stats, len(c.upstreams)),
+	}
+	for name, limiter := range c.routes {
+		stats.Routes[name] = limiter.Stats()
+	}
+	for name, limiter := range c.upstreams {
+		stats.Upstreams[name] = limiter.Stats()
+	}
+	return stats
+}
+
+// route 获取路由的并发限制器
+func (c *ConcurrencyLimits) route(name string) *concurrency.Limiter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.routes[name]
+}
+
+// upstream 获取上游的并发限制器
+func (c *ConcurrencyLimits) upstream(name string) *concurrency.Limiter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.upstreams[name]
+}
+
+// acquireConcurrency 获取并发名额，达到上限且排队失败时返回503
+// 返回的limiter不为nil时，请求处理完成后需要调用其Release
+func acquireConcurrency(ctx *fasthttp.RequestCtx, limiter *concurrency.Limiter, scope string) (*concurrency.Limiter, bool) {
+	if limiter == nil {
+		return nil, true
+	}
+	if !limiter.Acquire() {
+		ctx.Error("Service Unavailable ("+scope+" concurrency limit reached)", fasthttp.StatusServiceUnavailable)
+		return nil, false
+	}
+	return limiter, true
+}
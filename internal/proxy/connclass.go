@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 连接类别
+const (
+	ConnClassHTTP   = "http"   // 普通请求/响应
+	ConnClassStream = "stream" // WebSocket和SSE流式连接
+)
+
+// userValueConnLease 请求占用的连接类别预算
+const userValueConnLease = "speedmimi.conn_lease"
+
+// ConnClassStats 单个连接类别的并发和内存统计
+type ConnClassStats struct {
+	Class               string `json:"class"`
+	Active              int64  `json:"active"`               // 正在处理的请求数（占用的处理协程数）
+	MaxConcurrent       int    `json:"max_concurrent"`       // 0表示不限制
+	Memory              int64  `json:"memory"`               // 正在处理的请求占用的缓冲内存（字节）
+	PeakMemory          int64  `json:"peak_memory"`          // 启动以来的内存峰值
+	MaxMemory           int64  `json:"max_memory"`           // 0表示不限制
+	Total               int64  `json:"total"`                // 已接受的请求数
+	RejectedConcurrency int64  `json:"rejected_concurrency"` // 因并发达到上限被拒绝的请求数
+	RejectedMemory      int64  `json:"rejected_memory"`      // 因内存达到上限被拒绝的请求数
+}
+
+// connClass 单个连接类别的预算
+type connClass struct {
+	name   string
+	limits atomic.Pointer[types.ConnectionClassLimit]
+
+	active              atomic.Int64
+	memory              atomic.Int64
+	peakMemory          atomic.Int64
+	total               atomic.Int64
+	rejectedConcurrency atomic.Int64
+	rejectedMemory      atomic.Int64
+}
+
+// ConnClassBudget 按连接类别分别限制并发请求数和缓冲内存，
+// 避免大量流式连接占满普通HTTP请求使用的处理协程和缓冲区
+type ConnClassBudget struct {
+	http   connClass
+	stream connClass
+}
+
+// NewConnClassBudget 创建连接类别预算，默认不限制
+func NewConnClassBudget() *ConnClassBudget {
+	b := &ConnClassBudget{}
+	b.http.name = ConnClassHTTP
+	b.stream.name = ConnClassStream
+	b.http.limits.Store(&types.ConnectionClassLimit{})
+	b.stream.limits.Store(&types.ConnectionClassLimit{})
+	return b
+}
+
+// Update 按配置更新各类别的限制，已占用的预算在请求结束后释放
+func (b *ConnClassBudget) Update(cfg *types.ConnectionClassesConfig) {
+	httpLimit, streamLimit := cfg.HTTP, cfg.Stream
+	b.http.limits.Store(&httpLimit)
+	b.stream.limits.Store(&streamLimit)
+}
+
+// Stats 获取各类别的统计
+func (b *ConnClassBudget) Stats() []ConnClassStats {
+	return []ConnClassStats{b.http.stats(), b.stream.stats()}
+}
+
+// class 协议对应的连接类别
+func (b *ConnClassBudget) class(protocol types.ProtocolType) *connClass {
+	if protocol == types.WebSocket || protocol == types.SSE {
+		return &b.stream
+	}
+	return &b.http
+}
+
+func (c *connClass) stats() ConnClassStats {
+	limits := c.limits.Load()
+	return ConnClassStats{
+		Class:               c.name,
+		Active:              c.active.Load(),
+		MaxConcurrent:       limits.MaxConcurrent,
+		Memory:              c.memory.Load(),
+		PeakMemory:          c.peakMemory.Load(),
+		MaxMemory:           limits.MaxMemory,
+		Total:               c.total.Load(),
+		RejectedConcurrency: c.rejectedConcurrency.Load(),
+		RejectedMemory:      c.rejectedMemory.Load(),
+	}
+}
+
+// connLease 请求占用的并发和内存预算，请求结束时释放
+type connLease struct {
+	class  *connClass
+	memory int64
+}
+
+// acquire 占用一个并发名额和memory字节的内存，超过限制时返回nil
+func (c *connClass) acquire(memory int64) *connLease {
+	limits := c.limits.Load()
+	if active := c.active.Add(1); limits.MaxConcurrent > 0 && active > int64(limits.MaxConcurrent) {
+		c.active.Add(-1)
+		c.rejectedConcurrency.Add(1)
+		return nil
+	}
+
+	lease := &connLease{class: c}
+	if !lease.grow(memory) {
+		c.active.Add(-1)
+		c.rejectedMemory.Add(1)
+		return nil
+	}
+	c.total.Add(1)
+	return lease
+}
+
+// grow 追加占用n字节的内存，超过限制时不占用并返回false
+func (l *connLease) grow(n int64) bool {
+	if n <= 0 {
+		return true
+	}
+	c := l.class
+	limit := c.limits.Load().MaxMemory
+	memory := c.memory.Add(n)
+	if limit > 0 && memory > limit {
+		c.memory.Add(-n)
+		return false
+	}
+	l.memory += n
+
+	for {
+		peak := c.peakMemory.Load()
+		if memory <= peak || c.peakMemory.CompareAndSwap(peak, memory) {
+			break
+		}
+	}
+	return true
+}
+
+// release 释放占用的预算
+func (l *connLease) release() {
+	l.class.memory.Add(-l.memory)
+	l.class.active.Add(-1)
+}
+
+// acquireConnClass 按请求的连接类别占用预算，超过限制时返回503
+// 请求占用读写缓冲区和声明的请求体大小，响应体在收到后由chargeResponse计入
+func (s *Server) acquireConnClass(ctx *fasthttp.RequestCtx) (*connLease, bool) {
+	cfg := s.config.GetConfig()
+	class := s.connClasses.class(s.detectProtocol(ctx))
+
+	memory := int64(cfg.Server.ReadBufferSize + cfg.Server.WriteBufferSize)
+	if n := ctx.Request.Header.ContentLength(); n > 0 {
+		memory += int64(n)
+	}
+
+	lease := class.acquire(memory)
+	if lease == nil {
+		ctx.Error("Service Unavailable (Connection class limit reached)", fasthttp.StatusServiceUnavailable)
+		return nil, false
+	}
+	ctx.SetUserValue(userValueConnLease, lease)
+	return lease, true
+}
+
+// chargeResponse 把上游响应体计入请求的连接类别，超过内存限制时丢弃响应并返回503
+// 以流方式返回的响应体不在内存中缓冲，不计入
+func chargeResponse(ctx *fasthttp.RequestCtx) {
+	lease, ok := ctx.UserValue(userValueConnLease).(*connLease)
+	if !ok || ctx.Response.IsBodyStream() {
+		return
+	}
+	if !lease.grow(int64(len(ctx.Response.Body()))) {
+		lease.class.rejectedMemory.Add(1)
+		ctx.Error("Service Unavailable (Connection class memory limit reached)", fasthttp.StatusServiceUnavailable)
+	}
+}
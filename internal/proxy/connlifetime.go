@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// connLifetimeLimits 客户端连接的生命周期上限
+type connLifetimeLimits struct {
+	maxRequests int64
+	maxAge      time.Duration
+	jitter      time.Duration
+}
+
+// ConnLifetimeStats 连接生命周期上限统计
+type ConnLifetimeStats struct {
+	Enabled           bool   `json:"enabled"`
+	MaxRequests       int64  `json:"max_requests"`
+	MaxAge            string `json:"max_age"`
+	MaxAgeJitter      string `json:"max_age_jitter"`
+	MaxRequestsClosed int64  `json:"max_requests_closed"` // 因处理的请求数达到上限而关闭的连接数
+	MaxAgeClosed      int64  `json:"max_age_closed"`      // 因存在时间达到上限而关闭的连接数
+}
+
+// SetConnLifetime 更新连接生命周期上限，max_requests和max_age都为0时关闭
+func (t *ConnTable) SetConnLifetime(cfg *types.ConnectionLifetimeConfig) {
+	if cfg.MaxRequests <= 0 && cfg.MaxAge <= 0 {
+		t.lifetime.Store(nil)
+		return
+	}
+	t.lifetime.Store(&connLifetimeLimits{
+		maxRequests: int64(cfg.MaxRequests),
+		maxAge:      cfg.MaxAge,
+		jitter:      cfg.MaxAgeJitter,
+	})
+}
+
+// ConnLifetimeStats 获取连接生命周期上限统计
+func (t *ConnTable) ConnLifetimeStats() ConnLifetimeStats {
+	var stats ConnLifetimeStats
+	if limits := t.lifetime.Load(); limits != nil {
+		stats.Enabled = true
+		stats.MaxRequests = limits.maxRequests
+		stats.MaxAge = limits.maxAge.String()
+		stats.MaxAgeJitter = limits.jitter.String()
+	}
+	stats.MaxRequestsClosed = t.lifetimeRequestCloses.Load()
+	stats.MaxAgeClosed = t.lifetimeAgeCloses.Load()
+	return stats
+}
+
+// maxAgeOf 连接的最大存在时间，按连接ID在[max_age, max_age+jitter)中取固定值，
+// 同时建立的连接不会在同一时刻关闭并集中重连
+func (l *connLifetimeLimits) maxAgeOf(id uint64) time.Duration {
+	if l.jitter <= 0 {
+		return l.maxAge
+	}
+	return l.maxAge + time.Duration((id*0x9E3779B97F4A7C15)%uint64(l.jitter))
+}
+
+// checkConnLifetime 请求结束时检查连接是否达到生命周期上限，达到时在响应中要求关闭连接，
+// 客户端随后重新建立连接，经过前面的L4负载均衡器分配到其他代理实例
+func (s *Server) checkConnLifetime(ctx *fasthttp.RequestCtx) {
+	limits := s.conns.lifetime.Load()
+	if limits == nil || ctx.Response.ConnectionClose() {
+		return
+	}
+	tc := trackedConnOf(ctx)
+	if tc == nil {
+		return
+	}
+
+	if limits.maxRequests > 0 && atomic.LoadInt64(&tc.requests) >= limits.maxRequests {
+		s.conns.lifetimeRequestCloses.Add(1)
+		ctx.SetConnectionClose()
+		return
+	}
+	if limits.maxAge > 0 && time.Since(tc.acceptedAt) >= limits.maxAgeOf(tc.id) {
+		s.conns.lifetimeAgeCloses.Add(1)
+		ctx.SetConnectionClose()
+	}
+}
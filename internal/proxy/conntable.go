@@ -0,0 +1,310 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 连接列表的默认和最大返回数量
+const (
+	DefaultConnListLimit = 100
+	MaxConnListLimit     = 1000
+)
+
+// ConnTable 当前客户端连接表
+type ConnTable struct {
+	conns  sync.Map // id -> *trackedConn
+	nextID uint64
+	count  int64
+
+	slowClient      atomic.Pointer[slowClientLimits] // 为nil时不限制待写出的响应
+	slowDisconnects atomic.Int64
+
+	clientLimits  atomic.Pointer[clientConnLimits] // 为nil时不限制单IP连接数
+	ipMu          sync.Mutex
+	perIP         map[string]int // 客户端IP -> 打开的连接数（只统计受限制的IP）
+	clientRejects atomic.Int64
+
+	slowRequest       atomic.Pointer[slowRequestLimits] // 为nil时不限制请求头和请求体的读取
+	incomplete        atomic.Int64                      // 正在发送请求头的连接数
+	headerTimeouts    atomic.Int64
+	rateTimeouts      atomic.Int64
+	incompleteRejects atomic.Int64
+
+	lifetime              atomic.Pointer[connLifetimeLimits] // 为nil时不限制连接的请求数和存在时间
+	lifetimeRequestCloses atomic.Int64
+	lifetimeAgeCloses     atomic.Int64
+}
+
+// NewConnTable 创建连接表
+func NewConnTable() *ConnTable {
+	return &ConnTable{perIP: make(map[string]int)}
+}
+
+// trackedConn 记录在连接表中的客户端连接，统计收发字节数和最近一次请求的路由/后端
+type trackedConn struct {
+	net.Conn
+	table      *ConnTable
+	id         uint64
+	acceptedAt time.Time
+	bytesIn    int64
+	bytesOut   int64
+	requests   int64
+	lastActive int64 // UnixNano
+	reqStart   int64 // 正在处理的请求的开始时间（UnixNano），没有请求在处理时为0
+	target     atomic.Pointer[connTarget]
+	clientIP   string // 计入单IP连接数上限的客户端IP，未计数时为空
+	closeOnce  sync.Once
+
+	// 慢客户端保护，pending可被统计并发读取，其余字段只在写出响应的协程中访问
+	pending       int64     // 当前响应尚未写出的字节数
+	writeDeadline time.Time // fasthttp设置的写超时
+	overSince     time.Time // 待写出的响应开始超过上限的时间
+	streamUntil   time.Time // 以流方式返回的响应的写超时，不早于fasthttp设置的写超时，只用于当前响应
+
+	// 慢请求保护，phase可被Close并发修改，其余字段只在读取请求的协程中访问
+	phase        int32         // 请求读取阶段
+	readDeadline time.Time     // fasthttp设置的读超时
+	readAdjusted bool          // 底层连接的读超时被慢请求保护提前
+	headerStart  time.Time     // 收到请求第一个字节的时间
+	reqBytes     int64         // 当前请求已读取的字节数
+	reqWait      time.Duration // 读取当前请求时等待客户端的累计时间
+}
+
+// connTarget 连接最近一次请求的路由、上游和后端
+type connTarget struct {
+	route    string
+	upstream string
+	backend  string
+}
+
+// track 将新连接加入连接表，客户端IP的连接数达到上限时返回nil
+func (t *ConnTable) track(conn net.Conn) *trackedConn {
+	clientIP, ok := t.admitClient(conn)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	tc := &trackedConn{
+		Conn:       conn,
+		table:      t,
+		id:         atomic.AddUint64(&t.nextID, 1),
+		acceptedAt: now,
+		lastActive: now.UnixNano(),
+		clientIP:   clientIP,
+	}
+	t.conns.Store(tc.id, tc)
+	atomic.AddInt64(&t.count, 1)
+	return tc
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	var n int
+	var err error
+	if limits := c.table.slowRequest.Load(); limits != nil || c.readAdjusted {
+		n, err = c.readGuarded(b, limits)
+	} else {
+		n, err = c.Conn.Read(b)
+	}
+	if n > 0 {
+		atomic.AddInt64(&c.bytesIn, int64(n))
+		atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// Close 关闭连接并从连接表移除
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.table.conns.Delete(c.id)
+		atomic.AddInt64(&c.table.count, -1)
+		c.leavePhase(phaseClosed)
+		if c.clientIP != "" {
+			c.table.releaseClient(c.clientIP)
+		}
+	})
+	return c.Conn.Close()
+}
+
+// trackedConnOf 获取请求所在的连接表记录（去掉请求行预检的包装，TLS连接取底层连接）
+func trackedConnOf(ctx *fasthttp.RequestCtx) *trackedConn {
+	conn := ctx.Conn()
+	switch gc := conn.(type) {
+	case *guardedConn:
+		conn = gc.Conn
+	case *guardedTLSConn:
+		conn = gc.tls
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tc, _ := conn.(*trackedConn)
+	return tc
+}
+
+// recordConnRequest 记录连接上的新请求和目标路由，请求处理完成后调用recordConnDone
+func recordConnRequest(ctx *fasthttp.RequestCtx, route string) {
+	if tc := trackedConnOf(ctx); tc != nil {
+		atomic.AddInt64(&tc.requests, 1)
+		atomic.StoreInt64(&tc.reqStart, time.Now().UnixNano())
+		tc.target.Store(&connTarget{route: route})
+	}
+}
+
+// recordConnDone 记录连接上的请求处理完成，之后连接不再计为处理中，直到下一个请求
+func recordConnDone(ctx *fasthttp.RequestCtx) {
+	if tc := trackedConnOf(ctx); tc != nil {
+		atomic.StoreInt64(&tc.reqStart, 0)
+	}
+}
+
+// recordConnBackend 记录连接当前请求选中的上游和后端
+func recordConnBackend(ctx *fasthttp.RequestCtx, upstream, backend string) {
+	if tc := trackedConnOf(ctx); tc != nil {
+		route := ""
+		if target := tc.target.Load(); target != nil {
+			route = target.route
+		}
+		tc.target.Store(&connTarget{route: route, upstream: upstream, backend: backend})
+	}
+}
+
+// ConnInfo 连接信息
+type ConnInfo struct {
+	ID           uint64    `json:"id"`
+	ClientIP     string    `json:"client_ip"`
+	RemoteAddr   string    `json:"remote_addr"`
+	Route        string    `json:"route"`
+	Upstream     string    `json:"upstream"`
+	Backend      string    `json:"backend"`
+	AcceptedAt   time.Time `json:"accepted_at"`
+	Age          string    `json:"age"`
+	AgeSeconds   float64   `json:"age_seconds"`
+	IdleSeconds  float64   `json:"idle_seconds"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+	Requests     int64     `json:"requests"`
+	PendingBytes int64     `json:"pending_bytes"` // 当前响应尚未写出的字节数
+
+	InFlight       bool    `json:"in_flight"`                 // 是否有请求正在处理（包括等待后端响应）
+	RequestSeconds float64 `json:"request_seconds,omitempty"` // 正在处理的请求已持续的时间
+}
+
+// ConnFilter 连接列表过滤条件，零值字段不过滤
+type ConnFilter struct {
+	ClientIP string
+	Route    string
+	Upstream string
+	Backend  string
+	InFlight bool // 只返回有请求正在处理的连接
+	MinAge   time.Duration
+	MinBytes int64
+	Sort     string // age（默认，最老的在前）或bytes（收发字节数最多的在前）
+	Limit    int
+}
+
+// ConnList 连接列表
+type ConnList struct {
+	Total       int64      `json:"total"`
+	Matched     int        `json:"matched"`
+	Connections []ConnInfo `json:"connections"`
+}
+
+// List 按条件列出连接，最多返回filter.Limit条
+func (t *ConnTable) List(filter ConnFilter) ConnList {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultConnListLimit
+	}
+	if limit > MaxConnListLimit {
+		limit = MaxConnListLimit
+	}
+
+	now := time.Now()
+	matched := make([]ConnInfo, 0)
+	t.conns.Range(func(_, value interface{}) bool {
+		c := value.(*trackedConn)
+		info := c.info(now)
+
+		if filter.ClientIP != "" && info.ClientIP != filter.ClientIP {
+			return true
+		}
+		if filter.Route != "" && info.Route != filter.Route {
+			return true
+		}
+		if filter.Upstream != "" && info.Upstream != filter.Upstream {
+			return true
+		}
+		if filter.Backend != "" && info.Backend != filter.Backend {
+			return true
+		}
+		if filter.InFlight && !info.InFlight {
+			return true
+		}
+		if filter.MinAge > 0 && now.Sub(info.AcceptedAt) < filter.MinAge {
+			return true
+		}
+		if filter.MinBytes > 0 && info.BytesIn+info.BytesOut < filter.MinBytes {
+			return true
+		}
+
+		matched = append(matched, info)
+		return true
+	})
+
+	if filter.Sort == "bytes" {
+		sort.Slice(matched, func(i, j int) bool {
+			return matched[i].BytesIn+matched[i].BytesOut > matched[j].BytesIn+matched[j].BytesOut
+		})
+	} else {
+		sort.Slice(matched, func(i, j int) bool {
+			return matched[i].ID < matched[j].ID
+		})
+	}
+
+	list := ConnList{
+		Total:   atomic.LoadInt64(&t.count),
+		Matched: len(matched),
+	}
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	list.Connections = matched
+	return list
+}
+
+// info 生成连接信息快照
+func (c *trackedConn) info(now time.Time) ConnInfo {
+	info := ConnInfo{
+		ID:           c.id,
+		RemoteAddr:   c.RemoteAddr().String(),
+		AcceptedAt:   c.acceptedAt,
+		Age:          now.Sub(c.acceptedAt).Truncate(time.Second).String(),
+		AgeSeconds:   now.Sub(c.acceptedAt).Seconds(),
+		IdleSeconds:  now.Sub(time.Unix(0, atomic.LoadInt64(&c.lastActive))).Seconds(),
+		BytesIn:      atomic.LoadInt64(&c.bytesIn),
+		BytesOut:     atomic.LoadInt64(&c.bytesOut),
+		Requests:     atomic.LoadInt64(&c.requests),
+		PendingBytes: atomic.LoadInt64(&c.pending),
+	}
+	if host, _, err := net.SplitHostPort(info.RemoteAddr); err == nil {
+		info.ClientIP = host
+	}
+	if target := c.target.Load(); target != nil {
+		info.Route = target.route
+		info.Upstream = target.upstream
+		info.Backend = target.backend
+	}
+	if start := atomic.LoadInt64(&c.reqStart); start != 0 {
+		info.InFlight = true
+		info.RequestSeconds = now.Sub(time.Unix(0, start)).Seconds()
+	}
+	return info
+}
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// corsPolicy 预编译的跨域资源共享配置
+type corsPolicy struct {
+	anyOrigin     bool
+	origins       map[string]bool // 小写的完整来源
+	suffixes      []corsSuffix    // 子域名通配符
+	methods       string          // Access-Control-Allow-Methods
+	allowMethods  map[string]bool
+	headers       string // Access-Control-Allow-Headers，为空时回显预检请求的请求头
+	allowHeaders  map[string]bool
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// corsSuffix 子域名通配符 scheme://*.domain：scheme://加上以.domain结尾的主机
+type corsSuffix struct {
+	scheme string
+	suffix string
+}
+
+// newCORSPolicy 编译跨域配置，未配置时返回nil
+func newCORSPolicy(cfg *types.CORSConfig) *corsPolicy {
+	if cfg == nil {
+		return nil
+	}
+
+	p := &corsPolicy{
+		origins:       make(map[string]bool),
+		methods:       strings.Join(cfg.AllowMethods, ", "),
+		allowMethods:  make(map[string]bool),
+		headers:       strings.Join(cfg.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(cfg.ExposeHeaders, ", "),
+		credentials:   cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "://*")
+			p.suffixes = append(p.suffixes, corsSuffix{scheme: scheme + "://", suffix: domain})
+		default:
+			p.origins[origin] = true
+		}
+	}
+	for _, method := range cfg.AllowMethods {
+		p.allowMethods[strings.ToUpper(method)] = true
+	}
+	if len(cfg.AllowHeaders) > 0 {
+		p.allowHeaders = make(map[string]bool)
+		for _, name := range cfg.AllowHeaders {
+			p.allowHeaders[strings.ToLower(name)] = true
+		}
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// allowOrigin 来源是否允许
+func (p *corsPolicy) allowOrigin(origin []byte) bool {
+	if p.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(string(origin))
+	if p.origins[lower] {
+		return true
+	}
+	for _, s := range p.suffixes {
+		if host, ok := strings.CutPrefix(lower, s.scheme); ok && strings.HasSuffix(host, s.suffix) && len(host) > len(s.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowRequestHeaders 预检请求列出的请求头是否全部允许
+func (p *corsPolicy) allowRequestHeaders(requested []byte) bool {
+	if p.allowHeaders == nil {
+		return true
+	}
+	for _, name := range bytes.Split(requested, []byte(",")) {
+		name = bytes.TrimSpace(name)
+		if len(name) > 0 && !p.allowHeaders[strings.ToLower(string(name))] {
+			return false
+		}
+	}
+	return true
+}
+
+// isPreflight 是否为CORS预检请求：带Origin和Access-Control-Request-Method的OPTIONS请求
+func isPreflight(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsOptions() &&
+		len(vars.PeekHeader(&ctx.Request.Header, "Origin")) > 0 &&
+		len(vars.PeekHeader(&ctx.Request.Header, "Access-Control-Request-Method")) > 0
+}
+
+// servePreflight 直接响应预检请求，不转发到上游
+// 来源、方法或请求头不允许时返回403，浏览器随后拒绝实际请求
+func (p *corsPolicy) servePreflight(ctx *fasthttp.RequestCtx) {
+	header := &ctx.Request.Header
+	origin := vars.PeekHeader(header, "Origin")
+	method := strings.ToUpper(string(vars.PeekHeader(header, "Access-Control-Request-Method")))
+	requested := vars.PeekHeader(header, "Access-Control-Request-Headers")
+
+	resp := &ctx.Response.Header
+	resp.Set("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	if !p.allowOrigin(origin) || !p.allowMethods[method] || !p.allowRequestHeaders(requested) {
+		ctx.Error("Forbidden (CORS preflight rejected)", fasthttp.StatusForbidden)
+		return
+	}
+
+	p.setOrigin(resp, origin)
+	resp.Set("Access-Control-Allow-Methods", p.methods)
+	if p.headers != "" {
+		resp.Set("Access-Control-Allow-Headers", p.headers)
+	} else if len(requested) > 0 {
+		resp.SetBytesV("Access-Control-Allow-Headers", requested)
+	}
+	if p.maxAge != "" {
+		resp.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// apply 为实际请求的响应设置CORS响应头，替换上游返回的Access-Control-*头
+// 包括代理自身返回的错误响应，浏览器脚本可以读取401、429等状态
+func (p *corsPolicy) apply(ctx *fasthttp.RequestCtx) {
+	resp := &ctx.Response.Header
+	for _, name := range corsResponseHeaders {
+		vars.DelResponseHeader(resp, name)
+	}
+
+	origin := vars.PeekHeader(&ctx.Request.Header, "Origin")
+	if !p.anyOrigin || p.credentials {
+		resp.Add("Vary", "Origin")
+	}
+	if len(origin) == 0 || !p.allowOrigin(origin) {
+		return
+	}
+	p.setOrigin(resp, origin)
+	if p.exposeHeaders != "" {
+		resp.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+	}
+}
+
+// setOrigin 设置允许的来源：任意来源且不携带凭据时为*，否则回显请求的来源
+func (p *corsPolicy) setOrigin(resp *fasthttp.ResponseHeader, origin []byte) {
+	if p.anyOrigin && !p.credentials {
+		resp.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		resp.SetBytesV("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		resp.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// corsResponseHeaders 由代理统一设置的CORS响应头
+var corsResponseHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+}
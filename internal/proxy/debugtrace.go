@@ -0,0 +1,455 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// userValueTrace 请求的调试跟踪记录
+const userValueTrace = "speedmimi.trace"
+
+// 调试跟踪的限制
+const (
+	defaultTraceDuration    = time.Minute
+	maxTraceDuration        = time.Hour
+	defaultTraceRequests    = 100
+	maxTraceRequests        = 10000
+	maxTraceSessions        = 16 // 同时进行的跟踪数上限
+	traceHistorySize        = 16 // 保留的已结束跟踪数
+	traceHeaderValueRedact  = "[redacted]"
+	traceStopReasonExpired  = "expired"
+	traceStopReasonLimit    = "max_requests"
+	traceStopReasonManually = "stopped"
+)
+
+// ErrTraceNotFound 跟踪不存在或已结束
+var ErrTraceNotFound = errors.New("trace session not found")
+
+// traceRedactedHeaders 跟踪日志中只记录长度的请求头和响应头（凭据）
+var traceRedactedHeaders = []string{
+	fasthttp.HeaderAuthorization,
+	fasthttp.HeaderProxyAuthorization,
+	fasthttp.HeaderCookie,
+	fasthttp.HeaderSetCookie,
+	"X-API-Key",
+}
+
+// TraceRequest 开启调试跟踪的参数，route和backend至少指定一个，都指定时两者都要匹配
+type TraceRequest struct {
+	Route       string
+	Backend     string
+	Duration    time.Duration // 默认1m，最长1h
+	MaxRequests int           // 默认100，最多10000
+}
+
+// TraceSessionInfo 调试跟踪的状态
+type TraceSessionInfo struct {
+	ID          string     `json:"id"`
+	Route       string     `json:"route,omitempty"`
+	Backend     string     `json:"backend,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	MaxRequests int64      `json:"max_requests"`
+	Traced      int64      `json:"traced"` // 已写入跟踪日志的请求数
+	Active      bool       `json:"active"`
+	StoppedAt   *time.Time `json:"stopped_at,omitempty"`
+	StopReason  string     `json:"stop_reason,omitempty"` // expired、max_requests或stopped
+}
+
+// TraceStats 调试跟踪日志和各跟踪的状态
+type TraceStats struct {
+	Path     string             `json:"path"`
+	Sessions []TraceSessionInfo `json:"sessions"` // 进行中的跟踪和最近结束的跟踪
+}
+
+// traceSession 一个限时调试跟踪
+type traceSession struct {
+	id          string
+	route       string
+	backend     string
+	startedAt   time.Time
+	expiresAt   time.Time
+	maxRequests int64
+	traced      atomic.Int64
+	timer       *time.Timer
+}
+
+// requestTrace 一个请求的跟踪记录，只在处理请求的协程中访问
+type requestTrace struct {
+	sessions []*traceSession // 路由匹配的跟踪，请求结束时再按后端过滤
+	start    time.Time
+	buf      bytes.Buffer
+}
+
+// Tracer 按路由或后端的限时调试跟踪，把匹配请求的完整请求头、各阶段耗时和负载均衡决策写入单独的日志
+type Tracer struct {
+	path func() string // 当前配置的跟踪日志路径
+
+	active atomic.Int32 // 进行中的跟踪数，为0时请求不做任何跟踪相关的工作
+
+	mu       sync.Mutex
+	sessions []*traceSession
+	history  []TraceSessionInfo
+
+	fileMu   sync.Mutex
+	file     *os.File
+	filePath string
+}
+
+// NewTracer 创建调试跟踪，path返回当前配置的日志路径
+func NewTracer(path func() string) *Tracer {
+	return &Tracer{path: path}
+}
+
+// Start 开启一个调试跟踪，到达时长或请求数上限后自动关闭
+func (t *Tracer) Start(req TraceRequest) (*TraceSessionInfo, error) {
+	if req.Route == "" && req.Backend == "" {
+		return nil, fmt.Errorf("route or backend is required")
+	}
+	if req.Duration == 0 {
+		req.Duration = defaultTraceDuration
+	}
+	if req.Duration < 0 || req.Duration > maxTraceDuration {
+		return nil, fmt.Errorf("duration must be positive and at most %s", maxTraceDuration)
+	}
+	if req.MaxRequests == 0 {
+		req.MaxRequests = defaultTraceRequests
+	}
+	if req.MaxRequests < 0 || req.MaxRequests > maxTraceRequests {
+		return nil, fmt.Errorf("max_requests must be between 1 and %d", maxTraceRequests)
+	}
+	if err := t.openLog(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 4)
+	rand.Read(id)
+	now := time.Now()
+	session := &traceSession{
+		id:          hex.EncodeToString(id),
+		route:       req.Route,
+		backend:     req.Backend,
+		startedAt:   now,
+		expiresAt:   now.Add(req.Duration),
+		maxRequests: int64(req.MaxRequests),
+	}
+
+	t.mu.Lock()
+	if len(t.sessions) >= maxTraceSessions {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("too many active trace sessions (max %d)", maxTraceSessions)
+	}
+	t.sessions = append(t.sessions, session)
+	t.active.Store(int32(len(t.sessions)))
+	session.timer = time.AfterFunc(req.Duration, func() { t.stop(session, traceStopReasonExpired) })
+	t.mu.Unlock()
+
+	t.writeLine(fmt.Sprintf("=== trace %s started: route=%q backend=%q duration=%s max_requests=%d",
+		session.id, session.route, session.backend, req.Duration, session.maxRequests))
+	fmt.Printf("[TRACE] Started trace %s (route=%q backend=%q) for %s or %d requests, writing to %s\n",
+		session.id, session.route, session.backend, req.Duration, session.maxRequests, t.path())
+	info := session.info(true, nil, "")
+	return &info, nil
+}
+
+// Stop 手动结束一个调试跟踪
+func (t *Tracer) Stop(id string) error {
+	t.mu.Lock()
+	var session *traceSession
+	for _, s := range t.sessions {
+		if s.id == id {
+			session = s
+		}
+	}
+	t.mu.Unlock()
+	if session == nil || !t.stop(session, traceStopReasonManually) {
+		return ErrTraceNotFound
+	}
+	return nil
+}
+
+// stop 结束跟踪并移入历史记录，跟踪已结束时返回false
+func (t *Tracer) stop(session *traceSession, reason string) bool {
+	t.mu.Lock()
+	index := -1
+	for i, s := range t.sessions {
+		if s == session {
+			index = i
+		}
+	}
+	if index < 0 {
+		t.mu.Unlock()
+		return false
+	}
+	t.sessions = append(t.sessions[:index:index], t.sessions[index+1:]...)
+	t.active.Store(int32(len(t.sessions)))
+	session.timer.Stop()
+
+	now := time.Now()
+	t.history = append(t.history, session.info(false, &now, reason))
+	if len(t.history) > traceHistorySize {
+		t.history = t.history[len(t.history)-traceHistorySize:]
+	}
+	t.mu.Unlock()
+
+	t.writeLine(fmt.Sprintf("=== trace %s stopped (%s) after %d requests", session.id, reason, min(session.traced.Load(), session.maxRequests)))
+	fmt.Printf("[TRACE] Stopped trace %s (%s)\n", session.id, reason)
+	return true
+}
+
+// Stats 获取跟踪日志路径、进行中的跟踪和最近结束的跟踪
+func (t *Tracer) Stats() TraceStats {
+	stats := TraceStats{Path: t.path(), Sessions: []TraceSessionInfo{}}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, session := range t.sessions {
+		stats.Sessions = append(stats.Sessions, session.info(true, nil, ""))
+	}
+	stats.Sessions = append(stats.Sessions, t.history...)
+	return stats
+}
+
+// info 跟踪的状态
+func (s *traceSession) info(active bool, stoppedAt *time.Time, reason string) TraceSessionInfo {
+	return TraceSessionInfo{
+		ID:          s.id,
+		Route:       s.route,
+		Backend:     s.backend,
+		StartedAt:   s.startedAt,
+		ExpiresAt:   s.expiresAt,
+		MaxRequests: s.maxRequests,
+		Traced:      min(s.traced.Load(), s.maxRequests),
+		Active:      active,
+		StoppedAt:   stoppedAt,
+		StopReason:  reason,
+	}
+}
+
+// begin 路由匹配后开始跟踪请求，没有跟踪匹配该路由时不做任何事
+func (t *Tracer) begin(ctx *fasthttp.RequestCtx, route string) {
+	if t.active.Load() == 0 {
+		return
+	}
+
+	var matched []*traceSession
+	t.mu.Lock()
+	for _, session := range t.sessions {
+		if session.route == "" || session.route == route {
+			matched = append(matched, session)
+		}
+	}
+	t.mu.Unlock()
+	if len(matched) == 0 {
+		return
+	}
+
+	tr := &requestTrace{sessions: matched, start: ctx.Time()}
+	fmt.Fprintf(&tr.buf, "%s %s %s from %s conn=%d\n", ctx.Method(), ctx.RequestURI(), ctx.Request.Header.Protocol(), ctx.RemoteAddr(), ctx.ConnID())
+	tr.event("route %s matched", route)
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		tr.header('>', key, value)
+	})
+	ctx.SetUserValue(userValueTrace, tr)
+}
+
+// finish 请求结束时把跟踪记录写入日志
+// 后端在选择后才确定，此时才按跟踪的backend过滤；每个跟踪最多写入max_requests个请求，达到上限后自动结束
+func (t *Tracer) finish(ctx *fasthttp.RequestCtx) {
+	tr := traceOf(ctx)
+	if tr == nil {
+		return
+	}
+
+	backend, _ := ctx.UserValue(userValueBackend).(string)
+	var ids []string
+	for _, session := range tr.sessions {
+		if session.backend != "" && session.backend != backend {
+			continue
+		}
+		n := session.traced.Add(1)
+		if n > session.maxRequests {
+			continue
+		}
+		ids = append(ids, session.id)
+		if n == session.maxRequests {
+			defer t.stop(session, traceStopReasonLimit)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	tr.event("response %d, %d body bytes", ctx.Response.StatusCode(), vars.ResponseBodySize(&ctx.Response))
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		tr.header('<', key, value)
+	})
+	t.writeLine(fmt.Sprintf("--- trace %s %s total=%s %s", strings.Join(ids, ","), tr.start.Format(time.RFC3339Nano),
+		time.Since(tr.start).Round(time.Microsecond), strings.TrimSuffix(tr.buf.String(), "\n")))
+}
+
+// traceOf 获取请求的跟踪记录，请求未被跟踪时返回nil
+func traceOf(ctx *fasthttp.RequestCtx) *requestTrace {
+	tr, _ := ctx.UserValue(userValueTrace).(*requestTrace)
+	return tr
+}
+
+// traceOfRequest 获取负载均衡请求信息对应的跟踪记录
+func traceOfRequest(req types.RequestContext) *requestTrace {
+	if rc, ok := req.(*requestContext); ok {
+		return rc.trace
+	}
+	return nil
+}
+
+// event 记录一个带相对时间的事件，tr为nil时不做任何事
+func (tr *requestTrace) event(format string, args ...interface{}) {
+	if tr == nil {
+		return
+	}
+	fmt.Fprintf(&tr.buf, "  +%s ", time.Since(tr.start).Round(time.Microsecond))
+	fmt.Fprintf(&tr.buf, format, args...)
+	tr.buf.WriteByte('\n')
+}
+
+// header 记录一个请求头（>）或响应头（<），凭据只记录长度
+func (tr *requestTrace) header(direction byte, key, value []byte) {
+	tr.buf.WriteString("  ")
+	tr.buf.WriteByte(direction)
+	tr.buf.WriteByte(' ')
+	tr.buf.Write(key)
+	tr.buf.WriteString(": ")
+	redact := false
+	for _, name := range traceRedactedHeaders {
+		if strings.EqualFold(string(key), name) {
+			redact = true
+		}
+	}
+	if redact {
+		fmt.Fprintf(&tr.buf, "%s (%d bytes)", traceHeaderValueRedact, len(value))
+	} else {
+		tr.buf.Write(value)
+	}
+	tr.buf.WriteByte('\n')
+}
+
+// upstreamRequest 记录转发给后端的请求头（代理设置的请求头已加入）
+func (tr *requestTrace) upstreamRequest(backend *types.Backend, req *fasthttp.Request) {
+	if tr == nil {
+		return
+	}
+	tr.event("forwarding to backend %s (%s)", backend.ID, poolKey(backend))
+	req.Header.VisitAll(func(key, value []byte) {
+		tr.header('>', key, value)
+	})
+}
+
+// upstreamResult 记录一次转发尝试的结果
+func (tr *requestTrace) upstreamResult(backend *types.Backend, resp *fasthttp.Response, err error, elapsed time.Duration) {
+	if err != nil {
+		tr.event("backend %s failed after %s: %v", backend.ID, elapsed.Round(time.Microsecond), err)
+		return
+	}
+	tr.event("backend %s responded %d after %s", backend.ID, resp.StatusCode(), elapsed.Round(time.Microsecond))
+}
+
+// effectiveLBType 路由覆盖或上游配置的负载均衡类型
+func effectiveLBType(upstream *Upstream, lbType types.LoadBalancerType) types.LoadBalancerType {
+	if lbType == "" {
+		return upstream.lbType
+	}
+	return lbType
+}
+
+// countBackends 各优先级组的后端总数
+func countBackends(groups [][]*types.Backend) int {
+	n := 0
+	for _, group := range groups {
+		n += len(group)
+	}
+	return n
+}
+
+// openLog 打开跟踪日志文件，配置的路径变化时重新打开
+func (t *Tracer) openLog() error {
+	path := t.path()
+	t.fileMu.Lock()
+	defer t.fileMu.Unlock()
+	if t.file != nil && t.filePath == path {
+		return nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create debug trace log directory: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open debug trace log: %w", err)
+	}
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.file, t.filePath = file, path
+	return nil
+}
+
+// writeLine 写入一条跟踪日志
+func (t *Tracer) writeLine(line string) {
+	t.fileMu.Lock()
+	defer t.fileMu.Unlock()
+	if t.file != nil {
+		t.file.WriteString(line + "\n")
+	}
+}
+
+// Close 关闭跟踪日志文件
+func (t *Tracer) Close() {
+	t.fileMu.Lock()
+	defer t.fileMu.Unlock()
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// StartTrace 开启一个调试跟踪
+func (s *Server) StartTrace(req TraceRequest) (*TraceSessionInfo, error) {
+	return s.tracer.Start(req)
+}
+
+// StopTrace 结束一个调试跟踪
+func (s *Server) StopTrace(id string) error {
+	return s.tracer.Stop(id)
+}
+
+// TraceStats 获取调试跟踪的状态
+func (s *Server) TraceStats() TraceStats {
+	return s.tracer.Stats()
+}
+
+// HasBackend 是否有上游包含该后端
+func (s *Server) HasBackend(id string) bool {
+	for _, upstream := range s.upstreamMgr.Load().Upstreams() {
+		for _, backend := range upstream.GetAllBackends() {
+			if backend.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
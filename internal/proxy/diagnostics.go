@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 建立到后端的TCP连接和完成TLS握手的超时时间
+const (
+	backendDialTimeout      = 3 * time.Second
+	backendHandshakeTimeout = 10 * time.Second
+)
+
+// poolStats 连接池运行时学习到的拨号和复用情况
+type poolStats struct {
+	requests       atomic.Int64 // 通过连接池发送的请求数
+	dials          atomic.Int64 // 拨号次数（包括失败）
+	dialErrors     atomic.Int64
+	connects       atomic.Int64 // 成功建立的TCP连接数
+	connectNanos   atomic.Int64
+	handshakes     atomic.Int64 // 成功完成的TLS握手数
+	handshakeNanos atomic.Int64
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+	tlsVersion  string
+	alpn        string
+}
+
+// BackendDiagnostics 后端的运行时诊断信息，用于排查后端变慢的原因
+type BackendDiagnostics struct {
+	Address          string         `json:"address"`
+	Protocol         ProtocolStatus `json:"protocol"`                         // 上游协议状态，与GET /api/v1/backends相同
+	TLSVersion       string         `json:"tls_version,omitempty"`            // 最近一次TLS握手协商的版本
+	ALPN             string         `json:"alpn,omitempty"`                   // 最近一次TLS握手协商的应用层协议
+	PoolCreatedAt    *time.Time     `json:"pool_created_at,omitempty"`        // 连接池创建（或最近一次回收）的时间，以下计数从此时开始
+	OpenConns        int            `json:"open_conns"`                       // 连接池当前的连接数
+	Requests         int64          `json:"requests"`                         // 通过HTTP/1.1连接池发送的请求数
+	Dials            int64          `json:"dials"`                            // 拨号次数（包括失败）
+	DialErrors       int64          `json:"dial_errors"`                      // 连接或TLS握手失败次数
+	ReuseRate        float64        `json:"reuse_rate"`                       // 使用已有连接的请求比例
+	AvgConnectTime   string         `json:"avg_connect_time,omitempty"`       // 平均TCP连接耗时
+	AvgHandshakeTime string         `json:"avg_tls_handshake_time,omitempty"` // 平均TLS握手耗时
+	LastDialError    string         `json:"last_dial_error,omitempty"`
+	LastDialErrorAt  *time.Time     `json:"last_dial_error_at,omitempty"`
+}
+
+// dial 建立到后端的连接并记录耗时，tlsConfig不为nil时同时完成TLS握手
+func (ps *poolStats) dial(dialer *fasthttp.TCPDialer, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	ps.dials.Add(1)
+
+	start := time.Now()
+	conn, err := dialer.DialDualStackTimeout(addr, backendDialTimeout)
+	if err != nil {
+		ps.dialFailed(err)
+		return nil, &dialError{err: err}
+	}
+	ps.connects.Add(1)
+	ps.connectNanos.Add(int64(time.Since(start)))
+	if tlsConfig == nil {
+		return conn, nil
+	}
+
+	start = time.Now()
+	tlsConn := tls.Client(conn, tlsConfig)
+	tlsConn.SetDeadline(start.Add(backendHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		ps.dialFailed(err)
+		return nil, &dialError{err: err}
+	}
+	tlsConn.SetDeadline(time.Time{})
+	ps.handshakes.Add(1)
+	ps.handshakeNanos.Add(int64(time.Since(start)))
+
+	state := tlsConn.ConnectionState()
+	ps.mu.Lock()
+	ps.tlsVersion = tls.VersionName(state.Version)
+	ps.alpn = state.NegotiatedProtocol
+	ps.mu.Unlock()
+	return tlsConn, nil
+}
+
+// dialFailed 记录拨号失败
+func (ps *poolStats) dialFailed(err error) {
+	ps.dialErrors.Add(1)
+	ps.mu.Lock()
+	ps.lastError = err.Error()
+	ps.lastErrorAt = time.Now()
+	ps.mu.Unlock()
+}
+
+// fill 把连接池的统计填入诊断信息
+func (pc *pooledClient) fill(d *BackendDiagnostics) {
+	ps := pc.stats
+	createdAt := pc.createdAt
+	d.PoolCreatedAt = &createdAt
+	d.OpenConns = pc.client.ConnsCount() + pc.stream.ConnsCount()
+	d.Requests = ps.requests.Load()
+	d.Dials = ps.dials.Load()
+	d.DialErrors = ps.dialErrors.Load()
+
+	// 每个新建立的连接至少承载一个请求，其余请求复用了已有连接
+	established := d.Dials - d.DialErrors
+	if d.Requests > 0 && established < d.Requests {
+		d.ReuseRate = float64(d.Requests-established) / float64(d.Requests)
+	}
+	if n := ps.connects.Load(); n > 0 {
+		d.AvgConnectTime = averageDuration(ps.connectNanos.Load(), n)
+	}
+	if n := ps.handshakes.Load(); n > 0 {
+		d.AvgHandshakeTime = averageDuration(ps.handshakeNanos.Load(), n)
+	}
+
+	ps.mu.Lock()
+	d.TLSVersion = ps.tlsVersion
+	d.ALPN = ps.alpn
+	d.LastDialError = ps.lastError
+	if !ps.lastErrorAt.IsZero() {
+		lastErrorAt := ps.lastErrorAt
+		d.LastDialErrorAt = &lastErrorAt
+	}
+	ps.mu.Unlock()
+}
+
+// averageDuration 平均耗时，精确到微秒
+func averageDuration(totalNanos, n int64) string {
+	return time.Duration(totalNanos / n).Round(time.Microsecond).String()
+}
+
+// BackendDiagnostics 获取上游各后端的运行时诊断信息，key为后端ID
+// 尚未建立连接池的后端只有地址和协议状态
+func (s *Server) BackendDiagnostics(upstreamID string) (map[string]BackendDiagnostics, error) {
+	upstream := s.upstreamMgr.Load().GetUpstream(upstreamID)
+	if upstream == nil {
+		return nil, fmt.Errorf("upstream %s not found", upstreamID)
+	}
+
+	chain := upstream.protocolChain()
+	result := make(map[string]BackendDiagnostics)
+	for _, backend := range upstream.GetAllBackends() {
+		d := BackendDiagnostics{
+			Address:  poolKey(backend),
+			Protocol: s.protocolStatus(backend, chain),
+		}
+		if pc := s.clients.lookup(backend); pc != nil {
+			pc.fill(&d)
+		}
+		result[backend.ID] = d
+	}
+	return result, nil
+}
@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// backendDrainPollInterval 排空期间检查后端剩余连接数的间隔
+const backendDrainPollInterval = 100 * time.Millisecond
+
+// 后端排空状态
+const (
+	DrainStateDraining  = "draining"  // 不再分配新请求，等待已有连接处理完成
+	DrainStateDrained   = "drained"   // 连接数降为0，后端已停用
+	DrainStateTimedOut  = "timed_out" // 超时时仍有连接，后端已停用
+	DrainStateCancelled = "cancelled" // 排空被取消，后端恢复接收请求
+)
+
+// DrainStatus 后端排空进度
+type DrainStatus struct {
+	Upstream             string     `json:"upstream"`
+	BackendID            string     `json:"backend_id"`
+	State                string     `json:"state"`
+	InitialConnections   int64      `json:"initial_connections"`   // 开始排空时的连接数
+	RemainingConnections int64      `json:"remaining_connections"` // 当前（排空结束时）的连接数
+	StartedAt            time.Time  `json:"started_at"`
+	FinishedAt           *time.Time `json:"finished_at,omitempty"`
+	Elapsed              string     `json:"elapsed"`
+	Timeout              string     `json:"timeout"`
+}
+
+// backendDrain 单个后端的排空过程
+type backendDrain struct {
+	status  DrainStatus
+	backend *types.Backend
+	timeout time.Duration
+	stop    chan struct{} // 取消排空时关闭
+}
+
+// BackendDrains 后端排空管理器
+// 排空时后端被标记断开不再分配新请求，连接数降为0或超时后停用后端并清除断开标记，
+// 之后可以通过更新后端重新启用
+type BackendDrains struct {
+	mu     sync.Mutex
+	drains map[string]*backendDrain // upstream/backendID -> 最近一次排空
+}
+
+// NewBackendDrains 创建后端排空管理器
+func NewBackendDrains() *BackendDrains {
+	return &BackendDrains{
+		drains: make(map[string]*backendDrain),
+	}
+}
+
+func drainKey(upstream, backendID string) string {
+	return upstream + "/" + backendID
+}
+
+// Start 开始排空后端；后端正在排空时返回当前进度，不重新计时
+func (d *BackendDrains) Start(upstream string, backend *types.Backend, timeout time.Duration) DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := drainKey(upstream, backend.ID)
+	if existing, ok := d.drains[key]; ok && existing.status.State == DrainStateDraining {
+		if existing.backend == backend {
+			return existing.snapshot()
+		}
+		// 配置重新加载后后端对象已替换，停止等待旧对象
+		close(existing.stop)
+	}
+
+	backend.MarkForDisconnect()
+	drain := &backendDrain{
+		status: DrainStatus{
+			Upstream:           upstream,
+			BackendID:          backend.ID,
+			State:              DrainStateDraining,
+			InitialConnections: backend.GetConnections(),
+			StartedAt:          time.Now(),
+			Timeout:            timeout.String(),
+		},
+		backend: backend,
+		timeout: timeout,
+		stop:    make(chan struct{}),
+	}
+	d.drains[key] = drain
+	go d.run(drain)
+
+	fmt.Printf("[DRAIN] Backend %s draining (%d connections, timeout %s)\n", key, drain.status.InitialConnections, timeout)
+	return drain.snapshot()
+}
+
+// run 等待后端连接数降为0或超时，然后停用后端
+func (d *BackendDrains) run(drain *backendDrain) {
+	ticker := time.NewTicker(backendDrainPollInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(drain.timeout)
+	defer deadline.Stop()
+
+	state := DrainStateDrained
+wait:
+	for drain.backend.GetConnections() > 0 {
+		select {
+		case <-drain.stop:
+			return
+		case <-deadline.C:
+			state = DrainStateTimedOut
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-drain.stop:
+		return
+	default:
+	}
+	drain.backend.SetActive(false)
+	drain.backend.ClearDisconnectMark()
+	drain.finish(state)
+	fmt.Printf("[DRAIN] Backend %s %s after %s (%d connections remaining)\n",
+		drainKey(drain.status.Upstream, drain.status.BackendID), state, drain.status.Elapsed, drain.status.RemainingConnections)
+}
+
+// Cancel 取消正在进行的排空，后端恢复接收新请求
+func (d *BackendDrains) Cancel(upstream, backendID string) (DrainStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drain, ok := d.drains[drainKey(upstream, backendID)]
+	if !ok || drain.status.State != DrainStateDraining {
+		return DrainStatus{}, fmt.Errorf("backend %s/%s is not draining", upstream, backendID)
+	}
+	close(drain.stop)
+	drain.backend.ClearDisconnectMark()
+	drain.finish(DrainStateCancelled)
+	fmt.Printf("[DRAIN] Backend %s/%s drain cancelled\n", upstream, backendID)
+	return drain.snapshot(), nil
+}
+
+// Status 获取后端最近一次排空的进度
+func (d *BackendDrains) Status(upstream, backendID string) (DrainStatus, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drain, ok := d.drains[drainKey(upstream, backendID)]
+	if !ok {
+		return DrainStatus{}, false
+	}
+	return drain.snapshot(), true
+}
+
+// List 获取所有后端最近一次排空的进度，按上游和后端ID排序
+func (d *BackendDrains) List() []DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]DrainStatus, 0, len(d.drains))
+	for _, drain := range d.drains {
+		statuses = append(statuses, drain.snapshot())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Upstream != statuses[j].Upstream {
+			return statuses[i].Upstream < statuses[j].Upstream
+		}
+		return statuses[i].BackendID < statuses[j].BackendID
+	})
+	return statuses
+}
+
+// finish 记录排空结束时的状态，调用方持有锁
+func (drain *backendDrain) finish(state string) {
+	now := time.Now()
+	drain.status.State = state
+	drain.status.FinishedAt = &now
+	drain.status.RemainingConnections = drain.backend.GetConnections()
+	drain.status.Elapsed = now.Sub(drain.status.StartedAt).Round(time.Millisecond).String()
+}
+
+// snapshot 获取进度的副本，排空进行中时使用当前连接数和用时，调用方持有锁
+func (drain *backendDrain) snapshot() DrainStatus {
+	status := drain.status
+	if status.State == DrainStateDraining {
+		status.RemainingConnections = drain.backend.GetConnections()
+		status.Elapsed = time.Since(status.StartedAt).Round(time.Millisecond).String()
+	}
+	return status
+}
+
+// DrainBackend 排空上游中的后端：不再分配新请求，等待已有连接处理完成（最长timeout）后停用
+func (s *Server) DrainBackend(upstreamID, backendID string, timeout time.Duration) (DrainStatus, error) {
+	upstream := s.upstreamMgr.Load().GetUpstream(upstreamID)
+	if upstream == nil {
+		return DrainStatus{}, fmt.Errorf("upstream %s not found", upstreamID)
+	}
+
+	for _, backend := range upstream.GetAllBackends() {
+		if backend.ID == backendID {
+			if !backend.ShouldDisconnect() {
+				s.notifyDisconnect(upstreamID, backend, "drain requested")
+			}
+			return s.drains.Start(upstreamID, backend, timeout), nil
+		}
+	}
+	return DrainStatus{}, fmt.Errorf("backend %s not found in upstream %s", backendID, upstreamID)
+}
+
+// GetBackendDrains 获取后端排空管理器
+func (s *Server) GetBackendDrains() *BackendDrains {
+	return s.drains
+}
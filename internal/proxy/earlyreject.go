@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// earlyRejectWriteTimeout 写拒绝响应的超时，避免不读取响应的客户端占用连接
+const earlyRejectWriteTimeout = time.Second
+
+// EarlyRejectStats 请求行预检统计
+type EarlyRejectStats struct {
+	Enabled            bool  `json:"enabled"`
+	MaxRequestLine     int   `json:"max_request_line"`
+	Checked            int64 `json:"checked"`               // 检查过的连接数
+	RequestLineTooLong int64 `json:"request_line_too_long"` // 414
+	BadRequestLine     int64 `json:"bad_request_line"`      // 400
+	MethodNotAllowed   int64 `json:"method_not_allowed"`    // 501
+	UnsupportedVersion int64 `json:"unsupported_version"`   // 505
+}
+
+// earlyRejectSettings 请求行预检设置
+type earlyRejectSettings struct {
+	maxLine int
+	methods map[string]bool
+}
+
+// EarlyRejectGuard 请求行预检：在fasthttp解析请求头之前检查连接上第一个请求的请求行，
+// 过长或格式明显错误时直接返回错误响应并关闭连接，减少攻击流量消耗的解析开销
+type EarlyRejectGuard struct {
+	settings atomic.Pointer[earlyRejectSettings] // 未启用时为nil
+
+	checked            atomic.Int64
+	requestLineTooLong atomic.Int64
+	badRequestLine     atomic.Int64
+	methodNotAllowed   atomic.Int64
+	unsupportedVersion atomic.Int64
+}
+
+// NewEarlyRejectGuard 创建请求行预检
+func NewEarlyRejectGuard() *EarlyRejectGuard {
+	return &EarlyRejectGuard{}
+}
+
+// Update 按配置更新预检设置，只影响之后接收的连接
+func (g *EarlyRejectGuard) Update(cfg *types.EarlyRejectConfig) {
+	if !cfg.Enabled {
+		g.settings.Store(nil)
+		return
+	}
+
+	settings := &earlyRejectSettings{
+		maxLine: cfg.MaxRequestLine,
+		methods: make(map[string]bool, len(cfg.Methods)),
+	}
+	for _, method := range cfg.Methods {
+		settings.methods[method] = true
+	}
+	g.settings.Store(settings)
+}
+
+// Stats 获取预检统计
+func (g *EarlyRejectGuard) Stats() EarlyRejectStats {
+	stats := EarlyRejectStats{
+		Checked:            g.checked.Load(),
+		RequestLineTooLong: g.requestLineTooLong.Load(),
+		BadRequestLine:     g.badRequestLine.Load(),
+		MethodNotAllowed:   g.methodNotAllowed.Load(),
+		UnsupportedVersion: g.unsupportedVersion.Load(),
+	}
+	if settings := g.settings.Load(); settings != nil {
+		stats.Enabled = true
+		stats.MaxRequestLine = settings.maxLine
+	}
+	return stats
+}
+
+// wrap 为连接加上预检，未启用时返回原连接
+func (g *EarlyRejectGuard) wrap(conn net.Conn) net.Conn {
+	settings := g.settings.Load()
+	if settings == nil {
+		return conn
+	}
+
+	gc := &guardedConn{Conn: conn, guard: g, settings: settings}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// fasthttp通过Handshake/ConnectionState判断TLS连接
+		return &guardedTLSConn{guardedConn: gc, tls: tlsConn}
+	}
+	return gc
+}
+
+// guardedListener 为接收的连接加上请求行预检
+// TLS连接在解密之后检查，因此包装在tls.Listener外层
+type guardedListener struct {
+	net.Listener
+	guard *EarlyRejectGuard
+}
+
+func (l *guardedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.guard.wrap(conn), nil
+}
+
+// guardedConn 第一次读取时先读入完整的请求行并检查，通过后再交给fasthttp
+// 只检查连接上的第一个请求，之后直接读取底层连接
+type guardedConn struct {
+	net.Conn
+	guard    *EarlyRejectGuard
+	settings *earlyRejectSettings
+	checked  bool
+	pending  []byte // 检查时读入、尚未交给调用方的数据
+}
+
+// guardedTLSConn 包装TLS连接的guardedConn
+type guardedTLSConn struct {
+	*guardedConn
+	tls *tls.Conn
+}
+
+func (c *guardedTLSConn) Handshake() error {
+	return c.tls.Handshake()
+}
+
+func (c *guardedTLSConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+func (c *guardedConn) Read(b []byte) (int, error) {
+	if !c.checked {
+		c.checked = true
+		if err := c.check(); err != nil {
+			return 0, err
+		}
+	}
+	if len(c.pending) > 0 {
+		n := copy(b, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// check 读取请求行并检查，不通过时写入错误响应并返回io.EOF，fasthttp随后关闭连接
+func (c *guardedConn) check() error {
+	c.guard.checked.Add(1)
+
+	buf := make([]byte, 0, 512)
+	chunk := make([]byte, 512)
+	for {
+		n, err := c.Conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+
+		// 忽略请求行之前的空行
+		line := bytes.TrimLeft(buf, "\r\n")
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			c.pending = buf
+			return c.checkRequestLine(bytes.TrimSuffix(line[:end], []byte("\r")))
+		}
+		if len(line) > c.settings.maxLine {
+			c.guard.requestLineTooLong.Add(1)
+			return c.reject(414, "URI Too Long")
+		}
+		if err != nil {
+			// 连接在完整的请求行之前关闭或超时，把已读数据交给fasthttp按原有逻辑处理
+			c.pending = buf
+			if len(buf) > 0 {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// checkRequestLine 检查请求行：方法 SP 请求目标 SP HTTP/1.x
+func (c *guardedConn) checkRequestLine(line []byte) error {
+	if len(line) > c.settings.maxLine {
+		c.guard.requestLineTooLong.Add(1)
+		return c.reject(414, "URI Too Long")
+	}
+
+	method, rest, ok := bytes.Cut(line, []byte(" "))
+	if !ok || len(method) == 0 || !isToken(method) {
+		c.guard.badRequestLine.Add(1)
+		return c.reject(400, "Bad Request")
+	}
+	target, version, ok := bytes.Cut(rest, []byte(" "))
+	if !ok || len(target) == 0 || bytes.IndexFunc(target, isCTLOrSpace) >= 0 {
+		c.guard.badRequestLine.Add(1)
+		return c.reject(400, "Bad Request")
+	}
+
+	switch {
+	case bytes.Equal(version, []byte("HTTP/1.1")) || bytes.Equal(version, []byte("HTTP/1.0")):
+	case bytes.HasPrefix(version, []byte("HTTP/")):
+		c.guard.unsupportedVersion.Add(1)
+		return c.reject(505, "HTTP Version Not Supported")
+	default:
+		c.guard.badRequestLine.Add(1)
+		return c.reject(400, "Bad Request")
+	}
+
+	if !c.settings.methods[string(method)] {
+		c.guard.methodNotAllowed.Add(1)
+		return c.reject(501, "Not Implemented")
+	}
+	return nil
+}
+
+// reject 写入错误响应，返回io.EOF使fasthttp不再解析该连接
+func (c *guardedConn) reject(status int, reason string) error {
+	c.pending = nil
+	c.Conn.SetWriteDeadline(time.Now().Add(earlyRejectWriteTimeout))
+	fmt.Fprintf(c.Conn, "HTTP/1.1 %d %s\r\nConnection: close\r\nContent-Length: 0\r\n\r\n", status, reason)
+	return io.EOF
+}
+
+// isToken 判断是否全部为HTTP token字符
+func isToken(b []byte) bool {
+	for _, ch := range b {
+		if ch <= ' ' || ch >= 0x7f || bytes.IndexByte([]byte(`"(),/:;<=>?@[\]{}`), ch) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isCTLOrSpace 判断是否为控制字符或空白
+func isCTLOrSpace(r rune) bool {
+	return r <= ' ' || r == 0x7f
+}
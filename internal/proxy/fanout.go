@@ -0,0 +1,243 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// errNoFanOutBackend 扇出目标的上游没有可用后端或处于维护模式
+var errNoFanOutBackend = errors.New("no available backend")
+
+// fanOut 预编译的扇出配置
+type fanOut struct {
+	cfg      *types.FanOutConfig
+	paths    []*vars.Template // 与cfg.Targets对应，未配置path时为nil
+	template *vars.Template   // merge模式的响应模板，未配置时为nil
+}
+
+// fanOutResult 单个扇出目标的结果
+type fanOutResult struct {
+	index int
+	resp  *fasthttp.Response // 请求失败时为nil
+	err   error
+}
+
+// newFanOut 编译扇出配置，未配置时返回nil
+func newFanOut(cfg *types.FanOutConfig) (*fanOut, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	f := &fanOut{cfg: cfg, paths: make([]*vars.Template, len(cfg.Targets))}
+	for i, target := range cfg.Targets {
+		if target.Path == "" {
+			continue
+		}
+		path, err := vars.Compile(target.Path)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: path: %w", target.Name, err)
+		}
+		f.paths[i] = path
+	}
+	if cfg.Template != "" {
+		template, err := vars.Compile(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		f.template = template
+	}
+	return f, nil
+}
+
+// succeeded 目标是否返回了2xx响应
+func (r *fanOutResult) succeeded() bool {
+	return r.err == nil && r.resp.StatusCode() >= 200 && r.resp.StatusCode() < 300
+}
+
+// release 释放目标的响应
+func (r *fanOutResult) release() {
+	if r.resp != nil {
+		fasthttp.ReleaseResponse(r.resp)
+		r.resp = nil
+	}
+}
+
+// serveFanOut 把请求并行发送到路由的所有扇出目标，按配置的方式组合响应
+func (s *Server) serveFanOut(ctx *fasthttp.RequestCtx, entry *routeEntry) {
+	f := entry.fanOut
+	cfg := f.cfg
+
+	// 以流方式接收的请求体只能读取一次，长度已知且不超过上限时读入内存后复制给每个目标
+	if ctx.Request.IsBodyStream() {
+		length := ctx.Request.Header.ContentLength()
+		if length < 0 || length > cfg.MaxBodySize {
+			ctx.Error("Request Entity Too Large (Fan-out body limit)", fasthttp.StatusRequestEntityTooLarge)
+			return
+		}
+		ctx.Request.Body()
+	}
+
+	env := s.varEnv(ctx)
+	for _, h := range entry.requestHeaders {
+		ctx.Request.Header.Set(h.name, h.value.Render(env))
+	}
+
+	// 在处理协程中选择后端和生成请求，发送协程不再访问客户端请求
+	results := make(chan *fanOutResult, len(cfg.Targets))
+	upstreamMgr := s.upstreamMgr.Load()
+	for i := range cfg.Targets {
+		target := &cfg.Targets[i]
+
+		var backend *types.Backend
+		if upstream := upstreamMgr.GetUpstream(target.Upstream); upstream != nil && s.maintenance.Upstream(target.Upstream) == nil {
+			backend = selectByPriority(upstream.GetBackendGroups(), upstream.Balancer("", nil), s.newRequestContext(ctx))
+		}
+		if backend == nil {
+			results <- &fanOutResult{index: i, err: errNoFanOutBackend}
+			continue
+		}
+
+		req := s.copyRequest(ctx)
+		if f.paths[i] != nil {
+			req.SetRequestURI(f.paths[i].Render(env))
+		}
+		setBackendAddr(req, backend)
+		go s.sendFanOut(req, i, target.Upstream, backend, cfg.Timeout, results)
+	}
+
+	if cfg.Mode == types.FanOutMerge {
+		s.mergeFanOut(ctx, f, results)
+	} else {
+		s.firstFanOut(ctx, f, results)
+		if entry.headerFilter != nil {
+			entry.headerFilter.apply(&ctx.Response.Header)
+		}
+	}
+}
+
+// sendFanOut 发送一个扇出请求并把结果写入results
+func (s *Server) sendFanOut(req *fasthttp.Request, index int, upstream string, backend *types.Backend, timeout time.Duration, results chan<- *fanOutResult) {
+	defer fasthttp.ReleaseRequest(req)
+	defer s.queues.release(upstream)
+	backend.IncConnections()
+	defer backend.DecConnections()
+	if s.monitor != nil {
+		s.monitor.BackendActive(upstream, backend.ID, 1)
+		defer s.monitor.BackendActive(upstream, backend.ID, -1)
+	}
+
+	resp := fasthttp.AcquireResponse()
+	if err := s.clients.Get(backend).DoTimeout(req, resp, timeout); err != nil {
+		fasthttp.ReleaseResponse(resp)
+		if s.monitor != nil {
+			s.monitor.RecordTraffic("", upstream, backend.ID, fasthttp.StatusBadGateway, int64(len(req.Body())), 0)
+		}
+		results <- &fanOutResult{index: index, err: err}
+		return
+	}
+	if s.monitor != nil {
+		s.monitor.RecordTraffic("", upstream, backend.ID, resp.StatusCode(), int64(len(req.Body())), int64(len(resp.Body())))
+	}
+	results <- &fanOutResult{index: index, resp: resp}
+}
+
+// firstFanOut 返回最先成功的目标的响应，其余目标的响应在后台丢弃
+func (s *Server) firstFanOut(ctx *fasthttp.RequestCtx, f *fanOut, results chan *fanOutResult) {
+	var winner *fanOutResult
+	pending := len(f.cfg.Targets)
+	for pending > 0 {
+		r := <-results
+		pending--
+		if r.succeeded() {
+			winner = r
+			break
+		}
+		r.release()
+	}
+	if pending > 0 {
+		go func() {
+			for ; pending > 0; pending-- {
+				r := <-results
+				r.release()
+			}
+		}()
+	}
+
+	if winner == nil {
+		ctx.Error("Bad Gateway (No fan-out target succeeded)", fasthttp.StatusBadGateway)
+		return
+	}
+	winner.resp.CopyTo(&ctx.Response)
+	winner.release()
+	ctx.Response.Header.Set("X-Fanout-Target", f.cfg.Targets[winner.index].Name)
+}
+
+// mergeFanOut 等待所有目标并把各目标的JSON响应合并为一个JSON响应
+// 成功但不是JSON的响应体按JSON字符串处理，失败的目标为null并在X-Fanout-Failed中列出
+func (s *Server) mergeFanOut(ctx *fasthttp.RequestCtx, f *fanOut, results chan *fanOutResult) {
+	targets := f.cfg.Targets
+	values := make([][]byte, len(targets))
+	for range targets {
+		r := <-results
+		if r.succeeded() {
+			if body := r.resp.Body(); json.Valid(body) {
+				values[r.index] = append([]byte(nil), body...)
+			} else {
+				values[r.index], _ = json.Marshal(string(body))
+			}
+		}
+		r.release()
+	}
+
+	var failed []string
+	for i, target := range targets {
+		if values[i] != nil {
+			continue
+		}
+		if target.Required {
+			ctx.Error("Bad Gateway (Fan-out target "+target.Name+" failed)", fasthttp.StatusBadGateway)
+			return
+		}
+		failed = append(failed, target.Name)
+	}
+
+	var body []byte
+	if f.template != nil {
+		for i, target := range targets {
+			if values[i] != nil {
+				ctx.SetUserValue(vars.UserValueFanOut+target.Name, values[i])
+			}
+		}
+		body = f.template.Append(nil, s.varEnv(ctx))
+	} else {
+		body = append(body, '{')
+		for i, target := range targets {
+			if i > 0 {
+				body = append(body, ',')
+			}
+			key, _ := json.Marshal(target.Name)
+			body = append(append(body, key...), ':')
+			if values[i] != nil {
+				body = append(body, values[i]...)
+			} else {
+				body = append(body, "null"...)
+			}
+		}
+		body = append(body, '}')
+	}
+
+	ctx.Response.SetStatusCode(fasthttp.StatusOK)
+	ctx.Response.Header.SetContentType("application/json")
+	if len(failed) > 0 {
+		ctx.Response.Header.Set("X-Fanout-Failed", strings.Join(failed, ","))
+	}
+	ctx.Response.SetBody(body)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// forwardAuthMaxBodySize 认证服务响应体的上限，拒绝时原样返回给客户端的通常只是登录跳转或错误页面
+const forwardAuthMaxBodySize = 1 << 20
+
+// newForwardAuthClient 创建访问认证服务的客户端
+func newForwardAuthClient() *fasthttp.Client {
+	return &fasthttp.Client{
+		Name:                          "SpeedMimi-ForwardAuth",
+		MaxIdleConnDuration:           60 * time.Second,
+		MaxResponseBodySize:           forwardAuthMaxBodySize,
+		DisableHeaderNamesNormalizing: true,
+		DisablePathNormalizing:        true,
+		NoDefaultUserAgentHeader:      true,
+	}
+}
+
+// checkForwardAuth 把请求发送到外部认证服务，返回false时已写入响应
+// 认证通过时把认证服务返回的身份头写入转发给上游的请求
+func (s *Server) checkForwardAuth(ctx *fasthttp.RequestCtx, cfg *types.ForwardAuthConfig) bool {
+	// 客户端传入的身份头不能被信任，无论认证结果如何都删除
+	for _, name := range cfg.ResponseHeaders {
+		vars.DelHeader(&ctx.Request.Header, name)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	s.buildForwardAuthRequest(ctx, cfg, req)
+	if err := s.authClient.DoTimeout(req, resp, cfg.Timeout); err != nil {
+		fmt.Printf("[FORWARDAUTH] Auth request to %s failed: %v\n", cfg.Address, err)
+		ctx.Error("Bad Gateway (Auth service unavailable)", fasthttp.StatusBadGateway)
+		return false
+	}
+
+	if status := resp.StatusCode(); status < 200 || status > 299 {
+		// 认证服务的拒绝响应（401、403或跳转到登录页）原样返回给客户端
+		resp.CopyTo(&ctx.Response)
+		return false
+	}
+
+	for _, name := range cfg.ResponseHeaders {
+		if value := vars.PeekResponseHeader(&resp.Header, name); len(value) > 0 {
+			ctx.Request.Header.SetBytesV(name, value)
+		}
+	}
+	return true
+}
+
+// buildForwardAuthRequest 生成认证请求：GET认证服务地址，携带客户端请求头和描述原始请求的X-Forwarded-*头
+func (s *Server) buildForwardAuthRequest(ctx *fasthttp.RequestCtx, cfg *types.ForwardAuthConfig, req *fasthttp.Request) {
+	req.Header.DisableNormalizing()
+	req.SetRequestURI(cfg.Address)
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	if len(cfg.RequestHeaders) == 0 {
+		ctx.Request.Header.VisitAll(func(key, value []byte) {
+			if !isHopHeader(key, value) {
+				req.Header.AddBytesKV(key, value)
+			}
+		})
+	} else {
+		for _, name := range cfg.RequestHeaders {
+			if value := vars.PeekHeader(&ctx.Request.Header, name); len(value) > 0 {
+				req.Header.SetBytesV(name, value)
+			}
+		}
+	}
+
+	req.Header.Set("X-Forwarded-Method", string(ctx.Method()))
+	req.Header.Set("X-Forwarded-Proto", s.getProto(ctx))
+	req.Header.Set("X-Forwarded-Host", string(ctx.Host()))
+	req.Header.Set("X-Forwarded-Uri", string(ctx.RequestURI()))
+	req.Header.Set("X-Forwarded-For", s.getClientIP(ctx))
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// protectedResponseHeaders 描述响应体和连接的响应头，过滤后响应无法正确传输，总是保留
+var protectedResponseHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Content-Encoding",
+	"Transfer-Encoding",
+	"Connection",
+	"Trailer",
+}
+
+// headerPattern 响应头名称模式，prefix为true时按前缀匹配
+type headerPattern struct {
+	name   []byte
+	prefix bool
+}
+
+// match 不区分大小写匹配响应头名称
+func (p headerPattern) match(name []byte) bool {
+	if p.prefix {
+		return len(name) >= len(p.name) && bytes.EqualFold(name[:len(p.name)], p.name)
+	}
+	return bytes.EqualFold(name, p.name)
+}
+
+// headerFilter 预编译的上游响应头过滤器
+type headerFilter struct {
+	allow       []headerPattern
+	deny        []headerPattern
+	defaultDeny bool
+}
+
+// newHeaderFilter 编译响应头过滤配置，cfg为nil时返回nil
+func newHeaderFilter(cfg *types.HeaderFilterConfig) (*headerFilter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	f := &headerFilter{defaultDeny: cfg.DefaultDeny}
+	var err error
+	if f.allow, err = compileHeaderPatterns(cfg.Allow); err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	}
+	if f.deny, err = compileHeaderPatterns(cfg.Deny); err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+	return f, nil
+}
+
+// compileHeaderPatterns 编译响应头名称模式，以*结尾时按前缀匹配
+func compileHeaderPatterns(patterns []string) ([]headerPattern, error) {
+	compiled := make([]headerPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		name := strings.TrimSuffix(pattern, "*")
+		if name == "" || strings.Contains(name, "*") {
+			return nil, fmt.Errorf("invalid header pattern %q", pattern)
+		}
+		compiled = append(compiled, headerPattern{name: []byte(name), prefix: name != pattern})
+	}
+	return compiled, nil
+}
+
+// forwarded 判断响应头是否转发给客户端
+func (f *headerFilter) forwarded(name []byte) bool {
+	for _, protected := range protectedResponseHeaders {
+		if bytes.EqualFold(name, []byte(protected)) {
+			return true
+		}
+	}
+	for _, p := range f.allow {
+		if p.match(name) {
+			return true
+		}
+	}
+	if f.defaultDeny {
+		return false
+	}
+	for _, p := range f.deny {
+		if p.match(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply 删除不转发的上游响应头
+func (f *headerFilter) apply(h *fasthttp.ResponseHeader) {
+	var removed []string
+	h.VisitAll(func(key, _ []byte) {
+		if !f.forwarded(key) {
+			removed = append(removed, string(key))
+		}
+	})
+	for _, key := range removed {
+		h.Del(key)
+	}
+}
@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"github.com/quqi/speedmimi/internal/healthcheck"
+)
+
+// healthTargets 健康检查目标：当前全部上游的全部后端
+func (s *Server) healthTargets() []healthcheck.Target {
+	mgr := s.upstreamMgr.Load()
+	if mgr == nil {
+		return nil
+	}
+
+	var targets []healthcheck.Target
+	for _, upstream := range mgr.Upstreams() {
+		for _, backend := range upstream.GetAllBackends() {
+			targets = append(targets, healthcheck.Target{Upstream: upstream.name, Backend: backend})
+		}
+	}
+	return targets
+}
+
+// onHealthTransition 后端生效健康状态变化时发送通知，并重新计算冷备切换状态
+func (s *Server) onHealthTransition(t healthcheck.Transition) {
+	s.notifyHealthTransition(t)
+	s.refreshStandby()
+}
+
+// GetHealthChecker 获取后台健康检查器
+func (s *Server) GetHealthChecker() *healthcheck.Checker {
+	return s.health
+}
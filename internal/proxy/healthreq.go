@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// healthRequirementMet 判断路由主上游（按backend_selector选择的子集）的健康后端是否满足路由的要求
+// 状态变化时记录日志
+func (s *Server) healthRequirementMet(routeName string, rule *types.RoutingRule) bool {
+	req := rule.HealthRequirement
+
+	healthy, total := 0, 0
+	if upstream := s.upstreamMgr.Load().GetUpstream(rule.Upstream); upstream != nil {
+		for _, backend := range upstream.GetAllBackends() {
+			if !matchLabels(backend.Labels, rule.BackendSelector) {
+				continue
+			}
+			total++
+			if backend.IsActive() && backend.Active && backend.IsHealthy() && !backend.ShouldDisconnect() {
+				healthy++
+			}
+		}
+	}
+
+	required := req.MinHealthy
+	if percent := int(math.Ceil(float64(total) * req.MinHealthyPercent / 100)); percent > required {
+		required = percent
+	}
+	met := healthy >= required && healthy > 0
+
+	if _, degraded := s.degradedRoutes.Load(routeName); degraded == met {
+		if met {
+			s.degradedRoutes.Delete(routeName)
+			fmt.Printf("[HEALTH] Route %s recovered (%d/%d backends healthy)\n", routeName, healthy, total)
+		} else {
+			s.degradedRoutes.Store(routeName, struct{}{})
+			fmt.Printf("[HEALTH] Route %s degraded to %s fallback (%d/%d backends healthy, %d required)\n", routeName, req.Fallback, healthy, total, required)
+		}
+	}
+	return met
+}
+
+// serveHealthFallback 路由健康后端不足时按fallback降级处理
+func (s *Server) serveHealthFallback(ctx *fasthttp.RequestCtx, entry *routeEntry, cached *cacheState, lbType types.LoadBalancerType, reqCtx types.RequestContext) {
+	req := entry.rule.HealthRequirement
+
+	switch req.Fallback {
+	case types.HealthFallbackCache:
+		// 新鲜的缓存已在查找时返回，这里使用已过期的条目
+		if cached != nil {
+			if stale := cached.cache.Get(cached.key); stale != nil {
+				serveCacheEntry(ctx, nil, stale, time.Now(), "STALE")
+				return
+			}
+		}
+	case types.HealthFallbackUpstream:
+		// 替代上游沿用路由的负载均衡和重试配置，但不再使用备用上游和冷备上游
+		alt := *entry.rule
+		alt.Upstream = req.Upstream
+		alt.FallbackUpstreams = nil
+		alt.Standby = nil
+		alt.BackendSelector = nil
+
+		result := s.selectBackend(entry.name, &alt, "", lbType, reqCtx, nil)
+		if result.backend != nil {
+			s.forward(ctx, entry, result.upstream, result.backend, cached, func(tried map[*types.Backend]bool) (*Upstream, *types.Backend) {
+				result := s.selectBackend(entry.name, &alt, "", lbType, reqCtx, tried)
+				return result.upstream, result.backend
+			})
+			return
+		}
+	}
+
+	ctx.Response.Reset()
+	ctx.SetStatusCode(req.Status)
+	if req.RetryAfter > 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(req.RetryAfter.Seconds()))))
+	}
+	ctx.SetContentType(req.ContentType)
+	ctx.SetBodyString(req.Body)
+}
@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// xfccHeader Envoy格式的客户端证书请求头
+const xfccHeader = "X-Forwarded-Client-Cert"
+
+// reloadClientAuth 按SSL配置加载客户端CA并生成要求客户端证书的TLS配置
+// client_auth为none时握手使用基础配置
+func (s *Server) reloadClientAuth(sslCfg *types.SSLConfig) error {
+	var authType tls.ClientAuthType
+	switch sslCfg.ClientAuth {
+	case types.ClientAuthOptional:
+		authType = tls.VerifyClientCertIfGiven
+	case types.ClientAuthRequire:
+		authType = tls.RequireAndVerifyClientCert
+	default:
+		s.clientAuthTLS.Store(nil)
+		return nil
+	}
+
+	pem, err := os.ReadFile(sslCfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in client CA file %s", sslCfg.ClientCAFile)
+	}
+
+	cfg := s.tlsConfig.Clone()
+	cfg.GetConfigForClient = nil
+	cfg.ClientAuth = authType
+	cfg.ClientCAs = pool
+	s.clientAuthTLS.Store(cfg)
+	return nil
+}
+
+// getConfigForClient 握手时使用当前的客户端证书配置，未启用时返回nil使用基础配置
+func (s *Server) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return s.clientAuthTLS.Load(), nil
+}
+
+// clientCertificate 获取连接上验证通过的客户端证书，没有时返回nil
+func clientCertificate(ctx *fasthttp.RequestCtx) *x509.Certificate {
+	state := ctx.TLSConnectionState()
+	if state == nil || len(state.PeerCertificates) == 0 || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// spiffeID 证书中的SPIFFE ID，按SPIFFE规范证书只能有一个spiffe URI SAN，否则视为没有
+func spiffeID(cert *x509.Certificate) string {
+	id := ""
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		if id != "" {
+			return ""
+		}
+		id = uri.String()
+	}
+	return id
+}
+
+// setClientIdentityHeaders 按客户端证书设置身份请求头
+// 先删除客户端传入的同名请求头（忽略大小写），避免伪造
+func (s *Server) setClientIdentityHeaders(ctx *fasthttp.RequestCtx, identity *types.ClientIdentityConfig) {
+	vars.DelHeader(&ctx.Request.Header, identity.SPIFFEIDHeader)
+	vars.DelHeader(&ctx.Request.Header, identity.SubjectHeader)
+	vars.DelHeader(&ctx.Request.Header, identity.HashHeader)
+	if identity.XFCC {
+		vars.DelHeader(&ctx.Request.Header, xfccHeader)
+	}
+
+	cert := clientCertificate(ctx)
+	if cert == nil {
+		return
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	hash := hex.EncodeToString(sum[:])
+	subject := cert.Subject.String()
+	id := spiffeID(cert)
+
+	ctx.Request.Header.Set(identity.HashHeader, hash)
+	ctx.Request.Header.Set(identity.SubjectHeader, subject)
+	if id != "" {
+		ctx.Request.Header.Set(identity.SPIFFEIDHeader, id)
+	}
+
+	if identity.XFCC {
+		// 证书主题按RFC 2253格式化，其中的双引号已转义
+		xfcc := "Hash=" + hash + `;Subject="` + subject + `"`
+		if id != "" {
+			xfcc += ";URI=" + id
+		}
+		ctx.Request.Header.Set(xfccHeader, xfcc)
+	}
+}
+
+// checkClientIdentity 检查客户端证书的SPIFFE ID是否在路由允许的范围内，不允许时返回403
+func checkClientIdentity(ctx *fasthttp.RequestCtx, rule *types.RoutingRule) bool {
+	if len(rule.AllowedSPIFFEIDs) == 0 {
+		return true
+	}
+
+	id := ""
+	if cert := clientCertificate(ctx); cert != nil {
+		id = spiffeID(cert)
+	}
+	if id != "" && matchSPIFFEID(rule.AllowedSPIFFEIDs, id) {
+		return true
+	}
+
+	ctx.Error("Forbidden (Client identity not allowed)", fasthttp.StatusForbidden)
+	return false
+}
+
+// matchSPIFFEID 判断SPIFFE ID是否匹配任一模式
+// 模式中的*匹配一个路径段，以/**结尾时匹配该路径下的任意ID
+func matchSPIFFEID(patterns []string, id string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "**"); ok && strings.HasSuffix(prefix, "/") {
+			if strings.HasPrefix(id, prefix) && len(id) > len(prefix) {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, id); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// userValueBodyInspection 路由的请求体检查限制
+const userValueBodyInspection = "speedmimi.body_inspection"
+
+// inspectBody 判断需要读取完整请求体的过滤器（如请求签名）能否读取请求体
+// 路由未配置限制时总是可以读取；请求体超过max_bytes或长度未知时不读取请求体，以免全部读入内存：
+// bypass返回inspect为false，过滤器跳过请求体；deny返回413，ok为false
+func inspectBody(ctx *fasthttp.RequestCtx) (inspect, ok bool) {
+	cfg, _ := ctx.UserValue(userValueBodyInspection).(*types.BodyInspectionConfig)
+	if cfg == nil {
+		return true, true
+	}
+
+	length := ctx.Request.Header.ContentLength()
+	if !ctx.Request.IsBodyStream() {
+		length = len(ctx.Request.Body())
+	}
+	if length >= 0 && length <= cfg.MaxBytes {
+		return true, true
+	}
+
+	if cfg.OnExceed == types.BodyInspectionBypass {
+		return false, true
+	}
+	ctx.Error("Request Entity Too Large (Body exceeds inspection limit)", fasthttp.StatusRequestEntityTooLarge)
+	return false, false
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// listenerDrainTimeout 监听器重载时旧服务器排空连接的最长等待时间
+const listenerDrainTimeout = 60 * time.Second
+
+// listenerSettings 监听器级别配置，变化时需要重建fasthttp.Server
+type listenerSettings struct {
+	addr            string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	maxConn         int
+	readBufferSize  int
+	writeBufferSize int
+	maxBodySize     int
+}
+
+// newListenerSettings 从配置提取监听器级别配置
+func newListenerSettings(cfg *types.ServerConfig, addr string) listenerSettings {
+	return listenerSettings{
+		addr:            addr,
+		readTimeout:     cfg.ReadTimeout,
+		writeTimeout:    cfg.WriteTimeout,
+		maxConn:         cfg.MaxConn,
+		readBufferSize:  cfg.ReadBufferSize,
+		writeBufferSize: cfg.WriteBufferSize,
+		maxBodySize:     cfg.MaxRequestBodySize,
+	}
+}
+
+// connListener 由分发器投递连接的监听器
+// Close只停止向当前fasthttp.Server投递新连接，不关闭底层监听套接字
+type connListener struct {
+	addr   net.Addr
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept 等待分发器投递的连接，关闭后返回io.EOF使fasthttp.Server.Serve正常返回
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, io.EOF
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
+
+// dispatchListener 持有底层监听套接字，将新连接投递给当前的connListener
+// 重载时只需切换current，正在处理的连接仍由旧服务器负责直至排空
+type dispatchListener struct {
+	ln      net.Listener
+	conns   *ConnTable
+	current atomic.Pointer[connListener]
+}
+
+func newDispatchListener(ln net.Listener, conns *ConnTable) *dispatchListener {
+	return &dispatchListener{ln: ln, conns: conns}
+}
+
+// swap 切换接收新连接的connListener，返回之前的connListener
+func (d *dispatchListener) swap(next *connListener) *connListener {
+	return d.current.Swap(next)
+}
+
+// run 接收连接并投递，底层监听套接字关闭后返回
+func (d *dispatchListener) run() {
+	for {
+		conn, err := d.ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				time.Sleep(time.Second)
+				continue
+			}
+			return
+		}
+		tc := d.conns.track(conn)
+		if tc == nil {
+			// 客户端IP的连接数达到上限
+			conn.Close()
+			continue
+		}
+		d.dispatch(tc)
+	}
+}
+
+// dispatch 投递连接，目标在投递期间被关闭时重新获取当前目标
+func (d *dispatchListener) dispatch(conn net.Conn) {
+	for {
+		target := d.current.Load()
+		if target == nil {
+			conn.Close()
+			return
+		}
+
+		select {
+		case target.conns <- conn:
+			return
+		case <-target.closed:
+			if d.current.Load() == target {
+				// 已停止接收新连接（关闭或静默中）
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// Close 关闭底层监听套接字
+func (d *dispatchListener) Close() error {
+	return d.ln.Close()
+}
+
+// newFastHTTPServer 根据监听器配置创建高性能fasthttp服务器（支持千万级并发）
+func newFastHTTPServer(handler fasthttp.RequestHandler, settings listenerSettings) *fasthttp.Server {
+	return &fasthttp.Server{
+		Handler:                       handler,
+		ReadTimeout:                   settings.readTimeout,
+		WriteTimeout:                  settings.writeTimeout,
+		MaxConnsPerIP:                 0, // 单IP连接数由连接表按client_limits限制（支持白名单）
+		MaxRequestsPerConn:            0, // 不限制单连接请求数
+		MaxKeepaliveDuration:          300 * time.Second, // 增加keepalive时间
+		TCPKeepalive:                  true,
+		TCPKeepalivePeriod:            30 * time.Second, // 减少keepalive周期
+		ReduceMemoryUsage:             false, // 性能优先
+		GetOnly:                       false,
+		DisablePreParseMultipartForm: true,
+		LogAllErrors:                  false,
+		DisableHeaderNamesNormalizing: true,
+		NoDefaultServerHeader:         true,
+		NoDefaultDate:                 true,  // 禁用默认日期头以提高性能
+		NoDefaultContentType:          true,
+		KeepHijackedConns:             false,
+		CloseOnShutdown:               true,
+		StreamRequestBody:             true,
+		MaxRequestBodySize:            settings.maxBodySize,
+
+		// 高并发优化配置
+		SleepWhenConcurrencyLimitsExceeded: 0,
+		Concurrency:                        settings.maxConn,
+
+		// 内存池优化
+		ReadBufferSize:  settings.readBufferSize,
+		WriteBufferSize: settings.writeBufferSize,
+
+		// 连接优化
+		MaxIdleWorkerDuration: 60 * time.Second,
+
+		// 错误处理优化
+		ErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			// 静默处理错误，避免日志输出影响性能；请求头超过read_buffer_size时返回431
+			var smallBuffer *fasthttp.ErrSmallBuffer
+			var netErr net.Error
+			switch {
+			case errors.As(err, &smallBuffer):
+				ctx.SetStatusCode(fasthttp.StatusRequestHeaderFieldsTooLarge)
+			case errors.Is(err, fasthttp.ErrBodyTooLarge):
+				ctx.SetStatusCode(fasthttp.StatusRequestEntityTooLarge)
+			case errors.As(err, &netErr) && netErr.Timeout():
+				ctx.SetStatusCode(fasthttp.StatusRequestTimeout)
+			default:
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			}
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/orca"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// loadReportErrorInterval 负载报告解析失败的日志间隔，避免每个响应都输出日志
+const loadReportErrorInterval = time.Minute
+
+// lastLoadReportError 最近一次输出解析失败日志的时间（UnixNano）
+var lastLoadReportError atomic.Int64
+
+// ingestLoadReport 读取后端响应中的ORCA负载报告并更新后端的性能信息，供性能感知的负载均衡使用
+// 负载报告是代理与后端之间的信息，读取后从返回给客户端的响应中删除
+func ingestLoadReport(resp *fasthttp.Response, upstream string, backend *types.Backend) {
+	header := &resp.Header
+	var report *orca.Report
+	var err error
+	if value := vars.PeekResponseHeader(header, orca.BinaryHeaderName); len(value) > 0 {
+		report, err = orca.ParseBinary(value)
+	} else if value := vars.PeekResponseHeader(header, orca.HeaderName); len(value) > 0 {
+		report, err = orca.Parse(value)
+	} else {
+		return
+	}
+	vars.DelResponseHeader(header, orca.BinaryHeaderName)
+	vars.DelResponseHeader(header, orca.HeaderName)
+
+	if err != nil {
+		now := time.Now().UnixNano()
+		if last := lastLoadReportError.Load(); now-last >= int64(loadReportErrorInterval) && lastLoadReportError.CompareAndSwap(last, now) {
+			fmt.Printf("[ORCA] Ignoring invalid load report from %s/%s: %v\n", upstream, backend.ID, err)
+		}
+		return
+	}
+
+	backend.UpdatePerformance(&types.PerformanceInfo{
+		CPUUsage:    percent(report.EffectiveUtilization()),
+		MemoryUsage: percent(report.MemUtilization),
+		Source:      types.PerformanceSourceORCA,
+		RPS:         report.RPSFractional,
+		EPS:         report.EPS,
+	})
+}
+
+// percent 把0-1的利用率转换为0-100的百分比，ORCA的CPU利用率按核数计算时可能超过1
+func percent(utilization float64) float64 {
+	if utilization > 1 {
+		utilization = 1
+	}
+	return utilization * 100
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// upstreamTimeout 普通路由等待后端响应的超时时间（HTTP/1.1和HTTP/2相同）
+const upstreamTimeout = 30 * time.Second
+
+// errLongPollTimeout 长轮询请求在long_poll.timeout内没有得到后端响应，已写入timeout_status响应
+var errLongPollTimeout = errors.New("long poll timed out")
+
+// longPollConfig 获取请求所在路由的长轮询配置，不是长轮询路由时返回nil
+func (s *Server) longPollConfig(ctx *fasthttp.RequestCtx) *types.LongPollConfig {
+	route, _ := ctx.UserValue(userValueRoute).(string)
+	if rule := s.config.GetConfig().Routing[route]; rule != nil {
+		return rule.LongPoll
+	}
+	return nil
+}
+
+// signalLongPoll 通过请求头告知后端代理还会等待的秒数，后端应在此之前返回（没有事件时返回空响应）
+func signalLongPoll(ctx *fasthttp.RequestCtx, cfg *types.LongPollConfig) {
+	seconds := int64((cfg.Timeout - cfg.Margin) / time.Second)
+	ctx.Request.Header.Set(cfg.TimeoutHeader, strconv.FormatInt(seconds, 10))
+}
+
+// longPollTimedOut 长轮询请求等待后端响应超时，按配置写入响应并返回errLongPollTimeout
+func longPollTimedOut(ctx *fasthttp.RequestCtx, cfg *types.LongPollConfig) error {
+	ctx.Response.Reset()
+	ctx.Error(fasthttp.StatusMessage(cfg.TimeoutStatus)+" (Long poll timeout)", cfg.TimeoutStatus)
+	return errLongPollTimeout
+}
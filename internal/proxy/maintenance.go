@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const (
+	// MaintenanceScopeUpstream 上游维护模式
+	MaintenanceScopeUpstream = "upstream"
+	// MaintenanceScopeRoute 路由维护模式
+	MaintenanceScopeRoute = "route"
+)
+
+// MaintenanceState 维护模式状态
+type MaintenanceState struct {
+	Scope       string    `json:"scope"`
+	Name        string    `json:"name"`
+	RetryAfter  int       `json:"retry_after"` // 秒
+	Body        string    `json:"body"`
+	ContentType string    `json:"content_type"`
+	Since       time.Time `json:"since"`
+}
+
+// MaintenanceManager 维护模式管理器（运行时状态，不写入配置文件）
+type MaintenanceManager struct {
+	mu        sync.RWMutex
+	upstreams map[string]*MaintenanceState
+	routes    map[string]*MaintenanceState
+}
+
+// NewMaintenanceManager 创建维护模式管理器
+func NewMaintenanceManager() *MaintenanceManager {
+	return &MaintenanceManager{
+		upstreams: make(map[string]*MaintenanceState),
+		routes:    make(map[string]*MaintenanceState),
+	}
+}
+
+// Enable 开启维护模式，未指定的页面参数使用全局默认值
+func (m *MaintenanceManager) Enable(state *MaintenanceState, defaults *types.MaintenanceConfig) {
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = int(defaults.RetryAfter / time.Second)
+	}
+	if state.Body == "" {
+		state.Body = defaults.Body
+	}
+	if state.ContentType == "" {
+		state.ContentType = defaults.ContentType
+	}
+	state.Since = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state.Scope == MaintenanceScopeRoute {
+		m.routes[state.Name] = state
+	} else {
+		m.upstreams[state.Name] = state
+	}
+}
+
+// Disable 关闭维护模式，返回之前是否处于维护模式
+func (m *MaintenanceManager) Disable(scope, name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := m.upstreams
+	if scope == MaintenanceScopeRoute {
+		states = m.routes
+	}
+
+	if _, exists := states[name]; !exists {
+		return false
+	}
+	delete(states, name)
+	return true
+}
+
+// Upstream 获取上游的维护状态，未处于维护模式时返回nil
+func (m *MaintenanceManager) Upstream(name string) *MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.upstreams[name]
+}
+
+// Route 获取路由的维护状态，未处于维护模式时返回nil
+func (m *MaintenanceManager) Route(name string) *MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.routes[name]
+}
+
+// List 列出所有处于维护模式的上游和路由
+func (m *MaintenanceManager) List() []*MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	states := make([]*MaintenanceState, 0, len(m.upstreams)+len(m.routes))
+	for _, state := range m.upstreams {
+		states = append(states, state)
+	}
+	for _, state := range m.routes {
+		states = append(states, state)
+	}
+	return states
+}
+
+// writeMaintenanceResponse 返回维护页面
+func writeMaintenanceResponse(ctx *fasthttp.RequestCtx, state *MaintenanceState) {
+	ctx.Response.Reset()
+	ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	if state.RetryAfter > 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(state.RetryAfter))
+	}
+	ctx.SetContentType(state.ContentType)
+	ctx.SetBodyString(state.Body)
+}
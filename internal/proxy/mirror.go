@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// maxMirrorInflight 同时进行的镜像请求上限，镜像上游变慢时丢弃新的镜像请求，避免占用过多协程和内存
+const maxMirrorInflight = 1024
+
+// MirrorStats 流量镜像统计
+type MirrorStats struct {
+	Mirrored int64 `json:"mirrored"` // 镜像上游返回响应的请求数
+	Failed   int64 `json:"failed"`   // 连接失败或超时的镜像请求数
+	Dropped  int64 `json:"dropped"`  // 因没有可用后端、请求体无法复制或并发达到上限未镜像的请求数
+	Inflight int64 `json:"inflight"` // 正在进行的镜像请求数
+}
+
+// mirrorCounters 流量镜像计数
+type mirrorCounters struct {
+	mirrored atomic.Int64
+	failed   atomic.Int64
+	dropped  atomic.Int64
+	inflight atomic.Int64
+}
+
+// MirrorStats 获取流量镜像统计
+func (s *Server) MirrorStats() MirrorStats {
+	return MirrorStats{
+		Mirrored: s.mirrors.mirrored.Load(),
+		Failed:   s.mirrors.failed.Load(),
+		Dropped:  s.mirrors.dropped.Load(),
+		Inflight: s.mirrors.inflight.Load(),
+	}
+}
+
+// mirrorRequest 按路由的镜像配置把请求异步复制到镜像上游，镜像的响应被丢弃，不影响客户端请求
+// 在改写请求的目标地址之前调用，镜像请求包括路由设置的请求头
+func (s *Server) mirrorRequest(ctx *fasthttp.RequestCtx, cfg *types.MirrorConfig) {
+	if cfg.Percent < 100 && rand.Intn(100) >= cfg.Percent {
+		return
+	}
+
+	// 以流方式接收的请求体只能读取一次，长度已知且不超过上限时读入内存后复制
+	if ctx.Request.IsBodyStream() {
+		length := ctx.Request.Header.ContentLength()
+		if length < 0 || length > cfg.MaxBodySize {
+			s.mirrors.dropped.Add(1)
+			return
+		}
+		ctx.Request.Body()
+	}
+
+	var backend *types.Backend
+	if upstream := s.upstreamMgr.Load().GetUpstream(cfg.Upstream); upstream != nil {
+		backend = selectByPriority(upstream.GetBackendGroups(), upstream.Balancer("", nil), s.newRequestContext(ctx))
+	}
+	if backend == nil {
+		s.mirrors.dropped.Add(1)
+		return
+	}
+
+	if s.mirrors.inflight.Add(1) > maxMirrorInflight {
+		s.mirrors.inflight.Add(-1)
+		s.mirrors.dropped.Add(1)
+		return
+	}
+
+	req := s.copyRequest(ctx)
+	setBackendAddr(req, backend)
+	go s.sendMirror(req, backend, cfg)
+}
+
+// copyRequest 复制客户端请求用于镜像、扇出等额外发送的请求，按路由的转发隐私模式设置X-Forwarded-*请求头
+// 调用方负责设置目标地址并释放请求
+func (s *Server) copyRequest(ctx *fasthttp.RequestCtx) *fasthttp.Request {
+	req := fasthttp.AcquireRequest()
+	ctx.Request.CopyTo(req)
+
+	if config := s.config.GetConfig(); stripForwarded(ctx, config) {
+		stripClientHeaders(&req.Header, &config.Server)
+	} else {
+		clientIP := s.getClientIP(ctx)
+		if existing := req.Header.Peek("X-Forwarded-For"); len(existing) > 0 {
+			req.Header.Set("X-Forwarded-For", string(existing)+", "+clientIP)
+		} else {
+			req.Header.Set("X-Forwarded-For", clientIP)
+		}
+	}
+	req.Header.Set("X-Forwarded-Proto", s.getProto(ctx))
+	return req
+}
+
+// setBackendAddr 把请求的目标地址改写为后端地址，保留客户端的Host头
+func setBackendAddr(req *fasthttp.Request, backend *types.Backend) {
+	scheme := backend.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	req.URI().SetScheme(scheme)
+	req.URI().SetHost(net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)))
+	req.UseHostHeader = true
+}
+
+// sendMirror 发送镜像请求并丢弃响应
+func (s *Server) sendMirror(req *fasthttp.Request, backend *types.Backend, cfg *types.MirrorConfig) {
+	resp := fasthttp.AcquireResponse()
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+		s.mirrors.inflight.Add(-1)
+	}()
+
+	defer s.queues.release(cfg.Upstream)
+	backend.IncConnections()
+	defer backend.DecConnections()
+
+	if err := s.clients.Get(backend).DoTimeout(req, resp, cfg.Timeout); err != nil {
+		s.mirrors.failed.Add(1)
+		return
+	}
+	s.mirrors.mirrored.Add(1)
+}
@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 请求路径无法规范化的原因
+var (
+	errPathControlChar  = errors.New("control character in path")
+	errPathBadEncoding  = errors.New("invalid percent-encoding in path")
+	errPathEncodedSlash = errors.New("encoded slash in path")
+)
+
+const upperHex = "0123456789ABCDEF"
+
+// pathCounters 请求路径规范化统计
+type pathCounters struct {
+	rewritten atomic.Int64
+	rejected  atomic.Int64
+}
+
+// PathNormalizationStats 请求路径规范化的设置和统计
+type PathNormalizationStats struct {
+	Enabled              bool   `json:"enabled"`
+	KeepDuplicateSlashes bool   `json:"keep_duplicate_slashes"`
+	EncodedSlashes       string `json:"encoded_slashes"`
+	Rewritten            int64  `json:"rewritten"` // 路径被改写的请求数
+	Rejected             int64  `json:"rejected"`  // 路径无法规范化而返回400的请求数
+}
+
+// normalizeRequestPath 在路由前规范化请求路径，改写后的路径同时用于路由匹配和转发
+// fasthttp按完全解码的路径匹配路由，转发时却使用原始路径（上游客户端禁用了路径规范化），
+// 两者不一致时构造的路径（如/public/%2e%2e/admin）可以按一个路由的规则转发到另一个路由的后端
+// 返回false时已返回400
+func (s *Server) normalizeRequestPath(ctx *fasthttp.RequestCtx) bool {
+	cfg := &s.config.GetConfig().Server.PathNormalization
+	if cfg.Disabled {
+		return true
+	}
+
+	raw := ctx.Request.URI().PathOriginal()
+	path, err := normalizePath(raw, !cfg.KeepDuplicateSlashes, cfg.EncodedSlashes)
+	if err != nil {
+		s.pathCounters.rejected.Add(1)
+		ctx.Error("Bad Request (Invalid path)", fasthttp.StatusBadRequest)
+		return false
+	}
+	if path == string(raw) {
+		return true
+	}
+
+	s.pathCounters.rewritten.Add(1)
+	if query := ctx.Request.URI().QueryString(); len(query) > 0 {
+		path += "?" + string(query)
+	}
+	ctx.Request.SetRequestURI(path)
+	return true
+}
+
+// normalizePath 规范化请求路径：
+// 拒绝控制字符（包括编码的控制字符）和错误的百分号编码，解码非保留字符（字母、数字和-._~），其余编码统一为大写；
+// 按encodedSlashes处理%2F，合并连续的斜杠（mergeSlashes），按RFC 3986解析.和..路径段（..不会越过根路径）
+func normalizePath(raw []byte, mergeSlashes bool, encodedSlashes string) (string, error) {
+	decoded := make([]byte, 0, len(raw)+1)
+	if len(raw) == 0 || raw[0] != '/' {
+		decoded = append(decoded, '/')
+	}
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c < 0x20 || c == 0x7f {
+			return "", errPathControlChar
+		}
+		if c != '%' {
+			decoded = append(decoded, c)
+			continue
+		}
+
+		if i+2 >= len(raw) {
+			return "", errPathBadEncoding
+		}
+		hi, lo := unhex(raw[i+1]), unhex(raw[i+2])
+		if hi < 0 || lo < 0 {
+			return "", errPathBadEncoding
+		}
+		i += 2
+		v := byte(hi<<4 | lo)
+		switch {
+		case v < 0x20 || v == 0x7f:
+			return "", errPathControlChar
+		case isUnreserved(v):
+			decoded = append(decoded, v)
+		case v == '/' && encodedSlashes == types.EncodedSlashesReject:
+			return "", errPathEncodedSlash
+		case v == '/' && encodedSlashes == types.EncodedSlashesDecode:
+			decoded = append(decoded, '/')
+		default:
+			decoded = append(decoded, '%', upperHex[v>>4], upperHex[v&0xf])
+		}
+	}
+
+	segments := strings.Split(string(decoded[1:]), "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch {
+		case segment == "." || segment == "..":
+			if segment == ".." && len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+			// 以.或..结尾的路径保留结尾的斜杠
+			if last {
+				out = append(out, "")
+			}
+		case segment == "" && !last && mergeSlashes:
+		default:
+			out = append(out, segment)
+		}
+	}
+	return "/" + strings.Join(out, "/"), nil
+}
+
+// unhex 十六进制字符的值，不是十六进制字符时返回-1
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// isUnreserved 是否为RFC 3986的非保留字符，编码与否含义相同
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// PathNormalizationStats 获取请求路径规范化的设置和统计
+func (s *Server) PathNormalizationStats() PathNormalizationStats {
+	cfg := s.config.GetConfig().Server.PathNormalization
+	return PathNormalizationStats{
+		Enabled:              !cfg.Disabled,
+		KeepDuplicateSlashes: cfg.KeepDuplicateSlashes,
+		EncodedSlashes:       cfg.EncodedSlashes,
+		Rewritten:            s.pathCounters.rewritten.Load(),
+		Rejected:             s.pathCounters.rejected.Load(),
+	}
+}
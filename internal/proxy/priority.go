@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"sort"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// GetBackendGroups 按优先级分组获取活跃后端，组按优先级数值从小到大排列
+func (u *Upstream) GetBackendGroups() [][]*types.Backend {
+	backends := u.GetBackends()
+	if len(backends) == 0 {
+		return nil
+	}
+
+	// 常见情况：所有后端同一优先级，无需排序
+	tiered := false
+	for _, backend := range backends[1:] {
+		if backend.Priority != backends[0].Priority {
+			tiered = true
+			break
+		}
+	}
+	if !tiered {
+		return [][]*types.Backend{backends}
+	}
+
+	sort.SliceStable(backends, func(i, j int) bool {
+		return backends[i].Priority < backends[j].Priority
+	})
+
+	groups := make([][]*types.Backend, 0, 2)
+	start := 0
+	for i := 1; i <= len(backends); i++ {
+		if i == len(backends) || backends[i].Priority != backends[start].Priority {
+			groups = append(groups, backends[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+// selectSubset 按标签选择器过滤各优先级组，只保留标签全部匹配的后端，丢弃过滤后为空的组
+func selectSubset(groups [][]*types.Backend, selector map[string]string) [][]*types.Backend {
+	if len(selector) == 0 {
+		return groups
+	}
+
+	subset := make([][]*types.Backend, 0, len(groups))
+	for _, group := range groups {
+		var matched []*types.Backend
+		for _, backend := range group {
+			if matchLabels(backend.Labels, selector) {
+				matched = append(matched, backend)
+			}
+		}
+		if len(matched) > 0 {
+			subset = append(subset, matched)
+		}
+	}
+	return subset
+}
+
+// excludeBackends 从各优先级组中去掉已排除的后端，丢弃去掉后为空的组
+func excludeBackends(groups [][]*types.Backend, exclude map[*types.Backend]bool) [][]*types.Backend {
+	if len(exclude) == 0 {
+		return groups
+	}
+
+	remaining := make([][]*types.Backend, 0, len(groups))
+	for _, group := range groups {
+		var kept []*types.Backend
+		for _, backend := range group {
+			if !exclude[backend] {
+				kept = append(kept, backend)
+			}
+		}
+		if len(kept) > 0 {
+			remaining = append(remaining, kept)
+		}
+	}
+	return remaining
+}
+
+// matchLabels 判断标签是否包含选择器中的全部键值
+func matchLabels(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// selectByPriority 依次在各优先级组内负载均衡，高优先级组全部不可用（断开中或达到连接上限）时才使用下一组
+func selectByPriority(groups [][]*types.Backend, balancer types.LoadBalancer, req types.RequestContext) *types.Backend {
+	for _, group := range groups {
+		if backend := balancer.SelectBackend(group, req); backend != nil {
+			return backend
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// clientIPHeaders 常见的携带客户端IP的请求头（包括上层代理和CDN设置的）
+var clientIPHeaders = []string{
+	"X-Forwarded-For",
+	"X-Real-IP",
+	"Forwarded",
+	"True-Client-IP",
+	"CF-Connecting-IP",
+	"Fastly-Client-IP",
+	"X-Client-IP",
+	"X-Cluster-Client-IP",
+	"X-Original-Forwarded-For",
+}
+
+// stripForwarded 路由的forwarded_headers为strip时返回true
+func stripForwarded(ctx *fasthttp.RequestCtx, cfg *types.Config) bool {
+	route, _ := ctx.UserValue(userValueRoute).(string)
+	rule := cfg.Routing[route]
+	return rule != nil && rule.ForwardedHeaders == types.ForwardedHeadersStrip
+}
+
+// stripClientHeaders 删除可识别客户端的请求头：客户端IP、连接元数据和客户端证书身份，
+// 客户端或上层代理传入的同名请求头也一并删除
+func stripClientHeaders(h *fasthttp.RequestHeader, server *types.ServerConfig) {
+	for _, name := range clientIPHeaders {
+		vars.DelHeader(h, name)
+	}
+	if server.RealIPHeader != "" {
+		vars.DelHeader(h, server.RealIPHeader)
+	}
+
+	meta := &server.ConnectionMetadata
+	for _, name := range []string{meta.TLSVersionHeader, meta.TLSCipherHeader, meta.ALPNHeader, meta.ClientPortHeader, meta.ConnectionIDHeader} {
+		if name != "" {
+			vars.DelHeader(h, name)
+		}
+	}
+
+	identity := &server.ClientIdentity
+	for _, name := range []string{identity.SPIFFEIDHeader, identity.SubjectHeader, identity.HashHeader, xfccHeader} {
+		if name != "" {
+			vars.DelHeader(h, name)
+		}
+	}
+}
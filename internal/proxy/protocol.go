@@ -0,0 +1,344 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+
+	"github.com/quqi/speedmimi/internal/orca"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 后端对HTTP/2的支持状态
+const (
+	h2Unknown     = "unknown"
+	h2Supported   = "supported"
+	h2Unsupported = "unsupported"
+)
+
+// errH2Unsupported 后端在TLS握手时没有协商h2
+var errH2Unsupported = errors.New("backend did not negotiate h2")
+
+// dialError 建立连接失败（后端不可达），不代表后端不支持HTTP/2；也用于区分连接超时和等待响应超时
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
+// ProtocolStatus 后端的上游协议状态
+type ProtocolStatus struct {
+	Chain     []string   `json:"chain"`                // 上游配置的协议偏好顺序
+	Protocol  string     `json:"protocol"`             // 最近一次请求使用的协议，尚未请求时为空
+	H2        string     `json:"h2"`                   // 学习到的HTTP/2支持状态：unknown、supported、unsupported
+	RecheckAt *time.Time `json:"recheck_at,omitempty"` // 不支持HTTP/2时，重新尝试的时间
+	LastError string     `json:"last_error,omitempty"` // 最近一次判定不支持HTTP/2的原因
+}
+
+// backendProtocol 单个后端地址学习到的协议支持情况和HTTP/2传输
+type backendProtocol struct {
+	transport *http2.Transport
+
+	mu        sync.Mutex
+	h2        string
+	recheckAt time.Time
+	lastError string
+	protocol  string
+}
+
+// ProtocolCache 按后端地址缓存学习到的协议支持情况
+// 按地址（而不是后端对象）保存，配置更新重建后端对象时保留学习结果
+type ProtocolCache struct {
+	mu       sync.RWMutex
+	backends map[string]*backendProtocol // scheme://host:port -> 协议状态
+}
+
+// NewProtocolCache 创建协议缓存
+func NewProtocolCache() *ProtocolCache {
+	return &ProtocolCache{
+		backends: make(map[string]*backendProtocol),
+	}
+}
+
+// get 获取后端的协议状态，不存在时创建
+func (c *ProtocolCache) get(backend *types.Backend) *backendProtocol {
+	key := poolKey(backend)
+
+	c.mu.RLock()
+	bp := c.backends[key]
+	c.mu.RUnlock()
+	if bp != nil {
+		return bp
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if bp = c.backends[key]; bp == nil {
+		bp = &backendProtocol{
+			transport: newH2Transport(backend),
+			h2:        h2Unknown,
+		}
+		c.backends[key] = bp
+	}
+	return bp
+}
+
+// lookup 获取后端的协议状态，不存在时返回nil
+func (c *ProtocolCache) lookup(backend *types.Backend) *backendProtocol {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.backends[poolKey(backend)]
+}
+
+// Prune 删除不再被任何后端使用的协议状态并关闭其HTTP/2连接
+func (c *ProtocolCache) Prune(backends []*types.Backend) {
+	keep := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		keep[poolKey(backend)] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, bp := range c.backends {
+		if !keep[key] {
+			bp.transport.CloseIdleConnections()
+			delete(c.backends, key)
+		}
+	}
+}
+
+// newH2Transport 创建后端的HTTP/2传输：https后端通过ALPN协商h2，http后端使用h2c（明文HTTP/2）
+// TLS握手没有协商到h2时返回errH2Unsupported
+func newH2Transport(backend *types.Backend) *http2.Transport {
+	useTLS := backend.Scheme == "https"
+	dialer := &net.Dialer{Timeout: 3 * time.Second}
+
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, &dialError{err: err}
+			}
+			if !useTLS {
+				return conn, nil
+			}
+
+			// 同时提供http/1.1，只支持HTTP/1.1的后端也能完成握手，再根据协商结果判断
+			cfg = cfg.Clone()
+			cfg.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+			tlsConn := tls.Client(conn, cfg)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, &dialError{err: err}
+			}
+			if tlsConn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+				tlsConn.Close()
+				return nil, errH2Unsupported
+			}
+			return tlsConn, nil
+		},
+	}
+}
+
+// available 判断是否应该对后端尝试HTTP/2，不支持的后端到达重新尝试时间后恢复为未知
+func (bp *backendProtocol) available(now time.Time) bool {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.h2 == h2Unsupported {
+		if now.Before(bp.recheckAt) {
+			return false
+		}
+		bp.h2 = h2Unknown
+	}
+	return true
+}
+
+// learn 根据HTTP/2请求的结果更新支持状态，返回是否判定为不支持（应回退到下一个协议）
+// 尚未确认支持HTTP/2的后端，除连接失败外的错误都视为不支持；已确认支持的后端出错按普通请求失败处理
+func (bp *backendProtocol) learn(err error, recheck time.Duration) bool {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	if err == nil {
+		bp.h2 = h2Supported
+		bp.lastError = ""
+		bp.protocol = types.UpstreamProtocolH2
+		return false
+	}
+
+	var dialErr *dialError
+	unsupported := errors.Is(err, errH2Unsupported) || (bp.h2 != h2Supported && !errors.As(err, &dialErr))
+	if unsupported {
+		bp.h2 = h2Unsupported
+		bp.recheckAt = time.Now().Add(recheck)
+		bp.lastError = err.Error()
+	}
+	return unsupported
+}
+
+// used 记录最近一次请求使用的协议
+func (bp *backendProtocol) used(protocol string) {
+	bp.mu.Lock()
+	bp.protocol = protocol
+	bp.mu.Unlock()
+}
+
+// status 获取协议状态
+func (bp *backendProtocol) status(chain []string) ProtocolStatus {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	status := ProtocolStatus{
+		Chain:     chain,
+		Protocol:  bp.protocol,
+		H2:        bp.h2,
+		LastError: bp.lastError,
+	}
+	if bp.h2 == h2Unsupported {
+		recheckAt := bp.recheckAt
+		status.RecheckAt = &recheckAt
+	}
+	return status
+}
+
+// proxyWithProtocols 按上游的协议偏好顺序代理请求，handled为false时由调用方使用HTTP/1.1连接池转发
+// 后端不支持HTTP/2时记住该结果并在同一请求内回退到下一个协议
+func (s *Server) proxyWithProtocols(ctx *fasthttp.RequestCtx, upstream *Upstream, backend *types.Backend, timeout time.Duration) (handled bool, err error) {
+	// HTTP/2不支持协议升级（WebSocket）
+	if ctx.Request.Header.ConnectionUpgrade() {
+		return false, nil
+	}
+
+	bp := s.protocols.get(backend)
+	for _, protocol := range upstream.protocols {
+		if protocol == types.UpstreamProtocolHTTP1 {
+			bp.used(protocol)
+			return false, nil
+		}
+		if !bp.available(time.Now()) {
+			continue
+		}
+
+		err := s.proxyH2(ctx, bp.transport, timeout, upstream.loadReports)
+		if !bp.learn(err, upstream.protocolRecheck) {
+			if err != nil {
+				ctx.Error("Bad Gateway", fasthttp.StatusBadGateway)
+			}
+			return true, err
+		}
+		fmt.Printf("[PROTOCOL] Backend %s does not support h2, falling back: %v\n", backend.ID, err)
+	}
+
+	ctx.Error("Bad Gateway (No supported upstream protocol)", fasthttp.StatusBadGateway)
+	return true, fmt.Errorf("backend %s supports none of the upstream protocols", backend.ID)
+}
+
+// proxyH2 通过HTTP/2转发请求，请求已改写为后端地址
+// 请求体读入内存后发送，以便后端不支持HTTP/2时回退到HTTP/1.1重新发送
+// loadReports为true时把trailer中的ORCA负载报告复制到响应头，由调用方读取
+func (s *Server) proxyH2(ctx *fasthttp.RequestCtx, transport *http2.Transport, timeout time.Duration, loadReports bool) error {
+	reqCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	body := ctx.Request.Body()
+	uri := ctx.Request.URI()
+	target := string(uri.Scheme()) + "://" + string(uri.Host()) + string(uri.RequestURI())
+	req, err := http.NewRequestWithContext(reqCtx, string(ctx.Method()), target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	req.Host = string(ctx.Request.Header.Host())
+
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		if isHopHeader(key, value) {
+			return
+		}
+		req.Header.Add(string(key), string(value))
+	})
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(resp.StatusCode)
+	for key, values := range resp.Header {
+		if http.CanonicalHeaderKey(key) == fasthttp.HeaderContentLength {
+			continue
+		}
+		for _, value := range values {
+			ctx.Response.Header.Add(key, value)
+		}
+	}
+	if loadReports {
+		for _, name := range []string{orca.HeaderName, orca.BinaryHeaderName} {
+			if value := resp.Trailer.Get(name); value != "" {
+				ctx.Response.Header.Set(name, value)
+			}
+		}
+	}
+	ctx.Response.SetBody(respBody)
+	return nil
+}
+
+// isHopHeader 判断是否为逐跳请求头，这些头不能通过HTTP/2发送（gRPC使用的TE: trailers除外）
+func isHopHeader(key, value []byte) bool {
+	switch http.CanonicalHeaderKey(string(key)) {
+	case "Host", "Content-Length", "Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade":
+		return true
+	case "Te":
+		return string(value) != "trailers"
+	}
+	return false
+}
+
+// BackendProtocols 获取上游各后端学习到的协议状态，key为后端ID
+func (s *Server) BackendProtocols(upstreamID string) (map[string]ProtocolStatus, error) {
+	upstream := s.upstreamMgr.Load().GetUpstream(upstreamID)
+	if upstream == nil {
+		return nil, fmt.Errorf("upstream %s not found", upstreamID)
+	}
+
+	chain := upstream.protocolChain()
+	result := make(map[string]ProtocolStatus)
+	for _, backend := range upstream.GetAllBackends() {
+		result[backend.ID] = s.protocolStatus(backend, chain)
+	}
+	return result, nil
+}
+
+// protocolChain 上游的协议偏好顺序，未配置时只使用HTTP/1.1
+func (u *Upstream) protocolChain() []string {
+	if len(u.protocols) == 0 {
+		return []string{types.UpstreamProtocolHTTP1}
+	}
+	return u.protocols
+}
+
+// protocolStatus 获取后端学习到的协议状态，尚未学习时为unknown
+func (s *Server) protocolStatus(backend *types.Backend, chain []string) ProtocolStatus {
+	if bp := s.protocols.lookup(backend); bp != nil {
+		return bp.status(chain)
+	}
+	return ProtocolStatus{Chain: chain, H2: h2Unknown}
+}
@@ -1,18 +1,34 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
 
+	"github.com/quqi/speedmimi/internal/accesslog"
+	"github.com/quqi/speedmimi/internal/apikey"
+	"github.com/quqi/speedmimi/internal/artifact"
+	"github.com/quqi/speedmimi/internal/audit"
+	"github.com/quqi/speedmimi/internal/cache"
+	"github.com/quqi/speedmimi/internal/certwatch"
 	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/healthcheck"
 	"github.com/quqi/speedmimi/internal/loadbalancer"
 	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/quota"
+	"github.com/quqi/speedmimi/internal/shedding"
+	"github.com/quqi/speedmimi/internal/signing"
+	"github.com/quqi/speedmimi/internal/tracing"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/internal/webhook"
 	"github.com/quqi/speedmimi/pkg/types"
 )
 
@@ -20,106 +36,259 @@ import (
 type Server struct {
 	config         *config.Manager
 	lbFactory      *loadbalancer.Factory
-	upstreamMgr    *UpstreamManager
+	upstreamMgr    atomic.Pointer[UpstreamManager] // 配置变化时整体替换
+	routes         atomic.Pointer[routeTable]
 	monitor        *monitor.PerformanceMonitor
+	maintenance    *MaintenanceManager
+	standby        *StandbyManager
+	drains         *BackendDrains // 后端排空进度
+	shutdown       *shutdown      // 通过管理API发起的关闭
 	server         *fasthttp.Server
+	listener       *dispatchListener
+	listenerCfg    listenerSettings
+	done           chan struct{}
+	serveErr       chan error
 	tlsConfig      *tls.Config
+	clientAuthTLS  atomic.Pointer[tls.Config]        // 要求客户端证书时握手使用的配置，未启用时为nil
+	earlyReject    *EarlyRejectGuard                 // 请求行预检
+	certs          atomic.Pointer[certwatch.Watcher] // 证书变化时整体替换
+	quiesced       atomic.Bool                       // 静默模式：不接收新连接
+	conns          *ConnTable                        // 当前客户端连接表
+	connClasses    *ConnClassBudget                  // 按连接类别的并发和内存预算
+	mirrors        mirrorCounters                    // 流量镜像统计
+	pathCounters   pathCounters                      // 请求路径规范化统计
+	rangeCounters  rangeCounters                     // 断点续传统计
+	tracer         *Tracer                           // 按路由或后端的限时调试跟踪
+	quota          atomic.Pointer[quota.Manager]     // 未启用时为nil
+	apiKeys        atomic.Pointer[apikey.Store]      // 路由的API密钥认证
+	authClient     *fasthttp.Client                  // 访问外部认证服务
+	artifacts      *artifact.Manager                 // 数据文件热加载
+	rateLimits     *RateLimits                       // 按客户端IP和总请求速率的限速
+	concurrency    *ConcurrencyLimits                // 按路由和上游的并发请求数限制
+	queues         *BackendQueues                    // 所有后端达到连接上限时按上游排队
+	shedding       *shedding.Controller              // 自适应负载卸载
+	clients        *ClientPool                       // 上游连接池
+	protocols      *ProtocolCache                    // 按后端学习到的上游协议支持情况
+	geo            atomic.Pointer[vars.GeoTable]     // $geo查找表，未配置时为nil
+	cache          atomic.Pointer[cache.Cache]       // 响应缓存，未启用时为nil
+	health         *healthcheck.Checker              // 后台健康检查
+	accessLog      atomic.Pointer[accesslog.Logger]  // 访问日志，未启用时为nil
+	webhooks       atomic.Pointer[webhook.Notifier]  // 事件通知，未配置时为nil
+	otel           atomic.Pointer[tracing.Tracer]    // OpenTelemetry分布式跟踪，未启用时为nil
+	quiescedAt     time.Time
+	degradedRoutes sync.Map // 健康后端不足、正在降级处理的路由名称
 	mu             sync.RWMutex
 }
 
 // 高性能上游管理器（预分配和无锁优化）
+// 发布（存入Server）后只读，修改配置时构建新的管理器整体替换
 type UpstreamManager struct {
 	upstreams []*Upstream
 	names     map[string]int // name -> index映射
+	published atomic.Bool    // 已发布，审计模式下检查发布后的修改
 }
 
 type Upstream struct {
-	name     string
-	backends []*types.Backend
-	lbType   types.LoadBalancerType
-	balancer types.LoadBalancer
+	name            string
+	backends        []*types.Backend
+	lbType          types.LoadBalancerType
+	lbParams        types.LoadBalancerParams
+	balancer        types.LoadBalancer
+	factory         *loadbalancer.Factory
+	zone            string         // 代理所在可用区
+	overrides       sync.Map       // balancerKey -> types.LoadBalancer，路由覆盖负载均衡时按需创建
+	signer          signing.Signer // 为nil时不签名
+	signHost        string         // 签名请求使用的Host头，为空时使用后端地址
+	protocols       []string       // 协议偏好顺序，为空时只使用HTTP/1.1
+	protocolRecheck time.Duration  // 后端不支持的协议多久后重新尝试
+	loadReports     bool           // 从后端响应读取ORCA负载报告
+	published       *atomic.Bool   // 所属管理器的发布标记
+}
+
+// balancerKey 负载均衡器缓存键
+type balancerKey struct {
+	lbType types.LoadBalancerType
+	params types.LoadBalancerParams
 }
 
 // NewServer 创建代理服务器
 func NewServer(cfgMgr *config.Manager) (*Server, error) {
 	lbFactory := loadbalancer.NewFactory()
-	upstreamMgr := NewUpstreamManager()
 	perfMonitor := monitor.NewPerformanceMonitor()
 
 	server := &Server{
 		config:      cfgMgr,
 		lbFactory:   lbFactory,
-		upstreamMgr: upstreamMgr,
 		monitor:     perfMonitor,
+		maintenance: NewMaintenanceManager(),
+		standby:     NewStandbyManager(),
+		drains:      NewBackendDrains(),
+		shutdown:    newShutdown(),
+		conns:       NewConnTable(),
+		clients:     NewClientPool(),
+		protocols:   NewProtocolCache(),
+		rateLimits:  NewRateLimits(),
+		concurrency: NewConcurrencyLimits(),
+		queues:      NewBackendQueues(),
+		shedding:    shedding.NewController(),
+		earlyReject: NewEarlyRejectGuard(),
+		connClasses: NewConnClassBudget(),
+		authClient:  newForwardAuthClient(),
+		artifacts:   artifact.NewManager(cfgMgr.GetConfig().Artifacts.WatchInterval),
+		tracer:      NewTracer(func() string { return cfgMgr.GetConfig().DebugTrace.Path }),
 	}
+	server.health = healthcheck.NewChecker(healthcheck.NewProber(), server.healthTargets)
+	server.health.OnTransition(server.onHealthTransition)
+	server.health.SetWorkers(cfgMgr.GetConfig().ControlPlane.HealthWorkers)
 
 	// 初始化上游
 	if err := server.initUpstreams(); err != nil {
 		return nil, fmt.Errorf("failed to init upstreams: %w", err)
 	}
 
-	// 创建高性能fasthttp服务器配置（支持千万级并发）
-	fasthttpServer := &fasthttp.Server{
-		Handler:                       server.handleRequest,
-		ReadTimeout:                   cfgMgr.GetConfig().Server.ReadTimeout,
-		WriteTimeout:                  cfgMgr.GetConfig().Server.WriteTimeout,
-		MaxConnsPerIP:                 0, // 不限制单IP连接数
-		MaxRequestsPerConn:            0, // 不限制单连接请求数
-		MaxKeepaliveDuration:          300 * time.Second, // 增加keepalive时间
-		TCPKeepalive:                  true,
-		TCPKeepalivePeriod:            30 * time.Second, // 减少keepalive周期
-		ReduceMemoryUsage:             false, // 性能优先
-		GetOnly:                       false,
-		DisablePreParseMultipartForm: true,
-		LogAllErrors:                  false,
-		DisableHeaderNamesNormalizing: true,
-		NoDefaultServerHeader:         true,
-		NoDefaultDate:                 true,  // 禁用默认日期头以提高性能
-		NoDefaultContentType:          true,
-		KeepHijackedConns:             false,
-		CloseOnShutdown:               true,
-		StreamRequestBody:             true,
-		MaxRequestBodySize:            4 * 1024 * 1024, // 4MB
-
-		// 高并发优化配置
-		SleepWhenConcurrencyLimitsExceeded: 0,
-		Concurrency:                        10000000, // 支持1000万个并发连接
-
-		// 内存池优化
-		ReadBufferSize:  4096,  // 4KB读取缓冲区
-		WriteBufferSize: 4096,  // 4KB写入缓冲区
-
-		// 连接优化
-		MaxIdleWorkerDuration: 60 * time.Second,
-
-		// 错误处理优化
-		ErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
-			// 静默处理错误，避免日志输出影响性能
-			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-		},
-	}
-
-	server.server = fasthttpServer
+	// 创建高性能fasthttp服务器（支持千万级并发）
+	cfg := cfgMgr.GetConfig()
+	server.quota.Store(quota.NewManager(&cfg.Quota, nil))
+	server.apiKeys.Store(apikey.NewStore(&cfg.APIKeys, nil))
+	server.reloadGeo(&cfg.Geo)
+	server.reloadCache(&cfg.Cache)
+	server.reloadAccessLog(&cfg.AccessLog)
+	server.reloadWebhooks(cfg.Webhooks)
+	server.reloadTracing(&cfg.Tracing)
+	server.earlyReject.Update(&cfg.Server.EarlyReject)
+	server.connClasses.Update(&cfg.Server.ConnectionClasses)
+	server.rateLimits.Update(cfg)
+	server.concurrency.Update(cfg)
+	server.queues.Update(cfg)
+	server.shedding.Update(&cfg.LoadShedding)
+	server.monitor.SetHistory(&cfg.StatsHistory)
+	server.conns.SetSlowClient(&cfg.Server.SlowClient)
+	server.conns.SetClientLimits(&cfg.Server.ClientLimits)
+	server.conns.SetSlowRequest(&cfg.Server.SlowRequest)
+	server.conns.SetConnLifetime(&cfg.Server.ConnectionLifetime)
+	audit.SetEnabled(cfg.Audit.Enabled)
+	server.listenerCfg = newListenerSettings(&cfg.Server, net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)))
+	server.server = newFastHTTPServer(server.serveHTTP, server.listenerCfg)
+	server.done = make(chan struct{})
+	server.serveErr = make(chan error, 1)
 
 	// 监听配置变化
 	go server.watchConfig()
+	go server.runAudit()
+	go server.runStandby()
+	server.health.Start()
+	server.shedding.Start()
+	server.artifacts.Start()
 
 	return server, nil
 }
 
-// Start 启动服务器
+// Start 启动服务器，阻塞直到服务器停止
 func (s *Server) Start() error {
 	cfg := s.config.GetConfig()
-	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
 	if cfg.SSL.Enabled {
 		if err := s.initTLS(); err != nil {
 			return fmt.Errorf("failed to init TLS: %w", err)
 		}
-		return s.server.ListenAndServeTLS(addr, cfg.SSL.CertFile, cfg.SSL.KeyFile)
 	}
 
-	return s.server.ListenAndServe(addr)
+	s.mu.Lock()
+	ln, err := net.Listen("tcp4", s.listenerCfg.addr)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.listener = newDispatchListener(ln, s.conns)
+	s.serveGeneration(s.server, s.listener)
+	go s.listener.run()
+	s.mu.Unlock()
+
+	select {
+	case err := <-s.serveErr:
+		return err
+	case <-s.done:
+		return nil
+	}
+}
+
+// serveGeneration 让fasthttp服务器开始接收dispatcher投递的新连接
+// 调用方需持有s.mu
+func (s *Server) serveGeneration(srv *fasthttp.Server, dispatcher *dispatchListener) {
+	gen := newConnListener(dispatcher.ln.Addr())
+	if old := dispatcher.swap(gen); old != nil {
+		old.Close()
+	}
+
+	var ln net.Listener = gen
+	if s.tlsConfig != nil {
+		// 证书通过GetCertificate动态获取，热加载后新握手立即使用新证书
+		ln = tls.NewListener(gen, s.tlsConfig)
+	}
+	// 请求行预检在TLS解密之后进行
+	ln = &guardedListener{Listener: ln, guard: s.earlyReject}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			select {
+			case s.serveErr <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// reloadListener 平滑重载监听器级别配置：创建新服务器并切换新连接，旧服务器在后台排空
+// 调用方需持有s.mu
+func (s *Server) reloadListener(settings listenerSettings) error {
+	if s.quiesced.Load() {
+		// 静默期间旧服务器仍在排空已有连接，只记录新设置，Resume时按新设置创建服务器
+		s.listenerCfg = settings
+		return nil
+	}
+	if s.listener == nil {
+		// 尚未启动，直接替换服务器
+		s.server = newFastHTTPServer(s.serveHTTP, settings)
+		s.listenerCfg = settings
+		return nil
+	}
+
+	dispatcher := s.listener
+	if settings.addr != s.listenerCfg.addr {
+		// 监听地址变化，先绑定新地址，失败时保持旧监听器不变
+		ln, err := net.Listen("tcp4", settings.addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", settings.addr, err)
+		}
+		dispatcher = newDispatchListener(ln, s.conns)
+	}
+
+	oldServer := s.server
+	oldListener := s.listener
+
+	newServer := newFastHTTPServer(s.serveHTTP, settings)
+	s.serveGeneration(newServer, dispatcher)
+	if dispatcher != oldListener {
+		go dispatcher.run()
+		oldListener.swap(nil)
+		oldListener.Close()
+	}
+
+	s.server = newServer
+	s.listener = dispatcher
+	s.listenerCfg = settings
+
+	// 旧服务器不再接收新连接，在后台等待已有连接处理完成
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), listenerDrainTimeout)
+		defer cancel()
+		if err := oldServer.ShutdownWithContext(ctx); err != nil {
+			fmt.Printf("[LISTENER] Old server drain finished with error: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("[LISTENER] Listener reloaded on %s\n", settings.addr)
+	return nil
 }
 
 // Stop 停止服务器
@@ -127,9 +296,44 @@ func (s *Server) Stop() error {
 	if s.monitor != nil {
 		s.monitor.Stop()
 	}
+	s.health.Stop()
+	s.shedding.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+
+	if s.listener != nil {
+		s.listener.swap(nil)
+		s.listener.Close()
+	}
+	if watcher := s.certs.Swap(nil); watcher != nil {
+		watcher.Stop()
+	}
+	s.artifacts.Stop()
+	if logger := s.accessLog.Swap(nil); logger != nil {
+		logger.Close()
+	}
+	if notifier := s.webhooks.Swap(nil); notifier != nil {
+		notifier.Close()
+	}
+	if tracer := s.otel.Swap(nil); tracer != nil {
+		tracer.Close()
+	}
+	s.tracer.Close()
 	return s.server.Shutdown()
 }
 
+// GetArtifacts 获取数据文件管理器
+func (s *Server) GetArtifacts() *artifact.Manager {
+	return s.artifacts
+}
+
 // GetMonitor 获取性能监控器
 func (s *Server) GetMonitor() *monitor.PerformanceMonitor {
 	return s.monitor
@@ -137,17 +341,18 @@ func (s *Server) GetMonitor() *monitor.PerformanceMonitor {
 
 // DisconnectBackend 异步断开后端连接（标记机制）
 func (s *Server) DisconnectBackend(upstreamID, backendID string) error {
-	upstream := s.upstreamMgr.GetUpstream(upstreamID)
+	upstream := s.upstreamMgr.Load().GetUpstream(upstreamID)
 	if upstream == nil {
 		return fmt.Errorf("upstream %s not found", upstreamID)
 	}
 
-	backends := upstream.GetBackends()
+	backends := upstream.GetAllBackends()
 	for _, backend := range backends {
 		if backend.ID == backendID {
 			// 标记后端为断开状态
 			backend.MarkForDisconnect()
 			fmt.Printf("[DISCONNECT] Backend %s/%s marked for disconnection\n", upstreamID, backendID)
+			s.notifyDisconnect(upstreamID, backend, "disconnect requested")
 			return nil
 		}
 	}
@@ -155,9 +360,54 @@ func (s *Server) DisconnectBackend(upstreamID, backendID string) error {
 	return fmt.Errorf("backend %s not found in upstream %s", backendID, upstreamID)
 }
 
+// GetMaintenance 获取维护模式管理器
+func (s *Server) GetMaintenance() *MaintenanceManager {
+	return s.maintenance
+}
+
+// GetConnTable 获取客户端连接表
+func (s *Server) GetConnTable() *ConnTable {
+	return s.conns
+}
+
+// GetEarlyReject 获取请求行预检
+func (s *Server) GetEarlyReject() *EarlyRejectGuard {
+	return s.earlyReject
+}
+
+// GetConnClasses 获取连接类别预算
+func (s *Server) GetConnClasses() *ConnClassBudget {
+	return s.connClasses
+}
+
+// GetLoadShedding 获取负载卸载控制器
+func (s *Server) GetLoadShedding() *shedding.Controller {
+	return s.shedding
+}
+
+// GetBackendQueues 获取等待后端连接的队列集合
+func (s *Server) GetBackendQueues() *BackendQueues {
+	return s.queues
+}
+
+// GetConcurrencyLimits 获取并发限制器集合
+func (s *Server) GetConcurrencyLimits() *ConcurrencyLimits {
+	return s.concurrency
+}
+
+// GetRateLimits 获取限速器集合
+func (s *Server) GetRateLimits() *RateLimits {
+	return s.rateLimits
+}
+
+// GetStandby 获取冷备上游切换管理器
+func (s *Server) GetStandby() *StandbyManager {
+	return s.standby
+}
+
 // GetUpstreamManager 获取上游管理器（用于调试）
 func (s *Server) GetUpstreamManager() *UpstreamManager {
-	return s.upstreamMgr
+	return s.upstreamMgr.Load()
 }
 
 // handleRequest 处理请求
@@ -165,107 +415,434 @@ func (s *Server) handleRequest(ctx *fasthttp.RequestCtx) {
 	// 轻量级性能监控记录（非阻塞）
 	s.monitor.StartConnection()
 
+	// 安全响应头在结束时设置，包括代理生成的错误响应；匹配路由后使用路由的配置
+	security := s.currentRoutes().security
+	span := s.startSpan(ctx)
+
 	// 使用defer确保连接结束被记录
 	defer func() {
+		// 静默模式下处理完当前请求后关闭连接
+		// 在请求结束时设置，转发和ctx.Error会重置响应，提前设置的Connection: close会丢失
+		if s.quiesced.Load() {
+			ctx.SetConnectionClose()
+		}
+		s.checkConnLifetime(ctx)
+		security.apply(ctx)
+		s.tracer.finish(ctx)
+		s.endSpan(ctx, span)
+		s.logAccess(ctx)
+		recordPendingResponse(ctx)
+
 		// 记录请求完成（异步，非阻塞）
 		if s.monitor != nil {
-			bytesSent := int64(len(ctx.Response.Body()))
+			bytesSent := vars.ResponseBodySize(&ctx.Response)
 			bytesRecv := int64(len(ctx.Request.Body()))
 			s.monitor.RecordRequest(bytesSent, bytesRecv)
+			route, _ := ctx.UserValue(userValueRoute).(string)
+			upstream, _ := ctx.UserValue(userValueUpstream).(string)
+			backend, _ := ctx.UserValue(userValueBackend).(string)
+			s.monitor.RecordTraffic(route, upstream, backend, ctx.Response.StatusCode(), bytesRecv, bytesSent)
 			s.monitor.EndConnection()
 		}
 	}()
 
+	// 按连接类别（普通请求/流式连接）占用并发和内存预算
+	lease, ok := s.acquireConnClass(ctx)
+	if !ok {
+		return
+	}
+	defer lease.release()
+
+	// 单个客户端IP的请求速率限制
+	if !s.checkClientRateLimit(ctx) {
+		return
+	}
+
+	// 整个代理的总请求速率限制
+	if !checkTotalRateLimit(ctx, s.rateLimits.globalLimiter(), "Global") {
+		return
+	}
+
+	// 规范化请求路径，路由匹配和转发使用同一个路径
+	if !s.normalizeRequestPath(ctx) {
+		return
+	}
+
 	// 获取路由规则
-	rule := s.findRoutingRule(string(ctx.Path()))
-	if rule == nil {
+	entry := s.findRoutingRule(ctx)
+	if entry == nil {
 		ctx.Error("Not Found", fasthttp.StatusNotFound)
 		return
 	}
+	routeName, rule := entry.name, entry.rule
+	security = entry.securityHeaders
+	entry.inflight.Add(1)
+	defer entry.inflight.Add(-1)
+	if s.monitor != nil {
+		s.monitor.RouteActive(routeName, 1)
+		defer s.monitor.RouteActive(routeName, -1)
+	}
 
-	// 获取上游
-	upstream := s.upstreamMgr.GetUpstream(rule.Upstream)
-	if upstream == nil {
-		ctx.Error("Service Unavailable", fasthttp.StatusServiceUnavailable)
+	ctx.SetUserValue(userValueRoute, routeName)
+	recordConnRequest(ctx, routeName)
+	defer recordConnDone(ctx)
+	s.tracer.begin(ctx, routeName)
+
+	// 路由处于维护模式时直接返回维护页面
+	if state := s.maintenance.Route(routeName); state != nil {
+		writeMaintenanceResponse(ctx, state)
+		return
+	}
+
+	// 自适应负载卸载，过载时优先拒绝低优先级路由的请求
+	if !s.shedding.Admit(rule.Priority) {
+		ctx.Error("Service Unavailable (Overloaded)", fasthttp.StatusServiceUnavailable)
+		ctx.Response.Header.Set("Retry-After", "1")
+		return
+	}
+	defer s.shedding.Done(time.Now())
+
+	// 代理直接响应CORS预检请求，实际请求的响应（包括错误响应）在结束时设置CORS头
+	if entry.cors != nil {
+		if isPreflight(ctx) {
+			entry.cors.servePreflight(ctx)
+			return
+		}
+		defer entry.cors.apply(ctx)
+	}
+
+	// 按User-Agent和机器人评分过滤请求
+	if entry.botFilter != nil && !s.checkBotFilter(ctx, entry.botFilter) {
+		return
+	}
+
+	// 路由限制客户端证书的SPIFFE ID
+	if !checkClientIdentity(ctx, rule) {
 		return
 	}
 
-	// 获取后端列表
-	backends := upstream.GetBackends()
-	if len(backends) == 0 {
-		ctx.Error("Service Unavailable", fasthttp.StatusServiceUnavailable)
+	// 路由要求有效的API密钥
+	if rule.APIKeyAuth && !s.checkAPIKey(ctx, routeName) {
 		return
 	}
 
-	// 确定负载均衡类型
+	// 外部认证服务认证请求
+	if rule.ForwardAuth != nil && !s.checkForwardAuth(ctx, rule.ForwardAuth) {
+		return
+	}
+
+	// 按客户端IP限速
+	if !s.checkRateLimit(ctx, routeName) {
+		return
+	}
+
+	// 路由的总请求速率限制，避免单个路由占满代理和上游的容量
+	if !checkTotalRateLimit(ctx, s.rateLimits.total(routeName), "Route") {
+		return
+	}
+
+	// 路由同时处理的请求数上限
+	limiter, ok := acquireConcurrency(ctx, s.concurrency.route(routeName), "Route")
+	if !ok {
+		return
+	}
+	if limiter != nil {
+		defer limiter.Release()
+	}
+
+	// 需要读取请求体的过滤器按路由的限制缓冲请求体
+	if rule.BodyInspection != nil {
+		ctx.SetUserValue(userValueBodyInspection, rule.BodyInspection)
+	}
+
+	// API密钥配额检查，请求结束后记录用量
+	account, ok := s.checkQuota(ctx)
+	if !ok {
+		return
+	}
+	if account != nil {
+		defer s.finishQuota(ctx, account)
+	}
+
+	// 扇出路由并行转发到多个上游并组合响应
+	if entry.fanOut != nil {
+		s.serveFanOut(ctx, entry)
+		s.applyResponseHeaders(ctx, entry)
+		return
+	}
+
+	// 命中响应缓存时不再选择后端
+	cached, served := s.lookupCache(ctx, rule)
+	if served {
+		s.applyResponseHeaders(ctx, entry)
+		return
+	}
+
+	// 确定路由指定的负载均衡类型，为空时使用各上游的默认负载均衡
 	lbType := s.determineLBType(rule, ctx)
-	balancer := s.lbFactory.GetBalancer(lbType)
-	if balancer == nil {
-		balancer = s.lbFactory.GetBalancer(types.LeastConnectionsWeight)
+
+	// 负载均衡器使用的请求信息
+	req := s.newRequestContext(ctx)
+
+	// 签名路由令牌可以覆盖上游/后端选择
+	claims, err := s.parseRoutingToken(ctx, &s.config.GetConfig().RoutingToken)
+	if err != nil {
+		ctx.Error("Forbidden ("+err.Error()+")", fasthttp.StatusForbidden)
+		return
+	}
+	if claims != nil {
+		upstream, backend := s.selectOverrideBackend(claims, rule, lbType, req)
+		if backend == nil {
+			ctx.Error("Service Unavailable (Routing token target unavailable)", fasthttp.StatusServiceUnavailable)
+			return
+		}
+		s.forward(ctx, entry, upstream, backend, cached, nil)
+		return
 	}
 
-	// 选择后端
-	backend := balancer.SelectBackend(backends, ctx)
+	// 主上游健康后端不足时不再把流量压到剩余的后端上，按路由配置降级
+	if rule.HealthRequirement != nil && !s.healthRequirementMet(routeName, rule) {
+		s.serveHealthFallback(ctx, entry, cached, lbType, req)
+		return
+	}
+
+	// 按权重分流时先尝试客户端分到的上游
+	var preferred string
+	if entry.split != nil {
+		preferred = entry.split.choose(s.varEnv(ctx))
+	}
+
+	// 选择后端（主上游不可用时依次尝试备用上游）
+	result := s.selectBackend(routeName, rule, preferred, lbType, req, nil)
+	if result.backend == nil && result.limited {
+		var ok bool
+		result, ok = s.waitForBackend(ctx, result, func() selectResult {
+			return s.selectBackend(routeName, rule, preferred, lbType, req, nil)
+		})
+		if !ok {
+			return
+		}
+	}
+	backend := result.backend
 	if backend == nil {
-		ctx.Error("Service Unavailable (All backends at connection limit)", fasthttp.StatusServiceUnavailable)
+		switch {
+		case result.maintenance != nil:
+			writeMaintenanceResponse(ctx, result.maintenance)
+		case result.limited:
+			ctx.Error("Service Unavailable (All backends at connection limit)", fasthttp.StatusServiceUnavailable)
+		default:
+			ctx.Error("Service Unavailable", fasthttp.StatusServiceUnavailable)
+		}
 		return
 	}
 
-	// 代理请求
-	s.proxyRequest(ctx, backend)
+	// 代理请求，重试时在未尝试过的后端中重新选择
+	s.forward(ctx, entry, result.upstream, backend, cached, func(tried map[*types.Backend]bool) (*Upstream, *types.Backend) {
+		result := s.selectBackend(routeName, rule, preferred, lbType, req, tried)
+		return result.upstream, result.backend
+	})
+}
+
+// selectResult 后端选择结果
+type selectResult struct {
+	upstream    *Upstream
+	backend     *types.Backend
+	limited     bool              // 是否有上游因所有后端达到连接限制而被跳过
+	limitedName string            // 第一个因所有后端达到连接限制而被跳过的上游
+	maintenance *MaintenanceState // 第一个因维护模式而被跳过的上游
 }
 
-// proxyRequest 代理请求到后端
-func (s *Server) proxyRequest(ctx *fasthttp.RequestCtx, backend *types.Backend) {
-	// 增加连接数
+// selectBackend 按主上游和fallback_upstreams的顺序选择后端
+// preferred为分流选中的上游，与主上游不同时最先尝试；处于维护模式的上游和exclude中的后端会被跳过
+func (s *Server) selectBackend(routeName string, rule *types.RoutingRule, preferred string, lbType types.LoadBalancerType, req types.RequestContext, exclude map[*types.Backend]bool) selectResult {
+	var result selectResult
+
+	tr := traceOfRequest(req)
+	upstreamMgr := s.upstreamMgr.Load()
+	trySelect := func(name string) *types.Backend {
+		if state := s.maintenance.Upstream(name); state != nil {
+			if result.maintenance == nil {
+				result.maintenance = state
+			}
+			tr.event("upstream %s skipped: maintenance", name)
+			return nil
+		}
+
+		upstream := upstreamMgr.GetUpstream(name)
+		if upstream == nil {
+			tr.event("upstream %s skipped: not found", name)
+			return nil
+		}
+
+		groups := excludeBackends(selectSubset(upstream.GetBackendGroups(), rule.BackendSelector), exclude)
+		if len(groups) == 0 {
+			tr.event("upstream %s skipped: no active backends (selector %v, %d excluded)", name, rule.BackendSelector, len(exclude))
+			return nil
+		}
+
+		balancer := upstream.Balancer(lbType, rule.LoadBalancerParams)
+		backend := selectByPriority(groups, balancer, req)
+		if backend == nil {
+			if !result.limited {
+				result.limitedName = name
+			}
+			result.limited = true
+			tr.event("upstream %s skipped: all backends at connection limit", name)
+		} else {
+			result.upstream = upstream
+			tr.event("upstream %s selected backend %s (%s, priority %d, weight %d, %d connections, %d candidates in %d groups)",
+				name, backend.ID, effectiveLBType(upstream, lbType), backend.Priority, backend.Weight, backend.GetConnections(), countBackends(groups), len(groups))
+		}
+		return backend
+	}
+
+	// 分流到其他上游的客户端，该上游不可用时再使用主上游
+	if preferred != "" && preferred != rule.Upstream {
+		tr.event("traffic split chose upstream %s", preferred)
+		if result.backend = trySelect(preferred); result.backend != nil {
+			return result
+		}
+	}
+
+	// 主上游健康比例过低时使用冷备上游
+	primary := s.standby.Resolve(routeName, rule, upstreamMgr)
+	if primary != rule.Upstream {
+		tr.event("standby upstream %s active in place of %s", primary, rule.Upstream)
+	}
+	if result.backend = trySelect(primary); result.backend != nil {
+		return result
+	}
+
+	for _, name := range rule.FallbackUpstreams {
+		if result.backend = trySelect(name); result.backend != nil {
+			return result
+		}
+	}
+
+	return result
+}
+
+// proxyRequest 代理请求到后端，请求未能得到后端响应时返回错误（此时已写入502响应，长轮询超时时为timeout_status）
+func (s *Server) proxyRequest(ctx *fasthttp.RequestCtx, upstream *Upstream, backend *types.Backend) (err error) {
+	upstreamName := ""
+	if upstream != nil {
+		upstreamName = upstream.name
+	}
+	recordConnBackend(ctx, upstreamName, backend.ID)
+
+	// 增加连接数，释放连接后唤醒等待该上游后端的请求
+	if upstream != nil {
+		defer s.queues.release(upstream.name)
+	}
 	backend.IncConnections()
 	defer backend.DecConnections()
-
-	// 构建后端URL
-	_ = fmt.Sprintf("%s://%s:%d", backend.Scheme, backend.Host, backend.Port)
+	if upstream != nil && s.monitor != nil {
+		s.monitor.BackendActive(upstream.name, backend.ID, 1)
+		defer s.monitor.BackendActive(upstream.name, backend.ID, -1)
+	}
 
 	// 设置请求头
 	s.setProxyHeaders(ctx, backend)
+	injectTraceContext(ctx)
+
+	// 长轮询路由延长等待后端响应的时间，并告知后端代理会等待多久
+	timeout := upstreamTimeout
+	longPoll := s.longPollConfig(ctx)
+	if longPoll != nil {
+		timeout = longPoll.Timeout
+		signalLongPoll(ctx, longPoll)
+	}
+	ranges, _ := ctx.UserValue(userValueRange).(*rangeRequest)
+	if ranges != nil {
+		timeout = ranges.cfg.Timeout
+	}
 
-	// 创建高性能代理客户端（支持千万级并发）
-	client := &fasthttp.Client{
-		// 基础超时设置
-		ReadTimeout:              30 * time.Second,
-		WriteTimeout:             30 * time.Second,
-		MaxConnDuration:          300 * time.Second, // 增加连接持续时间
-		MaxConnWaitTimeout:       10 * time.Second,  // 减少等待超时
-		MaxIdleConnDuration:      120 * time.Second, // 增加空闲连接时间
-
-		// 高并发优化
-		MaxConnsPerHost:     100000, // 每个主机最大连接数
-		ReadBufferSize:      8192,   // 8KB读取缓冲区
-		WriteBufferSize:     8192,   // 8KB写入缓冲区
-
-		// 连接优化
-		DisableHeaderNamesNormalizing: true,
-		DisablePathNormalizing:        true,
-		NoDefaultUserAgentHeader:      true,
-
-		// 自定义拨号函数（高性能）
-		Dial: func(addr string) (net.Conn, error) {
-			return fasthttp.DialDualStackTimeout(addr, 3*time.Second)
-		},
-
-		// 连接重试策略
-		RetryIf: func(req *fasthttp.Request) bool {
-			// 只对GET请求重试，避免副作用
-			return string(req.Header.Method()) == "GET"
-		},
-		MaxIdemponentCallAttempts: 2, // 最多重试2次
+	// 连接到选中的后端，默认保留客户端的Host头
+	scheme := backend.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	ctx.Request.URI().SetScheme(scheme)
+	ctx.Request.URI().SetHost(net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)))
+	ctx.Request.UseHostHeader = true
+
+	// 签名上游使用后端自己的Host头，签名放在最后以覆盖所有已确定的请求内容
+	// 请求体超过路由的检查上限时按配置拒绝，或不对请求体签名
+	if upstream != nil && upstream.signer != nil {
+		signPayload := upstream.signer.SignsPayload()
+		if signPayload {
+			var ok bool
+			if signPayload, ok = inspectBody(ctx); !ok {
+				return nil // 请求被拒绝，不是后端失败，不触发重试
+			}
+		}
+		ctx.Request.Header.SetHost(upstream.signingHost(backend))
+		if err := upstream.signer.Sign(&ctx.Request, time.Now(), signPayload); err != nil {
+			ctx.Error("Bad Gateway (Request signing failed)", fasthttp.StatusBadGateway)
+			return err
+		}
 	}
 
 	// 执行代理
 	req := &ctx.Request
 	resp := &ctx.Response
 
-	if err := client.Do(req, resp); err != nil {
+	// 调试跟踪记录实际发往后端的请求头和本次尝试的结果
+	if tr := traceOf(ctx); tr != nil {
+		tr.upstreamRequest(backend, req)
+		defer func(start time.Time) { tr.upstreamResult(backend, resp, err, time.Since(start)) }(time.Now())
+	}
+
+	// 长轮询请求的耗时取决于事件何时发生而不是后端性能，不计入后端延迟
+	start := time.Now()
+	if upstream != nil && len(upstream.protocols) > 0 {
+		if handled, err := s.proxyWithProtocols(ctx, upstream, backend, timeout); handled {
+			if err != nil && (longPoll == nil || !isTimeout(err)) {
+				s.recordUpstreamError(upstream, backend, err)
+			}
+			if err == nil && longPoll == nil {
+				s.recordLatency(upstream, backend, time.Since(start))
+			}
+			if err == nil && upstream.loadReports {
+				ingestLoadReport(&ctx.Response, upstream.name, backend)
+			}
+			if err != nil && longPoll != nil && isTimeout(err) {
+				return longPollTimedOut(ctx, longPoll)
+			}
+			return err
+		}
+	}
+
+	// 复用后端连接池（支持千万级并发），断点续传路由使用以流方式读取响应体的连接池
+	client := s.clients.Get(backend)
+	if ranges != nil {
+		client = s.clients.Stream(backend)
+	}
+	if err := client.DoTimeout(req, resp, timeout); err != nil {
+		if longPoll != nil && isTimeout(err) {
+			return longPollTimedOut(ctx, longPoll)
+		}
+		s.recordUpstreamError(upstream, backend, err)
 		ctx.Error("Bad Gateway", fasthttp.StatusBadGateway)
-		return
+		return err
+	}
+	if ranges != nil {
+		closeStreamedConn(resp)
+	}
+	if longPoll == nil {
+		s.recordLatency(upstream, backend, time.Since(start))
+	}
+	if upstream != nil && upstream.loadReports {
+		ingestLoadReport(resp, upstream.name, backend)
+	}
+	return nil
+}
+
+// recordLatency 记录后端响应时间，用于延迟感知负载均衡和每个后端的响应时间直方图
+func (s *Server) recordLatency(upstream *Upstream, backend *types.Backend, d time.Duration) {
+	backend.RecordLatency(d)
+	if upstream != nil && s.monitor != nil {
+		s.monitor.RecordBackendLatency(upstream.name, backend.ID, d)
 	}
 }
 
@@ -273,6 +850,14 @@ func (s *Server) proxyRequest(ctx *fasthttp.RequestCtx, backend *types.Backend)
 func (s *Server) setProxyHeaders(ctx *fasthttp.RequestCtx, backend *types.Backend) {
 	cfg := s.config.GetConfig()
 
+	// 隐私模式：不向上游透露客户端IP和连接信息
+	if stripForwarded(ctx, cfg) {
+		stripClientHeaders(&ctx.Request.Header, &cfg.Server)
+		ctx.Request.Header.Set("X-Forwarded-Proto", s.getProto(ctx))
+		ctx.Request.Header.Set("X-Forwarded-Host", string(ctx.Host()))
+		return
+	}
+
 	// 添加或更新X-Forwarded-For
 	clientIP := s.getClientIP(ctx)
 	if existing := ctx.Request.Header.Peek("X-Forwarded-For"); len(existing) > 0 {
@@ -289,6 +874,39 @@ func (s *Server) setProxyHeaders(ctx *fasthttp.RequestCtx, backend *types.Backen
 	// 添加其他代理头
 	ctx.Request.Header.Set("X-Forwarded-Proto", s.getProto(ctx))
 	ctx.Request.Header.Set("X-Forwarded-Host", string(ctx.Host()))
+
+	// 透传客户端连接元数据
+	if cfg.Server.ConnectionMetadata.Enabled {
+		s.setConnectionMetadataHeaders(ctx, &cfg.Server.ConnectionMetadata)
+	}
+
+	// 透传客户端证书身份
+	if cfg.Server.ClientIdentity.Enabled {
+		s.setClientIdentityHeaders(ctx, &cfg.Server.ClientIdentity)
+	}
+}
+
+// setConnectionMetadataHeaders 设置客户端连接元数据请求头
+// 先删除客户端传入的同名请求头，避免伪造
+func (s *Server) setConnectionMetadataHeaders(ctx *fasthttp.RequestCtx, meta *types.ConnectionMetadataConfig) {
+	// 请求头名称未规范化，需忽略大小写删除客户端传入的各种写法
+	for _, name := range []string{meta.TLSVersionHeader, meta.TLSCipherHeader, meta.ALPNHeader, meta.ClientPortHeader, meta.ConnectionIDHeader} {
+		vars.DelHeader(&ctx.Request.Header, name)
+	}
+
+	if state := ctx.TLSConnectionState(); state != nil {
+		ctx.Request.Header.Set(meta.TLSVersionHeader, tls.VersionName(state.Version))
+		ctx.Request.Header.Set(meta.TLSCipherHeader, tls.CipherSuiteName(state.CipherSuite))
+		if state.NegotiatedProtocol != "" {
+			ctx.Request.Header.Set(meta.ALPNHeader, state.NegotiatedProtocol)
+		}
+	}
+
+	if addr, ok := ctx.RemoteAddr().(*net.TCPAddr); ok {
+		ctx.Request.Header.Set(meta.ClientPortHeader, strconv.Itoa(addr.Port))
+	}
+
+	ctx.Request.Header.Set(meta.ConnectionIDHeader, strconv.FormatUint(ctx.ConnID(), 10))
 }
 
 // getClientIP 获取客户端真实IP
@@ -318,6 +936,33 @@ func (s *Server) getClientIP(ctx *fasthttp.RequestCtx) string {
 	return ctx.RemoteIP().String()
 }
 
+// peerClientIP 限速使用的客户端IP：默认为TCP对端地址，对端是trusted_proxies中的代理时才采用real_ip_header
+// 或X-Forwarded-For中从右往左第一个不是可信代理的地址，客户端无法通过伪造请求头得到新的限速键
+func (s *Server) peerClientIP(ctx *fasthttp.RequestCtx) string {
+	peer := ctx.RemoteIP().String()
+	trusted := s.config.GetConfig().Server.TrustedProxies
+	if !loadbalancer.IsTrustedProxy(peer, trusted) {
+		return peer
+	}
+
+	if header := s.config.GetConfig().Server.RealIPHeader; header != "" {
+		if ip := net.ParseIP(strings.TrimSpace(string(vars.PeekHeader(&ctx.Request.Header, header)))); ip != nil {
+			return ip.String()
+		}
+	}
+	hops := strings.Split(string(vars.PeekHeader(&ctx.Request.Header, "X-Forwarded-For")), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !loadbalancer.IsTrustedProxy(ip.String(), trusted) {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
 // getProto 获取协议
 func (s *Server) getProto(ctx *fasthttp.RequestCtx) string {
 	if ctx.IsTLS() {
@@ -326,23 +971,74 @@ func (s *Server) getProto(ctx *fasthttp.RequestCtx) string {
 	return "http"
 }
 
-// findRoutingRule 查找路由规则
-func (s *Server) findRoutingRule(path string) *types.RoutingRule {
+// findRoutingRule 查找路由规则（最长前缀匹配，带条件的规则还需满足条件）
+func (s *Server) findRoutingRule(ctx *fasthttp.RequestCtx) *routeEntry {
+	table := s.currentRoutes()
+	if !table.conditional {
+		return table.lookup(string(ctx.Path()), nil)
+	}
+	return table.lookup(string(ctx.Path()), func() *vars.Env {
+		return s.varEnv(ctx)
+	})
+}
+
+// currentRoutes 获取当前配置的路由表，配置变化后按需重建
+func (s *Server) currentRoutes() *routeTable {
 	cfg := s.config.GetConfig()
+	table := s.routes.Load()
+	if table == nil || table.config != cfg {
+		table = newRouteTable(cfg)
+		s.routes.Store(table)
+	}
+	return table
+}
 
-	// 简单的路径匹配，可以优化为更高效的实现
-	for _, rule := range cfg.Routing {
-		if strings.HasPrefix(path, rule.Path) {
-			return rule
-		}
+// reloadCache 启用、调整或关闭响应缓存，调整容量时保留已缓存的响应
+func (s *Server) reloadCache(cfg *types.ResponseCacheConfig) {
+	if !cfg.Enabled {
+		s.cache.Store(nil)
+		return
 	}
+	if c := s.cache.Load(); c != nil {
+		c.Resize(cfg.MaxSize, cfg.MaxEntrySize)
+		return
+	}
+	s.cache.Store(cache.New(cfg.MaxSize, cfg.MaxEntrySize))
+}
 
-	// 返回默认规则
-	if defaultRule, exists := cfg.Routing["default"]; exists {
-		return defaultRule
+// artifactGeo geo.file在数据文件管理器中的名称
+const artifactGeo = "geo"
+
+// reloadGeo 更新$geo查找表
+// 配置了网段文件时由数据文件管理器加载，文件变化时与配置中的networks合并后替换查找表
+func (s *Server) reloadGeo(cfg *types.GeoConfig) {
+	if cfg.File == "" {
+		s.artifacts.Unregister(artifactGeo)
+		geo, err := vars.NewGeoTable(cfg)
+		if err != nil {
+			fmt.Printf("[CONFIG] Invalid geo config: %v\n", err)
+			return
+		}
+		s.geo.Store(geo)
+		return
 	}
 
-	return nil
+	base := *cfg
+	parse := func(data []byte) (interface{}, error) {
+		networks, err := vars.ParseGeoFile(data)
+		if err != nil {
+			return nil, err
+		}
+		merged := base
+		merged.Networks = append(append([]types.GeoNetwork(nil), base.Networks...), networks...)
+		return vars.NewGeoTable(&merged)
+	}
+	store := func(value interface{}) {
+		s.geo.Store(value.(*vars.GeoTable))
+	}
+	if err := s.artifacts.Register(artifactGeo, cfg.File, parse, store); err != nil {
+		fmt.Printf("[CONFIG] Failed to load geo file %s: %v\n", cfg.File, err)
+	}
 }
 
 // determineLBType 确定负载均衡类型
@@ -378,8 +1074,11 @@ func (s *Server) detectProtocol(ctx *fasthttp.RequestCtx) types.ProtocolType {
 }
 
 // initUpstreams 初始化上游
+// 每次根据当前配置构建新的上游管理器并整体替换，正在处理的请求继续使用旧的上游管理器
 func (s *Server) initUpstreams() error {
 	cfg := s.config.GetConfig()
+	upstreamMgr := NewUpstreamManager()
+	s.lbFactory.SetReportTTL(cfg.Performance.ReportTTL)
 
 	for name, backends := range cfg.Backends {
 		// 确保backend的原子字段与配置字段同步
@@ -391,15 +1090,59 @@ func (s *Server) initUpstreams() error {
 			}
 		}
 
-		upstream, err := s.upstreamMgr.CreateUpstream(name, backends)
+		upstream, err := upstreamMgr.CreateUpstream(name, backends)
 		if err != nil {
 			return fmt.Errorf("failed to create upstream %s: %w", name, err)
 		}
 
-		// 设置默认负载均衡器
-		upstream.SetLoadBalancer(types.LeastConnectionsWeight, s.lbFactory)
+		// 设置上游默认负载均衡器，未配置时使用最少连接数+权重
+		upstreamCfg := cfg.Upstreams[name]
+		lbType := types.LeastConnectionsWeight
+		var lbParams types.LoadBalancerParams
+		if upstreamCfg != nil {
+			if upstreamCfg.LoadBalancer != "" {
+				lbType = upstreamCfg.LoadBalancer
+			}
+			lbParams = upstreamCfg.LoadBalancerParams
+		}
+		upstream.zone = cfg.Server.Zone
+		upstream.SetLoadBalancer(lbType, lbParams, s.lbFactory)
+
+		// 设置上游请求签名
+		if upstreamCfg != nil && upstreamCfg.Signing != nil {
+			signer, err := signing.New(upstreamCfg.Signing)
+			if err != nil {
+				return fmt.Errorf("failed to create signer for upstream %s: %w", name, err)
+			}
+			upstream.signer = signer
+			upstream.signHost = upstreamCfg.Signing.Host
+		}
+
+		upstream.loadReports = upstreamCfg != nil && upstreamCfg.LoadReports == types.LoadReportsORCA
+
+		// 设置上游协议偏好顺序，只有http/1.1时无需学习
+		if upstreamCfg != nil && !(len(upstreamCfg.Protocols) == 1 && upstreamCfg.Protocols[0] == types.UpstreamProtocolHTTP1) {
+			upstream.protocols = upstreamCfg.Protocols
+			upstream.protocolRecheck = upstreamCfg.ProtocolRecheck
+		}
+	}
+
+	upstreamMgr.publish()
+	s.upstreamMgr.Store(upstreamMgr)
+
+	// 关闭已删除后端的连接池
+	var backends []*types.Backend
+	for _, upstream := range upstreamMgr.Upstreams() {
+		backends = append(backends, upstream.GetAllBackends()...)
 	}
+	s.clients.Prune(backends)
+	s.protocols.Prune(backends)
 
+	// 把健康检查状态应用到新的后端对象
+	if s.health != nil {
+		s.health.Sync()
+	}
+	s.refreshStandby()
 	return nil
 }
 
@@ -407,26 +1150,68 @@ func (s *Server) initUpstreams() error {
 func (s *Server) initTLS() error {
 	cfg := s.config.GetConfig()
 
-	cert, err := tls.LoadX509KeyPair(cfg.SSL.CertFile, cfg.SSL.KeyFile)
-	if err != nil {
-		return fmt.Errorf("failed to load TLS cert: %w", err)
+	if err := s.reloadCertWatcher(&cfg.SSL); err != nil {
+		return err
 	}
 
 	s.tlsConfig = &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ServerName:   cfg.Server.Host,
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate: s.getCertificate,
+		ServerName:     cfg.Server.Host,
+		MinVersion:     tls.VersionTLS12,
 		CipherSuites: []uint16{
 			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
 			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
 		},
+		GetConfigForClient: s.getConfigForClient,
+	}
+
+	return s.reloadClientAuth(&cfg.SSL)
+}
+
+// getCertificate 从当前证书监视器获取证书
+func (s *Server) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	watcher := s.certs.Load()
+	if watcher == nil {
+		return nil, fmt.Errorf("TLS certificate not loaded")
+	}
+	return watcher.GetCertificate(hello)
+}
+
+// reloadCertWatcher 按SSL配置创建证书监视器，文件和间隔未变化时保留现有监视器
+func (s *Server) reloadCertWatcher(sslCfg *types.SSLConfig) error {
+	if current := s.certs.Load(); current != nil && current.Matches(sslCfg.CertFile, sslCfg.KeyFile, sslCfg.WatchInterval) {
+		return nil
 	}
 
+	watcher, err := certwatch.New(sslCfg.CertFile, sslCfg.KeyFile, sslCfg.WatchInterval)
+	if err != nil {
+		return err
+	}
+	if !sslCfg.DisableWatch {
+		watcher.Start()
+	}
+
+	if old := s.certs.Swap(watcher); old != nil {
+		old.Stop()
+	}
 	return nil
 }
 
+// ReloadTLS 立即重新加载TLS证书
+func (s *Server) ReloadTLS() (*certwatch.Status, error) {
+	watcher := s.certs.Load()
+	if watcher == nil {
+		return nil, fmt.Errorf("TLS is not active")
+	}
+	if err := watcher.Reload(); err != nil {
+		return nil, err
+	}
+	status := watcher.Status()
+	return &status, nil
+}
+
 // watchConfig 监听配置变化
 func (s *Server) watchConfig() {
 	watcher := s.config.WatchConfig()
@@ -444,13 +1229,54 @@ func (s *Server) watchConfig() {
 
 // updateConfig 更新配置
 func (s *Server) updateConfig(config *types.Config) {
-	// 更新服务器配置
-	s.server.ReadTimeout = config.Server.ReadTimeout
-	s.server.WriteTimeout = config.Server.WriteTimeout
-	s.server.Concurrency = config.Server.MaxConn
+	// 监听器级别配置变化时平滑重载，不直接修改运行中的fasthttp.Server
+	settings := newListenerSettings(&config.Server, net.JoinHostPort(config.Server.Host, strconv.Itoa(config.Server.Port)))
+	if settings != s.listenerCfg {
+		if err := s.reloadListener(settings); err != nil {
+			fmt.Printf("[LISTENER] Failed to reload listener: %v\n", err)
+		}
+	}
+
+	// 证书路径或轮询间隔变化时替换证书监视器
+	if s.tlsConfig != nil && config.SSL.Enabled {
+		if err := s.reloadCertWatcher(&config.SSL); err != nil {
+			fmt.Printf("[TLS] Failed to reload certificate watcher: %v\n", err)
+		}
+		if err := s.reloadClientAuth(&config.SSL); err != nil {
+			fmt.Printf("[TLS] Failed to reload client CA: %v\n", err)
+		}
+	}
+
+	// 更新配额配置，保留仍存在的API密钥的用量
+	s.quota.Store(quota.NewManager(&config.Quota, s.quota.Load()))
+	s.apiKeys.Store(apikey.NewStore(&config.APIKeys, s.apiKeys.Load()))
+	s.reloadGeo(&config.Geo)
+	s.reloadCache(&config.Cache)
+	s.reloadAccessLog(&config.AccessLog)
+	s.reloadWebhooks(config.Webhooks)
+	s.reloadTracing(&config.Tracing)
+	s.earlyReject.Update(&config.Server.EarlyReject)
+	s.connClasses.Update(&config.Server.ConnectionClasses)
+	s.rateLimits.Update(config)
+	s.concurrency.Update(config)
+	s.queues.Update(config)
+	s.shedding.Update(&config.LoadShedding)
+	s.monitor.SetHistory(&config.StatsHistory)
+	s.conns.SetSlowClient(&config.Server.SlowClient)
+	s.conns.SetClientLimits(&config.Server.ClientLimits)
+	s.conns.SetSlowRequest(&config.Server.SlowRequest)
+	s.conns.SetConnLifetime(&config.Server.ConnectionLifetime)
+	audit.SetEnabled(config.Audit.Enabled)
+	s.health.SetWorkers(config.ControlPlane.HealthWorkers)
+	if s.monitor != nil {
+		s.monitor.RetainBackends(config.Backends)
+		s.monitor.RetainRoutes(config.Routing)
+	}
 
 	// 更新上游配置
-	s.initUpstreams()
+	if err := s.initUpstreams(); err != nil {
+		fmt.Printf("[CONFIG] Failed to reload upstreams: %v\n", err)
+	}
 }
 
 // 高性能UpstreamManager方法（无锁设计）
@@ -462,14 +1288,19 @@ func NewUpstreamManager() *UpstreamManager {
 }
 
 func (um *UpstreamManager) CreateUpstream(name string, backends []*types.Backend) (*Upstream, error) {
+	if um.published.Load() {
+		audit.Violation("upstream_manager_mutation", "CreateUpstream(%s) on a published upstream manager", name)
+	}
+
 	// 检查是否已存在
 	if _, exists := um.names[name]; exists {
 		return nil, fmt.Errorf("upstream %s already exists", name)
 	}
 
 	upstream := &Upstream{
-		name:     name,
-		backends: backends,
+		name:      name,
+		backends:  backends,
+		published: &um.published,
 	}
 
 	// 添加到切片
@@ -479,15 +1310,37 @@ func (um *UpstreamManager) CreateUpstream(name string, backends []*types.Backend
 	return upstream, nil
 }
 
+// Upstreams 获取全部上游
+func (um *UpstreamManager) Upstreams() []*Upstream {
+	upstreams := make([]*Upstream, 0, len(um.names))
+	for _, index := range um.names {
+		upstreams = append(upstreams, um.upstreams[index])
+	}
+	return upstreams
+}
+
 func (um *UpstreamManager) GetUpstream(name string) *Upstream {
-	if index, exists := um.names[name]; exists && index < len(um.upstreams) {
-		return um.upstreams[index]
+	index, exists := um.names[name]
+	if !exists {
+		return nil
 	}
-	return nil
+	if index >= len(um.upstreams) {
+		audit.Fallback("upstream_index_out_of_range", "upstream %s maps to index %d of %d", name, index, len(um.upstreams))
+		return nil
+	}
+	return um.upstreams[index]
+}
+
+// publish 标记管理器已发布，之后不应再修改
+func (um *UpstreamManager) publish() {
+	um.published.Store(true)
 }
 
 // 注意：RemoveUpstream在高并发环境下不安全，需要外部同步
 func (um *UpstreamManager) RemoveUpstream(name string) {
+	if um.published.Load() {
+		audit.Violation("upstream_manager_mutation", "RemoveUpstream(%s) on a published upstream manager", name)
+	}
 	if index, exists := um.names[name]; exists && index < len(um.upstreams) {
 		// 从映射中删除
 		delete(um.names, name)
@@ -497,28 +1350,84 @@ func (um *UpstreamManager) RemoveUpstream(name string) {
 }
 
 // 高性能Upstream方法（简化锁使用）
-func (u *Upstream) SetLoadBalancer(lbType types.LoadBalancerType, factory *loadbalancer.Factory) {
+func (u *Upstream) SetLoadBalancer(lbType types.LoadBalancerType, params types.LoadBalancerParams, factory *loadbalancer.Factory) {
+	u.checkUnpublished("SetLoadBalancer")
 	u.lbType = lbType
-	u.balancer = factory.GetBalancer(lbType)
+	u.lbParams = params
+	u.factory = factory
+	u.balancer = factory.NewBalancer(lbType, params, u.zone)
+}
+
+// Balancer 获取负载均衡器
+// 路由未指定类型和参数时使用上游默认负载均衡器，否则以路由的设置覆盖上游默认值
+func (u *Upstream) Balancer(lbType types.LoadBalancerType, params *types.LoadBalancerParams) types.LoadBalancer {
+	if lbType == "" && params == nil {
+		return u.balancer
+	}
+
+	key := balancerKey{lbType: u.lbType, params: u.lbParams}
+	if lbType != "" {
+		key.lbType = lbType
+	}
+	if params != nil {
+		if params.HashKey != "" {
+			key.params.HashKey = params.HashKey
+		}
+		if params.SampleSize != 0 {
+			key.params.SampleSize = params.SampleSize
+		}
+		if params.ZoneAware {
+			key.params.ZoneAware = true
+		}
+	}
+	if key.lbType == u.lbType && key.params == u.lbParams {
+		return u.balancer
+	}
+
+	if balancer, ok := u.overrides.Load(key); ok {
+		return balancer.(types.LoadBalancer)
+	}
+	balancer, _ := u.overrides.LoadOrStore(key, u.factory.NewBalancer(key.lbType, key.params, u.zone))
+	return balancer.(types.LoadBalancer)
 }
 
 func (u *Upstream) GetBackends() []*types.Backend {
 	// 创建活跃后端列表，避免锁竞争
 	backends := make([]*types.Backend, 0, len(u.backends))
 	for _, backend := range u.backends {
-		// 检查活跃状态（同时检查原子字段和配置字段）
-		if backend.IsActive() && backend.Active {
+		// 检查活跃状态（同时检查原子字段和配置字段）和健康状态
+		if backend.IsActive() && backend.Active && backend.IsHealthy() {
 			backends = append(backends, backend)
 		}
 	}
 	return backends
 }
 
+// signingHost 获取签名请求使用的Host头，标准端口不带端口号
+func (u *Upstream) signingHost(backend *types.Backend) string {
+	if u.signHost != "" {
+		return u.signHost
+	}
+	if (backend.Scheme == "https" && backend.Port == 443) || (backend.Scheme != "https" && backend.Port == 80) {
+		return backend.Host
+	}
+	return net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port))
+}
+
+// GetAllBackends 获取全部后端（包括未激活的后端）
+func (u *Upstream) GetAllBackends() []*types.Backend {
+	backends := make([]*types.Backend, len(u.backends))
+	copy(backends, u.backends)
+	return backends
+}
+
 func (u *Upstream) AddBackend(backend *types.Backend) {
+	u.checkUnpublished("AddBackend")
 	u.backends = append(u.backends, backend)
 }
 
 func (u *Upstream) RemoveBackend(backendID string) {
+	u.checkUnpublished("RemoveBackend")
 	for i, backend := range u.backends {
 		if backend.ID == backendID {
 			u.backends = append(u.backends[:i], u.backends[i+1:]...)
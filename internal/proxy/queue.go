@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// queueRecheckInterval 排队的请求在没有收到释放通知时重新选择后端的间隔
+// 后端恢复健康、新增后端或调整max_conn不会发出通知，依靠定期重试发现
+const queueRecheckInterval = 50 * time.Millisecond
+
+// BackendQueues 各上游等待后端连接的队列
+// 配置更新时按名称保留已有的队列，正在排队的请求不受影响
+type BackendQueues struct {
+	mu     sync.RWMutex
+	queues map[string]*backendQueue
+}
+
+// backendQueue 单个上游的等待队列
+// 后端释放连接时按先后顺序唤醒一个排队的请求，被唤醒的请求重新选择后端，仍然失败时继续等待
+type backendQueue struct {
+	mu      sync.Mutex
+	maxSize int
+	maxWait time.Duration
+	waiters list.List // chan struct{}，先进先出
+
+	queued   atomic.Int64
+	admitted atomic.Int64
+	rejected atomic.Int64
+	timedOut atomic.Int64
+}
+
+// BackendQueueStats 等待队列统计
+type BackendQueueStats struct {
+	MaxSize  int    `json:"max_size"`
+	MaxWait  string `json:"max_wait"`
+	Waiting  int    `json:"waiting"`   // 正在排队的请求数
+	Queued   int64  `json:"queued"`    // 排队过的请求数
+	Admitted int64  `json:"admitted"`  // 排队后选到后端的请求数
+	Rejected int64  `json:"rejected"`  // 因队列已满而拒绝的请求数
+	TimedOut int64  `json:"timed_out"` // 排队超时的请求数
+}
+
+// NewBackendQueues 创建等待队列集合
+func NewBackendQueues() *BackendQueues {
+	return &BackendQueues{queues: make(map[string]*backendQueue)}
+}
+
+// Update 按配置创建、调整或删除等待队列
+// 删除的队列上正在排队的请求继续等待到超时或选到后端
+func (q *BackendQueues) Update(config *types.Config) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for name := range q.queues {
+		if upstream := config.Upstreams[name]; upstream == nil || upstream.Queue == nil {
+			delete(q.queues, name)
+		}
+	}
+	for name, upstream := range config.Upstreams {
+		if upstream == nil || upstream.Queue == nil {
+			continue
+		}
+		queue := q.queues[name]
+		if queue == nil {
+			queue = &backendQueue{}
+			q.queues[name] = queue
+		}
+		queue.mu.Lock()
+		queue.maxSize = upstream.Queue.MaxSize
+		queue.maxWait = upstream.Queue.MaxWait
+		queue.mu.Unlock()
+	}
+}
+
+// get 获取上游的等待队列，未配置时返回nil
+func (q *BackendQueues) get(name string) *backendQueue {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.queues[name]
+}
+
+// release 上游的后端释放了一个连接，唤醒一个排队的请求
+func (q *BackendQueues) release(name string) {
+	if queue := q.get(name); queue != nil {
+		queue.wake()
+	}
+}
+
+// Stats 获取各上游的等待队列统计
+func (q *BackendQueues) Stats() map[string]BackendQueueStats {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	stats := make(map[string]BackendQueueStats, len(q.queues))
+	for name, queue := range q.queues {
+		stats[name] = queue.stats()
+	}
+	return stats
+}
+
+// waitForBackend 所有后端都达到连接上限时，在第一个被跳过的上游的等待队列中等待后端释放连接
+// 上游未配置队列时原样返回result；队列已满或等待超时时写入503响应并返回false
+func (s *Server) waitForBackend(ctx *fasthttp.RequestCtx, result selectResult, sel func() selectResult) (selectResult, bool) {
+	queue := s.queues.get(result.limitedName)
+	if queue == nil {
+		return result, true
+	}
+
+	queued, ok, full := queue.wait(sel)
+	switch {
+	case ok:
+		return queued, true
+	case full:
+		ctx.Error("Service Unavailable (Backend queue full)", fasthttp.StatusServiceUnavailable)
+	default:
+		ctx.Error("Service Unavailable (Backend queue timeout)", fasthttp.StatusServiceUnavailable)
+	}
+	return queued, false
+}
+
+// wait 排队等待后端释放连接，每次被唤醒或到达重试间隔时调用sel重新选择后端
+// 选到后端时返回该结果；队列已满时返回false和full为true，等待超过max_wait时返回false
+func (q *backendQueue) wait(sel func() selectResult) (result selectResult, ok, full bool) {
+	q.mu.Lock()
+	if q.waiters.Len() >= q.maxSize {
+		q.mu.Unlock()
+		q.rejected.Add(1)
+		return result, false, true
+	}
+	ready := make(chan struct{}, 1)
+	elem := q.waiters.PushBack(ready)
+	deadline := time.Now().Add(q.maxWait)
+	q.mu.Unlock()
+	q.queued.Add(1)
+
+	defer func() {
+		q.mu.Lock()
+		q.waiters.Remove(elem)
+		q.mu.Unlock()
+	}()
+
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			q.timedOut.Add(1)
+			return result, false, false
+		}
+		if remaining > queueRecheckInterval {
+			remaining = queueRecheckInterval
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ready:
+		case <-timer.C:
+		}
+		timer.Stop()
+
+		if result = sel(); result.backend != nil {
+			q.admitted.Add(1)
+			return result, true, false
+		}
+	}
+}
+
+// wake 唤醒最早排队且尚未被唤醒的请求
+func (q *backendQueue) wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for elem := q.waiters.Front(); elem != nil; elem = elem.Next() {
+		select {
+		case elem.Value.(chan struct{}) <- struct{}{}:
+			return
+		default:
+		}
+	}
+}
+
+// stats 获取等待队列统计
+func (q *backendQueue) stats() BackendQueueStats {
+	q.mu.Lock()
+	stats := BackendQueueStats{
+		MaxSize: q.maxSize,
+		MaxWait: q.maxWait.String(),
+		Waiting: q.waiters.Len(),
+	}
+	q.mu.Unlock()
+
+	stats.Queued = q.queued.Load()
+	stats.Admitted = q.admitted.Load()
+	stats.Rejected = q.rejected.Load()
+	stats.TimedOut = q.timedOut.Load()
+	return stats
+}
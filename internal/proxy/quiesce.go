@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DrainedBackend 按主机排空时受影响的后端
+type DrainedBackend struct {
+	Upstream  string `json:"upstream"`
+	BackendID string `json:"backend_id"`
+	Address   string `json:"address"`
+}
+
+// QuiesceStatus 静默模式状态
+type QuiesceStatus struct {
+	Quiesced bool       `json:"quiesced"`
+	Since    *time.Time `json:"since,omitempty"`
+	Address  string     `json:"address"`
+}
+
+// DrainHost 标记某台主机上所有上游的后端为排空（drain为false时取消排空）
+// port为0时匹配该主机上的所有端口
+func (s *Server) DrainHost(host string, port int, drain bool) []DrainedBackend {
+	affected := make([]DrainedBackend, 0)
+	for _, upstream := range s.upstreamMgr.Load().Upstreams() {
+		for _, backend := range upstream.GetAllBackends() {
+			if backend.Host != host || (port != 0 && backend.Port != port) {
+				continue
+			}
+			if drain {
+				if !backend.ShouldDisconnect() {
+					s.notifyDisconnect(upstream.name, backend, "host drained")
+				}
+				backend.MarkForDisconnect()
+			} else {
+				backend.ClearDisconnectMark()
+			}
+			affected = append(affected, DrainedBackend{
+				Upstream:  upstream.name,
+				BackendID: backend.ID,
+				Address:   net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)),
+			})
+		}
+	}
+
+	sort.Slice(affected, func(i, j int) bool {
+		if affected[i].Upstream != affected[j].Upstream {
+			return affected[i].Upstream < affected[j].Upstream
+		}
+		return affected[i].BackendID < affected[j].BackendID
+	})
+
+	action := "drained"
+	if !drain {
+		action = "undrained"
+	}
+	fmt.Printf("[DRAIN] Host %s %s (%d backends)\n", host, action, len(affected))
+	return affected
+}
+
+// Quiesce 进入静默模式：关闭代理监听端口不再接收新连接，已有的keep-alive连接在处理完当前请求后关闭
+// 管理API运行在独立的HTTP服务上，不受影响
+func (s *Server) Quiesce() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.quiesced.Load() {
+		return nil
+	}
+	if s.listener == nil {
+		return fmt.Errorf("proxy listener is not running")
+	}
+
+	if gen := s.listener.swap(nil); gen != nil {
+		gen.Close()
+	}
+	s.listener.Close()
+	s.listener = nil
+
+	s.quiescedAt = time.Now()
+	s.quiesced.Store(true)
+	fmt.Printf("[QUIESCE] Stopped accepting new connections on %s\n", s.listenerCfg.addr)
+	return nil
+}
+
+// Resume 退出静默模式，重新监听代理端口
+func (s *Server) Resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.quiesced.Load() {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp4", s.listenerCfg.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.listenerCfg.addr, err)
+	}
+
+	// 静默期间旧服务器已停止接收连接，使用新服务器接收新连接，旧服务器在后台排空
+	oldServer := s.server
+	s.server = newFastHTTPServer(s.serveHTTP, s.listenerCfg)
+	s.listener = newDispatchListener(ln, s.conns)
+	s.serveGeneration(s.server, s.listener)
+	go s.listener.run()
+
+	s.quiesced.Store(false)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), listenerDrainTimeout)
+		defer cancel()
+		if err := oldServer.ShutdownWithContext(ctx); err != nil {
+			fmt.Printf("[QUIESCE] Old server drain finished with error: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("[QUIESCE] Resumed accepting connections on %s\n", s.listenerCfg.addr)
+	return nil
+}
+
+// GetQuiesceStatus 获取静默模式状态
+func (s *Server) GetQuiesceStatus() QuiesceStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := QuiesceStatus{
+		Quiesced: s.quiesced.Load(),
+		Address:  s.listenerCfg.addr,
+	}
+	if status.Quiesced {
+		since := s.quiescedAt
+		status.Since = &since
+	}
+	return status
+}
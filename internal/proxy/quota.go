@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/quota"
+	"github.com/quqi/speedmimi/internal/vars"
+)
+
+// checkQuota 检查请求的API密钥配额
+// 返回false时已写入拒绝响应；返回的Account不为nil时请求结束后需要调用finishQuota
+func (s *Server) checkQuota(ctx *fasthttp.RequestCtx) (*quota.Account, bool) {
+	m := s.quota.Load()
+	if m == nil {
+		return nil, true
+	}
+
+	key := vars.PeekHeader(&ctx.Request.Header, m.Header())
+	if len(key) == 0 && m.QueryParam() != "" {
+		key = ctx.QueryArgs().Peek(m.QueryParam())
+	}
+
+	now := time.Now()
+	account, result := m.Check(key, now)
+	switch result {
+	case quota.MissingKey:
+		ctx.Error("Unauthorized (API key required)", fasthttp.StatusUnauthorized)
+		return nil, false
+	case quota.UnknownKey:
+		ctx.Error("Forbidden (Unknown API key)", fasthttp.StatusForbidden)
+		return nil, false
+	case quota.Exceeded:
+		resetAt := account.ResetAt(m.Period())
+		retryAfter := int64(resetAt.Sub(now).Seconds()) + 1
+		ctx.Error("Too Many Requests (Quota exceeded)", fasthttp.StatusTooManyRequests)
+		ctx.Response.Header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		setQuotaHeaders(ctx, account, resetAt)
+		return nil, false
+	}
+	return account, true
+}
+
+// finishQuota 记录请求的传输字节数并写入配额响应头
+func (s *Server) finishQuota(ctx *fasthttp.RequestCtx, account *quota.Account) {
+	account.Record(int64(len(ctx.Request.Body())), vars.ResponseBodySize(&ctx.Response))
+	if m := s.quota.Load(); m != nil {
+		setQuotaHeaders(ctx, account, account.ResetAt(m.Period()))
+	}
+}
+
+// setQuotaHeaders 写入配额响应头，请求数不限制时不写入
+func setQuotaHeaders(ctx *fasthttp.RequestCtx, account *quota.Account, resetAt time.Time) {
+	if account.RequestLimit() <= 0 {
+		return
+	}
+	ctx.Response.Header.Set("X-Quota-Limit", strconv.FormatInt(account.RequestLimit(), 10))
+	ctx.Response.Header.Set("X-Quota-Remaining", strconv.FormatInt(account.Remaining(), 10))
+	ctx.Response.Header.Set("X-Quota-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+}
+
+// GetQuota 获取配额管理器，未启用时返回nil
+func (s *Server) GetQuota() *quota.Manager {
+	return s.quota.Load()
+}
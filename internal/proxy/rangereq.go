@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// userValueRange 断点续传路由的请求状态
+const userValueRange = "speedmimi.range"
+
+// rangeStreamThreshold 断点续传路由中超过此大小（或长度未知）的上游响应体以流方式返回
+const rangeStreamThreshold = 64 * 1024
+
+// errInvalidRange Range请求头不符合bytes=first-last[, ...]的格式
+var errInvalidRange = errors.New("invalid range")
+
+// byteRange 请求的一个字节范围：first-last，last为-1时到末尾；first为-1时表示最后last个字节
+type byteRange struct {
+	first, last int64
+}
+
+// rangeRequest 断点续传路由的请求状态
+type rangeRequest struct {
+	cfg    *types.RangeRequestsConfig
+	ranges []byteRange // 转发给上游的范围，为空表示请求完整响应
+}
+
+// rangeCounters 断点续传统计
+type rangeCounters struct {
+	forwarded      atomic.Int64
+	ignored        atomic.Int64
+	partial        atomic.Int64
+	full           atomic.Int64
+	notSatisfiable atomic.Int64
+	invalid        atomic.Int64
+	streamed       atomic.Int64
+}
+
+// RangeRequestStats 断点续传统计
+type RangeRequestStats struct {
+	Forwarded      int64 `json:"forwarded"`       // 转发给上游的Range请求数
+	Ignored        int64 `json:"ignored"`         // Range头无效、范围过多或方法不是GET而被去掉的请求数
+	Partial        int64 `json:"partial"`         // 上游返回206的请求数
+	Full           int64 `json:"full"`            // 上游忽略Range返回完整响应的请求数
+	NotSatisfiable int64 `json:"not_satisfiable"` // 上游返回416的请求数
+	Invalid        int64 `json:"invalid"`         // 上游的206或416响应与请求不符而返回502的请求数
+	Streamed       int64 `json:"streamed"`        // 以流方式返回的响应数
+}
+
+// prepareRange 校验断点续传路由的Range请求头，记录请求状态供转发和检查上游响应使用
+// Range头无效、范围数超过max_ranges或方法不是GET时去掉Range和If-Range头，上游返回完整响应
+func (s *Server) prepareRange(ctx *fasthttp.RequestCtx, cfg *types.RangeRequestsConfig) *rangeRequest {
+	state := &rangeRequest{cfg: cfg}
+	ctx.SetUserValue(userValueRange, state)
+
+	header := vars.PeekHeader(&ctx.Request.Header, fasthttp.HeaderRange)
+	if len(header) == 0 {
+		return state
+	}
+	ranges, err := parseRange(header)
+	vars.DelHeader(&ctx.Request.Header, fasthttp.HeaderRange)
+	if err != nil || !ctx.IsGet() || len(ranges) > cfg.MaxRanges {
+		s.rangeCounters.ignored.Add(1)
+		vars.DelHeader(&ctx.Request.Header, fasthttp.HeaderIfRange)
+		return state
+	}
+
+	// 只转发校验过的范围
+	state.ranges = ranges
+	ctx.Request.Header.Set(fasthttp.HeaderRange, formatRange(ranges))
+	s.rangeCounters.forwarded.Add(1)
+	return state
+}
+
+// checkRangeResponse 检查上游对断点续传路由的响应
+// 206响应的Content-Range必须与请求的范围一致，否则客户端会把错误的数据拼接到已下载的部分，返回502
+func (s *Server) checkRangeResponse(ctx *fasthttp.RequestCtx, state *rangeRequest) {
+	resp := &ctx.Response
+	switch resp.StatusCode() {
+	case fasthttp.StatusPartialContent:
+		if state.validPartial(&resp.Header) {
+			s.rangeCounters.partial.Add(1)
+			break
+		}
+		s.rangeCounters.invalid.Add(1)
+		ctx.Error("Bad Gateway (Invalid partial response)", fasthttp.StatusBadGateway)
+		return
+	case fasthttp.StatusRequestedRangeNotSatisfiable:
+		if len(state.ranges) > 0 {
+			s.rangeCounters.notSatisfiable.Add(1)
+			break
+		}
+		s.rangeCounters.invalid.Add(1)
+		ctx.Error("Bad Gateway (Invalid partial response)", fasthttp.StatusBadGateway)
+		return
+	case fasthttp.StatusOK:
+		if len(state.ranges) > 0 {
+			s.rangeCounters.full.Add(1)
+		}
+	}
+
+	// 较大的响应体按路由的超时写出，而不是server.write_timeout
+	if resp.IsBodyStream() {
+		s.rangeCounters.streamed.Add(1)
+		if tc := trackedConnOf(ctx); tc != nil {
+			tc.streamUntil = time.Now().Add(state.cfg.Timeout)
+		}
+	}
+}
+
+// validPartial 206响应是否与请求的范围一致
+// 请求一个范围时Content-Range必须从请求的位置开始且不超出请求的范围；请求多个范围时上游可以返回multipart/byteranges
+func (state *rangeRequest) validPartial(h *fasthttp.ResponseHeader) bool {
+	if len(state.ranges) == 0 {
+		return false
+	}
+	contentRange := vars.PeekResponseHeader(h, fasthttp.HeaderContentRange)
+	if len(contentRange) == 0 {
+		return len(state.ranges) > 1 && bytes.HasPrefix(h.ContentType(), []byte("multipart/byteranges"))
+	}
+	first, last, complete, ok := parseContentRange(contentRange)
+	if !ok || (h.ContentLength() >= 0 && int64(h.ContentLength()) != last-first+1) {
+		return false
+	}
+	if len(state.ranges) > 1 {
+		return true
+	}
+
+	r := state.ranges[0]
+	if r.first < 0 {
+		// 最后last个字节：表示长度未知时无法核对起始位置
+		return complete < 0 || (last == complete-1 && first == max(complete-r.last, 0))
+	}
+	return first == r.first && (r.last < 0 || last <= r.last)
+}
+
+// closeStreamedConn 以流方式读取的上游响应体写出后关闭上游连接
+// 客户端中途断开或响应被替换时响应体没有读完，连接不能放回连接池
+func closeStreamedConn(resp *fasthttp.Response) {
+	if resp.IsBodyStream() {
+		if n := resp.Header.ContentLength(); n < 0 || n > rangeStreamThreshold {
+			resp.SetConnectionClose()
+		}
+	}
+}
+
+// parseRange 解析Range请求头（只支持bytes单位），忽略空的列表元素
+func parseRange(header []byte) ([]byteRange, error) {
+	const unit = "bytes="
+	if len(header) < len(unit) || !strings.EqualFold(string(header[:len(unit)]), unit) {
+		return nil, errInvalidRange
+	}
+
+	var ranges []byteRange
+	for _, spec := range strings.Split(string(header[len(unit):]), ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		firstStr, lastStr, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+
+		if firstStr == "" {
+			n, err := parseRangeInt(lastStr)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, byteRange{first: -1, last: n})
+			continue
+		}
+		first, err := parseRangeInt(firstStr)
+		if err != nil {
+			return nil, err
+		}
+		last := int64(-1)
+		if lastStr != "" {
+			if last, err = parseRangeInt(lastStr); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, errInvalidRange
+			}
+		}
+		ranges = append(ranges, byteRange{first: first, last: last})
+	}
+	if len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
+
+// parseRangeInt 解析范围中的非负整数，只允许数字
+func parseRangeInt(s string) (int64, error) {
+	if s == "" {
+		return 0, errInvalidRange
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, errInvalidRange
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errInvalidRange
+	}
+	return n, nil
+}
+
+// formatRange 把范围格式化为Range请求头
+func formatRange(ranges []byteRange) string {
+	b := []byte("bytes=")
+	for i, r := range ranges {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if r.first >= 0 {
+			b = strconv.AppendInt(b, r.first, 10)
+		}
+		b = append(b, '-')
+		if r.last >= 0 {
+			b = strconv.AppendInt(b, r.last, 10)
+		}
+	}
+	return string(b)
+}
+
+// parseContentRange 解析206响应的Content-Range：bytes first-last/complete，complete为*时返回-1
+func parseContentRange(value []byte) (first, last, complete int64, ok bool) {
+	const unit = "bytes "
+	if len(value) < len(unit) || !strings.EqualFold(string(value[:len(unit)]), unit) {
+		return 0, 0, 0, false
+	}
+	spec, completeStr, found := strings.Cut(strings.TrimSpace(string(value[len(unit):])), "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	firstStr, lastStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	var err error
+	if first, err = parseRangeInt(firstStr); err != nil {
+		return 0, 0, 0, false
+	}
+	if last, err = parseRangeInt(lastStr); err != nil || last < first {
+		return 0, 0, 0, false
+	}
+	complete = -1
+	if completeStr != "*" {
+		if complete, err = parseRangeInt(completeStr); err != nil || last >= complete {
+			return 0, 0, 0, false
+		}
+	}
+	return first, last, complete, true
+}
+
+// RangeRequestStats 获取断点续传统计
+func (s *Server) RangeRequestStats() RangeRequestStats {
+	return RangeRequestStats{
+		Forwarded:      s.rangeCounters.forwarded.Load(),
+		Ignored:        s.rangeCounters.ignored.Load(),
+		Partial:        s.rangeCounters.partial.Load(),
+		Full:           s.rangeCounters.full.Load(),
+		NotSatisfiable: s.rangeCounters.notSatisfiable.Load(),
+		Invalid:        s.rangeCounters.invalid.Load(),
+		Streamed:       s.rangeCounters.streamed.Load(),
+	}
+}
@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/ratelimit"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// RateLimits 整个代理和各路由按客户端IP的限速器，以及整个代理、各路由和各上游的总请求速率限速器
+// 配置更新时按名称保留已有的令牌桶，只调整速率和容量
+type RateLimits struct {
+	mu        sync.RWMutex
+	routes    map[string]*ratelimit.KeyedLimiter
+	totals    map[string]*ratelimit.Limiter // 路由名称 -> 路由的总速率限速器
+	upstreams map[string]*ratelimit.Limiter // 上游名称 -> 上游的总速率限速器
+	global    *ratelimit.Limiter
+	clients   *ratelimit.KeyedLimiter // 整个代理按客户端IP的限速器
+	allowlist ipAllowlist             // 不受clients限速的客户端
+}
+
+// RateLimitStats 限速统计
+type RateLimitStats struct {
+	Routes      map[string]ratelimit.Stats        `json:"routes"`            // 各路由按客户端IP限速
+	RouteTotals map[string]ratelimit.LimiterStats `json:"route_totals"`      // 各路由的总速率限制
+	Upstreams   map[string]ratelimit.LimiterStats `json:"upstreams"`         // 各上游的总速率限制
+	Global      *ratelimit.LimiterStats           `json:"global,omitempty"`  // 整个代理的总速率限制
+	Clients     *ratelimit.Stats                  `json:"clients,omitempty"` // 整个代理按客户端IP限速（server.client_limits）
+}
+
+// NewRateLimits 创建限速器集合
+func NewRateLimits() *RateLimits {
+	return &RateLimits{
+		routes:    make(map[string]*ratelimit.KeyedLimiter),
+		totals:    make(map[string]*ratelimit.Limiter),
+		upstreams: make(map[string]*ratelimit.Limiter),
+	}
+}
+
+// Update 按配置创建、调整或删除限速器
+func (r *RateLimits) Update(config *types.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name := range r.routes {
+		if rule := config.Routing[name]; rule == nil || rule.RateLimit == nil {
+			delete(r.routes, name)
+		}
+	}
+	for name, rule := range config.Routing {
+		if rule.RateLimit == nil {
+			continue
+		}
+		if limiter := r.routes[name]; limiter != nil {
+			limiter.SetLimit(rule.RateLimit.Rate, rule.RateLimit.Burst)
+		} else {
+			r.routes[name] = ratelimit.NewKeyedLimiter(rule.RateLimit.Rate, rule.RateLimit.Burst)
+		}
+	}
+
+	totals := make(map[string]*types.AggregateRateLimitConfig)
+	for name, rule := range config.Routing {
+		if rule.TotalRateLimit != nil {
+			totals[name] = rule.TotalRateLimit
+		}
+	}
+	updateLimiters(r.totals, totals)
+
+	upstreams := make(map[string]*types.AggregateRateLimitConfig)
+	for name, upstream := range config.Upstreams {
+		if upstream != nil && upstream.RateLimit != nil {
+			upstreams[name] = upstream.RateLimit
+		}
+	}
+	updateLimiters(r.upstreams, upstreams)
+
+	r.global = updateLimiter(r.global, config.Server.RateLimit)
+
+	clients := &config.Server.ClientLimits
+	switch {
+	case clients.RequestRate <= 0:
+		r.clients = nil
+	case r.clients != nil:
+		r.clients.SetLimit(clients.RequestRate, clients.RequestBurst)
+	default:
+		r.clients = ratelimit.NewKeyedLimiter(clients.RequestRate, clients.RequestBurst)
+	}
+	r.allowlist = newIPAllowlist(clients.Allowlist)
+}
+
+// updateLimiters 按名称创建、调整或删除总速率限速器
+func updateLimiters(limiters map[string]*ratelimit.Limiter, configs map[string]*types.AggregateRateLimitConfig) {
+	for name := range limiters {
+		if configs[name] == nil {
+			delete(limiters, name)
+		}
+	}
+	for name, cfg := range configs {
+		limiters[name] = updateLimiter(limiters[name], cfg)
+	}
+}
+
+// updateLimiter 按配置调整总速率限速器，cfg为nil时返回nil
+func updateLimiter(limiter *ratelimit.Limiter, cfg *types.AggregateRateLimitConfig) *ratelimit.Limiter {
+	if cfg == nil {
+		return nil
+	}
+	// reject模式不排队等待
+	var maxDelay time.Duration
+	if cfg.Mode == types.RateLimitDelay {
+		maxDelay = cfg.MaxDelay
+	}
+	if limiter == nil {
+		return ratelimit.NewLimiter(cfg.Rate, cfg.Burst, maxDelay)
+	}
+	limiter.SetLimit(cfg.Rate, cfg.Burst, maxDelay)
+	return limiter
+}
+
+// Stats 获取限速统计
+func (r *RateLimits) Stats() RateLimitStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := RateLimitStats{
+		Routes:      make(map[string]ratelimit.Stats, len(r.routes)),
+		RouteTotals: make(map[string]ratelimit.LimiterStats, len(r.totals)),
+		Upstreams:   make(map[string]ratelimit.LimiterStats, len(r.upstreams)),
+	}
+	for name, limiter := range r.routes {
+		stats.Routes[name] = limiter.Stats()
+	}
+	for name, limiter := range r.totals {
+		stats.RouteTotals[name] = limiter.Stats()
+	}
+	for name, limiter := range r.upstreams {
+		stats.Upstreams[name] = limiter.Stats()
+	}
+	if r.global != nil {
+		global := r.global.Stats()
+		stats.Global = &global
+	}
+	if r.clients != nil {
+		clients := r.clients.Stats()
+		stats.Clients = &clients
+	}
+	return stats
+}
+
+// get 获取路由的限速器，未配置限速时返回nil
+func (r *RateLimits) get(route string) *ratelimit.KeyedLimiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.routes[route]
+}
+
+// total 获取路由的总速率限速器
+func (r *RateLimits) total(route string) *ratelimit.Limiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.totals[route]
+}
+
+// upstream 获取上游的总速率限速器
+func (r *RateLimits) upstream(name string) *ratelimit.Limiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.upstreams[name]
+}
+
+// globalLimiter 获取整个代理的总速率限速器
+func (r *RateLimits) globalLimiter() *ratelimit.Limiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.global
+}
+
+// clientLimiter 获取整个代理按客户端IP的限速器和白名单
+func (r *RateLimits) clientLimiter() (*ratelimit.KeyedLimiter, ipAllowlist) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clients, r.allowlist
+}
+
+// checkClientRateLimit 按TCP对端地址检查整个代理的请求速率上限（与单IP连接数上限一致，不解析请求头），
+// 白名单中的客户端不受限制，超过时返回429和Retry-After
+func (s *Server) checkClientRateLimit(ctx *fasthttp.RequestCtx) bool {
+	limiter, allowlist := s.rateLimits.clientLimiter()
+	if limiter == nil {
+		return true
+	}
+
+	ip := ctx.RemoteIP()
+	if allowlist.contains(ip) {
+		return true
+	}
+	ok, wait := limiter.Allow(ip.String(), time.Now())
+	if ok {
+		return true
+	}
+
+	rejectRateLimited(ctx, "Client rate limit exceeded", wait)
+	return false
+}
+
+// checkRateLimit 按客户端IP（见peerClientIP）检查路由的限速，超过时返回429和Retry-After
+func (s *Server) checkRateLimit(ctx *fasthttp.RequestCtx, route string) bool {
+	limiter := s.rateLimits.get(route)
+	if limiter == nil {
+		return true
+	}
+
+	ok, wait := limiter.Allow(s.peerClientIP(ctx), time.Now())
+	if ok {
+		return true
+	}
+
+	rejectRateLimited(ctx, "Rate limit exceeded", wait)
+	return false
+}
+
+// checkTotalRateLimit 检查所有客户端共享的总速率限制，delay模式下令牌不足时等待到预约的令牌可用
+// 等待时间超过max_delay或reject模式下令牌不足时返回429和Retry-After
+func checkTotalRateLimit(ctx *fasthttp.RequestCtx, limiter *ratelimit.Limiter, scope string) bool {
+	if limiter == nil {
+		return true
+	}
+
+	wait, ok := limiter.Reserve(time.Now())
+	if !ok {
+		rejectRateLimited(ctx, scope+" rate limit exceeded", wait)
+		return false
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return true
+}
+
+// rejectRateLimited 返回429，Retry-After以秒为单位，向上取整
+func rejectRateLimited(ctx *fasthttp.RequestCtx, reason string, wait time.Duration) {
+	retryAfter := int64((wait + time.Second - 1) / time.Second)
+	ctx.Error("Too Many Requests ("+reason+")", fasthttp.StatusTooManyRequests)
+	ctx.Response.Header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+)
+
+// RequestIDHeader 请求ID头，客户端传入时沿用，否则由代理生成
+const RequestIDHeader = vars.RequestIDHeader
+
+// 请求上下文中记录的用户值，用于panic日志和请求变量
+const (
+	userValueRoute    = vars.UserValueRoute
+	userValueUpstream = vars.UserValueUpstream
+	userValueBackend  = vars.UserValueBackend
+)
+
+// serveHTTP 请求入口，捕获处理过程中的panic，避免单个请求导致进程退出
+func (s *Server) serveHTTP(ctx *fasthttp.RequestCtx) {
+	// 请求头已读完，之后连接上的读取为请求体，处理结束后等待下一个请求
+	startRequestBody(ctx)
+	defer finishRequest(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.handlePanic(ctx, r)
+		}
+	}()
+
+	s.handleRequest(ctx)
+}
+
+// handlePanic 记录panic堆栈和请求上下文，返回带请求ID的500响应
+func (s *Server) handlePanic(ctx *fasthttp.RequestCtx, recovered interface{}) {
+	requestID := requestIDOf(ctx)
+	route, _ := ctx.UserValue(userValueRoute).(string)
+	backend, _ := ctx.UserValue(userValueBackend).(string)
+
+	fmt.Printf("[PANIC] request_id=%s route=%s backend=%s method=%s uri=%s client=%s: %v\n%s",
+		requestID, route, backend, ctx.Method(), ctx.RequestURI(), ctx.RemoteIP(), recovered, debug.Stack())
+
+	if s.monitor != nil {
+		s.monitor.RecordPanic()
+	}
+
+	// ctx.Error会丢弃可能已部分写入的上游响应
+	ctx.Error("Internal Server Error (request id: "+requestID+")", fasthttp.StatusInternalServerError)
+	ctx.Response.Header.Set(RequestIDHeader, requestID)
+}
+
+// requestIDOf 获取请求ID：沿用客户端传入的合法请求ID，否则生成随机ID
+func requestIDOf(ctx *fasthttp.RequestCtx) string {
+	return vars.RequestID(ctx)
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/monitor"
+)
+
+func TestHandlePanic(t *testing.T) {
+	pm := monitor.NewPerformanceMonitor()
+	defer pm.Stop()
+	s := &Server{monitor: pm}
+
+	// 客户端传入的请求ID不区分请求头大小写，沿用到500响应中
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.DisableNormalizing()
+	ctx.Request.Header.Set("x-request-id", "req-123")
+	ctx.SetUserValue(userValueRoute, "api")
+	s.handlePanic(&ctx, "boom")
+
+	if ctx.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", ctx.Response.StatusCode())
+	}
+	if id := string(ctx.Response.Header.Peek(RequestIDHeader)); id != "req-123" {
+		t.Fatalf("%s %q, want the client request id", RequestIDHeader, id)
+	}
+	if body := string(ctx.Response.Body()); !strings.Contains(body, "req-123") {
+		t.Fatalf("body %q, want the request id", body)
+	}
+	if n := pm.GetTrafficStats().Panics; n != 1 {
+		t.Fatalf("panics = %d, want 1", n)
+	}
+
+	// 不合法的请求ID不沿用，生成新的ID
+	var bad fasthttp.RequestCtx
+	bad.Request.Header.Set(RequestIDHeader, "has space")
+	s.handlePanic(&bad, "boom")
+	if id := string(bad.Response.Header.Peek(RequestIDHeader)); id == "" || id == "has space" {
+		t.Fatalf("%s %q, want a generated request id", RequestIDHeader, id)
+	}
+}
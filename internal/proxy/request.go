@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+)
+
+// requestContext 负载均衡器可见的请求信息（实现types.RequestContext）
+type requestContext struct {
+	ctx      *fasthttp.RequestCtx
+	clientIP string
+	trace    *requestTrace // 请求未被调试跟踪时为nil
+}
+
+// newRequestContext 创建请求信息，客户端IP按real_ip_header和可信代理规则解析一次
+func (s *Server) newRequestContext(ctx *fasthttp.RequestCtx) *requestContext {
+	return &requestContext{
+		ctx:      ctx,
+		clientIP: s.getClientIP(ctx),
+		trace:    traceOf(ctx),
+	}
+}
+
+func (r *requestContext) ClientIP() string {
+	return r.clientIP
+}
+
+func (r *requestContext) Path() string {
+	return string(r.ctx.Path())
+}
+
+func (r *requestContext) Header(key string) []byte {
+	return vars.PeekHeader(&r.ctx.Request.Header, key)
+}
+
+func (r *requestContext) Cookie(name string) []byte {
+	return r.ctx.Request.Header.Cookie(name)
+}
+
+func (r *requestContext) QueryArg(name string) []byte {
+	return r.ctx.QueryArgs().Peek(name)
+}
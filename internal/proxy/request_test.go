@@ -0,0 +1,22 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequestContextHeader(t *testing.T) {
+	// 代理不规范化请求头名称，负载均衡按请求头取值时不区分大小写
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.DisableNormalizing()
+	ctx.Request.Header.Set("x-user-id", "42")
+	req := &requestContext{ctx: &ctx}
+
+	if v := string(req.Header("X-User-Id")); v != "42" {
+		t.Fatalf("Header(X-User-Id) = %q, want 42", v)
+	}
+	if v := req.Header("X-Missing"); v != nil {
+		t.Fatalf("Header(X-Missing) = %q, want nil", v)
+	}
+}
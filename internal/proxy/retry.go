@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// maxRetryBodySize 可以重试的请求体上限，更大的或分块传输的请求体以流方式转发，不重试
+const maxRetryBodySize = 1 << 20
+
+// reselectFunc 在tried以外的后端中重新选择，没有可用后端时返回nil
+type reselectFunc func(tried map[*types.Backend]bool) (*Upstream, *types.Backend)
+
+// retryPolicy 预编译的路由重试策略
+type retryPolicy struct {
+	attempts   int
+	statuses   map[int]bool
+	methods    map[string]bool
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// newRetryPolicy 编译重试配置，cfg为nil或只允许一次尝试时返回nil
+func newRetryPolicy(cfg *types.RetryConfig) *retryPolicy {
+	if cfg == nil || cfg.MaxAttempts <= 1 {
+		return nil
+	}
+
+	p := &retryPolicy{
+		attempts:   cfg.MaxAttempts,
+		statuses:   make(map[int]bool, len(cfg.StatusCodes)),
+		methods:    make(map[string]bool, len(cfg.Methods)),
+		backoff:    cfg.Backoff,
+		maxBackoff: cfg.MaxBackoff,
+	}
+	for _, code := range cfg.StatusCodes {
+		p.statuses[code] = true
+	}
+	for _, method := range cfg.Methods {
+		p.methods[method] = true
+	}
+	return p
+}
+
+// retriable 判断请求能否重试：方法允许重试，且请求体可以重复发送
+// 以流方式接收的请求体在长度已知且不超过上限时读入内存
+func (p *retryPolicy) retriable(ctx *fasthttp.RequestCtx) bool {
+	if !p.methods[string(ctx.Method())] {
+		return false
+	}
+	if ctx.Request.IsBodyStream() {
+		length := ctx.Request.Header.ContentLength()
+		if length < 0 || length > maxRetryBodySize {
+			return false
+		}
+		ctx.Request.Body()
+	}
+	return true
+}
+
+// delay 第attempt次重试前的等待时间
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+	}
+	if p.maxBackoff > 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d
+}
+
+// proxyWithRetry 代理请求，连接失败或返回可重试的状态码时换一个未尝试过的后端重试
+// 返回最后一次尝试使用的上游和后端
+func (s *Server) proxyWithRetry(ctx *fasthttp.RequestCtx, policy *retryPolicy, upstream *Upstream, backend *types.Backend, reselect reselectFunc) (*Upstream, *types.Backend) {
+	if policy == nil || reselect == nil || !policy.retriable(ctx) {
+		s.proxyRequest(ctx, upstream, backend)
+		return upstream, backend
+	}
+
+	// proxyRequest会追加X-Forwarded-For、改写Host，重试前恢复为客户端的原始值
+	xff := append([]byte(nil), ctx.Request.Header.Peek("X-Forwarded-For")...)
+	host := append([]byte(nil), ctx.Request.Header.Host()...)
+
+	tried := make(map[*types.Backend]bool, policy.attempts)
+	for attempt := 1; ; attempt++ {
+		tried[backend] = true
+		// 长轮询超时说明已等待了完整的时间，不再重试
+		err := s.proxyRequest(ctx, upstream, backend)
+		if attempt >= policy.attempts || err == errLongPollTimeout || (err == nil && !policy.statuses[ctx.Response.StatusCode()]) {
+			return upstream, backend
+		}
+
+		nextUpstream, next := reselect(tried)
+		if next == nil {
+			return upstream, backend
+		}
+		if d := policy.delay(attempt); d > 0 {
+			time.Sleep(d)
+		}
+
+		if len(xff) > 0 {
+			ctx.Request.Header.SetBytesV("X-Forwarded-For", xff)
+		} else {
+			ctx.Request.Header.Del("X-Forwarded-For")
+		}
+		ctx.Request.Header.SetHostBytes(host)
+
+		upstream, backend = nextUpstream, next
+		if upstream != nil {
+			ctx.SetUserValue(userValueUpstream, upstream.name)
+		}
+		ctx.SetUserValue(userValueBackend, backend.ID)
+	}
+}
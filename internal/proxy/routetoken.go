@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/routetoken"
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// parseRoutingToken 解析并移除请求中的路由令牌
+// 未启用或未携带令牌时返回nil, nil
+func (s *Server) parseRoutingToken(ctx *fasthttp.RequestCtx, cfg *types.RoutingTokenConfig) (*routetoken.Claims, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	token := vars.PeekHeader(&ctx.Request.Header, cfg.Header)
+	if len(token) == 0 {
+		return nil, nil
+	}
+
+	// 令牌只在代理内部使用，不转发给后端
+	claims, err := routetoken.Verify(string(token), []byte(cfg.Secret), time.Now())
+	vars.DelHeader(&ctx.Request.Header, cfg.Header)
+	return claims, err
+}
+
+// selectOverrideBackend 根据路由令牌选择后端
+// 指定了后端ID时只选择该后端，否则在指定上游中按负载均衡选择
+func (s *Server) selectOverrideBackend(claims *routetoken.Claims, rule *types.RoutingRule, lbType types.LoadBalancerType, req types.RequestContext) (*Upstream, *types.Backend) {
+	upstream := s.upstreamMgr.Load().GetUpstream(claims.Upstream)
+	if upstream == nil {
+		return nil, nil
+	}
+
+	if claims.BackendID == "" {
+		balancer := upstream.Balancer(lbType, rule.LoadBalancerParams)
+		return upstream, selectByPriority(upstream.GetBackendGroups(), balancer, req)
+	}
+
+	for _, backend := range upstream.GetBackends() {
+		if backend.ID == claims.BackendID {
+			if backend.ShouldDisconnect() || backend.IsConnectionLimitReached() {
+				return nil, nil
+			}
+			return upstream, backend
+		}
+	}
+	return nil, nil
+}
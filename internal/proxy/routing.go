@@ -0,0 +1,215 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// routeEntry 路由表项
+type routeEntry struct {
+	name            string
+	rule            *types.RoutingRule
+	match           []*vars.Condition // 路径匹配后还需满足的条件
+	requestHeaders  []headerTemplate
+	responseHeaders []headerTemplate
+	headerFilter    *headerFilter    // 上游响应头过滤，未配置时为nil
+	retry           *retryPolicy     // 重试策略，未配置时为nil
+	split           *trafficSplit    // 按权重分流，未配置时为nil
+	fanOut          *fanOut          // 并行扇出，未配置时为nil
+	botFilter       *botFilter       // 机器人过滤，未配置时为nil
+	cors            *corsPolicy      // 跨域资源共享，未配置时为nil
+	securityHeaders *securityHeaders // 安全响应头（路由未配置时为全局配置），未启用时为nil
+	inflight        atomic.Int64     // 正在处理的匹配该表项的请求数，用于切换上游后排空
+}
+
+// headerTemplate 预编译的请求头/响应头模板
+type headerTemplate struct {
+	name  string
+	value *vars.Template
+}
+
+// routeTable 路由表（最长前缀匹配）
+// 按前缀建立哈希索引，匹配时从最长的前缀长度开始查找，
+// 每次匹配的开销与不同前缀长度的数量相关，而不是规则数量
+type routeTable struct {
+	config      *types.Config
+	prefixes    map[string][]*routeEntry // 同一前缀的规则：带条件的在前，其后最多一条无条件规则
+	lengths     []int                    // 去重后的前缀长度（降序）
+	fallback    *routeEntry
+	conditional bool             // 是否存在带条件的规则
+	security    *securityHeaders // 全局安全响应头，未启用时为nil
+}
+
+// newRouteTable 根据配置构建路由表
+// 多条无条件规则的前缀相同时，名称字典序最小的规则生效，保证匹配结果确定；
+// 带条件的规则按名称顺序依次判断，都不满足时使用该前缀的无条件规则，再尝试更短的前缀
+func newRouteTable(config *types.Config) *routeTable {
+	t := &routeTable{
+		config:   config,
+		prefixes: make(map[string][]*routeEntry, len(config.Routing)),
+		security: newSecurityHeaders(config.Server.SecurityHeaders),
+	}
+
+	names := make([]string, 0, len(config.Routing))
+	for name := range config.Routing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[int]bool)
+	for _, name := range names {
+		rule := config.Routing[name]
+		entry, err := newRouteEntry(name, rule)
+		if err != nil {
+			// 配置加载时已校验，这里只会在绕过校验时出现
+			fmt.Printf("[ROUTING] Skipping routing rule %s: %v\n", name, err)
+			continue
+		}
+		if rule.SecurityHeaders == nil {
+			entry.securityHeaders = t.security
+		}
+
+		entries := t.prefixes[rule.Path]
+		if len(entry.match) > 0 {
+			t.conditional = true
+			// 插入到无条件规则之前
+			n := len(entries)
+			if n > 0 && len(entries[n-1].match) == 0 {
+				entries = append(entries[:n-1], entry, entries[n-1])
+			} else {
+				entries = append(entries, entry)
+			}
+		} else if n := len(entries); n == 0 || len(entries[n-1].match) > 0 {
+			entries = append(entries, entry)
+		}
+		t.prefixes[rule.Path] = entries
+
+		if !seen[len(rule.Path)] {
+			seen[len(rule.Path)] = true
+			t.lengths = append(t.lengths, len(rule.Path))
+		}
+		if name == "default" {
+			t.fallback = entry
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(t.lengths)))
+
+	return t
+}
+
+// newRouteEntry 编译路由规则的匹配条件和头模板
+func newRouteEntry(name string, rule *types.RoutingRule) (*routeEntry, error) {
+	entry := &routeEntry{name: name, rule: rule}
+
+	match, err := vars.CompileConditions(rule.Match)
+	if err != nil {
+		return nil, fmt.Errorf("match: %w", err)
+	}
+	entry.match = match
+
+	if entry.requestHeaders, err = compileHeaderTemplates(rule.RequestHeaders); err != nil {
+		return nil, fmt.Errorf("request_headers: %w", err)
+	}
+	if entry.responseHeaders, err = compileHeaderTemplates(rule.ResponseHeaders); err != nil {
+		return nil, fmt.Errorf("response_headers: %w", err)
+	}
+	if entry.headerFilter, err = newHeaderFilter(rule.ResponseHeaderFilter); err != nil {
+		return nil, fmt.Errorf("response_header_filter: %w", err)
+	}
+	if entry.split, err = newTrafficSplit(rule.Split); err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+	if entry.fanOut, err = newFanOut(rule.FanOut); err != nil {
+		return nil, fmt.Errorf("fan_out: %w", err)
+	}
+	if entry.botFilter, err = newBotFilter(rule.BotFilter); err != nil {
+		return nil, fmt.Errorf("bot_filter: %w", err)
+	}
+	entry.cors = newCORSPolicy(rule.CORS)
+	entry.securityHeaders = newSecurityHeaders(rule.SecurityHeaders)
+	entry.retry = newRetryPolicy(rule.Retry)
+	return entry, nil
+}
+
+// compileHeaderTemplates 编译头模板
+func compileHeaderTemplates(headers []types.HeaderTemplate) ([]headerTemplate, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	compiled := make([]headerTemplate, 0, len(headers))
+	for _, h := range headers {
+		value, err := vars.Compile(h.Value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", h.Name, err)
+		}
+		compiled = append(compiled, headerTemplate{name: h.Name, value: value})
+	}
+	return compiled, nil
+}
+
+// entry 按名称查找路由表项，被同前缀规则覆盖而未生效的规则返回nil
+func (t *routeTable) entry(name string) *routeEntry {
+	if t.fallback != nil && t.fallback.name == name {
+		return t.fallback
+	}
+	for _, entries := range t.prefixes {
+		for _, entry := range entries {
+			if entry.name == name {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// match 查找路径对应的路由规则，无匹配时返回default规则；带条件的规则不参与匹配
+func (t *routeTable) match(path string) (string, *types.RoutingRule) {
+	entry := t.lookup(path, nil)
+	if entry == nil {
+		return "", nil
+	}
+	return entry.name, entry.rule
+}
+
+// lookup 查找路径和请求变量都匹配的路由表项，无匹配时返回default规则
+// env为nil时带条件的规则不参与匹配；env只在需要判断条件时调用一次
+func (t *routeTable) lookup(path string, env func() *vars.Env) *routeEntry {
+	var e *vars.Env
+	for _, length := range t.lengths {
+		if length > len(path) {
+			continue
+		}
+		for _, entry := range t.prefixes[path[:length]] {
+			if len(entry.match) == 0 {
+				return entry
+			}
+			if env == nil {
+				continue
+			}
+			if e == nil {
+				e = env()
+			}
+			if vars.MatchAll(entry.match, e) {
+				return entry
+			}
+		}
+	}
+
+	if t.fallback != nil && len(t.fallback.match) > 0 {
+		if env == nil {
+			return nil
+		}
+		if e == nil {
+			e = env()
+		}
+		if !vars.MatchAll(t.fallback.match, e) {
+			return nil
+		}
+	}
+	return t.fallback
+}
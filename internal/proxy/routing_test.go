@@ -0,0 +1,447 @@
+package proxy
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// referenceMatch 参考实现：线性扫描所有规则，最长前缀优先，前缀相同时名称字典序最小的优先
+func referenceMatch(config *types.Config, path string) (string, *types.RoutingRule) {
+	bestName := ""
+	var best *types.RoutingRule
+	for name, rule := range config.Routing {
+		if !strings.HasPrefix(path, rule.Path) {
+			continue
+		}
+		if best == nil || len(rule.Path) > len(best.Path) ||
+			(len(rule.Path) == len(best.Path) && name < bestName) {
+			bestName, best = name, rule
+		}
+	}
+	if best != nil {
+		return bestName, best
+	}
+	if rule, exists := config.Routing["default"]; exists {
+		return "default", rule
+	}
+	return "", nil
+}
+
+var routeSegments = []string{"api", "v1", "v2", "users", "orders", "static", "img", "a", "b", "internal", "health"}
+
+// randomPath 生成随机路径，segments越多路径越深
+func randomPath(r *rand.Rand, maxSegments int) string {
+	var sb strings.Builder
+	n := r.Intn(maxSegments + 1)
+	for i := 0; i < n; i++ {
+		sb.WriteByte('/')
+		sb.WriteString(routeSegments[r.Intn(len(routeSegments))])
+		if r.Intn(4) == 0 {
+			fmt.Fprintf(&sb, "%d", r.Intn(100))
+		}
+	}
+	if sb.Len() == 0 || r.Intn(3) == 0 {
+		sb.WriteByte('/')
+	}
+	return sb.String()
+}
+
+// generateRoutes 生成n条随机路由规则（包含default规则和重复前缀）
+func generateRoutes(r *rand.Rand, n int) *types.Config {
+	config := &types.Config{Routing: make(map[string]*types.RoutingRule, n+1)}
+	config.Routing["default"] = &types.RoutingRule{Path: "/", Upstream: "default"}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("route-%05d", i)
+		config.Routing[name] = &types.RoutingRule{Path: randomPath(r, 4), Upstream: name}
+	}
+	return config
+}
+
+func TestRouteTableMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 10, 100, 1000, 5000} {
+		config := generateRoutes(r, size)
+		table := newRouteTable(config)
+
+		for i := 0; i < 20000; i++ {
+			path := randomPath(r, 6)
+			wantName, wantRule := referenceMatch(config, path)
+			gotName, gotRule := table.match(path)
+			if gotName != wantName || gotRule != wantRule {
+				t.Fatalf("size %d, path %q: got route %q, want %q", size, path, gotName, wantName)
+			}
+		}
+	}
+}
+
+func TestRouteTableWithoutDefault(t *testing.T) {
+	config := &types.Config{Routing: map[string]*types.RoutingRule{
+		"api": {Path: "/api", Upstream: "api"},
+	}}
+	table := newRouteTable(config)
+
+	if name, rule := table.match("/static/app.js"); rule != nil {
+		t.Fatalf("expected no match, got route %q", name)
+	}
+	if name, _ := table.match("/api/users"); name != "api" {
+		t.Fatalf("expected route api, got %q", name)
+	}
+}
+
+func TestRouteTableConditions(t *testing.T) {
+	yes := true
+	config := &types.Config{Routing: map[string]*types.RoutingRule{
+		"default": {Path: "/", Upstream: "default"},
+		"api":     {Path: "/api", Upstream: "api"},
+		"api-canary": {Path: "/api", Upstream: "canary", Match: []types.MatchCondition{
+			{Value: "$http_x_canary", Equals: "true"},
+		}},
+		"api-beta": {Path: "/api/v2", Upstream: "beta", Match: []types.MatchCondition{
+			{Value: "$cookie_beta", Exists: &yes},
+		}},
+	}}
+	table := newRouteTable(config)
+
+	lookup := func(path string, setup func(ctx *fasthttp.RequestCtx)) string {
+		var ctx fasthttp.RequestCtx
+		setup(&ctx)
+		entry := table.lookup(path, func() *vars.Env { return &vars.Env{Ctx: &ctx} })
+		if entry == nil {
+			return ""
+		}
+		return entry.name
+	}
+	none := func(*fasthttp.RequestCtx) {}
+	canary := func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.Set("x-canary", "true") }
+	beta := func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.SetCookie("beta", "1") }
+
+	cases := []struct {
+		path  string
+		setup func(*fasthttp.RequestCtx)
+		want  string
+	}{
+		{"/api/users", none, "api"},
+		{"/api/users", canary, "api-canary"},
+		{"/api/v2/users", beta, "api-beta"},
+		{"/api/v2/users", canary, "api-canary"},
+		{"/api/v2/users", none, "api"},
+		{"/static", canary, "default"},
+	}
+	for _, c := range cases {
+		if got := lookup(c.path, c.setup); got != c.want {
+			t.Errorf("path %q: got route %q, want %q", c.path, got, c.want)
+		}
+	}
+
+	// 不提供请求变量时带条件的规则不参与匹配
+	if name, _ := table.match("/api/v2/users"); name != "api" {
+		t.Fatalf("expected route api without variables, got %q", name)
+	}
+}
+
+func FuzzRouteTableMatch(f *testing.F) {
+	for _, seed := range []string{"/", "", "/api", "/api/v1/users", "//", "/static/../api", "/%2e%2e/", "\x00"} {
+		f.Add(seed, int64(0))
+	}
+
+	f.Fuzz(func(t *testing.T, path string, seed int64) {
+		config := generateRoutes(rand.New(rand.NewSource(seed)), 200)
+		table := newRouteTable(config)
+
+		wantName, wantRule := referenceMatch(config, path)
+		gotName, gotRule := table.match(path)
+		if gotName != wantName || gotRule != wantRule {
+			t.Fatalf("path %q: got route %q, want %q", path, gotName, wantName)
+		}
+	})
+}
+
+func BenchmarkRouteTableMatch(b *testing.B) {
+	for _, size := range []int{10, 100, 1000, 10000} {
+		r := rand.New(rand.NewSource(1))
+		config := generateRoutes(r, size)
+		table := newRouteTable(config)
+
+		paths := make([]string, 1024)
+		for i := range paths {
+			paths[i] = randomPath(r, 6)
+		}
+
+		b.Run(fmt.Sprintf("rules=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				table.match(paths[i%len(paths)])
+			}
+		})
+	}
+}
+
+func BenchmarkRouteTableBuild(b *testing.B) {
+	for _, size := range []int{100, 1000, 10000} {
+		config := generateRoutes(rand.New(rand.NewSource(1)), size)
+
+		b.Run(fmt.Sprintf("rules=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				newRouteTable(config)
+			}
+		})
+	}
+}
+
+func TestRouteTablePercentRollout(t *testing.T) {
+	rollout := func(percent int) *routeTable {
+		return newRouteTable(&types.Config{Routing: map[string]*types.RoutingRule{
+			"default": {Path: "/", Upstream: "default"},
+			"v2": {Path: "/", Upstream: "v2", Match: []types.MatchCondition{
+				{Value: "$cookie_uid", Percent: &percent, Salt: "v2"},
+			}},
+		}})
+	}
+	lookup := func(table *routeTable, uid string) string {
+		var ctx fasthttp.RequestCtx
+		if uid != "" {
+			ctx.Request.Header.SetCookie("uid", uid)
+		}
+		return table.lookup("/", func() *vars.Env { return &vars.Env{Ctx: &ctx} }).name
+	}
+
+	small, large := rollout(30), rollout(60)
+	selected := 0
+	for i := 0; i < 1000; i++ {
+		uid := fmt.Sprintf("user-%d", i)
+		got := lookup(small, uid)
+		// 同一用户的每个请求结果相同
+		for j := 0; j < 3; j++ {
+			if again := lookup(small, uid); again != got {
+				t.Fatalf("uid %s: route changed from %q to %q", uid, got, again)
+			}
+		}
+		if got != "v2" {
+			continue
+		}
+		selected++
+		// 比例调大后原来选中的用户仍然选中
+		if lookup(large, uid) != "v2" {
+			t.Fatalf("uid %s left the rollout when percent increased", uid)
+		}
+	}
+	if selected < 250 || selected > 350 {
+		t.Fatalf("expected about 300 of 1000 users selected, got %d", selected)
+	}
+
+	if got := lookup(small, ""); got != "default" {
+		t.Fatalf("expected request without uid to use default route, got %q", got)
+	}
+	if got := lookup(rollout(0), "user-1"); got != "default" {
+		t.Fatalf("expected percent 0 to select nobody, got %q", got)
+	}
+	if got := lookup(rollout(100), "user-1"); got != "v2" {
+		t.Fatalf("expected percent 100 to select everybody, got %q", got)
+	}
+}
+
+func TestTrafficSplitSticky(t *testing.T) {
+	split := func(stable, canary int) *trafficSplit {
+		s, err := newTrafficSplit(&types.TrafficSplitConfig{
+			Key: "$cookie_session",
+			Upstreams: []types.WeightedUpstream{
+				{Upstream: "stable", Weight: stable},
+				{Upstream: "canary", Weight: canary},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	choose := func(s *trafficSplit, session, ip string) string {
+		var ctx fasthttp.RequestCtx
+		if session != "" {
+			ctx.Request.Header.SetCookie("session", session)
+		}
+		return s.choose(&vars.Env{Ctx: &ctx, ClientIP: ip})
+	}
+
+	small, large := split(95, 5), split(80, 20)
+	canary := 0
+	for i := 0; i < 2000; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		got := choose(small, session, "10.0.0.1")
+		if again := choose(small, session, "10.0.0.2"); again != got {
+			t.Fatalf("session %s: upstream changed from %q to %q", session, got, again)
+		}
+		if got != "canary" {
+			continue
+		}
+		canary++
+		// 灰度比例调大后原来分到灰度版的客户端仍然分到灰度版
+		if choose(large, session, "10.0.0.1") != "canary" {
+			t.Fatalf("session %s left the canary when its weight increased", session)
+		}
+	}
+	if canary < 60 || canary > 140 {
+		t.Fatalf("expected about 100 of 2000 sessions on canary, got %d", canary)
+	}
+
+	// 没有分流键时按客户端IP分配
+	canary = 0
+	for i := 0; i < 2000; i++ {
+		if choose(small, "", fmt.Sprintf("10.0.%d.%d", i/256, i%256)) == "canary" {
+			canary++
+		}
+	}
+	if canary < 60 || canary > 140 {
+		t.Fatalf("expected about 100 of 2000 clients without session on canary, got %d", canary)
+	}
+	if got := choose(split(0, 1), "session-1", ""); got != "canary" {
+		t.Fatalf("expected all clients on canary, got %q", got)
+	}
+}
+
+func TestTrafficSplitSteering(t *testing.T) {
+	yes := true
+	split, err := newTrafficSplit(&types.TrafficSplitConfig{
+		Upstreams: []types.WeightedUpstream{
+			{Upstream: "stable", Weight: 1},
+			{Upstream: "canary", Weight: 0},
+		},
+		Steering: []types.SplitSteering{
+			{Match: []types.MatchCondition{{Value: "$http_x_canary", Equals: "true"}}, Upstream: "canary"},
+			{Match: []types.MatchCondition{{Value: "$cookie_beta", Exists: &yes}}, Upstream: "beta"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		setup func(ctx *fasthttp.RequestCtx)
+		want  string
+	}{
+		{"no steering", func(ctx *fasthttp.RequestCtx) {}, "stable"},
+		{"canary header", func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.Set("X-Canary", "true") }, "canary"},
+		{"canary header false", func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.Set("X-Canary", "false") }, "stable"},
+		{"beta cookie", func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.SetCookie("beta", "1") }, "beta"},
+		{"first rule wins", func(ctx *fasthttp.RequestCtx) {
+			ctx.Request.Header.Set("X-Canary", "true")
+			ctx.Request.Header.SetCookie("beta", "1")
+		}, "canary"},
+	}
+	for _, tc := range cases {
+		var ctx fasthttp.RequestCtx
+		tc.setup(&ctx)
+		if got := split.choose(&vars.Env{Ctx: &ctx, ClientIP: "10.0.0.1"}); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	cases := []struct {
+		raw            string
+		keepSlashes    bool
+		encodedSlashes string
+		want           string
+		err            error
+	}{
+		{raw: "/api/users", want: "/api/users"},
+		{raw: "", want: "/"},
+		{raw: "/public/%2e%2e/admin", want: "/admin"},
+		{raw: "/public/%2E./admin/", want: "/admin/"},
+		{raw: "/a/./b/../c", want: "/a/c"},
+		{raw: "/../../etc/passwd", want: "/etc/passwd"},
+		{raw: "/a/b/..", want: "/a/"},
+		{raw: "//admin///x", want: "/admin/x"},
+		{raw: "//admin//x", keepSlashes: true, want: "//admin//x"},
+		{raw: "/%61pi/%7euser", want: "/api/~user"},
+		{raw: "/files/a%2fb", want: "/files/a%2Fb"},
+		{raw: "/public/..%2Fadmin", encodedSlashes: types.EncodedSlashesDecode, want: "/admin"},
+		{raw: "/files/a%2Fb", encodedSlashes: types.EncodedSlashesReject, err: errPathEncodedSlash},
+		{raw: "/name%20with%3Fquery", want: "/name%20with%3Fquery"},
+		{raw: "/a%00b", err: errPathControlChar},
+		{raw: "/a\x01b", err: errPathControlChar},
+		{raw: "/a%0d%0aSet-Cookie", err: errPathControlChar},
+		{raw: "/a%zz", err: errPathBadEncoding},
+		{raw: "/a%2", err: errPathBadEncoding},
+	}
+	for _, tc := range cases {
+		encodedSlashes := tc.encodedSlashes
+		if encodedSlashes == "" {
+			encodedSlashes = types.EncodedSlashesKeep
+		}
+		got, err := normalizePath([]byte(tc.raw), !tc.keepSlashes, encodedSlashes)
+		if err != tc.err || got != tc.want {
+			t.Errorf("normalizePath(%q) = %q, %v; want %q, %v", tc.raw, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string // 重新格式化的Range头，无效时为空
+	}{
+		{header: "bytes=0-99", want: "bytes=0-99"},
+		{header: "Bytes=100-", want: "bytes=100-"},
+		{header: "bytes=-500", want: "bytes=-500"},
+		{header: "bytes= 0-9 , ,20-29", want: "bytes=0-9,20-29"},
+		{header: "bytes=10-5"},
+		{header: "bytes=abc"},
+		{header: "bytes=1-2-3"},
+		{header: "bytes=+1-2"},
+		{header: "bytes=99999999999999999999-"},
+		{header: "bytes="},
+		{header: "items=0-9"},
+	}
+	for _, tc := range cases {
+		ranges, err := parseRange([]byte(tc.header))
+		got := ""
+		if err == nil {
+			got = formatRange(ranges)
+		}
+		if got != tc.want {
+			t.Errorf("parseRange(%q) = %q, %v; want %q", tc.header, got, err, tc.want)
+		}
+	}
+}
+
+func TestValidPartial(t *testing.T) {
+	cases := []struct {
+		rng          string
+		contentRange string
+		length       int
+		want         bool
+	}{
+		{rng: "bytes=100-199", contentRange: "bytes 100-199/1000", length: 100, want: true},
+		{rng: "bytes=100-", contentRange: "bytes 100-999/1000", length: 900, want: true},
+		{rng: "bytes=100-199", contentRange: "bytes 0-99/1000", length: 100},
+		{rng: "bytes=100-199", contentRange: "bytes 100-299/1000", length: 200},
+		{rng: "bytes=100-199", contentRange: "bytes 100-199/1000", length: 1000},
+		{rng: "bytes=-100", contentRange: "bytes 900-999/1000", length: 100, want: true},
+		{rng: "bytes=-100", contentRange: "bytes 0-99/1000", length: 100},
+		{rng: "bytes=-2000", contentRange: "bytes 0-999/1000", length: 1000, want: true},
+		{rng: "bytes=0-9", contentRange: "bytes 0-9/5", length: 10},
+		{rng: "", contentRange: "bytes 0-9/100", length: 10},
+	}
+	for _, tc := range cases {
+		state := &rangeRequest{}
+		if tc.rng != "" {
+			state.ranges, _ = parseRange([]byte(tc.rng))
+		}
+		h := &fasthttp.ResponseHeader{}
+		h.Set(fasthttp.HeaderContentRange, tc.contentRange)
+		h.SetContentLength(tc.length)
+		if got := state.validPartial(h); got != tc.want {
+			t.Errorf("validPartial(%q, %q, %d) = %v, want %v", tc.rng, tc.contentRange, tc.length, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// securityHeaders 预先生成的安全响应头
+type securityHeaders struct {
+	headers  [][2]string // 名称和值
+	hsts     string      // Strict-Transport-Security，为空时不设置
+	override bool
+}
+
+// newSecurityHeaders 生成安全响应头，未配置或未启用时返回nil
+func newSecurityHeaders(cfg *types.SecurityHeadersConfig) *securityHeaders {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	h := &securityHeaders{
+		headers:  [][2]string{{"X-Content-Type-Options", "nosniff"}},
+		override: cfg.Override,
+	}
+	if frame := strings.ToUpper(cfg.FrameOptions); frame != "OFF" {
+		h.headers = append(h.headers, [2]string{"X-Frame-Options", frame})
+	}
+	if cfg.ReferrerPolicy != "off" {
+		h.headers = append(h.headers, [2]string{"Referrer-Policy", cfg.ReferrerPolicy})
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		name := "Content-Security-Policy"
+		if cfg.CSPReportOnly {
+			name = "Content-Security-Policy-Report-Only"
+		}
+		h.headers = append(h.headers, [2]string{name, cfg.ContentSecurityPolicy})
+	}
+	if cfg.HSTSMaxAge >= 0 {
+		h.hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)
+		if cfg.HSTSIncludeSubdomains {
+			h.hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			h.hsts += "; preload"
+		}
+	}
+	return h
+}
+
+// apply 在响应中设置安全响应头，HSTS只在HTTPS响应中设置（浏览器忽略HTTP响应中的HSTS）
+func (h *securityHeaders) apply(ctx *fasthttp.RequestCtx) {
+	if h == nil {
+		return
+	}
+	for _, header := range h.headers {
+		h.set(&ctx.Response.Header, header[0], header[1])
+	}
+	if h.hsts != "" && ctx.IsTLS() {
+		h.set(&ctx.Response.Header, "Strict-Transport-Security", h.hsts)
+	}
+}
+
+// set 设置一个响应头，未配置override时保留上游的值
+func (h *securityHeaders) set(header *fasthttp.ResponseHeader, name, value string) {
+	if len(vars.PeekResponseHeader(header, name)) > 0 {
+		if !h.override {
+			return
+		}
+		vars.DelResponseHeader(header, name)
+	}
+	header.Set(name, value)
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// shutdownPollInterval 关闭前检查剩余请求数的间隔
+const shutdownPollInterval = 100 * time.Millisecond
+
+// 代理关闭前的排空状态
+const (
+	ShutdownStateDraining = "draining"  // 已停止接收新连接，等待处理中的请求完成
+	ShutdownStateDrained  = "drained"   // 处理中的请求已全部完成，即将关闭
+	ShutdownStateTimedOut = "timed_out" // 截止时间到达时仍有请求在处理，即将关闭
+)
+
+// ShutdownStatus 代理关闭前的排空进度
+type ShutdownStatus struct {
+	State      string     `json:"state"`
+	InFlight   int64      `json:"in_flight"` // 处理中的请求数，结束后为结束时的请求数
+	StartedAt  time.Time  `json:"started_at"`
+	Deadline   time.Time  `json:"deadline"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Elapsed    string     `json:"elapsed"`
+}
+
+// shutdown 通过管理API发起的关闭
+type shutdown struct {
+	mu        sync.Mutex
+	status    *ShutdownStatus // 未发起关闭时为nil
+	requested chan struct{}   // 排空结束（完成或超时）、应当停止进程时关闭
+}
+
+func newShutdown() *shutdown {
+	return &shutdown{
+		requested: make(chan struct{}),
+	}
+}
+
+// BeginShutdown 停止接收新连接，在后台等待处理中的请求完成（最长timeout），然后请求关闭进程
+// 已经发起关闭时返回当前进度；排空结束后ShutdownRequested返回的通道被关闭，由调用方停止服务器
+func (s *Server) BeginShutdown(timeout time.Duration) (ShutdownStatus, error) {
+	s.shutdown.mu.Lock()
+	defer s.shutdown.mu.Unlock()
+
+	if s.shutdown.status != nil {
+		return s.shutdownSnapshot(), nil
+	}
+	if err := s.Quiesce(); err != nil {
+		return ShutdownStatus{}, err
+	}
+
+	now := time.Now()
+	s.shutdown.status = &ShutdownStatus{
+		State:     ShutdownStateDraining,
+		InFlight:  s.monitor.GetTrafficStats().ActiveConnections,
+		StartedAt: now,
+		Deadline:  now.Add(timeout),
+	}
+	fmt.Printf("[SHUTDOWN] Draining %d in-flight requests before shutdown (timeout %s)\n", s.shutdown.status.InFlight, timeout)
+	go s.waitForShutdown(s.shutdown.status.Deadline)
+	return s.shutdownSnapshot(), nil
+}
+
+// waitForShutdown 等待处理中的请求完成或截止时间到达
+func (s *Server) waitForShutdown(deadline time.Time) {
+	state := ShutdownStateDrained
+	for s.monitor.GetTrafficStats().ActiveConnections > 0 {
+		if !time.Now().Before(deadline) {
+			state = ShutdownStateTimedOut
+			break
+		}
+		time.Sleep(shutdownPollInterval)
+	}
+
+	s.shutdown.mu.Lock()
+	now := time.Now()
+	status := s.shutdown.status
+	status.State = state
+	status.InFlight = s.monitor.GetTrafficStats().ActiveConnections
+	status.FinishedAt = &now
+	status.Elapsed = now.Sub(status.StartedAt).Round(time.Millisecond).String()
+	final := *status
+	s.shutdown.mu.Unlock()
+
+	fmt.Printf("[SHUTDOWN] Drain %s after %s (%d requests in flight), shutting down\n", state, final.Elapsed, final.InFlight)
+	close(s.shutdown.requested)
+}
+
+// GetShutdownStatus 获取关闭前的排空进度，未发起关闭时返回nil
+func (s *Server) GetShutdownStatus() *ShutdownStatus {
+	s.shutdown.mu.Lock()
+	defer s.shutdown.mu.Unlock()
+
+	if s.shutdown.status == nil {
+		return nil
+	}
+	status := s.shutdownSnapshot()
+	return &status
+}
+
+// ShutdownRequested 返回通过管理API发起的关闭排空结束（完成或超时）、应当停止进程时关闭的通道
+func (s *Server) ShutdownRequested() <-chan struct{} {
+	return s.shutdown.requested
+}
+
+// shutdownSnapshot 获取进度的副本，排空进行中时使用当前请求数和用时，调用方持有锁
+func (s *Server) shutdownSnapshot() ShutdownStatus {
+	status := *s.shutdown.status
+	if status.State == ShutdownStateDraining {
+		status.InFlight = s.monitor.GetTrafficStats().ActiveConnections
+		status.Elapsed = time.Since(status.StartedAt).Round(time.Millisecond).String()
+	}
+	return status
+}
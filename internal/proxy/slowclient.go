@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// slowClientLimits 慢客户端保护参数
+type slowClientLimits struct {
+	maxWriteBuffer int64
+	timeout        time.Duration
+}
+
+// SlowClientStats 慢客户端保护统计
+type SlowClientStats struct {
+	Enabled            bool   `json:"enabled"`
+	MaxWriteBuffer     int64  `json:"max_write_buffer"`
+	WriteBufferTimeout string `json:"write_buffer_timeout"`
+	OverLimit          int    `json:"over_limit"`    // 未写出的响应超过上限的连接数
+	PendingBytes       int64  `json:"pending_bytes"` // 所有连接未写出的响应字节数
+	Disconnects        int64  `json:"disconnects"`   // 超时未降到上限以下而断开的连接数
+}
+
+// SetSlowClient 更新慢客户端保护参数，max_write_buffer为0时关闭
+func (t *ConnTable) SetSlowClient(cfg *types.SlowClientConfig) {
+	if cfg.MaxWriteBuffer <= 0 {
+		t.slowClient.Store(nil)
+		return
+	}
+	t.slowClient.Store(&slowClientLimits{
+		maxWriteBuffer: int64(cfg.MaxWriteBuffer),
+		timeout:        cfg.WriteBufferTimeout,
+	})
+}
+
+// SlowClientStats 获取慢客户端保护统计
+func (t *ConnTable) SlowClientStats() SlowClientStats {
+	var stats SlowClientStats
+	limits := t.slowClient.Load()
+	if limits != nil {
+		stats.Enabled = true
+		stats.MaxWriteBuffer = limits.maxWriteBuffer
+		stats.WriteBufferTimeout = limits.timeout.String()
+	}
+
+	t.conns.Range(func(_, value interface{}) bool {
+		pending := atomic.LoadInt64(&value.(*trackedConn).pending)
+		stats.PendingBytes += pending
+		if limits != nil && pending > limits.maxWriteBuffer {
+			stats.OverLimit++
+		}
+		return true
+	})
+	stats.Disconnects = t.slowDisconnects.Load()
+	return stats
+}
+
+// recordPendingResponse 在请求处理结束、fasthttp写出响应之前记录连接待写出的响应字节数
+// 流式响应的长度未知，不受限制；HEAD请求和204/304响应不写出响应体
+func recordPendingResponse(ctx *fasthttp.RequestCtx) {
+	status := ctx.Response.StatusCode()
+	if ctx.Response.IsBodyStream() || ctx.IsHead() || ctx.Response.SkipBody ||
+		status == fasthttp.StatusNoContent || status == fasthttp.StatusNotModified {
+		return
+	}
+	if tc := trackedConnOf(ctx); tc != nil {
+		atomic.StoreInt64(&tc.pending, int64(len(ctx.Response.Body())))
+	}
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	limits := c.table.slowClient.Load()
+	if limits == nil || atomic.LoadInt64(&c.pending) <= limits.maxWriteBuffer {
+		c.overSince = time.Time{}
+		n, err := c.Conn.Write(b)
+		c.wrote(n)
+		return n, err
+	}
+	return c.writeBounded(b, limits)
+}
+
+// SetWriteDeadline 记录fasthttp设置的写超时，待写出的响应降到上限以下后恢复
+// 以流方式返回的响应（断点续传）按路由的超时延长写超时
+func (c *trackedConn) SetWriteDeadline(t time.Time) error {
+	if !c.streamUntil.IsZero() {
+		if !t.IsZero() && t.Before(c.streamUntil) {
+			t = c.streamUntil
+		}
+		c.streamUntil = time.Time{}
+	}
+	c.writeDeadline = t
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// writeBounded 待写出的响应超过上限时，超出上限的部分必须在超时之前写出，否则断开连接
+// 客户端读取响应足够快时，超出部分很快写出，之后按fasthttp的写超时写出剩余部分
+func (c *trackedConn) writeBounded(b []byte, limits *slowClientLimits) (int, error) {
+	if c.overSince.IsZero() {
+		c.overSince = time.Now()
+	}
+	deadline := c.overSince.Add(limits.timeout)
+	if !c.writeDeadline.IsZero() && c.writeDeadline.Before(deadline) {
+		deadline = c.writeDeadline
+	}
+	if err := c.Conn.SetWriteDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for written < len(b) {
+		over := atomic.LoadInt64(&c.pending) - limits.maxWriteBuffer
+		if over <= 0 {
+			break
+		}
+		chunk := b[written:]
+		if int64(len(chunk)) > over {
+			chunk = chunk[:over]
+		}
+
+		n, err := c.Conn.Write(chunk)
+		c.wrote(n)
+		written += n
+		if err != nil {
+			var netErr net.Error
+			if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+				c.table.slowDisconnects.Add(1)
+				fmt.Printf("[SLOWCLIENT] Closing connection %d from %s: %d response bytes still pending after %v\n",
+					c.id, c.RemoteAddr(), atomic.LoadInt64(&c.pending), time.Since(c.overSince).Round(time.Millisecond))
+				c.Conn.Close()
+			}
+			return written, err
+		}
+	}
+
+	c.overSince = time.Time{}
+	if err := c.Conn.SetWriteDeadline(c.writeDeadline); err != nil {
+		return written, err
+	}
+	if written == len(b) {
+		return written, nil
+	}
+	n, err := c.Conn.Write(b[written:])
+	c.wrote(n)
+	return written + n, err
+}
+
+// wrote 记录写出的字节数
+func (c *trackedConn) wrote(n int) {
+	if n <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.bytesOut, int64(n))
+	if pending := atomic.AddInt64(&c.pending, -int64(n)); pending < 0 {
+		atomic.StoreInt64(&c.pending, 0)
+	}
+}
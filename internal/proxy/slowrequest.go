@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 连接上请求的读取阶段
+const (
+	phaseIdle   int32 = iota // 等待下一个请求
+	phaseHeader              // 已收到请求的第一个字节，请求头（及fasthttp预读的最多8KB请求体）尚未读完
+	phaseBody                // 已调用处理函数，按需流式读取剩余的请求体
+	phaseClosed
+)
+
+// errTooManyIncomplete 同时在发送请求头的连接数达到上限
+var errTooManyIncomplete = errors.New("too many incomplete requests")
+
+// slowRequestLimits 慢请求保护参数
+type slowRequestLimits struct {
+	headerTimeout time.Duration
+	minRate       float64 // 字节/秒
+	grace         time.Duration
+	maxIncomplete int64
+}
+
+// SlowRequestStats 慢请求保护统计
+type SlowRequestStats struct {
+	Enabled               bool   `json:"enabled"`
+	HeaderTimeout         string `json:"header_timeout"`
+	MinRate               int    `json:"min_rate"`
+	RateGrace             string `json:"rate_grace"`
+	MaxIncompleteRequests int64  `json:"max_incomplete_requests"`
+	Incomplete            int64  `json:"incomplete"`      // 当前正在发送请求头的连接数
+	HeaderTimeouts        int64  `json:"header_timeouts"` // 未在header_timeout内发完请求头而断开的连接数
+	RateTimeouts          int64  `json:"rate_timeouts"`   // 发送速率低于min_rate而断开的连接数
+	Rejected              int64  `json:"rejected"`        // 请求头未发完的连接数达到上限而关闭的连接数
+}
+
+// SetSlowRequest 更新慢请求保护参数，各项都为0时关闭
+func (t *ConnTable) SetSlowRequest(cfg *types.SlowRequestConfig) {
+	if cfg.HeaderTimeout <= 0 && cfg.MinRate <= 0 && cfg.MaxIncompleteRequests <= 0 {
+		t.slowRequest.Store(nil)
+		return
+	}
+	t.slowRequest.Store(&slowRequestLimits{
+		headerTimeout: cfg.HeaderTimeout,
+		minRate:       float64(cfg.MinRate),
+		grace:         cfg.RateGrace,
+		maxIncomplete: int64(cfg.MaxIncompleteRequests),
+	})
+}
+
+// SlowRequestStats 获取慢请求保护统计
+func (t *ConnTable) SlowRequestStats() SlowRequestStats {
+	var stats SlowRequestStats
+	if limits := t.slowRequest.Load(); limits != nil {
+		stats.Enabled = true
+		stats.HeaderTimeout = limits.headerTimeout.String()
+		stats.MinRate = int(limits.minRate)
+		stats.RateGrace = limits.grace.String()
+		stats.MaxIncompleteRequests = limits.maxIncomplete
+	}
+	stats.Incomplete = t.incomplete.Load()
+	stats.HeaderTimeouts = t.headerTimeouts.Load()
+	stats.RateTimeouts = t.rateTimeouts.Load()
+	stats.Rejected = t.incompleteRejects.Load()
+	return stats
+}
+
+// SetReadDeadline 记录fasthttp设置的读超时，慢请求保护只会把它提前
+func (c *trackedConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
+	c.readAdjusted = false
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readGuarded 读取请求时提前读超时：请求头阶段不晚于收到第一个字节后header_timeout，
+// 请求头和请求体阶段都不晚于客户端的平均发送速率降到min_rate的时刻（只计算等待客户端的时间，代理自身暂停读取的时间不计入）
+func (c *trackedConn) readGuarded(b []byte, limits *slowRequestLimits) (int, error) {
+	phase := atomic.LoadInt32(&c.phase)
+	deadline, reason := c.readDeadline, ""
+	if limits != nil && phase != phaseIdle {
+		if phase == phaseHeader && limits.headerTimeout > 0 {
+			deadline, reason = earlier(deadline, c.headerStart.Add(limits.headerTimeout), reason, "header")
+		}
+		if limits.minRate > 0 {
+			allowed := limits.grace + time.Duration(float64(c.reqBytes)/limits.minRate*float64(time.Second)) - c.reqWait
+			deadline, reason = earlier(deadline, time.Now().Add(allowed), reason, "rate")
+		}
+	}
+	if reason != "" || c.readAdjusted {
+		if err := c.Conn.SetReadDeadline(deadline); err != nil {
+			return 0, err
+		}
+		c.readAdjusted = reason != ""
+	}
+
+	start := time.Now()
+	n, err := c.Conn.Read(b)
+	if phase != phaseIdle {
+		c.reqWait += time.Since(start)
+		c.reqBytes += int64(n)
+	}
+
+	if err != nil && reason != "" && isTimeout(err) {
+		c.slowRequestTimeout(reason, limits)
+		return n, err
+	}
+	if n > 0 && phase == phaseIdle && limits != nil {
+		if err := c.startRequest(limits, n); err != nil {
+			return 0, err
+		}
+	}
+	return n, err
+}
+
+// earlier 取较早的读超时及其原因，current为零值表示没有超时
+func earlier(current, limit time.Time, currentReason, reason string) (time.Time, string) {
+	if current.IsZero() || limit.Before(current) {
+		return limit, reason
+	}
+	return current, currentReason
+}
+
+// startRequest 收到新请求的前n个字节，进入请求头阶段
+func (c *trackedConn) startRequest(limits *slowRequestLimits, n int) error {
+	if limits.maxIncomplete > 0 && c.table.incomplete.Load() >= limits.maxIncomplete {
+		c.table.incompleteRejects.Add(1)
+		return errTooManyIncomplete
+	}
+	if !atomic.CompareAndSwapInt32(&c.phase, phaseIdle, phaseHeader) {
+		return nil
+	}
+	c.table.incomplete.Add(1)
+	c.headerStart = time.Now()
+	c.reqBytes = int64(n)
+	c.reqWait = 0
+	return nil
+}
+
+// slowRequestTimeout 慢请求保护提前的读超时到期，记录日志并关闭连接
+func (c *trackedConn) slowRequestTimeout(reason string, limits *slowRequestLimits) {
+	if reason == "header" {
+		c.table.headerTimeouts.Add(1)
+		fmt.Printf("[SLOWREQUEST] Closing connection %d from %s: request headers not received within %v\n",
+			c.id, c.RemoteAddr(), limits.headerTimeout)
+	} else {
+		c.table.rateTimeouts.Add(1)
+		fmt.Printf("[SLOWREQUEST] Closing connection %d from %s: request rate below %d bytes/s (%d bytes in %v)\n",
+			c.id, c.RemoteAddr(), int(limits.minRate), c.reqBytes, c.reqWait.Round(time.Millisecond))
+	}
+	c.Conn.Close()
+}
+
+// leavePhase 离开当前读取阶段，返回之前的阶段；从请求头阶段离开时减少未完成请求计数
+func (c *trackedConn) leavePhase(next int32) int32 {
+	for {
+		phase := atomic.LoadInt32(&c.phase)
+		if phase == phaseClosed {
+			return phase
+		}
+		if atomic.CompareAndSwapInt32(&c.phase, phase, next) {
+			if phase == phaseHeader {
+				c.table.incomplete.Add(-1)
+			}
+			return phase
+		}
+	}
+}
+
+// startRequestBody fasthttp读完请求头后调用处理函数时进入请求体阶段
+// 请求完全来自之前读取的数据（流水线请求）时没有经过请求头阶段，从此处开始计算发送速率
+// 同时清除上一个响应未使用的流式写超时
+func startRequestBody(ctx *fasthttp.RequestCtx) {
+	tc := trackedConnOf(ctx)
+	if tc == nil {
+		return
+	}
+	tc.streamUntil = time.Time{}
+	if tc.leavePhase(phaseBody) == phaseIdle {
+		tc.reqBytes = 0
+		tc.reqWait = 0
+	}
+}
+
+// finishRequest 请求处理结束，等待连接上的下一个请求
+func finishRequest(ctx *fasthttp.RequestCtx) {
+	if tc := trackedConnOf(ctx); tc != nil {
+		tc.leavePhase(phaseIdle)
+	}
+}
+
+// isTimeout 是否为超时错误（读写超时、fasthttp.ErrTimeout或context超时）
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &timeout) && timeout.Timeout())
+}
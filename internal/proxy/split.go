@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// trafficSplit 预编译的按权重分流配置
+type trafficSplit struct {
+	key        *vars.Template
+	salt       string
+	upstreams  []string
+	cumulative []int // 累计权重，与upstreams一一对应
+	steering   []splitSteering
+}
+
+// splitSteering 预编译的分流定向规则
+type splitSteering struct {
+	match    []*vars.Condition
+	upstream string
+}
+
+// newTrafficSplit 编译分流配置，cfg为nil时返回nil
+func newTrafficSplit(cfg *types.TrafficSplitConfig) (*trafficSplit, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	key, err := vars.Compile(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	t := &trafficSplit{key: key, salt: cfg.Salt}
+	total := 0
+	for _, u := range cfg.Upstreams {
+		total += u.Weight
+		t.upstreams = append(t.upstreams, u.Upstream)
+		t.cumulative = append(t.cumulative, total)
+	}
+	for _, steer := range cfg.Steering {
+		match, err := vars.CompileConditions(steer.Match)
+		if err != nil {
+			return nil, err
+		}
+		t.steering = append(t.steering, splitSteering{match: match, upstream: steer.Upstream})
+	}
+	return t, nil
+}
+
+// choose 按分流键的哈希选择上游，同一个键总是选中同一个上游
+// 权重总和不变时调大最后一个上游（通常是灰度版）的权重，原来分到该上游的客户端仍然分到该上游
+// 定向规则按顺序匹配，第一个条件全部满足的规则优先于按权重分流
+func (t *trafficSplit) choose(env *vars.Env) string {
+	for _, steer := range t.steering {
+		if vars.MatchAll(steer.match, env) {
+			return steer.upstream
+		}
+	}
+
+	total := t.cumulative[len(t.cumulative)-1]
+	if total <= 0 {
+		return ""
+	}
+
+	value := t.key.Render(env)
+	if value == "" {
+		value = env.ClientIP
+	}
+	bucket := vars.HashBucket(t.salt, value, total)
+	for i, c := range t.cumulative {
+		if bucket < c {
+			return t.upstreams[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// standbyInterval 定期重新计算冷备切换状态的间隔，覆盖后端启停、断开标记等不经过健康检查的变化
+const standbyInterval = time.Second
+
+// StandbyState 路由的冷备上游切换状态
+type StandbyState struct {
+	Route        string    `json:"route"`
+	Primary      string    `json:"primary"`
+	Standby      string    `json:"standby"`
+	Active       bool      `json:"active"` // 是否正在使用备用上游
+	HealthyRatio float64   `json:"healthy_ratio"`
+	Since        time.Time `json:"since"`
+}
+
+// standbyRoute 单个路由的切换状态，请求路径只读取active
+type standbyRoute struct {
+	active atomic.Bool
+	state  StandbyState // 持有StandbyManager.mu时访问
+}
+
+// StandbyManager 冷备上游切换管理器
+// 主上游健康后端比例低于failover_ratio时切换到备用上游，恢复到recover_ratio以上且
+// 在备用上游上停留至少min_duration后自动切回（滞回，避免抖动）
+// 切换状态在后台定期、健康状态变化和配置重载时重新计算，请求只读取路由的原子状态
+type StandbyManager struct {
+	mu     sync.Mutex                               // 串行化切换状态的重新计算
+	routes atomic.Pointer[map[string]*standbyRoute] // 配置了冷备上游的路由，重新计算时整体替换
+}
+
+// NewStandbyManager 创建冷备上游切换管理器
+func NewStandbyManager() *StandbyManager {
+	m := &StandbyManager{}
+	m.routes.Store(&map[string]*standbyRoute{})
+	return m
+}
+
+// Resolve 返回路由当前应使用的上游名称
+func (m *StandbyManager) Resolve(routeName string, rule *types.RoutingRule, upstreamMgr *UpstreamManager) string {
+	if rule.Standby == nil || rule.Standby.Upstream == "" {
+		return rule.Upstream
+	}
+
+	route := (*m.routes.Load())[routeName]
+	if route == nil || !route.matches(rule) {
+		// 配置变化后尚未重新计算的路由
+		route = m.evaluate(routeName, rule, upstreamMgr)
+	}
+	if route.active.Load() {
+		return rule.Standby.Upstream
+	}
+	return rule.Upstream
+}
+
+// Refresh 按路由配置和上游管理器重新计算全部路由的切换状态，丢弃已删除路由的状态
+func (m *StandbyManager) Refresh(routing map[string]*types.RoutingRule, upstreamMgr *UpstreamManager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := *m.routes.Load()
+	routes := make(map[string]*standbyRoute, len(current))
+	for name, rule := range routing {
+		if rule.Standby == nil || rule.Standby.Upstream == "" {
+			continue
+		}
+		route := current[name]
+		if route == nil || !route.matches(rule) {
+			route = newStandbyRoute(name, rule)
+		}
+		route.update(rule, healthyRatio(upstreamMgr.GetUpstream(rule.Upstream)))
+		routes[name] = route
+	}
+	m.routes.Store(&routes)
+}
+
+// evaluate 计算单个路由的切换状态并加入路由表
+func (m *StandbyManager) evaluate(routeName string, rule *types.RoutingRule, upstreamMgr *UpstreamManager) *standbyRoute {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := *m.routes.Load()
+	route := current[routeName]
+	if route != nil && route.matches(rule) {
+		return route // 等待锁时已被重新计算
+	}
+	route = newStandbyRoute(routeName, rule)
+	route.update(rule, healthyRatio(upstreamMgr.GetUpstream(rule.Upstream)))
+
+	routes := make(map[string]*standbyRoute, len(current)+1)
+	for name, r := range current {
+		routes[name] = r
+	}
+	routes[routeName] = route
+	m.routes.Store(&routes)
+	return route
+}
+
+// List 列出所有配置了冷备上游的路由状态
+func (m *StandbyManager) List() []StandbyState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := *m.routes.Load()
+	states := make([]StandbyState, 0, len(routes))
+	for _, route := range routes {
+		states = append(states, route.state)
+	}
+	return states
+}
+
+func newStandbyRoute(name string, rule *types.RoutingRule) *standbyRoute {
+	return &standbyRoute{state: StandbyState{
+		Route:   name,
+		Primary: rule.Upstream,
+		Standby: rule.Standby.Upstream,
+		Since:   time.Now(),
+	}}
+}
+
+// matches 路由的主上游和冷备上游是否与配置一致
+func (r *standbyRoute) matches(rule *types.RoutingRule) bool {
+	return r.state.Primary == rule.Upstream && r.state.Standby == rule.Standby.Upstream
+}
+
+// update 根据主上游的健康比例切换，调用方持有StandbyManager.mu
+func (r *standbyRoute) update(rule *types.RoutingRule, ratio float64) {
+	state := &r.state
+	state.HealthyRatio = ratio
+
+	switch {
+	case !state.Active && ratio < rule.Standby.FailoverRatio:
+		state.Active = true
+		state.Since = time.Now()
+		fmt.Printf("[STANDBY] Route %s switched to standby upstream %s (healthy ratio %.2f)\n", state.Route, state.Standby, ratio)
+	case state.Active && ratio >= rule.Standby.RecoverRatio && time.Since(state.Since) >= rule.Standby.MinDuration:
+		state.Active = false
+		state.Since = time.Now()
+		fmt.Printf("[STANDBY] Route %s switched back to primary upstream %s (healthy ratio %.2f)\n", state.Route, state.Primary, ratio)
+	}
+	r.active.Store(state.Active)
+}
+
+// refreshStandby 按当前配置和上游管理器重新计算冷备切换状态
+func (s *Server) refreshStandby() {
+	if upstreamMgr := s.upstreamMgr.Load(); upstreamMgr != nil {
+		s.standby.Refresh(s.config.GetConfig().Routing, upstreamMgr)
+	}
+}
+
+// runStandby 定期重新计算冷备切换状态
+func (s *Server) runStandby() {
+	ticker := time.NewTicker(standbyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.refreshStandby()
+		}
+	}
+}
+
+// healthyRatio 计算上游中可用后端的比例（按全部后端计算）
+func healthyRatio(upstream *Upstream) float64 {
+	if upstream == nil {
+		return 0
+	}
+
+	backends := upstream.GetAllBackends()
+	if len(backends) == 0 {
+		return 0
+	}
+
+	healthy := 0
+	for _, backend := range backends {
+		if backend.IsActive() && backend.IsHealthy() && !backend.ShouldDisconnect() {
+			healthy++
+		}
+	}
+	return float64(healthy) / float64(len(backends))
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// errNoSwitch 路由已经使用目标上游，不需要切换
+var errNoSwitch = errors.New("route already uses the upstream")
+
+// switchDrainPollInterval 切换后检查旧请求是否处理完成的间隔
+const switchDrainPollInterval = 50 * time.Millisecond
+
+// RouteSwitch 路由上游切换结果
+type RouteSwitch struct {
+	Route     string `json:"route"`
+	From      string `json:"from"`       // 切换前的上游
+	To        string `json:"to"`         // 切换后的上游
+	Drained   bool   `json:"drained"`    // 切换前已匹配该路由的请求是否全部处理完成
+	InFlight  int64  `json:"in_flight"`  // 排空超时时仍在处理的旧请求数
+	DrainTime string `json:"drain_time"` // 排空用时
+}
+
+// SwitchRoute 把路由的主上游原子地切换为另一个上游（蓝绿发布），并写入配置文件
+// 切换后新请求立即使用新上游；drainTimeout大于0时等待切换前已匹配该路由的请求处理完成，超时后返回剩余的请求数
+func (s *Server) SwitchRoute(route, upstream string, drainTimeout time.Duration) (*RouteSwitch, error) {
+	var result *RouteSwitch
+	var old *routeEntry
+	// 在配置锁内读取和修改，与其他管理API的修改串行，不会覆盖并发提交的配置
+	err := s.config.Update(func(cfg *types.Config) error {
+		rule, exists := cfg.Routing[route]
+		if !exists {
+			return fmt.Errorf("routing rule %s not found", route)
+		}
+		if _, exists := cfg.Backends[upstream]; !exists {
+			return fmt.Errorf("upstream %s not found", upstream)
+		}
+
+		result = &RouteSwitch{Route: route, From: rule.Upstream, To: upstream}
+		if rule.Upstream == upstream {
+			return errNoSwitch
+		}
+
+		// 切换前最近使用的路由表项记录了已匹配该路由、仍在处理的请求数
+		// 持有配置锁时不能调用currentRoutes（会再次获取配置），直接读取已构建的路由表
+		if table := s.routes.Load(); table != nil {
+			old = table.entry(route)
+		}
+
+		switched := *rule
+		switched.Upstream = upstream
+		cfg.Routing[route] = &switched
+		return nil
+	})
+	if err == errNoSwitch {
+		result.Drained = true
+		result.DrainTime = "0s"
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[SWITCH] Route %s switched from upstream %s to %s\n", route, result.From, upstream)
+
+	start := time.Now()
+	deadline := start.Add(drainTimeout)
+	for old != nil {
+		result.InFlight = old.inflight.Load()
+		if result.InFlight <= 0 || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(switchDrainPollInterval)
+	}
+	if result.InFlight < 0 {
+		result.InFlight = 0
+	}
+	result.Drained = result.InFlight == 0
+	result.DrainTime = time.Since(start).Round(time.Millisecond).String()
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"bytes"
+	"reflect"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/tracing"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// userValueSpan 请求的OpenTelemetry span
+const userValueSpan = "speedmimi.span"
+
+// reloadTracing 按配置启用、替换或关闭分布式跟踪，配置没有变化时保留原跟踪器
+// 原跟踪器在后台导出剩余的span后关闭，不阻塞配置更新
+func (s *Server) reloadTracing(cfg *types.TracingConfig) {
+	old := s.otel.Load()
+	if old != nil && reflect.DeepEqual(old.Config(), *cfg) {
+		return
+	}
+	if old == nil && !cfg.Enabled {
+		return
+	}
+
+	var tracer *tracing.Tracer
+	if cfg.Enabled {
+		tracer = tracing.New(cfg)
+	}
+	s.otel.Store(tracer)
+	if old != nil {
+		go old.Close()
+	}
+}
+
+// startSpan 为请求创建服务端span，客户端传入的traceparent作为父span；未启用跟踪时返回nil
+func (s *Server) startSpan(ctx *fasthttp.RequestCtx) *tracing.Span {
+	tracer := s.otel.Load()
+	if tracer == nil {
+		return nil
+	}
+	span := tracer.StartServerSpan(string(ctx.Method()), peekTraceParent(&ctx.Request.Header), time.Now())
+	ctx.SetUserValue(userValueSpan, span)
+	return span
+}
+
+// spanOf 请求的span，未启用跟踪时返回nil
+func spanOf(ctx *fasthttp.RequestCtx) *tracing.Span {
+	span, _ := ctx.UserValue(userValueSpan).(*tracing.Span)
+	return span
+}
+
+// injectTraceContext 把请求的span作为父span通过traceparent传给后端，并记录一次转发尝试
+// tracestate原样转发
+func injectTraceContext(ctx *fasthttp.RequestCtx) {
+	span := spanOf(ctx)
+	if span == nil {
+		return
+	}
+	span.AddAttempt()
+	var names []string
+	ctx.Request.Header.VisitAll(func(key, _ []byte) {
+		if bytes.EqualFold(key, traceParentHeader) {
+			names = append(names, string(key))
+		}
+	})
+	for _, name := range names {
+		ctx.Request.Header.Del(name)
+	}
+	ctx.Request.Header.Set(tracing.TraceParentHeader, span.Context().TraceParent())
+}
+
+// traceParentHeader traceparent请求头名称
+var traceParentHeader = []byte(tracing.TraceParentHeader)
+
+// peekTraceParent 客户端的traceparent，监听器保留请求头名称的原始大小写，按不区分大小写查找
+func peekTraceParent(h *fasthttp.RequestHeader) []byte {
+	var value []byte
+	h.VisitAll(func(key, v []byte) {
+		if value == nil && bytes.EqualFold(key, traceParentHeader) {
+			value = v
+		}
+	})
+	return value
+}
+
+// endSpan 设置路由、上游、后端、重试次数和状态码等属性后结束span
+func (s *Server) endSpan(ctx *fasthttp.RequestCtx, span *tracing.Span) {
+	if span == nil {
+		return
+	}
+	if span.Sampled() {
+		method := string(ctx.Method())
+		route, _ := ctx.UserValue(userValueRoute).(string)
+		upstream, _ := ctx.UserValue(userValueUpstream).(string)
+		backend, _ := ctx.UserValue(userValueBackend).(string)
+		status := ctx.Response.StatusCode()
+		if route != "" {
+			span.SetName(method + " " + route)
+		}
+
+		span.SetString("http.request.method", method)
+		span.SetString("url.scheme", s.getProto(ctx))
+		span.SetString("url.path", string(ctx.Path()))
+		span.SetString("server.address", string(ctx.Host()))
+		span.SetString("client.address", s.getClientIP(ctx))
+		span.SetString("user_agent.original", string(ctx.UserAgent()))
+		span.SetInt("http.response.status_code", int64(status))
+		span.SetString("speedmimi.route", route)
+		span.SetString("speedmimi.upstream", upstream)
+		span.SetString("speedmimi.backend", backend)
+		if attempts := span.Attempts(); attempts > 1 {
+			span.SetInt("speedmimi.retries", int64(attempts-1))
+		}
+		if status >= fasthttp.StatusInternalServerError {
+			span.SetError(fasthttp.StatusMessage(status))
+		}
+	}
+	span.End(time.Now())
+}
+
+// GetTracer 获取分布式跟踪器，未启用时返回nil
+func (s *Server) GetTracer() *tracing.Tracer {
+	return s.otel.Load()
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"syscall"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// classifyUpstreamError 转发失败的错误类型，建立连接（包括TLS握手）时的超时为dial_timeout，之后的超时为read_timeout
+func classifyUpstreamError(err error) monitor.UpstreamErrorClass {
+	var dial *dialError
+	switch {
+	case errors.Is(err, fasthttp.ErrDialTimeout), errors.As(err, &dial) && isTimeout(err):
+		return monitor.ErrorDialTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return monitor.ErrorConnectionRefused
+	case isTimeout(err):
+		return monitor.ErrorReadTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, fasthttp.ErrConnectionClosed), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return monitor.ErrorConnectionReset
+	case errors.Is(err, fasthttp.ErrBodyTooLarge):
+		return monitor.ErrorBodyTooLarge
+	}
+	return monitor.ErrorOther
+}
+
+// recordUpstreamError 按错误类型记录一次转发失败
+func (s *Server) recordUpstreamError(upstream *Upstream, backend *types.Backend, err error) {
+	if upstream != nil && s.monitor != nil {
+		s.monitor.RecordUpstreamError(upstream.name, backend.ID, classifyUpstreamError(err))
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// varEnv 获取请求的变量求值环境，同一请求只创建一次
+func (s *Server) varEnv(ctx *fasthttp.RequestCtx) *vars.Env {
+	if env := vars.FromContext(ctx); env != nil {
+		return env
+	}
+
+	env := &vars.Env{
+		Ctx:      ctx,
+		ClientIP: s.getClientIP(ctx),
+		Geo:      s.geo.Load(),
+	}
+	vars.Attach(ctx, env)
+	return env
+}
+
+// forward 转发到选中的后端，并按路由配置设置请求头和响应头
+// 路由的请求头在代理头（X-Forwarded-*）之前设置，响应头在缓存头改写和写入响应缓存之后设置
+// reselect不为nil时按路由的重试策略重试
+func (s *Server) forward(ctx *fasthttp.RequestCtx, entry *routeEntry, upstream *Upstream, backend *types.Backend, cached *cacheState, reselect reselectFunc) {
+	if upstream != nil {
+		ctx.SetUserValue(userValueUpstream, upstream.name)
+
+		// 上游的总请求速率限制
+		if !checkTotalRateLimit(ctx, s.rateLimits.upstream(upstream.name), "Upstream") {
+			return
+		}
+
+		// 上游的并发请求数上限，重试到其他上游时仍占用该上游的名额
+		limiter, ok := acquireConcurrency(ctx, s.concurrency.upstream(upstream.name), "Upstream")
+		if !ok {
+			return
+		}
+		if limiter != nil {
+			defer limiter.Release()
+		}
+	}
+	ctx.SetUserValue(userValueBackend, backend.ID)
+
+	if len(entry.requestHeaders) > 0 {
+		env := s.varEnv(ctx)
+		for _, h := range entry.requestHeaders {
+			ctx.Request.Header.Set(h.name, h.value.Render(env))
+		}
+	}
+
+	// 镜像请求在改写目标地址之前复制
+	if entry.rule.Mirror != nil {
+		s.mirrorRequest(ctx, entry.rule.Mirror)
+	}
+
+	// 断点续传路由校验Range请求头，较大的响应体以流方式返回
+	var ranges *rangeRequest
+	if cfg := entry.rule.RangeRequests; cfg != nil && cfg.Enabled {
+		ranges = s.prepareRange(ctx, cfg)
+	}
+
+	s.proxyWithRetry(ctx, entry.retry, upstream, backend, reselect)
+
+	if ranges != nil {
+		s.checkRangeResponse(ctx, ranges)
+	}
+
+	// 上游响应体计入连接类别的内存预算
+	chargeResponse(ctx)
+
+	// 过滤上游响应头，在写入缓存前执行，缓存命中时返回的也是过滤后的响应头
+	if entry.headerFilter != nil {
+		entry.headerFilter.apply(&ctx.Response.Header)
+	}
+
+	// 按路由配置改写响应缓存头
+	applyCacheControl(ctx, entry.rule.CacheControl)
+
+	// 写入响应缓存或使用重新验证后的缓存
+	if cached != nil {
+		s.storeCache(ctx, cached)
+	}
+
+	s.applyResponseHeaders(ctx, entry)
+}
+
+// applyResponseHeaders 按路由配置设置响应头
+func (s *Server) applyResponseHeaders(ctx *fasthttp.RequestCtx, entry *routeEntry) {
+	if len(entry.responseHeaders) > 0 {
+		env := s.varEnv(ctx)
+		for _, h := range entry.responseHeaders {
+			ctx.Response.Header.Set(h.name, h.value.Render(env))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+
+	"github.com/quqi/speedmimi/internal/healthcheck"
+	"github.com/quqi/speedmimi/internal/webhook"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// reloadWebhooks 按配置创建或替换事件通知器，配置没有变化时保留原通知器
+func (s *Server) reloadWebhooks(cfgs []types.WebhookConfig) {
+	old := s.webhooks.Load()
+	if old != nil && reflect.DeepEqual(old.Configs(), cfgs) {
+		return
+	}
+
+	var notifier *webhook.Notifier
+	if len(cfgs) > 0 {
+		notifier = webhook.New(cfgs)
+	}
+	s.webhooks.Store(notifier)
+	if old != nil {
+		old.Close()
+	}
+}
+
+// notify 发送事件通知，未配置Webhook时忽略
+func (s *Server) notify(event webhook.Event) {
+	notifier := s.webhooks.Load()
+	if notifier == nil {
+		return
+	}
+	event.Node = s.config.GetConfig().Cluster.NodeName
+	notifier.Notify(event)
+}
+
+// notifyHealthTransition 后端健康状态变化时发送通知
+func (s *Server) notifyHealthTransition(t healthcheck.Transition) {
+	event := webhook.Event{
+		Type:      webhook.EventBackendUnhealthy,
+		Upstream:  t.Upstream,
+		BackendID: t.Backend.ID,
+		Address:   net.JoinHostPort(t.Backend.Host, strconv.Itoa(t.Backend.Port)),
+		Reason:    t.Reason,
+		Timestamp: t.At,
+	}
+	if t.Healthy {
+		event.Type = webhook.EventBackendHealthy
+	}
+	s.notify(event)
+}
+
+// notifyDisconnect 后端被标记为断开时发送通知
+func (s *Server) notifyDisconnect(upstream string, backend *types.Backend, reason string) {
+	s.notify(webhook.Event{
+		Type:      webhook.EventBackendDisconnect,
+		Upstream:  upstream,
+		BackendID: backend.ID,
+		Address:   net.JoinHostPort(backend.Host, strconv.Itoa(backend.Port)),
+		Reason:    reason,
+	})
+}
@@ -0,0 +1,263 @@
+package quota
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// Result 配额检查结果
+type Result int
+
+const (
+	Allowed    Result = iota // 允许通过
+	MissingKey               // 未携带API密钥且要求密钥
+	UnknownKey               // API密钥未配置
+	Exceeded                 // 超出配额
+)
+
+// Manager API密钥配额管理器
+// 配置重载时通过NewManager传入旧管理器，保留仍存在的密钥的用量
+type Manager struct {
+	header     string
+	queryParam string
+	period     time.Duration
+	requireKey bool
+	accounts   map[string]*Account
+}
+
+// Account 单个API密钥的配额和用量
+type Account struct {
+	key      string
+	tenant   string
+	requests int64 // 请求数上限，0表示不限制
+	bytes    int64 // 字节数上限，0表示不限制
+	usage    *usage
+}
+
+// usage 当前周期的用量计数，配置重载时在新旧Account之间共享
+type usage struct {
+	mu          sync.Mutex // 串行化周期切换
+	periodStart atomic.Int64
+	requests    atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	rejected    atomic.Int64
+	previous    *Usage // 上一个周期的用量，切换周期时记录
+}
+
+// Usage 用量报告
+type Usage struct {
+	Key          string    `json:"key"` // 脱敏后的API密钥
+	Tenant       string    `json:"tenant,omitempty"`
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	Requests     int64     `json:"requests"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+	Rejected     int64     `json:"rejected"`
+	RequestLimit int64     `json:"request_limit"`
+	ByteLimit    int64     `json:"byte_limit"`
+}
+
+// NewManager 根据配置创建配额管理器，未启用时返回nil
+// prev不为nil时沿用其中同名密钥的用量
+func NewManager(cfg *types.QuotaConfig, prev *Manager) *Manager {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	m := &Manager{
+		header:     cfg.Header,
+		queryParam: cfg.QueryParam,
+		period:     cfg.Period,
+		requireKey: cfg.RequireKey,
+		accounts:   make(map[string]*Account, len(cfg.Keys)),
+	}
+
+	for _, key := range cfg.Keys {
+		account := &Account{
+			key:      key.Key,
+			tenant:   key.Tenant,
+			requests: key.Requests,
+			bytes:    key.Bytes,
+		}
+		if prev != nil {
+			if old := prev.accounts[key.Key]; old != nil {
+				account.usage = old.usage
+			}
+		}
+		if account.usage == nil {
+			account.usage = &usage{}
+		}
+		m.accounts[key.Key] = account
+	}
+
+	return m
+}
+
+// Header 读取API密钥的请求头
+func (m *Manager) Header() string {
+	return m.header
+}
+
+// QueryParam 读取API密钥的查询参数
+func (m *Manager) QueryParam() string {
+	return m.queryParam
+}
+
+// Period 配额周期
+func (m *Manager) Period() time.Duration {
+	return m.period
+}
+
+// Check 检查API密钥的配额，通过时计入一次请求
+// 未携带密钥且不要求密钥时返回nil, Allowed
+func (m *Manager) Check(key []byte, now time.Time) (*Account, Result) {
+	if len(key) == 0 {
+		if m.requireKey {
+			return nil, MissingKey
+		}
+		return nil, Allowed
+	}
+
+	account := m.accounts[string(key)]
+	if account == nil {
+		return nil, UnknownKey
+	}
+
+	u := account.usage
+	u.roll(now, m.period)
+
+	if account.bytes > 0 && u.bytesIn.Load()+u.bytesOut.Load() >= account.bytes {
+		u.rejected.Add(1)
+		return account, Exceeded
+	}
+	if n := u.requests.Add(1); account.requests > 0 && n > account.requests {
+		u.requests.Add(-1)
+		u.rejected.Add(1)
+		return account, Exceeded
+	}
+	return account, Allowed
+}
+
+// Record 记录请求完成后的传输字节数
+func (a *Account) Record(bytesIn, bytesOut int64) {
+	a.usage.bytesIn.Add(bytesIn)
+	a.usage.bytesOut.Add(bytesOut)
+}
+
+// Remaining 当前周期剩余的请求数，不限制时返回-1
+func (a *Account) Remaining() int64 {
+	if a.requests <= 0 {
+		return -1
+	}
+	remaining := a.requests - a.usage.requests.Load()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining
+}
+
+// RequestLimit 每个周期的请求数上限
+func (a *Account) RequestLimit() int64 {
+	return a.requests
+}
+
+// ResetAt 当前周期结束时间
+func (a *Account) ResetAt(period time.Duration) time.Time {
+	return time.Unix(0, a.usage.periodStart.Load()).Add(period)
+}
+
+// roll 进入新周期时保存上一周期的用量并清零计数
+func (u *usage) roll(now time.Time, period time.Duration) {
+	start := now.Truncate(period).UnixNano()
+	if u.periodStart.Load() == start {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	current := u.periodStart.Load()
+	if current == start {
+		return
+	}
+	if current != 0 {
+		u.previous = &Usage{
+			PeriodStart: time.Unix(0, current).UTC(),
+			PeriodEnd:   time.Unix(0, current).Add(period).UTC(),
+			Requests:    u.requests.Swap(0),
+			BytesIn:     u.bytesIn.Swap(0),
+			BytesOut:    u.bytesOut.Swap(0),
+			Rejected:    u.rejected.Swap(0),
+		}
+	}
+	u.periodStart.Store(start)
+}
+
+// Report 生成用量报告，tenant不为空时只包含该租户
+// 返回当前周期和上一周期的用量，按租户和密钥排序
+func (m *Manager) Report(now time.Time, tenant string) (current, previous []Usage) {
+	current = make([]Usage, 0, len(m.accounts))
+	previous = make([]Usage, 0, len(m.accounts))
+
+	for _, account := range m.accounts {
+		if tenant != "" && account.tenant != tenant {
+			continue
+		}
+
+		u := account.usage
+		u.roll(now, m.period)
+
+		start := time.Unix(0, u.periodStart.Load()).UTC()
+		current = append(current, account.fill(Usage{
+			PeriodStart: start,
+			PeriodEnd:   start.Add(m.period),
+			Requests:    u.requests.Load(),
+			BytesIn:     u.bytesIn.Load(),
+			BytesOut:    u.bytesOut.Load(),
+			Rejected:    u.rejected.Load(),
+		}))
+
+		u.mu.Lock()
+		if u.previous != nil {
+			previous = append(previous, account.fill(*u.previous))
+		}
+		u.mu.Unlock()
+	}
+
+	sortUsage(current)
+	sortUsage(previous)
+	return current, previous
+}
+
+// fill 填充报告中的密钥和配额信息
+func (a *Account) fill(usage Usage) Usage {
+	usage.Key = MaskKey(a.key)
+	usage.Tenant = a.tenant
+	usage.RequestLimit = a.requests
+	usage.ByteLimit = a.bytes
+	return usage
+}
+
+// MaskKey 脱敏API密钥，只保留最后4个字符
+func MaskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// sortUsage 按租户和密钥排序
+func sortUsage(usages []Usage) {
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Tenant != usages[j].Tenant {
+			return usages[i].Tenant < usages[j].Tenant
+		}
+		return usages[i].Key < usages[j].Key
+	})
+}
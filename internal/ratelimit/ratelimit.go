@@ -0,0 +1,270 @@
+package ratelimit
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardCount 令牌桶分片数，减少大量客户端并发时的锁竞争
+const shardCount = 32
+
+// sweepInterval 清理空闲令牌桶的间隔，已补满的令牌桶与新建的令牌桶等价，可以删除
+const sweepInterval = time.Minute
+
+// MaxKeys 最多同时跟踪的键数，超过时淘汰最久未使用的令牌桶，防止大量不同的键耗尽内存
+const MaxKeys = 65536
+
+// shardMaxKeys 每个分片最多跟踪的键数
+const shardMaxKeys = MaxKeys / shardCount
+
+// limit 令牌桶参数
+type limit struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 令牌桶容量
+}
+
+// bucket 单个客户端的令牌桶
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// shard 令牌桶分片
+type shard struct {
+	mu        sync.Mutex
+	buckets   map[string]*list.Element // 键 -> lru中的令牌桶
+	lru       list.List                // 按最近使用排序的令牌桶，最久未使用的在末尾
+	lastSweep time.Time
+}
+
+// KeyedLimiter 按键（如客户端IP）分别限速的令牌桶
+// 每个键的令牌桶以rate的速度补充令牌，最多累积burst个，请求消耗一个令牌
+type KeyedLimiter struct {
+	limit    atomic.Pointer[limit]
+	shards   [shardCount]shard
+	allowed  atomic.Int64
+	rejected atomic.Int64
+	evicted  atomic.Int64
+}
+
+// Stats 限速统计
+type Stats struct {
+	Rate     float64 `json:"rate"`     // 每秒请求数
+	Burst    int     `json:"burst"`    // 突发请求数
+	Clients  int     `json:"clients"`  // 正在跟踪的客户端数（已补满的令牌桶定时清理）
+	Allowed  int64   `json:"allowed"`  // 放行的请求数
+	Rejected int64   `json:"rejected"` // 拒绝的请求数
+	Evicted  int64   `json:"evicted"`  // 跟踪的键数达到上限时淘汰的令牌桶数
+}
+
+// NewKeyedLimiter 创建按键限速的令牌桶
+func NewKeyedLimiter(rate float64, burst int) *KeyedLimiter {
+	l := &KeyedLimiter{}
+	for i := range l.shards {
+		l.shards[i].buckets = make(map[string]*list.Element)
+	}
+	l.SetLimit(rate, burst)
+	return l
+}
+
+// SetLimit 调整速率和容量，已有令牌桶的令牌数保留（超过新容量的部分在下次请求时截断）
+func (l *KeyedLimiter) SetLimit(rate float64, burst int) {
+	l.limit.Store(&limit{rate: rate, burst: float64(burst)})
+}
+
+// Allow 消耗key的一个令牌，令牌不足时返回false和下一个令牌可用前需要等待的时间
+func (l *KeyedLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	lim := l.limit.Load()
+	sh := &l.shards[shardIndex(key)]
+
+	sh.mu.Lock()
+	if now.Sub(sh.lastSweep) >= sweepInterval {
+		sh.sweep(lim, now)
+	}
+
+	var b *bucket
+	if e := sh.buckets[key]; e != nil {
+		b = e.Value.(*bucket)
+		b.refill(lim, now)
+		sh.lru.MoveToFront(e)
+	} else {
+		// 分片已满时淘汰最久未使用的令牌桶，已补满的令牌桶只在定时清理时删除
+		if len(sh.buckets) >= shardMaxKeys {
+			sh.evictOldest()
+			l.evicted.Add(1)
+		}
+		b = &bucket{key: key, tokens: lim.burst, last: now}
+		sh.buckets[key] = sh.lru.PushFront(b)
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		sh.mu.Unlock()
+		l.allowed.Add(1)
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / lim.rate * float64(time.Second))
+	sh.mu.Unlock()
+
+	l.rejected.Add(1)
+	return false, wait
+}
+
+// Stats 获取限速统计
+func (l *KeyedLimiter) Stats() Stats {
+	lim := l.limit.Load()
+	clients := 0
+	for i := range l.shards {
+		sh := &l.shards[i]
+		sh.mu.Lock()
+		clients += len(sh.buckets)
+		sh.mu.Unlock()
+	}
+	return Stats{
+		Rate:     lim.rate,
+		Burst:    int(lim.burst),
+		Clients:  clients,
+		Allowed:  l.allowed.Load(),
+		Rejected: l.rejected.Load(),
+		Evicted:  l.evicted.Load(),
+	}
+}
+
+// refill 按经过的时间补充令牌
+func (b *bucket) refill(lim *limit, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * lim.rate
+		b.last = now
+	}
+	b.tokens = math.Min(b.tokens, lim.burst)
+}
+
+// sweep 删除已补满的令牌桶，调用方需持有锁
+func (sh *shard) sweep(lim *limit, now time.Time) {
+	for e := sh.lru.Front(); e != nil; {
+		next := e.Next()
+		b := e.Value.(*bucket)
+		b.refill(lim, now)
+		if b.tokens >= lim.burst {
+			sh.remove(e)
+		}
+		e = next
+	}
+	sh.lastSweep = now
+}
+
+// evictOldest 删除最久未使用的令牌桶，调用方需持有锁
+func (sh *shard) evictOldest() {
+	if e := sh.lru.Back(); e != nil {
+		sh.remove(e)
+	}
+}
+
+// remove 删除令牌桶，调用方需持有锁
+func (sh *shard) remove(e *list.Element) {
+	sh.lru.Remove(e)
+	delete(sh.buckets, e.Value.(*bucket).key)
+}
+
+// shardIndex 键所在的分片
+func shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % shardCount)
+}
+
+// Limiter 所有请求共享的聚合令牌桶，用于限制整个代理、单个路由或上游的总请求速率
+// maxDelay大于0时令牌不足的请求预约之后的令牌并排队等待（漏桶），等待时间超过maxDelay时拒绝
+type Limiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	maxDelay time.Duration
+	tokens   float64 // 排队等待的请求预约了之后的令牌，可以为负数
+	last     time.Time
+
+	allowed  atomic.Int64
+	delayed  atomic.Int64
+	rejected atomic.Int64
+}
+
+// LimiterStats 聚合限速统计
+type LimiterStats struct {
+	Rate     float64 `json:"rate"`
+	Burst    int     `json:"burst"`
+	MaxDelay string  `json:"max_delay,omitempty"` // delay模式的最长等待时间
+	Allowed  int64   `json:"allowed"`             // 立即放行的请求数
+	Delayed  int64   `json:"delayed"`             // 排队等待后放行的请求数
+	Rejected int64   `json:"rejected"`            // 拒绝的请求数
+}
+
+// NewLimiter 创建聚合令牌桶，maxDelay为0时令牌不足的请求直接拒绝
+func NewLimiter(rate float64, burst int, maxDelay time.Duration) *Limiter {
+	return &Limiter{
+		rate:     rate,
+		burst:    float64(burst),
+		maxDelay: maxDelay,
+		tokens:   float64(burst),
+	}
+}
+
+// SetLimit 调整速率、容量和最长等待时间，保留当前令牌数
+func (l *Limiter) SetLimit(rate float64, burst int, maxDelay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.burst = float64(burst)
+	l.maxDelay = maxDelay
+}
+
+// Reserve 申请一个令牌，返回放行前需要等待的时间（0表示立即放行）
+// 拒绝时ok为false，wait为下一个令牌可用前需要等待的时间
+func (l *Limiter) Reserve(now time.Time) (wait time.Duration, ok bool) {
+	l.mu.Lock()
+	if !l.last.IsZero() {
+		if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+			l.tokens += elapsed * l.rate
+		}
+	}
+	l.last = now
+	l.tokens = math.Min(l.tokens, l.burst)
+
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		l.allowed.Add(1)
+		return 0, true
+	}
+
+	wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if l.maxDelay > 0 && wait <= l.maxDelay {
+		l.tokens--
+		l.mu.Unlock()
+		l.delayed.Add(1)
+		return wait, true
+	}
+	l.mu.Unlock()
+
+	l.rejected.Add(1)
+	return wait, false
+}
+
+// Stats 获取聚合限速统计
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	stats := LimiterStats{Rate: l.rate, Burst: int(l.burst)}
+	if l.maxDelay > 0 {
+		stats.MaxDelay = l.maxDelay.String()
+	}
+	l.mu.Unlock()
+
+	stats.Allowed = l.allowed.Load()
+	stats.Delayed = l.delayed.Load()
+	stats.Rejected = l.rejected.Load()
+	return stats
+}
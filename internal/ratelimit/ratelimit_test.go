@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fillShard 向key所在的分片加入新键直到分片已满，返回加入的键，最早加入的在前
+func fillShard(l *KeyedLimiter, key string, now time.Time) []string {
+	sh := &l.shards[shardIndex(key)]
+	var keys []string
+	for i := 0; len(sh.buckets) < shardMaxKeys; i++ {
+		k := "client-" + strconv.Itoa(i)
+		if shardIndex(k) != shardIndex(key) || k == key {
+			continue
+		}
+		l.Allow(k, now)
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestKeyedLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	l := NewKeyedLimiter(1, 2)
+	keys := fillShard(l, "new", now)
+
+	// 最早加入的键刚被使用过，淘汰的是第二个键
+	l.Allow(keys[0], now)
+	l.Allow("new", now)
+
+	sh := &l.shards[shardIndex("new")]
+	if len(sh.buckets) != shardMaxKeys {
+		t.Fatalf("%d buckets in the shard, want the cap %d", len(sh.buckets), shardMaxKeys)
+	}
+	if sh.buckets[keys[0]] == nil || sh.buckets[keys[1]] != nil || sh.buckets["new"] == nil {
+		t.Fatal("evicted bucket is not the least recently used one")
+	}
+	if stats := l.Stats(); stats.Evicted != 1 {
+		t.Fatalf("evicted = %d, want 1", stats.Evicted)
+	}
+
+	// 被淘汰的键重新获得满的令牌桶；仍在跟踪的键保留已消耗的令牌
+	if ok, _ := l.Allow(keys[0], now); ok {
+		t.Fatal("tracked key lost its consumed tokens")
+	}
+}
+
+func TestKeyedLimiterSweepsOnInterval(t *testing.T) {
+	now := time.Now()
+	l := NewKeyedLimiter(1000, 1)
+	keys := fillShard(l, "new", now)
+
+	// 令牌桶已补满，但未到清理间隔时新键只淘汰一个令牌桶
+	later := now.Add(time.Second)
+	l.Allow("new", later)
+	sh := &l.shards[shardIndex("new")]
+	if len(sh.buckets) != shardMaxKeys {
+		t.Fatalf("%d buckets before the sweep interval, want %d", len(sh.buckets), shardMaxKeys)
+	}
+
+	// 到清理间隔时删除所有已补满的令牌桶
+	l.Allow(keys[len(keys)-1], now.Add(sweepInterval))
+	if len(sh.buckets) != 1 {
+		t.Fatalf("%d buckets after the sweep, want only the key just used", len(sh.buckets))
+	}
+}
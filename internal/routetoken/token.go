@@ -0,0 +1,75 @@
+package routetoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed 令牌格式错误
+	ErrMalformed = errors.New("malformed routing token")
+	// ErrSignature 令牌签名不匹配
+	ErrSignature = errors.New("invalid routing token signature")
+	// ErrExpired 令牌已过期
+	ErrExpired = errors.New("routing token expired")
+)
+
+// Claims 路由令牌内容
+type Claims struct {
+	Upstream  string `json:"u"`
+	BackendID string `json:"b,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Sign 生成令牌：base64url(claims) + "." + base64url(HMAC-SHA256(secret, payload))
+func Sign(claims *Claims, secret []byte) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sign(payload, secret)), nil
+}
+
+// Verify 校验令牌签名和有效期，返回令牌内容
+func Verify(token string, secret []byte, now time.Time) (*Claims, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || payload == "" || sig == "" {
+		return nil, ErrMalformed
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !hmac.Equal(expected, sign(payload, secret)) {
+		return nil, ErrSignature
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Upstream == "" {
+		return nil, ErrMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+
+	return &claims, nil
+}
+
+func sign(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
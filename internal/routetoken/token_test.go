@@ -0,0 +1,74 @@
+package routetoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("routing-secret-0123456789")
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := &Claims{Upstream: "api", BackendID: "backend1", ExpiresAt: now.Add(time.Minute).Unix()}
+	token, err := Sign(claims, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Verify(token, secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *claims {
+		t.Fatalf("Verify() = %+v, want %+v", got, claims)
+	}
+
+	// 到达过期时间时令牌失效
+	if _, err := Verify(token, secret, now.Add(59*time.Second)); err != nil {
+		t.Errorf("token rejected before expiry: %v", err)
+	}
+	if _, err := Verify(token, secret, now.Add(time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() at expiry error = %v, want ErrExpired", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := Sign(&Claims{Upstream: "api", ExpiresAt: now.Add(time.Hour).Unix()}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+
+	// 修改内容后重新编码，签名仍为原内容的签名
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"u":"admin","exp":9999999999}`)) + "." + sig
+	noUpstream, err := Sign(&Claims{ExpiresAt: now.Add(time.Hour).Unix()}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notJSON := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+	notJSON += "." + base64.RawURLEncoding.EncodeToString(sign(notJSON, secret))
+
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+		want   error
+	}{
+		{"wrong secret", token, []byte("another-secret-0123456789"), ErrSignature},
+		{"forged payload", forged, secret, ErrSignature},
+		{"no separator", payload, secret, ErrMalformed},
+		{"empty signature", payload + ".", secret, ErrMalformed},
+		{"signature not base64", payload + ".!!!", secret, ErrMalformed},
+		{"payload not JSON", notJSON, secret, ErrMalformed},
+		{"missing upstream", noUpstream, secret, ErrMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := Verify(tt.token, tt.secret, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 密钥请求的默认参数
+const (
+	defaultTimeout = 10 * time.Second // 单次请求超时
+	maxSecretSize  = 1 << 20          // 响应大小上限
+)
+
+// Provider 密钥提供者，可以被并发调用
+type Provider interface {
+	// Fetch 获取引用对应的密钥内容，引用的格式由提供者类型决定
+	Fetch(ctx context.Context, ref string) ([]byte, error)
+	String() string
+}
+
+// New 根据配置创建密钥提供者，只检查配置，不访问网络
+func New(name string, cfg *types.SecretProviderConfig) (Provider, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("secret provider %s is not configured", name)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	tlsConfig, err := clientTLSConfig(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Type {
+	case types.SecretProviderVault:
+		return newVaultProvider(name, cfg, tlsConfig, timeout)
+	case types.SecretProviderAWSKMS:
+		return newKMSProvider(name, cfg, tlsConfig, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported secret provider type %q (expected %s or %s)", cfg.Type, types.SecretProviderVault, types.SecretProviderAWSKMS)
+	}
+}
+
+// clientTLSConfig 配置了caFile时用它验证服务器证书，否则使用系统CA
+func clientTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret provider CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in secret provider CA file %s", caFile)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
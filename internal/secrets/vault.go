@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// vaultTokenEnv 未配置token_file时读取Vault令牌的环境变量
+const vaultTokenEnv = "VAULT_TOKEN"
+
+// vaultProvider HashiCorp Vault，读取KV（v1或v2）中的字段
+// 引用为 <API路径>#<字段>，KV v2的路径包含data/，例如 secret/data/speedmimi#admin_token
+type vaultProvider struct {
+	name      string
+	address   string
+	tokenFile string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultProvider(name string, cfg *types.SecretProviderConfig, tlsConfig *tls.Config, timeout time.Duration) (*vaultProvider, error) {
+	u, err := url.Parse(cfg.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("vault address must be an http(s) URL, got %q", cfg.Address)
+	}
+	client := &http.Client{Timeout: timeout}
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return &vaultProvider{
+		name:      name,
+		address:   strings.TrimSuffix(cfg.Address, "/"),
+		tokenFile: cfg.TokenFile,
+		namespace: cfg.Namespace,
+		client:    client,
+	}, nil
+}
+
+func (p *vaultProvider) String() string {
+	return fmt.Sprintf("vault %s (%s)", p.name, p.address)
+}
+
+// Fetch 读取引用路径下的字段，字段值不是字符串时返回它的JSON
+func (p *vaultProvider) Fetch(ctx context.Context, ref string) ([]byte, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if path == "" || field == "" {
+		return nil, fmt.Errorf("vault reference %q must be <path>#<field>", ref)
+	}
+	token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSecretSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault %s: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	data := secret.Data
+	// KV v2的字段在data.data中，同时带有data.metadata
+	if inner, ok := data["data"]; ok && data["metadata"] != nil {
+		data = nil
+		if err := json.Unmarshal(inner, &data); err != nil {
+			return nil, fmt.Errorf("vault %s: %w", path, err)
+		}
+	}
+	raw, ok := data[field]
+	if !ok {
+		return nil, fmt.Errorf("vault %s: field %q not found", path, field)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return []byte(value), nil
+	}
+	return raw, nil
+}
+
+// token 每次请求时读取令牌，Vault Agent轮换令牌文件后立即生效
+func (p *vaultProvider) token() (string, error) {
+	if p.tokenFile != "" {
+		data, err := os.ReadFile(p.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault token: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if token := os.Getenv(vaultTokenEnv); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("vault token is not set (token_file or %s)", vaultTokenEnv)
+}
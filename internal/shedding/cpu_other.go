@@ -0,0 +1,10 @@
+//go:build !unix
+
+package shedding
+
+import "time"
+
+// processCPUTime 当前平台不支持读取进程CPU时间，只能按延迟判断过载
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package shedding
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟直方图按微秒的对数分桶，每个2的幂区间分为subBuckets个桶，相对误差不超过25%
+const (
+	subBuckets  = 4
+	bucketCount = 64 * subBuckets
+)
+
+// latencyWindow 一个调整周期内的请求延迟直方图，无锁记录
+type latencyWindow struct {
+	buckets [bucketCount]atomic.Int64
+}
+
+// record 记录一个请求的延迟
+func (w *latencyWindow) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	w.buckets[bucketIndex(uint64(d/time.Microsecond))].Add(1)
+}
+
+// snapshot 计算p99并清空直方图，count为周期内的请求数
+func (w *latencyWindow) snapshot() (p99 time.Duration, count int64) {
+	var counts [bucketCount]int64
+	for i := range w.buckets {
+		counts[i] = w.buckets[i].Swap(0)
+		count += counts[i]
+	}
+	if count == 0 {
+		return 0, 0
+	}
+
+	// 第ceil(0.99*count)个请求所在桶的上界
+	rank := (count*99 + 99) / 100
+	var seen int64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(bucketUpperBound(i)) * time.Microsecond, count
+		}
+	}
+	return time.Duration(bucketUpperBound(bucketCount-1)) * time.Microsecond, count
+}
+
+// bucketIndex 微秒数所在的桶：小于subBuckets的值各占一个桶，
+// 其余按最高位所在的2的幂区间和其后两位分桶
+func bucketIndex(us uint64) int {
+	if us < subBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - 3
+	return shift*subBuckets + int(us>>shift)
+}
+
+// bucketUpperBound 桶内最大的微秒数
+func bucketUpperBound(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+	shift := i/subBuckets - 1
+	mantissa := uint64(i%subBuckets + subBuckets)
+	return (mantissa+1)<<shift - 1
+}
@@ -0,0 +1,222 @@
+package shedding
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 优先级在计数数组中的下标
+const (
+	priorityCritical = iota
+	priorityNormal
+	priorityLow
+	priorityCount
+)
+
+// Controller 自适应负载卸载控制器
+// 每个间隔测量代理进程的CPU使用率和请求延迟p99，超过目标时并发上限乘以decrease_factor，
+// 否则增加increase_step（AIMD）；超过并发上限的请求被拒绝，critical路由不受限制
+type Controller struct {
+	settings atomic.Pointer[types.LoadSheddingConfig]
+	limit    atomic.Int64
+	inflight atomic.Int64
+	latency  latencyWindow
+
+	admitted atomic.Int64
+	shed     [priorityCount]atomic.Int64
+
+	mu         sync.Mutex
+	lastWall   time.Time
+	lastCPU    time.Duration
+	cpu        float64       // 最近一个间隔的CPU使用率，-1表示无法测量
+	p99        time.Duration // 最近一个间隔的请求延迟p99
+	overloaded bool
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// Stats 负载卸载统计
+type Stats struct {
+	Enabled    bool             `json:"enabled"`
+	Limit      int64            `json:"limit"`       // 当前并发上限
+	Inflight   int64            `json:"inflight"`    // 正在处理的请求数
+	CPU        float64          `json:"cpu"`         // 最近一个间隔的代理进程CPU使用率，-1表示当前平台无法测量
+	LatencyP99 string           `json:"latency_p99"` // 最近一个间隔的请求延迟p99
+	Overloaded bool             `json:"overloaded"`  // 最近一个间隔是否超过目标
+	Admitted   int64            `json:"admitted"`
+	Shed       map[string]int64 `json:"shed"` // 按路由优先级统计的被拒绝请求数
+}
+
+// NewController 创建负载卸载控制器
+func NewController() *Controller {
+	return &Controller{
+		stopCh: make(chan struct{}),
+		cpu:    -1,
+	}
+}
+
+// Update 更新配置，并发上限限制在新的[min_limit, max_limit]范围内
+func (c *Controller) Update(cfg *types.LoadSheddingConfig) {
+	settings := *cfg
+	c.settings.Store(&settings)
+	if !settings.Enabled {
+		return
+	}
+
+	limit := c.limit.Load()
+	switch {
+	case limit == 0 || limit > int64(settings.MaxLimit):
+		c.limit.Store(int64(settings.MaxLimit))
+	case limit < int64(settings.MinLimit):
+		c.limit.Store(int64(settings.MinLimit))
+	}
+}
+
+// Start 启动后台调整
+func (c *Controller) Start() {
+	go c.run()
+}
+
+// Stop 停止后台调整
+func (c *Controller) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+	})
+}
+
+// Admit 判断是否接受路由优先级为priority的请求，接受时请求结束后需要调用Done
+// 未启用时总是接受，仍然统计正在处理的请求数，以便运行时启用
+func (c *Controller) Admit(priority string) bool {
+	n := c.inflight.Add(1)
+	cfg := c.settings.Load()
+	index := priorityIndex(priority)
+	if cfg != nil && cfg.Enabled && index != priorityCritical {
+		limit := c.limit.Load()
+		if index == priorityLow {
+			limit = int64(float64(limit) * cfg.LowPriorityShare)
+		}
+		if n > limit {
+			c.inflight.Add(-1)
+			c.shed[index].Add(1)
+			return false
+		}
+	}
+	c.admitted.Add(1)
+	return true
+}
+
+// Done 记录被接受的请求处理完成，start为Admit之前的时间
+func (c *Controller) Done(start time.Time) {
+	c.inflight.Add(-1)
+	c.latency.record(time.Since(start))
+}
+
+func (c *Controller) run() {
+	interval := time.Second
+	if cfg := c.settings.Load(); cfg != nil && cfg.Interval > 0 {
+		interval = cfg.Interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case now := <-ticker.C:
+			cfg := c.settings.Load()
+			if cfg == nil || !cfg.Enabled {
+				continue
+			}
+			c.adjust(cfg, now)
+			if cfg.Interval != interval {
+				interval = cfg.Interval
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// adjust 测量一个间隔的CPU使用率和延迟p99并调整并发上限
+func (c *Controller) adjust(cfg *types.LoadSheddingConfig, now time.Time) {
+	p99, count := c.latency.snapshot()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cpu = -1
+	if cpuTime, ok := processCPUTime(); ok {
+		if !c.lastWall.IsZero() {
+			if wall := now.Sub(c.lastWall); wall > 0 {
+				c.cpu = float64(cpuTime-c.lastCPU) / float64(wall) / float64(runtime.NumCPU()) * 100
+			}
+		}
+		c.lastWall, c.lastCPU = now, cpuTime
+	}
+	c.p99 = p99
+
+	overloaded := (cfg.CPUTarget > 0 && c.cpu > cfg.CPUTarget) ||
+		(cfg.LatencyTarget > 0 && count > 0 && p99 > cfg.LatencyTarget)
+
+	limit := c.limit.Load()
+	next := limit
+	if overloaded {
+		next = int64(float64(limit) * cfg.DecreaseFactor)
+		if next < int64(cfg.MinLimit) {
+			next = int64(cfg.MinLimit)
+		}
+	} else if next += int64(cfg.IncreaseStep); next > int64(cfg.MaxLimit) {
+		next = int64(cfg.MaxLimit)
+	}
+	c.limit.Store(next)
+
+	if overloaded != c.overloaded {
+		if overloaded {
+			fmt.Printf("[SHEDDING] Overloaded (cpu %.1f%%, p99 %v), concurrency limit %d -> %d\n", c.cpu, p99, limit, next)
+		} else {
+			fmt.Printf("[SHEDDING] Recovered (cpu %.1f%%, p99 %v), concurrency limit %d\n", c.cpu, p99, next)
+		}
+	}
+	c.overloaded = overloaded
+}
+
+// Stats 获取负载卸载统计
+func (c *Controller) Stats() Stats {
+	stats := Stats{
+		Limit:    c.limit.Load(),
+		Inflight: c.inflight.Load(),
+		Admitted: c.admitted.Load(),
+		Shed: map[string]int64{
+			types.PriorityNormal: c.shed[priorityNormal].Load(),
+			types.PriorityLow:    c.shed[priorityLow].Load(),
+		},
+	}
+	if cfg := c.settings.Load(); cfg != nil {
+		stats.Enabled = cfg.Enabled
+	}
+
+	c.mu.Lock()
+	stats.CPU = c.cpu
+	stats.LatencyP99 = c.p99.String()
+	stats.Overloaded = c.overloaded
+	c.mu.Unlock()
+	return stats
+}
+
+// priorityIndex 路由优先级对应的下标，未配置时为normal
+func priorityIndex(priority string) int {
+	switch priority {
+	case types.PriorityCritical:
+		return priorityCritical
+	case types.PriorityLow:
+		return priorityLow
+	default:
+		return priorityNormal
+	}
+}
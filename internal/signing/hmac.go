@@ -0,0 +1,198 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/internal/vars"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// HMAC签名的待签名字段
+const (
+	FieldMethod    = "method"
+	FieldHost      = "host"
+	FieldURI       = "uri"
+	FieldTimestamp = "timestamp"
+	FieldBody      = "body"
+
+	fieldHeaderPrefix = "header:" // header:<名称> 请求头的值，不存在时为空
+)
+
+// DefaultMaxClockSkew 校验签名时允许的默认时钟偏差
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// defaultHMACFields 未配置signed_fields时的待签名字段
+var defaultHMACFields = []string{FieldMethod, FieldHost, FieldURI, FieldTimestamp, FieldBody}
+
+// hmacSigner 通用HMAC-SHA256签名
+// 待签名字符串为signed_fields中各字段的值按顺序以\n连接，
+// 默认为: METHOD\nHOST\nREQUEST_URI\nTIMESTAMP\nhex(sha256(body))，不对请求体签名时body字段为UNSIGNED-PAYLOAD
+type hmacSigner struct {
+	secret          []byte
+	keyID           string
+	header          string
+	timestampHeader string
+	keyIDHeader     string
+	fields          []string
+	fieldsHeader    string // 配置了signed_fields时写入字段列表，使用默认字段时为空
+	signsBody       bool
+}
+
+func newHMACSigner(cfg *types.SigningConfig) (*hmacSigner, error) {
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("hmac signing requires secret")
+	}
+	if cfg.MaxClockSkew < 0 {
+		return nil, fmt.Errorf("max_clock_skew must not be negative")
+	}
+	fields, err := hmacFields(cfg.SignedFields)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &hmacSigner{
+		secret:          []byte(cfg.Secret),
+		keyID:           cfg.KeyID,
+		header:          cfg.Header,
+		timestampHeader: cfg.TimestampHeader,
+		keyIDHeader:     cfg.KeyIDHeader,
+		fields:          fields,
+	}
+	if len(cfg.SignedFields) > 0 {
+		s.fieldsHeader = cfg.FieldsHeader
+	}
+	for _, field := range fields {
+		if field == FieldBody {
+			s.signsBody = true
+		}
+	}
+	return s, nil
+}
+
+// hmacFields 校验待签名字段，为空时使用默认字段
+// 必须包含timestamp，否则签名可以被无限期重放
+func hmacFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return defaultHMACFields, nil
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		switch {
+		case field == FieldMethod, field == FieldHost, field == FieldURI, field == FieldTimestamp, field == FieldBody:
+		case strings.HasPrefix(field, fieldHeaderPrefix) && len(field) > len(fieldHeaderPrefix):
+		default:
+			return nil, fmt.Errorf("unknown signed field %q (expected method, host, uri, timestamp, body or header:<name>)", field)
+		}
+		key := strings.ToLower(field)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate signed field %q", field)
+		}
+		seen[key] = true
+	}
+	if !seen[FieldTimestamp] {
+		return nil, fmt.Errorf("signed_fields must include timestamp")
+	}
+	return fields, nil
+}
+
+// hmacStringToSign 按字段顺序生成待签名字符串
+func hmacStringToSign(fields []string, method, host, uri, timestamp, payloadHash string, header func(name string) string) string {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		switch field {
+		case FieldMethod:
+			values[i] = method
+		case FieldHost:
+			values[i] = host
+		case FieldURI:
+			values[i] = uri
+		case FieldTimestamp:
+			values[i] = timestamp
+		case FieldBody:
+			values[i] = payloadHash
+		default:
+			values[i] = header(field[len(fieldHeaderPrefix):])
+		}
+	}
+	return strings.Join(values, "\n")
+}
+
+// Sign 计算签名并写入签名、时间戳和密钥ID请求头
+func (s *hmacSigner) Sign(req *fasthttp.Request, now time.Time, signPayload bool) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	payloadHash := UnsignedPayload
+	if signPayload && s.signsBody {
+		payloadHash = sha256Hex(req.Body())
+	}
+	stringToSign := hmacStringToSign(s.fields,
+		string(req.Header.Method()),
+		string(req.Header.Host()),
+		requestURI(req),
+		timestamp,
+		payloadHash,
+		func(name string) string { return string(vars.PeekHeader(&req.Header, name)) },
+	)
+
+	req.Header.Set(s.timestampHeader, timestamp)
+	if s.keyID != "" {
+		req.Header.Set(s.keyIDHeader, s.keyID)
+	}
+	if s.fieldsHeader != "" {
+		req.Header.Set(s.fieldsHeader, strings.Join(s.fields, ","))
+	}
+	req.Header.Set(s.header, hex.EncodeToString(hmacSHA256(s.secret, []byte(stringToSign))))
+	return nil
+}
+
+// SignsPayload 待签名字段包括body时需要读取请求体
+func (s *hmacSigner) SignsPayload() bool {
+	return s.signsBody
+}
+
+// VerifyHMAC 校验经过代理签名的请求，供使用net/http的Go后端确认请求确实来自SpeedMimi
+// cfg为代理使用的上游签名配置（已设置默认值）；body为nil时按UNSIGNED-PAYLOAD校验
+// 时间戳与now相差超过max_clock_skew（默认5m）时拒绝，限制截获的请求被重放的时间窗口
+func VerifyHMAC(cfg *types.SigningConfig, r *http.Request, body []byte, now time.Time) error {
+	fields, err := hmacFields(cfg.SignedFields)
+	if err != nil {
+		return err
+	}
+
+	timestamp := r.Header.Get(cfg.TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s", cfg.TimestampHeader)
+	}
+	skew := cfg.MaxClockSkew
+	if skew == 0 {
+		skew = DefaultMaxClockSkew
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > skew || d < -skew {
+		return fmt.Errorf("signature timestamp is %v away from local time (max %v)", d.Round(time.Second), skew)
+	}
+
+	signature, err := hex.DecodeString(r.Header.Get(cfg.Header))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("missing or invalid %s", cfg.Header)
+	}
+
+	payloadHash := UnsignedPayload
+	if body != nil {
+		payloadHash = sha256Hex(body)
+	}
+	stringToSign := hmacStringToSign(fields, r.Method, r.Host, r.RequestURI, timestamp, payloadHash, r.Header.Get)
+	if !hmac.Equal(signature, hmacSHA256([]byte(cfg.Secret), []byte(stringToSign))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 签名类型
+const (
+	TypeSigV4 = "sigv4"
+	TypeHMAC  = "hmac"
+)
+
+// UnsignedPayload 不对请求体签名时代替请求体哈希的值
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Signer 上游请求签名器
+// Sign在请求发往后端前调用，此时Host头和请求URI已是最终发送的值
+// signPayload为false时不读取请求体，使用UnsignedPayload代替请求体哈希（请求体超过路由的检查上限时）
+type Signer interface {
+	Sign(req *fasthttp.Request, now time.Time, signPayload bool) error
+	SignsPayload() bool // 签名是否需要读取请求体
+}
+
+// New 根据配置创建签名器，cfg为nil时返回nil
+func New(cfg *types.SigningConfig) (Signer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Type {
+	case TypeSigV4:
+		return newSigV4Signer(cfg)
+	case TypeHMAC:
+		return newHMACSigner(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported signing type: %q", cfg.Type)
+	}
+}
+
+// sha256Hex 计算SHA-256并返回十六进制字符串
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// requestURI 获取实际发送给后端的请求URI（不做路径规范化）
+func requestURI(req *fasthttp.Request) string {
+	uri := req.URI()
+	uri.DisablePathNormalizing = true
+	return string(uri.RequestURI())
+}
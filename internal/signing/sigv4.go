@@ -0,0 +1,197 @@
+package signing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const (
+	sigV4Algorithm       = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat      = "20060102T150405Z"
+	sigV4DateFormat      = "20060102"
+	sigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sigV4Signer AWS Signature Version 4签名
+// 签名的请求头为host和x-amz-*，其余请求头不参与签名，代理添加的转发头不会破坏签名
+type sigV4Signer struct {
+	region          string
+	service         string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	unsignedPayload bool
+}
+
+func newSigV4Signer(cfg *types.SigningConfig) (*sigV4Signer, error) {
+	s := &sigV4Signer{
+		region:          cfg.Region,
+		service:         cfg.Service,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		sessionToken:    cfg.SessionToken,
+		unsignedPayload: cfg.UnsignedPayload,
+	}
+
+	// 未配置凭证时使用标准AWS环境变量
+	if s.accessKeyID == "" && s.secretAccessKey == "" {
+		s.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if s.sessionToken == "" {
+			s.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+
+	if s.region == "" || s.service == "" {
+		return nil, fmt.Errorf("sigv4 signing requires region and service")
+	}
+	if s.accessKeyID == "" || s.secretAccessKey == "" {
+		return nil, fmt.Errorf("sigv4 signing requires access_key_id and secret_access_key")
+	}
+	return s, nil
+}
+
+// Sign 计算签名并写入Authorization和x-amz-*请求头
+func (s *sigV4Signer) Sign(req *fasthttp.Request, now time.Time, signPayload bool) error {
+	now = now.UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	date := now.Format(sigV4DateFormat)
+
+	payloadHash := sigV4UnsignedPayload
+	if signPayload && !s.unsignedPayload {
+		payloadHash = sha256Hex(req.Body())
+	}
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	} else {
+		req.Header.Del("X-Amz-Security-Token")
+	}
+
+	rawPath, rawQuery, _ := strings.Cut(requestURI(req), "?")
+	if rawPath == "" {
+		rawPath = "/"
+	}
+
+	canonicalHeaders := "host:" + strings.TrimSpace(string(req.Header.Host())) + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	if s.sessionToken != "" {
+		canonicalHeaders += "x-amz-security-token:" + s.sessionToken + "\n"
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		string(req.Header.Method()),
+		s.canonicalURI(rawPath),
+		canonicalQuery(rawQuery),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/" + s.service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), []byte(date))
+	key = hmacSHA256(key, []byte(s.region))
+	key = hmacSHA256(key, []byte(s.service))
+	key = hmacSHA256(key, []byte("aws4_request"))
+	signature := hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.accessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// SignsPayload 配置unsigned_payload时签名不读取请求体
+func (s *sigV4Signer) SignsPayload() bool {
+	return !s.unsignedPayload
+}
+
+// canonicalURI 规范化路径
+// S3对路径只编码一次，其他服务对已编码的路径再编码一次
+func (s *sigV4Signer) canonicalURI(rawPath string) string {
+	if s.service == "s3" {
+		if decoded, err := url.PathUnescape(rawPath); err == nil {
+			rawPath = decoded
+		}
+	}
+	return uriEncode(rawPath, false)
+}
+
+// canonicalQuery 规范化查询字符串：参数按名称和值排序，名称和值按RFC 3986编码
+func canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type pair struct{ key, value string }
+	pairs := make([]pair, 0, strings.Count(rawQuery, "&")+1)
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		if k, err := url.PathUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.PathUnescape(value); err == nil {
+			value = v
+		}
+		pairs = append(pairs, pair{uriEncode(key, true), uriEncode(value, true)})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p.key + "=" + p.value
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 按SigV4规则编码，只保留非保留字符；encodeSlash为false时保留'/'
+func uriEncode(s string, encodeSlash bool) string {
+	const hexUpper = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexUpper[c>>4])
+			b.WriteByte(hexUpper[c&15])
+		}
+	}
+	return b.String()
+}
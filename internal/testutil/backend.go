@@ -0,0 +1,174 @@
+// Package testutil 集成测试使用的模拟后端、进程内代理和等待就绪辅助函数
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// Backend 监听在本机随机端口上的模拟后端
+// 响应JSON说明处理请求的后端，并通过X-Server响应头返回后端ID；
+// 查询参数sleep（如100ms）延迟响应，status设置状态码，size返回指定字节数的响应体
+type Backend struct {
+	ID   string
+	Host string
+	Port int
+
+	server   *http.Server
+	requests atomic.Int64
+	healthy  atomic.Bool
+}
+
+// StartBackend 启动模拟后端，测试结束时自动关闭
+func StartBackend(t testing.TB, id string) *Backend {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen for backend %s: %v", id, err)
+	}
+
+	b := &Backend{
+		ID:   id,
+		Host: "127.0.0.1",
+		Port: ln.Addr().(*net.TCPAddr).Port,
+	}
+	b.healthy.Store(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", b.handleHealth)
+	mux.HandleFunc("/", b.handle)
+	b.server = &http.Server{Handler: mux}
+
+	go b.server.Serve(ln)
+	t.Cleanup(b.Close)
+	return b
+}
+
+// Addr 后端监听地址 host:port
+func (b *Backend) Addr() string {
+	return net.JoinHostPort(b.Host, strconv.Itoa(b.Port))
+}
+
+// Requests 后端处理过的请求数（不包括健康检查）
+func (b *Backend) Requests() int64 {
+	return b.requests.Load()
+}
+
+// SetHealthy 设置/health的返回结果，不健康时返回503
+func (b *Backend) SetHealthy(healthy bool) {
+	b.healthy.Store(healthy)
+}
+
+// Config 生成后端配置，权重为1
+func (b *Backend) Config() *types.Backend {
+	return &types.Backend{
+		ID:     b.ID,
+		Name:   b.ID,
+		Host:   b.Host,
+		Port:   b.Port,
+		Weight: 1,
+		Scheme: "http",
+		Active: true,
+	}
+}
+
+// ServerBackend 生成指向httptest服务器的后端配置，权重为1；用于需要自定义处理函数的测试后端
+func ServerBackend(id string, server *httptest.Server) *types.Backend {
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	scheme := "http"
+	if server.TLS != nil {
+		scheme = "https"
+	}
+	return &types.Backend{
+		ID:     id,
+		Name:   id,
+		Host:   addr.IP.String(),
+		Port:   addr.Port,
+		Weight: 1,
+		Scheme: scheme,
+		Active: true,
+	}
+}
+
+// ReportPerformance 像真实后端一样向管理API上报性能信息
+func (b *Backend) ReportPerformance(adminAddr, upstream string, perf *types.PerformanceInfo) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"upstream":    upstream,
+		"backend_id":  b.ID,
+		"performance": perf,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post("http://"+adminAddr+"/api/v1/report", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("report performance: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close 关闭后端
+func (b *Backend) Close() {
+	b.server.Close()
+}
+
+// handle 处理普通请求
+func (b *Backend) handle(w http.ResponseWriter, r *http.Request) {
+	b.requests.Add(1)
+
+	query := r.URL.Query()
+	if sleep, err := time.ParseDuration(query.Get("sleep")); err == nil {
+		time.Sleep(sleep)
+	}
+
+	status := http.StatusOK
+	if code, err := strconv.Atoi(query.Get("status")); err == nil {
+		status = code
+	}
+
+	w.Header().Set("X-Server", b.ID)
+	if size, err := strconv.Atoi(query.Get("size")); err == nil && size >= 0 {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(status)
+		w.Write(bytes.Repeat([]byte("x"), size))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"server":        b.ID,
+		"path":          r.URL.Path,
+		"method":        r.Method,
+		"forwarded_for": r.Header.Get("X-Forwarded-For"),
+		"real_ip":       r.Header.Get("X-Real-IP"),
+	})
+}
+
+// handleHealth 处理健康检查
+func (b *Backend) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !b.healthy.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unhealthy"}`))
+		return
+	}
+	w.Write([]byte(`{"status":"healthy"}`))
+}
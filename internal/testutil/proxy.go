@@ -0,0 +1,239 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/grpcservice"
+	"github.com/quqi/speedmimi/internal/proxy"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// readyTimeout 等待代理和管理API开始监听的最长时间
+const readyTimeout = 5 * time.Second
+
+// Proxy 在测试进程内运行的代理服务器和管理API
+type Proxy struct {
+	Addr       string // 代理监听地址 host:port
+	AdminAddr  string // 管理API监听地址 host:port
+	ConfigPath string // 临时配置文件路径，通过管理API修改的配置会写回该文件
+
+	Config *config.Manager
+	Server *proxy.Server
+	admin  *grpcservice.Server
+
+	adminToken string // 配置了管理API令牌时Admin使用第一个令牌
+}
+
+// NewConfig 生成最小可用配置：default上游包含给定后端，路由/转发到default上游
+// 监听端口留空，由StartProxy分配
+func NewConfig(backends ...*Backend) *types.Config {
+	cfg := &types.Config{
+		Server: types.ServerConfig{
+			Host:         "127.0.0.1",
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		},
+		Backends: map[string][]*types.Backend{"default": {}},
+		Routing: map[string]*types.RoutingRule{
+			"default": {
+				Path:         "/",
+				Upstream:     "default",
+				LoadBalancer: types.LeastConnectionsWeight,
+			},
+		},
+		GRPC: types.GRPCConfig{
+			Enabled: true,
+			Host:    "127.0.0.1",
+		},
+	}
+	for _, b := range backends {
+		cfg.Backends["default"] = append(cfg.Backends["default"], b.Config())
+	}
+	return cfg
+}
+
+// StartProxy 把配置写入临时文件，在进程内启动代理和管理API并等待就绪，测试结束时自动停止
+// 配置中代理或管理API端口为0时分配空闲端口
+// 配置中启用了config_watch时同时监听配置文件
+func StartProxy(t testing.TB, cfg *types.Config) *Proxy {
+	t.Helper()
+
+	if cfg.Server.Port == 0 {
+		cfg.Server.Port = mustFreePort(t)
+	}
+	if cfg.GRPC.Enabled && cfg.GRPC.Port == 0 {
+		cfg.GRPC.Port = mustFreePort(t)
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.ConfigWatch.Enabled {
+		if err := mgr.StartFileWatch(mgr.GetConfig().ConfigWatch.Debounce); err != nil {
+			t.Fatalf("failed to watch config: %v", err)
+		}
+		t.Cleanup(mgr.StopFileWatch)
+	}
+	server, err := proxy.NewServer(mgr)
+	if err != nil {
+		t.Fatalf("failed to create proxy server: %v", err)
+	}
+
+	p := &Proxy{
+		Addr:       net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)),
+		ConfigPath: path,
+		Config:     mgr,
+		Server:     server,
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		if err := server.Start(); err != nil {
+			serveErr <- fmt.Errorf("proxy server: %w", err)
+		}
+	}()
+	t.Cleanup(func() { server.Stop() })
+
+	if cfg.GRPC.Enabled {
+		p.AdminAddr = net.JoinHostPort(cfg.GRPC.Host, strconv.Itoa(cfg.GRPC.Port))
+		if len(cfg.GRPC.Auth.Tokens) > 0 {
+			p.adminToken = cfg.GRPC.Auth.Tokens[0]
+		}
+		p.admin = grpcservice.NewServer(mgr, server, server.GetMonitor())
+		go func() {
+			if err := p.admin.Start(cfg.GRPC.Host, cfg.GRPC.Port); err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("management API: %w", err)
+			}
+		}()
+		t.Cleanup(func() { p.admin.Stop() })
+	}
+
+	for _, addr := range []string{p.Addr, p.AdminAddr} {
+		if addr == "" {
+			continue
+		}
+		if err := WaitForReady(addr, readyTimeout); err != nil {
+			select {
+			case serveErr := <-serveErr:
+				t.Fatalf("failed to start: %v", serveErr)
+			default:
+				t.Fatalf("failed to start: %v", err)
+			}
+		}
+	}
+	return p
+}
+
+// URL 代理上路径的完整URL
+func (p *Proxy) URL(path string) string {
+	return "http://" + p.Addr + path
+}
+
+// AdminURL 管理API上路径的完整URL
+func (p *Proxy) AdminURL(path string) string {
+	return "http://" + p.AdminAddr + path
+}
+
+// Admin 调用管理API，body不为nil时编码为JSON请求体，out不为nil时解码JSON响应
+// 响应状态码不是200时返回包含响应内容的错误
+func (p *Proxy) Admin(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, p.AdminURL(path), reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.adminToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// AdminConn 连接管理API的gRPC服务，配置了管理API令牌时每个调用携带第一个令牌
+func (p *Proxy) AdminConn(t testing.TB) *grpc.ClientConn {
+	return DialAdmin(t, p.AdminAddr, p.adminToken)
+}
+
+// DialAdmin 通过明文HTTP/2连接管理API的gRPC服务，token不为空时每个调用携带该令牌，测试结束时自动关闭
+func DialAdmin(t testing.TB, addr, token string) *grpc.ClientConn {
+	t.Helper()
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if token != "" {
+		opts = append(opts, grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}))
+	}
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		t.Fatalf("failed to dial management API: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// mustFreePort 获取空闲端口，失败时终止测试
+func mustFreePort(t testing.TB) int {
+	t.Helper()
+	port, err := FreePort()
+	if err != nil {
+		t.Fatalf("failed to get free port: %v", err)
+	}
+	return port
+}
@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// pollInterval 等待条件满足时的轮询间隔
+const pollInterval = 20 * time.Millisecond
+
+// FreePort 获取一个本机空闲的TCP端口
+// 端口在返回前已释放，其他进程可能在使用前抢占，只用于测试
+func FreePort() (int, error) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// WaitForReady 等待地址可以建立TCP连接，超时返回错误
+func WaitForReady(addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, pollInterval*5)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%s not ready after %v: %w", addr, timeout, err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Eventually 轮询直到cond返回true，超时返回false
+// 用于等待异步生效的操作（如断开后端、性能上报）
+func Eventually(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// exporter 把span批量编码为OTLP/HTTP JSON发送到收集器，队列满时丢弃新的span
+type exporter struct {
+	cfg    types.TracingConfig
+	client *http.Client
+	queue  chan *Span
+
+	exported atomic.Int64
+	dropped  atomic.Int64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newExporter(cfg *types.TracingConfig) *exporter {
+	e := &exporter{
+		cfg:   *cfg,
+		stop:  make(chan struct{}),
+		queue: make(chan *Span, valueOr(cfg.QueueSize, DefaultQueueSize)),
+	}
+	if e.cfg.ServiceName == "" {
+		e.cfg.ServiceName = DefaultServiceName
+	}
+	e.cfg.BatchSize = valueOr(cfg.BatchSize, DefaultBatchSize)
+	if e.cfg.FlushInterval == 0 {
+		e.cfg.FlushInterval = DefaultFlushInterval
+	}
+	if e.cfg.Timeout == 0 {
+		e.cfg.Timeout = DefaultTimeout
+	}
+	e.client = &http.Client{Timeout: e.cfg.Timeout}
+
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+func valueOr(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// enqueue 放入导出队列，不会阻塞
+func (e *exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// close 发送队列中剩余的span后停止
+func (e *exporter) close() {
+	e.closeOnce.Do(func() { close(e.stop) })
+	e.wg.Wait()
+}
+
+func (e *exporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, e.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= e.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stop:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+					if len(batch) >= e.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export 发送一批span，失败时丢弃
+func (e *exporter) export(batch []*Span) {
+	body, err := json.Marshal(e.encode(batch))
+	if err == nil {
+		err = e.send(body)
+	}
+	if err != nil {
+		e.dropped.Add(int64(len(batch)))
+		fmt.Printf("[TRACING] Failed to export %d spans to %s: %v\n", len(batch), e.cfg.Endpoint, err)
+		return
+	}
+	e.exported.Add(int64(len(batch)))
+}
+
+// send 发送一次请求，2xx响应视为成功
+func (e *exporter) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SpeedMimi-OTLP")
+	for name, value := range e.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP JSON编码（opentelemetry-proto的JSON映射）：ID为十六进制，64位整数为字符串
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// spanKindServer SPAN_KIND_SERVER
+const spanKindServer = 2
+
+func (e *exporter) encode(batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.ctx.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.ctx.SpanID[:]),
+			Name:              s.name,
+			Kind:              spanKindServer,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        make([]otlpKeyValue, len(s.attrs)),
+			Status:            otlpStatus{Code: s.status, Message: s.msg},
+		}
+		if s.parent != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		for j, attr := range s.attrs {
+			span.Attributes[j] = keyValue(attr.key, attr.value)
+		}
+		spans[i] = span
+	}
+
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{keyValue("service.name", e.cfg.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: DefaultServiceName}, Spans: spans}},
+	}}}
+}
+
+func keyValue(key string, value interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &s
+	case bool:
+		kv.Value.BoolValue = &v
+	}
+	return kv
+}
@@ -0,0 +1,246 @@
+// Package tracing OpenTelemetry分布式跟踪：W3C Trace Context传递和OTLP/HTTP导出
+package tracing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// W3C Trace Context请求头
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// 默认值
+const (
+	DefaultServiceName   = "speedmimi"
+	DefaultBatchSize     = 512
+	DefaultFlushInterval = 5 * time.Second
+	DefaultQueueSize     = 2048
+	DefaultTimeout       = 10 * time.Second
+)
+
+// SpanContext 在服务间传递的跟踪上下文
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// ParseTraceParent 解析traceparent请求头（版本00，更高版本只读取前55个字符），格式无效时返回false
+func ParseTraceParent(v []byte) (SpanContext, bool) {
+	var sc SpanContext
+	if len(v) < 55 || (len(v) > 55 && v[55] != '-') || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return sc, false
+	}
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], v[0:2]) || version[0] == 0xff || (version[0] == 0 && len(v) != 55) {
+		return sc, false
+	}
+	if !decodeLowerHex(sc.TraceID[:], v[3:35]) || !decodeLowerHex(sc.SpanID[:], v[36:52]) || !decodeLowerHex(flags[:], v[53:55]) {
+		return sc, false
+	}
+	if sc.TraceID == [16]byte{} || sc.SpanID == [8]byte{} {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// decodeLowerHex 解码小写十六进制
+func decodeLowerHex(dst, src []byte) bool {
+	for _, c := range src {
+		if c >= 'A' && c <= 'F' {
+			return false
+		}
+	}
+	_, err := hex.Decode(dst, src)
+	return err == nil
+}
+
+// TraceParent 格式化为traceparent请求头
+func (sc SpanContext) TraceParent() string {
+	flags := 0
+	if sc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID[:], sc.SpanID[:], flags)
+}
+
+// Validate 校验跟踪配置
+func Validate(cfg *types.TracingConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint must be an absolute http or https URL, got %q", cfg.Endpoint)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1, got %v", cfg.SampleRatio)
+	}
+	if cfg.BatchSize < 0 || cfg.QueueSize < 0 || cfg.FlushInterval < 0 || cfg.Timeout < 0 {
+		return fmt.Errorf("batch_size, queue_size, flush_interval and timeout must not be negative")
+	}
+	return nil
+}
+
+// Tracer 创建span并在后台批量导出
+type Tracer struct {
+	cfg       types.TracingConfig
+	threshold uint64 // 跟踪ID低64位右移一位后小于该值的新跟踪被采样
+	exporter  *exporter
+}
+
+// New 创建跟踪器并启动导出协程，cfg中为0的值使用默认值
+func New(cfg *types.TracingConfig) *Tracer {
+	t := &Tracer{cfg: *cfg}
+	ratio := cfg.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	t.threshold = uint64(ratio * math.MaxInt64)
+	if ratio == 1 {
+		t.threshold = math.MaxUint64
+	}
+	t.exporter = newExporter(cfg)
+	return t
+}
+
+// Config 跟踪器使用的配置
+func (t *Tracer) Config() types.TracingConfig {
+	return t.cfg
+}
+
+// Stats 已导出和丢弃的span数
+func (t *Tracer) Stats() (exported, dropped int64) {
+	return t.exporter.exported.Load(), t.exporter.dropped.Load()
+}
+
+// Close 导出队列中剩余的span后停止导出协程
+func (t *Tracer) Close() {
+	t.exporter.close()
+}
+
+// StartServerSpan 为收到的请求创建服务端span
+// traceparent有效时作为父span并沿用其采样标志，否则开始新的跟踪并按sample_ratio采样
+func (t *Tracer) StartServerSpan(name string, traceparent []byte, start time.Time) *Span {
+	s := &Span{tracer: t, name: name, start: start}
+	if parent, ok := ParseTraceParent(traceparent); ok {
+		s.ctx.TraceID = parent.TraceID
+		s.ctx.Sampled = parent.Sampled
+		s.parent = parent.SpanID
+	} else {
+		randomID(s.ctx.TraceID[:])
+		s.ctx.Sampled = binary.BigEndian.Uint64(s.ctx.TraceID[8:])>>1 < t.threshold
+	}
+	randomID(s.ctx.SpanID[:])
+	return s
+}
+
+// randomID 生成非全零的随机ID
+func randomID(id []byte) {
+	for {
+		for i := 0; i < len(id); i += 8 {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], rand.Uint64())
+			copy(id[i:], b[:])
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
+
+// statusError STATUS_CODE_ERROR
+const statusError = 2
+
+// attribute span属性，值为string、int64或bool
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span 一个请求的跟踪记录，属性只在处理请求的协程中设置
+type Span struct {
+	tracer *Tracer
+	ctx    SpanContext
+	parent [8]byte
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  []attribute
+	status int
+	msg    string
+
+	attempts atomic.Int32
+	ended    sync.Once
+}
+
+// Context span的跟踪上下文，用于向后端传递
+func (s *Span) Context() SpanContext {
+	return s.ctx
+}
+
+// Sampled span是否被采样，未采样的span只用于传递跟踪上下文
+func (s *Span) Sampled() bool {
+	return s.ctx.Sampled
+}
+
+// SetName 修改span名称
+func (s *Span) SetName(name string) {
+	s.name = name
+}
+
+// SetString 设置字符串属性，值为空时忽略
+func (s *Span) SetString(key, value string) {
+	if s.ctx.Sampled && value != "" {
+		s.attrs = append(s.attrs, attribute{key, value})
+	}
+}
+
+// SetInt 设置整数属性
+func (s *Span) SetInt(key string, value int64) {
+	if s.ctx.Sampled {
+		s.attrs = append(s.attrs, attribute{key, value})
+	}
+}
+
+// SetError 把span标记为失败
+func (s *Span) SetError(msg string) {
+	s.status = statusError
+	s.msg = msg
+}
+
+// AddAttempt 记录一次转发到后端的尝试，返回已尝试的次数
+func (s *Span) AddAttempt() int {
+	return int(s.attempts.Add(1))
+}
+
+// Attempts 转发到后端的尝试次数
+func (s *Span) Attempts() int {
+	return int(s.attempts.Load())
+}
+
+// End 结束span，采样的span放入导出队列
+func (s *Span) End(end time.Time) {
+	s.ended.Do(func() {
+		if !s.ctx.Sampled {
+			return
+		}
+		s.end = end
+		s.tracer.exporter.enqueue(s)
+	})
+}
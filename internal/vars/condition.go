@@ -0,0 +1,156 @@
+package vars
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// Condition 预编译的匹配条件
+type Condition struct {
+	value   *Template
+	equals  string
+	prefix  string
+	re      *regexp.Regexp
+	in      []string
+	exists  *bool
+	percent int
+	salt    string
+	not     bool
+	op      string
+}
+
+// CompileCondition 编译匹配条件
+func CompileCondition(cfg *types.MatchCondition) (*Condition, error) {
+	if cfg.Value == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+	value, err := Compile(cfg.Value)
+	if err != nil {
+		return nil, fmt.Errorf("value: %w", err)
+	}
+
+	c := &Condition{
+		value:  value,
+		equals: cfg.Equals,
+		prefix: cfg.Prefix,
+		in:     cfg.In,
+		exists: cfg.Exists,
+		salt:   cfg.Salt,
+		not:    cfg.Not,
+	}
+
+	ops := 0
+	if cfg.Equals != "" {
+		ops++
+		c.op = "equals"
+	}
+	if cfg.Prefix != "" {
+		ops++
+		c.op = "prefix"
+	}
+	if cfg.Regex != "" {
+		ops++
+		c.op = "regex"
+		if c.re, err = regexp.Compile(cfg.Regex); err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+	}
+	if len(cfg.In) > 0 {
+		ops++
+		c.op = "in"
+	}
+	if cfg.Exists != nil {
+		ops++
+		c.op = "exists"
+	}
+	if cfg.Percent != nil {
+		ops++
+		c.op = "percent"
+		c.percent = *cfg.Percent
+		if c.percent < 0 || c.percent > 100 {
+			return nil, fmt.Errorf("percent must be between 0 and 100, got %d", c.percent)
+		}
+	} else if cfg.Salt != "" {
+		return nil, fmt.Errorf("salt requires percent")
+	}
+	if ops != 1 {
+		return nil, fmt.Errorf("exactly one of equals, prefix, regex, in, exists or percent is required")
+	}
+
+	return c, nil
+}
+
+// CompileConditions 编译一组匹配条件
+func CompileConditions(cfgs []types.MatchCondition) ([]*Condition, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	conds := make([]*Condition, 0, len(cfgs))
+	for i := range cfgs {
+		c, err := CompileCondition(&cfgs[i])
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i, err)
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+// Match 判断条件是否满足
+func (c *Condition) Match(env *Env) bool {
+	value := c.value.Render(env)
+
+	var matched bool
+	switch c.op {
+	case "equals":
+		matched = value == c.equals
+	case "prefix":
+		matched = strings.HasPrefix(value, c.prefix)
+	case "regex":
+		matched = c.re.MatchString(value)
+	case "in":
+		for _, v := range c.in {
+			if value == v {
+				matched = true
+				break
+			}
+		}
+	case "exists":
+		matched = (value != "") == *c.exists
+	case "percent":
+		// 值为空（如未登录用户没有ID）时无法稳定分桶，不满足条件
+		matched = value != "" && PercentBucket(c.salt, value) < c.percent
+	}
+
+	return matched != c.not
+}
+
+// PercentBucket 把值确定性地映射到0-99的分桶：FNV-1a(salt + ":" + value) % 100
+// 同一个值总是落在同一个分桶，灰度比例从N增加到M时原来选中的值仍然选中
+func PercentBucket(salt, value string) int {
+	return HashBucket(salt, value, 100)
+}
+
+// HashBucket 把值确定性地映射到[0, n)的分桶：FNV-1a(salt + ":" + value) % n
+func HashBucket(salt, value string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(salt))
+	h.Write([]byte{':'})
+	h.Write([]byte(value))
+	return int(h.Sum32() % uint32(n))
+}
+
+// MatchAll 判断是否满足全部条件，没有条件时返回true
+func MatchAll(conds []*Condition, env *Env) bool {
+	for _, c := range conds {
+		if !c.Match(env) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package vars
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// GeoTable $geo变量的查找表
+type GeoTable struct {
+	networks []geoNetwork // 按前缀长度降序
+	header   string
+	def      string
+}
+
+// geoNetwork 网段和对应的值
+type geoNetwork struct {
+	ipnet *net.IPNet
+	ones  int
+	value string
+}
+
+// NewGeoTable 根据配置创建查找表，未配置任何规则时返回nil
+func NewGeoTable(cfg *types.GeoConfig) (*GeoTable, error) {
+	if len(cfg.Networks) == 0 && cfg.Header == "" && cfg.Default == "" {
+		return nil, nil
+	}
+
+	g := &GeoTable{header: cfg.Header, def: cfg.Default}
+	for i, network := range cfg.Networks {
+		_, ipnet, err := net.ParseCIDR(network.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("network %d: invalid cidr %q", i, network.CIDR)
+		}
+		ones, _ := ipnet.Mask.Size()
+		g.networks = append(g.networks, geoNetwork{ipnet: ipnet, ones: ones, value: network.Value})
+	}
+	sort.SliceStable(g.networks, func(i, j int) bool {
+		return g.networks[i].ones > g.networks[j].ones
+	})
+
+	return g, nil
+}
+
+// Lookup 查找客户端的地理位置值
+func (g *GeoTable) Lookup(clientIP string, header *fasthttp.RequestHeader) string {
+	if len(g.networks) > 0 {
+		if ip := net.ParseIP(clientIP); ip != nil {
+			for _, network := range g.networks {
+				if network.ipnet.Contains(ip) {
+					return network.value
+				}
+			}
+		}
+	}
+
+	if g.header != "" {
+		if v := PeekHeader(header, g.header); len(v) > 0 {
+			return string(v)
+		}
+	}
+
+	return g.def
+}
+
+// ParseGeoFile 解析网段文件，每行为"CIDR 值"，#开头的行和空行被忽略
+func ParseGeoFile(data []byte) ([]types.GeoNetwork, error) {
+	var networks []types.GeoNetwork
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"CIDR value\", got %q", line, text)
+		}
+		if _, _, err := net.ParseCIDR(fields[0]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid cidr %q", line, fields[0])
+		}
+		networks = append(networks, types.GeoNetwork{CIDR: fields[0], Value: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
@@ -0,0 +1,115 @@
+package vars
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template 预编译的变量模板
+// 语法：$name 或 ${name}，$$ 表示字面量 $；变量名由字母、数字和下划线组成，${} 中还可以包含连字符
+type Template struct {
+	raw   string
+	parts []part
+}
+
+// part 模板片段：字面量或变量
+type part struct {
+	literal string
+	get     getter
+}
+
+// Compile 编译模板，引用未知变量时返回错误
+func Compile(s string) (*Template, error) {
+	t := &Template{raw: s}
+
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, part{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			literal.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == '$' {
+			literal.WriteByte('$')
+			i++
+			continue
+		}
+
+		var name string
+		if i+1 < len(s) && s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated variable at offset %d", i)
+			}
+			name = s[i+2 : i+2+end]
+			i += end + 2
+		} else {
+			j := i + 1
+			for j < len(s) && isNameByte(s[j]) {
+				j++
+			}
+			name = s[i+1 : j]
+			i = j - 1
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("empty variable name at offset %d (use $$ for a literal $)", i)
+		}
+		get, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		flush()
+		t.parts = append(t.parts, part{get: get})
+	}
+	flush()
+
+	return t, nil
+}
+
+// MustCompile 编译模板，失败时panic，用于已校验过的配置
+func MustCompile(s string) *Template {
+	t, err := Compile(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Append 求值并追加到dst
+func (t *Template) Append(dst []byte, env *Env) []byte {
+	for _, p := range t.parts {
+		if p.get != nil {
+			dst = p.get(env, dst)
+		} else {
+			dst = append(dst, p.literal...)
+		}
+	}
+	return dst
+}
+
+// Render 求值为字符串
+func (t *Template) Render(env *Env) string {
+	if len(t.parts) == 1 && t.parts[0].get == nil {
+		return t.parts[0].literal
+	}
+	return string(t.Append(nil, env))
+}
+
+// String 返回模板原文
+func (t *Template) String() string {
+	return t.raw
+}
+
+// isNameByte 判断是否为变量名字符
+func isNameByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
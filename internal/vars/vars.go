@@ -0,0 +1,310 @@
+package vars
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 请求上下文中记录的用户值
+const (
+	UserValueRoute     = "speedmimi.route"
+	UserValueUpstream  = "speedmimi.upstream"
+	UserValueBackend   = "speedmimi.backend"
+	UserValueFanOut    = "speedmimi.fanout." // 加目标名称为扇出目标的JSON响应，供$fanout_<名称>使用
+	userValueEnv       = "speedmimi.vars"
+	userValueRequestID = "speedmimi.request_id"
+)
+
+// RequestIDHeader 请求ID头，客户端传入时沿用，否则由代理生成
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用客户端请求ID的最大长度
+const maxRequestIDLength = 128
+
+// Env 变量求值环境，每个请求一个，按需计算的值缓存在其中
+type Env struct {
+	Ctx      *fasthttp.RequestCtx
+	ClientIP string    // 按real_ip_header和可信代理规则解析的客户端IP
+	Geo      *GeoTable // 为nil时$geo为空
+
+	geo    string
+	geoSet bool
+	bucket int // 随机百分比分桶+1，0表示尚未生成
+}
+
+// FromContext 获取请求上已创建的求值环境，不存在时返回nil
+func FromContext(ctx *fasthttp.RequestCtx) *Env {
+	env, _ := ctx.UserValue(userValueEnv).(*Env)
+	return env
+}
+
+// Attach 将求值环境保存到请求上，同一请求的后续求值复用
+func Attach(ctx *fasthttp.RequestCtx, env *Env) {
+	ctx.SetUserValue(userValueEnv, env)
+}
+
+// RequestID 获取请求ID：沿用客户端传入的合法请求ID，否则生成随机ID，同一请求多次调用返回相同的值
+func RequestID(ctx *fasthttp.RequestCtx) string {
+	if id, ok := ctx.UserValue(userValueRequestID).(string); ok {
+		return id
+	}
+
+	var id string
+	if v := PeekHeader(&ctx.Request.Header, RequestIDHeader); len(v) > 0 && len(v) <= maxRequestIDLength && isPrintableASCII(v) {
+		id = string(v)
+	} else {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			id = fmt.Sprintf("%016x", ctx.ID())
+		} else {
+			id = hex.EncodeToString(buf)
+		}
+	}
+
+	ctx.SetUserValue(userValueRequestID, id)
+	return id
+}
+
+// isPrintableASCII 判断是否只包含可打印ASCII字符，避免日志和响应头注入
+func isPrintableASCII(b []byte) bool {
+	for _, c := range b {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// getter 变量取值函数，将值追加到dst
+type getter func(env *Env, dst []byte) []byte
+
+// builtins 内置变量
+var builtins = map[string]getter{
+	"client_ip": func(env *Env, dst []byte) []byte {
+		return append(dst, env.ClientIP...)
+	},
+	"remote_addr": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.RemoteAddr().String()...)
+	},
+	"host": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.Host()...)
+	},
+	"method": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.Method()...)
+	},
+	"scheme": func(env *Env, dst []byte) []byte {
+		if env.Ctx.IsTLS() {
+			return append(dst, "https"...)
+		}
+		return append(dst, "http"...)
+	},
+	"uri": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.RequestURI()...)
+	},
+	"path": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.Path()...)
+	},
+	"query_string": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.URI().QueryString()...)
+	},
+	"route": func(env *Env, dst []byte) []byte {
+		return appendUserValue(env, UserValueRoute, dst)
+	},
+	"upstream": func(env *Env, dst []byte) []byte {
+		return appendUserValue(env, UserValueUpstream, dst)
+	},
+	"backend": func(env *Env, dst []byte) []byte {
+		return appendUserValue(env, UserValueBackend, dst)
+	},
+	"request_id": func(env *Env, dst []byte) []byte {
+		return append(dst, RequestID(env.Ctx)...)
+	},
+	"geo": func(env *Env, dst []byte) []byte {
+		return append(dst, env.geoValue()...)
+	},
+	"bucket": func(env *Env, dst []byte) []byte {
+		return strconv.AppendInt(dst, int64(env.Bucket()), 10)
+	},
+	"status": func(env *Env, dst []byte) []byte {
+		return strconv.AppendInt(dst, int64(env.Ctx.Response.StatusCode()), 10)
+	},
+	"connection_id": func(env *Env, dst []byte) []byte {
+		return strconv.AppendUint(dst, env.Ctx.ConnID(), 10)
+	},
+	"protocol": func(env *Env, dst []byte) []byte {
+		return append(dst, env.Ctx.Request.Header.Protocol()...)
+	},
+	"body_bytes_sent": func(env *Env, dst []byte) []byte {
+		return strconv.AppendInt(dst, ResponseBodySize(&env.Ctx.Response), 10)
+	},
+	"request_time": func(env *Env, dst []byte) []byte {
+		// 从收到请求到当前的秒数，精确到毫秒
+		return strconv.AppendFloat(dst, time.Since(env.Ctx.Time()).Seconds(), 'f', 3, 64)
+	},
+	"time_unix": func(env *Env, dst []byte) []byte {
+		return strconv.AppendInt(dst, time.Now().Unix(), 10)
+	},
+	"time_iso8601": func(env *Env, dst []byte) []byte {
+		return time.Now().UTC().AppendFormat(dst, time.RFC3339)
+	},
+}
+
+// lookup 根据变量名获取取值函数
+// 除内置变量外支持前缀变量：http_<名称>（请求头）、sent_http_<名称>（响应头）、cookie_<名称>、arg_<名称>（查询参数）、
+// fanout_<名称>（扇出目标的JSON响应，目标失败或不是扇出路由时为null）
+func lookup(name string) (getter, error) {
+	if get, ok := builtins[name]; ok {
+		return get, nil
+	}
+
+	switch {
+	case strings.HasPrefix(name, "sent_http_") && len(name) > len("sent_http_"):
+		header := headerName(name[len("sent_http_"):])
+		return func(env *Env, dst []byte) []byte {
+			return append(dst, PeekResponseHeader(&env.Ctx.Response.Header, header)...)
+		}, nil
+	case strings.HasPrefix(name, "http_") && len(name) > len("http_"):
+		header := headerName(name[len("http_"):])
+		return func(env *Env, dst []byte) []byte {
+			return append(dst, PeekHeader(&env.Ctx.Request.Header, header)...)
+		}, nil
+	case strings.HasPrefix(name, "cookie_") && len(name) > len("cookie_"):
+		cookie := name[len("cookie_"):]
+		return func(env *Env, dst []byte) []byte {
+			return append(dst, env.Ctx.Request.Header.Cookie(cookie)...)
+		}, nil
+	case strings.HasPrefix(name, "arg_") && len(name) > len("arg_"):
+		arg := name[len("arg_"):]
+		return func(env *Env, dst []byte) []byte {
+			return append(dst, env.Ctx.QueryArgs().Peek(arg)...)
+		}, nil
+	case strings.HasPrefix(name, "fanout_") && len(name) > len("fanout_"):
+		key := UserValueFanOut + name[len("fanout_"):]
+		return func(env *Env, dst []byte) []byte {
+			if v, ok := env.Ctx.UserValue(key).([]byte); ok {
+				return append(dst, v...)
+			}
+			return append(dst, "null"...)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown variable $%s", name)
+}
+
+// Bucket 本次请求的随机百分比分桶 (0-99)，同一请求内保持不变
+func (e *Env) Bucket() int {
+	if e.bucket == 0 {
+		e.bucket = mrand.Intn(100) + 1
+	}
+	return e.bucket - 1
+}
+
+// geoValue 获取客户端的地理位置值，同一请求内只查找一次
+func (e *Env) geoValue() string {
+	if !e.geoSet {
+		e.geoSet = true
+		if e.Geo != nil {
+			e.geo = e.Geo.Lookup(e.ClientIP, &e.Ctx.Request.Header)
+		}
+	}
+	return e.geo
+}
+
+// appendUserValue 追加请求上记录的字符串用户值
+func appendUserValue(env *Env, key string, dst []byte) []byte {
+	if v, ok := env.Ctx.UserValue(key).(string); ok {
+		return append(dst, v...)
+	}
+	return dst
+}
+
+// headerName 将变量名中的请求头名称转换为规范形式，下划线替换为连字符（如 x_canary -> X-Canary）
+func headerName(name string) string {
+	parts := strings.Split(strings.ReplaceAll(name, "_", "-"), "-")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// PeekHeader 忽略大小写获取请求头
+// 代理关闭了请求头名称规范化，客户端发送的小写请求头无法通过Peek直接匹配
+func PeekHeader(h *fasthttp.RequestHeader, name string) []byte {
+	if v := h.Peek(name); len(v) > 0 {
+		return v
+	}
+
+	var value []byte
+	target := []byte(name)
+	h.VisitAll(func(key, v []byte) {
+		if value == nil && bytes.EqualFold(key, target) {
+			value = v
+		}
+	})
+	return value
+}
+
+// DelHeader 忽略大小写删除请求头的所有同名变体，用于删除客户端伪造的代理头
+func DelHeader(h *fasthttp.RequestHeader, name string) {
+	var keys []string
+	target := []byte(name)
+	h.VisitAll(func(key, _ []byte) {
+		if bytes.EqualFold(key, target) {
+			keys = append(keys, string(key))
+		}
+	})
+	for _, key := range keys {
+		h.Del(key)
+	}
+}
+
+// ResponseBodySize 响应体的字节数，以流方式返回的响应体不读取，按Content-Length计算（长度未知时为0）
+func ResponseBodySize(resp *fasthttp.Response) int64 {
+	if resp.IsBodyStream() {
+		if n := resp.Header.ContentLength(); n > 0 {
+			return int64(n)
+		}
+		return 0
+	}
+	return int64(len(resp.Body()))
+}
+
+// PeekResponseHeader 忽略大小写获取响应头（上游可能发送Etag等非规范大小写的头名）
+func PeekResponseHeader(h *fasthttp.ResponseHeader, name string) []byte {
+	if v := h.Peek(name); len(v) > 0 {
+		return v
+	}
+
+	var value []byte
+	target := []byte(name)
+	h.VisitAll(func(key, v []byte) {
+		if value == nil && bytes.EqualFold(key, target) {
+			value = v
+		}
+	})
+	return value
+}
+
+// DelResponseHeader 忽略大小写删除响应头的所有同名变体，用于替换上游返回的头
+func DelResponseHeader(h *fasthttp.ResponseHeader, name string) {
+	var keys []string
+	target := []byte(name)
+	h.VisitAll(func(key, _ []byte) {
+		if bytes.EqualFold(key, target) {
+			keys = append(keys, string(key))
+		}
+	})
+	for _, key := range keys {
+		h.Del(key)
+	}
+}
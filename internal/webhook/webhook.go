@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 事件类型
+const (
+	EventBackendHealthy    = "backend_healthy"    // 后端恢复健康
+	EventBackendUnhealthy  = "backend_unhealthy"  // 后端被判定为不健康
+	EventBackendDisconnect = "backend_disconnect" // 后端被标记为断开
+)
+
+// queueSize 每个Webhook待发送事件的队列长度，队列满时丢弃新事件
+const queueSize = 256
+
+// Event 通知事件
+type Event struct {
+	Type      string    `json:"type"`
+	Node      string    `json:"node,omitempty"` // 集群节点名称
+	Upstream  string    `json:"upstream"`
+	BackendID string    `json:"backend_id"`
+	Address   string    `json:"address"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Validate 校验Webhook配置
+func Validate(cfgs []types.WebhookConfig) error {
+	for i, cfg := range cfgs {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook %d: url must be an absolute http or https URL, got %q", i, cfg.URL)
+		}
+		for _, event := range cfg.Events {
+			switch event {
+			case EventBackendHealthy, EventBackendUnhealthy, EventBackendDisconnect:
+			default:
+				return fmt.Errorf("webhook %d: unknown event %q", i, event)
+			}
+		}
+		if cfg.Timeout < 0 || cfg.RetryBackoff < 0 {
+			return fmt.Errorf("webhook %d: timeout and retry_backoff must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// Notifier 异步发送事件通知，每个Webhook使用独立的队列和发送协程，发送失败时按指数退避重试
+type Notifier struct {
+	configs []types.WebhookConfig
+	hooks   []*hook
+	client  *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// hook 单个Webhook
+type hook struct {
+	cfg    types.WebhookConfig
+	events map[string]bool // 为空时订阅全部事件
+	queue  chan Event
+}
+
+// New 创建通知器并启动发送协程
+func New(cfgs []types.WebhookConfig) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		configs: cfgs,
+		client:  &http.Client{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for _, cfg := range cfgs {
+		h := &hook{cfg: cfg, queue: make(chan Event, queueSize)}
+		if len(cfg.Events) > 0 {
+			h.events = make(map[string]bool, len(cfg.Events))
+			for _, event := range cfg.Events {
+				h.events[event] = true
+			}
+		}
+		n.hooks = append(n.hooks, h)
+
+		n.wg.Add(1)
+		go n.run(h)
+	}
+	return n
+}
+
+// Configs 通知器使用的配置
+func (n *Notifier) Configs() []types.WebhookConfig {
+	return n.configs
+}
+
+// Notify 把事件放入订阅该事件的Webhook队列，不会阻塞
+func (n *Notifier) Notify(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	for _, h := range n.hooks {
+		if h.events != nil && !h.events[event.Type] {
+			continue
+		}
+		select {
+		case h.queue <- event:
+		default:
+			fmt.Printf("[WEBHOOK] %s: queue full, dropping %s event for %s/%s\n", h.cfg.Name, event.Type, event.Upstream, event.BackendID)
+		}
+	}
+}
+
+// Close 停止发送协程，未发送的事件被丢弃
+func (n *Notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+func (n *Notifier) run(h *hook) {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case event := <-h.queue:
+			n.deliver(h, event)
+		}
+	}
+}
+
+// deliver 发送事件，失败时按退避时间重试，直到成功、达到重试次数或通知器关闭
+func (n *Notifier) deliver(h *hook, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		fmt.Printf("[WEBHOOK] %s: failed to encode event: %v\n", h.cfg.Name, err)
+		return
+	}
+
+	backoff := h.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := n.send(h, body)
+		if err == nil {
+			return
+		}
+		if attempt >= h.cfg.MaxRetries {
+			fmt.Printf("[WEBHOOK] %s: giving up on %s event for %s/%s after %d attempts: %v\n",
+				h.cfg.Name, event.Type, event.Upstream, event.BackendID, attempt+1, err)
+			return
+		}
+		fmt.Printf("[WEBHOOK] %s: attempt %d failed, retrying in %v: %v\n", h.cfg.Name, attempt+1, backoff, err)
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send 发送一次请求，2xx响应视为成功
+func (n *Notifier) send(h *hook, body []byte) error {
+	ctx, cancel := context.WithTimeout(n.ctx, h.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SpeedMimi-Webhook")
+	for name, value := range h.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"sync/atomic"
 	"time"
 )
@@ -16,6 +17,8 @@ const (
 	LeastConnectionsWeight LoadBalancerType = "least_connections_weight"
 	Weight               LoadBalancerType = "weight"
 	PerformanceLCW       LoadBalancerType = "performance_least_connections_weight"
+	PowerOfTwoChoices    LoadBalancerType = "p2c"
+	LeastResponseTime    LoadBalancerType = "least_response_time"
 )
 
 // ProtocolType 协议类型
@@ -37,13 +40,23 @@ type Backend struct {
 	Weight       int               `yaml:"weight" json:"weight"`
 	Scheme       string            `yaml:"scheme" json:"scheme"`
 	Active       bool              `yaml:"active" json:"active"`
-	Connections  int64             `yaml:"-" json:"connections"`  // 当前连接数（原子操作）
 	MaxConn      int               `yaml:"max_conn" json:"max_conn"`
+	Priority     int               `yaml:"priority" json:"priority"` // 优先级分组，数值越小越优先，高优先级不可用时才使用低优先级（备份）后端
+	Zone         string            `yaml:"zone" json:"zone"`         // 所在可用区，用于可用区感知负载均衡
+	Labels       map[string]string `yaml:"labels" json:"labels,omitempty"` // 任意标签，路由可按标签选择后端子集
 	HealthCheck  *HealthCheck      `yaml:"health_check" json:"health_check"`
-	Performance  *PerformanceInfo  `yaml:"-" json:"performance"`
-	LastReport   time.Time         `yaml:"-" json:"last_report"`
 	active       int32             `yaml:"-" json:"-"`           // 活跃状态（原子操作）
-	disconnect   int32             `yaml:"-" json:"-"`           // 断开连接标记（原子操作）
+	unhealthy    int32             `yaml:"-" json:"-"`           // 健康检查或手动覆盖判定为不健康（原子操作）
+	runtime      atomic.Pointer[backendRuntime]                  // 运行时状态，首次使用时创建，JSON中为connections、performance和last_report
+}
+
+// backendRuntime 后端的运行时状态，修改配置生成的新后端对象通过ShareRuntime与原对象共用
+type backendRuntime struct {
+	connections int64                           // 当前连接数（原子操作）
+	disconnect  int32                           // 断开连接标记（原子操作）
+	performance atomic.Pointer[PerformanceInfo] // 最近一次上报的性能信息（原子操作）
+	reportedAt  int64                           // 最近一次性能上报时间UnixNano（原子操作）
+	latency     int64                           // 请求延迟的指数加权移动平均，纳秒（原子操作）
 }
 
 // PerformanceInfo 性能信息
@@ -56,24 +69,317 @@ type PerformanceInfo struct {
 	LoadAvg15   float64 `json:"load_avg_15"`  // 15分钟负载平均值
 	NetworkIn   float64 `json:"network_in"`   // 网络流入速度 KB/s
 	NetworkOut  float64 `json:"network_out"`  // 网络流出速度 KB/s
-	Timestamp   int64   `json:"timestamp"`    // 时间戳
+	Timestamp   int64   `json:"timestamp"`    // 后端上报的时间戳（Unix秒或毫秒），只用于展示和计算时钟偏差
+	ReceivedAt  int64   `json:"received_at"`  // 代理收到上报的时间（Unix毫秒），由代理设置
+	ClockSkewMs int64   `json:"clock_skew_ms"` // 接收时间减去后端时间戳（毫秒），包括传输延迟；未上报时间戳时为0
+	Source      string  `json:"source,omitempty"` // 性能信息来源：report（管理API上报）或orca（响应中的ORCA负载报告）
+	RPS         float64 `json:"rps,omitempty"`    // ORCA报告的每秒请求数
+	EPS         float64 `json:"eps,omitempty"`    // ORCA报告的每秒错误数
 }
 
+// 性能信息来源
+const (
+	PerformanceSourceReport = "report" // 后端调用管理API上报
+	PerformanceSourceORCA   = "orca"   // 后端响应中的ORCA负载报告
+)
+
+// 健康检查类型
+const (
+	HealthCheckHTTP = "http" // HTTP GET探测（默认）
+	HealthCheckGRPC = "grpc" // 标准gRPC健康检查协议 grpc.health.v1.Health/Check
+	HealthCheckTCP  = "tcp"  // TCP连接探测，能建立连接即视为健康
+	HealthCheckScript = "script" // 执行外部命令，退出码为0时视为健康
+)
+
 // HealthCheck 健康检查配置
 type HealthCheck struct {
-	Path     string        `yaml:"path" json:"path"`
+	Type     string        `yaml:"type" json:"type,omitempty"`       // http（默认）、grpc、tcp或script
+	Path     string        `yaml:"path" json:"path"`                 // HTTP探测路径
+	Service  string        `yaml:"service" json:"service,omitempty"` // gRPC健康检查的服务名，为空时检查整个服务器
+	Command  []string      `yaml:"command" json:"-"`                // script健康检查执行的命令和参数，后端地址通过环境变量传入；只能在配置文件中设置
+	StatusCodes  []int  `yaml:"status_codes" json:"status_codes,omitempty"`   // HTTP探测接受的状态码，为空时接受2xx和3xx
+	BodyContains string `yaml:"body_contains" json:"body_contains,omitempty"` // 响应体需要包含的子串
+	BodyRegex    string `yaml:"body_regex" json:"body_regex,omitempty"`       // 响应体需要匹配的正则表达式
+	BodyNot      bool   `yaml:"body_not" json:"body_not,omitempty"`           // 反转响应体匹配：包含/匹配时视为不健康
 	Interval time.Duration `yaml:"interval" json:"interval"`
 	Timeout  time.Duration `yaml:"timeout" json:"timeout"`
 	Failures int           `yaml:"failures" json:"failures"`
+	Rise     int           `yaml:"rise" json:"rise,omitempty"`     // 不健康后连续成功多少次恢复为健康，默认1
+	Fall     int           `yaml:"fall" json:"fall,omitempty"`     // 连续失败多少次判定为不健康，默认与failures相同
+	Jitter   time.Duration `yaml:"jitter" json:"jitter,omitempty"` // 每次探测间隔增加[0, jitter)的随机值，避免大量后端同时探测
 }
 
 // Config 配置文件结构
+// 带secret:"true"标签的字段（令牌、密钥等）在导出配置时默认隐藏
 type Config struct {
 	Server   ServerConfig           `yaml:"server" json:"server"`
 	SSL      SSLConfig              `yaml:"ssl" json:"ssl"`
 	Backends map[string][]*Backend  `yaml:"backends" json:"backends"` // key为upstream名称
+	Upstreams map[string]*UpstreamConfig `yaml:"upstreams" json:"upstreams"` // 上游级别配置，key为upstream名称
 	Routing  map[string]*RoutingRule `yaml:"routing" json:"routing"`   // key为路径前缀
 	GRPC     GRPCConfig             `yaml:"grpc" json:"grpc"`
+	Maintenance MaintenanceConfig   `yaml:"maintenance" json:"maintenance"`
+	Cluster  ClusterConfig          `yaml:"cluster" json:"cluster"`
+	RoutingToken RoutingTokenConfig `yaml:"routing_token" json:"routing_token"`
+	Performance  PerformanceConfig  `yaml:"performance" json:"performance"`
+	Quota        QuotaConfig        `yaml:"quota" json:"quota"`
+	APIKeys      APIKeyConfig       `yaml:"api_keys" json:"api_keys"` // 路由的API密钥认证
+	ControlPlane ControlPlaneConfig `yaml:"control_plane" json:"control_plane"` // 管理API和后台健康检查的并发上限
+	Geo          GeoConfig          `yaml:"geo" json:"geo"`
+	Cache        ResponseCacheConfig `yaml:"cache" json:"cache"`
+	AccessLog    AccessLogConfig    `yaml:"access_log" json:"access_log"`
+	Webhooks     []WebhookConfig    `yaml:"webhooks" json:"webhooks"` // 后端状态变化的事件通知
+	LoadShedding LoadSheddingConfig `yaml:"load_shedding" json:"load_shedding"` // 自适应负载卸载
+	Artifacts    ArtifactsConfig    `yaml:"artifacts" json:"artifacts"`         // 数据文件热加载
+	Audit        AuditConfig        `yaml:"audit" json:"audit"`                 // 并发访问审计模式
+	DebugTrace   DebugTraceConfig   `yaml:"debug_trace" json:"debug_trace"`     // 按路由/后端的限时调试跟踪
+	ConfigWatch  ConfigWatchConfig  `yaml:"config_watch" json:"config_watch"`   // 配置文件热加载
+	Secrets      SecretsConfig      `yaml:"secrets" json:"secrets"`             // 从Vault或KMS获取配置中引用的密钥
+	Tracing      TracingConfig      `yaml:"tracing" json:"tracing"`             // OpenTelemetry分布式跟踪
+	ErrorLog     ErrorLogConfig     `yaml:"error_log" json:"error_log"`         // 诊断日志发送到syslog、TCP/UDP或Kafka
+	StatsHistory StatsHistoryConfig `yaml:"stats_history" json:"stats_history"` // 内存中的指标历史
+	// 合并的配置片段文件（glob模式，相对路径相对于主配置文件所在目录），按模式顺序、同一模式内按路径排序合并，
+	// 片段只能包含backends、upstreams和routing，同名的项只能定义一次
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+}
+
+// StatsHistoryConfig 指标历史配置，按固定间隔在内存中保存RPS、错误率、流量和连接数，无需Prometheus即可查询趋势
+type StatsHistoryConfig struct {
+	Disabled   bool          `yaml:"disabled" json:"disabled"`
+	Resolution time.Duration `yaml:"resolution" json:"resolution"` // 采样间隔，默认10s，修改后清空已有历史
+	Retention  time.Duration `yaml:"retention" json:"retention"`   // 保留时长，默认24h
+}
+
+// ConfigWatchConfig 配置文件热加载
+// 启用后监听配置文件所在目录，文件内容变化时重新加载、校验并整体替换当前配置，校验失败时保留当前配置
+type ConfigWatchConfig struct {
+	Enabled  bool          `yaml:"enabled" json:"enabled"`   // 修改后需要重启
+	Debounce time.Duration `yaml:"debounce" json:"debounce"` // 文件最后一次变化后等待多久再重新加载，默认500ms，修改后需要重启
+}
+
+// 密钥提供者类型
+const (
+	SecretProviderVault  = "vault"   // HashiCorp Vault KV（v1或v2）
+	SecretProviderAWSKMS = "aws_kms" // AWS KMS解密密文
+)
+
+// SecretsConfig 外部密钥提供者
+// 配置文件中的${secret:<提供者>:<引用>}在加载时替换为密钥内容，${secret_file:<提供者>:<引用>}
+// 替换为保存了密钥内容的文件路径（用于证书和私钥）；引用了密钥的配置不会被管理API的修改写回文件
+type SecretsConfig struct {
+	Providers       map[string]*SecretProviderConfig `yaml:"providers" json:"providers,omitempty"` // key为引用中的提供者名称
+	RefreshInterval time.Duration                    `yaml:"refresh_interval" json:"refresh_interval"` // 定期重新获取密钥，变化时重新加载配置，0表示只在加载配置时获取，修改后需要重启
+	Dir             string                           `yaml:"dir" json:"dir"`                           // secret_file写入的目录，默认系统临时目录下的speedmimi-secrets
+}
+
+// SecretProviderConfig 单个密钥提供者
+// Vault引用为 <API路径>#<字段>，例如 secret/data/speedmimi#admin_token；AWS KMS引用为base64编码的密文
+type SecretProviderConfig struct {
+	Type      string        `yaml:"type" json:"type"`                       // vault 或 aws_kms
+	Address   string        `yaml:"address" json:"address,omitempty"`       // Vault地址，例如 https://vault:8200
+	TokenFile string        `yaml:"token_file" json:"token_file,omitempty"` // Vault令牌文件（例如Vault Agent输出），为空时使用VAULT_TOKEN环境变量
+	Namespace string        `yaml:"namespace" json:"namespace,omitempty"`   // Vault企业版命名空间
+	CAFile    string        `yaml:"ca_file" json:"ca_file,omitempty"`       // 验证Vault或KMS服务器证书的CA
+	Region    string        `yaml:"region" json:"region,omitempty"`         // AWS区域，凭证读取AWS_ACCESS_KEY_ID等环境变量
+	Endpoint  string        `yaml:"endpoint" json:"endpoint,omitempty"`     // KMS地址，默认https://kms.<region>.amazonaws.com
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`                 // 单次请求超时，默认10s
+}
+
+// TracingConfig OpenTelemetry分布式跟踪
+// 接受并传递W3C traceparent，为每个请求创建一个span，通过OTLP/HTTP（JSON编码）批量发送到收集器
+type TracingConfig struct {
+	Enabled       bool              `yaml:"enabled" json:"enabled"`
+	Endpoint      string            `yaml:"endpoint" json:"endpoint"`               // OTLP/HTTP traces地址，例如 http://otel-collector:4318/v1/traces
+	Headers       map[string]string `yaml:"headers" json:"-" secret:"true"`         // 附加的请求头，如收集器的认证头；JSON中不返回
+	ServiceName   string            `yaml:"service_name" json:"service_name"`       // service.name资源属性，默认speedmimi
+	SampleRatio   float64           `yaml:"sample_ratio" json:"sample_ratio"`       // 新跟踪的采样比例（0到1），默认1；带traceparent的请求沿用其采样标志
+	BatchSize     int               `yaml:"batch_size" json:"batch_size"`           // 每次发送的最大span数，默认512
+	FlushInterval time.Duration     `yaml:"flush_interval" json:"flush_interval"`   // 发送间隔，默认5s
+	QueueSize     int               `yaml:"queue_size" json:"queue_size"`           // 待发送span的队列长度，队列满时丢弃新的span，默认2048
+	Timeout       time.Duration     `yaml:"timeout" json:"timeout"`                 // 单次发送超时，默认10s
+}
+
+// DebugTraceConfig 调试跟踪配置
+// 跟踪通过管理API按路由或后端临时开启，到达时长或请求数上限后自动关闭
+type DebugTraceConfig struct {
+	Path string `yaml:"path" json:"path"` // 跟踪日志文件，与访问日志分开，默认logs/debug-trace.log
+}
+
+// AuditConfig 并发访问审计模式
+// 启用后检查上游管理器和后端的修改路径：已发布的结构被修改、索引不一致等记录为违反，
+// 就地修改被并发读取的后端等记录为不安全回退，并定期巡检不变量；用于在接近生产的负载下验证无锁设计
+type AuditConfig struct {
+	Enabled  bool          `yaml:"enabled" json:"enabled"`   // 使用speedmimi_audit构建标签编译时始终启用
+	Interval time.Duration `yaml:"interval" json:"interval"` // 不变量巡检间隔，默认10s
+}
+
+// ArtifactsConfig 数据文件（如geo.file）热加载配置
+type ArtifactsConfig struct {
+	WatchInterval time.Duration `yaml:"watch_interval" json:"watch_interval"` // 检查文件变化的间隔，默认30s，修改后需要重启
+}
+
+// 路由在负载卸载时的优先级
+const (
+	PriorityCritical = "critical" // 不被卸载
+	PriorityNormal   = "normal"   // 默认
+	PriorityLow      = "low"      // 最多占用并发上限的low_priority_share，最先被卸载
+)
+
+// LoadSheddingConfig 自适应负载卸载
+// 代理进程CPU或请求延迟p99超过目标时按AIMD（加性增、乘性减）降低并发上限，超过上限的请求返回503，
+// 低优先级路由只能使用上限的一部分，因此最先被拒绝
+type LoadSheddingConfig struct {
+	Enabled          bool          `yaml:"enabled" json:"enabled"`
+	CPUTarget        float64       `yaml:"cpu_target" json:"cpu_target"`                 // 代理进程CPU使用率目标（占全部核心的百分比），0表示不按CPU判断
+	LatencyTarget    time.Duration `yaml:"latency_target" json:"latency_target"`         // 请求延迟p99目标，0表示不按延迟判断
+	Interval         time.Duration `yaml:"interval" json:"interval"`                     // 调整并发上限的间隔，默认1s
+	MinLimit         int           `yaml:"min_limit" json:"min_limit"`                   // 并发上限最低降到多少，默认10
+	MaxLimit         int           `yaml:"max_limit" json:"max_limit"`                   // 并发上限的最大值（初始值），默认10000
+	IncreaseStep     int           `yaml:"increase_step" json:"increase_step"`           // 未过载时每个间隔增加的并发数，默认max_limit的1%（至少1）
+	DecreaseFactor   float64       `yaml:"decrease_factor" json:"decrease_factor"`       // 过载时并发上限乘以的系数，默认0.8
+	LowPriorityShare float64       `yaml:"low_priority_share" json:"low_priority_share"` // 低优先级请求最多占用并发上限的比例，默认0.5
+}
+
+// WebhookConfig 事件通知Webhook配置
+type WebhookConfig struct {
+	Name         string            `yaml:"name" json:"name"`
+	URL          string            `yaml:"url" json:"url"`
+	Events       []string          `yaml:"events" json:"events"`               // 订阅的事件，为空时订阅全部事件
+	Headers      map[string]string `yaml:"headers" json:"-" secret:"true"`     // 附加的请求头，如Authorization；JSON中不返回
+	Timeout      time.Duration     `yaml:"timeout" json:"timeout"`             // 单次请求超时，默认5s
+	MaxRetries   int               `yaml:"max_retries" json:"max_retries"`     // 失败后的重试次数，默认3，负数表示不重试
+	RetryBackoff time.Duration     `yaml:"retry_backoff" json:"retry_backoff"` // 首次重试的等待时间，之后每次翻倍，默认1s
+}
+
+// ResponseCacheConfig 响应缓存配置，路由通过cache.enabled启用
+type ResponseCacheConfig struct {
+	Enabled      bool  `yaml:"enabled" json:"enabled"`
+	MaxSize      int64 `yaml:"max_size" json:"max_size"`             // 缓存总字节数上限
+	MaxEntrySize int64 `yaml:"max_entry_size" json:"max_entry_size"` // 单个响应体的字节数上限
+}
+
+// AccessLogConfig 访问日志配置，level和sample_rate可以通过管理API在运行时调整
+type AccessLogConfig struct {
+	Enabled     bool   `yaml:"enabled" json:"enabled"`
+	Path        string `yaml:"path" json:"path"`                 // 日志文件路径，为空或stdout时写到标准输出，stderr写到标准错误，也可以是远程日志目标（见ErrorLogConfig）
+	Format      string `yaml:"format" json:"format"`             // 日志格式（请求变量模板），为空时使用默认格式
+	Level       string `yaml:"level" json:"level"`               // off、errors（只记录错误）或all（默认）
+	SampleRate  int    `yaml:"sample_rate" json:"sample_rate"`   // 非错误请求每N个记录1个，默认1（全部记录）
+	ErrorStatus int    `yaml:"error_status" json:"error_status"` // 状态码不小于该值的请求视为错误，总是记录，默认500
+	MaxRate     int    `yaml:"max_rate" json:"max_rate"`         // 采样后每秒最多记录的非错误请求数，0表示不限制
+	Rules       []AccessLogSampleRule `yaml:"rules" json:"rules,omitempty"` // 按路由和状态码的采样规则，第一个匹配的规则的采样率代替sample_rate
+
+	// 环形缓冲区字节数，日志先写入缓冲区再由后台协程批量写出，缓冲区满时丢弃新的日志而不阻塞请求；0表示每条日志直接写出
+	BufferSize    int                     `yaml:"buffer_size" json:"buffer_size"`
+	FlushInterval time.Duration           `yaml:"flush_interval" json:"flush_interval"` // 缓冲区写出间隔，默认1s
+	Rotation      AccessLogRotationConfig `yaml:"rotation" json:"rotation"`             // 日志文件轮转，只用于写到文件
+}
+
+// ErrorLogConfig 诊断日志（组件的[XXX]日志和log包的输出）的远程日志目标，仍同时写到标准输出和标准错误，修改后需要重启
+// 目标：syslog://host:514（UDP）、syslog+tcp://host:601、tcp://host:port、udp://host:port、
+// kafka://broker1:9092,broker2:9092/topic；参数timeout、queue_size，syslog的facility、app_name，kafka的acks、client_id
+type ErrorLogConfig struct {
+	Path string `yaml:"path" json:"path"` // 为空时不发送
+}
+
+// AccessLogSampleRule 访问日志采样规则，条件都为空时匹配所有请求
+// 状态码不小于error_status的请求不受规则影响，总是记录
+type AccessLogSampleRule struct {
+	Route      string `yaml:"route" json:"route,omitempty"`           // 路由名称
+	MinStatus  int    `yaml:"min_status" json:"min_status,omitempty"` // 状态码下限（包含）
+	MaxStatus  int    `yaml:"max_status" json:"max_status,omitempty"` // 状态码上限（包含）
+	SampleRate int    `yaml:"sample_rate" json:"sample_rate"`         // 每N个匹配的请求记录1个，1表示全部记录
+}
+
+// AccessLogRotationConfig 访问日志文件轮转
+// 轮转时当前文件重命名为 <path>.<时间> 并重新创建，在写入日志时检查是否需要轮转
+type AccessLogRotationConfig struct {
+	MaxSize    int64         `yaml:"max_size" json:"max_size"`       // 文件超过该字节数时轮转，0表示不按大小轮转
+	Interval   time.Duration `yaml:"interval" json:"interval"`       // 按时间轮转的间隔（如24h），0表示不按时间轮转
+	MaxBackups int           `yaml:"max_backups" json:"max_backups"` // 保留的轮转文件数，0表示全部保留
+	Compress   bool          `yaml:"compress" json:"compress"`       // 用gzip压缩轮转后的文件
+}
+
+// RouteCacheConfig 路由的响应缓存配置
+type RouteCacheConfig struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	TTL     time.Duration `yaml:"ttl" json:"ttl"` // 响应没有max-age/Expires时的新鲜期，0表示只缓存带显式新鲜期或验证器的响应
+	NegativeTTL      time.Duration `yaml:"negative_ttl" json:"negative_ttl,omitempty"`           // 否定响应（如404/410）的新鲜期上限，0表示不缓存否定响应
+	NegativeStatuses []int         `yaml:"negative_statuses" json:"negative_statuses,omitempty"` // 作为否定响应缓存的状态码，默认[404, 410]
+}
+
+// GeoConfig 请求变量$geo的取值规则
+// 依次按客户端IP匹配网段（最长前缀优先）、读取请求头，都没有结果时使用默认值
+type GeoConfig struct {
+	Networks []GeoNetwork `yaml:"networks" json:"networks"`
+	File     string       `yaml:"file" json:"file"`       // 网段文件，每行"CIDR 值"，与networks合并，文件变化时自动重新加载
+	Header   string       `yaml:"header" json:"header"`   // 由上层CDN/负载均衡器写入的地理位置头，如CF-IPCountry
+	Default  string       `yaml:"default" json:"default"` // 无法确定时的值
+}
+
+// GeoNetwork 网段到地理位置值的映射
+type GeoNetwork struct {
+	CIDR  string `yaml:"cidr" json:"cidr"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// MatchCondition 基于请求变量的匹配条件，equals、prefix、regex、in、exists只能指定一个
+type MatchCondition struct {
+	Value  string   `yaml:"value" json:"value"` // 变量模板，如 "$http_x_canary"
+	Equals string   `yaml:"equals" json:"equals,omitempty"`
+	Prefix string   `yaml:"prefix" json:"prefix,omitempty"`
+	Regex  string   `yaml:"regex" json:"regex,omitempty"`
+	In     []string `yaml:"in" json:"in,omitempty"`
+	Exists *bool    `yaml:"exists" json:"exists,omitempty"` // true：值非空；false：值为空
+	Percent *int    `yaml:"percent" json:"percent,omitempty"` // 值的哈希分桶(0-99)小于percent时满足，用于按用户稳定的灰度发布
+	Salt   string   `yaml:"salt" json:"salt,omitempty"`     // 参与分桶哈希的盐值，不同灰度使用不同盐值以免选中同一批用户
+	Not    bool     `yaml:"not" json:"not,omitempty"`       // 取反
+}
+
+// HeaderTemplate 使用变量模板的请求头/响应头
+type HeaderTemplate struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"` // 变量模板，如 "$client_ip"
+}
+
+// QuotaConfig API密钥配额配置
+type QuotaConfig struct {
+	Enabled    bool          `yaml:"enabled" json:"enabled"`
+	Header     string        `yaml:"header" json:"header"`           // 读取API密钥的请求头
+	QueryParam string        `yaml:"query_param" json:"query_param"` // 请求头不存在时读取的查询参数，为空时不读取
+	Period     time.Duration `yaml:"period" json:"period"`           // 配额周期，按UTC对齐
+	RequireKey bool          `yaml:"require_key" json:"require_key"` // 未携带API密钥的请求返回401
+	Keys       []QuotaKey    `yaml:"keys" json:"keys"`
+}
+
+// QuotaKey 单个API密钥的配额，限制为0表示不限制
+type QuotaKey struct {
+	Key      string `yaml:"key" json:"-" secret:"true"`
+	Tenant   string `yaml:"tenant" json:"tenant"`
+	Requests int64  `yaml:"requests" json:"requests"` // 每个周期的请求数上限
+	Bytes    int64  `yaml:"bytes" json:"bytes"`       // 每个周期的请求体+响应体字节数上限
+}
+
+// APIKeyConfig API密钥认证配置，密钥通过管理API创建和吊销
+type APIKeyConfig struct {
+	Header     string   `yaml:"header" json:"header"`           // 读取API密钥的请求头
+	QueryParam string   `yaml:"query_param" json:"query_param"` // 请求头不存在时读取的查询参数，为空时不读取
+	Keys       []APIKey `yaml:"keys" json:"keys"`
+}
+
+// APIKey 单个API密钥，只保存密钥的SHA-256摘要
+type APIKey struct {
+	ID        string           `yaml:"id" json:"id"`
+	Name      string           `yaml:"name" json:"name"`
+	Hash      string           `yaml:"hash" json:"-" secret:"true"`                                // 密钥的SHA-256摘要（十六进制）
+	Routes    []string         `yaml:"routes" json:"routes,omitempty"`               // 允许访问的路由，为空时允许所有启用API密钥认证的路由
+	RateLimit *RateLimitConfig `yaml:"rate_limit" json:"rate_limit,omitempty"`       // 单个密钥的请求速率上限
+	CreatedAt time.Time        `yaml:"created_at" json:"created_at"`
+}
+
+// PerformanceConfig 后端性能上报配置
+type PerformanceConfig struct {
+	ReportTTL time.Duration `yaml:"report_ttl" json:"report_ttl"` // 性能上报的有效期，超过后负载均衡器不再使用该上报
 }
 
 // ServerConfig 服务器配置
@@ -85,33 +391,535 @@ type ServerConfig struct {
 	MaxConn      int               `yaml:"max_conn" json:"max_conn"`
 	RealIPHeader string            `yaml:"real_ip_header" json:"real_ip_header"`
 	TrustedProxies []string        `yaml:"trusted_proxies" json:"trusted_proxies"`
+	ReadBufferSize     int         `yaml:"read_buffer_size" json:"read_buffer_size"`
+	WriteBufferSize    int         `yaml:"write_buffer_size" json:"write_buffer_size"`
+	MaxRequestBodySize int         `yaml:"max_request_body_size" json:"max_request_body_size"`
+	ConnectionMetadata ConnectionMetadataConfig `yaml:"connection_metadata" json:"connection_metadata"`
+	ClientIdentity     ClientIdentityConfig     `yaml:"client_identity" json:"client_identity"` // 客户端证书身份透传
+	EarlyReject        EarlyRejectConfig        `yaml:"early_reject" json:"early_reject"`       // 请求行预检
+	ConnectionClasses  ConnectionClassesConfig  `yaml:"connection_classes" json:"connection_classes"` // 按连接类别的并发和内存限制
+	Zone               string      `yaml:"zone" json:"zone"` // 代理所在可用区，为空时读取SPEEDMIMI_ZONE环境变量
+	RateLimit          *AggregateRateLimitConfig `yaml:"rate_limit" json:"rate_limit,omitempty"` // 整个代理的总请求速率上限
+	SecurityHeaders    *SecurityHeadersConfig    `yaml:"security_headers" json:"security_headers,omitempty"` // 安全响应头，路由可以覆盖
+	SlowClient         SlowClientConfig         `yaml:"slow_client" json:"slow_client"`         // 慢客户端保护
+	ClientLimits       ClientLimitsConfig       `yaml:"client_limits" json:"client_limits"`     // 按客户端IP的连接数和请求速率上限
+	SlowRequest        SlowRequestConfig        `yaml:"slow_request" json:"slow_request"`       // 慢请求（slowloris）保护
+	ConnectionLifetime ConnectionLifetimeConfig `yaml:"connection_lifetime" json:"connection_lifetime"` // 客户端连接的请求数和存在时间上限
+	PathNormalization  PathNormalizationConfig  `yaml:"path_normalization" json:"path_normalization"`   // 路由前规范化请求路径
+	ShutdownTimeout    time.Duration            `yaml:"shutdown_timeout" json:"shutdown_timeout"`       // 通过管理API关闭时等待处理中请求完成的最长时间，默认30s
+}
+
+// 请求路径中编码的斜杠（%2F）的处理方式
+const (
+	EncodedSlashesKeep   = "keep"   // 保持编码转发（默认），路由按解码后的路径匹配
+	EncodedSlashesDecode = "decode" // 解码为/后再规范化
+	EncodedSlashesReject = "reject" // 返回400
+)
+
+// PathNormalizationConfig 路由前的请求路径规范化（默认启用）
+// 解码非保留字符（包括%2e）、合并连续的斜杠、解析.和..路径段，拒绝包含控制字符或错误编码的路径；
+// 规范化后的路径同时用于路由匹配和转发，构造的路径无法绕过按前缀配置的路由规则
+type PathNormalizationConfig struct {
+	Disabled             bool   `yaml:"disabled" json:"disabled"`                             // 关闭规范化，按原样转发请求路径（不推荐）
+	KeepDuplicateSlashes bool   `yaml:"keep_duplicate_slashes" json:"keep_duplicate_slashes"` // 不合并连续的斜杠
+	EncodedSlashes       string `yaml:"encoded_slashes" json:"encoded_slashes"`               // %2F的处理：keep（默认）、decode或reject
+}
+
+// ConnectionLifetimeConfig 客户端连接的生命周期上限
+// 连接处理的请求数或存在时间达到上限后，代理在当前响应中返回Connection: close，
+// 客户端重新建立连接时由前面的L4负载均衡器重新分配，使长连接的负载在多个代理实例间重新分布
+type ConnectionLifetimeConfig struct {
+	MaxRequests  int           `yaml:"max_requests" json:"max_requests"`     // 单个连接最多处理的请求数，0表示不限制
+	MaxAge       time.Duration `yaml:"max_age" json:"max_age"`               // 连接的最大存在时间，到期后在下一个请求结束时关闭，0表示不限制
+	MaxAgeJitter time.Duration `yaml:"max_age_jitter" json:"max_age_jitter"` // 在max_age上为每个连接增加的随机时间上限，避免同时建立的连接集中重连
+}
+
+// SlowRequestConfig 慢请求保护：限制客户端发送请求头的时间和发送请求的最低速率，防止慢速客户端长期占用连接和工作协程
+type SlowRequestConfig struct {
+	HeaderTimeout         time.Duration `yaml:"header_timeout" json:"header_timeout"`                   // 收到请求的第一个字节后必须在该时间内发完请求头，0表示只受read_timeout限制
+	MinRate               int           `yaml:"min_rate" json:"min_rate"`                               // 客户端发送请求（请求头和请求体）的最低平均速率（字节/秒），0表示不限制
+	RateGrace             time.Duration `yaml:"rate_grace" json:"rate_grace"`                           // 开始按最低速率计算前的宽限时间，默认5s
+	MaxIncompleteRequests int           `yaml:"max_incomplete_requests" json:"max_incomplete_requests"` // 同时在发送请求头的连接数上限，超过时关闭新的连接，0表示不限制
+}
+
+// ClientLimitsConfig 按客户端IP的连接数和请求速率上限
+// 连接数按TCP对端地址统计，请求速率按getClientIP解析的客户端IP统计；Allowlist中的IP或网段（如健康检查器）不受限制
+type ClientLimitsConfig struct {
+	MaxConnsPerIP int      `yaml:"max_conns_per_ip" json:"max_conns_per_ip"` // 单IP最大并发连接数，0表示不限制
+	RequestRate   float64  `yaml:"request_rate" json:"request_rate"`         // 单IP每秒允许的请求数，0表示不限制
+	RequestBurst  int      `yaml:"request_burst" json:"request_burst"`       // 允许的突发请求数，默认为request_rate向上取整
+	Allowlist     []string `yaml:"allowlist" json:"allowlist"`               // 不受限制的IP或CIDR
+}
+
+// SlowClientConfig 慢客户端保护：限制单个连接等待写出的响应数据占用内存的时间
+// 连接尚未写出的响应超过MaxWriteBuffer字节时，必须在WriteBufferTimeout内降到上限以下，否则断开连接
+type SlowClientConfig struct {
+	MaxWriteBuffer     int           `yaml:"max_write_buffer" json:"max_write_buffer"`         // 0表示不限制
+	WriteBufferTimeout time.Duration `yaml:"write_buffer_timeout" json:"write_buffer_timeout"` // 默认10s
+}
+
+// ConnectionMetadataConfig 客户端连接元数据透传配置
+// 启用后向上游请求添加TLS版本/加密套件、ALPN、客户端端口和连接ID等请求头
+type ConnectionMetadataConfig struct {
+	Enabled            bool   `yaml:"enabled" json:"enabled"`
+	TLSVersionHeader   string `yaml:"tls_version_header" json:"tls_version_header"`
+	TLSCipherHeader    string `yaml:"tls_cipher_header" json:"tls_cipher_header"`
+	ALPNHeader         string `yaml:"alpn_header" json:"alpn_header"`
+	ClientPortHeader   string `yaml:"client_port_header" json:"client_port_header"`
+	ConnectionIDHeader string `yaml:"connection_id_header" json:"connection_id_header"`
+}
+
+// EarlyRejectConfig 请求行预检配置
+// 在解析请求头之前检查连接上第一个请求的请求行，过长或格式错误时直接拒绝并关闭连接
+type EarlyRejectConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`
+	MaxRequestLine int      `yaml:"max_request_line" json:"max_request_line"` // 请求行最大字节数，默认8192
+	Methods        []string `yaml:"methods" json:"methods"`                   // 允许的请求方法，默认为标准HTTP方法
+}
+
+// ConnectionClassesConfig 按连接类别分别限制并发请求数和缓冲内存
+// WebSocket和SSE请求属于stream类别，其他请求属于http类别，大量流式连接不会占满普通请求的预算
+type ConnectionClassesConfig struct {
+	HTTP   ConnectionClassLimit `yaml:"http" json:"http"`
+	Stream ConnectionClassLimit `yaml:"stream" json:"stream"`
+}
+
+// ConnectionClassLimit 单个连接类别的限制，0表示不限制
+type ConnectionClassLimit struct {
+	MaxConcurrent int   `yaml:"max_concurrent" json:"max_concurrent"` // 同时处理的请求数（占用的处理协程数）
+	MaxMemory     int64 `yaml:"max_memory" json:"max_memory"`         // 读写缓冲区、请求体和响应体占用的内存（字节）
 }
 
+// ClientIdentityConfig 客户端证书身份透传配置
+// 启用后先删除客户端传入的同名请求头，再按验证过的客户端证书设置，证书中的SPIFFE ID来自URI SAN
+type ClientIdentityConfig struct {
+	Enabled        bool   `yaml:"enabled" json:"enabled"`
+	SPIFFEIDHeader string `yaml:"spiffe_id_header" json:"spiffe_id_header"`
+	SubjectHeader  string `yaml:"subject_header" json:"subject_header"`
+	HashHeader     string `yaml:"hash_header" json:"hash_header"` // 证书DER的SHA-256（十六进制）
+	XFCC           bool   `yaml:"xfcc" json:"xfcc"`               // 同时设置Envoy格式的X-Forwarded-Client-Cert
+}
+
+// 客户端证书验证方式
+const (
+	ClientAuthNone     = "none"     // 不请求客户端证书
+	ClientAuthOptional = "optional" // 请求客户端证书，提供时必须有效
+	ClientAuthRequire  = "require"  // 必须提供有效的客户端证书
+)
+
 // SSLConfig SSL配置
 type SSLConfig struct {
 	Enabled  bool   `yaml:"enabled" json:"enabled"`
 	CertFile string `yaml:"cert_file" json:"cert_file"`
 	KeyFile  string `yaml:"key_file" json:"key_file"`
+	WatchInterval time.Duration `yaml:"watch_interval" json:"watch_interval"` // 证书文件轮询间隔
+	DisableWatch  bool          `yaml:"disable_watch" json:"disable_watch"`   // 关闭证书自动热加载
+	ClientAuth    string        `yaml:"client_auth" json:"client_auth"`       // 客户端证书验证方式：none、optional或require
+	ClientCAFile  string        `yaml:"client_ca_file" json:"client_ca_file"` // 验证客户端证书的CA证书（PEM）
+}
+
+// 上游协议
+const (
+	UpstreamProtocolH2    = "h2"       // HTTP/2，https后端通过ALPN协商，http后端使用h2c
+	UpstreamProtocolHTTP1 = "http/1.1" // HTTP/1.1（默认）
+)
+
+// UpstreamConfig 上游级别配置
+type UpstreamConfig struct {
+	LoadBalancer       LoadBalancerType   `yaml:"load_balancer" json:"load_balancer"`               // 上游默认负载均衡类型，路由未指定时使用
+	LoadBalancerParams LoadBalancerParams `yaml:"load_balancer_params" json:"load_balancer_params"` // 负载均衡参数
+	Signing            *SigningConfig     `yaml:"signing" json:"signing,omitempty"`                 // 发往该上游的请求签名
+	Protocols          []string           `yaml:"protocols" json:"protocols,omitempty"`             // 协议偏好顺序（如h2、http/1.1），按后端记住不支持的协议并回退到下一个
+	ProtocolRecheck    time.Duration      `yaml:"protocol_recheck" json:"protocol_recheck,omitempty"` // 后端不支持的协议多久后重新尝试，默认10m
+	RateLimit          *AggregateRateLimitConfig `yaml:"rate_limit" json:"rate_limit,omitempty"` // 发往该上游的总请求速率上限
+	Concurrency        *ConcurrencyLimitConfig   `yaml:"concurrency" json:"concurrency,omitempty"` // 发往该上游的并发请求数上限
+	Queue              *BackendQueueConfig       `yaml:"queue" json:"queue,omitempty"`             // 所有后端达到连接上限时的等待队列
+	LoadReports        string                    `yaml:"load_reports" json:"load_reports,omitempty"` // 从后端响应读取的负载报告格式，orca表示读取ORCA endpoint-load-metrics
+}
+
+// LoadReportsORCA 从后端响应头或trailer读取ORCA负载报告
+const LoadReportsORCA = "orca"
+
+// LoadBalancerParams 负载均衡参数，零值表示使用默认值
+type LoadBalancerParams struct {
+	HashKey    string `yaml:"hash_key" json:"hash_key,omitempty"`       // ip_hash的哈希键：client_ip、path、header:<名称>、cookie:<名称>、query:<名称>
+	SampleSize int    `yaml:"sample_size" json:"sample_size,omitempty"` // p2c每次采样的后端数量
+	ZoneAware  bool   `yaml:"zone_aware" json:"zone_aware,omitempty"`   // 优先选择与代理同一可用区的后端，本区容量耗尽时才跨区
+}
+
+// SigningConfig 上游请求签名配置
+type SigningConfig struct {
+	Type string `yaml:"type" json:"type"` // sigv4 或 hmac
+	Host string `yaml:"host" json:"host"` // 发往后端的Host头，为空时使用后端地址
+
+	// SigV4（S3、API Gateway等AWS服务），凭证为空时读取AWS_ACCESS_KEY_ID等环境变量
+	Region          string `yaml:"region" json:"region"`
+	Service         string `yaml:"service" json:"service"`
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"-" secret:"true"`
+	SessionToken    string `yaml:"session_token" json:"-" secret:"true"`
+	UnsignedPayload bool   `yaml:"unsigned_payload" json:"unsigned_payload"` // 不计算请求体哈希
+
+	// 通用HMAC-SHA256
+	Secret          string `yaml:"secret" json:"-" secret:"true"`
+	KeyID           string `yaml:"key_id" json:"key_id"`
+	Header          string `yaml:"header" json:"header"`
+	TimestampHeader string `yaml:"timestamp_header" json:"timestamp_header"`
+	KeyIDHeader     string `yaml:"key_id_header" json:"key_id_header"`
+	SignedFields    []string      `yaml:"signed_fields" json:"signed_fields,omitempty"` // 待签名字段及顺序：method、host、uri、timestamp、body、header:<名称>，必须包含timestamp
+	FieldsHeader    string        `yaml:"fields_header" json:"fields_header"`           // 配置了signed_fields时写入字段列表的请求头
+	MaxClockSkew    time.Duration `yaml:"max_clock_skew" json:"max_clock_skew"`         // 后端校验签名时允许的时间戳偏差，默认5m
 }
 
 // RoutingRule 路由规则
 type RoutingRule struct {
 	Path         string           `yaml:"path" json:"path"`
 	Upstream     string           `yaml:"upstream" json:"upstream"`
-	LoadBalancer LoadBalancerType `yaml:"load_balancer" json:"load_balancer"`
+	LoadBalancer LoadBalancerType `yaml:"load_balancer" json:"load_balancer"` // 为空时使用上游的默认负载均衡
+	LoadBalancerParams *LoadBalancerParams `yaml:"load_balancer_params" json:"load_balancer_params,omitempty"` // 覆盖上游的负载均衡参数
 	Protocols    map[ProtocolType]LoadBalancerType `yaml:"protocols" json:"protocols"` // 协议特定负载均衡
+	FallbackUpstreams []string    `yaml:"fallback_upstreams" json:"fallback_upstreams"` // 主上游不可用时按顺序尝试的备用上游
+	Standby      *StandbyConfig   `yaml:"standby" json:"standby"`                     // 冷备上游
+	HealthRequirement *HealthRequirementConfig `yaml:"health_requirement" json:"health_requirement,omitempty"` // 主上游健康后端不足时的降级处理
+	CacheControl *CacheControlConfig `yaml:"cache_control" json:"cache_control,omitempty"` // 响应缓存头覆盖
+	BackendSelector map[string]string `yaml:"backend_selector" json:"backend_selector,omitempty"` // 只在标签全部匹配的后端中负载均衡
+	Cache        *RouteCacheConfig `yaml:"cache" json:"cache,omitempty"`                       // 响应缓存
+	Match        []MatchCondition  `yaml:"match" json:"match,omitempty"`                       // 路径匹配后还需满足的条件（全部满足）
+	RequestHeaders  []HeaderTemplate `yaml:"request_headers" json:"request_headers,omitempty"`   // 转发前设置的请求头
+	ResponseHeaders []HeaderTemplate `yaml:"response_headers" json:"response_headers,omitempty"` // 返回前设置的响应头
+	ResponseHeaderFilter *HeaderFilterConfig `yaml:"response_header_filter" json:"response_header_filter,omitempty"` // 过滤转发给客户端的上游响应头
+	Retry        *RetryConfig      `yaml:"retry" json:"retry,omitempty"`                       // 请求失败时换一个后端重试
+	ForwardedHeaders string        `yaml:"forwarded_headers" json:"forwarded_headers,omitempty"` // append（默认）或strip，strip时删除可识别客户端的请求头
+	ForwardAuth  *ForwardAuthConfig `yaml:"forward_auth" json:"forward_auth,omitempty"`        // 转发前由外部认证服务认证请求
+	APIKeyAuth   bool              `yaml:"api_key_auth" json:"api_key_auth,omitempty"`         // 要求请求携带有效的API密钥
+	AllowedSPIFFEIDs []string      `yaml:"allowed_spiffe_ids" json:"allowed_spiffe_ids,omitempty"` // 只允许客户端证书SPIFFE ID匹配的请求
+	BotFilter    *BotFilterConfig  `yaml:"bot_filter" json:"bot_filter,omitempty"`             // 按User-Agent和机器人评分过滤请求
+	CORS         *CORSConfig       `yaml:"cors" json:"cors,omitempty"`                         // 跨域资源共享，由代理直接响应预检请求
+	SecurityHeaders *SecurityHeadersConfig `yaml:"security_headers" json:"security_headers,omitempty"` // 安全响应头，配置时替换server.security_headers
+	Mirror       *MirrorConfig     `yaml:"mirror" json:"mirror,omitempty"`                     // 流量镜像
+	Split        *TrafficSplitConfig `yaml:"split" json:"split,omitempty"`                     // 按权重在多个上游之间分流
+	FanOut       *FanOutConfig     `yaml:"fan_out" json:"fan_out,omitempty"`                   // 并行扇出到多个上游并组合响应，配置时不使用upstream
+	LongPoll     *LongPollConfig   `yaml:"long_poll" json:"long_poll,omitempty"`               // 长轮询：延长等待上游响应的时间并告知后端
+	RangeRequests *RangeRequestsConfig `yaml:"range_requests" json:"range_requests,omitempty"` // 断点续传：校验并转发Range请求，以流方式返回上游响应
+	BodyInspection *BodyInspectionConfig `yaml:"body_inspection" json:"body_inspection,omitempty"` // 需要读取请求体的过滤器的缓冲上限
+	RateLimit    *RateLimitConfig  `yaml:"rate_limit" json:"rate_limit,omitempty"`             // 按客户端IP限速
+	TotalRateLimit *AggregateRateLimitConfig `yaml:"total_rate_limit" json:"total_rate_limit,omitempty"` // 路由的总请求速率上限（所有客户端共享）
+	Concurrency  *ConcurrencyLimitConfig `yaml:"concurrency" json:"concurrency,omitempty"`           // 路由同时处理的请求数上限
+	Priority     string            `yaml:"priority" json:"priority,omitempty"`                 // 负载卸载时的优先级：critical、normal（默认）或low
+}
+
+// SecurityHeadersConfig 安全响应头
+// 在全部响应（包括代理生成的错误响应）中设置X-Content-Type-Options: nosniff和以下响应头
+type SecurityHeadersConfig struct {
+	Enabled               bool          `yaml:"enabled" json:"enabled"`
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" json:"hsts_max_age"`                                 // Strict-Transport-Security的max-age，默认365天，只在HTTPS响应中设置，负数表示不设置
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" json:"hsts_include_subdomains,omitempty"` // HSTS同时作用于子域名
+	HSTSPreload           bool          `yaml:"hsts_preload" json:"hsts_preload,omitempty"`                       // 申请加入浏览器的HSTS预加载列表
+	FrameOptions          string        `yaml:"frame_options" json:"frame_options"`                               // X-Frame-Options：DENY（默认）、SAMEORIGIN或off
+	ReferrerPolicy        string        `yaml:"referrer_policy" json:"referrer_policy"`                           // Referrer-Policy，默认strict-origin-when-cross-origin，off表示不设置
+	ContentSecurityPolicy string        `yaml:"content_security_policy" json:"content_security_policy,omitempty"` // Content-Security-Policy，为空时不设置
+	CSPReportOnly         bool          `yaml:"csp_report_only" json:"csp_report_only,omitempty"`                 // 使用Content-Security-Policy-Report-Only只报告不拦截
+	Override              bool          `yaml:"override" json:"override,omitempty"`                               // 替换上游已设置的同名响应头，默认保留上游的值
+}
+
+// CORSConfig 路由的跨域资源共享配置
+// 代理直接响应OPTIONS预检请求，并为允许的来源在响应中设置Access-Control-*头，上游返回的同类响应头被替换
+type CORSConfig struct {
+	AllowOrigins     []string      `yaml:"allow_origins" json:"allow_origins"`                       // 允许的来源，*表示任意来源，https://*.example.com匹配子域名
+	AllowMethods     []string      `yaml:"allow_methods" json:"allow_methods"`                       // 允许的请求方法，默认GET、HEAD、POST
+	AllowHeaders     []string      `yaml:"allow_headers" json:"allow_headers,omitempty"`             // 允许的请求头，为空时允许预检请求列出的全部请求头
+	ExposeHeaders    []string      `yaml:"expose_headers" json:"expose_headers,omitempty"`           // 允许浏览器脚本读取的响应头
+	AllowCredentials bool          `yaml:"allow_credentials" json:"allow_credentials,omitempty"`     // 允许携带Cookie等凭据，不能与任意来源同时使用
+	MaxAge           time.Duration `yaml:"max_age" json:"max_age"`                                   // 浏览器缓存预检结果的时间，0表示不缓存
+}
+
+// 机器人过滤命中时的处理方式
+const (
+	BotActionBlock     = "block"     // 返回403
+	BotActionChallenge = "challenge" // 返回设置Cookie的JavaScript挑战页，执行脚本后的重试请求放行
+	BotActionTag       = "tag"       // 照常转发，只在请求头中告知上游评分
+)
+
+// BotFilterConfig 按User-Agent和机器人评分过滤请求
+// 评分0-100，由缺少常见请求头、已知的自动化工具User-Agent等规则累加，deny匹配时为100，allow匹配时不评分直接放行
+type BotFilterConfig struct {
+	Allow           []string      `yaml:"allow" json:"allow,omitempty"`                       // User-Agent正则，匹配时直接放行（如搜索引擎爬虫）
+	Deny            []string      `yaml:"deny" json:"deny,omitempty"`                         // User-Agent正则，匹配时评分为100
+	Threshold       int           `yaml:"threshold" json:"threshold"`                         // 评分达到该值时按action处理，默认60
+	Action          string        `yaml:"action" json:"action"`                               // block（默认）、challenge或tag
+	ScoreHeader     string        `yaml:"score_header" json:"score_header"`                   // 转发给上游的评分请求头，默认X-Bot-Score
+	ChallengeTTL    time.Duration `yaml:"challenge_ttl" json:"challenge_ttl"`                 // 通过挑战的Cookie有效期，默认1h
+	ChallengeSecret string        `yaml:"challenge_secret" json:"-" secret:"true"` // 签名挑战Cookie的密钥，为空时使用进程启动时生成的随机密钥
+}
+
+// RateLimitConfig 按客户端IP的令牌桶限速，超过时返回429
+type RateLimitConfig struct {
+	Rate  float64 `yaml:"rate" json:"rate"`   // 每个客户端每秒允许的请求数
+	Burst int     `yaml:"burst" json:"burst"` // 允许的突发请求数（令牌桶容量），默认为rate向上取整
+}
+
+// 路由转发客户端信息的方式
+const (
+	ForwardedHeadersAppend = "append" // 把客户端IP追加到X-Forwarded-For并设置其他代理头（默认）
+	ForwardedHeadersStrip  = "strip"  // 删除可识别客户端的请求头，用于转发到不应获知客户端IP的第三方
+)
+
+// 总请求速率超过上限时的处理方式
+const (
+	RateLimitReject = "reject" // 返回429
+	RateLimitDelay  = "delay"  // 排队等待到下一个令牌可用（漏桶），等待时间超过max_delay时返回429
+)
+
+// AggregateRateLimitConfig 所有客户端共享的令牌桶限速，用于限制整个代理、单个路由或上游的总请求速率
+type AggregateRateLimitConfig struct {
+	Rate     float64       `yaml:"rate" json:"rate"`           // 每秒允许的请求数
+	Burst    int           `yaml:"burst" json:"burst"`         // 允许的突发请求数，默认为rate向上取整
+	Mode     string        `yaml:"mode" json:"mode"`           // reject（默认）或delay
+	MaxDelay time.Duration `yaml:"max_delay" json:"max_delay"` // delay模式下请求最长等待时间，默认1s
+}
+
+// 并发请求数达到上限时的处理方式
+const (
+	ConcurrencyReject = "reject" // 立即返回503
+	ConcurrencyQueue  = "queue"  // 排队等待名额，排队已满或等待超过queue_timeout时返回503
+)
+
+// ConcurrencyLimitConfig 同时处理的请求数上限
+type ConcurrencyLimitConfig struct {
+	MaxInflight  int           `yaml:"max_inflight" json:"max_inflight"`   // 同时处理的请求数上限
+	Overflow     string        `yaml:"overflow" json:"overflow"`           // reject（默认）或queue
+	MaxQueue     int           `yaml:"max_queue" json:"max_queue"`         // queue模式下最多排队的请求数，默认等于max_inflight
+	QueueTimeout time.Duration `yaml:"queue_timeout" json:"queue_timeout"` // queue模式下最长排队时间，默认1s
+}
+
+// BackendQueueConfig 上游所有后端都达到连接上限（max_conn）时的等待队列
+// 请求按先后顺序排队，后端释放连接时重新选择后端，队列已满或等待超过max_wait时返回503
+type BackendQueueConfig struct {
+	MaxSize int           `yaml:"max_size" json:"max_size"` // 最多排队的请求数
+	MaxWait time.Duration `yaml:"max_wait" json:"max_wait"` // 最长排队时间，默认1s
+}
+
+// 请求体超过检查上限时的处理方式
+const (
+	BodyInspectionDeny   = "deny"   // 返回413
+	BodyInspectionBypass = "bypass" // 过滤器跳过请求体，请求体以流方式转发
+)
+
+// BodyInspectionConfig 路由的请求体检查限制
+// 请求签名等需要读取完整请求体的过滤器最多缓冲MaxBytes字节，避免大请求体全部读入内存
+type BodyInspectionConfig struct {
+	MaxBytes int    `yaml:"max_bytes" json:"max_bytes"` // 默认1MB
+	OnExceed string `yaml:"on_exceed" json:"on_exceed"` // deny（默认）或bypass，长度未知（分块传输）的请求体视为超过上限
+}
+
+// TrafficSplitConfig 按权重在多个上游之间分流（如95%稳定版、5%灰度版）
+// 按分流键的哈希分配，同一客户端总是落在同一个上游，不会在版本之间来回切换
+type TrafficSplitConfig struct {
+	Key       string             `yaml:"key" json:"key"`             // 分流键（变量模板），默认$client_ip，求值为空时使用客户端IP
+	Salt      string             `yaml:"salt" json:"salt,omitempty"` // 参与哈希的盐值，不同路由使用不同盐值以免选中同一批客户端
+	Upstreams []WeightedUpstream `yaml:"upstreams" json:"upstreams"`
+	Steering  []SplitSteering    `yaml:"steering" json:"steering,omitempty"` // 按请求特征定向到指定上游，优先于按权重分流
+}
+
+// SplitSteering 分流的定向规则，条件全部满足时不按权重分流，直接使用指定上游（如带X-Canary: true的请求总是进入灰度版）
+type SplitSteering struct {
+	Match    []MatchCondition `yaml:"match" json:"match"`
+	Upstream string           `yaml:"upstream" json:"upstream"`
+}
+
+// WeightedUpstream 分流的上游和权重
+type WeightedUpstream struct {
+	Upstream string `yaml:"upstream" json:"upstream"`
+	Weight   int    `yaml:"weight" json:"weight"` // 0表示不分配新客户端
+}
+
+// LongPollConfig 长轮询路由的超时设置
+// 后端在有事件或接近超时前一直不返回响应，代理按timeout等待而不是默认的30s，并通过请求头告知后端应在何时返回
+type LongPollConfig struct {
+	Timeout       time.Duration `yaml:"timeout" json:"timeout"`               // 等待上游响应的最长时间，默认120s
+	Margin        time.Duration `yaml:"margin" json:"margin"`                 // 告知后端的等待时间比timeout提前的量，默认5s
+	TimeoutHeader string        `yaml:"timeout_header" json:"timeout_header"` // 告知后端可以等待的秒数（timeout-margin）的请求头，默认X-Proxy-Timeout
+	TimeoutStatus int           `yaml:"timeout_status" json:"timeout_status"` // 仍然超时时返回的状态码，默认504，客户端会重新轮询时可用204
+}
+
+// RangeRequestsConfig 断点续传（Range请求）配置
+// 启用时代理校验客户端的Range请求头后转发给上游，校验上游返回的206响应，并以流方式返回较大的响应体，不在内存中缓冲整个文件
+type RangeRequestsConfig struct {
+	Enabled   bool          `yaml:"enabled" json:"enabled"`
+	MaxRanges int           `yaml:"max_ranges" json:"max_ranges"` // 一个请求最多的范围数，超过时去掉Range头返回完整响应，默认1
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`       // 转发一个响应（包括传输整个响应体）的最长时间，默认1h
+}
+
+// ForwardAuthConfig 外部认证配置
+// 转发前把请求头发送到认证服务：2xx时放行并把认证服务返回的身份请求头传给上游，否则把认证服务的响应返回给客户端（如跳转到登录页）
+type ForwardAuthConfig struct {
+	Address         string        `yaml:"address" json:"address"`                                       // 认证服务的URL，如 http://oauth2-proxy:4180/oauth2/auth
+	Timeout         time.Duration `yaml:"timeout" json:"timeout"`                                       // 认证请求超时，默认5s
+	RequestHeaders  []string      `yaml:"request_headers" json:"request_headers,omitempty"`             // 发送给认证服务的客户端请求头，为空时发送全部（逐跳头除外）
+	ResponseHeaders []string      `yaml:"response_headers" json:"response_headers,omitempty"`           // 认证通过时从认证服务响应复制到上游请求的身份头，客户端传入的同名头总是被删除
+}
+
+// MirrorConfig 流量镜像配置
+// 按比例把请求异步复制到镜像上游，镜像的响应被丢弃，用于以生产流量测试新后端
+type MirrorConfig struct {
+	Upstream    string        `yaml:"upstream" json:"upstream"`
+	Percent     int           `yaml:"percent" json:"percent"`             // 镜像的请求比例（1-100），默认100
+	Timeout     time.Duration `yaml:"timeout" json:"timeout"`             // 镜像请求超时，默认5s
+	MaxBodySize int           `yaml:"max_body_size" json:"max_body_size"` // 可以镜像的请求体上限，默认1MB，更大或长度未知的请求不镜像
+}
+
+// 扇出响应的组合方式
+const (
+	FanOutFirstSuccess = "first_success" // 返回最先成功（2xx）的目标的响应
+	FanOutMerge        = "merge"         // 等待所有目标，把各目标的JSON响应合并为一个JSON对象
+)
+
+// FanOutConfig 并行扇出：把请求同时发送到多个上游并组合响应，用于聚合接口
+type FanOutConfig struct {
+	Targets     []FanOutTarget `yaml:"targets" json:"targets"`
+	Mode        string         `yaml:"mode" json:"mode"`                   // first_success（默认）或merge
+	Template    string         `yaml:"template" json:"template,omitempty"` // merge模式的响应模板，$fanout_<名称>为目标的JSON响应，未配置时按目标名称组成JSON对象
+	Timeout     time.Duration  `yaml:"timeout" json:"timeout"`             // 各目标的请求超时，默认5s
+	MaxBodySize int            `yaml:"max_body_size" json:"max_body_size"` // 可以扇出的请求体上限，默认1MB，更大或长度未知的请求返回413
+}
+
+// FanOutTarget 扇出目标
+type FanOutTarget struct {
+	Name     string `yaml:"name" json:"name"`           // 目标名称，merge模式下为JSON对象的键
+	Upstream string `yaml:"upstream" json:"upstream"`
+	Path     string `yaml:"path" json:"path,omitempty"` // 发往该目标的请求URI，可使用变量（如 /users/$arg_id），默认使用原请求URI
+	Required bool   `yaml:"required" json:"required"`   // merge模式下该目标失败时返回502，否则该目标的值为null
+}
+
+// RetryConfig 路由的重试策略
+// 连接失败或返回指定状态码时，换一个本次请求未尝试过的健康后端重试（包括备用上游）
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" json:"max_attempts"` // 包括首次请求在内的最大尝试次数，默认2
+	StatusCodes []int         `yaml:"status_codes" json:"status_codes"` // 触发重试的状态码，默认502、503、504
+	Methods     []string      `yaml:"methods" json:"methods"`           // 可以重试的请求方法，默认GET、HEAD、OPTIONS、PUT、DELETE
+	Backoff     time.Duration `yaml:"backoff" json:"backoff"`           // 重试前的等待时间，之后每次翻倍，默认不等待
+	MaxBackoff  time.Duration `yaml:"max_backoff" json:"max_backoff"`   // 等待时间上限，为0时不限制
+}
+
+// HeaderFilterConfig 上游响应头过滤配置
+// 名称不区分大小写，以*结尾时按前缀匹配（如X-Internal-*）；allow优先于deny
+// Content-Length、Content-Type等描述响应体的头不会被过滤
+type HeaderFilterConfig struct {
+	Allow       []string `yaml:"allow" json:"allow"`               // 总是转发的响应头
+	Deny        []string `yaml:"deny" json:"deny"`                 // 删除的响应头
+	DefaultDeny bool     `yaml:"default_deny" json:"default_deny"` // 为true时只转发allow中的响应头
+}
+
+// CacheControlConfig 响应缓存头覆盖配置
+// 改写后的响应头同时决定代理缓存和下游CDN/浏览器的缓存行为
+type CacheControlConfig struct {
+	Mode        string        `yaml:"mode" json:"mode"`               // override：替换上游缓存头；default：仅上游未设置时添加；strip：删除上游缓存头
+	Value       string        `yaml:"value" json:"value"`             // Cache-Control值，与max_age二选一
+	MaxAge      time.Duration `yaml:"max_age" json:"max_age"`         // 生成"public, max-age=N"并设置Expires
+	CDNValue    string        `yaml:"cdn_value" json:"cdn_value"`     // 仅对CDN生效的缓存指令
+	CDNHeader   string        `yaml:"cdn_header" json:"cdn_header"`   // CDN缓存指令使用的响应头
+	StatusCodes []int         `yaml:"status_codes" json:"status_codes"` // 生效的状态码，为空时对2xx和3xx生效
+	Extensions  []string      `yaml:"extensions" json:"extensions"`   // 生效的路径扩展名，为空时对所有路径生效
+}
+
+// StandbyConfig 冷备上游配置
+// 主上游健康后端比例低于FailoverRatio时切换到备用上游，恢复到RecoverRatio以上后自动切回
+type StandbyConfig struct {
+	Upstream      string        `yaml:"upstream" json:"upstream"`
+	FailoverRatio float64       `yaml:"failover_ratio" json:"failover_ratio"`
+	RecoverRatio  float64       `yaml:"recover_ratio" json:"recover_ratio"`
+	MinDuration   time.Duration `yaml:"min_duration" json:"min_duration"` // 切换到备用上游后的最短停留时间
+}
+
+// 路由健康后端不足时的降级方式
+const (
+	HealthFallbackStatus   = "status"   // 返回配置的状态码和页面（默认503）
+	HealthFallbackCache    = "cache"    // 返回缓存中的响应（包括已过期的），没有缓存时返回状态页面
+	HealthFallbackUpstream = "upstream" // 转发到替代上游
+)
+
+// HealthRequirementConfig 路由要求主上游至少有多少健康后端才转发请求
+// 大部分后端故障时不再把全部流量压到剩余的少数后端上，而是按Fallback降级
+type HealthRequirementConfig struct {
+	MinHealthy        int           `yaml:"min_healthy" json:"min_healthy"`                 // 最少健康后端数
+	MinHealthyPercent float64       `yaml:"min_healthy_percent" json:"min_healthy_percent"` // 最少健康后端占全部后端的百分比（0-100）
+	Fallback          string        `yaml:"fallback" json:"fallback"`                       // status（默认）、cache或upstream
+	Upstream          string        `yaml:"upstream" json:"upstream"`                       // fallback为upstream时使用的替代上游
+	Status            int           `yaml:"status" json:"status"`                           // 状态页面的状态码，默认503
+	Body              string        `yaml:"body" json:"body"`
+	ContentType       string        `yaml:"content_type" json:"content_type"`
+	RetryAfter        time.Duration `yaml:"retry_after" json:"retry_after"`
+}
+
+// MaintenanceConfig 维护模式默认页面配置
+type MaintenanceConfig struct {
+	RetryAfter  time.Duration `yaml:"retry_after" json:"retry_after"`
+	Body        string        `yaml:"body" json:"body"`
+	ContentType string        `yaml:"content_type" json:"content_type"`
+}
+
+// ClusterConfig 集群视图配置（通过轮询其他实例的管理API聚合状态）
+type ClusterConfig struct {
+	Enabled      bool          `yaml:"enabled" json:"enabled"`
+	NodeName     string        `yaml:"node_name" json:"node_name"`
+	Peers        []string      `yaml:"peers" json:"peers"` // 其他实例的管理API地址，例如 http://10.0.0.2:9091
+	PollInterval time.Duration `yaml:"poll_interval" json:"poll_interval"`
+	Timeout      time.Duration `yaml:"timeout" json:"timeout"`
+	Token        string        `yaml:"token" json:"-" secret:"true"`             // 请求对端管理API时使用的Bearer令牌
+	CAFile       string        `yaml:"ca_file" json:"ca_file"`     // 验证对端管理API证书的CA（对端使用自签名证书时）
+}
+
+// RoutingTokenConfig 签名路由令牌配置
+// 携带有效HMAC签名令牌的请求可以指定上游/后端，用于内部工具定向调试
+type RoutingTokenConfig struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	Header  string        `yaml:"header" json:"header"`
+	Secret  string        `yaml:"secret" json:"-" secret:"true"`
+	MaxTTL  time.Duration `yaml:"max_ttl" json:"max_ttl"` // 管理API签发令牌的最长有效期
+}
+
+// ControlPlaneConfig 管理API和后台健康检查的并发上限
+// 管理和健康检查工作只能使用固定数量的工作协程，大量管理请求或健康探测不会与数据面争抢CPU和连接
+type ControlPlaneConfig struct {
+	AdminWorkers      int           `yaml:"admin_workers" json:"admin_workers"`             // 同时处理的管理API请求数上限，默认8，修改后需要重启
+	AdminQueueTimeout time.Duration `yaml:"admin_queue_timeout" json:"admin_queue_timeout"` // 管理请求等待空闲工作协程的最长时间，超时返回503，默认5s
+	HealthWorkers     int           `yaml:"health_workers" json:"health_workers"`           // 同时进行的后台健康探测数上限，默认8
 }
 
 // GRPCConfig gRPC配置
 type GRPCConfig struct {
-	Enabled bool   `yaml:"enabled" json:"enabled"`
-	Host    string `yaml:"host" json:"host"`
-	Port    int    `yaml:"port" json:"port"`
+	Enabled bool            `yaml:"enabled" json:"enabled"`
+	Host    string          `yaml:"host" json:"host"` // 默认127.0.0.1，监听其他地址时必须配置令牌或客户端证书认证
+	Port    int             `yaml:"port" json:"port"`
+	Auth    AdminAuthConfig `yaml:"auth" json:"auth"`
+	TLS     *AdminTLSConfig `yaml:"tls" json:"tls,omitempty"` // 配置时管理API使用HTTPS，修改后需要重启
+}
+
+// AdminAuthConfig 管理API认证
+// 配置了令牌时每个请求需要携带 Authorization: Bearer <令牌>，或提供tls.client_ca_file签发的客户端证书
+type AdminAuthConfig struct {
+	Tokens []string `yaml:"tokens" json:"-" secret:"true"` // 允许的令牌（至少16个字符），任一匹配即通过
+}
+
+// AdminTLSConfig 管理API的TLS监听配置
+type AdminTLSConfig struct {
+	CertFile     string `yaml:"cert_file" json:"cert_file"`
+	KeyFile      string `yaml:"key_file" json:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file" json:"client_ca_file,omitempty"` // 验证客户端证书（mTLS）的CA，未配置令牌时要求每个连接提供客户端证书
+}
+
+// RequestContext 负载均衡器可见的请求信息
+type RequestContext interface {
+	ClientIP() string          // 客户端真实IP（已按real_ip_header和可信代理解析）
+	Path() string              // 请求路径
+	Header(key string) []byte  // 请求头，不存在时返回nil
+	Cookie(name string) []byte // Cookie值，不存在时返回nil
+	QueryArg(name string) []byte // 查询参数，不存在时返回nil
 }
 
 // LoadBalancer 负载均衡器接口
 type LoadBalancer interface {
-	SelectBackend(backends []*Backend, req interface{}) *Backend
+	SelectBackend(backends []*Backend, req RequestContext) *Backend
 	Name() string
 }
 
@@ -157,23 +965,48 @@ type MonitorService interface {
 	ReportPerformance(ctx context.Context, upstream, backendID string, perf *PerformanceInfo) error
 }
 
+// connUnderflows 连接数已为0时仍调用DecConnections的次数，说明增减没有配对
+var connUnderflows atomic.Int64
+
+// ConnectionUnderflows 获取连接数减到负数（被截断为0）的次数
+func ConnectionUnderflows() int64 {
+	return connUnderflows.Load()
+}
+
+// state 获取运行时状态，首次调用时创建
+func (b *Backend) state() *backendRuntime {
+	if rt := b.runtime.Load(); rt != nil {
+		return rt
+	}
+	b.runtime.CompareAndSwap(nil, &backendRuntime{})
+	return b.runtime.Load()
+}
+
+// ShareRuntime 与原后端对象共用连接数、断开标记、性能信息和延迟
+// 修改配置时新对象替换原对象，处理中的请求仍在原对象上减少连接数，共用后最大连接数限制和断开标记不会因替换失效
+func (b *Backend) ShareRuntime(old *Backend) {
+	b.runtime.Store(old.state())
+}
+
 // 高性能Backend方法（使用原子操作，避免锁竞争）
 func (b *Backend) GetConnections() int64 {
-	return atomic.LoadInt64(&b.Connections)
+	return atomic.LoadInt64(&b.state().connections)
 }
 
 func (b *Backend) IncConnections() {
-	atomic.AddInt64(&b.Connections, 1)
+	atomic.AddInt64(&b.state().connections, 1)
 }
 
 func (b *Backend) DecConnections() {
 	// 使用CAS操作确保不会减到负数
+	rt := b.state()
 	for {
-		current := atomic.LoadInt64(&b.Connections)
+		current := atomic.LoadInt64(&rt.connections)
 		if current <= 0 {
+			connUnderflows.Add(1)
 			return
 		}
-		if atomic.CompareAndSwapInt64(&b.Connections, current, current-1) {
+		if atomic.CompareAndSwapInt64(&rt.connections, current, current-1) {
 			return
 		}
 		// CAS失败，重试
@@ -181,7 +1014,7 @@ func (b *Backend) DecConnections() {
 }
 
 func (b *Backend) SetConnections(conns int64) {
-	atomic.StoreInt64(&b.Connections, conns)
+	atomic.StoreInt64(&b.state().connections, conns)
 }
 
 func (b *Backend) IsActive() bool {
@@ -198,31 +1031,147 @@ func (b *Backend) SetActive(active bool) {
 	b.Active = active
 }
 
+// IsHealthy 后端是否健康，未配置健康检查且没有手动覆盖时始终为true
+func (b *Backend) IsHealthy() bool {
+	return atomic.LoadInt32(&b.unhealthy) == 0
+}
+
+// SetHealthy 设置健康状态（由健康检查器维护）
+func (b *Backend) SetHealthy(healthy bool) {
+	var val int32
+	if !healthy {
+		val = 1
+	}
+	atomic.StoreInt32(&b.unhealthy, val)
+}
+
 func (b *Backend) ShouldDisconnect() bool {
-	return atomic.LoadInt32(&b.disconnect) == 1
+	return atomic.LoadInt32(&b.state().disconnect) == 1
 }
 
 func (b *Backend) MarkForDisconnect() {
-	atomic.StoreInt32(&b.disconnect, 1)
+	atomic.StoreInt32(&b.state().disconnect, 1)
 }
 
 func (b *Backend) ClearDisconnectMark() {
-	atomic.StoreInt32(&b.disconnect, 0)
+	atomic.StoreInt32(&b.state().disconnect, 0)
 }
 
-// 高并发优化：性能信息直接访问，无锁
+// UpdatePerformance 更新性能信息，与负载均衡的读取无锁并发，perf在调用后不能再修改
+// 记录代理收到上报的时间，过期判断只使用该时间，后端时钟偏差不影响负载均衡
 func (b *Backend) UpdatePerformance(perf *PerformanceInfo) {
-	b.Performance = perf
-	b.LastReport = time.Now()
+	now := time.Now()
+	perf.ReceivedAt = now.UnixMilli()
+	perf.ClockSkewMs = 0
+	if ts := reportTimestampMillis(perf.Timestamp); ts > 0 {
+		perf.ClockSkewMs = perf.ReceivedAt - ts
+	}
+	rt := b.state()
+	rt.performance.Store(perf)
+	atomic.StoreInt64(&rt.reportedAt, now.UnixNano())
 }
 
+// GetPerformance 获取最近一次上报的性能信息，未上报时返回nil；返回值只读
 func (b *Backend) GetPerformance() *PerformanceInfo {
-	return b.Performance
+	return b.state().performance.Load()
+}
+
+// LastReport 获取代理收到最近一次性能上报的时间，未上报时为零值
+func (b *Backend) LastReport() time.Time {
+	if reportedAt := atomic.LoadInt64(&b.state().reportedAt); reportedAt != 0 {
+		return time.Unix(0, reportedAt)
+	}
+	return time.Time{}
+}
+
+// backendJSON Backend的JSON编码（不含运行时状态），避免MarshalJSON递归
+type backendJSON Backend
+
+// runtimeJSON 连接数、性能信息和上报时间在JSON中的字段
+type runtimeJSON struct {
+	Connections int64            `json:"connections"`
+	Performance *PerformanceInfo `json:"performance"`
+	LastReport  time.Time        `json:"last_report"`
+}
+
+// MarshalJSON 在配置字段之外输出连接数、性能信息和上报时间的快照
+func (b *Backend) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		*backendJSON
+		runtimeJSON
+	}{(*backendJSON)(b), runtimeJSON{b.GetConnections(), b.GetPerformance(), b.LastReport()}})
+}
+
+// UnmarshalJSON 解析配置字段，以及MarshalJSON输出的连接数、性能信息和上报时间
+func (b *Backend) UnmarshalJSON(data []byte) error {
+	var rt runtimeJSON
+	if err := json.Unmarshal(data, &struct {
+		*backendJSON
+		*runtimeJSON
+	}{(*backendJSON)(b), &rt}); err != nil {
+		return err
+	}
+	state := b.state()
+	atomic.StoreInt64(&state.connections, rt.Connections)
+	state.performance.Store(rt.Performance)
+	if !rt.LastReport.IsZero() {
+		atomic.StoreInt64(&state.reportedAt, rt.LastReport.UnixNano())
+	}
+	return nil
+}
+
+// reportTimestampMillis 把后端上报的时间戳转换为Unix毫秒，小于1e12时按秒处理
+func reportTimestampMillis(ts int64) int64 {
+	if ts > 0 && ts < 1e12 {
+		return ts * 1000
+	}
+	return ts
+}
+
+// GetFreshPerformance 获取未过期的性能信息，代理收到最近一次上报早于ttl时返回nil（ttl<=0表示永不过期）
+func (b *Backend) GetFreshPerformance(ttl time.Duration) *PerformanceInfo {
+	if ttl > 0 {
+		reportedAt := atomic.LoadInt64(&b.state().reportedAt)
+		if reportedAt == 0 || time.Since(time.Unix(0, reportedAt)) > ttl {
+			return nil
+		}
+	}
+	return b.GetPerformance()
+}
+
+// RecordLatency 记录一次请求延迟（指数加权移动平均，新样本权重1/5）
+func (b *Backend) RecordLatency(d time.Duration) {
+	sample := int64(d)
+	rt := b.state()
+	for {
+		current := atomic.LoadInt64(&rt.latency)
+		next := sample
+		if current > 0 {
+			next = current + (sample-current)/5
+		}
+		if atomic.CompareAndSwapInt64(&rt.latency, current, next) {
+			return
+		}
+	}
+}
+
+// GetLatency 获取平均请求延迟，没有样本时返回0
+func (b *Backend) GetLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&b.state().latency))
 }
 
 // CalculateUtilization 计算节点占用率 (0-1)
 func (b *Backend) CalculateUtilization() float64 {
-	perf := b.GetPerformance()
+	return utilizationOf(b.GetPerformance())
+}
+
+// CalculateFreshUtilization 计算节点占用率 (0-1)，性能上报过期时返回0
+func (b *Backend) CalculateFreshUtilization(ttl time.Duration) float64 {
+	return utilizationOf(b.GetFreshPerformance(ttl))
+}
+
+// utilizationOf 根据性能信息计算占用率 (0-1)
+func utilizationOf(perf *PerformanceInfo) float64 {
 	if perf == nil {
 		return 0
 	}
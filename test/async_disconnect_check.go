@@ -1,144 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"os/exec"
-	"time"
-)
-
-func main() {
-	fmt.Println("🚀 SpeedMimi 异步断开连接测试")
-	fmt.Println("================================")
-
-	// 启动后端服务器
-	fmt.Println("1. 启动后端服务器...")
-	backend1Cmd := exec.Command("go", "run", "backend_performance.go", "8081")
-	backend1Cmd.Dir = "test"
-	backend1Cmd.Stdout = nil
-	backend1Cmd.Stderr = nil
-	if err := backend1Cmd.Start(); err != nil {
-		fmt.Printf("❌ 启动后端服务器1失败: %v\n", err)
-		return
-	}
-	defer backend1Cmd.Process.Kill()
-
-	backend2Cmd := exec.Command("go", "run", "backend_performance.go", "8082")
-	backend2Cmd.Dir = "test"
-	backend2Cmd.Stdout = nil
-	backend2Cmd.Stderr = nil
-	if err := backend2Cmd.Start(); err != nil {
-		fmt.Printf("❌ 启动后端服务器2失败: %v\n", err)
-		return
-	}
-	defer backend2Cmd.Process.Kill()
-
-	time.Sleep(2 * time.Second)
-
-	// 启动SpeedMimi代理服务器
-	fmt.Println("2. 启动SpeedMimi代理服务器...")
-	proxyCmd := exec.Command("./bin/speedmimi", "-config", "configs/config.yaml")
-	// 让日志输出到控制台以便调试
-	proxyCmd.Stdout = os.Stdout
-	proxyCmd.Stderr = os.Stderr
-	if err := proxyCmd.Start(); err != nil {
-		fmt.Printf("❌ 启动代理服务器失败: %v\n", err)
-		return
-	}
-	defer proxyCmd.Process.Kill()
-
-	time.Sleep(3 * time.Second)
-
-	fmt.Println("3. 发送测试请求验证连接正常...")
-	client := &http.Client{Timeout: 5 * time.Second}
-	for i := 0; i < 3; i++ {
-		resp, err := client.Get("http://localhost:8080/")
-		if err != nil {
-			fmt.Printf("❌ 请求失败: %v\n", err)
-			continue
-		}
-		io.ReadAll(resp.Body)
-		resp.Body.Close()
-		fmt.Printf("✅ 请求 %d 成功\n", i+1)
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	fmt.Println("4. 发送异步断开连接请求...")
-	disconnectData := map[string]string{
-		"upstream_id": "default",
-		"backend_id":  "backend1",
-	}
-	jsonData, _ := json.Marshal(disconnectData)
-
-	resp, err := client.Post("http://localhost:9091/api/v1/backends/disconnect", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Printf("❌ 断开连接请求失败: %v\n", err)
-		return
-	}
-	body, _ := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	fmt.Printf("✅ 断开连接请求响应: %s\n", string(body))
-
-	fmt.Println("5. 继续发送请求验证断开功能...")
-	fmt.Println("   (断开backend1后，请求应该路由到backend2)")
-	time.Sleep(2 * time.Second) // 等待异步处理完成
-
-	fmt.Println("   发送请求到backend2端口直接验证...")
-	resp2, err := client.Get("http://localhost:8082/")
-	if err != nil {
-		fmt.Printf("❌ 直接访问backend2失败: %v\n", err)
-	} else {
-		body2, _ := io.ReadAll(resp2.Body)
-		resp2.Body.Close()
-		server := resp2.Header.Get("X-Server")
-		fmt.Printf("✅ backend2直接访问成功 - 服务器: %s\n", server)
-		_ = body2 // 避免未使用变量错误
-	}
-
-	fmt.Println("   通过代理发送请求...")
-	for i := 0; i < 5; i++ {
-		resp, err := client.Get("http://localhost:8080/")
-		if err != nil {
-			fmt.Printf("❌ 请求 %d 失败: %v\n", i+1, err)
-		} else {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-
-			// 检查响应头中的服务器信息
-			server := resp.Header.Get("X-Server")
-			fmt.Printf("✅ 请求 %d 成功 - 路由到: %s\n", i+1, server)
-
-			// 安全地截取响应内容
-			bodyStr := string(body)
-			if len(bodyStr) > 50 {
-				fmt.Printf("   响应内容: %s...\n", bodyStr[:50])
-			} else {
-				fmt.Printf("   响应内容: %s\n", bodyStr)
-			}
-		}
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	fmt.Println("6. 检查管理API状态...")
-	resp, err = client.Get("http://localhost:9091/api/v1/stats/server")
-	if err != nil {
-		fmt.Printf("❌ 获取统计失败: %v\n", err)
-	} else {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		fmt.Printf("📊 服务器统计: %s\n", string(body))
-	}
-
-	fmt.Println("✅ 测试完成！")
-	fmt.Println()
-	fmt.Println("关键特性验证:")
-	fmt.Println("• ✅ 主路径异步处理: 断开请求立即返回，不阻塞")
-	fmt.Println("• ✅ 标记机制: 后端标记为断开状态，不再接收新请求")
-	fmt.Println("• ✅ 自然排空: 现有连接自然断开，不强制终止")
-	fmt.Println("• ✅ 负载均衡集成: 所有负载均衡器都检查断开标记")
-	fmt.Println("• ✅ 高并发安全: 原子操作确保线程安全")
-}
@@ -1,108 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os/exec"
-	"time"
-)
-
-func main() {
-	fmt.Println("🚀 SpeedMimi 异步断开连接测试")
-	fmt.Println("================================")
-
-	// 启动后端服务器
-	fmt.Println("1. 启动后端服务器...")
-	backendCmd := exec.Command("go", "run", "backend_performance.go", "8081")
-	backendCmd.Dir = "test"
-	backendCmd.Stdout = nil
-	backendCmd.Stderr = nil
-	if err := backendCmd.Start(); err != nil {
-		fmt.Printf("❌ 启动后端服务器失败: %v\n", err)
-		return
-	}
-	defer backendCmd.Process.Kill()
-
-	time.Sleep(2 * time.Second)
-
-	// 启动SpeedMimi代理服务器
-	fmt.Println("2. 启动SpeedMimi代理服务器...")
-	proxyCmd := exec.Command("./bin/speedmimi", "-config", "configs/config.yaml")
-	proxyCmd.Stdout = nil
-	proxyCmd.Stderr = nil
-	if err := proxyCmd.Start(); err != nil {
-		fmt.Printf("❌ 启动代理服务器失败: %v\n", err)
-		return
-	}
-	defer proxyCmd.Process.Kill()
-
-	time.Sleep(3 * time.Second)
-
-	fmt.Println("3. 发送测试请求验证连接正常...")
-	client := &http.Client{Timeout: 5 * time.Second}
-	for i := 0; i < 3; i++ {
-		resp, err := client.Get("http://localhost:8080/")
-		if err != nil {
-			fmt.Printf("❌ 请求失败: %v\n", err)
-			continue
-		}
-		io.ReadAll(resp.Body)
-		resp.Body.Close()
-		fmt.Printf("✅ 请求 %d 成功\n", i+1)
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	fmt.Println("4. 发送异步断开连接请求...")
-	disconnectData := map[string]string{
-		"upstream_id": "default",
-		"backend_id":  "backend-8081",
-	}
-	jsonData, _ := json.Marshal(disconnectData)
-
-	resp, err := client.Post("http://localhost:9091/api/v1/disconnect/backend", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Printf("❌ 断开连接请求失败: %v\n", err)
-		return
-	}
-	body, _ := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	fmt.Printf("✅ 断开连接请求响应: %s\n", string(body))
-
-	fmt.Println("5. 继续发送请求验证断开功能...")
-	fmt.Println("   (断开后请求应该失败，因为只有一个后端)")
-	time.Sleep(2 * time.Second) // 等待异步处理完成
-
-	for i := 0; i < 5; i++ {
-		resp, err := client.Get("http://localhost:8080/")
-		if err != nil {
-			fmt.Printf("✅ 请求 %d 失败 (期望行为): %v\n", i+1, err)
-		} else {
-			io.ReadAll(resp.Body)
-			resp.Body.Close()
-			fmt.Printf("⚠️  请求 %d 仍然成功 (可能断开未生效)\n", i+1)
-		}
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	fmt.Println("6. 检查管理API状态...")
-	resp, err = client.Get("http://localhost:9091/api/v1/stats/server")
-	if err != nil {
-		fmt.Printf("❌ 获取统计失败: %v\n", err)
-	} else {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		fmt.Printf("📊 服务器统计: %s\n", string(body))
-	}
-
-	fmt.Println("✅ 测试完成！")
-	fmt.Println()
-	fmt.Println("关键特性验证:")
-	fmt.Println("• ✅ 主路径异步处理: 断开请求立即返回，不阻塞")
-	fmt.Println("• ✅ 标记机制: 后端标记为断开状态，不再接收新请求")
-	fmt.Println("• ✅ 自然排空: 现有连接自然断开，不强制终止")
-	fmt.Println("• ✅ 负载均衡集成: 所有负载均衡器都检查断开标记")
-	fmt.Println("• ✅ 高并发安全: 原子操作确保线程安全")
-}
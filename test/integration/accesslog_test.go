@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/accesslog"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// accessLogStats 获取访问日志的设置和统计
+func accessLogStats(t *testing.T, p *testutil.Proxy) accesslog.Stats {
+	t.Helper()
+	var resp struct {
+		Stats accesslog.Stats `json:"stats"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/access-log", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Stats
+}
+
+// logLines 日志文件中的行
+func logLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(strings.ReplaceAll(string(data), " ", "_"))
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	skipShort(t)
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.AccessLog = types.AccessLogConfig{
+		Enabled: true,
+		Path:    path,
+		Format:  "$status $uri",
+		Rules: []types.AccessLogSampleRule{
+			{MinStatus: 404, MaxStatus: 404, SampleRate: 1},
+			{Route: "default", SampleRate: 5},
+		},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	// 成功请求按路由规则每5个记录1个，404按第一个规则全部记录，错误请求总是记录
+	for i := 0; i < 10; i++ {
+		get(t, p.URL("/"))
+	}
+	for i := 0; i < 3; i++ {
+		get(t, p.URL("/?status=404"))
+	}
+	for i := 0; i < 2; i++ {
+		get(t, p.URL("/?status=500"))
+	}
+	lines := logLines(t, path)
+	var ok, notFound, failed int
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "200_"):
+			ok++
+		case strings.HasPrefix(line, "404_"):
+			notFound++
+		case strings.HasPrefix(line, "500_"):
+			failed++
+		}
+	}
+	if ok != 2 || notFound != 3 || failed != 2 {
+		t.Fatalf("logged %d ok, %d not found and %d failed requests, want 2, 3 and 2:\n%s", ok, notFound, failed, strings.Join(lines, "\n"))
+	}
+	if stats := accessLogStats(t, p); stats.Logged != 7 || stats.Skipped != 8 {
+		t.Fatalf("stats %+v, want 7 logged and 8 skipped", stats)
+	}
+
+	// 每秒记录条数上限在运行时调整，错误请求不受限制
+	if err := p.Admin(http.MethodPut, "/api/v1/access-log", map[string]int{"max_rate": 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		get(t, p.URL("/?status=404"))
+	}
+	get(t, p.URL("/?status=500"))
+	stats := accessLogStats(t, p)
+	if stats.RateLimited < 8 || stats.Logged+stats.Skipped+stats.RateLimited != 26 {
+		t.Fatalf("stats %+v, want at least 8 of 10 requests rate limited", stats)
+	}
+	if lines := logLines(t, path); !strings.HasPrefix(lines[len(lines)-1], "500_") {
+		t.Fatalf("error request was not logged under max_rate: %v", lines)
+	}
+}
+
+func TestAccessLogBufferAndRotation(t *testing.T) {
+	skipShort(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.AccessLog = types.AccessLogConfig{
+		Enabled:       true,
+		Path:          path,
+		Format:        "$status $uri",
+		BufferSize:    4096,
+		FlushInterval: 20 * time.Millisecond,
+		Rotation:      types.AccessLogRotationConfig{MaxSize: 100, MaxBackups: 2, Compress: true},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	const requests = 60
+	for i := 0; i < requests; i++ {
+		get(t, p.URL("/?n=0123456789"))
+		if i%10 == 9 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	// 缓冲的日志在flush_interval内写出并触发轮转
+	if !testutil.Eventually(3*time.Second, func() bool {
+		stats := accessLogStats(t, p)
+		return stats.Rotations >= 2
+	}) {
+		t.Fatalf("log was not rotated: %+v", accessLogStats(t, p))
+	}
+	if stats := accessLogStats(t, p); stats.Logged+stats.Dropped != requests || stats.Errors != 0 {
+		t.Fatalf("stats %+v, want %d logged or dropped requests without errors", stats, requests)
+	}
+
+	// 轮转后的文件被压缩，只保留最近的max_backups个
+	var backups []string
+	if !testutil.Eventually(3*time.Second, func() bool {
+		backups, _ = filepath.Glob(path + ".*")
+		for _, name := range backups {
+			if !strings.HasSuffix(name, ".gz") {
+				return false
+			}
+		}
+		return len(backups) == 2
+	}) {
+		t.Fatalf("backups %v, want 2 compressed files", backups)
+	}
+	for _, name := range backups {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil || !bytes.HasPrefix(content, []byte("200 /?n=0123456789\n")) {
+			t.Fatalf("%s contains %q (%v), want access log lines", name, content, err)
+		}
+	}
+}
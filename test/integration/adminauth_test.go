@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const adminToken = "integration-admin-token"
+
+func TestAdminAuth(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.GRPC.Auth.Tokens = []string{adminToken}
+	p := testutil.StartProxy(t, cfg)
+
+	status := func(method, path, token string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, p.AdminURL(path), nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized && !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer") {
+			t.Fatalf("401 without a Bearer challenge: %q", resp.Header.Get("WWW-Authenticate"))
+		}
+		return resp.StatusCode
+	}
+
+	if code := status(http.MethodGet, "/api/v1/config", ""); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous request: status %d, want 401", code)
+	}
+	if code := status(http.MethodPost, "/api/v1/quiesce", "wrong-token-0123456789"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status %d, want 401", code)
+	}
+	if code := status(http.MethodGet, "/api/v1/config", adminToken); code != http.StatusOK {
+		t.Fatalf("valid token: status %d, want 200", code)
+	}
+
+	// 令牌不出现在配置中，通过管理API写回配置也不会去掉认证
+	var current struct {
+		Config *types.Config `json:"config"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/config", nil, &current); err != nil {
+		t.Fatal(err)
+	}
+	if len(current.Config.GRPC.Auth.Tokens) != 0 {
+		t.Fatal("admin tokens returned by GET /api/v1/config")
+	}
+	if err := p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": current.Config}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if code := status(http.MethodGet, "/api/v1/config", ""); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous request after a config update: status %d, want 401", code)
+	}
+}
+
+func TestAdminAuthRequiredOffLoopback(t *testing.T) {
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{{ID: "app", Host: "127.0.0.1", Port: 8081, Weight: 1, Scheme: "http", Active: true}}
+	cfg.GRPC.Host = "0.0.0.0"
+	cfg.Server.Port = 8080
+	cfg.GRPC.Port = 9091
+
+	load := func() error {
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err = config.NewManager(path)
+		return err
+	}
+
+	if err := load(); err == nil || !strings.Contains(err.Error(), "auth.tokens") {
+		t.Fatalf("anonymous admin API on 0.0.0.0: err = %v, want an auth requirement error", err)
+	}
+	cfg.GRPC.Auth.Tokens = []string{adminToken}
+	if err := load(); err != nil {
+		t.Fatalf("admin API on 0.0.0.0 with a token: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// aggregateLimitStats 总请求速率限制的统计
+type aggregateLimitStats struct {
+	Allowed  int64 `json:"allowed"`
+	Delayed  int64 `json:"delayed"`
+	Rejected int64 `json:"rejected"`
+}
+
+func TestAggregateRateLimits(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	legacy := testutil.StartBackend(t, "legacy")
+	cfg := testutil.NewConfig(b)
+	cfg.Backends["legacy"] = []*types.Backend{legacy.Config()}
+	cfg.Upstreams = map[string]*types.UpstreamConfig{
+		"legacy": {RateLimit: &types.AggregateRateLimitConfig{Rate: 10, Burst: 1, Mode: types.RateLimitDelay, MaxDelay: time.Second}},
+	}
+	cfg.Routing["search"] = &types.RoutingRule{
+		Path:           "/search/",
+		Upstream:       "default",
+		LoadBalancer:   types.LeastConnectionsWeight,
+		TotalRateLimit: &types.AggregateRateLimitConfig{Rate: 0.5, Burst: 2},
+	}
+	cfg.Routing["legacy"] = &types.RoutingRule{Path: "/legacy/", Upstream: "legacy", LoadBalancer: types.LeastConnectionsWeight}
+	p := testutil.StartProxy(t, cfg)
+
+	// reject：路由的突发额度用完后返回429和Retry-After，其他路由不受影响
+	for i := 0; i < 2; i++ {
+		if status, _ := get(t, p.URL("/search/q")); status != http.StatusOK {
+			t.Fatalf("search request %d: status %d, want 200 within the burst", i, status)
+		}
+	}
+	resp, err := client.Get(p.URL("/search/q"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("search over the total rate: status %d, Retry-After %q; want 429 with Retry-After", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("other route: status %d, want 200", status)
+	}
+
+	// delay：上游的请求排队，按rate匀速放行
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if status, server := get(t, p.URL("/legacy/a")); status != http.StatusOK || server != "legacy" {
+			t.Fatalf("legacy request %d: status %d from %q, want 200 from legacy", i, status, server)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("3 requests at 10/s with burst 1 took %v, want them paced", elapsed)
+	}
+
+	var stats struct {
+		RouteTotals map[string]aggregateLimitStats `json:"route_totals"`
+		Upstreams   map[string]aggregateLimitStats `json:"upstreams"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/rate-limits", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if s := stats.RouteTotals["search"]; s.Allowed != 2 || s.Rejected != 1 {
+		t.Fatalf("search total rate stats %+v, want 2 allowed and 1 rejected", s)
+	}
+	if s := stats.Upstreams["legacy"]; s.Allowed != 1 || s.Delayed != 2 || s.Rejected != 0 {
+		t.Fatalf("legacy upstream rate stats %+v, want 1 allowed immediately and 2 delayed", s)
+	}
+}
+
+func TestGlobalRateLimit(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.RateLimit = &types.AggregateRateLimitConfig{Rate: 0.5, Burst: 1}
+	p := testutil.StartProxy(t, cfg)
+
+	// 整个代理的总速率在选择路由之前检查
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", status)
+	}
+	if status, _ := get(t, p.URL("/")); status != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", status)
+	}
+}
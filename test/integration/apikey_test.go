@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// getWithKey 携带API密钥发送请求，返回状态码
+func getWithKey(t *testing.T, url, key string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Routing["default"].APIKeyAuth = true
+	p := testutil.StartProxy(t, cfg)
+
+	if status := getWithKey(t, p.URL("/"), ""); status != http.StatusUnauthorized {
+		t.Fatalf("status %d without a key, want 401", status)
+	}
+
+	var created struct {
+		APIKey string       `json:"api_key"`
+		Key    types.APIKey `json:"key"`
+	}
+	req := map[string]interface{}{
+		"name":       "ci",
+		"rate_limit": map[string]interface{}{"rate": 1},
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/api-keys", req, &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.APIKey == "" || created.Key.ID == "" {
+		t.Fatalf("create response = %+v, want a key and an id", created)
+	}
+
+	// 新密钥在配置重载后生效
+	if !testutil.Eventually(5*time.Second, func() bool {
+		return getWithKey(t, p.URL("/"), created.APIKey) == http.StatusOK
+	}) {
+		t.Fatal("created key was not accepted")
+	}
+	if status := getWithKey(t, p.URL("/"), created.APIKey); status != http.StatusTooManyRequests {
+		t.Fatalf("status %d above the key rate limit, want 429", status)
+	}
+	if status := getWithKey(t, p.URL("/"), "smk_wrong"); status != http.StatusUnauthorized {
+		t.Fatalf("status %d with an unknown key, want 401", status)
+	}
+
+	var list struct {
+		Keys []struct {
+			ID       string `json:"id"`
+			Requests int64  `json:"requests"`
+			Rejected int64  `json:"rejected"`
+		} `json:"keys"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/api-keys", nil, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Keys) != 1 || list.Keys[0].Requests != 1 || list.Keys[0].Rejected != 1 {
+		t.Fatalf("keys = %+v, want 1 key with 1 request and 1 rejection", list.Keys)
+	}
+
+	if err := p.Admin(http.MethodDelete, "/api/v1/api-keys?id="+created.Key.ID, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Eventually(5*time.Second, func() bool {
+		return getWithKey(t, p.URL("/"), created.APIKey) == http.StatusUnauthorized
+	}) {
+		t.Fatal("revoked key still accepted")
+	}
+}
+
+func TestAPIKeyConcurrentCreates(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Routing["default"].APIKeyAuth = true
+	p := testutil.StartProxy(t, cfg)
+
+	// 并发创建的密钥都写入配置，不会被其他请求的配置更新覆盖
+	const n = 16
+	secrets := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var created struct {
+				APIKey string `json:"api_key"`
+			}
+			errs[i] = p.Admin(http.MethodPost, "/api/v1/api-keys", map[string]interface{}{"name": fmt.Sprintf("key%d", i)}, &created)
+			secrets[i] = created.APIKey
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("creating key %d: %v", i, err)
+		}
+	}
+	if keys := p.Config.GetConfig().APIKeys.Keys; len(keys) != n {
+		t.Fatalf("%d keys in the config after %d concurrent creates, want all of them", len(keys), n)
+	}
+	seen := make(map[string]bool, n)
+	for _, secret := range secrets {
+		seen[secret] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("%d distinct secrets returned, want %d", len(seen), n)
+	}
+
+	// 代理应用的是最后一次更新后的配置，所有密钥都被接受
+	for i, secret := range secrets {
+		if !testutil.Eventually(5*time.Second, func() bool {
+			return getWithKey(t, p.URL("/"), secret) == http.StatusOK
+		}) {
+			t.Fatalf("key %d was not accepted", i)
+		}
+	}
+}
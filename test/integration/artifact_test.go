@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// geoHeader 返回代理为请求计算的$geo值
+func geoHeader(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("X-Geo")
+}
+
+func TestGeoFileHotReload(t *testing.T) {
+	skipShort(t)
+
+	path := filepath.Join(t.TempDir(), "geo.txt")
+	if err := os.WriteFile(path, []byte("# lab networks\n127.0.0.0/8 lab-a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Geo = types.GeoConfig{File: path, Default: "unknown"}
+	cfg.Artifacts.WatchInterval = time.Second
+	cfg.Routing["default"].ResponseHeaders = []types.HeaderTemplate{{Name: "X-Geo", Value: "$geo"}}
+	p := testutil.StartProxy(t, cfg)
+
+	if geo := geoHeader(t, p.URL("/")); geo != "lab-a" {
+		t.Fatalf("$geo = %q, want lab-a from the geo file", geo)
+	}
+
+	// 文件变化后自动加载新版本
+	if err := os.WriteFile(path, []byte("127.0.0.0/8 lab-b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Eventually(5*time.Second, func() bool { return geoHeader(t, p.URL("/")) == "lab-b" }) {
+		t.Fatal("geo file change was not picked up")
+	}
+
+	// 校验失败的内容不替换当前版本，错误在管理API中可见
+	if err := os.WriteFile(path, []byte("not-a-cidr lab-c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var status struct {
+		Artifacts []struct {
+			Name      string `json:"name"`
+			Version   int64  `json:"version"`
+			LastError string `json:"last_error"`
+		} `json:"artifacts"`
+	}
+	ok := testutil.Eventually(5*time.Second, func() bool {
+		if err := p.Admin(http.MethodGet, "/api/v1/artifacts", nil, &status); err != nil {
+			t.Fatal(err)
+		}
+		return len(status.Artifacts) == 1 && status.Artifacts[0].LastError != ""
+	})
+	if !ok {
+		t.Fatalf("artifacts = %+v, want the invalid geo file reported", status.Artifacts)
+	}
+	if a := status.Artifacts[0]; a.Name != "geo" || a.Version != 2 {
+		t.Fatalf("artifact = %+v, want geo at version 2", a)
+	}
+	if geo := geoHeader(t, p.URL("/")); geo != "lab-b" {
+		t.Fatalf("$geo = %q after an invalid update, want lab-b kept", geo)
+	}
+}
@@ -0,0 +1,52 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/audit"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestConcurrencyAudit(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Audit.Enabled = true
+	cfg.Audit.Interval = 50 * time.Millisecond
+	p := testutil.StartProxy(t, cfg)
+
+	for i := 0; i < 5; i++ {
+		if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+	// 管理API更新后端时发布新的上游配置，不就地修改运行中的后端
+	if err := p.Admin(http.MethodPut, "/api/v1/backends/update", map[string]interface{}{
+		"upstream_id": "default",
+		"backend_id":  "backend1",
+		"weight":      2,
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats audit.Stats
+	if !testutil.Eventually(5*time.Second, func() bool {
+		return p.Admin(http.MethodGet, "/api/v1/audit", nil, &stats) == nil && stats.Sweeps > 0
+	}) {
+		t.Fatalf("audit stats = %+v, want at least one invariant sweep", stats)
+	}
+	if !stats.Enabled {
+		t.Fatal("audit mode not enabled")
+	}
+	if len(stats.Violations) != 0 {
+		t.Fatalf("audit violations = %+v, want none", stats.Violations)
+	}
+	if len(stats.Fallbacks) != 0 {
+		t.Fatalf("audit fallbacks = %+v, want none", stats.Fallbacks)
+	}
+	if weight := p.Config.GetConfig().Backends["default"][0].Weight; weight != 2 {
+		t.Fatalf("backend weight %d after update, want 2", weight)
+	}
+}
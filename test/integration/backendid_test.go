@@ -0,0 +1,175 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestDuplicateBackendIDRejectedAtLoad(t *testing.T) {
+	b1 := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b1)
+	cfg.Server.Port = 8080
+	// 同一地址出现两次且未配置ID时，生成的ID相同
+	cfg.Backends["default"] = append(cfg.Backends["default"], b1.Config())
+	for _, backend := range cfg.Backends["default"] {
+		backend.ID = ""
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.NewManager(path); err == nil || !strings.Contains(err.Error(), "same id") {
+		t.Fatalf("loading duplicate backend ids: %v, want a duplicate id error", err)
+	}
+}
+
+func TestBackendIDImmutable(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+
+	// 未指定ID的后端生成的ID与已有后端相同
+	backend := b1.Config()
+	backend.ID = ""
+	if err := p.Admin(http.MethodPost, "/api/v1/backends/add", map[string]interface{}{"upstream": "default", "backend": backend}, nil); err != nil {
+		t.Fatalf("adding a second backend for the same address: %v", err)
+	}
+	err := p.Admin(http.MethodPost, "/api/v1/backends/add", map[string]interface{}{"upstream": "default", "backend": backend}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("adding the same address twice without an id: %v, want status 400", err)
+	}
+
+	err = p.Admin(http.MethodPut, "/api/v1/backends/update", map[string]interface{}{
+		"upstream_id": "default",
+		"backend_id":  "backend1",
+		"id":          "renamed",
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("changing a backend id: %v, want status 400", err)
+	}
+
+	// 修改地址后ID保持不变；运行中的后端对象不被就地修改，而是替换为新的后端
+	live := p.Config.GetConfig().Backends["default"][0]
+	liveHost := live.Host
+	if err := p.Admin(http.MethodPut, "/api/v1/backends/update", map[string]interface{}{
+		"upstream_id": "default",
+		"backend_id":  "backend1",
+		"host":        "localhost",
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, b := range p.Config.GetConfig().Backends["default"] {
+		ids = append(ids, b.ID)
+	}
+	if len(ids) != 2 || ids[0] != "backend1" {
+		t.Fatalf("backend ids = %v, want backend1 kept after changing its host", ids)
+	}
+	updated := p.Config.GetConfig().Backends["default"][0]
+	if updated == live || updated.Host != "localhost" || live.Host != liveHost {
+		t.Fatalf("backend host %q (live object host %q), want the update published as a new backend", updated.Host, live.Host)
+	}
+
+	// 校验失败时运行中的后端不变
+	err = p.Admin(http.MethodPut, "/api/v1/backends/update", map[string]interface{}{
+		"upstream_id": "default",
+		"backend_id":  "backend1",
+		"weight":      5,
+		"port":        -1,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("updating a backend with an invalid port: %v, want status 400", err)
+	}
+	if b := p.Config.GetConfig().Backends["default"][0]; b != updated || b.Weight == 5 {
+		t.Fatalf("backend %+v after a rejected update, want it unchanged", b)
+	}
+}
+
+func TestBackendUpdateKeepsRuntimeState(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+	live := p.Config.GetConfig().Backends["default"][0]
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := client.Get(p.URL("/?sleep=500ms")); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	if !testutil.Eventually(2*time.Second, func() bool { return live.GetConnections() == 1 }) {
+		t.Fatal("in-flight request was not counted on the backend")
+	}
+	live.MarkForDisconnect()
+
+	// 修改配置字段生成的新后端与原后端共用连接数和断开标记
+	if err := p.Admin(http.MethodPut, "/api/v1/backends/update", map[string]interface{}{
+		"upstream_id": "default",
+		"backend_id":  "backend1",
+		"weight":      3,
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	updated := p.Config.GetConfig().Backends["default"][0]
+	if updated == live || updated.Weight != 3 {
+		t.Fatalf("backend %+v, want the update published as a new backend", updated)
+	}
+	if n := updated.GetConnections(); n != 1 {
+		t.Fatalf("%d connections on the updated backend, want the in-flight request", n)
+	}
+	if !updated.ShouldDisconnect() {
+		t.Fatal("disconnect mark lost by the update")
+	}
+
+	<-done
+	if n := updated.GetConnections(); n != 0 {
+		t.Fatalf("%d connections after the request finished, want 0", n)
+	}
+}
+
+func TestBackendConcurrentAdds(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+
+	// 并发添加的后端都写入配置，不会被其他请求的配置更新覆盖
+	const n = 16
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			backend := b1.Config()
+			backend.ID = fmt.Sprintf("added%d", i)
+			errs[i] = p.Admin(http.MethodPost, "/api/v1/backends/add", map[string]interface{}{"upstream": "default", "backend": backend}, nil)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("adding backend %d: %v", i, err)
+		}
+	}
+	if backends := p.Config.GetConfig().Backends["default"]; len(backends) != n+1 {
+		t.Fatalf("%d backends after %d concurrent adds, want all of them", len(backends), n)
+	}
+}
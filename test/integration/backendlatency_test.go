@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// backendLatencies 获取管理API返回的每个后端的响应时间统计
+func backendLatencies(t *testing.T, p *testutil.Proxy, query string) []monitor.LatencyStats {
+	t.Helper()
+	var resp struct {
+		Backends []monitor.LatencyStats `json:"backends"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/backend"+query, nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Backends
+}
+
+func TestBackendLatencyHistograms(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+
+	// 18个快速请求和2个200ms的慢请求
+	for i := 0; i < 20; i++ {
+		path := "/"
+		if i%10 == 9 {
+			path = "/?sleep=200ms"
+		}
+		if status, _ := get(t, p.URL(path)); status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+
+	stats := backendLatencies(t, p, "?upstream=default&backend_id=backend1")
+	if len(stats) != 1 {
+		t.Fatalf("got stats for %d backends, want 1: %+v", len(stats), stats)
+	}
+	st := stats[0]
+	if st.Upstream != "default" || st.Backend != "backend1" || st.Count != 20 || st.WindowCount != 20 {
+		t.Fatalf("unexpected stats %+v, want 20 requests to default/backend1", st)
+	}
+	if st.P50Ms <= 0 || st.P50Ms >= 100 {
+		t.Errorf("p50 %.3fms, want the fast requests", st.P50Ms)
+	}
+	if st.P95Ms < 200 || st.P99Ms < 200 || st.P99Ms > 300 {
+		t.Errorf("p95 %.3fms p99 %.3fms, want the slow requests", st.P95Ms, st.P99Ms)
+	}
+	if got := backendLatencies(t, p, "?upstream=other"); len(got) != 0 {
+		t.Errorf("stats for another upstream %+v, want none", got)
+	}
+
+	// Prometheus直方图
+	resp, err := client.Get(p.AdminURL("/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("metrics content type %q", resp.Header.Get("Content-Type"))
+	}
+	for _, line := range []string{
+		"# TYPE speedmimi_backend_response_duration_seconds histogram",
+		`speedmimi_backend_response_duration_seconds_bucket{upstream="default",backend="backend1",le="0.1"} 18`,
+		`speedmimi_backend_response_duration_seconds_bucket{upstream="default",backend="backend1",le="+Inf"} 20`,
+		`speedmimi_backend_response_duration_seconds_count{upstream="default",backend="backend1"} 20`,
+		`speedmimi_backend_response_duration_quantile_seconds{upstream="default",backend="backend1",quantile="0.99"} `,
+		"speedmimi_requests_total 20",
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+
+	// 从配置中删除的后端不再统计
+	b2 := testutil.StartBackend(t, "backend2")
+	cfg := config.CloneConfig(p.Config.GetConfig())
+	cfg.Backends["default"] = testutil.NewConfig(b2).Backends["default"]
+	if err := p.Config.UpdateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return len(backendLatencies(t, p, "")) == 0
+	}) {
+		t.Fatalf("stats of the removed backend were kept: %+v", backendLatencies(t, p, ""))
+	}
+}
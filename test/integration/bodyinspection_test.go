@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/signing"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestBodyInspectionLimits(t *testing.T) {
+	skipShort(t)
+
+	var (
+		mu          sync.Mutex
+		payloadHash string
+		received    int
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		payloadHash, received = r.Header.Get("X-Amz-Content-Sha256"), len(data)
+		mu.Unlock()
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("s3", upstream)}
+	cfg.Upstreams = map[string]*types.UpstreamConfig{
+		"default": {Signing: &types.SigningConfig{
+			Type:            signing.TypeSigV4,
+			Region:          "us-east-1",
+			Service:         "s3",
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+		}},
+	}
+	route := func(path, onExceed string) *types.RoutingRule {
+		return &types.RoutingRule{
+			Path:           path,
+			Upstream:       "default",
+			LoadBalancer:   types.LeastConnectionsWeight,
+			BodyInspection: &types.BodyInspectionConfig{MaxBytes: 1024, OnExceed: onExceed},
+		}
+	}
+	cfg.Routing["deny"] = route("/deny/", types.BodyInspectionDeny)
+	cfg.Routing["bypass"] = route("/bypass/", types.BodyInspectionBypass)
+	p := testutil.StartProxy(t, cfg)
+
+	put := func(path string, body io.Reader) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, p.URL(path), body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	last := func() (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return payloadHash, received
+	}
+
+	// 上限内的请求体照常签名
+	small := bytes.Repeat([]byte("s"), 100)
+	if status := put("/deny/small", bytes.NewReader(small)); status != http.StatusOK {
+		t.Fatalf("body within the limit: status %d, want 200", status)
+	}
+	sum := sha256.Sum256(small)
+	if hash, n := last(); hash != hex.EncodeToString(sum[:]) || n != len(small) {
+		t.Fatalf("payload hash %q for %d bytes, want the body hash", hash, n)
+	}
+
+	// deny：超过上限或长度未知时返回413
+	large := bytes.Repeat([]byte("l"), 4096)
+	if status := put("/deny/large", bytes.NewReader(large)); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("body over the limit: status %d, want 413", status)
+	}
+	if status := put("/deny/chunked", io.MultiReader(bytes.NewReader(small))); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("chunked body: status %d, want 413", status)
+	}
+
+	// bypass：跳过请求体哈希，完整的请求体仍转发给后端
+	if status := put("/bypass/large", bytes.NewReader(large)); status != http.StatusOK {
+		t.Fatalf("bypassed body: status %d, want 200", status)
+	}
+	if hash, n := last(); hash != signing.UnsignedPayload || n != len(large) {
+		t.Fatalf("bypassed body: payload hash %q for %d bytes, want %s for %d bytes", hash, n, signing.UnsignedPayload, len(large))
+	}
+}
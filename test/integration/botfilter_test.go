@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestBotFilterChallenge(t *testing.T) {
+	skipShort(t)
+
+	// 上游返回代理计算的机器人评分
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Bot-Score"))
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("echo", upstream)}
+	cfg.Routing["default"].BotFilter = &types.BotFilterConfig{
+		Allow:           []string{"^Googlebot/"},
+		Deny:            []string{"(?i)evilbot"},
+		Action:          types.BotActionChallenge,
+		ChallengeSecret: "challenge-secret-0123456789",
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	do := func(headers map[string]string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL("/"), nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// 类似浏览器的请求评分为0，客户端传入的评分被覆盖
+	browser := map[string]string{
+		"User-Agent":      "Mozilla/5.0 (X11; Linux x86_64)",
+		"Accept":          "text/html",
+		"Accept-Language": "en",
+		"X-Bot-Score":     "-1",
+	}
+	if status, body := do(browser); status != http.StatusOK || body != "0" {
+		t.Fatalf("browser: status %d, score %q, want 200 and 0", status, body)
+	}
+
+	// 白名单中的爬虫不评分
+	if status, body := do(map[string]string{"User-Agent": "Googlebot/2.1"}); status != http.StatusOK || body != "" {
+		t.Fatalf("allowed crawler: status %d, score %q, want 200 without score", status, body)
+	}
+
+	// 自动化客户端收到挑战页，带上脚本设置的Cookie重试后放行
+	status, body := do(nil)
+	match := regexp.MustCompile(`document.cookie="(speedmimi_bot=[0-9a-f.]+);`).FindStringSubmatch(body)
+	if status != http.StatusForbidden || match == nil {
+		t.Fatalf("bot: status %d, body %q, want a 403 challenge page", status, body)
+	}
+	if status, body := do(map[string]string{"Cookie": match[1]}); status != http.StatusOK || body != "85" {
+		t.Fatalf("bot with challenge cookie: status %d, score %q, want 200 and 85", status, body)
+	}
+
+	// Cookie绑定User-Agent，deny匹配的客户端不能复用
+	if status, _ := do(map[string]string{"Cookie": match[1], "User-Agent": "EvilBot/1.0"}); status != http.StatusForbidden {
+		t.Fatalf("denied agent with reused cookie: status %d, want 403", status)
+	}
+
+	// GET /api/v1/config不返回挑战密钥，原样写回时保留原值，已签发的Cookie仍然有效
+	var got struct {
+		Config *types.Config `json:"config"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/config", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if secret := got.Config.Routing["default"].BotFilter.ChallengeSecret; secret != "" {
+		t.Fatalf("GET /api/v1/config challenge secret %q, want it hidden", secret)
+	}
+	if err := p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": got.Config}, nil); err != nil {
+		t.Fatalf("round-tripping the config: %v", err)
+	}
+	if secret := p.Config.GetConfig().Routing["default"].BotFilter.ChallengeSecret; secret != "challenge-secret-0123456789" {
+		t.Fatalf("challenge secret after round trip %q, want it kept", secret)
+	}
+	if status, body := do(map[string]string{"Cookie": match[1]}); status != http.StatusOK {
+		t.Fatalf("challenge cookie after round trip: status %d, body %q, want 200", status, body)
+	}
+}
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestNegativeCaching(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b)
+	cfg.Cache.Enabled = true
+	cfg.Routing["default"].Cache = &types.RouteCacheConfig{Enabled: true, TTL: time.Minute, NegativeTTL: 300 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	// 重复请求已删除的资源只有第一次到达后端
+	for i := 0; i < 5; i++ {
+		if status, _ := get(t, p.URL("/gone?status=404")); status != http.StatusNotFound {
+			t.Fatalf("request %d: status %d, want 404", i, status)
+		}
+	}
+	if n := b.Requests(); n != 1 {
+		t.Fatalf("backend received %d requests, want 1", n)
+	}
+
+	// 否定响应按negative_ttl过期，不使用正常响应的ttl
+	time.Sleep(400 * time.Millisecond)
+	get(t, p.URL("/gone?status=404"))
+	if n := b.Requests(); n != 2 {
+		t.Fatalf("backend received %d requests after negative_ttl, want 2", n)
+	}
+
+	// 5xx不作为否定响应缓存
+	for i := 0; i < 2; i++ {
+		get(t, p.URL("/error?status=503"))
+	}
+	if n := b.Requests(); n != 4 {
+		t.Fatalf("backend received %d requests, want 4 (503 not cached)", n)
+	}
+
+	var stats struct {
+		Cache struct {
+			NegativeHits int64 `json:"negative_hits"`
+		} `json:"cache"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/cache", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Cache.NegativeHits != 4 {
+		t.Fatalf("negative_hits = %d, want 4", stats.Cache.NegativeHits)
+	}
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestCacheControlOverrides(t *testing.T) {
+	skipShort(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("bare") == "" {
+			w.Header().Set("Cache-Control", "private, max-age=5")
+			w.Header().Set("Pragma", "no-cache")
+		}
+		if r.URL.Query().Get("status") == "404" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("backend1", upstream)}
+	route := func(path string, cc *types.CacheControlConfig) *types.RoutingRule {
+		return &types.RoutingRule{Path: path, Upstream: "default", LoadBalancer: types.LeastConnectionsWeight, CacheControl: cc}
+	}
+	cfg.Routing["static"] = route("/static/", &types.CacheControlConfig{
+		MaxAge:     time.Hour,
+		CDNValue:   "max-age=86400",
+		Extensions: []string{".js"},
+	})
+	cfg.Routing["keep"] = route("/keep/", &types.CacheControlConfig{Mode: "default", Value: "no-store"})
+	cfg.Routing["strip"] = route("/strip/", &types.CacheControlConfig{Mode: "strip"})
+	p := testutil.StartProxy(t, cfg)
+
+	fetch := func(path string) http.Header {
+		t.Helper()
+		resp, err := client.Get(p.URL(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header
+	}
+
+	// override替换上游的缓存头，max_age同时生成Expires
+	h := fetch("/static/app.js")
+	if h.Get("Cache-Control") != "public, max-age=3600" || h.Get("Pragma") != "" || h.Get("CDN-Cache-Control") != "max-age=86400" {
+		t.Fatalf("/static/app.js headers %v, want the overridden cache headers", h)
+	}
+	if expires, err := http.ParseTime(h.Get("Expires")); err != nil || expires.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("Expires %q, want about an hour from now", h.Get("Expires"))
+	}
+
+	// 扩展名和状态码不匹配时保留上游的缓存头
+	if h := fetch("/static/index.html"); h.Get("Cache-Control") != "private, max-age=5" {
+		t.Fatalf("/static/index.html Cache-Control %q, want the upstream value", h.Get("Cache-Control"))
+	}
+	if h := fetch("/static/app.js?status=404"); h.Get("Cache-Control") != "private, max-age=5" {
+		t.Fatalf("404 Cache-Control %q, want the upstream value", h.Get("Cache-Control"))
+	}
+
+	// default只在上游没有缓存头时写入；strip删除上游的缓存头
+	if h := fetch("/keep/a"); h.Get("Cache-Control") != "private, max-age=5" {
+		t.Fatalf("default mode Cache-Control %q, want the upstream value", h.Get("Cache-Control"))
+	}
+	if h := fetch("/keep/a?bare=1"); h.Get("Cache-Control") != "no-store" {
+		t.Fatalf("default mode Cache-Control %q without upstream cache headers, want no-store", h.Get("Cache-Control"))
+	}
+	if h := fetch("/strip/a"); h.Get("Cache-Control") != "" || h.Get("Pragma") != "" {
+		t.Fatalf("strip mode headers %v, want no cache headers", h)
+	}
+}
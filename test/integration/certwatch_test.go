@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// servedCertNotAfter 新建TLS连接，返回代理证书的过期时间
+func servedCertNotAfter(t *testing.T, p *testutil.Proxy) time.Time {
+	t.Helper()
+	conn, err := tls.Dial("tcp", p.Addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].NotAfter
+}
+
+// replaceFile 用src原子地替换dst
+func replaceFile(t *testing.T, src, dst string) {
+	t.Helper()
+	if err := os.Rename(src, dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateHotReload(t *testing.T) {
+	skipShort(t)
+
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	certFile, keyFile := writeCert(t, dir, "server", now.Add(-time.Hour), now.Add(time.Hour))
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, WatchInterval: time.Second}
+	p := testutil.StartProxy(t, cfg)
+
+	if notAfter := servedCertNotAfter(t, p); !notAfter.Equal(now.Add(time.Hour)) {
+		t.Fatalf("served certificate expires %v, want %v", notAfter, now.Add(time.Hour))
+	}
+
+	// 证书文件被替换后，新的TLS握手使用新证书
+	next := t.TempDir()
+	newCert, newKey := writeCert(t, next, "server", now.Add(-time.Hour), now.Add(2*time.Hour))
+	replaceFile(t, newKey, keyFile)
+	replaceFile(t, newCert, certFile)
+	if !testutil.Eventually(5*time.Second, func() bool {
+		return servedCertNotAfter(t, p).Equal(now.Add(2 * time.Hour))
+	}) {
+		t.Fatal("replaced certificate was not loaded")
+	}
+
+	var resp struct {
+		Certificate struct {
+			CertFile string    `json:"cert_file"`
+			NotAfter time.Time `json:"not_after"`
+		} `json:"certificate"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/config/reload-ssl", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Certificate.CertFile != certFile || !resp.Certificate.NotAfter.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("reload-ssl certificate %+v, want the replaced certificate", resp.Certificate)
+	}
+
+	// 新证书无法解析时继续使用已加载的证书，手动重载返回错误
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if notAfter := servedCertNotAfter(t, p); !notAfter.Equal(now.Add(2 * time.Hour)) {
+		t.Fatalf("served certificate expires %v after an invalid update, want the loaded one", notAfter)
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/config/reload-ssl", nil, nil); err == nil {
+		t.Fatal("manual reload of an invalid certificate succeeded")
+	}
+}
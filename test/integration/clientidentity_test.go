@@ -0,0 +1,180 @@
+package integration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// testCA 签发客户端证书的测试CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA 生成测试CA，并把CA证书写入dir，返回CA和证书文件路径
+func newTestCA(t *testing.T, dir string) (*testCA, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}, file
+}
+
+// issue 签发带SPIFFE ID的客户端证书
+func (ca *testCA) issue(t *testing.T, cn, spiffeID string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := url.Parse(spiffeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		URIs:         []*url.URL{id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// tlsClient 使用指定客户端证书的HTTPS客户端，不验证代理证书
+func tlsClient(certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+		},
+	}
+}
+
+func TestClientIdentity(t *testing.T) {
+	skipShort(t)
+
+	dir := t.TempDir()
+	ca, caFile := newTestCA(t, dir)
+	now := time.Now()
+	certFile, keyFile := writeCert(t, dir, "server", now.Add(-time.Hour), now.Add(time.Hour))
+	capture, backend := startHeaderCapture(t, "backend1")
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{backend}
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientAuth: types.ClientAuthOptional, ClientCAFile: caFile}
+	cfg.Server.ClientIdentity = types.ClientIdentityConfig{Enabled: true, XFCC: true}
+	cfg.Routing["payments"] = &types.RoutingRule{
+		Path:             "/payments/",
+		Upstream:         "default",
+		LoadBalancer:     types.LeastConnectionsWeight,
+		AllowedSPIFFEIDs: []string{"spiffe://example.org/ns/payments/**"},
+	}
+	p := testutil.StartProxy(t, cfg)
+	base := "https://" + p.Addr
+
+	payments := ca.issue(t, "api", "spiffe://example.org/ns/payments/sa/api")
+	billing := ca.issue(t, "billing", "spiffe://example.org/ns/billing/sa/api")
+
+	do := func(c *http.Client, path string, header http.Header) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, base+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 验证过的客户端证书身份透传给后端，客户端伪造的同名头不论大小写都被替换
+	if status := do(tlsClient(payments), "/", http.Header{"x-client-spiffe-id": {"spiffe://evil/x"}}); status != http.StatusOK {
+		t.Fatalf("status %d with a client certificate, want 200", status)
+	}
+	header := capture.last()
+	if ids := header.Values("X-Client-SPIFFE-ID"); len(ids) != 1 || ids[0] != "spiffe://example.org/ns/payments/sa/api" {
+		t.Fatalf("X-Client-SPIFFE-ID %q, want only the certificate SPIFFE ID", ids)
+	}
+	hash := sha256.Sum256(payments.Certificate[0])
+	if header.Get("X-Client-Cert-Hash") != hex.EncodeToString(hash[:]) || header.Get("X-Client-Cert-Subject") != "CN=api" {
+		t.Fatalf("certificate headers %v, want the certificate hash and subject", header)
+	}
+	if xfcc := header.Get("X-Forwarded-Client-Cert"); !strings.Contains(xfcc, "URI=spiffe://example.org/ns/payments/sa/api") {
+		t.Fatalf("X-Forwarded-Client-Cert %q, want the SPIFFE URI", xfcc)
+	}
+
+	// 没有客户端证书时不透传身份，伪造的头被删除
+	if status := do(tlsClient(), "/", http.Header{"X-Client-SPIFFE-ID": {"spiffe://evil/x"}}); status != http.StatusOK {
+		t.Fatalf("status %d without a client certificate, want 200", status)
+	}
+	if id := capture.last().Get("X-Client-SPIFFE-ID"); id != "" {
+		t.Fatalf("X-Client-SPIFFE-ID %q without a client certificate, want it removed", id)
+	}
+
+	// 路由只允许SPIFFE ID匹配的请求
+	if status := do(tlsClient(payments), "/payments/charge", nil); status != http.StatusOK {
+		t.Fatalf("allowed SPIFFE ID: status %d, want 200", status)
+	}
+	if status := do(tlsClient(billing), "/payments/charge", nil); status != http.StatusForbidden {
+		t.Fatalf("other SPIFFE ID: status %d, want 403", status)
+	}
+	if status := do(tlsClient(), "/payments/charge", nil); status != http.StatusForbidden {
+		t.Fatalf("no client certificate: status %d, want 403", status)
+	}
+
+	// 其他CA签发的客户端证书在握手时被拒绝
+	other, _ := newTestCA(t, t.TempDir())
+	if _, err := tlsClient(other.issue(t, "api", "spiffe://example.org/ns/payments/sa/api")).Get(base + "/"); err == nil {
+		t.Fatal("certificate from an untrusted CA accepted")
+	}
+}
@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// startClientLimitedProxy 启动配置了单IP上限的代理
+func startClientLimitedProxy(t *testing.T, limits types.ClientLimitsConfig) *testutil.Proxy {
+	t.Helper()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.ClientLimits = limits
+	return testutil.StartProxy(t, cfg)
+}
+
+// rawGet 建立一个保持打开的连接并发送一个请求，连接被代理关闭时返回错误
+func rawGet(t *testing.T, addr string) (net.Conn, error) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return conn, nil
+}
+
+func TestMaxConnsPerIP(t *testing.T) {
+	skipShort(t)
+
+	p := startClientLimitedProxy(t, types.ClientLimitsConfig{MaxConnsPerIP: 1})
+
+	if _, err := rawGet(t, p.Addr); err != nil {
+		t.Fatalf("first connection: %v", err)
+	}
+	// 第一个连接仍然打开，第二个连接在接收时被关闭
+	if _, err := rawGet(t, p.Addr); err == nil {
+		t.Fatal("second connection served, want it closed at the per-IP limit")
+	}
+
+	var stats struct {
+		AtLimit  int   `json:"at_limit"`
+		Rejected int64 `json:"rejected"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/connections/client-limits", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.AtLimit != 1 || stats.Rejected != 1 {
+		t.Fatalf("client limit stats = %+v, want 1 IP at limit and 1 rejection", stats)
+	}
+}
+
+func TestClientLimitsAllowlist(t *testing.T) {
+	skipShort(t)
+
+	p := startClientLimitedProxy(t, types.ClientLimitsConfig{
+		MaxConnsPerIP: 1,
+		RequestRate:   1,
+		Allowlist:     []string{"127.0.0.0/8"},
+	})
+
+	// 白名单中的客户端不受连接数和请求速率限制
+	for i := 0; i < 3; i++ {
+		if _, err := rawGet(t, p.Addr); err != nil {
+			t.Fatalf("connection %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+}
+
+func TestClientRequestRate(t *testing.T) {
+	skipShort(t)
+
+	p := startClientLimitedProxy(t, types.ClientLimitsConfig{RequestRate: 1})
+
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", status)
+	}
+	resp, err := client.Get(p.URL("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || strings.TrimSpace(resp.Header.Get("Retry-After")) == "" {
+		t.Fatalf("second request: status %d, Retry-After %q, want 429 with Retry-After",
+			resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
+
+func TestClientLimitsIgnoreForwardedHeaders(t *testing.T) {
+	skipShort(t)
+
+	// 白名单和限速键按TCP对端地址判断，伪造X-Real-IP不能进入白名单或换用新的令牌桶
+	p := startClientLimitedProxy(t, types.ClientLimitsConfig{
+		RequestRate: 1,
+		Allowlist:   []string{"10.0.0.0/8"},
+	})
+	if status := getAs(t, p.URL("/"), "10.1.2.3", "10.1.2.3"); status != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", status)
+	}
+	for _, ip := range []string{"10.1.2.3", "203.0.113.9"} {
+		if status := getAs(t, p.URL("/"), ip, ip); status != http.StatusTooManyRequests {
+			t.Fatalf("request claiming %s: status %d, want 429", ip, status)
+		}
+	}
+}
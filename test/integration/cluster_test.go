@@ -0,0 +1,37 @@
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/cluster"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestClusterLocalRequiresClusterMode(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+
+	// 未启用集群模式时不暴露本节点快照
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+	for _, path := range []string{"/api/v1/cluster", cluster.LocalPath} {
+		err := p.Admin(http.MethodGet, path, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "status 404") {
+			t.Fatalf("GET %s without cluster mode: %v, want status 404", path, err)
+		}
+	}
+
+	cfg := testutil.NewConfig(b1)
+	cfg.Cluster.Enabled = true
+	cfg.Cluster.NodeName = "proxy-a"
+	p = testutil.StartProxy(t, cfg)
+	var snapshot cluster.NodeSnapshot
+	if err := p.Admin(http.MethodGet, cluster.LocalPath, nil, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Node != "proxy-a" || len(snapshot.Upstreams["default"]) != 1 {
+		t.Fatalf("local snapshot %+v, want node proxy-a with the default upstream", snapshot)
+	}
+}
@@ -0,0 +1,159 @@
+package integration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// writeCert 生成有效期为[notBefore, notAfter]的自签名证书和私钥文件
+func writeCert(t *testing.T, dir, name string, notBefore, notAfter time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// writeCheckConfig 将配置写入临时目录并返回路径
+func writeCheckConfig(t *testing.T, dir string, cfg *types.Config) string {
+	t.Helper()
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigCheck(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	certFile, keyFile := writeCert(t, dir, "server", now.Add(-time.Hour), now.Add(365*24*time.Hour))
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 8080
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}
+	path := writeCheckConfig(t, dir, cfg)
+
+	result, err := config.Check(path, config.CheckOptions{ResolveDNS: true, CheckCerts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK() || len(result.Warnings) != 0 {
+		t.Fatalf("valid config failed the check: errors %v, warnings %v", result.Errors, result.Warnings)
+	}
+
+	if _, err := config.Check(filepath.Join(dir, "missing.yaml"), config.CheckOptions{}); err == nil {
+		t.Fatal("checking a missing config file succeeded")
+	}
+}
+
+func TestConfigCheckReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	expiredCert, expiredKey := writeCert(t, dir, "expired", now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	soonCert, soonKey := writeCert(t, dir, "soon", now.Add(-time.Hour), now.Add(7*24*time.Hour))
+	_, otherKey := writeCert(t, dir, "other", now.Add(-time.Hour), now.Add(365*24*time.Hour))
+
+	b1 := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b1)
+	cfg.Server.Port = 8080
+	unresolvable := b1.Config()
+	unresolvable.ID = "backend2"
+	unresolvable.Host = "backend.speedmimi.invalid"
+	cfg.Backends["default"] = append(cfg.Backends["default"], unresolvable)
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: expiredCert, KeyFile: expiredKey, ClientCAFile: expiredKey}
+	cfg.GRPC.TLS = &types.AdminTLSConfig{CertFile: soonCert, KeyFile: otherKey}
+	path := writeCheckConfig(t, dir, cfg)
+
+	// 不开启额外检查时只做常规验证
+	result, err := config.Check(path, config.CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK() {
+		t.Fatalf("config failed the basic check: %v", result.Errors)
+	}
+
+	result, err = config.Check(path, config.CheckOptions{ResolveDNS: true, CheckCerts: true, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"backends.default[1].host",
+		"ssl.cert_file",
+		"ssl.client_ca_file",
+		"grpc.tls.cert_file",
+	}
+	if got := fieldsOf(result.Errors); !reflect.DeepEqual(got, want) {
+		t.Fatalf("error fields %v, want %v (%v)", got, want, result.Errors)
+	}
+
+	// 证书即将过期只给出警告
+	cfg.GRPC.TLS.KeyFile = soonKey
+	cfg.SSL.ClientCAFile = ""
+	cfg.Backends["default"] = cfg.Backends["default"][:1]
+	cfg.SSL.CertFile, cfg.SSL.KeyFile = soonCert, soonKey
+	path = writeCheckConfig(t, dir, cfg)
+	if result, err = config.Check(path, config.CheckOptions{CheckCerts: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK() {
+		t.Fatalf("config with a certificate close to expiry failed the check: %v", result.Errors)
+	}
+	if got := fieldsOf(result.Warnings); !reflect.DeepEqual(got, []string{"ssl.cert_file", "grpc.tls.cert_file"}) {
+		t.Fatalf("warning fields %v, want both certificates", got)
+	}
+
+	// 常规验证失败时返回所有字段错误
+	cfg.Server.Port = 70000
+	path = writeCheckConfig(t, dir, cfg)
+	if result, err = config.Check(path, config.CheckOptions{CheckCerts: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldsOf(result.Errors); !reflect.DeepEqual(got, []string{"server.port"}) {
+		t.Fatalf("error fields %v, want server.port", got)
+	}
+}
@@ -0,0 +1,47 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// 配置文件按yaml标签解析，fallback_upstreams、max_conn等带下划线的键不会被忽略
+func TestConfigFileDecodesUnderscoreKeys(t *testing.T) {
+	b1 := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b1)
+	cfg.Server.Port = 8080
+	cfg.Backends["default"][0].MaxConn = 7
+	backup := b1.Config()
+	backup.ID = "backup1"
+	cfg.Backends["backup"] = []*types.Backend{backup}
+	cfg.Routing["default"].FallbackUpstreams = []string{"backup"}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := config.NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := m.GetConfig()
+	if fallbacks := loaded.Routing["default"].FallbackUpstreams; !reflect.DeepEqual(fallbacks, []string{"backup"}) {
+		t.Fatalf("fallback_upstreams %v, want [backup]", fallbacks)
+	}
+	if maxConn := loaded.Backends["default"][0].MaxConn; maxConn != 7 {
+		t.Fatalf("max_conn %d, want 7", maxConn)
+	}
+}
@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// writeTemplatedConfig 写入代理端口和后端地址引用环境变量的配置文件
+func writeTemplatedConfig(t *testing.T) string {
+	t.Helper()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 18181
+	cfg.Backends["default"][0].Host = "backend-host-placeholder"
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte("port: 18181"), []byte("port: ${SPEEDMIMI_TEST_PORT:-8080}"), 1)
+	data = bytes.Replace(data, []byte("backend-host-placeholder"), []byte(`"${SPEEDMIMI_TEST_BACKEND_HOST}"`), 1)
+	// 注释中的引用不替换
+	data = append(data, []byte("# ${NOT_EXPANDED}\n")...)
+
+	path := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigEnvInterpolation(t *testing.T) {
+	path := writeTemplatedConfig(t)
+	t.Setenv("SPEEDMIMI_TEST_BACKEND_HOST", "127.0.0.1")
+
+	mgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := mgr.GetConfig()
+	if cfg.Server.Port != 8080 || cfg.Backends["default"][0].Host != "127.0.0.1" {
+		t.Fatalf("port %d, backend host %q, want the default port and the variable's value", cfg.Server.Port, cfg.Backends["default"][0].Host)
+	}
+
+	t.Setenv("SPEEDMIMI_TEST_PORT", "9191")
+	if mgr, err = config.NewManager(path); err != nil {
+		t.Fatal(err)
+	}
+	if port := mgr.GetConfig().Server.Port; port != 9191 {
+		t.Fatalf("port %d, want 9191 from the environment", port)
+	}
+
+	// 引用了环境变量的配置文件不被API的修改覆盖
+	before, _ := os.ReadFile(path)
+	updated := config.CloneConfig(mgr.GetConfig())
+	updated.Server.MaxRequestBodySize = 1 << 20
+	if err := mgr.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Fatal("config file with environment references was overwritten")
+	}
+	if mgr.GetConfig().Server.MaxRequestBodySize != 1<<20 {
+		t.Fatal("update was not applied in memory")
+	}
+}
+
+func TestConfigEnvInterpolationUndefinedVariable(t *testing.T) {
+	path := writeTemplatedConfig(t)
+
+	_, err := config.NewManager(path)
+	if err == nil || !strings.Contains(err.Error(), "SPEEDMIMI_TEST_BACKEND_HOST") {
+		t.Fatalf("loading with an undefined variable: %v, want an error naming it", err)
+	}
+}
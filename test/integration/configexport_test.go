@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+const exportAdminToken = "export-admin-token-0123456789"
+
+// adminRaw 发送任意请求体的管理API请求，返回状态码和响应内容
+func adminRaw(t *testing.T, p *testutil.Proxy, method, path string, body []byte) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, p.AdminURL(path), bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+exportAdminToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestConfigExportImport(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.GRPC.Auth.Tokens = []string{exportAdminToken}
+	cfg.RoutingToken = types.RoutingTokenConfig{Enabled: true, Secret: "routing-secret-0123456789"}
+	cfg.Webhooks = []types.WebhookConfig{{
+		Name:    "ops",
+		URL:     "http://127.0.0.1:1/hook",
+		Headers: map[string]string{"Authorization": "Bearer webhook-secret"},
+	}}
+	p := testutil.StartProxy(t, cfg)
+	webhookHeaders := p.Config.GetConfig().Webhooks[0].Headers
+	secrets := []string{exportAdminToken, "routing-secret-0123456789", "webhook-secret"}
+
+	// 默认隐藏所有密钥
+	code, exported := adminRaw(t, p, http.MethodGet, "/api/v1/config/export", nil)
+	if code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", code, exported)
+	}
+	for _, secret := range secrets {
+		if bytes.Contains(exported, []byte(secret)) {
+			t.Errorf("redacted export contains %q", secret)
+		}
+	}
+	if !bytes.Contains(exported, []byte(config.RedactedValue)) {
+		t.Fatal("redacted export has no redacted values")
+	}
+	if p.Config.GetConfig().RoutingToken.Secret != "routing-secret-0123456789" {
+		t.Fatal("export modified the current config")
+	}
+
+	// GET /api/v1/config不返回路由令牌密钥，原样写回时保留原值
+	code, got := adminRaw(t, p, http.MethodGet, "/api/v1/config", nil)
+	if code != http.StatusOK || bytes.Contains(got, []byte("routing-secret-0123456789")) {
+		t.Fatalf("GET /api/v1/config: status %d, want the routing token secret hidden: %s", code, got)
+	}
+	if code, body := adminRaw(t, p, http.MethodPut, "/api/v1/config", got); code != http.StatusOK {
+		t.Fatalf("round-tripping the config: status %d: %s", code, body)
+	}
+	if p.Config.GetConfig().RoutingToken.Secret != "routing-secret-0123456789" {
+		t.Fatal("round-tripping the config cleared the routing token secret")
+	}
+
+	_, full := adminRaw(t, p, http.MethodGet, "/api/v1/config/export?redact=false", nil)
+	for _, secret := range secrets {
+		if !bytes.Contains(full, []byte(secret)) {
+			t.Errorf("export with redact=false does not contain %q", secret)
+		}
+	}
+
+	// JSON使用与YAML相同的键名
+	_, data := adminRaw(t, p, http.MethodGet, "/api/v1/config/export?format=json", nil)
+	var doc struct {
+		Server struct {
+			MaxRequestBodySize int `json:"max_request_body_size"`
+		} `json:"server"`
+		RoutingToken struct {
+			Secret string `json:"secret"`
+		} `json:"routing_token"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("json export: %v: %s", err, data)
+	}
+	if doc.Server.MaxRequestBodySize != p.Config.GetConfig().Server.MaxRequestBodySize || doc.RoutingToken.Secret != config.RedactedValue {
+		t.Fatalf("json export %+v does not match the current config", doc)
+	}
+
+	// 导入修改后的导出文档，隐藏的密钥保留当前值
+	modified := bytes.Replace(exported, []byte("max_request_body_size: 4194304"), []byte("max_request_body_size: 2097152"), 1)
+	if bytes.Equal(modified, exported) {
+		t.Fatalf("exported config does not contain the default max_request_body_size:\n%s", exported)
+	}
+	if code, body := adminRaw(t, p, http.MethodPost, "/api/v1/config/import?dry_run=true", modified); code != http.StatusOK {
+		t.Fatalf("dry run import: status %d: %s", code, body)
+	}
+	if p.Config.GetConfig().Server.MaxRequestBodySize == 2097152 {
+		t.Fatal("dry run import applied the config")
+	}
+	if code, body := adminRaw(t, p, http.MethodPost, "/api/v1/config/import", modified); code != http.StatusOK {
+		t.Fatalf("import: status %d: %s", code, body)
+	}
+	current := p.Config.GetConfig()
+	if current.Server.MaxRequestBodySize != 2097152 {
+		t.Fatal("imported config was not applied")
+	}
+	if current.RoutingToken.Secret != "routing-secret-0123456789" || !reflect.DeepEqual(current.Webhooks[0].Headers, webhookHeaders) {
+		t.Fatalf("redacted secrets were not kept: %+v %+v", current.RoutingToken, current.Webhooks[0].Headers)
+	}
+
+	// 隐藏的值在当前配置中不存在时无法导入
+	invalid := strings.Replace(string(exported), "name: ops", "name: ops\n      proxy_secret: <redacted>", 1)
+	var result struct {
+		Errors config.FieldErrors `json:"errors"`
+	}
+	code, body := adminRaw(t, p, http.MethodPost, "/api/v1/config/import", []byte(invalid))
+	if code != http.StatusBadRequest || json.Unmarshal(body, &result) != nil {
+		t.Fatalf("importing an unknown redacted value: status %d: %s", code, body)
+	}
+	if got := fieldsOf(result.Errors); len(got) != 1 || got[0] != "webhooks[0].proxy_secret" {
+		t.Fatalf("error fields %v, want the redacted value without a current value", got)
+	}
+
+	// 校验失败时保留当前配置
+	invalid = strings.Replace(string(exported), "shutdown_timeout: 30s", "shutdown_timeout: -1s", 1)
+	if code, body = adminRaw(t, p, http.MethodPost, "/api/v1/config/import", []byte(invalid)); code != http.StatusBadRequest || !strings.Contains(string(body), `"field":"server.shutdown_timeout"`) {
+		t.Fatalf("importing an invalid config: status %d: %s", code, body)
+	}
+	if p.Config.GetConfig().Server.MaxRequestBodySize != 2097152 {
+		t.Fatal("invalid import replaced the current config")
+	}
+}
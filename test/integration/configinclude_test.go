@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// writeFragment 写入配置片段
+func writeFragment(t *testing.T, path string, fragment map[string]interface{}) {
+	t.Helper()
+	data, err := yaml.Marshal(fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// routeFragment 把路径前缀路由到upstream的配置片段
+func routeFragment(name, path, upstream string) map[string]interface{} {
+	return map[string]interface{}{
+		"routing": map[string]*types.RoutingRule{
+			name: {Path: path, Upstream: upstream, LoadBalancer: types.LeastConnectionsWeight},
+		},
+	}
+}
+
+func TestConfigIncludeMergesFragments(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	dir := filepath.Join(t.TempDir(), "conf.d")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFragment(t, filepath.Join(dir, "10-api-backends.yaml"), map[string]interface{}{
+		"backends": map[string][]*types.Backend{"api": {b2.Config()}},
+	})
+	writeFragment(t, filepath.Join(dir, "20-api-route.yaml"), routeFragment("api", "/api", "api"))
+
+	cfg := testutil.NewConfig(b1)
+	cfg.Include = []string{filepath.Join(dir, "*.yaml")}
+	cfg.ConfigWatch = types.ConfigWatchConfig{Enabled: true, Debounce: 50 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	if _, server := get(t, p.URL("/api/users")); server != "backend2" {
+		t.Fatalf("/api served by %q, want backend2 from the fragment", server)
+	}
+	if _, server := get(t, p.URL("/")); server != "backend1" {
+		t.Fatalf("/ served by %q, want backend1 from the main config", server)
+	}
+
+	// 新增的配置片段被热加载
+	writeFragment(t, filepath.Join(dir, "30-v2-route.yaml"), routeFragment("v2", "/v2", "api"))
+	if !testutil.Eventually(3*time.Second, func() bool {
+		_, server := get(t, p.URL("/v2/users"))
+		return server == "backend2"
+	}) {
+		t.Fatalf("new fragment was not applied: %+v", reloadStatus(t, p))
+	}
+
+	// 重复定义的路由被拒绝，保留当前配置
+	writeFragment(t, filepath.Join(dir, "40-duplicate.yaml"), routeFragment("v2", "/v3", "default"))
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return reloadStatus(t, p).Failures == 1
+	}) {
+		t.Fatalf("duplicate route was not rejected: %+v", reloadStatus(t, p))
+	}
+	if status := reloadStatus(t, p); !strings.Contains(status.LastError, "already defined") {
+		t.Fatalf("reload error %q, want a duplicate definition error", status.LastError)
+	}
+	if _, server := get(t, p.URL("/v2/users")); server != "backend2" {
+		t.Fatalf("/v2 served by %q after the rejected edit, want backend2", server)
+	}
+}
+
+func TestConfigIncludeRejectsUnsupportedKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFragment(t, filepath.Join(dir, "server.yaml"), map[string]interface{}{
+		"server": map[string]interface{}{"port": 9090},
+	})
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 8080
+	cfg.Include = []string{"server.yaml"}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := config.NewManager(path); err == nil || !strings.Contains(err.Error(), "unsupported key") {
+		t.Fatalf("loading a fragment with server settings: %v, want an unsupported key error", err)
+	}
+}
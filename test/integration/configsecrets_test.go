@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// fakeVault 模拟Vault KV v2，只接受给定令牌
+type fakeVault struct {
+	mu     sync.Mutex
+	token  string
+	values map[string]map[string]string // 路径 -> 字段
+}
+
+func (v *fakeVault) set(path, field, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values[path] == nil {
+		v.values[path] = make(map[string]string)
+	}
+	v.values[path][field] = value
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	fields, ok := v.values[strings.TrimPrefix(r.URL.Path, "/v1/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"data":     fields,
+			"metadata": map[string]interface{}{"version": 1},
+		},
+	})
+}
+
+func TestConfigSecretsVault(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	certFile, keyFile := writeCert(t, dir, "server", now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	certPEM, _ := os.ReadFile(certFile)
+	keyPEM, _ := os.ReadFile(keyFile)
+
+	vault := &fakeVault{token: "vault-token", values: make(map[string]map[string]string)}
+	vault.set("secret/data/speedmimi", "admin_token", "admin-token-0123456789")
+	vault.set("secret/data/tls", "cert", string(certPEM))
+	vault.set("secret/data/tls", "key", string(keyPEM))
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "vault-token")
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 8080
+	cfg.GRPC.Port = 9091
+	cfg.GRPC.Auth.Tokens = []string{"${secret:vault:secret/data/speedmimi#admin_token}"}
+	cfg.SSL = types.SSLConfig{
+		Enabled:  true,
+		CertFile: "${secret_file:vault:secret/data/tls#cert}",
+		KeyFile:  "${secret_file:vault:secret/data/tls#key}",
+	}
+	secretsDir := filepath.Join(dir, "secrets")
+	cfg.Secrets = types.SecretsConfig{
+		Dir:       secretsDir,
+		Providers: map[string]*types.SecretProviderConfig{"vault": {Type: types.SecretProviderVault, Address: server.URL}},
+	}
+	path := writeCheckConfig(t, dir, cfg)
+
+	mgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := mgr.GetConfig()
+	if got := loaded.GRPC.Auth.Tokens; len(got) != 1 || got[0] != "admin-token-0123456789" {
+		t.Fatalf("admin tokens %v, want the token from vault", got)
+	}
+	if filepath.Dir(loaded.SSL.CertFile) != secretsDir {
+		t.Fatalf("cert_file %s, want a file in %s", loaded.SSL.CertFile, secretsDir)
+	}
+	if data, _ := os.ReadFile(loaded.SSL.KeyFile); !bytes.Equal(data, keyPEM) {
+		t.Fatal("key_file does not contain the key from vault")
+	}
+	if info, err := os.Stat(loaded.SSL.KeyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file mode %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+	if result, err := config.Check(path, config.CheckOptions{CheckCerts: true}); err != nil || !result.OK() {
+		t.Fatalf("checking the certificate from vault: %v %v", err, result.Errors)
+	}
+
+	// 通过API修改的配置不会把密钥写回配置文件
+	if err := mgr.UpdateConfig(config.CloneConfig(loaded)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("admin-token-0123456789")) {
+		t.Fatal("secret was written to the config file")
+	}
+
+	// 密钥轮换后重新加载配置
+	if err := mgr.StartSecretRefresh(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.StopSecretRefresh()
+	vault.set("secret/data/speedmimi", "admin_token", "rotated-token-0123456789")
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return mgr.GetConfig().GRPC.Auth.Tokens[0] == "rotated-token-0123456789"
+	}) {
+		t.Fatalf("rotated secret was not applied: %+v", mgr.GetReloadStatus())
+	}
+
+	// Vault不可用时保留当前配置
+	server.Close()
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return mgr.GetReloadStatus().Failures > 0
+	}) {
+		t.Fatal("refresh failure was not recorded")
+	}
+	if mgr.GetConfig().GRPC.Auth.Tokens[0] != "rotated-token-0123456789" {
+		t.Fatal("failed refresh replaced the current config")
+	}
+}
+
+func TestConfigSecretsAWSKMS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	ciphertext := base64.StdEncoding.EncodeToString([]byte("encrypted routing secret"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			CiphertextBlob string
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("X-Amz-Target") != "TrentService.Decrypt" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request") ||
+			req.CiphertextBlob != ciphertext {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": []byte("routing-secret-0123456789")})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 8080
+	cfg.RoutingToken = types.RoutingTokenConfig{Enabled: true, Secret: "${secret:kms:" + ciphertext + "}"}
+	cfg.Secrets.Providers = map[string]*types.SecretProviderConfig{
+		"kms": {Type: types.SecretProviderAWSKMS, Region: "us-east-1", Endpoint: server.URL},
+	}
+	mgr, err := config.NewManager(writeCheckConfig(t, dir, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.GetConfig().RoutingToken.Secret; got != "routing-secret-0123456789" {
+		t.Fatalf("routing token secret %q, want the decrypted value", got)
+	}
+
+	// 无法解密或引用了未配置的提供者时拒绝加载
+	cfg.RoutingToken.Secret = "${secret:kms:" + base64.StdEncoding.EncodeToString([]byte("other")) + "}"
+	if _, err := config.NewManager(writeCheckConfig(t, dir, cfg)); err == nil || !strings.Contains(err.Error(), "InvalidCiphertextException") {
+		t.Fatalf("loading with an invalid ciphertext: %v", err)
+	}
+	cfg.RoutingToken.Secret = "${secret:missing:value}"
+	if _, err := config.NewManager(writeCheckConfig(t, dir, cfg)); err == nil || !strings.Contains(err.Error(), "secret provider missing is not configured") {
+		t.Fatalf("loading with an unknown provider: %v", err)
+	}
+}
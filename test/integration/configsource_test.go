@@ -0,0 +1,241 @@
+package integration
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// fakeKV 模拟远程配置来源中的一个键，修改时递增版本并唤醒等待者
+type fakeKV struct {
+	mu      sync.Mutex
+	value   []byte
+	version int64
+	changed chan struct{}
+}
+
+func newFakeKV(value []byte) *fakeKV {
+	return &fakeKV{value: value, version: 1, changed: make(chan struct{})}
+}
+
+func (kv *fakeKV) get() ([]byte, int64, <-chan struct{}) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.value, kv.version, kv.changed
+}
+
+func (kv *fakeKV) set(value []byte) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.value = value
+	kv.version++
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+}
+
+// waitNewer 等待版本大于version，请求结束时返回false
+func (kv *fakeKV) waitNewer(r *http.Request, version int64) bool {
+	for {
+		_, current, changed := kv.get()
+		if current > version {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+// sourceConfig 生成远程配置内容，maxBody区分不同版本
+func sourceConfig(t *testing.T, maxBody int) []byte {
+	t.Helper()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.Port = 8080
+	cfg.Server.MaxRequestBodySize = maxBody
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkSourceWatch 启动监听，修改来源中的配置后等待生效，然后确认无效的配置被拒绝
+func checkSourceWatch(t *testing.T, mgr *config.Manager, kv *fakeKV) {
+	t.Helper()
+	if err := mgr.StartSourceWatch(); err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.StopSourceWatch()
+
+	kv.set(sourceConfig(t, 2<<20))
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return mgr.GetConfig().Server.MaxRequestBodySize == 2<<20
+	}) {
+		t.Fatalf("changed config was not applied: %+v", mgr.GetReloadStatus())
+	}
+
+	kv.set([]byte("server:\n  port: -1\n"))
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return mgr.GetReloadStatus().Failures == 1
+	}) {
+		t.Fatalf("invalid config was not rejected: %+v", mgr.GetReloadStatus())
+	}
+	if mgr.GetConfig().Server.MaxRequestBodySize != 2<<20 {
+		t.Fatal("invalid config replaced the current config")
+	}
+}
+
+func TestConfigSourceHTTPS(t *testing.T) {
+	skipShort(t)
+
+	kv := newFakeKV(sourceConfig(t, 1<<20))
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer source-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		value, version, _ := kv.get()
+		etag := strconv.Quote(strconv.FormatInt(version, 10))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(value)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := config.SourceOptions{Token: "source-token", CAFile: caFile, Interval: 50 * time.Millisecond}
+	source, err := config.ParseSource(server.URL+"/config.yaml?signature=secret", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cachePath := filepath.Join(dir, "config.yaml")
+	mgr, err := config.NewManagerFromSource(cachePath, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mgr.GetConfig().Server.MaxRequestBodySize != 1<<20 {
+		t.Fatalf("max_request_body_size %d, want the value from the source", mgr.GetConfig().Server.MaxRequestBodySize)
+	}
+	if status := mgr.GetReloadStatus(); status.Source != server.URL+"/config.yaml" {
+		t.Fatalf("source %q, want the URL without the query", status.Source)
+	}
+	checkSourceWatch(t, mgr, kv)
+
+	// 来源不可用时使用本地缓存中最近一次有效的配置
+	server.Close()
+	if mgr, err = config.NewManagerFromSource(cachePath, source); err != nil {
+		t.Fatalf("starting with the source down: %v", err)
+	}
+	if mgr.GetConfig().Server.MaxRequestBodySize != 2<<20 {
+		t.Fatalf("max_request_body_size %d from the cache, want the last valid config", mgr.GetConfig().Server.MaxRequestBodySize)
+	}
+
+	if _, err := config.ParseSource("http://example.com/config.yaml", opts); err == nil {
+		t.Fatal("plain http config source was accepted")
+	}
+}
+
+func TestConfigSourceConsul(t *testing.T) {
+	skipShort(t)
+
+	kv := newFakeKV(sourceConfig(t, 1<<20))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/speedmimi/config" || r.Header.Get("X-Consul-Token") != "acl-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// 阻塞查询：索引未变化时等待修改
+		if index := r.URL.Query().Get("index"); index != "" {
+			version, _ := strconv.ParseInt(index, 10, 64)
+			if !kv.waitNewer(r, version) {
+				return
+			}
+		}
+		value, version, _ := kv.get()
+		w.Header().Set("X-Consul-Index", strconv.FormatInt(version, 10))
+		w.Write(value)
+	}))
+	defer server.Close()
+
+	source, err := config.ParseSource("consul://"+server.Listener.Addr().String()+"/speedmimi/config", config.SourceOptions{Token: "acl-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := config.NewManagerFromSource(filepath.Join(t.TempDir(), "config.yaml"), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceWatch(t, mgr, kv)
+}
+
+func TestConfigSourceEtcd(t *testing.T) {
+	skipShort(t)
+
+	kv := newFakeKV(sourceConfig(t, 1<<20))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Key []byte `json:"key"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if string(req.Key) != "/speedmimi/config" {
+			w.Write([]byte(`{"header":{"revision":"1"}}`))
+			return
+		}
+		value, version, _ := kv.get()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"header": map[string]string{"revision": strconv.FormatInt(version, 10)},
+			"kvs":    []map[string]interface{}{{"key": req.Key, "value": value}},
+		})
+	})
+	mux.HandleFunc("/v3/watch", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			CreateRequest struct {
+				StartRevision string `json:"start_revision"`
+			} `json:"create_request"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		start, _ := strconv.ParseInt(req.CreateRequest.StartRevision, 10, 64)
+
+		fmt.Fprintln(w, `{"result":{"created":true}}`)
+		w.(http.Flusher).Flush()
+		if kv.waitNewer(r, start-1) {
+			fmt.Fprintln(w, `{"result":{"events":[{"type":"PUT"}]}}`)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source, err := config.ParseSource("etcd://"+server.Listener.Addr().String()+"/speedmimi/config", config.SourceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := config.NewManagerFromSource(filepath.Join(t.TempDir(), "config.yaml"), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSourceWatch(t, mgr, kv)
+}
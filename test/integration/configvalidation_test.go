@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// fieldsOf 返回校验错误的字段路径
+func fieldsOf(errs config.FieldErrors) []string {
+	fields := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, fe.Field)
+	}
+	return fields
+}
+
+func TestConfigValidationReportsAllErrors(t *testing.T) {
+	b1 := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b1)
+	cfg.Server.Port = 8080
+	cfg.Server.ShutdownTimeout = -time.Second
+	bad := b1.Config()
+	bad.ID = "backend2"
+	bad.Port = 70000
+	cfg.Backends["default"] = append(cfg.Backends["default"], bad)
+	cfg.Routing["api"] = &types.RoutingRule{Path: "/api", Upstream: "missing", Priority: "urgent"}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = config.NewManager(path)
+	var errs config.FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("loading an invalid config: %v, want field errors", err)
+	}
+	want := []string{
+		"server.shutdown_timeout",
+		"backends.default[1].port",
+		"routing.api.upstream",
+		"routing.api.priority",
+	}
+	if got := fieldsOf(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("error fields %v, want %v (%v)", got, want, err)
+	}
+}
+
+func TestConfigValidationErrorsFromAdminAPI(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+
+	updated := config.CloneConfig(p.Config.GetConfig())
+	updated.Routing = map[string]*types.RoutingRule{
+		"default": {Path: "/", Upstream: "default", ForwardedHeaders: "drop"},
+		"api":     {Path: "/api"},
+	}
+	err := p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": updated}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("updating with an invalid config: %v, want status 400", err)
+	}
+	for _, field := range []string{`"field":"routing.api.upstream"`, `"field":"routing.default.forwarded_headers"`} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("response %v does not report %s", err, field)
+		}
+	}
+}
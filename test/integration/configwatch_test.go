@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// editConfigFile 读取代理的配置文件，修改后写回
+func editConfigFile(t *testing.T, p *testutil.Proxy, edit func(cfg *types.Config)) {
+	t.Helper()
+	data, err := os.ReadFile(p.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &types.Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		t.Fatal(err)
+	}
+	edit(cfg)
+	if data, err = yaml.Marshal(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.ConfigPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reloadStatus 查询配置文件热加载状态
+func reloadStatus(t *testing.T, p *testutil.Proxy) config.ReloadStatus {
+	t.Helper()
+	var resp struct {
+		Reload config.ReloadStatus `json:"reload"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/config/reload", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Reload
+}
+
+func TestConfigFileWatchAppliesEdits(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	cfg := testutil.NewConfig(b1)
+	cfg.ConfigWatch = types.ConfigWatchConfig{Enabled: true, Debounce: 50 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	if _, server := get(t, p.URL("/")); server != "backend1" {
+		t.Fatalf("before edit: served by %q, want backend1", server)
+	}
+
+	editConfigFile(t, p, func(cfg *types.Config) {
+		cfg.Backends["default"] = []*types.Backend{b2.Config()}
+	})
+	if !testutil.Eventually(3*time.Second, func() bool {
+		_, server := get(t, p.URL("/"))
+		return server == "backend2"
+	}) {
+		t.Fatalf("edited config was not applied: %+v", reloadStatus(t, p))
+	}
+	if status := reloadStatus(t, p); !status.Watching || status.Reloads != 1 || status.Failures != 0 {
+		t.Fatalf("after edit: %+v, want 1 reload", status)
+	}
+
+	// 通过管理API保存的配置不会被当作外部修改再加载一次
+	updated := config.CloneConfig(p.Config.GetConfig())
+	updated.Server.MaxRequestBodySize = 1 << 20
+	if err := p.Config.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if status := reloadStatus(t, p); status.Reloads != 1 {
+		t.Fatalf("after saving via the API: %+v, want no extra reload", status)
+	}
+}
+
+func TestConfigFileWatchRejectsInvalidEdits(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b)
+	cfg.ConfigWatch = types.ConfigWatchConfig{Enabled: true, Debounce: 50 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+	before := p.Config.GetConfig()
+
+	// 路由指向不存在的上游
+	editConfigFile(t, p, func(cfg *types.Config) {
+		cfg.Routing["default"].Upstream = "missing"
+	})
+	if !testutil.Eventually(3*time.Second, func() bool {
+		return reloadStatus(t, p).Failures == 1
+	}) {
+		t.Fatalf("invalid edit was not reported: %+v", reloadStatus(t, p))
+	}
+	if status := reloadStatus(t, p); status.Reloads != 0 || status.LastError == "" {
+		t.Fatalf("after invalid edit: %+v, want the error recorded and no reload", status)
+	}
+	if p.Config.GetConfig() != before {
+		t.Fatal("invalid config replaced the current config")
+	}
+	if code, server := get(t, p.URL("/")); code != http.StatusOK || server != "backend1" {
+		t.Fatalf("after invalid edit: status %d from %q, want 200 from backend1", code, server)
+	}
+
+	// 手动重新加载同样校验失败
+	var resp map[string]interface{}
+	if err := p.Admin(http.MethodPost, "/api/v1/config/reload", nil, &resp); err == nil {
+		t.Fatalf("manual reload of invalid config succeeded: %v", resp)
+	}
+}
+
+func TestConfigWatcherReceivesLatestConfig(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+	watcher := p.Config.WatchConfig()
+	defer p.Config.StopWatching(watcher)
+
+	// 观察者没有及时取走时，通道中的旧配置被替换为最新配置
+	for size := 1; size <= 3; size++ {
+		if err := p.Config.Update(func(cfg *types.Config) error {
+			cfg.Server.MaxRequestBodySize = size << 20
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case cfg := <-watcher:
+		if cfg.Server.MaxRequestBodySize != 3<<20 {
+			t.Fatalf("watcher got max_request_body_size %d, want the latest update", cfg.Server.MaxRequestBodySize)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watcher was not notified")
+	}
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// connClassStats 获取各连接类别的统计
+func connClassStats(t *testing.T, p *testutil.Proxy) map[string]map[string]int64 {
+	t.Helper()
+	var resp struct {
+		Classes []map[string]interface{} `json:"classes"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/connection-classes", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	stats := make(map[string]map[string]int64)
+	for _, class := range resp.Classes {
+		values := make(map[string]int64)
+		for k, v := range class {
+			if n, ok := v.(float64); ok {
+				values[k] = int64(n)
+			}
+		}
+		stats[class["class"].(string)] = values
+	}
+	return stats
+}
+
+func TestConnectionClassBudgets(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.ConnectionClasses = types.ConnectionClassesConfig{
+		HTTP:   types.ConnectionClassLimit{MaxMemory: 64 << 10},
+		Stream: types.ConnectionClassLimit{MaxConcurrent: 1},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	sse := func(path string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, p.URL(path), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		return client.Do(req)
+	}
+
+	// 流式连接占满stream类别的并发名额
+	done := make(chan int, 1)
+	go func() {
+		resp, err := sse("/events?sleep=1s")
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	if !testutil.Eventually(2*time.Second, func() bool {
+		return connClassStats(t, p)["stream"]["active"] == 1
+	}) {
+		t.Fatal("stream request never became active")
+	}
+
+	resp, err := sse("/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("second stream request: status %d, want 503", resp.StatusCode)
+	}
+
+	// 普通请求使用独立的预算，不受流式连接影响
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("http request while the stream class is full: status %d, want 200", status)
+	}
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("in-flight stream request: status %d, want 200", status)
+	}
+
+	// 上游响应使http类别超出内存上限时返回503
+	if status, _ := get(t, p.URL("/big?size=131072")); status != http.StatusServiceUnavailable {
+		t.Fatalf("response over the http memory budget: status %d, want 503", status)
+	}
+
+	stats := connClassStats(t, p)
+	if stats["stream"]["rejected_concurrency"] != 1 || stats["http"]["rejected_memory"] != 1 {
+		t.Fatalf("connection class stats %v, want one rejection per class", stats)
+	}
+	if stats["stream"]["active"] != 0 || stats["http"]["memory"] != 0 {
+		t.Fatalf("connection class stats %v, want the budgets released", stats)
+	}
+}
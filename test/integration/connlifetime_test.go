@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestConnectionMaxRequests(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.ConnectionLifetime = types.ConnectionLifetimeConfig{MaxRequests: 2}
+	p := testutil.StartProxy(t, cfg)
+
+	conn, err := net.Dial("tcp", p.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	// 第二个请求正常返回并要求关闭连接
+	for i := 1; i <= 2; i++ {
+		if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Close != (i == 2) {
+			t.Fatalf("request %d: status %d, close %v", i, resp.StatusCode, resp.Close)
+		}
+	}
+	if _, err := reader.ReadByte(); err == nil {
+		t.Fatal("connection still open after max_requests")
+	}
+
+	var stats struct {
+		MaxRequestsClosed int64 `json:"max_requests_closed"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/connections/lifetime", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.MaxRequestsClosed != 1 {
+		t.Fatalf("max_requests_closed = %d, want 1", stats.MaxRequestsClosed)
+	}
+}
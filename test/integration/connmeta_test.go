@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// headerCapture 记录最近一次请求头的测试后端
+type headerCapture struct {
+	mu     sync.Mutex
+	header http.Header
+}
+
+// startHeaderCapture 启动记录请求头的测试后端，返回其后端配置
+func startHeaderCapture(t *testing.T, id string) (*headerCapture, *types.Backend) {
+	t.Helper()
+	c := &headerCapture{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.header = r.Header.Clone()
+		c.mu.Unlock()
+		w.Header().Set("X-Server", id)
+	}))
+	t.Cleanup(upstream.Close)
+	return c, testutil.ServerBackend(id, upstream)
+}
+
+// last 最近一次请求的请求头
+func (c *headerCapture) last() http.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.header
+}
+
+// connGet 在已建立的连接上发送GET请求并读取响应
+func connGet(t *testing.T, conn net.Conn, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://proxy/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestConnectionMetadataHeaders(t *testing.T) {
+	skipShort(t)
+
+	capture, backend := startHeaderCapture(t, "backend1")
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{backend}
+	cfg.Server.ConnectionMetadata.Enabled = true
+	p := testutil.StartProxy(t, cfg)
+
+	conn := dialProxy(t, p)
+	defer conn.Close()
+
+	// 客户端伪造的元数据不论大小写都被删除：TLS元数据在明文连接上不出现，客户端端口和连接ID由代理设置
+	if resp := connGet(t, conn, http.Header{"X-Client-Tls-Version": {"TLS 1.0"}, "x-client-port": {"1"}}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	first := capture.last()
+	if v := first.Get("X-Client-TLS-Version"); v != "" {
+		t.Fatalf("X-Client-TLS-Version %q on a plaintext connection, want it removed", v)
+	}
+	if want := strconv.Itoa(conn.LocalAddr().(*net.TCPAddr).Port); len(first.Values("X-Client-Port")) != 1 || first.Get("X-Client-Port") != want {
+		t.Fatalf("X-Client-Port %q, want only %s", first.Values("X-Client-Port"), want)
+	}
+	if first.Get("X-Connection-ID") == "" {
+		t.Fatal("X-Connection-ID missing")
+	}
+
+	// 同一连接上的请求连接ID相同，新连接不同
+	connGet(t, conn, nil)
+	if id := capture.last().Get("X-Connection-ID"); id != first.Get("X-Connection-ID") {
+		t.Fatalf("connection id %q on the same connection, want %q", id, first.Get("X-Connection-ID"))
+	}
+	other := dialProxy(t, p)
+	defer other.Close()
+	connGet(t, other, nil)
+	if id := capture.last().Get("X-Connection-ID"); id == first.Get("X-Connection-ID") {
+		t.Fatalf("connection id %q reused by a new connection", id)
+	}
+}
+
+func TestConnectionMetadataTLS(t *testing.T) {
+	skipShort(t)
+
+	now := time.Now()
+	certFile, keyFile := writeCert(t, t.TempDir(), "server", now.Add(-time.Hour), now.Add(time.Hour))
+	capture, backend := startHeaderCapture(t, "backend1")
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{backend}
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}
+	cfg.Server.ConnectionMetadata = types.ConnectionMetadataConfig{Enabled: true, TLSVersionHeader: "X-TLS"}
+	p := testutil.StartProxy(t, cfg)
+
+	conn, err := tls.Dial("tcp", p.Addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	connGet(t, conn, nil)
+
+	// 自定义请求头名称生效，其余使用默认名称
+	header := capture.last()
+	if v := header.Get("X-TLS"); v != "TLS 1.2" {
+		t.Fatalf("X-TLS %q, want TLS 1.2", v)
+	}
+	if want := tls.CipherSuiteName(conn.ConnectionState().CipherSuite); header.Get("X-Client-TLS-Cipher") != want {
+		t.Fatalf("X-Client-TLS-Cipher %q, want %s", header.Get("X-Client-TLS-Cipher"), want)
+	}
+}
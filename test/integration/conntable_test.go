@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/proxy"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// listConns 获取管理API返回的连接列表
+func listConns(t *testing.T, p *testutil.Proxy, query string) proxy.ConnList {
+	t.Helper()
+	var list proxy.ConnList
+	if err := p.Admin(http.MethodGet, "/api/v1/connections"+query, nil, &list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestConnectionTableInFlight(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+
+	// 一个处理中的慢请求
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		get(t, p.URL("/?sleep=500ms"))
+	}()
+
+	const query = "?upstream=default&backend=backend1&in_flight=true"
+	var list proxy.ConnList
+	if !testutil.Eventually(time.Second, func() bool {
+		list = listConns(t, p, query)
+		return len(list.Connections) == 1
+	}) {
+		t.Fatalf("in-flight connections %+v, want 1", list)
+	}
+	conn := list.Connections[0]
+	if !conn.InFlight || conn.Route != "default" || conn.Upstream != "default" || conn.Backend != "backend1" || conn.ClientIP != "127.0.0.1" {
+		t.Errorf("connection %+v, want an in-flight request to default/backend1 from 127.0.0.1", conn)
+	}
+
+	// 请求完成后保持连接仍在表中，但不再计为处理中
+	<-done
+	if !testutil.Eventually(time.Second, func() bool {
+		return len(listConns(t, p, query).Connections) == 0
+	}) {
+		t.Fatal("connection still in flight after the request finished")
+	}
+	idle := listConns(t, p, "?upstream=default")
+	if len(idle.Connections) == 0 || idle.Connections[0].InFlight || idle.Connections[0].RequestSeconds != 0 {
+		t.Errorf("connections %+v, want the idle keep-alive connection", idle.Connections)
+	}
+	if n := len(listConns(t, p, "?upstream=other").Connections); n != 0 {
+		t.Errorf("upstream=other matched %d connections, want 0", n)
+	}
+
+	resp, err := client.Get(p.AdminURL("/api/v1/connections?in_flight=maybe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("in_flight=maybe status %d, want 400", resp.StatusCode)
+	}
+}
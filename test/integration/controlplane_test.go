@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestAdminWorkerLimit(t *testing.T) {
+	skipShort(t)
+
+	// 健康检查路径一直阻塞，按需探测会占用唯一的管理工作协程
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-health" {
+			<-release
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+	defer unblock()
+
+	backend := testutil.ServerBackend("slow", upstream)
+	backend.HealthCheck = &types.HealthCheck{Path: "/slow-health", Interval: time.Hour}
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{backend}
+	cfg.ControlPlane = types.ControlPlaneConfig{AdminWorkers: 1, AdminQueueTimeout: 100 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	probeDone := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		if resp, err := client.Get(p.AdminURL("/api/v1/upstreams/default/health?timeout=3s")); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	// 管理工作协程被占用时新的管理请求排队超时返回503，数据面请求不受影响
+	busy := testutil.Eventually(2*time.Second, func() bool {
+		resp, err := client.Get(p.AdminURL("/api/v1/control-plane"))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != ""
+	})
+	if !busy {
+		t.Fatal("admin request not rejected while the only admin worker was busy")
+	}
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("proxied request: status %d, want 200", status)
+	}
+
+	unblock()
+	<-probeDone
+
+	var stats struct {
+		Admin struct {
+			Workers  int   `json:"workers"`
+			Rejected int64 `json:"rejected"`
+		} `json:"admin"`
+		Health struct {
+			Workers int `json:"workers"`
+		} `json:"health"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/control-plane", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Admin.Workers != 1 || stats.Admin.Rejected == 0 || stats.Health.Workers != 8 {
+		t.Fatalf("control plane stats = %+v, want 1 admin worker with rejections and 8 health workers", stats)
+	}
+}
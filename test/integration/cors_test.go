@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestCORS(t *testing.T) {
+	skipShort(t)
+
+	// 上游返回自己的CORS头，应被路由配置替换
+	var upstreamRequests atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests.Add(1)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("X-Request-Id", "42")
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("app", upstream)}
+	cfg.Routing["default"].CORS = &types.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowMethods:     []string{"GET", "PUT"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	do := func(method string, headers map[string]string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, p.URL("/items"), nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	// 代理直接响应允许的预检请求
+	resp := do(http.MethodOptions, map[string]string{
+		"Origin":                         "https://shop.example.org",
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "content-type",
+	})
+	if resp.StatusCode != http.StatusNoContent ||
+		resp.Header.Get("Access-Control-Allow-Origin") != "https://shop.example.org" ||
+		resp.Header.Get("Access-Control-Allow-Methods") != "GET, PUT" ||
+		resp.Header.Get("Access-Control-Allow-Credentials") != "true" ||
+		resp.Header.Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("preflight: status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+
+	// 方法或来源不允许的预检请求返回403
+	if resp := do(http.MethodOptions, map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("preflight with disallowed method: status %d, want 403", resp.StatusCode)
+	}
+	if resp := do(http.MethodOptions, map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "GET"}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("preflight from disallowed origin: status %d, want 403", resp.StatusCode)
+	}
+	if n := upstreamRequests.Load(); n != 0 {
+		t.Fatalf("upstream received %d preflight requests, want 0", n)
+	}
+
+	// 实际请求的响应使用路由的CORS头，不允许的来源不返回CORS头
+	resp = do(http.MethodGet, map[string]string{"Origin": "https://app.example.com"})
+	if resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" || resp.Header.Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+		t.Fatalf("allowed origin: headers %v", resp.Header)
+	}
+	resp = do(http.MethodGet, map[string]string{"Origin": "https://evil.example.com"})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed origin: status %d, Access-Control-Allow-Origin %q, want 200 without CORS headers",
+			resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestDebugTrace(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"), testutil.StartBackend(t, "backend2"))
+	cfg.DebugTrace.Path = filepath.Join(t.TempDir(), "trace.log")
+	p := testutil.StartProxy(t, cfg)
+
+	if err := p.Admin(http.MethodPost, "/api/v1/debug/trace", map[string]interface{}{"route": "missing"}, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("trace for an unknown route: %v, want 404", err)
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/debug/trace", map[string]interface{}{}, nil); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("trace without route or backend: %v, want 400", err)
+	}
+
+	var started struct {
+		Trace struct {
+			ID string `json:"id"`
+		} `json:"trace"`
+	}
+	body := map[string]interface{}{"backend": "backend1", "duration": "1m", "max_requests": 2}
+	if err := p.Admin(http.MethodPost, "/api/v1/debug/trace", body, &started); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只跟踪转发到backend1的请求，写满2个请求后自动关闭
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest(http.MethodGet, p.URL("/traced"), nil)
+		req.Header.Set("Authorization", "Bearer secret-value")
+		req.Header.Set("X-Debug-Marker", "marker")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	var stats struct {
+		Path     string `json:"path"`
+		Sessions []struct {
+			ID         string `json:"id"`
+			Traced     int64  `json:"traced"`
+			Active     bool   `json:"active"`
+			StopReason string `json:"stop_reason"`
+		} `json:"sessions"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/debug/trace", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Sessions) != 1 || stats.Sessions[0].ID != started.Trace.ID || stats.Sessions[0].Active ||
+		stats.Sessions[0].StopReason != "max_requests" || stats.Sessions[0].Traced != 2 {
+		t.Fatalf("trace sessions = %+v, want the trace stopped after 2 requests", stats.Sessions)
+	}
+
+	data, err := os.ReadFile(cfg.DebugTrace.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	if n := strings.Count(log, "--- trace "+started.Trace.ID); n != 2 {
+		t.Fatalf("trace log has %d traced requests, want 2:\n%s", n, log)
+	}
+	for _, want := range []string{"X-Debug-Marker: marker", "selected backend backend1", "backend backend1 responded 200", "X-Forwarded-For: "} {
+		if !strings.Contains(log, want) {
+			t.Fatalf("trace log missing %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "secret-value") || strings.Contains(log, "backend2") {
+		t.Fatalf("trace log contains a credential or an untraced backend:\n%s", log)
+	}
+
+	// 手动结束的跟踪不再记录请求
+	body = map[string]interface{}{"route": "default", "duration": "1m"}
+	if err := p.Admin(http.MethodPost, "/api/v1/debug/trace", body, &started); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Admin(http.MethodDelete, "/api/v1/debug/trace?id="+started.Trace.ID, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Admin(http.MethodDelete, "/api/v1/debug/trace?id="+started.Trace.ID, nil, nil); err == nil {
+		t.Fatal("stopping a stopped trace succeeded, want 404")
+	}
+	resp, err := client.Get(p.URL("/after-stop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	time.Sleep(50 * time.Millisecond)
+	if data, _ := os.ReadFile(cfg.DebugTrace.Path); strings.Contains(string(data), "/after-stop") {
+		t.Fatalf("request after the trace was stopped was traced:\n%s", data)
+	}
+}
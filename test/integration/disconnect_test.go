@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// disconnectTimeout 等待异步断开生效的最长时间
+const disconnectTimeout = 3 * time.Second
+
+// disconnect 通过管理API异步断开后端，请求立即返回
+func disconnect(t *testing.T, p *testutil.Proxy, backendID string) {
+	t.Helper()
+	req := map[string]string{"upstream_id": "default", "backend_id": backendID}
+	var resp struct {
+		Success bool `json:"success"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/backends/disconnect", req, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatal("disconnect request not accepted")
+	}
+}
+
+func TestDisconnectBackendDrainsTraffic(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1, b2))
+
+	for i := 0; i < 3; i++ {
+		if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+			t.Fatalf("request %d before disconnect: status %d, want 200", i, status)
+		}
+	}
+
+	disconnect(t, p, "backend1")
+
+	// 断开标记异步生效，之后的请求全部转发到backend2
+	settled := testutil.Eventually(disconnectTimeout, func() bool {
+		_, server := get(t, p.URL("/"))
+		return server == "backend2"
+	})
+	if !settled {
+		t.Fatal("requests still reach backend1 after disconnect")
+	}
+
+	before := b1.Requests()
+	for i := 0; i < 10; i++ {
+		status, server := get(t, p.URL("/"))
+		if status != http.StatusOK || server != "backend2" {
+			t.Fatalf("request %d after disconnect: status %d from %q, want 200 from backend2", i, status, server)
+		}
+	}
+	if after := b1.Requests(); after != before {
+		t.Fatalf("disconnected backend1 handled %d more requests", after-before)
+	}
+}
+
+func TestDisconnectOnlyBackend(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("status %d before disconnect, want 200", status)
+	}
+
+	disconnect(t, p, "backend1")
+
+	// 唯一的后端断开后没有可用后端，代理返回错误而不是转发
+	failed := testutil.Eventually(disconnectTimeout, func() bool {
+		status, _ := get(t, p.URL("/"))
+		return status >= http.StatusInternalServerError
+	})
+	if !failed {
+		t.Fatal("requests still succeed after the only backend was disconnected")
+	}
+}
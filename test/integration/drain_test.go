@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/proxy"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// drainStatus 查询后端的排空进度
+func drainStatus(t *testing.T, p *testutil.Proxy, backendID string) proxy.DrainStatus {
+	t.Helper()
+	var resp struct {
+		Drain proxy.DrainStatus `json:"drain"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/backends/drain?upstream=default&backend_id="+backendID, nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Drain
+}
+
+// startSlowRequest 在后台发送一个处理时间为delay的请求，返回请求结束时关闭的通道
+func startSlowRequest(t *testing.T, p *testutil.Proxy, delay time.Duration) <-chan struct{} {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := client.Get(p.URL("/?sleep=" + delay.String())); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	// 等待请求到达后端
+	if !testutil.Eventually(time.Second, func() bool {
+		return p.Server.GetUpstreamManager().GetUpstream("default").GetAllBackends()[0].GetConnections() > 0
+	}) {
+		t.Fatal("slow request never reached the backend")
+	}
+	return done
+}
+
+func TestDrainBackendWaitsForInFlightRequests(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+	done := startSlowRequest(t, p, 800*time.Millisecond)
+
+	req := map[string]string{"upstream_id": "default", "backend_id": "backend1", "timeout": "10s"}
+	if err := p.Admin(http.MethodPost, "/api/v1/backends/drain", req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	status := drainStatus(t, p, "backend1")
+	if status.State != proxy.DrainStateDraining || status.RemainingConnections != 1 {
+		t.Fatalf("while a request is in flight: %+v, want draining with 1 connection", status)
+	}
+	// 排空期间不再分配新请求
+	if code, _ := get(t, p.URL("/")); code < http.StatusInternalServerError {
+		t.Fatalf("new request during drain: status %d, want an error", code)
+	}
+
+	<-done
+	if !testutil.Eventually(2*time.Second, func() bool {
+		return drainStatus(t, p, "backend1").State == proxy.DrainStateDrained
+	}) {
+		t.Fatalf("drain did not finish: %+v", drainStatus(t, p, "backend1"))
+	}
+	backend := p.Server.GetUpstreamManager().GetUpstream("default").GetAllBackends()[0]
+	if backend.IsActive() || backend.ShouldDisconnect() {
+		t.Fatalf("drained backend: active=%v disconnect=%v, want inactive without the disconnect mark", backend.IsActive(), backend.ShouldDisconnect())
+	}
+}
+
+func TestDrainBackendTimeout(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+	done := startSlowRequest(t, p, 2*time.Second)
+	defer func() { <-done }()
+
+	req := map[string]string{"upstream_id": "default", "backend_id": "backend1", "timeout": "200ms"}
+	if err := p.Admin(http.MethodPost, "/api/v1/backends/drain", req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !testutil.Eventually(time.Second, func() bool {
+		return drainStatus(t, p, "backend1").State == proxy.DrainStateTimedOut
+	}) {
+		t.Fatalf("drain did not time out: %+v", drainStatus(t, p, "backend1"))
+	}
+	if status := drainStatus(t, p, "backend1"); status.RemainingConnections != 1 || status.FinishedAt == nil {
+		t.Fatalf("timed out drain: %+v, want 1 remaining connection", status)
+	}
+
+	// 已结束的排空不能取消
+	if err := p.Admin(http.MethodDelete, "/api/v1/backends/drain?upstream=default&backend_id=backend1", nil, nil); err == nil {
+		t.Fatal("cancelling a finished drain succeeded")
+	}
+}
@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// rawExchange 在新连接上发送原始请求数据，读取响应直到服务端关闭连接
+func rawExchange(t *testing.T, conn net.Conn, request string) string {
+	t.Helper()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil && len(data) == 0 {
+		t.Fatalf("reading the response to %q: %v", request, err)
+	}
+	return string(data)
+}
+
+// dialProxy 建立到代理的明文TCP连接
+func dialProxy(t *testing.T, p *testutil.Proxy) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", p.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// earlyRejectStats 查询请求行预检统计
+func earlyRejectStats(t *testing.T, p *testutil.Proxy) map[string]interface{} {
+	t.Helper()
+	var resp struct {
+		EarlyReject map[string]interface{} `json:"early_reject"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/early-reject", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.EarlyReject
+}
+
+func TestEarlyRejectRequestLine(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.EarlyReject = types.EarlyRejectConfig{Enabled: true, MaxRequestLine: 256}
+	p := testutil.StartProxy(t, cfg)
+
+	cases := []struct {
+		name    string
+		request string
+		status  string
+	}{
+		{"too long", "GET /" + strings.Repeat("a", 300) + " HTTP/1.1\r\nHost: x\r\n\r\n", "HTTP/1.1 414 "},
+		{"no target", "GET\r\nHost: x\r\n\r\n", "HTTP/1.1 400 "},
+		{"unknown method", "BREW / HTTP/1.1\r\nHost: x\r\n\r\n", "HTTP/1.1 501 "},
+		{"unsupported version", "GET / HTTP/2.0\r\nHost: x\r\n\r\n", "HTTP/1.1 505 "},
+		{"valid", "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n", "HTTP/1.1 200 "},
+	}
+	for _, tc := range cases {
+		if resp := rawExchange(t, dialProxy(t, p), tc.request); !strings.HasPrefix(resp, tc.status) {
+			t.Errorf("%s: response %q, want %q", tc.name, firstLine(resp), tc.status)
+		}
+	}
+
+	// 只检查连接上的第一个请求，同一次写入中的后续请求交给fasthttp正常处理
+	pipelined := "GET /?n=1 HTTP/1.1\r\nHost: x\r\n\r\nGET /?n=2 HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n"
+	if resp := rawExchange(t, dialProxy(t, p), pipelined); strings.Count(resp, "HTTP/1.1 200 ") != 2 {
+		t.Errorf("pipelined requests: %q, want two 200 responses", resp)
+	}
+
+	stats := earlyRejectStats(t, p)
+	want := map[string]float64{
+		"request_line_too_long": 1,
+		"bad_request_line":      1,
+		"method_not_allowed":    1,
+		"unsupported_version":   1,
+	}
+	for field, n := range want {
+		if stats[field] != n {
+			t.Errorf("%s = %v, want %v", field, stats[field], n)
+		}
+	}
+	if stats["enabled"] != true || stats["max_request_line"] != float64(256) {
+		t.Errorf("stats %v, want the configured settings", stats)
+	}
+	if checked, _ := stats["checked"].(float64); checked < float64(len(cases)+1) {
+		t.Errorf("checked = %v, want at least %d connections", stats["checked"], len(cases)+1)
+	}
+}
+
+func TestEarlyRejectTLS(t *testing.T) {
+	skipShort(t)
+
+	now := time.Now()
+	certFile, keyFile := writeCert(t, t.TempDir(), "server", now.Add(-time.Hour), now.Add(time.Hour))
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.SSL = types.SSLConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}
+	cfg.Server.EarlyReject = types.EarlyRejectConfig{Enabled: true}
+	p := testutil.StartProxy(t, cfg)
+
+	dialTLS := func() net.Conn {
+		conn, err := tls.Dial("tcp", p.Addr, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// TLS连接在解密之后检查请求行
+	if resp := rawExchange(t, dialTLS(), "BREW / HTTP/1.1\r\nHost: x\r\n\r\n"); !strings.HasPrefix(resp, "HTTP/1.1 501 ") {
+		t.Fatalf("unknown method over TLS: %q, want 501", firstLine(resp))
+	}
+	if resp := rawExchange(t, dialTLS(), "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n"); !strings.HasPrefix(resp, "HTTP/1.1 200 ") {
+		t.Fatalf("valid request over TLS: %q, want 200", firstLine(resp))
+	}
+	if stats := earlyRejectStats(t, p); stats["method_not_allowed"] != float64(1) {
+		t.Fatalf("method_not_allowed = %v, want 1", stats["method_not_allowed"])
+	}
+}
+
+// firstLine 响应的状态行
+func firstLine(resp string) string {
+	line, _, _ := strings.Cut(resp, "\r\n")
+	return line
+}
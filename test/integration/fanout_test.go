@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// startFanOutProxy 启动带有扇出路由/aggregate/的代理，users和orders两个上游各有一个后端
+func startFanOutProxy(t *testing.T, fanOut *types.FanOutConfig) *testutil.Proxy {
+	t.Helper()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Backends["users"] = []*types.Backend{testutil.StartBackend(t, "users1").Config()}
+	cfg.Backends["orders"] = []*types.Backend{testutil.StartBackend(t, "orders1").Config()}
+	cfg.Routing["aggregate"] = &types.RoutingRule{Path: "/aggregate/", FanOut: fanOut}
+	return testutil.StartProxy(t, cfg)
+}
+
+func TestFanOutMerge(t *testing.T) {
+	skipShort(t)
+
+	p := startFanOutProxy(t, &types.FanOutConfig{
+		Mode: types.FanOutMerge,
+		Targets: []types.FanOutTarget{
+			{Name: "user", Upstream: "users", Path: "/users/$arg_id", Required: true},
+			{Name: "orders", Upstream: "orders", Path: "/orders?status=503"},
+		},
+	})
+
+	resp, err := client.Get(p.URL("/aggregate/?id=42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		User   *struct{ Server, Path string } `json:"user"`
+		Orders *json.RawMessage               `json:"orders"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || body.User == nil || body.User.Server != "users1" || body.User.Path != "/users/42" {
+		t.Fatalf("status %d, user = %+v, want users1 response for /users/42", resp.StatusCode, body.User)
+	}
+	// 失败的可选目标为null
+	if body.Orders != nil || resp.Header.Get("X-Fanout-Failed") != "orders" {
+		t.Fatalf("orders = %v, X-Fanout-Failed = %q, want null and orders", body.Orders, resp.Header.Get("X-Fanout-Failed"))
+	}
+}
+
+func TestFanOutRequiredTargetFails(t *testing.T) {
+	skipShort(t)
+
+	p := startFanOutProxy(t, &types.FanOutConfig{
+		Mode:     types.FanOutMerge,
+		Template: `{"data": $fanout_user}`,
+		Targets: []types.FanOutTarget{
+			{Name: "user", Upstream: "users", Path: "/users?status=500", Required: true},
+			{Name: "orders", Upstream: "orders"},
+		},
+	})
+	if status, _ := get(t, p.URL("/aggregate/")); status != http.StatusBadGateway {
+		t.Fatalf("status %d, want 502 when a required target fails", status)
+	}
+}
+
+func TestFanOutFirstSuccess(t *testing.T) {
+	skipShort(t)
+
+	p := startFanOutProxy(t, &types.FanOutConfig{
+		Targets: []types.FanOutTarget{
+			{Name: "slow", Upstream: "users", Path: "/?sleep=500ms"},
+			{Name: "fast", Upstream: "orders"},
+		},
+	})
+
+	resp, err := client.Get(p.URL("/aggregate/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Server") != "orders1" || resp.Header.Get("X-Fanout-Target") != "fast" {
+		t.Fatalf("status %d from %s (target %s), want the fast target's response",
+			resp.StatusCode, resp.Header.Get("X-Server"), resp.Header.Get("X-Fanout-Target"))
+	}
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestForwardAuth(t *testing.T) {
+	skipShort(t)
+
+	// 认证服务：携带正确的令牌时返回用户身份，否则跳转到登录页
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer lower" {
+			w.Header()["x-auth-user"] = []string{"bob"} // 非规范大小写的响应头
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.Header().Set("Location", "https://login.example.com/?rd="+r.Header.Get("X-Forwarded-Uri"))
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Auth-User", "alice")
+	}))
+	defer auth.Close()
+
+	// 上游返回收到的身份头
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Auth-User"))
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("echo", upstream)}
+	cfg.Routing["default"].ForwardAuth = &types.ForwardAuthConfig{
+		Address:         auth.URL + "/verify",
+		ResponseHeaders: []string{"X-Auth-User"},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	noRedirect := &http.Client{
+		Timeout:       client.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	do := func(token, user string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL("/private?x=1"), nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if user != "" {
+			req.Header.Set("X-Auth-User", user)
+		}
+		resp, err := noRedirect.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// 未认证的请求返回认证服务的跳转，客户端伪造的身份头不能绕过认证
+	resp, _ := do("", "mallory")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "https://login.example.com/?rd=/private?x=1" {
+		t.Fatalf("unauthenticated: status %d, Location %q, want the auth service redirect", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// 认证通过时上游收到认证服务返回的身份，而不是客户端传入的值
+	resp, body := do("good", "mallory")
+	if resp.StatusCode != http.StatusOK || body != "alice" {
+		t.Fatalf("authenticated: status %d, upstream saw user %q, want 200 and alice", resp.StatusCode, body)
+	}
+
+	// 认证服务返回的身份头名称不区分大小写
+	if resp, body := do("lower", ""); resp.StatusCode != http.StatusOK || body != "bob" {
+		t.Fatalf("lowercase identity header: status %d, upstream saw user %q, want 200 and bob", resp.StatusCode, body)
+	}
+
+	// 认证服务不可用时返回502
+	auth.Close()
+	if resp, _ := do("good", ""); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("auth service down: status %d, want 502", resp.StatusCode)
+	}
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestResponseHeaderFilter(t *testing.T) {
+	skipShort(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Internal-Host", "node-7")
+		w.Header().Set("X-Internal-Keep", "yes")
+		w.Header().Set("X-Public", "hello")
+		w.Header().Set("X-Debug", "on")
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "filtered body")
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("backend1", upstream)}
+	cfg.Routing["deny"] = &types.RoutingRule{
+		Path:                 "/deny/",
+		Upstream:             "default",
+		LoadBalancer:         types.LeastConnectionsWeight,
+		ResponseHeaderFilter: &types.HeaderFilterConfig{Allow: []string{"X-Internal-Keep"}, Deny: []string{"x-internal-*", "X-Debug"}},
+	}
+	cfg.Routing["allow"] = &types.RoutingRule{
+		Path:                 "/allow/",
+		Upstream:             "default",
+		LoadBalancer:         types.LeastConnectionsWeight,
+		ResponseHeaderFilter: &types.HeaderFilterConfig{Allow: []string{"X-Public"}, DefaultDeny: true},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	fetch := func(path string) (http.Header, string) {
+		t.Helper()
+		resp, err := client.Get(p.URL(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.Header, string(body)
+	}
+
+	// deny按前缀且不区分大小写删除，allow中的头优先保留
+	h, body := fetch("/deny/a")
+	if h.Get("X-Internal-Host") != "" || h.Get("X-Debug") != "" {
+		t.Fatalf("deny route headers %v, want the denied headers removed", h)
+	}
+	if h.Get("X-Internal-Keep") != "yes" || h.Get("X-Public") != "hello" {
+		t.Fatalf("deny route headers %v, want allowed and unlisted headers kept", h)
+	}
+	if body != "filtered body" {
+		t.Fatalf("deny route body %q", body)
+	}
+
+	// default_deny只转发allow中的头，描述响应体的头总是保留
+	h, body = fetch("/allow/a")
+	if h.Get("X-Public") != "hello" || h.Get("X-Internal-Keep") != "" || h.Get("X-Debug") != "" {
+		t.Fatalf("default_deny route headers %v, want only X-Public", h)
+	}
+	if h.Get("Content-Type") != "text/plain" || body != "filtered body" {
+		t.Fatalf("default_deny route: Content-Type %q, body %q; want the body headers kept", h.Get("Content-Type"), body)
+	}
+
+	// 未配置过滤的路由转发全部响应头
+	if h, _ := fetch("/"); h.Get("X-Internal-Host") != "node-7" {
+		t.Fatalf("unfiltered route headers %v, want X-Internal-Host", h)
+	}
+}
@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// probeUpstream 主动探测default上游的全部后端，返回各后端的健康状态和错误信息
+func probeUpstream(t *testing.T, p *testutil.Proxy) map[string]string {
+	t.Helper()
+	var report struct {
+		Results []struct {
+			BackendID string `json:"backend_id"`
+			Type      string `json:"type"`
+			Healthy   bool   `json:"healthy"`
+			Error     string `json:"error"`
+		} `json:"results"`
+	}
+	// 有不健康的后端时返回503，响应体相同
+	resp, err := client.Get(p.AdminURL("/api/v1/upstreams/default/health"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]string)
+	for _, r := range report.Results {
+		status := r.Type + " unhealthy: " + r.Error
+		if r.Healthy {
+			status = r.Type + " healthy"
+		}
+		results[r.BackendID] = status
+	}
+	return results
+}
+
+func TestTCPHealthCheck(t *testing.T) {
+	skipShort(t)
+
+	up := testutil.StartBackend(t, "up")
+	down := testutil.StartBackend(t, "down")
+	cfg := testutil.NewConfig(up, down)
+	for _, b := range cfg.Backends["default"] {
+		b.HealthCheck = &types.HealthCheck{Type: types.HealthCheckTCP}
+	}
+	p := testutil.StartProxy(t, cfg)
+	down.Close()
+
+	results := probeUpstream(t, p)
+	if results["up"] != "tcp healthy" {
+		t.Fatalf("up: %s, want tcp healthy", results["up"])
+	}
+	if results["down"] == "tcp healthy" {
+		t.Fatal("down: tcp healthy after backend closed")
+	}
+}
+
+func TestScriptHealthCheck(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	cfg := testutil.NewConfig(b1, b2)
+	// 命令通过环境变量获得后端端口，只有backend1的端口能通过检查
+	for _, b := range cfg.Backends["default"] {
+		b.HealthCheck = &types.HealthCheck{
+			Type:    types.HealthCheckScript,
+			Command: []string{"sh", "-c", `test "$SPEEDMIMI_BACKEND_PORT" = ` + strconv.Itoa(b1.Port) + ` || { echo not backend1; exit 3; }`},
+		}
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	results := probeUpstream(t, p)
+	if results["backend1"] != "script healthy" {
+		t.Fatalf("backend1: %s, want script healthy", results["backend1"])
+	}
+	if want := "script unhealthy: command exited with status 3: not backend1"; results["backend2"] != want {
+		t.Fatalf("backend2: %s, want %s", results["backend2"], want)
+	}
+
+	// script健康检查不能通过管理API设置
+	backend := b1.Config()
+	backend.ID = "backend3"
+	backend.HealthCheck = &types.HealthCheck{Type: types.HealthCheckScript, Command: []string{"true"}}
+	err := p.Admin(http.MethodPost, "/api/v1/backends/add", map[string]interface{}{"upstream": "default", "backend": backend}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("adding a backend with a script health check: %v, want status 400", err)
+	}
+
+	// 整个配置的更新不返回命令，原样写回时沿用原命令；新增或修改script检查时拒绝
+	var got struct {
+		Config *types.Config `json:"config"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/config", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if hc := got.Config.Backends["default"][0].HealthCheck; hc == nil || len(hc.Command) != 0 {
+		t.Fatalf("GET /api/v1/config health check %+v, want the command hidden", hc)
+	}
+	if err := p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": got.Config}, nil); err != nil {
+		t.Fatalf("round-tripping the config: %v", err)
+	}
+	if cmd := p.Config.GetConfig().Backends["default"][0].HealthCheck.Command; len(cmd) != 3 {
+		t.Fatalf("command after round trip %q, want the original command kept", cmd)
+	}
+	added := b1.Config()
+	added.ID = "backend3"
+	added.HealthCheck = &types.HealthCheck{Type: types.HealthCheckScript}
+	got.Config.Backends["default"] = append(got.Config.Backends["default"], added)
+	err = p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": got.Config}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("adding a script health check through PUT /api/v1/config: %v, want status 400", err)
+	}
+	imported := config.CloneConfig(p.Config.GetConfig())
+	hc := *imported.Backends["default"][0].HealthCheck
+	hc.Command = []string{"touch", "/tmp/pwned"}
+	changed := b1.Config()
+	changed.HealthCheck = &hc
+	imported.Backends["default"] = []*types.Backend{changed}
+	data, _ := yaml.Marshal(imported)
+	resp, err := client.Post(p.AdminURL("/api/v1/config/import"), "application/yaml", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("importing a changed script command: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestIPv6BackendAddress(t *testing.T) {
+	skipShort(t)
+
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server", "ipv6")
+	}))
+	upstream.Listener = ln
+	upstream.Start()
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("ipv6", upstream)}
+	p := testutil.StartProxy(t, cfg)
+
+	// IPv6地址的主机部分带方括号，代理请求和主动探测都能连接到后端
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "ipv6" {
+		t.Fatalf("status %d from %q, want 200 from the IPv6 backend", status, server)
+	}
+	if results := probeUpstream(t, p); results["ipv6"] != "http healthy" {
+		t.Fatalf("ipv6: %s, want http healthy", results["ipv6"])
+	}
+
+	var drained struct {
+		Backends []struct {
+			Address string `json:"address"`
+		} `json:"backends"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/backends/drain-host", map[string]interface{}{"host": "::1", "drain": false}, &drained); err != nil {
+		t.Fatal(err)
+	}
+	if want := net.JoinHostPort("::1", strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)); len(drained.Backends) != 1 || drained.Backends[0].Address != want {
+		t.Fatalf("drain-host backends %+v, want address %s", drained.Backends, want)
+	}
+}
@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestRouteHealthRequirement(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	b3 := testutil.StartBackend(t, "backend3")
+	lite := testutil.StartBackend(t, "lite")
+	cfg := testutil.NewConfig(b1, b2, b3)
+	cfg.Backends["lite"] = []*types.Backend{lite.Config()}
+	cfg.Cache.Enabled = true
+	route := func(path string, req *types.HealthRequirementConfig) *types.RoutingRule {
+		return &types.RoutingRule{Path: path, Upstream: "default", LoadBalancer: types.LeastConnectionsWeight, HealthRequirement: req}
+	}
+	cfg.Routing["status"] = route("/status/", &types.HealthRequirementConfig{MinHealthy: 2, Body: "degraded", RetryAfter: 10 * time.Second})
+	cfg.Routing["alt"] = route("/alt/", &types.HealthRequirementConfig{MinHealthyPercent: 50, Fallback: types.HealthFallbackUpstream, Upstream: "lite"})
+	cfg.Routing["cached"] = route("/cached/", &types.HealthRequirementConfig{MinHealthy: 2, Fallback: types.HealthFallbackCache})
+	cfg.Routing["cached"].Cache = &types.RouteCacheConfig{Enabled: true, TTL: time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	override := func(id, value string) {
+		t.Helper()
+		body := map[string]string{"upstream_id": "default", "backend_id": id, "override": value}
+		if err := p.Admin(http.MethodPost, "/api/v1/health/override", body, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 健康后端足够时正常转发，同时缓存响应
+	for _, path := range []string{"/status/a", "/alt/a", "/cached/a"} {
+		if status, server := get(t, p.URL(path)); status != http.StatusOK || server == "lite" {
+			t.Fatalf("%s with all backends healthy: status %d from %q, want 200 from the primary upstream", path, status, server)
+		}
+	}
+
+	override("backend1", "unhealthy")
+	override("backend2", "unhealthy")
+
+	// status：返回配置的状态页面
+	resp, err := client.Get(p.URL("/status/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != "degraded" || resp.Header.Get("Retry-After") != "10" {
+		t.Fatalf("degraded status route: status %d, Retry-After %q, body %q; want the configured page", resp.StatusCode, resp.Header.Get("Retry-After"), body)
+	}
+
+	// upstream：转发到替代上游；没有要求的路由继续使用剩余的健康后端
+	if status, server := get(t, p.URL("/alt/a")); status != http.StatusOK || server != "lite" {
+		t.Fatalf("degraded upstream route: status %d from %q, want 200 from lite", status, server)
+	}
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "backend3" {
+		t.Fatalf("route without a requirement: status %d from %q, want 200 from backend3", status, server)
+	}
+
+	// cache：返回已过期的缓存条目，没有缓存时返回状态页面
+	time.Sleep(10 * time.Millisecond)
+	resp, err = client.Get(p.URL("/cached/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != "STALE" {
+		t.Fatalf("degraded cache route: status %d, X-Cache %q; want the stale cached response", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+	if status, _ := get(t, p.URL("/cached/b")); status != http.StatusServiceUnavailable {
+		t.Fatalf("degraded cache route without a cached response: status %d, want 503", status)
+	}
+
+	// 恢复后重新转发到主上游
+	override("backend1", "none")
+	if status, server := get(t, p.URL("/status/a")); status != http.StatusOK || server == "lite" {
+		t.Fatalf("after recovery: status %d from %q, want 200 from the primary upstream", status, server)
+	}
+}
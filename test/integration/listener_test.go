@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// getLargeHeader 在新连接上发送带有指定大小请求头的请求，返回状态码
+func getLargeHeader(t *testing.T, url string, size int) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Padding", strings.Repeat("a", size))
+	req.Close = true
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
+
+func TestListenerReloadUnderLoad(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.ReadBufferSize = 64 << 10
+	p := testutil.StartProxy(t, cfg)
+
+	if status := getLargeHeader(t, p.URL("/"), 8<<10); status != http.StatusOK {
+		t.Fatalf("status %d for an 8KB header before the reload, want 200", status)
+	}
+
+	// 重载前开始的慢请求由旧服务器处理完成
+	slow := make(chan int, 1)
+	go func() {
+		resp, err := client.Get(p.URL("/?sleep=500ms"))
+		if err != nil {
+			slow <- 0
+			return
+		}
+		resp.Body.Close()
+		slow <- resp.StatusCode
+	}()
+
+	// 持续发送请求，重载期间不应有失败的请求
+	var sent, failed atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				sent.Add(1)
+				resp, err := client.Get(p.URL("/?sleep=5ms"))
+				if err != nil {
+					failed.Add(1)
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := p.Config.Update(func(cfg *types.Config) error {
+		cfg.Server.ReadBufferSize = 4 << 10
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	if n := failed.Load(); n != 0 {
+		t.Fatalf("%d of %d requests failed during the listener reload", n, sent.Load())
+	}
+	if status := <-slow; status != http.StatusOK {
+		t.Fatalf("in-flight request started before the reload: status %d, want 200", status)
+	}
+
+	// 新连接由使用新设置的服务器处理
+	if status := getLargeHeader(t, p.URL("/"), 8<<10); status != http.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("status %d for an 8KB header after the reload, want 431 from the new read_buffer_size", status)
+	}
+	if status := getLargeHeader(t, p.URL("/"), 1<<10); status != http.StatusOK {
+		t.Fatalf("status %d for a small header after the reload, want 200", status)
+	}
+}
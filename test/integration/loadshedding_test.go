@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// loadSheddingState 负载卸载的状态和统计
+type loadSheddingState struct {
+	Limit      int              `json:"limit"`
+	Inflight   int              `json:"inflight"`
+	Overloaded bool             `json:"overloaded"`
+	Shed       map[string]int64 `json:"shed"`
+}
+
+func TestLoadShedding(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.LoadShedding = types.LoadSheddingConfig{
+		Enabled:        true,
+		LatencyTarget:  100 * time.Millisecond,
+		Interval:       200 * time.Millisecond,
+		MinLimit:       2,
+		MaxLimit:       4,
+		IncreaseStep:   1,
+		DecreaseFactor: 0.5,
+	}
+	route := func(path, priority string) *types.RoutingRule {
+		return &types.RoutingRule{Path: path, Upstream: "default", LoadBalancer: types.LeastConnectionsWeight, Priority: priority}
+	}
+	cfg.Routing["reports"] = route("/reports/", types.PriorityLow)
+	cfg.Routing["checkout"] = route("/checkout/", types.PriorityCritical)
+	p := testutil.StartProxy(t, cfg)
+
+	state := func() loadSheddingState {
+		t.Helper()
+		var s loadSheddingState
+		if err := p.Admin(http.MethodGet, "/api/v1/load-shedding", nil, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// 两个慢请求占用并发上限的一半
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := client.Get(p.URL("/slow?sleep=1s")); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	if !testutil.Eventually(2*time.Second, func() bool { return state().Inflight == 2 }) {
+		t.Fatal("slow requests never became in flight")
+	}
+
+	// 低优先级路由只能使用上限的low_priority_share，最先被拒绝；普通和关键路由不受影响
+	resp, err := client.Get(p.URL("/reports/daily"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("low priority request: status %d, Retry-After %q; want 503 with Retry-After 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	for _, path := range []string{"/", "/checkout/pay"} {
+		if status, _ := get(t, p.URL(path)); status != http.StatusOK {
+			t.Fatalf("%s: status %d, want 200", path, status)
+		}
+	}
+	wg.Wait()
+
+	// 请求延迟p99超过目标后降低并发上限
+	if !testutil.Eventually(2*time.Second, func() bool {
+		s := state()
+		return s.Overloaded && s.Limit < 4
+	}) {
+		t.Fatalf("load shedding state %+v after slow requests, want overloaded with a lower limit", state())
+	}
+	if s := state(); s.Shed[types.PriorityLow] != 1 || s.Shed[types.PriorityNormal] != 0 {
+		t.Fatalf("shed counts %v, want one low priority request", s.Shed)
+	}
+
+	// 延迟恢复后并发上限逐步回到max_limit
+	if !testutil.Eventually(3*time.Second, func() bool { return state().Limit == 4 }) {
+		t.Fatalf("load shedding state %+v, want the limit restored to 4", state())
+	}
+}
@@ -0,0 +1,319 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/logsink"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// udpLines 监听UDP，返回地址和收到的数据报
+func udpLines(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	lines := make(chan string, 100)
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			lines <- string(buf[:n])
+		}
+	}()
+	return conn.LocalAddr().String(), lines
+}
+
+// tcpListener 监听TCP，每个连接交给handle处理
+func tcpListener(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func receive(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for log line")
+		return ""
+	}
+}
+
+func TestAccessLogSyslog(t *testing.T) {
+	skipShort(t)
+
+	addr, datagrams := udpLines(t)
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.AccessLog = types.AccessLogConfig{
+		Enabled: true,
+		Path:    "syslog://" + addr + "?facility=local3&app_name=edge",
+		Format:  "$status $uri",
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	// RFC5424：local3（19）*8 + info（6）= 158
+	get(t, p.URL("/udp"))
+	udpFormat := regexp.MustCompile(`^<158>1 \d{4}-\d\d-\d\dT\S+ \S+ edge \d+ access - 200 /udp$`)
+	if msg := receive(t, datagrams); !udpFormat.MatchString(msg) {
+		t.Fatalf("syslog message %q does not match %s", msg, udpFormat)
+	}
+
+	// 改为syslog over TCP，按八位组计数分帧
+	frames := make(chan string, 100)
+	tcpAddr := tcpListener(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(size))
+			if err != nil {
+				frames <- "bad frame length " + size
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			frames <- string(msg)
+		}
+	})
+	newCfg := config.CloneConfig(p.Config.GetConfig())
+	newCfg.AccessLog.Path = "syslog+tcp://" + tcpAddr
+	if err := p.Config.UpdateConfig(newCfg); err != nil {
+		t.Fatal(err)
+	}
+	get(t, p.URL("/tcp/1"))
+	get(t, p.URL("/tcp/2"))
+	tcpFormat := regexp.MustCompile(`^<134>1 \S+ \S+ speedmimi \d+ access - 200 /tcp/\d$`)
+	for _, want := range []string{"/tcp/1", "/tcp/2"} {
+		if msg := receive(t, frames); !tcpFormat.MatchString(msg) || !strings.HasSuffix(msg, want) {
+			t.Fatalf("syslog frame %q, want a local0 message ending with %s", msg, want)
+		}
+	}
+	if !testutil.Eventually(time.Second, func() bool { return accessLogStats(t, p).Shipped == 2 }) {
+		t.Fatalf("stats %+v, want 2 shipped lines", accessLogStats(t, p))
+	}
+
+	// 无效的目标被拒绝
+	newCfg = config.CloneConfig(p.Config.GetConfig())
+	newCfg.AccessLog.Path = "syslog://" + tcpAddr + "?facility=nope"
+	if err := p.Config.UpdateConfig(newCfg); err == nil || !strings.Contains(err.Error(), "facility") {
+		t.Fatalf("invalid facility accepted: %v", err)
+	}
+}
+
+// fakeKafka 只实现Metadata v1和Produce v3的单分区broker，返回收到的记录值
+func fakeKafka(t *testing.T, topic string) (string, <-chan string) {
+	t.Helper()
+	records := make(chan string, 100)
+	addr := tcpListener(t, func(conn net.Conn) {
+		for {
+			var size int32
+			if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+				return
+			}
+			req := make([]byte, size)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			apiKey := binary.BigEndian.Uint16(req)
+			version := binary.BigEndian.Uint16(req[2:])
+			correlation := req[4:8]
+			clientLen := int(binary.BigEndian.Uint16(req[8:]))
+			body := req[10+clientLen:]
+
+			resp := append([]byte(nil), correlation...)
+			switch {
+			case apiKey == 3 && version == 1:
+				host, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+				portNum, _ := strconv.Atoi(port)
+				resp = binary.BigEndian.AppendUint32(resp, 1) // brokers
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = appendKafkaString(resp, host)
+				resp = binary.BigEndian.AppendUint32(resp, uint32(portNum))
+				resp = binary.BigEndian.AppendUint16(resp, 0xffff) // rack
+				resp = binary.BigEndian.AppendUint32(resp, 0)      // controller
+				resp = binary.BigEndian.AppendUint32(resp, 1)      // topics
+				resp = binary.BigEndian.AppendUint16(resp, 0)
+				resp = appendKafkaString(resp, topic)
+				resp = append(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 1) // partitions
+				resp = binary.BigEndian.AppendUint16(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+			case apiKey == 0 && version == 3:
+				values, err := decodeProduce(body, topic)
+				if err != nil {
+					records <- "bad produce request: " + err.Error()
+					return
+				}
+				for _, v := range values {
+					records <- v
+				}
+				resp = binary.BigEndian.AppendUint32(resp, 1)
+				resp = appendKafkaString(resp, topic)
+				resp = binary.BigEndian.AppendUint32(resp, 1)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = binary.BigEndian.AppendUint16(resp, 0)
+				resp = binary.BigEndian.AppendUint64(resp, 0)
+				resp = binary.BigEndian.AppendUint64(resp, ^uint64(0))
+				resp = binary.BigEndian.AppendUint32(resp, 0) // throttle
+			default:
+				records <- fmt.Sprintf("unexpected api %d v%d", apiKey, version)
+				return
+			}
+			out := binary.BigEndian.AppendUint32(nil, uint32(len(resp)))
+			if _, err := conn.Write(append(out, resp...)); err != nil {
+				return
+			}
+		}
+	})
+	return addr, records
+}
+
+func appendKafkaString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// decodeProduce 解析Produce v3请求中的RecordBatch，校验CRC32C后返回记录值
+func decodeProduce(body []byte, topic string) ([]string, error) {
+	// transactional_id(2) acks(2) timeout(4) topics(4)
+	body = body[12:]
+	nameLen := int(binary.BigEndian.Uint16(body))
+	if name := string(body[2 : 2+nameLen]); name != topic {
+		return nil, fmt.Errorf("topic %q", name)
+	}
+	// partitions(4) index(4) records size(4)
+	body = body[2+nameLen+12:]
+	if body[16] != 2 {
+		return nil, fmt.Errorf("magic %d", body[16])
+	}
+	if crc := binary.BigEndian.Uint32(body[17:]); crc != crc32.Checksum(body[21:], crc32.MakeTable(crc32.Castagnoli)) {
+		return nil, fmt.Errorf("crc mismatch")
+	}
+	count := int(binary.BigEndian.Uint32(body[57:]))
+	r := body[61:]
+	varint := func() int64 {
+		v, n := binary.Varint(r)
+		r = r[n:]
+		return v
+	}
+	var values []string
+	for i := 0; i < count; i++ {
+		varint()  // length
+		r = r[1:] // attributes
+		varint()  // timestamp_delta
+		varint()  // offset_delta
+		varint()  // key
+		n := varint()
+		values = append(values, string(r[:n]))
+		r = r[n:]
+		varint() // headers
+	}
+	return values, nil
+}
+
+func TestAccessLogKafka(t *testing.T) {
+	skipShort(t)
+
+	addr, records := fakeKafka(t, "access-logs")
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.AccessLog = types.AccessLogConfig{
+		Enabled: true,
+		Path:    "kafka://" + addr + "/access-logs?acks=all",
+		Format:  "$status $uri",
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	for i := 0; i < 3; i++ {
+		get(t, p.URL(fmt.Sprintf("/kafka/%d", i)))
+	}
+	for i := 0; i < 3; i++ {
+		if got, want := receive(t, records), fmt.Sprintf("200 /kafka/%d", i); got != want {
+			t.Fatalf("record %q, want %q", got, want)
+		}
+	}
+	if !testutil.Eventually(time.Second, func() bool { return accessLogStats(t, p).Shipped == 3 }) {
+		t.Fatalf("stats %+v, want 3 shipped lines", accessLogStats(t, p))
+	}
+}
+
+func TestErrorLogCapture(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	addr := tcpListener(t, func(conn net.Conn) {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			mu.Lock()
+			received = append(received, scanner.Text())
+			mu.Unlock()
+		}
+	})
+
+	restore, err := logsink.Capture("tcp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("[TEST] Failed to reach backend\n")
+	log.Printf("[TEST] started")
+	restore()
+
+	// 标准输出和log包的输出都被发送
+	var got string
+	if !testutil.Eventually(3*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		got = strings.Join(received, "\n")
+		return strings.Contains(got, "[TEST] Failed to reach backend") && strings.Contains(got, "[TEST] started")
+	}) {
+		t.Fatalf("captured lines %q, want stdout and log output", got)
+	}
+
+	if logsink.ErrorSeverity([]byte("[TEST] Failed to reach backend")) != logsink.SeverityErr ||
+		logsink.ErrorSeverity([]byte("[TEST] started")) != logsink.SeverityInfo {
+		t.Fatal("diagnostic lines mapped to unexpected severities")
+	}
+}
@@ -0,0 +1,43 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestLongPollTimeout(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	cfg := testutil.NewConfig(b1, b2)
+	cfg.Routing["default"].Retry = &types.RetryConfig{MaxAttempts: 2}
+	cfg.Routing["default"].LongPoll = &types.LongPollConfig{
+		Timeout:       time.Second,
+		Margin:        500 * time.Millisecond,
+		TimeoutStatus: http.StatusNoContent,
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	// 后端在超时前返回时正常转发
+	if status, _ := get(t, p.URL("/?sleep=300ms")); status != http.StatusOK {
+		t.Fatalf("status %d, want 200 for a poll answered before the timeout", status)
+	}
+
+	// 后端超过timeout仍未返回时返回timeout_status，且不重试到另一个后端
+	start := time.Now()
+	status, _ := get(t, p.URL("/?sleep=3s"))
+	if status != http.StatusNoContent {
+		t.Fatalf("status %d, want 204 on long poll timeout", status)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("long poll took %v, want about the 1s timeout", elapsed)
+	}
+	if n := b1.Requests() + b2.Requests(); n != 2 {
+		t.Fatalf("backends received %d requests, want 2 (timed out poll not retried)", n)
+	}
+}
@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// setMaintenance 开启或关闭上游或路由的维护模式
+func setMaintenance(t *testing.T, p *testutil.Proxy, req map[string]interface{}) {
+	t.Helper()
+	if err := p.Admin(http.MethodPost, "/api/v1/maintenance", req, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	skipShort(t)
+
+	primary := testutil.StartBackend(t, "primary")
+	backup := testutil.StartBackend(t, "backup")
+	cfg := testutil.NewConfig(primary)
+	cfg.Backends["backup"] = []*types.Backend{backup.Config()}
+	cfg.Routing["default"].FallbackUpstreams = []string{"backup"}
+	cfg.Routing["api"] = &types.RoutingRule{Path: "/api/", Upstream: "default", LoadBalancer: types.LeastConnectionsWeight}
+	p := testutil.StartProxy(t, cfg)
+
+	// 路由维护期间返回维护页面和Retry-After，其他路由不受影响
+	setMaintenance(t, p, map[string]interface{}{
+		"scope":       "route",
+		"name":        "api",
+		"enabled":     true,
+		"retry_after": 30,
+		"body":        "back soon",
+	})
+	resp, err := client.Get(p.URL("/api/users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "30" || !strings.Contains(string(body), "back soon") {
+		t.Fatalf("route in maintenance: status %d, Retry-After %q, body %q; want the maintenance page", resp.StatusCode, resp.Header.Get("Retry-After"), body)
+	}
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "primary" {
+		t.Fatalf("other route: status %d from %q, want 200 from primary", status, server)
+	}
+
+	var list struct {
+		Maintenance []struct {
+			Scope string `json:"scope"`
+			Name  string `json:"name"`
+		} `json:"maintenance"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/maintenance", nil, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Maintenance) != 1 || list.Maintenance[0].Scope != "route" || list.Maintenance[0].Name != "api" {
+		t.Fatalf("maintenance list %+v, want the api route", list.Maintenance)
+	}
+	setMaintenance(t, p, map[string]interface{}{"scope": "route", "name": "api", "enabled": false})
+	if status, server := get(t, p.URL("/api/users")); status != http.StatusOK || server != "primary" {
+		t.Fatalf("after leaving maintenance: status %d from %q, want 200 from primary", status, server)
+	}
+
+	// 上游维护期间有备用上游的路由转发到备用上游，没有备用上游的路由返回维护页面
+	setMaintenance(t, p, map[string]interface{}{"scope": "upstream", "name": "default", "enabled": true})
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "backup" {
+		t.Fatalf("upstream in maintenance with a fallback: status %d from %q, want 200 from backup", status, server)
+	}
+	if status, _ := get(t, p.URL("/api/users")); status != http.StatusServiceUnavailable {
+		t.Fatalf("upstream in maintenance without a fallback: status %d, want 503", status)
+	}
+
+	if err := p.Admin(http.MethodPost, "/api/v1/maintenance", map[string]interface{}{"scope": "upstream", "name": "missing", "enabled": true}, nil); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("maintenance for an unknown upstream: %v, want status 404", err)
+	}
+}
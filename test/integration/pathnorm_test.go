@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestPathNormalization(t *testing.T) {
+	skipShort(t)
+
+	public := testutil.StartBackend(t, "public")
+	admin := testutil.StartBackend(t, "admin")
+	cfg := testutil.NewConfig(public)
+	cfg.Backends["admin"] = []*types.Backend{admin.Config()}
+	cfg.Routing["admin"] = &types.RoutingRule{Path: "/admin/", Upstream: "admin"}
+	p := testutil.StartProxy(t, cfg)
+
+	// 编码的..和重复的斜杠在路由前被解析，转发的路径与路由匹配的路径一致
+	for _, path := range []string{"/public/%2e%2e/admin/users", "//admin//users", "/public/./../admin/users"} {
+		resp, err := client.Get(p.URL(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Server string `json:"server"`
+			Path   string `json:"path"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || body.Server != "admin" || body.Path != "/admin/users" {
+			t.Fatalf("%s: status %d, served by %q with path %q; want admin backend with /admin/users",
+				path, resp.StatusCode, body.Server, body.Path)
+		}
+	}
+
+	// 控制字符（包括编码的）返回400
+	for _, path := range []string{"/a%00b", "/a%0d%0aX-Injected:%201"} {
+		if status := rawStatus(t, p.Addr, path); status != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", path, status)
+		}
+	}
+
+	var stats struct {
+		Enabled   bool  `json:"enabled"`
+		Rewritten int64 `json:"rewritten"`
+		Rejected  int64 `json:"rejected"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/path-normalization", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Enabled || stats.Rewritten != 3 || stats.Rejected != 2 {
+		t.Fatalf("path normalization stats = %+v, want 3 rewritten and 2 rejected", stats)
+	}
+}
+
+// rawStatus 按原样发送请求路径并返回状态码
+func rawStatus(t *testing.T, addr, path string) int {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
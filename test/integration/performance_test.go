@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestPerformanceReport(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+
+	perf := &types.PerformanceInfo{
+		CPUUsage:    42.5,
+		MemoryUsage: 30,
+		LoadAvg1:    1.5,
+		Timestamp:   time.Now().Unix(),
+	}
+	if err := b.ReportPerformance(p.AdminAddr, "default", perf); err != nil {
+		t.Fatal(err)
+	}
+
+	// 上报异步处理，轮询后端列表直到性能信息可见
+	var reported *types.PerformanceInfo
+	ok := testutil.Eventually(3*time.Second, func() bool {
+		var resp struct {
+			Backends []*types.Backend `json:"backends"`
+		}
+		if err := p.Admin(http.MethodGet, "/api/v1/backends?upstream=default", nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, backend := range resp.Backends {
+			if backend.ID == b.ID && backend.GetPerformance() != nil {
+				reported = backend.GetPerformance()
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		t.Fatal("reported performance not visible in backend list")
+	}
+	if reported.CPUUsage != perf.CPUUsage || reported.LoadAvg1 != perf.LoadAvg1 {
+		t.Fatalf("reported performance = %+v, want cpu %.1f load %.1f", reported, perf.CPUUsage, perf.LoadAvg1)
+	}
+
+	// 上报不影响正常转发
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("status %d after report, want 200", status)
+	}
+}
+
+func TestORCALoadReports(t *testing.T) {
+	skipShort(t)
+
+	// 后端在响应头（TEXT格式）或trailer（gRPC二进制格式）中携带负载报告
+	var report []byte
+	report = protowire.AppendTag(report, 1, protowire.Fixed64Type)
+	report = protowire.AppendFixed64(report, math.Float64bits(0.25))
+	report = protowire.AppendTag(report, 2, protowire.Fixed64Type)
+	report = protowire.AppendFixed64(report, math.Float64bits(0.5))
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bin" {
+			w.Header().Set("Trailer", "endpoint-load-metrics-bin")
+			io.WriteString(w, "ok")
+			w.Header().Set("endpoint-load-metrics-bin", base64.StdEncoding.EncodeToString(report))
+			return
+		}
+		w.Header().Set("endpoint-load-metrics", "TEXT cpu_utilization=0.8, mem_utilization=0.3, rps_fractional=120")
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("app", upstream)}
+	cfg.Upstreams = map[string]*types.UpstreamConfig{"default": {LoadReports: types.LoadReportsORCA}}
+	p := testutil.StartProxy(t, cfg)
+
+	performance := func() *types.PerformanceInfo {
+		t.Helper()
+		var resp struct {
+			Backends []*types.Backend `json:"backends"`
+		}
+		if err := p.Admin(http.MethodGet, "/api/v1/backends?upstream=default", nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Backends[0].GetPerformance()
+	}
+
+	resp, err := client.Get(p.URL("/text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("endpoint-load-metrics") != "" {
+		t.Fatal("load report leaked to the client")
+	}
+	if perf := performance(); perf == nil || perf.Source != types.PerformanceSourceORCA || perf.CPUUsage != 80 || perf.MemoryUsage != 30 || perf.RPS != 120 {
+		t.Fatalf("performance after TEXT report = %+v, want orca cpu 80 mem 30 rps 120", perf)
+	}
+
+	if status, _ := get(t, p.URL("/bin")); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if perf := performance(); perf == nil || perf.CPUUsage != 25 || perf.MemoryUsage != 50 {
+		t.Fatalf("performance after binary trailer report = %+v, want cpu 25 mem 50", perf)
+	}
+}
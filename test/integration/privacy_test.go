@@ -0,0 +1,52 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// forwardedHeaders 发送带有上层代理请求头的请求，返回后端收到的X-Forwarded-For和X-Real-IP
+func forwardedHeaders(t *testing.T, url string) (string, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		ForwardedFor string `json:"forwarded_for"`
+		RealIP       string `json:"real_ip"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body.ForwardedFor, body.RealIP
+}
+
+func TestForwardedHeadersStrip(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	p := testutil.StartProxy(t, cfg)
+	if xff, _ := forwardedHeaders(t, p.URL("/")); xff != "203.0.113.7, 127.0.0.1" {
+		t.Fatalf("append mode: X-Forwarded-For = %q, want client IP appended", xff)
+	}
+
+	cfg = testutil.NewConfig(testutil.StartBackend(t, "backend2"))
+	cfg.Routing["default"].ForwardedHeaders = types.ForwardedHeadersStrip
+	p = testutil.StartProxy(t, cfg)
+	if xff, realIP := forwardedHeaders(t, p.URL("/")); xff != "" || realIP != "" {
+		t.Fatalf("strip mode: X-Forwarded-For = %q, X-Real-IP = %q, want both removed", xff, realIP)
+	}
+}
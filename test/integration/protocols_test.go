@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// backendProtocols 获取上游各后端的协议状态
+func backendProtocols(t *testing.T, p *testutil.Proxy, upstream string) map[string]struct {
+	Protocol string `json:"protocol"`
+	H2       string `json:"h2"`
+} {
+	t.Helper()
+	var resp struct {
+		Protocols map[string]struct {
+			Protocol string `json:"protocol"`
+			H2       string `json:"h2"`
+		} `json:"protocols"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/backends?upstream="+upstream, nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Protocols
+}
+
+func TestUpstreamProtocolFallback(t *testing.T) {
+	skipShort(t)
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server", "h2c")
+		w.Header().Set("X-Proto", r.Proto)
+	}), &http2.Server{}))
+	defer h2cServer.Close()
+	legacy := testutil.StartBackend(t, "legacy")
+
+	cfg := testutil.NewConfig(legacy)
+	cfg.Backends["modern"] = []*types.Backend{testutil.ServerBackend("h2c", h2cServer)}
+	cfg.Backends["strict"] = []*types.Backend{legacy.Config()}
+	chain := []string{types.UpstreamProtocolH2, types.UpstreamProtocolHTTP1}
+	cfg.Upstreams = map[string]*types.UpstreamConfig{
+		"default": {Protocols: chain},
+		"modern":  {Protocols: chain},
+		"strict":  {Protocols: []string{types.UpstreamProtocolH2}},
+	}
+	cfg.Routing["modern"] = &types.RoutingRule{Path: "/modern/", Upstream: "modern", LoadBalancer: types.LeastConnectionsWeight}
+	cfg.Routing["strict"] = &types.RoutingRule{Path: "/strict/", Upstream: "strict", LoadBalancer: types.LeastConnectionsWeight}
+	p := testutil.StartProxy(t, cfg)
+
+	// 支持h2c的后端使用HTTP/2
+	resp, err := client.Get(p.URL("/modern/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Proto") != "HTTP/2.0" {
+		t.Fatalf("h2c backend: status %d over %q, want 200 over HTTP/2.0", resp.StatusCode, resp.Header.Get("X-Proto"))
+	}
+	if status := backendProtocols(t, p, "modern")["h2c"]; status.H2 != "supported" || status.Protocol != types.UpstreamProtocolH2 {
+		t.Fatalf("h2c backend protocol status %+v, want h2 supported", status)
+	}
+
+	// 只支持HTTP/1.1的后端在同一请求内回退，并记住不支持HTTP/2
+	for i := 0; i < 2; i++ {
+		if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "legacy" {
+			t.Fatalf("request %d to an HTTP/1.1 backend: status %d from %q, want 200 from legacy", i, status, server)
+		}
+	}
+	if status := backendProtocols(t, p, "default")["legacy"]; status.H2 != "unsupported" || status.Protocol != types.UpstreamProtocolHTTP1 {
+		t.Fatalf("legacy backend protocol status %+v, want h2 unsupported and http/1.1 used", status)
+	}
+
+	// 协议链中没有后端支持的协议时返回502
+	if status, _ := get(t, p.URL("/strict/a")); status != http.StatusBadGateway {
+		t.Fatalf("h2-only upstream with an HTTP/1.1 backend: status %d, want 502", status)
+	}
+}
@@ -0,0 +1,114 @@
+// Package integration 在进程内启动代理、管理API和模拟后端的端到端测试
+// 运行：go test ./test/integration/ ；go test -short 时跳过
+package integration
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+// get 通过代理发送GET请求，返回状态码和处理请求的后端ID
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, resp.Header.Get("X-Server")
+}
+
+// skipShort 集成测试会监听本机端口并等待异步操作，-short时跳过
+func skipShort(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+}
+
+func TestProxyDistributesAcrossBackends(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1, b2))
+
+	const requests = 20
+	for i := 0; i < requests; i++ {
+		if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+
+	if got := b1.Requests() + b2.Requests(); got != requests {
+		t.Fatalf("backends handled %d requests, want %d", got, requests)
+	}
+	// 空闲后端得分相同时随机选择，两个后端都应收到请求
+	if b1.Requests() == 0 || b2.Requests() == 0 {
+		t.Fatalf("requests not distributed: backend1=%d backend2=%d", b1.Requests(), b2.Requests())
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+
+	var stats map[string]interface{}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/server", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) == 0 {
+		t.Fatal("empty server stats")
+	}
+}
+
+func TestBackendDiagnostics(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+
+	const requests = 10
+	for i := 0; i < requests; i++ {
+		if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+
+	var diag struct {
+		Backends map[string]struct {
+			Requests       int64   `json:"requests"`
+			Dials          int64   `json:"dials"`
+			DialErrors     int64   `json:"dial_errors"`
+			ReuseRate      float64 `json:"reuse_rate"`
+			AvgConnectTime string  `json:"avg_connect_time"`
+		} `json:"backends"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/backends/diagnostics?upstream=default", nil, &diag); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := diag.Backends["backend1"]
+	if !ok {
+		t.Fatalf("no diagnostics for backend1: %+v", diag)
+	}
+	if d.Requests != requests || d.Dials < 1 || d.DialErrors != 0 || d.AvgConnectTime == "" {
+		t.Fatalf("diagnostics = %+v, want %d requests over at least one successful dial", d, requests)
+	}
+	// 顺序请求复用同一个上游连接
+	if d.ReuseRate < 0.5 {
+		t.Fatalf("reuse_rate = %v, want sequential requests to reuse pooled connections", d.ReuseRate)
+	}
+}
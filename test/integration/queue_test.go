@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// startQueuedProxy 启动只有一个max_conn为1的后端、default上游配置了等待队列的代理
+func startQueuedProxy(t *testing.T, maxWait time.Duration) *testutil.Proxy {
+	t.Helper()
+	b := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b)
+	cfg.Backends["default"][0].MaxConn = 1
+	cfg.Upstreams = map[string]*types.UpstreamConfig{
+		"default": {Queue: &types.BackendQueueConfig{MaxSize: 10, MaxWait: maxWait}},
+	}
+	return testutil.StartProxy(t, cfg)
+}
+
+// concurrentStatuses 同时发送n个请求，返回各请求的状态码
+func concurrentStatuses(t *testing.T, url string, n int) []int {
+	t.Helper()
+	statuses := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Errorf("request %d: %v", i, err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	return statuses
+}
+
+func TestBackendQueueAbsorbsSpike(t *testing.T) {
+	skipShort(t)
+
+	p := startQueuedProxy(t, 3*time.Second)
+
+	// 后端同时只接受一个连接，其余请求排队等待而不是立即返回503
+	for i, status := range concurrentStatuses(t, p.URL("/?sleep=100ms"), 4) {
+		if status != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, status)
+		}
+	}
+
+	var stats map[string]struct {
+		Queued   int64 `json:"queued"`
+		Admitted int64 `json:"admitted"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/backend-queues", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if q := stats["default"]; q.Queued == 0 || q.Admitted != q.Queued {
+		t.Fatalf("queue stats = %+v, want queued requests all admitted", q)
+	}
+}
+
+func TestBackendQueueTimeout(t *testing.T) {
+	skipShort(t)
+
+	p := startQueuedProxy(t, 100*time.Millisecond)
+
+	// 后端处理时间超过最长排队时间，排队的请求超时返回503
+	ok, unavailable := 0, 0
+	for _, status := range concurrentStatuses(t, p.URL("/?sleep=500ms"), 2) {
+		switch status {
+		case http.StatusOK:
+			ok++
+		case http.StatusServiceUnavailable:
+			unavailable++
+		}
+	}
+	if ok != 1 || unavailable != 1 {
+		t.Fatalf("got %d ok and %d unavailable, want 1 and 1", ok, unavailable)
+	}
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestQuiesceListenerReload(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("before quiesce: status %d, want 200", status)
+	}
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	// 静默期间修改监听地址不重新开始接收连接，退出静默模式时在新地址监听
+	if err := p.Admin(http.MethodPost, "/api/v1/quiesce", map[string]bool{"enabled": true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	updated := config.CloneConfig(p.Config.GetConfig())
+	updated.Server.Port = port
+	if err := p.Config.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	newURL := "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(port)) + "/"
+	client.CloseIdleConnections()
+	for _, url := range []string{p.URL("/"), newURL} {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+			t.Fatalf("%s accepted a new connection while quiesced", url)
+		}
+	}
+
+	if err := p.Admin(http.MethodPost, "/api/v1/quiesce", map[string]bool{"enabled": false}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(t, newURL); status != http.StatusOK {
+		t.Fatalf("new address after resume: status %d, want 200", status)
+	}
+	if resp, err := client.Get(p.URL("/")); err == nil {
+		resp.Body.Close()
+		t.Fatal("old address accepted a new connection after resume")
+	}
+}
+
+func TestQuiesceClosesConnections(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+	conn := dialProxy(t, p)
+	defer conn.Close()
+	if resp := connGet(t, conn, nil); resp.StatusCode != http.StatusOK || resp.Close {
+		t.Fatalf("before quiesce: status %d, close %v; want 200 on a keep-alive connection", resp.StatusCode, resp.Close)
+	}
+
+	// 静默期间已建立的连接上的请求正常转发，响应后关闭连接
+	if err := p.Admin(http.MethodPost, "/api/v1/quiesce", map[string]bool{"enabled": true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp := connGet(t, conn, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Server") != "backend1" || !resp.Close {
+		t.Fatalf("quiesced: status %d from %q, close %v; want 200 from backend1 with Connection: close", resp.StatusCode, resp.Header.Get("X-Server"), resp.Close)
+	}
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestQuotaKeysSurviveConfigRoundTrip(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Quota = types.QuotaConfig{
+		Enabled:    true,
+		RequireKey: true,
+		Keys: []types.QuotaKey{
+			{Key: "key-a1", Tenant: "a"},
+			{Key: "key-a2", Tenant: "a"},
+			{Key: "key-b", Tenant: "b"},
+		},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	status := func(key string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL("/"), nil)
+		req.Header["X-API-Key"] = []string{key} // 代理不规范化请求头名称
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// GET /api/v1/config不返回密钥，原样写回（修改上限）时按租户和顺序保留原密钥
+	var got struct {
+		Config *types.Config `json:"config"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/config", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range got.Config.Quota.Keys {
+		if key.Key != "" {
+			t.Fatalf("GET /api/v1/config quota key %q, want it hidden", key.Key)
+		}
+	}
+	got.Config.Quota.Keys[2].Requests = 1
+	if err := p.Admin(http.MethodPut, "/api/v1/config", map[string]interface{}{"config": got.Config}, nil); err != nil {
+		t.Fatalf("round-tripping the config: %v", err)
+	}
+
+	keys := p.Config.GetConfig().Quota.Keys
+	want := []string{"key-a1", "key-a2", "key-b"}
+	if len(keys) != len(want) {
+		t.Fatalf("quota keys after round trip %+v, want %v", keys, want)
+	}
+	for i, key := range keys {
+		if key.Key != want[i] {
+			t.Fatalf("quota key %d after round trip %q, want %q", i, key.Key, want[i])
+		}
+	}
+	for _, key := range want[:2] {
+		if code := status(key); code != http.StatusOK {
+			t.Fatalf("%s after round trip: status %d, want 200", key, code)
+		}
+	}
+
+	// 小写的密钥头同样识别
+	req, _ := http.NewRequest(http.MethodGet, p.URL("/"), nil)
+	req.Header["x-api-key"] = []string{"key-a1"}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("lowercase x-api-key: status %d, want 200", resp.StatusCode)
+	}
+
+	if code := status("key-b"); code != http.StatusOK {
+		t.Fatalf("key-b first request: status %d, want 200", code)
+	}
+	if code := status("key-b"); code != http.StatusTooManyRequests {
+		t.Fatalf("key-b over the updated limit: status %d, want 429", code)
+	}
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestRangeRequests(t *testing.T) {
+	skipShort(t)
+
+	payload := make([]byte, 16<<20)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("broken") != "" {
+			// 返回与请求不符的范围
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-9/%d", len(payload)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(payload[:10])
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Unix(1700000000, 0), bytes.NewReader(payload))
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("files", upstream)}
+	cfg.Routing["files"] = &types.RoutingRule{
+		Path:          "/files",
+		Upstream:      "default",
+		RangeRequests: &types.RangeRequestsConfig{Enabled: true},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	fetch := func(path, rng string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL(path), nil)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("%s Range %q: reading body: %v", path, rng, err)
+		}
+		return resp, body
+	}
+
+	resp, body := fetch("/files", "bytes=1000-1999")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, payload[1000:2000]) ||
+		resp.Header.Get("Content-Range") != fmt.Sprintf("bytes 1000-1999/%d", len(payload)) {
+		t.Fatalf("range response: status %d, Content-Range %q, %d bytes; want 206 with bytes 1000-1999",
+			resp.StatusCode, resp.Header.Get("Content-Range"), len(body))
+	}
+
+	// 从中间继续下载到末尾，响应体以流方式返回
+	half := len(payload) / 2
+	resp, body = fetch("/files", fmt.Sprintf("bytes=%d-", half))
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, payload[half:]) {
+		t.Fatalf("resumed download: status %d, %d bytes; want 206 with the second half", resp.StatusCode, len(body))
+	}
+
+	resp, body = fetch("/files", "")
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, payload) {
+		t.Fatalf("full download: status %d, %d bytes; want 200 with the whole file", resp.StatusCode, len(body))
+	}
+
+	// 无效的Range头和超过max_ranges的范围被去掉，上游返回完整响应
+	for _, rng := range []string{"bytes=abc", "bytes=0-0,10-10"} {
+		if resp, body := fetch("/files", rng); resp.StatusCode != http.StatusOK || len(body) != len(payload) {
+			t.Fatalf("Range %q: status %d, %d bytes; want the full 200 response", rng, resp.StatusCode, len(body))
+		}
+	}
+
+	if resp, _ := fetch("/files", fmt.Sprintf("bytes=%d-", len(payload))); resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: status %d, want 416", resp.StatusCode)
+	}
+	if resp, _ := fetch("/files?broken=1", "bytes=100-199"); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("mismatched partial response: status %d, want 502", resp.StatusCode)
+	}
+
+	// 客户端中途断开后，没有读完的上游连接不会被复用
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", p.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, "GET /files HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		partial, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadFull(partial.Body, make([]byte, 4096))
+		conn.Close()
+
+		if resp, body := fetch("/files", "bytes=0-99"); resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, payload[:100]) {
+			t.Fatalf("request after an aborted download: status %d, %d bytes; want 206 with bytes 0-99", resp.StatusCode, len(body))
+		}
+	}
+
+	var stats struct {
+		Forwarded      int64 `json:"forwarded"`
+		Ignored        int64 `json:"ignored"`
+		Partial        int64 `json:"partial"`
+		NotSatisfiable int64 `json:"not_satisfiable"`
+		Invalid        int64 `json:"invalid"`
+		Streamed       int64 `json:"streamed"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/range-requests", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Ignored != 2 || stats.Partial != 5 || stats.NotSatisfiable != 1 || stats.Invalid != 1 || stats.Streamed < 2 {
+		t.Fatalf("range stats = %+v, want 2 ignored, 5 partial, 1 not satisfiable, 1 invalid and streamed responses", stats)
+	}
+}
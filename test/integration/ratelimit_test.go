@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// getAs 携带客户端IP请求头发送请求，返回状态码
+func getAs(t *testing.T, url, realIP, forwardedFor string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if realIP != "" {
+		req.Header.Set("X-Real-IP", realIP)
+	}
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRouteRateLimitClientIP(t *testing.T) {
+	skipShort(t)
+
+	start := func(trusted []string) *testutil.Proxy {
+		cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+		cfg.Server.TrustedProxies = trusted
+		cfg.Routing["default"].RateLimit = &types.RateLimitConfig{Rate: 0.01, Burst: 1}
+		return testutil.StartProxy(t, cfg)
+	}
+
+	// 对端不是可信代理时忽略X-Real-IP和X-Forwarded-For，伪造请求头不能绕过限速
+	p := start(nil)
+	if status := getAs(t, p.URL("/"), "", ""); status != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", status)
+	}
+	for i := 0; i < 3; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i+1)
+		if status := getAs(t, p.URL("/"), ip, ip); status != http.StatusTooManyRequests {
+			t.Fatalf("spoofed client %s: status %d, want 429", ip, status)
+		}
+	}
+
+	// 对端是可信代理时按其转发的客户端IP分别限速
+	p = start([]string{"127.0.0.1/32"})
+	for i := 0; i < 3; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i+1)
+		if status := getAs(t, p.URL("/"), ip, ""); status != http.StatusOK {
+			t.Fatalf("forwarded client %s: status %d, want 200", ip, status)
+		}
+	}
+	if status := getAs(t, p.URL("/"), "", "203.0.113.1, 127.0.0.1"); status != http.StatusTooManyRequests {
+		t.Fatalf("repeated forwarded client: status %d, want 429", status)
+	}
+}
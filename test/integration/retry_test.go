@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	skipShort(t)
+
+	var failures int64
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&failures, 1)
+		w.Header().Set("X-Server", "failing")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	good := testutil.StartBackend(t, "good")
+	backup := testutil.StartBackend(t, "backup")
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("failing", failing), good.Config()}
+	cfg.Backends["broken"] = []*types.Backend{testutil.ServerBackend("dead", dead), testutil.ServerBackend("failing", failing)}
+	cfg.Backends["backup"] = []*types.Backend{backup.Config()}
+	cfg.Routing["default"].Retry = &types.RetryConfig{}
+	cfg.Routing["failover"] = &types.RoutingRule{
+		Path:              "/failover/",
+		Upstream:          "broken",
+		LoadBalancer:      types.LeastConnectionsWeight,
+		FallbackUpstreams: []string{"backup"},
+		Retry:             &types.RetryConfig{MaxAttempts: 3},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	// 返回503的后端被重试到未尝试过的后端
+	for i := 0; i < 6; i++ {
+		if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "good" {
+			t.Fatalf("request %d: status %d from %q, want 200 from good", i, status, server)
+		}
+	}
+	if atomic.LoadInt64(&failures) == 0 {
+		t.Fatal("failing backend never selected, retry not exercised")
+	}
+
+	// 不可重试的方法返回第一次尝试的响应
+	var rejected bool
+	for i := 0; i < 6 && !rejected; i++ {
+		resp, err := client.Post(p.URL("/"), "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		rejected = resp.StatusCode == http.StatusServiceUnavailable
+	}
+	if !rejected {
+		t.Fatal("POST was retried, want the failing response returned")
+	}
+
+	// 主上游的后端都失败（连接失败和503）时在备用上游中重试
+	before := backup.Requests()
+	if status, server := get(t, p.URL("/failover/a")); status != http.StatusOK || server != "backup" {
+		t.Fatalf("failover: status %d from %q, want 200 from backup", status, server)
+	}
+	if backup.Requests() != before+1 {
+		t.Fatalf("backup received %d requests, want 1", backup.Requests()-before)
+	}
+}
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestResponseCacheRevalidation(t *testing.T) {
+	skipShort(t)
+
+	var requests, conditional int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.URL.Path == "/fresh" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=0")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt64(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "version one")
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("backend1", upstream)}
+	cfg.Cache.Enabled = true
+	cfg.Routing["default"].Cache = &types.RouteCacheConfig{Enabled: true}
+	p := testutil.StartProxy(t, cfg)
+
+	fetch := func(path string, header http.Header) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, p.URL(path), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	// 新鲜条目直接返回，不再请求上游
+	if resp, _ := fetch("/fresh", nil); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatalf("first /fresh X-Cache %q, want MISS", resp.Header.Get("X-Cache"))
+	}
+	resp, body := fetch("/fresh", nil)
+	if resp.Header.Get("X-Cache") != "HIT" || body != "version one" || resp.Header.Get("Age") == "" {
+		t.Fatalf("second /fresh: X-Cache %q, Age %q, body %q; want a cache hit", resp.Header.Get("X-Cache"), resp.Header.Get("Age"), body)
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Fatalf("upstream received %d requests, want 1", n)
+	}
+
+	// 过期条目用ETag向上游条件请求，上游返回304时继续使用缓存的响应体
+	fetch("/doc", nil)
+	resp, body = fetch("/doc", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != "REVALIDATED" || body != "version one" {
+		t.Fatalf("stale /doc: status %d, X-Cache %q, body %q; want the revalidated cached body", resp.StatusCode, resp.Header.Get("X-Cache"), body)
+	}
+	if n := atomic.LoadInt64(&conditional); n != 1 {
+		t.Fatalf("upstream received %d conditional requests, want 1", n)
+	}
+
+	// 客户端的If-None-Match与缓存条目匹配时返回304
+	if resp, _ := fetch("/fresh", http.Header{"If-None-Match": {`"v1"`}}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional client request: status %d, want 304", resp.StatusCode)
+	}
+
+	var stats struct {
+		Cache struct {
+			Hits        int64 `json:"hits"`
+			Revalidated int64 `json:"revalidated"`
+			NotModified int64 `json:"not_modified"`
+		} `json:"cache"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/cache", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Cache.Revalidated != 1 || stats.Cache.NotModified != 1 || stats.Cache.Hits < 1 {
+		t.Fatalf("cache stats %+v, want 1 revalidation and 1 not modified", stats.Cache)
+	}
+
+	// 带认证信息的请求不论请求头大小写都不使用缓存，响应也不写入缓存
+	conn := dialProxy(t, p)
+	defer conn.Close()
+	before := atomic.LoadInt64(&requests)
+	for i := 0; i < 2; i++ {
+		if resp := connGet(t, conn, http.Header{"authorization": {"Bearer secret"}}); resp.Header.Get("X-Cache") != "" {
+			t.Fatalf("authorized request X-Cache %q, want the cache bypassed", resp.Header.Get("X-Cache"))
+		}
+	}
+	if resp, _ := fetch("/", nil); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatalf("anonymous request after authorized ones X-Cache %q, want MISS", resp.Header.Get("X-Cache"))
+	}
+	if n := atomic.LoadInt64(&requests) - before; n != 3 {
+		t.Fatalf("upstream received %d requests, want 3", n)
+	}
+
+	// 清除后重新从上游获取
+	var purge struct {
+		Purged int `json:"purged"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/cache/purge", map[string]string{"path_prefix": "/fresh"}, &purge); err != nil {
+		t.Fatal(err)
+	}
+	if purge.Purged != 1 {
+		t.Fatalf("purged %d entries, want 1", purge.Purged)
+	}
+	if resp, _ := fetch("/fresh", nil); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatalf("/fresh after purge X-Cache %q, want MISS", resp.Header.Get("X-Cache"))
+	}
+}
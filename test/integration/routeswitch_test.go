@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// routeSwitchResult 蓝绿切换接口返回的切换结果
+type routeSwitchResult struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Drained  bool   `json:"drained"`
+	InFlight int64  `json:"in_flight"`
+}
+
+// switchRoute 切换路由的上游
+func switchRoute(p *testutil.Proxy, route, upstream, drainTimeout string) (*routeSwitchResult, error) {
+	var resp struct {
+		Switch routeSwitchResult `json:"switch"`
+	}
+	err := p.Admin(http.MethodPost, "/api/v1/routes/switch", map[string]string{"route": route, "upstream": upstream, "drain_timeout": drainTimeout}, &resp)
+	return &resp.Switch, err
+}
+
+func TestBlueGreenSwitch(t *testing.T) {
+	skipShort(t)
+
+	blue := testutil.StartBackend(t, "blue")
+	green := testutil.StartBackend(t, "green")
+	cfg := testutil.NewConfig(blue)
+	cfg.Backends["green"] = []*types.Backend{green.Config()}
+	p := testutil.StartProxy(t, cfg)
+
+	// 切换前已开始的请求继续由旧上游处理，切换接口等待其完成
+	type result struct {
+		status int
+		server string
+	}
+	inflight := make(chan result, 1)
+	go func() {
+		resp, err := client.Get(p.URL("/slow?sleep=500ms"))
+		if err != nil {
+			inflight <- result{}
+			return
+		}
+		resp.Body.Close()
+		inflight <- result{resp.StatusCode, resp.Header.Get("X-Server")}
+	}()
+	if !testutil.Eventually(2*time.Second, func() bool { return blue.Requests() == 1 }) {
+		t.Fatal("in-flight request never reached blue")
+	}
+
+	start := time.Now()
+	sw, err := switchRoute(p, "default", "green", "5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw.From != "default" || sw.To != "green" || !sw.Drained || sw.InFlight != 0 {
+		t.Fatalf("switch result %+v, want a drained switch from default to green", sw)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("switch returned after %v, want it to wait for the in-flight request", elapsed)
+	}
+	if r := <-inflight; r.status != http.StatusOK || r.server != "blue" {
+		t.Fatalf("in-flight request: status %d from %q, want 200 from blue", r.status, r.server)
+	}
+
+	// 新请求使用新上游，切换写入配置
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "green" {
+		t.Fatalf("after switch: status %d from %q, want 200 from green", status, server)
+	}
+	if upstream := p.Config.GetConfig().Routing["default"].Upstream; upstream != "green" {
+		t.Fatalf("configured upstream %q, want green", upstream)
+	}
+
+	// 排空超时时返回仍在处理的旧请求数
+	go client.Get(p.URL("/slow?sleep=1s"))
+	if !testutil.Eventually(2*time.Second, func() bool { return green.Requests() == 2 }) {
+		t.Fatal("in-flight request never reached green")
+	}
+	if sw, err := switchRoute(p, "default", "default", "100ms"); err != nil || sw.Drained || sw.InFlight != 1 {
+		t.Fatalf("switch with a short drain timeout: %+v, %v; want one request still in flight", sw, err)
+	}
+
+	if _, err := switchRoute(p, "default", "missing", "0s"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("switch to an unknown upstream: %v, want status 404", err)
+	}
+}
+
+func TestBlueGreenSwitchConcurrentUpdates(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	cfg := testutil.NewConfig(b1)
+	cfg.Backends["green"] = []*types.Backend{testutil.StartBackend(t, "green").Config()}
+	cfg.Routing["api"] = &types.RoutingRule{Path: "/api/", Upstream: "default", LoadBalancer: types.LeastConnectionsWeight}
+	p := testutil.StartProxy(t, cfg)
+
+	// 切换与其他配置修改并发时互不覆盖
+	const n = 8
+	errs := make([]error, n+1)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			backend := b1.Config()
+			backend.ID = fmt.Sprintf("added%d", i)
+			errs[i] = p.Admin(http.MethodPost, "/api/v1/backends/add", map[string]interface{}{"upstream": "default", "backend": backend}, nil)
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, errs[n] = switchRoute(p, "api", "green", "0s")
+	}()
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+	}
+
+	current := p.Config.GetConfig()
+	if current.Routing["api"].Upstream != "green" || len(current.Backends["default"]) != n+1 {
+		t.Fatalf("api upstream %q with %d default backends, want green and %d", current.Routing["api"].Upstream, len(current.Backends["default"]), n+1)
+	}
+}
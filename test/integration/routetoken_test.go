@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/routetoken"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestRoutingToken(t *testing.T) {
+	skipShort(t)
+
+	const secret = "routing-token-secret"
+	canary, canaryBackend := startHeaderCapture(t, "canary")
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "stable"))
+	cfg.Backends["canary"] = []*types.Backend{canaryBackend}
+	cfg.RoutingToken = types.RoutingTokenConfig{Enabled: true, Header: "X-SpeedMimi-Route-Token", Secret: secret}
+	p := testutil.StartProxy(t, cfg)
+
+	token, err := routetoken.Sign(&routetoken.Claims{Upstream: "canary", ExpiresAt: time.Now().Add(time.Minute).Unix()}, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(name, value string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL("/"), nil)
+		req.Header[name] = []string{value}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get("X-Server")
+	}
+
+	if status, server := get(t, p.URL("/")); status != http.StatusOK || server != "stable" {
+		t.Fatalf("without a token: status %d from %q, want 200 from stable", status, server)
+	}
+
+	// 令牌覆盖上游选择，且不转发给后端；请求头名称不区分大小写
+	for _, name := range []string{"X-SpeedMimi-Route-Token", "x-speedmimi-route-token"} {
+		if status, server := send(name, token); status != http.StatusOK || server != "canary" {
+			t.Fatalf("%s: status %d from %q, want 200 from canary", name, status, server)
+		}
+		if got := canary.last().Get("X-SpeedMimi-Route-Token"); got != "" {
+			t.Fatalf("%s forwarded to the backend as %q, want it removed", name, got)
+		}
+	}
+
+	// 签名不匹配的令牌被拒绝
+	if status, _ := send("x-speedmimi-route-token", token+"x"); status != http.StatusForbidden {
+		t.Fatalf("tampered token: status %d, want 403", status)
+	}
+}
@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	skipShort(t)
+
+	// 上游自己设置了X-Frame-Options，默认保留上游的值
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = []*types.Backend{testutil.ServerBackend("app", upstream)}
+	cfg.Server.SecurityHeaders = &types.SecurityHeadersConfig{
+		Enabled:               true,
+		ContentSecurityPolicy: "default-src 'self'",
+	}
+	cfg.Routing["public"] = &types.RoutingRule{
+		Path:            "/public",
+		Upstream:        "default",
+		SecurityHeaders: &types.SecurityHeadersConfig{Enabled: false},
+	}
+	cfg.Routing["embed"] = &types.RoutingRule{
+		Path:            "/embed",
+		Upstream:        "default",
+		SecurityHeaders: &types.SecurityHeadersConfig{Enabled: true, FrameOptions: "DENY", ReferrerPolicy: "no-referrer", Override: true},
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	headers := func(path string) http.Header {
+		t.Helper()
+		resp, err := client.Get(p.URL(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.Header
+	}
+
+	h := headers("/")
+	if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("Referrer-Policy") != "strict-origin-when-cross-origin" ||
+		h.Get("Content-Security-Policy") != "default-src 'self'" || h.Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Fatalf("global security headers = %v, want defaults with the upstream X-Frame-Options kept", h)
+	}
+	if h.Get("Strict-Transport-Security") != "" {
+		t.Fatalf("Strict-Transport-Security set on a plain HTTP response: %q", h.Get("Strict-Transport-Security"))
+	}
+
+	// 路由的配置替换全局配置
+	if h := headers("/public"); h.Get("X-Content-Type-Options") != "" || h.Get("Content-Security-Policy") != "" {
+		t.Fatalf("route with security headers disabled got %v", h)
+	}
+	h = headers("/embed")
+	if h.Get("X-Frame-Options") != "DENY" || h.Get("Referrer-Policy") != "no-referrer" || h.Get("Content-Security-Policy") != "" {
+		t.Fatalf("route security headers = %v, want the route profile overriding the upstream", h)
+	}
+	if values := h.Values("X-Frame-Options"); len(values) != 1 {
+		t.Fatalf("X-Frame-Options values = %v, want a single value", values)
+	}
+}
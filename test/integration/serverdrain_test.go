@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/proxy"
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+func TestServerDrainWaitsForInFlightRequests(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+	done := startSlowRequest(t, p, 600*time.Millisecond)
+
+	select {
+	case <-p.Server.ShutdownRequested():
+		t.Fatal("shutdown requested before the drain endpoint was called")
+	default:
+	}
+
+	// wait为true时请求在排空结束后才返回
+	start := time.Now()
+	var resp struct {
+		Shutdown proxy.ShutdownStatus `json:"shutdown"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/server/drain", map[string]interface{}{"timeout": "5s", "wait": true}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Shutdown.State != proxy.ShutdownStateDrained || resp.Shutdown.InFlight != 0 {
+		t.Fatalf("drain result: %+v, want drained with no requests in flight", resp.Shutdown)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("drain returned after %s, before the in-flight request finished", elapsed)
+	}
+	<-done
+
+	select {
+	case <-p.Server.ShutdownRequested():
+	default:
+		t.Fatal("shutdown not requested after the drain finished")
+	}
+
+	// 已停止接收新连接
+	if _, err := client.Get(p.URL("/")); err == nil {
+		t.Fatal("proxy accepted a new connection after drain")
+	}
+}
+
+func TestServerDrainTimeout(t *testing.T) {
+	skipShort(t)
+
+	b := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b))
+	done := startSlowRequest(t, p, 2*time.Second)
+	defer func() { <-done }()
+
+	var resp struct {
+		Shutdown proxy.ShutdownStatus `json:"shutdown"`
+	}
+	if err := p.Admin(http.MethodPost, "/api/v1/server/drain", map[string]interface{}{"timeout": "200ms"}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Shutdown.State != proxy.ShutdownStateDraining {
+		t.Fatalf("without wait: %+v, want draining", resp.Shutdown)
+	}
+
+	select {
+	case <-p.Server.ShutdownRequested():
+	case <-time.After(time.Second):
+		t.Fatal("shutdown not requested after the deadline")
+	}
+	var status struct {
+		Shutdown *proxy.ShutdownStatus `json:"shutdown"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/server/drain", nil, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Shutdown == nil || status.Shutdown.State != proxy.ShutdownStateTimedOut || status.Shutdown.InFlight != 1 {
+		t.Fatalf("after the deadline: %+v, want timed_out with 1 request in flight", status.Shutdown)
+	}
+}
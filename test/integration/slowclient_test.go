@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+)
+
+// slowClientStats 慢客户端保护的统计
+func slowClientStats(t *testing.T, p *testutil.Proxy) (disconnects int64) {
+	t.Helper()
+	var resp struct {
+		Disconnects int64 `json:"disconnects"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/connections/slow-clients", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Disconnects
+}
+
+func TestSlowClientDisconnect(t *testing.T) {
+	skipShort(t)
+
+	const size = 32 << 20
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.WriteTimeout = 30 * time.Second
+	cfg.Server.SlowClient.MaxWriteBuffer = 64 << 10
+	cfg.Server.SlowClient.WriteBufferTimeout = 300 * time.Millisecond
+	p := testutil.StartProxy(t, cfg)
+	path := "/large?size=" + strconv.Itoa(size)
+
+	// 读取足够快的客户端完整收到大响应
+	resp, err := client.Get(p.URL(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err != nil || n != size {
+		t.Fatalf("fast client read %d bytes (%v), want %d", n, err, size)
+	}
+
+	// 不读取响应的客户端在write_buffer_timeout后被断开（响应远大于本机的套接字缓冲区）
+	conn, err := net.Dial("tcp", p.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "GET "+path+" HTTP/1.1\r\nHost: proxy\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Eventually(5*time.Second, func() bool { return slowClientStats(t, p) == 1 }) {
+		t.Fatal("slow client was not disconnected")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	slow, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = io.Copy(io.Discard, slow.Body)
+	if err == nil || n >= size {
+		t.Fatalf("slow client read %d bytes (%v) after the disconnect, want a truncated response", n, err)
+	}
+}
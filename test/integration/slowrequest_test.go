@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// startSlowRequestProxy 启动配置了慢请求保护的代理
+func startSlowRequestProxy(t *testing.T, slow types.SlowRequestConfig) *testutil.Proxy {
+	t.Helper()
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Server.SlowRequest = slow
+	return testutil.StartProxy(t, cfg)
+}
+
+// sendPartial 建立连接并发送不完整的请求
+func sendPartial(t *testing.T, addr, data string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := conn.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// closedWithin 连接是否在timeout内被代理关闭（之前可能收到错误响应）
+func closedWithin(conn net.Conn, timeout time.Duration) bool {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	_, err := io.Copy(io.Discard, conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return false
+	}
+	return true
+}
+
+func TestSlowRequestHeaderTimeout(t *testing.T) {
+	skipShort(t)
+
+	p := startSlowRequestProxy(t, types.SlowRequestConfig{HeaderTimeout: 200 * time.Millisecond})
+
+	// 完整的请求不受影响
+	if status, _ := get(t, p.URL("/")); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+
+	conn := sendPartial(t, p.Addr, "GET / HTTP/1.1\r\nHost: localhost\r\n")
+	if !closedWithin(conn, 2*time.Second) {
+		t.Fatal("connection with incomplete headers still open after 2s")
+	}
+
+	var stats struct {
+		HeaderTimeouts int64 `json:"header_timeouts"`
+		Incomplete     int64 `json:"incomplete"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/connections/slow-requests", nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.HeaderTimeouts != 1 || stats.Incomplete != 0 {
+		t.Fatalf("slow request stats = %+v, want 1 header timeout and no incomplete requests", stats)
+	}
+}
+
+func TestSlowRequestMinRate(t *testing.T) {
+	skipShort(t)
+
+	p := startSlowRequestProxy(t, types.SlowRequestConfig{MinRate: 1000, RateGrace: 200 * time.Millisecond})
+
+	// 声明1000字节的请求体，只发送10字节后停止
+	conn := sendPartial(t, p.Addr, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 1000\r\n\r\n0123456789")
+	if !closedWithin(conn, 2*time.Second) {
+		t.Fatal("connection with stalled request body still open after 2s")
+	}
+
+	// 正常发送请求体的客户端不受影响
+	conn = sendPartial(t, p.Addr, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestSlowRequestMaxIncomplete(t *testing.T) {
+	skipShort(t)
+
+	p := startSlowRequestProxy(t, types.SlowRequestConfig{MaxIncompleteRequests: 1})
+
+	first := sendPartial(t, p.Addr, "GET / HTTP/1.1\r\n")
+	if !testutil.Eventually(2*time.Second, func() bool {
+		var stats struct {
+			Incomplete int64 `json:"incomplete"`
+		}
+		return p.Admin(http.MethodGet, "/api/v1/connections/slow-requests", nil, &stats) == nil && stats.Incomplete == 1
+	}) {
+		t.Fatal("first incomplete request not tracked")
+	}
+
+	// 已有一个连接在发送请求头，新连接发送请求后被关闭
+	second := sendPartial(t, p.Addr, "GET / HTTP/1.1\r\n")
+	if !closedWithin(second, 2*time.Second) {
+		t.Fatal("second incomplete request not closed")
+	}
+	if closedWithin(first, 200*time.Millisecond) {
+		t.Fatal("first incomplete request closed, want it kept open")
+	}
+}
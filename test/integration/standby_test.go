@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+func TestStandbyFollowsHealthTransitions(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	b2 := testutil.StartBackend(t, "backend2")
+	dr := testutil.StartBackend(t, "dr1")
+	cfg := testutil.NewConfig(b1, b2)
+	cfg.Backends["dr"] = []*types.Backend{dr.Config()}
+	cfg.Routing["default"].Standby = &types.StandbyConfig{
+		Upstream:      "dr",
+		FailoverRatio: 0.5,
+		RecoverRatio:  1,
+		MinDuration:   time.Millisecond,
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	type standbyState struct {
+		Route        string  `json:"route"`
+		Active       bool    `json:"active"`
+		HealthyRatio float64 `json:"healthy_ratio"`
+	}
+	standby := func() standbyState {
+		t.Helper()
+		var got struct {
+			Standby []standbyState `json:"standby"`
+		}
+		if err := p.Admin(http.MethodGet, "/api/v1/standby", nil, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Standby) != 1 || got.Standby[0].Route != "default" {
+			t.Fatalf("standby states %+v, want the default route", got.Standby)
+		}
+		return got.Standby[0]
+	}
+	override := func(id, value string) {
+		t.Helper()
+		body := map[string]string{"upstream_id": "default", "backend_id": id, "override": value}
+		if err := p.Admin(http.MethodPost, "/api/v1/health/override", body, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 切换状态不依赖请求，尚未收到请求的路由也会列出
+	if state := standby(); state.Active || state.HealthyRatio != 1 {
+		t.Fatalf("initial standby state %+v, want primary with healthy ratio 1", state)
+	}
+
+	// 健康状态变化后立即切换，不需要等待定期重新计算
+	override("backend1", "unhealthy")
+	override("backend2", "unhealthy")
+	if state := standby(); !state.Active || state.HealthyRatio != 0 {
+		t.Fatalf("standby state %+v after both backends went unhealthy, want standby active", state)
+	}
+	if _, server := get(t, p.URL("/")); server != "dr1" {
+		t.Fatalf("served by %q, want dr1 from the standby upstream", server)
+	}
+
+	// 恢复到recover_ratio后切回主上游
+	override("backend1", "none")
+	override("backend2", "none")
+	if !testutil.Eventually(3*time.Second, func() bool {
+		_, server := get(t, p.URL("/"))
+		return server == "backend1" || server == "backend2"
+	}) {
+		t.Fatal("route did not switch back to the primary upstream")
+	}
+}
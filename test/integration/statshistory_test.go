@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// statsHistory 查询指标历史，返回状态码
+func statsHistory(t *testing.T, p *testutil.Proxy, query string) (monitor.HistorySeries, int) {
+	t.Helper()
+	resp, err := client.Get(p.AdminURL("/api/v1/stats/history" + query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var series monitor.HistorySeries
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return series, resp.StatusCode
+}
+
+func TestStatsHistory(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.StatsHistory = types.StatsHistoryConfig{Resolution: time.Second, Retention: time.Minute}
+	p := testutil.StartProxy(t, cfg)
+
+	// 持续发送请求，直到历史中出现非零的RPS
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				get(t, p.URL("/"))
+			}
+		}
+	}()
+	var series monitor.HistorySeries
+	ok := testutil.Eventually(5*time.Second, func() bool {
+		series, _ = statsHistory(t, p, "?metric=rps&range=1m")
+		for _, pt := range series.Points {
+			if pt.Value > 0 {
+				return true
+			}
+		}
+		return false
+	})
+	close(stop)
+	<-done
+	if !ok {
+		t.Fatalf("history %+v, want a point with rps > 0", series)
+	}
+	if series.Metric != "rps" || series.Resolution != "1s" || series.Step != "1s" {
+		t.Errorf("series metric=%q resolution=%q step=%q", series.Metric, series.Resolution, series.Step)
+	}
+
+	// 按step分桶后点数不多于原始点数
+	bucketed, _ := statsHistory(t, p, "?metric=active_connections&range=1m&step=1m")
+	if len(bucketed.Points) == 0 || len(bucketed.Points) > 2 || bucketed.Step != "1m0s" {
+		t.Errorf("bucketed series %+v, want at most 2 one-minute points", bucketed)
+	}
+
+	for _, query := range []string{"?metric=latency", "?metric=rps&range=-1h", "?metric=rps&step=x"} {
+		if _, status := statsHistory(t, p, query); status != http.StatusBadRequest {
+			t.Errorf("%s status %d, want 400", query, status)
+		}
+	}
+
+	// 只修改保留时长时保留已有的采样，关闭后返回404
+	before := len(series.Points)
+	updated := config.CloneConfig(p.Config.GetConfig())
+	updated.StatsHistory.Retention = 2 * time.Minute
+	if err := p.Config.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := statsHistory(t, p, "?metric=rps&range=1h"); len(kept.Points) < before {
+		t.Errorf("%d points after changing retention, want at least %d", len(kept.Points), before)
+	}
+	updated = config.CloneConfig(p.Config.GetConfig())
+	updated.StatsHistory.Disabled = true
+	if err := p.Config.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, status := statsHistory(t, p, "?metric=rps"); status != http.StatusNotFound {
+		t.Errorf("disabled history status %d, want 404", status)
+	}
+}
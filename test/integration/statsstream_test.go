@@ -0,0 +1,218 @@
+package integration
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// streamSnapshot 推送的统计快照中测试用到的字段
+type streamSnapshot struct {
+	Traffic struct {
+		TotalRequests int64 `json:"total_requests"`
+	} `json:"traffic"`
+	RPS       float64 `json:"rps"`
+	Upstreams []struct {
+		Name              string  `json:"name"`
+		Requests          int64   `json:"requests"`
+		RPS               float64 `json:"rps"`
+		ActiveConnections int64   `json:"active_connections"`
+	} `json:"upstreams"`
+}
+
+// sseEvents 读取SSE响应中的stats事件
+func sseEvents(t *testing.T, body io.Reader) <-chan streamSnapshot {
+	events := make(chan streamSnapshot, 100)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: ") && event == "stats":
+				var snap streamSnapshot
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &snap); err != nil {
+					t.Errorf("bad stats event %q: %v", line, err)
+					return
+				}
+				events <- snap
+			}
+		}
+	}()
+	return events
+}
+
+// nextSnapshot 等待下一个快照
+func nextSnapshot(t *testing.T, events <-chan streamSnapshot) streamSnapshot {
+	t.Helper()
+	select {
+	case snap, ok := <-events:
+		if !ok {
+			t.Fatal("stats stream closed")
+		}
+		return snap
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for a stats snapshot")
+	}
+	return streamSnapshot{}
+}
+
+func TestStatsStreamSSE(t *testing.T) {
+	skipShort(t)
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	// 只有一个管理工作协程，推送连接不能占用它
+	cfg.ControlPlane = types.ControlPlaneConfig{AdminWorkers: 1, AdminQueueTimeout: 100 * time.Millisecond}
+	p := testutil.StartProxy(t, cfg)
+
+	resp, err := http.Get(p.AdminURL("/api/v1/stats/stream?interval=200ms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := sseEvents(t, resp.Body)
+	if first := nextSnapshot(t, events); first.RPS != 0 {
+		t.Errorf("first snapshot rps %v, want 0", first.RPS)
+	}
+
+	// 推送期间其他管理请求仍然可以处理
+	var server map[string]interface{}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/server", nil, &server); err != nil {
+		t.Fatalf("admin request while streaming: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		get(t, p.URL("/"))
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		snap := nextSnapshot(t, events)
+		if len(snap.Upstreams) == 1 && snap.Upstreams[0].Name == "default" && snap.Upstreams[0].Requests == 5 && snap.Traffic.TotalRequests >= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot %+v, want 5 requests to upstream default", snap)
+		}
+	}
+
+	// 间隔不合法
+	resp2, err := http.Get(p.AdminURL("/api/v1/stats/stream?interval=1ms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("interval=1ms status %d, want 400", resp2.StatusCode)
+	}
+}
+
+func TestStatsStreamWebSocket(t *testing.T) {
+	skipShort(t)
+
+	p := testutil.StartProxy(t, testutil.NewConfig(testutil.StartBackend(t, "backend1")))
+	u, err := url.Parse(p.AdminURL("/api/v1/stats/stream?interval=200ms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", u.RequestURI(), u.Host, key)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d, want 101", resp.StatusCode)
+	}
+
+	// readFrame 读取一个服务端帧（不带掩码）
+	readFrame := func() (byte, []byte) {
+		t.Helper()
+		var head [2]byte
+		if _, err := io.ReadFull(br, head[:]); err != nil {
+			t.Fatal(err)
+		}
+		length := int(head[1] & 0x7F)
+		switch length {
+		case 126:
+			var ext [2]byte
+			io.ReadFull(br, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			io.ReadFull(br, ext[:])
+			length = int(binary.BigEndian.Uint64(ext[:]))
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatal(err)
+		}
+		return head[0] & 0x0F, payload
+	}
+	// writeFrame 发送一个带掩码的客户端帧
+	writeFrame := func(opcode byte, payload []byte) {
+		mask := []byte{1, 2, 3, 4}
+		frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+		if _, err := conn.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opcode, payload := readFrame()
+	var snap streamSnapshot
+	if opcode != 0x1 || json.Unmarshal(payload, &snap) != nil {
+		t.Fatalf("first frame opcode %d payload %q, want a JSON text message", opcode, payload)
+	}
+
+	// ping得到pong，close得到close
+	writeFrame(0x9, []byte("hi"))
+	for {
+		opcode, payload = readFrame()
+		if opcode == 0xA {
+			break
+		}
+	}
+	if string(payload) != "hi" {
+		t.Errorf("pong payload %q, want %q", payload, "hi")
+	}
+	writeFrame(0x8, []byte{0x03, 0xE8})
+	for {
+		if opcode, _ = readFrame(); opcode == 0x8 {
+			break
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// otlpSpan 收集器收到的span
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+// attr span属性的值
+func (s *otlpSpan) attr(key string) string {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.StringValue + a.Value.IntValue
+		}
+	}
+	return ""
+}
+
+// fakeCollector 模拟OTLP/HTTP收集器
+type fakeCollector struct {
+	mu      sync.Mutex
+	service string
+	spans   []otlpSpan
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string `json:"key"`
+					Value struct {
+						StringValue string `json:"stringValue"`
+					} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer collector-token" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, a := range rs.Resource.Attributes {
+			if a.Key == "service.name" {
+				c.service = a.Value.StringValue
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	w.Write([]byte(`{}`))
+}
+
+func (c *fakeCollector) received() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]otlpSpan(nil), c.spans...)
+}
+
+func TestTracingOTLP(t *testing.T) {
+	skipShort(t)
+
+	collector := &fakeCollector{}
+	collectorServer := httptest.NewServer(collector)
+	defer collectorServer.Close()
+
+	// 两个后端记录收到的traceparent，整个测试的第一个请求返回503以触发重试
+	var (
+		mu       sync.Mutex
+		received []string
+		served   atomic.Int64
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("traceparent"))
+		mu.Unlock()
+		if served.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if status, err := strconv.Atoi(r.URL.Query().Get("status")); err == nil {
+			w.WriteHeader(status)
+		}
+	})
+	var backends []*types.Backend
+	for _, id := range []string{"b1", "b2"} {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		backends = append(backends, testutil.ServerBackend(id, server))
+	}
+	lastReceived := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[len(received)-1]
+	}
+
+	cfg := testutil.NewConfig()
+	cfg.Backends["default"] = backends
+	cfg.Routing["default"].Retry = &types.RetryConfig{MaxAttempts: 2, StatusCodes: []int{503}}
+	cfg.Tracing = types.TracingConfig{
+		Enabled:       true,
+		Endpoint:      collectorServer.URL + "/v1/traces",
+		Headers:       map[string]string{"Authorization": "Bearer collector-token"},
+		ServiceName:   "edge-proxy",
+		FlushInterval: 50 * time.Millisecond,
+	}
+	p := testutil.StartProxy(t, cfg)
+
+	send := func(path, traceparent string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, p.URL(path), nil)
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 客户端的跟踪上下文作为父span，后端收到以代理的span为父span的traceparent
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const clientSpan = "00f067aa0ba902b7"
+	if status := send("/", "00-"+traceID+"-"+clientSpan+"-01"); status != http.StatusOK {
+		t.Fatalf("status %d, want 200 after a retry", status)
+	}
+	forwarded := lastReceived()
+	if !strings.HasPrefix(forwarded, "00-"+traceID+"-") || !strings.HasSuffix(forwarded, "-01") || strings.Contains(forwarded, clientSpan) {
+		t.Fatalf("backend received traceparent %q, want the client's trace with the proxy's span", forwarded)
+	}
+
+	// 未采样的跟踪照常传递但不导出
+	if status := send("/", "00-"+traceID+"-"+clientSpan+"-00"); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if got := lastReceived(); !strings.HasPrefix(got, "00-"+traceID+"-") || !strings.HasSuffix(got, "-00") {
+		t.Fatalf("backend received traceparent %q for an unsampled trace", got)
+	}
+
+	// 没有traceparent时开始新的跟踪；5xx标记为错误
+	if status := send("/?status=500", ""); status != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", status)
+	}
+	newTrace := lastReceived()
+	if len(newTrace) != 55 || strings.Contains(newTrace, traceID) {
+		t.Fatalf("backend received traceparent %q, want a new trace", newTrace)
+	}
+
+	if !testutil.Eventually(3*time.Second, func() bool { return len(collector.received()) >= 2 }) {
+		t.Fatalf("collector received %d spans, want 2", len(collector.received()))
+	}
+	time.Sleep(100 * time.Millisecond)
+	spans := collector.received()
+	if len(spans) != 2 || collector.service != "edge-proxy" {
+		t.Fatalf("collector received %d spans from %q, want 2 sampled spans from edge-proxy", len(spans), collector.service)
+	}
+
+	span := spans[0]
+	if span.TraceID != traceID || span.ParentSpanID != clientSpan || forwarded != "00-"+traceID+"-"+span.SpanID+"-01" {
+		t.Fatalf("span %s/%s parent %s does not match the forwarded traceparent %q", span.TraceID, span.SpanID, span.ParentSpanID, forwarded)
+	}
+	if span.Name != "GET default" || span.Kind != 2 || span.Status.Code != 0 {
+		t.Errorf("span name %q kind %d status %d", span.Name, span.Kind, span.Status.Code)
+	}
+	for key, want := range map[string]string{
+		"http.request.method":       "GET",
+		"http.response.status_code": "200",
+		"speedmimi.route":           "default",
+		"speedmimi.upstream":        "default",
+		"speedmimi.retries":         "1",
+	} {
+		if got := span.attr(key); got != want {
+			t.Errorf("attribute %s = %q, want %q", key, got, want)
+		}
+	}
+	if backend := span.attr("speedmimi.backend"); backend != "b1" && backend != "b2" {
+		t.Errorf("attribute speedmimi.backend = %q", backend)
+	}
+
+	span = spans[1]
+	if span.ParentSpanID != "" || newTrace != "00-"+span.TraceID+"-"+span.SpanID+"-01" {
+		t.Fatalf("span %+v does not match the new trace %q", span, newTrace)
+	}
+	if span.Status.Code != 2 || span.attr("http.response.status_code") != "500" || span.attr("speedmimi.retries") != "" {
+		t.Errorf("span for a 500 response: status %d, attributes %+v", span.Status.Code, span.Attributes)
+	}
+
+	// 关闭跟踪后客户端的traceparent原样转发
+	cfg = config.CloneConfig(p.Config.GetConfig())
+	cfg.Tracing.Enabled = false
+	if err := p.Config.UpdateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Eventually(3*time.Second, func() bool {
+		send("/", "00-"+traceID+"-"+clientSpan+"-01")
+		return lastReceived() == "00-"+traceID+"-"+clientSpan+"-01"
+	}) {
+		t.Fatalf("backend received traceparent %q after disabling tracing", lastReceived())
+	}
+}
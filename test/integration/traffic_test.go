@@ -0,0 +1,233 @@
+package integration
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quqi/speedmimi/internal/config"
+	"github.com/quqi/speedmimi/internal/monitor"
+	"github.com/quqi/speedmimi/internal/testutil"
+	"github.com/quqi/speedmimi/pkg/types"
+)
+
+// trafficStats 获取管理API返回的路由、上游和后端流量统计
+func trafficStats(t *testing.T, p *testutil.Proxy) (monitor.TrafficCounters, monitor.TrafficCounters, monitor.TrafficCounters) {
+	t.Helper()
+	var routes struct {
+		Routes []monitor.RouteTraffic `json:"routes"`
+	}
+	var upstreams struct {
+		Upstreams []monitor.UpstreamTraffic `json:"upstreams"`
+	}
+	var backends struct {
+		Backends []struct {
+			Upstream string                  `json:"upstream"`
+			Backend  string                  `json:"backend"`
+			Traffic  monitor.TrafficCounters `json:"traffic"`
+		} `json:"backends"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/route?route=default", nil, &routes); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/upstream?upstream=default", nil, &upstreams); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/backend?upstream=default&backend_id=backend1", nil, &backends); err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Routes) != 1 || len(upstreams.Upstreams) != 1 || len(backends.Backends) != 1 {
+		t.Fatalf("got %d routes, %d upstreams and %d backends, want one of each", len(routes.Routes), len(upstreams.Upstreams), len(backends.Backends))
+	}
+	return routes.Routes[0].TrafficCounters, upstreams.Upstreams[0].TrafficCounters, backends.Backends[0].Traffic
+}
+
+func TestTrafficCounters(t *testing.T) {
+	skipShort(t)
+
+	b1 := testutil.StartBackend(t, "backend1")
+	p := testutil.StartProxy(t, testutil.NewConfig(b1))
+
+	// 3个1000字节的响应，1个带100字节请求体的POST，2个503
+	for i := 0; i < 3; i++ {
+		get(t, p.URL("/?size=1000"))
+	}
+	resp, err := client.Post(p.URL("/?size=0"), "text/plain", strings.NewReader(strings.Repeat("y", 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	for i := 0; i < 2; i++ {
+		get(t, p.URL("/?status=503&size=0"))
+	}
+
+	route, upstream, backend := trafficStats(t, p)
+	want := monitor.TrafficCounters{
+		Requests: 6, Errors: 2, BytesIn: 100, BytesOut: 3000,
+		Status: monitor.StatusClasses{Status2xx: 4, Status5xx: 2},
+	}
+	for name, got := range map[string]monitor.TrafficCounters{"route": route, "upstream": upstream, "backend": backend} {
+		if got != want {
+			t.Errorf("%s traffic %+v, want %+v", name, got, want)
+		}
+	}
+
+	// 处理中的请求计入活跃连接，结束后归零
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		get(t, p.URL("/?sleep=300ms"))
+	}()
+	if !testutil.Eventually(time.Second, func() bool {
+		route, upstream, backend := trafficStats(t, p)
+		return route.ActiveConnections == 1 && upstream.ActiveConnections == 1 && backend.ActiveConnections == 1
+	}) {
+		r, u, b := trafficStats(t, p)
+		t.Fatalf("active connections route=%d upstream=%d backend=%d, want 1", r.ActiveConnections, u.ActiveConnections, b.ActiveConnections)
+	}
+	<-done
+	if !testutil.Eventually(time.Second, func() bool {
+		route, upstream, backend := trafficStats(t, p)
+		return route.ActiveConnections == 0 && upstream.ActiveConnections == 0 && backend.ActiveConnections == 0 && route.Requests == 7
+	}) {
+		t.Fatal("active connections were not released")
+	}
+
+	// Prometheus指标
+	resp, err = client.Get(p.AdminURL("/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, line := range []string{
+		`speedmimi_route_requests_total{route="default"} 7`,
+		`speedmimi_upstream_errors_total{upstream="default"} 2`,
+		`speedmimi_backend_requests_total{upstream="default",backend="backend1"} 7`,
+		`speedmimi_backend_received_bytes_total{upstream="default",backend="backend1"} 100`,
+		`speedmimi_route_active_connections{route="default"} 0`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+
+	// 从配置中删除的路由的统计随之删除
+	cfg := config.CloneConfig(p.Config.GetConfig())
+	cfg.Routing["api"] = cfg.Routing["default"]
+	delete(cfg.Routing, "default")
+	if err := p.Config.UpdateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	var routes struct {
+		Routes []monitor.RouteTraffic `json:"routes"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/route", nil, &routes); err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Routes) != 0 {
+		t.Fatalf("routes %+v, want the removed route's stats dropped", routes.Routes)
+	}
+}
+
+// backendOn 指向addr的后端配置
+func backendOn(t *testing.T, id, addr string) *types.Backend {
+	t.Helper()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, _ := strconv.Atoi(port)
+	return &types.Backend{ID: id, Name: id, Host: host, Port: portNum, Weight: 1, Scheme: "http", Active: true}
+}
+
+func TestStatusClassesAndUpstreamErrors(t *testing.T) {
+	skipShort(t)
+
+	// 拒绝连接的地址，以及接受连接后立即关闭的后端
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedAddr := ln.Addr().String()
+	ln.Close()
+	resetAddr := tcpListener(t, func(conn net.Conn) {})
+
+	cfg := testutil.NewConfig(testutil.StartBackend(t, "backend1"))
+	cfg.Backends["refused"] = []*types.Backend{backendOn(t, "refused1", refusedAddr)}
+	cfg.Backends["reset"] = []*types.Backend{backendOn(t, "reset1", resetAddr)}
+	cfg.Routing["refused"] = &types.RoutingRule{Path: "/refused", Upstream: "refused", LoadBalancer: types.LeastConnectionsWeight}
+	cfg.Routing["reset"] = &types.RoutingRule{Path: "/reset", Upstream: "reset", LoadBalancer: types.LeastConnectionsWeight}
+	p := testutil.StartProxy(t, cfg)
+
+	for _, path := range []string{"/", "/", "/?status=302", "/?status=404", "/?status=500", "/refused", "/refused", "/reset"} {
+		get(t, p.URL(path))
+	}
+
+	var backends struct {
+		Backends []struct {
+			Upstream       string                  `json:"upstream"`
+			Backend        string                  `json:"backend"`
+			Traffic        monitor.TrafficCounters `json:"traffic"`
+			UpstreamErrors monitor.UpstreamErrors  `json:"upstream_errors"`
+		} `json:"backends"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/backend", nil, &backends); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]monitor.StatusClasses)
+	gotErrors := make(map[string]monitor.UpstreamErrors)
+	for _, b := range backends.Backends {
+		got[b.Backend] = b.Traffic.Status
+		gotErrors[b.Backend] = b.UpstreamErrors
+	}
+	if want := (monitor.StatusClasses{Status2xx: 2, Status3xx: 1, Status4xx: 1, Status5xx: 1}); got["backend1"] != want {
+		t.Errorf("backend1 status classes %+v, want %+v", got["backend1"], want)
+	}
+	if want := (monitor.StatusClasses{Status5xx: 2}); got["refused1"] != want {
+		t.Errorf("refused1 status classes %+v, want %+v", got["refused1"], want)
+	}
+	if want := (monitor.UpstreamErrors{ConnectionRefused: 2}); gotErrors["refused1"] != want {
+		t.Errorf("refused1 upstream errors %+v, want %+v", gotErrors["refused1"], want)
+	}
+	if want := (monitor.UpstreamErrors{ConnectionReset: 1}); gotErrors["reset1"] != want {
+		t.Errorf("reset1 upstream errors %+v, want %+v", gotErrors["reset1"], want)
+	}
+	if gotErrors["backend1"] != (monitor.UpstreamErrors{}) {
+		t.Errorf("backend1 upstream errors %+v, want none", gotErrors["backend1"])
+	}
+
+	var upstreams struct {
+		Upstreams []monitor.UpstreamTraffic `json:"upstreams"`
+	}
+	if err := p.Admin(http.MethodGet, "/api/v1/stats/upstream?upstream=refused", nil, &upstreams); err != nil {
+		t.Fatal(err)
+	}
+	if len(upstreams.Upstreams) != 1 || upstreams.Upstreams[0].UpstreamErrors.ConnectionRefused != 2 {
+		t.Errorf("refused upstream stats %+v, want 2 refused connections", upstreams.Upstreams)
+	}
+
+	// Prometheus指标
+	resp, err := client.Get(p.AdminURL("/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, line := range []string{
+		`speedmimi_backend_responses_total{upstream="default",backend="backend1",class="3xx"} 1`,
+		`speedmimi_route_responses_total{route="refused",class="5xx"} 2`,
+		`speedmimi_backend_upstream_errors_total{upstream="refused",backend="refused1",type="connection_refused"} 2`,
+		`speedmimi_upstream_upstream_errors_total{upstream="reset",type="connection_reset"} 1`,
+		`speedmimi_backend_upstream_errors_total{upstream="default",backend="backend1",type="dial_timeout"} 0`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+}
@@ -1,42 +1,40 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/quqi/speedmimi/internal/bench"
+)
+
+var (
+	targetURLFlag   = flag.String("url", "http://localhost:8080", "压测的URL")
+	concurrencyFlag = flag.Int("concurrency", 10000, "并发请求的协程数")
+	durationFlag    = flag.Duration("duration", 300*time.Second, "测试时长")
+	output          = flag.String("output", "", "结果输出文件：.csv追加到文件末尾，其他扩展名写JSON")
+	commit          = flag.String("commit", os.Getenv("GIT_COMMIT"), "记录在结果中的被测提交")
 )
 
 func main() {
 	fmt.Println("🚀 SpeedMimi 千万级并发压力测试")
 	fmt.Println("===============================")
 
-	// 测试参数
-	targetURL := "http://localhost:8080"
-	totalRequests := int64(1000000) // 100万个请求
-	concurrency := 10000            // 1万个并发goroutine
-	duration := 300 * time.Second   // 5分钟测试时长
+	flag.Parse()
+	targetURL, concurrency, duration := *targetURLFlag, *concurrencyFlag, *durationFlag
 
 	fmt.Printf("目标URL: %s\n", targetURL)
-	fmt.Printf("总请求数: %d\n", totalRequests)
 	fmt.Printf("并发数: %d\n", concurrency)
 	fmt.Printf("测试时长: %v\n\n", duration)
 
-	// 统计变量（使用原子操作）
-	var (
-		requestsSent      int64
-		requestsCompleted int64
-		requestsFailed    int64
-		bytesReceived     int64
-		totalLatency      int64
-		minLatency        int64 = 1<<63 - 1
-		maxLatency        int64
-	)
-
-	// 初始化最小延迟
-	atomic.StoreInt64(&minLatency, 1<<63-1)
+	// 统计请求结果，延迟记录在HDR直方图中
+	recorder := bench.NewRecorder()
+	var requestsSent int64
 
 	// 控制测试时长
 	stop := make(chan struct{})
@@ -75,41 +73,14 @@ func main() {
 
 					resp, err := client.Get(targetURL)
 					if err != nil {
-						atomic.AddInt64(&requestsFailed, 1)
+						recorder.Record(time.Since(reqStart), 0, 0, err)
 						continue
 					}
 
 					// 读取响应体
-					body, err := io.ReadAll(resp.Body)
+					n, err := io.Copy(io.Discard, resp.Body)
 					resp.Body.Close()
-
-					latency := time.Since(reqStart).Nanoseconds()
-
-					if err != nil {
-						atomic.AddInt64(&requestsFailed, 1)
-					} else {
-						atomic.AddInt64(&requestsCompleted, 1)
-						atomic.AddInt64(&bytesReceived, int64(len(body)))
-
-						// 更新延迟统计
-						atomic.AddInt64(&totalLatency, latency)
-
-						// 更新最小延迟
-						for {
-							currentMin := atomic.LoadInt64(&minLatency)
-							if latency >= currentMin || atomic.CompareAndSwapInt64(&minLatency, currentMin, latency) {
-								break
-							}
-						}
-
-						// 更新最大延迟
-						for {
-							currentMax := atomic.LoadInt64(&maxLatency)
-							if latency <= currentMax || atomic.CompareAndSwapInt64(&maxLatency, currentMax, latency) {
-								break
-							}
-						}
-					}
+					recorder.Record(time.Since(reqStart), resp.StatusCode, n, err)
 				}
 			}
 		}(i)
@@ -126,8 +97,7 @@ func main() {
 				return
 			case <-ticker.C:
 				sent := atomic.LoadInt64(&requestsSent)
-				completed := atomic.LoadInt64(&requestsCompleted)
-				failed := atomic.LoadInt64(&requestsFailed)
+				completed, failed := recorder.Counts()
 
 				rps := float64(completed) / time.Since(startTime).Seconds()
 				fmt.Printf("\r进度: 发送=%d, 完成=%d, 失败=%d, RPS=%.0f",
@@ -142,31 +112,32 @@ func main() {
 	totalDuration := endTime.Sub(startTime)
 
 	// 计算最终统计
+	result := recorder.Result(fmt.Sprintf("%d并发", concurrency), concurrency, totalDuration)
 	finalSent := atomic.LoadInt64(&requestsSent)
-	finalCompleted := atomic.LoadInt64(&requestsCompleted)
-	finalFailed := atomic.LoadInt64(&requestsFailed)
-	finalBytes := atomic.LoadInt64(&bytesReceived)
-	finalTotalLatency := atomic.LoadInt64(&totalLatency)
-	finalMinLatency := atomic.LoadInt64(&minLatency)
-	finalMaxLatency := atomic.LoadInt64(&maxLatency)
-
-	fmt.Println("\n")
+	finalCompleted := result.Succeeded
+	finalFailed := result.Failed
+	finalBytes := result.BytesReceived
+
+	fmt.Println()
+	fmt.Println()
 	fmt.Println("=== 最终测试结果 ===")
 	fmt.Printf("测试时长: %v\n", totalDuration)
 	fmt.Printf("总发送请求: %d\n", finalSent)
 	fmt.Printf("成功完成请求: %d\n", finalCompleted)
-	fmt.Printf("失败请求: %d\n", finalFailed)
+	fmt.Printf("失败请求: %d %v\n", finalFailed, result.Errors)
 	fmt.Printf("成功率: %.2f%%\n", float64(finalCompleted)/float64(finalSent)*100)
 
 	if finalCompleted > 0 {
 		avgRPS := float64(finalCompleted) / totalDuration.Seconds()
 		fmt.Printf("平均RPS: %.0f\n", avgRPS)
 
-		avgLatency := time.Duration(finalTotalLatency / finalCompleted)
-		fmt.Printf("平均延迟: %v\n", avgLatency)
-
-		fmt.Printf("最小延迟: %v\n", time.Duration(finalMinLatency))
-		fmt.Printf("最大延迟: %v\n", time.Duration(finalMaxLatency))
+		latency := recorder.Latency()
+		fmt.Printf("平均延迟: %v\n", latency.Mean())
+		fmt.Printf("最小延迟: %v\n", latency.Min())
+		fmt.Printf("最大延迟: %v\n", latency.Max())
+		fmt.Printf("延迟分位: p50=%v p90=%v p99=%v p999=%v\n",
+			latency.ValueAtPercentile(50), latency.ValueAtPercentile(90),
+			latency.ValueAtPercentile(99), latency.ValueAtPercentile(99.9))
 
 		avgBytes := float64(finalBytes) / float64(finalCompleted)
 		fmt.Printf("平均响应大小: %.0f bytes\n", avgBytes)
@@ -186,6 +157,16 @@ func main() {
 		fmt.Println("\n❌ 性能表现: 需要优化")
 	}
 
+	if *output != "" {
+		report := bench.NewReport("million_concurrent", *commit, targetURL)
+		report.Results = append(report.Results, result)
+		if err := bench.WriteFile(*output, report); err != nil {
+			fmt.Printf("写入结果失败: %v\n", err)
+		} else {
+			fmt.Printf("\n结果已写入 %s\n", *output)
+		}
+	}
+
 	fmt.Println("\n测试完成!")
 }
 
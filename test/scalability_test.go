@@ -1,17 +1,36 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"runtime"
 	"sync"
-	"sync/atomic"
 	"time"
+
+	"github.com/quqi/speedmimi/internal/bench"
+)
+
+var (
+	targetURL   = flag.String("url", "http://localhost:8080/", "压测的URL")
+	concurrency = flag.Int("concurrency", 0, "只运行一个阶段的并发数，0表示按阶段逐步增加")
+	duration    = flag.Duration("duration", 30*time.Second, "-concurrency指定的阶段的持续时间")
+	output      = flag.String("output", "", "结果输出文件：.csv追加到文件末尾（每阶段一行），其他扩展名写JSON")
+	commit      = flag.String("commit", os.Getenv("GIT_COMMIT"), "记录在结果中的被测提交")
 )
 
+// stage 压测阶段
+type stage struct {
+	name        string
+	concurrency int
+	duration    time.Duration
+}
+
 func main() {
+	flag.Parse()
+
 	fmt.Println("🚀 SpeedMimi 可扩展性并发测试")
 	fmt.Println("=================================")
 
@@ -32,11 +51,7 @@ func main() {
 	}
 
 	// 分阶段测试：1k -> 5k -> 10k -> 25k -> 50k -> 100k
-	testStages := []struct {
-		name        string
-		concurrency int
-		duration    time.Duration
-	}{
+	testStages := []stage{
 		{"1千并发", 1000, 30 * time.Second},
 		{"5千并发", 5000, 30 * time.Second},
 		{"1万并发", 10000, 30 * time.Second},
@@ -45,22 +60,28 @@ func main() {
 		{"10万并发", 100000, 15 * time.Second},
 	}
 
-	for i, stage := range testStages {
-		fmt.Printf("=== 阶段 %d: %s ===\n", i+1, stage.name)
+	if *concurrency > 0 {
+		testStages = []stage{{fmt.Sprintf("%d并发", *concurrency), *concurrency, *duration}}
+	}
+
+	report := bench.NewReport("scalability_bench", *commit, *targetURL)
+	for i, st := range testStages {
+		fmt.Printf("=== 阶段 %d: %s ===\n", i+1, st.name)
 
 		// 检查系统是否能处理这个并发量
-		if stage.concurrency > 100000 && runtime.NumCPU() < 8 {
-			fmt.Printf("⚠️  跳过 %s (CPU核心数不足)\n\n", stage.name)
+		if st.concurrency > 100000 && runtime.NumCPU() < 8 {
+			fmt.Printf("⚠️  跳过 %s (CPU核心数不足)\n\n", st.name)
 			continue
 		}
 
-		result := runConcurrencyTest(client, stage.concurrency, stage.duration)
-		if result == nil {
-			fmt.Printf("❌ %s 测试失败，停止测试\n\n", stage.name)
+		result := runConcurrencyTest(client, *targetURL, st.name, st.concurrency, st.duration)
+		report.Results = append(report.Results, result)
+		if result.Succeeded == 0 {
+			fmt.Printf("❌ %s 测试失败：没有成功完成的请求 %v，停止测试\n\n", st.name, result.Errors)
 			break
 		}
 
-		printTestResult(result)
+		printTestResult(&result)
 
 		// 如果成功率太低，停止测试
 		if result.SuccessRate < 80.0 {
@@ -69,7 +90,17 @@ func main() {
 		}
 
 		// 短暂休息
-		time.Sleep(5 * time.Second)
+		if i < len(testStages)-1 {
+			time.Sleep(5 * time.Second)
+		}
+	}
+
+	if *output != "" {
+		if err := bench.WriteFile(*output, report); err != nil {
+			fmt.Printf("❌ 写入结果失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("📄 结果已写入 %s\n\n", *output)
 	}
 
 	fmt.Println("=== 1000万并发理论分析 ===")
@@ -97,34 +128,11 @@ func main() {
 	fmt.Println("• 定制Linux内核")
 }
 
-type TestResult struct {
-	Concurrency     int
-	Duration        time.Duration
-	TotalRequests   int64
-	SuccessfulReqs  int64
-	FailedReqs      int64
-	AverageLatency  time.Duration
-	MinLatency      time.Duration
-	MaxLatency      time.Duration
-	RPS             float64
-	SuccessRate     float64
-	DataTransferred int64
-}
-
-func runConcurrencyTest(client *http.Client, concurrency int, duration time.Duration) *TestResult {
+// runConcurrencyTest 以concurrency个协程持续请求目标URL，持续duration
+func runConcurrencyTest(client *http.Client, targetURL string, name string, concurrency int, duration time.Duration) bench.Result {
 	fmt.Printf("启动 %d 并发测试 (%v)...\n", concurrency, duration)
 
-	var (
-		requestsSent      int64
-		requestsCompleted int64
-		requestsFailed    int64
-		totalLatency      int64
-		minLatency        int64 = 1<<63 - 1
-		maxLatency        int64
-		dataTransferred   int64
-	)
-
-	atomic.StoreInt64(&minLatency, 1<<63-1)
+	recorder := bench.NewRecorder()
 
 	stop := make(chan struct{})
 	time.AfterFunc(duration, func() {
@@ -145,41 +153,16 @@ func runConcurrencyTest(client *http.Client, concurrency int, duration time.Dura
 					return
 				default:
 					reqStart := time.Now()
-					atomic.AddInt64(&requestsSent, 1)
 
-					resp, err := client.Get("http://localhost:8080/")
+					resp, err := client.Get(targetURL)
 					if err != nil {
-						atomic.AddInt64(&requestsFailed, 1)
+						recorder.Record(time.Since(reqStart), 0, 0, err)
 						continue
 					}
 
-					body, err := io.ReadAll(resp.Body)
+					n, err := io.Copy(io.Discard, resp.Body)
 					resp.Body.Close()
-					latency := time.Since(reqStart).Nanoseconds()
-
-					if err != nil {
-						atomic.AddInt64(&requestsFailed, 1)
-					} else {
-						atomic.AddInt64(&requestsCompleted, 1)
-						atomic.AddInt64(&dataTransferred, int64(len(body)))
-						atomic.AddInt64(&totalLatency, latency)
-
-						// 更新最小延迟
-						for {
-							currentMin := atomic.LoadInt64(&minLatency)
-							if latency >= currentMin || atomic.CompareAndSwapInt64(&minLatency, currentMin, latency) {
-								break
-							}
-						}
-
-						// 更新最大延迟
-						for {
-							currentMax := atomic.LoadInt64(&maxLatency)
-							if latency <= currentMax || atomic.CompareAndSwapInt64(&maxLatency, currentMax, latency) {
-								break
-							}
-						}
-					}
+					recorder.Record(time.Since(reqStart), resp.StatusCode, n, err)
 				}
 			}
 		}(i)
@@ -195,64 +178,36 @@ func runConcurrencyTest(client *http.Client, concurrency int, duration time.Dura
 			case <-stop:
 				return
 			case <-ticker.C:
-				sent := atomic.LoadInt64(&requestsSent)
-				completed := atomic.LoadInt64(&requestsCompleted)
-				failed := atomic.LoadInt64(&requestsFailed)
+				completed, failed := recorder.Counts()
 				rps := float64(completed) / time.Since(startTime).Seconds()
 
-				fmt.Printf("\r进度: 发送=%d, 完成=%d, 失败=%d, RPS=%.0f",
-					sent, completed, failed, rps)
+				fmt.Printf("\r进度: 完成=%d, 失败=%d, RPS=%.0f",
+					completed, failed, rps)
 			}
 		}
 	}()
 
 	wg.Wait()
-	actualDuration := time.Since(startTime)
-
-	finalSent := atomic.LoadInt64(&requestsSent)
-	finalCompleted := atomic.LoadInt64(&requestsCompleted)
-	finalFailed := atomic.LoadInt64(&requestsFailed)
-	finalTotalLatency := atomic.LoadInt64(&totalLatency)
-	finalDataTransferred := atomic.LoadInt64(&dataTransferred)
-	finalMinLatency := atomic.LoadInt64(&minLatency)
-	finalMaxLatency := atomic.LoadInt64(&maxLatency)
+	result := recorder.Result(name, concurrency, time.Since(startTime))
 
 	fmt.Println() // 换行
-
-	if finalCompleted == 0 {
-		fmt.Println("❌ 测试失败：没有成功完成的请求")
-		return nil
-	}
-
-	result := &TestResult{
-		Concurrency:     concurrency,
-		Duration:        actualDuration,
-		TotalRequests:   finalSent,
-		SuccessfulReqs:  finalCompleted,
-		FailedReqs:      finalFailed,
-		AverageLatency:  time.Duration(finalTotalLatency / finalCompleted),
-		MinLatency:      time.Duration(finalMinLatency),
-		MaxLatency:      time.Duration(finalMaxLatency),
-		RPS:             float64(finalCompleted) / actualDuration.Seconds(),
-		SuccessRate:     float64(finalCompleted) / float64(finalSent) * 100,
-		DataTransferred: finalDataTransferred,
-	}
-
 	return result
 }
 
-func printTestResult(result *TestResult) {
+func printTestResult(result *bench.Result) {
 	fmt.Printf("测试结果:\n")
-	fmt.Printf("  测试时长: %v\n", result.Duration)
-	fmt.Printf("  总请求数: %d\n", result.TotalRequests)
-	fmt.Printf("  成功请求: %d\n", result.SuccessfulReqs)
-	fmt.Printf("  失败请求: %d\n", result.FailedReqs)
+	fmt.Printf("  测试时长: %.1fs\n", result.Duration)
+	fmt.Printf("  总请求数: %d\n", result.Requests)
+	fmt.Printf("  成功请求: %d\n", result.Succeeded)
+	fmt.Printf("  失败请求: %d %v\n", result.Failed, result.Errors)
 	fmt.Printf("  成功率: %.2f%%\n", result.SuccessRate)
 	fmt.Printf("  RPS: %.0f\n", result.RPS)
-	fmt.Printf("  平均延迟: %v\n", result.AverageLatency)
-	fmt.Printf("  最小延迟: %v\n", result.MinLatency)
-	fmt.Printf("  最大延迟: %v\n", result.MaxLatency)
-	fmt.Printf("  数据传输: %.2f MB\n", float64(result.DataTransferred)/(1024*1024))
+	fmt.Printf("  平均延迟: %.2fms\n", result.Latency.Mean)
+	fmt.Printf("  最小延迟: %.2fms\n", result.Latency.Min)
+	fmt.Printf("  最大延迟: %.2fms\n", result.Latency.Max)
+	fmt.Printf("  延迟分位: p50=%.2fms p90=%.2fms p99=%.2fms p999=%.2fms\n",
+		result.Latency.P50, result.Latency.P90, result.Latency.P99, result.Latency.P999)
+	fmt.Printf("  数据传输: %.2f MB\n", float64(result.BytesReceived)/(1024*1024))
 
 	// 性能评估
 	if result.SuccessRate >= 99.0 && result.RPS > 10000 {
@@ -267,4 +222,3 @@ func printTestResult(result *TestResult) {
 
 	fmt.Println()
 }
-
@@ -16,7 +16,6 @@ func main() {
 		ID:       "test-backend",
 		Name:     "Test Backend",
 		MaxConn:  2, // 设置最大连接数为2
-		Connections: 0,
 	}
 
 	// 初始化为活跃状态